@@ -14,7 +14,9 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/integration/nwo/artifactgen/gen"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/cmd/certfier"
 	pp2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/cmd/pp"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/cmd/signer"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/cmd/version"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/cmd/wallet"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -44,7 +46,10 @@ func main() {
 	mainCmd.AddCommand(pp2.UtilsCmd())
 	mainCmd.AddCommand(certfier.KeyPairGenCmd())
 	mainCmd.AddCommand(gen.Cmd())
+	mainCmd.AddCommand(signer.ServerCmd())
+	mainCmd.AddCommand(signer.OfflineSignCmd())
 	mainCmd.AddCommand(version.Cmd())
+	mainCmd.AddCommand(wallet.Cmd())
 
 	// On failure Cobra prints the usage message and error string, so we only
 	// need to exit with a non-0 status
@@ -7,17 +7,22 @@ SPDX-License-Identifier: Apache-2.0
 package main
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"testing"
 
+	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/cmd/pp/common"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/disclosure"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/anonym"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/gh/driver"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh/driver"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gexec"
@@ -52,6 +57,9 @@ func TestGenFullSuccess(t *testing.T) {
 			"./testdata/issuers/msp",
 			"--auditors",
 			"./testdata/auditors/msp",
+			"--anonymous-issuers",
+			"2",
+			"--audit-encryption",
 			"--output",
 			tempOutput,
 		},
@@ -75,6 +83,26 @@ func TestGenFullSuccess(t *testing.T) {
 	gt.Expect(err).NotTo(HaveOccurred())
 	gt.Expect(issuers[0]).To(BeEquivalentTo(issuer))
 
+	// the anonymous issuers follow the others
+	gt.Expect(issuers).To(HaveLen(3))
+	for i := 0; i < 2; i++ {
+		raw, err := ioutil.ReadFile(filepath.Join(tempOutput, fmt.Sprintf("anonymous_issuer_%d.json", i)))
+		gt.Expect(err).NotTo(HaveOccurred())
+		key := &anonym.SecretKey{}
+		gt.Expect(key.Deserialize(raw)).To(Succeed())
+		id, err := key.Identity()
+		gt.Expect(err).NotTo(HaveOccurred())
+		gt.Expect(issuers[i+1]).To(BeEquivalentTo(id))
+	}
+
+	// the auditor encryption key matches the public parameters
+	gt.Expect(pp.AuditEncryption()).To(BeTrue())
+	raw, err := ioutil.ReadFile(filepath.Join(tempOutput, "auditor_encryption_key"))
+	gt.Expect(err).NotTo(HaveOccurred())
+	sk, err := disclosure.SecretKeyFromBytes(raw, pp)
+	gt.Expect(err).NotTo(HaveOccurred())
+	gt.Expect(sk.H.Equals(pp.AuditEncryptionParams.PK)).To(BeTrue())
+
 	idemixPK, err := ioutil.ReadFile("./testdata/idemix/msp/IssuerPublicKey")
 	gt.Expect(err).NotTo(HaveOccurred())
 	gt.Expect(idemixPK).To(BeEquivalentTo(pp.IdemixIssuerPK))
@@ -145,6 +173,44 @@ func TestGenFailure(t *testing.T) {
 			},
 			ErrMsg: "Error: failed to generate public parameters: failed to get issuer identity [Error: failed to generate public parameters: failed to get issuer identity [aOrg1MSP]: invalid input [aOrg1MSP]]: invalid input [Error: failed to generate public parameters: failed to get issuer identity [aOrg1MSP]: invalid input [aOrg1MSP]]",
 		},
+		{
+			Args: []string{
+				"gen",
+				"dlog",
+				"--idemix", "./testdata/idemix",
+				"--migrate-from", "zkatdlog",
+			},
+			ErrMsg: "Error: failed to generate public parameters: failed setting up migration: invalid migration parameters: cannot migrate from [zkatdlog], only [fabtoken] is supported",
+		},
+		{
+			Args: []string{
+				"gen",
+				"dlog",
+				"--idemix", "./testdata/idemix",
+				"--migration-deadline", "2030-01-01T00:00:00Z",
+			},
+			ErrMsg: "Error: failed to generate public parameters: failed setting up migration: a deadline requires the driver to migrate from",
+		},
+		{
+			Args: []string{
+				"gen",
+				"dlog",
+				"--idemix", "./testdata/idemix",
+				"--curve", "BLS12_381",
+			},
+			ErrMsg: "Error: failed to generate public parameters: invalid curve: unsupported curve [BLS12_381], expected one of [BN254 FP256BN_AMCL FP256BN_AMCL_MIRACL]",
+		},
+		{
+			Args: []string{
+				"gen",
+				"dlog",
+				"--idemix", "./testdata/idemix",
+				"--curve", "FP256BN_AMCL",
+				"--idemix-curve", "BN254",
+				"--graph-hiding", "4",
+			},
+			ErrMsg: "Error: failed to generate public parameters: failed setting up graph hiding: the tokens must be on the idemix curve",
+		},
 	}...,
 	)
 
@@ -167,6 +233,21 @@ func TestGenFailure(t *testing.T) {
 	gt.Expect(err).NotTo(HaveOccurred())
 	_, _, err = token.NewServicesFromPublicParams(raw)
 	gt.Expect(err).NotTo(HaveOccurred())
+
+	testGenRun(gt, tokengen, []string{"gen", "dlog", "--idemix", "./testdata/idemix", "--output", tempOutput, "--migrate-from", "fabtoken", "--migration-deadline", "2030-01-01T00:00:00Z"})
+	raw, err = ioutil.ReadFile(filepath.Join(tempOutput, "zkatdlog_pp.json"))
+	gt.Expect(err).NotTo(HaveOccurred())
+	_, _, err = token.NewServicesFromPublicParams(raw)
+	gt.Expect(err).NotTo(HaveOccurred())
+
+	testGenRun(gt, tokengen, []string{"gen", "dlog", "--idemix", "./testdata/idemix", "--output", tempOutput, "--curve", "FP256BN_AMCL", "--idemix-curve", "BN254"})
+	raw, err = ioutil.ReadFile(filepath.Join(tempOutput, "zkatdlog_pp.json"))
+	gt.Expect(err).NotTo(HaveOccurred())
+	pp, err := crypto.NewPublicParamsFromBytes(raw, crypto.DLogPublicParameters)
+	gt.Expect(err).NotTo(HaveOccurred())
+	gt.Expect(pp.Validate()).NotTo(HaveOccurred())
+	gt.Expect(pp.Curve).To(Equal(math.FP256BN_AMCL))
+	gt.Expect(pp.IdemixCurveID).To(Equal(math.BN254))
 }
 
 func testGenRunWithError(gt *WithT, tokengen string, args []string, errMsg string) {
@@ -20,6 +20,42 @@ func (m *ConfigManager) Certifiers() []string {
 	return m.cm.TMS().Certification.Interactive.IDs
 }
 
+// InteractiveCertification returns the configuration of the interactive certification process, if any.
+func (m *ConfigManager) InteractiveCertification() *config.InteractiveCertification {
+	if m.cm.TMS().Certification == nil {
+		return nil
+	}
+	return m.cm.TMS().Certification.Interactive
+}
+
+// OwnerWalletPath returns the translated path of the configured owner wallet with the passed identifier.
+// It returns the empty string if no such wallet is configured.
+func (m *ConfigManager) OwnerWalletPath(id string) string {
+	if m.cm.TMS().Wallets == nil {
+		return ""
+	}
+	for _, owner := range m.cm.TMS().Wallets.Owners {
+		if owner.ID == id {
+			return m.cm.TranslatePath(owner.Path)
+		}
+	}
+	return ""
+}
+
+// OwnerWalletPolicy returns the spending policy of the configured owner wallet with the passed identifier.
+// It returns nil if no such wallet is configured or if the wallet has no policy.
+func (m *ConfigManager) OwnerWalletPolicy(id string) *config.Policy {
+	if m.cm.TMS().Wallets == nil {
+		return nil
+	}
+	for _, owner := range m.cm.TMS().Wallets.Owners {
+		if owner.ID == id {
+			return owner.Policy
+		}
+	}
+	return nil
+}
+
 // UnmarshalKey takes a single key and unmarshals it into a Struct
 func (m *ConfigManager) UnmarshalKey(key string, rawVal interface{}) error {
 	return m.cm.UnmarshalKey(key, rawVal)
@@ -29,6 +29,15 @@ type PP interface {
 	AddAuditor(raw view.Identity)
 	// AddIssuer adds an issuer to the public parameters
 	AddIssuer(raw view.Identity)
+	// AddRevokedEnrollmentID adds a revoked enrollment ID to the public parameters
+	AddRevokedEnrollmentID(eid string)
+}
+
+// SetupRevokedEnrollmentIDs adds the passed revoked enrollment IDs to the given public parameters
+func SetupRevokedEnrollmentIDs(pp PP, revoked []string) {
+	for _, eid := range revoked {
+		pp.AddRevokedEnrollmentID(eid)
+	}
 }
 
 // GetMSPIdentity returns the MSP identity from the passed entry formatted as <MSPConfigPath>:<MSPID>.
@@ -10,12 +10,16 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"time"
 
 	math3 "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/cmd/pp/cc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/cmd/pp/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/cmd/pp/idemix"
+	idemix2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/idemix"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/disclosure"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/anonym"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -29,12 +33,31 @@ type GeneratorArgs struct {
 	GenerateCCPackage bool
 	// Issuers is the list of issuer MSP directories containing the corresponding issuer certificate
 	Issuers []string
+	// AnonymousIssuers is the number of anonymous issuer keys to generate
+	AnonymousIssuers uint
 	// Auditors is the list of auditor MSP directories containing the corresponding auditor certificate
 	Auditors []string
+	// Revoked is the list of enrollment IDs that are not allowed to transact
+	Revoked []string
 	// Base is a dlog driver related parameter
 	Base uint
 	// Exponent is a dlog driver related parameter
 	Exponent uint
+	// AnonymitySetBitLength enables graph hiding, if not zero, with anonymity sets of 2^AnonymitySetBitLength tokens
+	AnonymitySetBitLength uint
+	// BulletproofBitLength enables Bulletproofs range proofs, if not zero, over values of BulletproofBitLength bits
+	BulletproofBitLength uint
+	// AuditEncryption, if true, generates an Elgamal key for the auditor and lets the outputs carry
+	// the encryption of their type and value under it
+	AuditEncryption bool
+	// MigrateFrom declares, if not empty, the driver whose tokens are migrated to zkatdlog
+	MigrateFrom string
+	// MigrationDeadline closes the migration window, if not zero
+	MigrationDeadline time.Time
+	// Curve is the name of the curve of the commitments and the range proofs, BN254 if empty
+	Curve string
+	// IdemixCurve is the name of the curve of the idemix owners, the same as Curve if empty
+	IdemixCurve string
 }
 
 var (
@@ -46,14 +69,38 @@ var (
 	GenerateCCPackage bool
 	// Issuers is the list of issuer MSP directories containing the corresponding issuer certificate
 	Issuers []string
+	// AnonymousIssuers is the number of anonymous issuer keys to generate.
+	// Each key is stored in the output folder, and its identity is added to the issuers
+	AnonymousIssuers uint
 	// Auditors is the list of auditor MSP directories containing the corresponding auditor certificate
 	Auditors []string
+	// Revoked is the list of enrollment IDs that are not allowed to transact
+	Revoked []string
 	// Base is a dlog driver related parameter.
 	// It is used to define the maximum quantity a token can contain as Base^Exponent
 	Base uint
 	// Exponent is a dlog driver related parameter
 	// It is used to define the maximum quantity a token can contain as Base^Exponent
 	Exponent uint
+	// AnonymitySetBitLength enables graph hiding, if not zero.
+	// Spent tokens are hidden in anonymity sets of 2^AnonymitySetBitLength tokens
+	AnonymitySetBitLength uint
+	// BulletproofBitLength enables Bulletproofs range proofs, if not zero.
+	// Token quantities are then in [0, 2^BulletproofBitLength), and Base and Exponent are ignored
+	BulletproofBitLength uint
+	// AuditEncryption, if true, lets the outputs carry the encryption of their type and value for the auditor.
+	// The secret key of the auditor is stored in the output folder
+	AuditEncryption bool
+	// MigrateFrom declares, if not empty, the driver whose tokens are migrated to zkatdlog.
+	// Its outputs can then be upgraded to zkatdlog tokens until the migration window closes.
+	MigrateFrom string
+	// MigrationDeadline closes the migration window, if not empty. It is formatted following RFC 3339
+	MigrationDeadline string
+	// Curve is the name of the pairing-friendly curve of the commitments and the range proofs
+	Curve string
+	// IdemixCurve is the name of the pairing-friendly curve of the idemix owners.
+	// If empty, it is the same as Curve
+	IdemixCurve string
 )
 
 // Cmd returns the Cobra Command for Version
@@ -64,9 +111,18 @@ func Cmd() *cobra.Command {
 	flags.BoolVarP(&GenerateCCPackage, "cc", "", false, "generate chaincode package")
 	flags.StringSliceVarP(&Auditors, "auditors", "a", nil, "list of auditor MSP directories containing the corresponding auditor certificate")
 	flags.StringSliceVarP(&Issuers, "issuers", "s", nil, "list of issuer MSP directories containing the corresponding issuer certificate")
+	flags.UintVarP(&AnonymousIssuers, "anonymous-issuers", "", 0, "number of anonymous issuer keys to generate, stored in the output folder as anonymous_issuer_<index>.json")
+	flags.StringSliceVarP(&Revoked, "revoked", "r", nil, "list of enrollment IDs that are not allowed to transact")
 	flags.StringVarP(&IdemixMSPDir, "idemix", "i", "", "idemix msp dir")
 	flags.UintVarP(&Base, "base", "b", 100, "base is used to define the maximum quantity a token can contain as Base^Exponent")
 	flags.UintVarP(&Exponent, "exponent", "e", 2, "exponent is used to define the maximum quantity a token can contain as Base^Exponent")
+	flags.UintVarP(&AnonymitySetBitLength, "graph-hiding", "g", 0, "enables graph hiding, hiding spent tokens in anonymity sets of 2^graph-hiding tokens")
+	flags.UintVarP(&BulletproofBitLength, "bulletproofs", "", 0, "enables Bulletproofs range proofs over values of the passed number of bits, base and exponent are then ignored")
+	flags.BoolVarP(&AuditEncryption, "audit-encryption", "", false, "encrypts the type and value of the outputs for the auditor, whose secret key is stored in the output folder as auditor_encryption_key")
+	flags.StringVarP(&MigrateFrom, "migrate-from", "", "", "declares the driver whose tokens are migrated to zkatdlog, only fabtoken is supported")
+	flags.StringVarP(&MigrationDeadline, "migration-deadline", "", "", "closes the migration window at the passed time, formatted following RFC 3339")
+	flags.StringVarP(&Curve, "curve", "", "BN254", fmt.Sprintf("curve of the commitments and the range proofs, one of %v", crypto.CurveNames()))
+	flags.StringVarP(&IdemixCurve, "idemix-curve", "", "", "curve of the idemix owners, the same as curve if empty")
 
 	return cobraCommand
 }
@@ -81,14 +137,32 @@ var cobraCommand = &cobra.Command{
 		}
 		// Parsing of the command line is done so silence cmd usage
 		cmd.SilenceUsage = true
+		var deadline time.Time
+		if len(MigrationDeadline) != 0 {
+			var err error
+			deadline, err = time.Parse(time.RFC3339, MigrationDeadline)
+			if err != nil {
+				return errors.Wrap(err, "failed to parse migration deadline")
+			}
+		}
 		raw, err := Gen(&GeneratorArgs{
 			IdemixMSPDir:      IdemixMSPDir,
 			OutputDir:         OutputDir,
 			GenerateCCPackage: GenerateCCPackage,
 			Issuers:           Issuers,
+			AnonymousIssuers:  AnonymousIssuers,
 			Auditors:          Auditors,
+			Revoked:           Revoked,
 			Base:              Base,
 			Exponent:          Exponent,
+
+			AnonymitySetBitLength: AnonymitySetBitLength,
+			BulletproofBitLength:  BulletproofBitLength,
+			AuditEncryption:       AuditEncryption,
+			MigrateFrom:           MigrateFrom,
+			MigrationDeadline:     deadline,
+			Curve:                 Curve,
+			IdemixCurve:           IdemixCurve,
 		})
 		if err != nil {
 			return errors.Wrap(err, "failed to generate public parameters")
@@ -112,28 +186,131 @@ func Gen(args *GeneratorArgs) ([]byte, error) {
 		return nil, err
 	}
 
+	curveID, idemixCurveID, err := curves(args.Curve, args.IdemixCurve)
+	if err != nil {
+		return nil, err
+	}
+
 	// Setup
-	// TODO: update the curve here
-	pp, err := crypto.Setup(args.Base, args.Exponent, ipkBytes, math3.BN254)
+	var pp *crypto.PublicParams
+	fileName := "zkatdlog_pp.json"
+	if args.AnonymitySetBitLength != 0 {
+		if curveID != idemixCurveID {
+			return nil, errors.New("failed setting up graph hiding: the tokens must be on the idemix curve")
+		}
+		hRand, err := idemix2.HRand(ipkBytes, idemixCurveID)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed loading idemix issuer public key")
+		}
+		pp, err = crypto.SetupGraphHiding(args.Base, args.Exponent, ipkBytes, idemixCurveID, hRand, args.AnonymitySetBitLength)
+		fileName = "zkatdloggh_pp.json"
+	} else {
+		pp, err = crypto.SetupWithCurves(args.Base, args.Exponent, ipkBytes, crypto.DLogPublicParameters, curveID, idemixCurveID)
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, "failed setting up public parameters")
 	}
+	if args.BulletproofBitLength != 0 {
+		if err := pp.EnableBulletproofs(args.BulletproofBitLength); err != nil {
+			return nil, errors.Wrap(err, "failed setting up bulletproofs")
+		}
+	}
+	if args.AuditEncryption {
+		if err := setupAuditEncryption(pp, args.OutputDir); err != nil {
+			return nil, err
+		}
+	}
+	if len(args.MigrateFrom) != 0 {
+		if err := pp.EnableMigration(args.MigrateFrom, args.MigrationDeadline); err != nil {
+			return nil, errors.Wrap(err, "failed setting up migration")
+		}
+	} else if !args.MigrationDeadline.IsZero() {
+		return nil, errors.New("failed setting up migration: a deadline requires the driver to migrate from")
+	}
 	if err := pp.Validate(); err != nil {
 		return nil, errors.Wrapf(err, "failed to validate public parameters")
 	}
 	if err := common.SetupIssuersAndAuditors(pp, args.Auditors, args.Issuers); err != nil {
 		return nil, err
 	}
+	common.SetupRevokedEnrollmentIDs(pp, args.Revoked)
+	if err := setupAnonymousIssuers(pp, args.AnonymousIssuers, args.OutputDir); err != nil {
+		return nil, err
+	}
 
 	// Store Public Params
 	raw, err := pp.Serialize()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed serializing public parameters")
 	}
-	path := filepath.Join(args.OutputDir, "zkatdlog_pp.json")
+	path := filepath.Join(args.OutputDir, fileName)
 	if err := ioutil.WriteFile(path, raw, 0755); err != nil {
 		return nil, errors.Wrap(err, "failed writing public parameters to file")
 	}
 
 	return raw, nil
 }
+
+// setupAnonymousIssuers generates the passed number of anonymous issuer keys for the passed public parameters.
+// Each key is stored in the output folder, to be referenced by an anonymous issuer wallet,
+// and its identity is added to the issuers.
+func setupAnonymousIssuers(pp *crypto.PublicParams, n uint, outputDir string) error {
+	for i := uint(0); i < n; i++ {
+		key, err := anonym.NewSecretKey(pp)
+		if err != nil {
+			return errors.WithMessage(err, "failed generating anonymous issuer key")
+		}
+		id, err := key.Identity()
+		if err != nil {
+			return errors.WithMessage(err, "failed generating anonymous issuer key")
+		}
+		raw, err := key.Serialize()
+		if err != nil {
+			return errors.Wrap(err, "failed serializing anonymous issuer key")
+		}
+		path := filepath.Join(outputDir, fmt.Sprintf("anonymous_issuer_%d.json", i))
+		if err := ioutil.WriteFile(path, raw, 0600); err != nil {
+			return errors.Wrap(err, "failed writing anonymous issuer key to file")
+		}
+		pp.AddIssuer(id)
+	}
+	return nil
+}
+
+// setupAuditEncryption generates an Elgamal key for the auditor and enables it in the passed public parameters.
+// The secret key is stored in the output folder, to be used by the auditor to open the outputs from the ledger.
+func setupAuditEncryption(pp *crypto.PublicParams, outputDir string) error {
+	sk, err := disclosure.NewSecretKey(pp)
+	if err != nil {
+		return errors.WithMessage(err, "failed generating auditor encryption key")
+	}
+	if err := pp.EnableAuditEncryption(sk.H); err != nil {
+		return errors.Wrap(err, "failed setting up audit encryption")
+	}
+	path := filepath.Join(outputDir, "auditor_encryption_key")
+	if err := ioutil.WriteFile(path, sk.Bytes(), 0600); err != nil {
+		return errors.Wrap(err, "failed writing auditor encryption key to file")
+	}
+	return nil
+}
+
+// curves returns the identifiers of the curves with the passed names.
+// An empty name selects the default curve, an empty idemix curve name selects the same curve as the tokens.
+func curves(curve, idemixCurve string) (math3.CurveID, math3.CurveID, error) {
+	curveID := crypto.DefaultCurve
+	if len(curve) != 0 {
+		var err error
+		curveID, err = crypto.CurveIDByName(curve)
+		if err != nil {
+			return 0, 0, errors.WithMessage(err, "invalid curve")
+		}
+	}
+	if len(idemixCurve) == 0 {
+		return curveID, curveID, nil
+	}
+	idemixCurveID, err := crypto.CurveIDByName(idemixCurve)
+	if err != nil {
+		return 0, 0, errors.WithMessage(err, "invalid idemix curve")
+	}
+	return curveID, idemixCurveID, nil
+}
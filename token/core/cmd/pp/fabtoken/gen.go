@@ -29,6 +29,8 @@ var (
 	Issuers []string
 	// Auditors is the list of auditor MSP directories containing the corresponding auditor certificate
 	Auditors []string
+	// Revoked is the list of enrollment IDs that are not allowed to transact
+	Revoked []string
 )
 
 // Cmd returns the Cobra Command for Version
@@ -39,6 +41,7 @@ func Cmd() *cobra.Command {
 	flags.BoolVarP(&GenerateCCPackage, "cc", "", false, "generate chaincode package")
 	flags.StringSliceVarP(&Auditors, "auditors", "a", nil, "list of auditor MSP directories containing the corresponding auditor certificate")
 	flags.StringSliceVarP(&Issuers, "issuers", "s", nil, "list of issuer MSP directories containing the corresponding issuer certificate")
+	flags.StringSliceVarP(&Revoked, "revoked", "r", nil, "list of enrollment IDs that are not allowed to transact")
 	return cobraCommand
 }
 
@@ -57,6 +60,7 @@ var cobraCommand = &cobra.Command{
 			GenerateCCPackage: GenerateCCPackage,
 			Issuers:           Issuers,
 			Auditors:          Auditors,
+			Revoked:           Revoked,
 		})
 		if err != nil {
 			return errors.Wrap(err, "failed to generate public parameters")
@@ -81,6 +85,8 @@ type GeneratorArgs struct {
 	Issuers []string
 	// Auditors is the list of auditor MSP directories containing the corresponding auditor certificate
 	Auditors []string
+	// Revoked is the list of enrollment IDs that are not allowed to transact
+	Revoked []string
 }
 
 // Gen generates the public parameters for the FabToken driver
@@ -93,6 +99,7 @@ func Gen(args *GeneratorArgs) ([]byte, error) {
 	if err := common.SetupIssuersAndAuditors(pp, args.Auditors, args.Issuers); err != nil {
 		return nil, err
 	}
+	common.SetupRevokedEnrollmentIDs(pp, args.Revoked)
 	// Store Public Params
 	raw, err := pp.Serialize()
 	if err != nil {
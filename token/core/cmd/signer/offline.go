@@ -0,0 +1,177 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package signer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-smart-client/pkg/utils/proto"
+	x5092 "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/x509"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/x509"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/signer/offline"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/gh/driver"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh/driver"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var requestPath string
+var signaturePath string
+var mspDir string
+var ppPath string
+var assumeYes bool
+
+// OfflineSignCmd returns the Cobra Command for the offline signer
+func OfflineSignCmd() *cobra.Command {
+	// Set the flags on the offline sign command.
+	flags := offlineSignCommand.Flags()
+	flags.StringVarP(&requestPath, "request", "r", "", "path of the signature request exported by the initiator")
+	flags.StringVarP(&signaturePath, "output", "o", "", "path of the signature file to produce")
+	flags.StringVarP(&mspDir, "msp", "m", "", "MSP directory containing the certificate and the secret key of the signer")
+	flags.StringVarP(&ppPath, "pp", "p", "", "path of the public parameters of the token management service, as generated by tokengen")
+	flags.BoolVarP(&assumeYes, "yes", "y", false, "sign without asking for confirmation")
+
+	return offlineSignCommand
+}
+
+var offlineSignCommand = &cobra.Command{
+	Use:   "offline-sign",
+	Short: "Sign a token request offline.",
+	Long: `Verify a signature request exported by the initiator of a token transaction against the passed public parameters,
+show its inputs and outputs, and sign it with the secret key found in the passed MSP directory.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("trailing args detected")
+		}
+		if len(requestPath) == 0 || len(signaturePath) == 0 || len(mspDir) == 0 || len(ppPath) == 0 {
+			return fmt.Errorf("request, output, msp, and pp must be set")
+		}
+		// Parsing of the command line is done so silence cmd usage
+		cmd.SilenceUsage = true
+		return offlineSign(os.Stdin, os.Stdout)
+	},
+}
+
+// offlineSign verifies the signature request, asks for confirmation, unless assumed, and writes the signature.
+// The outputs of the request are opened with the public parameters read from the pinned file, not with those of the request.
+func offlineSign(in io.Reader, out io.Writer) error {
+	request, err := offline.ReadSignatureRequest(requestPath)
+	if err != nil {
+		return err
+	}
+	publicParameters, err := ioutil.ReadFile(ppPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed reading public parameters [%s]", ppPath)
+	}
+	if err := request.Verify(publicParameters); err != nil {
+		return errors.WithMessage(err, "invalid signature request")
+	}
+	sk, err := loadSignerKey(mspDir, request.Signer)
+	if err != nil {
+		return err
+	}
+
+	printSignatureRequest(out, request)
+	if !assumeYes {
+		fmt.Fprint(out, "Sign? [y/N] ")
+		answer, _ := bufio.NewReader(in).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return errors.New("signature refused")
+		}
+	}
+
+	signature, err := offline.Sign(request, publicParameters, x509.NewSignerFromKey(sk))
+	if err != nil {
+		return err
+	}
+	if err := x509.NewVerifier(&sk.PublicKey).Verify(request.MessageToSign(), signature.Signature); err != nil {
+		return errors.Wrap(err, "failed verifying the produced signature")
+	}
+	if err := offline.WriteSignature(signaturePath, signature); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Signature written to [%s]\n", signaturePath)
+	return nil
+}
+
+// loadSignerKey returns the secret key, in the keystore of the passed MSP directory,
+// of the passed identity, that must match the certificate of the MSP directory
+func loadSignerKey(dir string, identity []byte) (*ecdsa.PrivateKey, error) {
+	si := &msp.SerializedIdentity{}
+	if err := proto.Unmarshal(identity, si); err != nil {
+		return nil, errors.Wrap(err, "the signer is not an x509 identity")
+	}
+	certRaw, err := x5092.LoadLocalMSPSignerCert(dir)
+	if err != nil {
+		// Try with "msp"
+		dir = filepath.Join(dir, "msp")
+		certRaw, err = x5092.LoadLocalMSPSignerCert(dir)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to load signer certificate from [%s]", dir)
+		}
+	}
+	if !bytes.Equal(certRaw, si.IdBytes) {
+		return nil, errors.Errorf("the signature is requested to another identity, MSP [%s]", si.Mspid)
+	}
+	cert, err := x5092.PemDecodeCert(certRaw)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to decode signer certificate")
+	}
+	pk, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("expected *ecdsa.PublicKey, got [%T]", cert.PublicKey)
+	}
+
+	keystore := filepath.Join(dir, "keystore")
+	entries, err := ioutil.ReadDir(keystore)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading keystore in [%s]", dir)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		raw, err := ioutil.ReadFile(filepath.Join(keystore, entry.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed reading key [%s]", entry.Name())
+		}
+		key, err := x509.PemDecodeKey(raw)
+		if err != nil {
+			continue
+		}
+		if sk, ok := key.(*ecdsa.PrivateKey); ok && sk.PublicKey.Equal(pk) {
+			return sk, nil
+		}
+	}
+	return nil, errors.Errorf("no secret key matching the signer certificate in [%s]", keystore)
+}
+
+func printSignatureRequest(out io.Writer, r *offline.SignatureRequest) {
+	fmt.Fprintf(out, "Transaction [%s] on [%s:%s:%s]\n", r.TxID, r.Network, r.Channel, r.Namespace)
+	fmt.Fprintf(out, "Digest [%x]\n", r.Digest)
+	fmt.Fprintf(out, "Inputs:\n")
+	for _, t := range r.Inputs {
+		fmt.Fprintf(out, "  [%s] %s %s owned by [%s]\n", t.ID, t.Quantity, t.Type, t.Owner)
+	}
+	fmt.Fprintf(out, "Outputs:\n")
+	for _, t := range r.Outputs {
+		fmt.Fprintf(out, "  %s %s to [%s]\n", t.Quantity, t.Type, t.Owner)
+	}
+	for k, v := range r.Metadata {
+		fmt.Fprintf(out, "Metadata [%s]: [%x]\n", k, v)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package signer
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/x509"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/signer/remote"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var address string
+var keystores []string
+var token string
+var tlsCert string
+var tlsKey string
+
+// ServerCmd returns the Cobra Command for the reference remote signer
+func ServerCmd() *cobra.Command {
+	// Set the flags on the node start command.
+	flags := cobraCommand.Flags()
+	flags.StringVarP(&address, "address", "a", "127.0.0.1:9443", "listening address")
+	flags.StringSliceVarP(&keystores, "keystores", "k", nil, "list of MSP directories whose keystore folders contain the keys to serve")
+	flags.StringVarP(&token, "token", "t", "", "bearer token clients must present")
+	flags.StringVar(&tlsCert, "tls-cert", "", "path to the PEM-encoded TLS certificate")
+	flags.StringVar(&tlsKey, "tls-key", "", "path to the PEM-encoded TLS secret key")
+
+	return cobraCommand
+}
+
+var cobraCommand = &cobra.Command{
+	Use:   "remote-signer",
+	Short: "Start a reference remote signer.",
+	Long:  `Start a reference remote signer serving the keys found in the passed MSP keystores.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("trailing args detected")
+		}
+		// Parsing of the command line is done so silence cmd usage
+		cmd.SilenceUsage = true
+		return serve()
+	},
+}
+
+// serve loads the keys and serves them until an error occurs
+func serve() error {
+	server := remote.NewServer(token)
+	for _, dir := range keystores {
+		if err := loadKeys(server, dir); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Serving remote signer at [%s]...\n", address)
+	if len(tlsCert) != 0 {
+		return http.ListenAndServeTLS(address, tlsCert, tlsKey, server.Handler())
+	}
+	return http.ListenAndServe(address, server.Handler())
+}
+
+// loadKeys registers the ECDSA secret keys stored in the keystore folder of the passed MSP directory
+func loadKeys(server *remote.Server, dir string) error {
+	keystore := filepath.Join(dir, "keystore")
+	entries, err := ioutil.ReadDir(keystore)
+	if err != nil {
+		// Try with "msp"
+		keystore = filepath.Join(dir, "msp", "keystore")
+		entries, err = ioutil.ReadDir(keystore)
+		if err != nil {
+			return errors.Wrapf(err, "failed reading keystore in [%s]", dir)
+		}
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		raw, err := ioutil.ReadFile(filepath.Join(keystore, entry.Name()))
+		if err != nil {
+			return errors.Wrapf(err, "failed reading key [%s]", entry.Name())
+		}
+		key, err := x509.PemDecodeKey(raw)
+		if err != nil {
+			return errors.Wrapf(err, "failed decoding key [%s]", entry.Name())
+		}
+		sk, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			fmt.Printf("Skipping [%s], not an ECDSA secret key\n", entry.Name())
+			continue
+		}
+		keyID := server.AddKey(sk)
+		fmt.Printf("Serving key [%s] from [%s]\n", keyID, filepath.Join(keystore, entry.Name()))
+	}
+	return nil
+}
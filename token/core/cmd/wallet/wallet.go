@@ -0,0 +1,154 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package wallet
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/backup"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var walletID string
+var mspDir string
+var passphraseFile string
+var input string
+var archivePath string
+var outputDir string
+
+// Cmd returns the Cobra Command for the wallet archive commands
+func Cmd() *cobra.Command {
+	flags := exportCobraCommand.Flags()
+	flags.StringVarP(&walletID, "id", "i", "", "identifier of the wallet")
+	flags.StringVarP(&mspDir, "msp", "m", "", "MSP folder of the wallet")
+	flags.StringVarP(&passphraseFile, "passphrase-file", "p", "", "path of the file containing the passphrase protecting the archive")
+	flags.StringVarP(&archivePath, "output", "o", "./wallet.archive", "path of the archive to write")
+
+	flags = importCobraCommand.Flags()
+	flags.StringVarP(&input, "input", "i", "", "path of the archive to read")
+	flags.StringVarP(&passphraseFile, "passphrase-file", "p", "", "path of the file containing the passphrase protecting the archive")
+	flags.StringVarP(&outputDir, "output", "o", "", "folder where to extract the MSP folder of the wallet")
+
+	walletCobraCommand.AddCommand(exportCobraCommand)
+	walletCobraCommand.AddCommand(importCobraCommand)
+	return walletCobraCommand
+}
+
+var walletCobraCommand = &cobra.Command{
+	Use:   "wallet",
+	Short: "Wallet archive utils.",
+	Long:  `Export and import wallets to and from encrypted archives`,
+}
+
+var exportCobraCommand = &cobra.Command{
+	Use:   "export",
+	Short: "Export a wallet to an encrypted archive.",
+	Long:  `Export the MSP folder of a wallet to an encrypted archive`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("trailing args detected")
+		}
+		// Parsing of the command line is done so silence cmd usage
+		cmd.SilenceUsage = true
+		return exportWallet()
+	},
+}
+
+var importCobraCommand = &cobra.Command{
+	Use:   "import",
+	Short: "Import a wallet from an encrypted archive.",
+	Long:  `Import the MSP folder of a wallet from an encrypted archive`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("trailing args detected")
+		}
+		// Parsing of the command line is done so silence cmd usage
+		cmd.SilenceUsage = true
+		return importWallet()
+	},
+}
+
+func exportWallet() error {
+	if len(walletID) == 0 {
+		return errors.New("wallet identifier not specified")
+	}
+	if len(mspDir) == 0 {
+		return errors.New("msp folder not specified")
+	}
+	passphrase, err := readPassphrase()
+	if err != nil {
+		return err
+	}
+
+	archive := backup.NewArchive(walletID)
+	if err := archive.AddMSP(mspDir); err != nil {
+		return errors.WithMessagef(err, "failed adding msp folder [%s]", mspDir)
+	}
+	raw, err := archive.Seal(passphrase)
+	if err != nil {
+		return errors.WithMessage(err, "failed sealing archive")
+	}
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0700); err != nil {
+		return errors.Wrapf(err, "failed creating folder for [%s]", archivePath)
+	}
+	if err := ioutil.WriteFile(archivePath, raw, 0600); err != nil {
+		return errors.Wrapf(err, "failed writing archive to [%s]", archivePath)
+	}
+	fmt.Printf("Wallet [%s] exported to [%s]\n", walletID, archivePath)
+	return nil
+}
+
+func importWallet() error {
+	if len(input) == 0 {
+		return errors.New("archive not specified")
+	}
+	if len(outputDir) == 0 {
+		return errors.New("output folder not specified")
+	}
+	passphrase, err := readPassphrase()
+	if err != nil {
+		return err
+	}
+
+	raw, err := ioutil.ReadFile(input)
+	if err != nil {
+		return errors.Wrapf(err, "failed reading archive [%s]", input)
+	}
+	archive, err := backup.OpenArchive(raw, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := archive.ExtractMSP(outputDir); err != nil {
+		return errors.WithMessagef(err, "failed extracting msp folder to [%s]", outputDir)
+	}
+	fmt.Printf("Wallet [%s] imported to [%s]\n", archive.WalletID, outputDir)
+	if len(archive.Identities) != 0 || len(archive.Transactions) != 0 {
+		fmt.Printf("The archive also carries [%d] identities and [%d] transaction records of TMS [%s], "+
+			"import the archive in the node with the backup.ImportView to restore them\n", len(archive.Identities), len(archive.Transactions), archive.TMSID)
+	}
+	return nil
+}
+
+func readPassphrase() ([]byte, error) {
+	if len(passphraseFile) == 0 {
+		return nil, errors.New("passphrase file not specified")
+	}
+	raw, err := ioutil.ReadFile(passphraseFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading passphrase file [%s]", passphraseFile)
+	}
+	passphrase := bytes.TrimRight(raw, "\r\n")
+	if len(passphrase) == 0 {
+		return nil, errors.Errorf("empty passphrase in [%s]", passphraseFile)
+	}
+	return passphrase, nil
+}
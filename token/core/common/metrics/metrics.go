@@ -0,0 +1,182 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+)
+
+const (
+	// Success is the outcome label of the operations that succeeded
+	Success = "success"
+	// Failure is the outcome label of the operations that failed
+	Failure = "failure"
+
+	// ReasonMalformed is the reason of the validation failures due to a token request that cannot be parsed
+	ReasonMalformed = "malformed"
+	// ReasonAuditorSignature is the reason of the validation failures due to a missing or invalid auditor signature
+	ReasonAuditorSignature = "auditor_signature"
+	// ReasonIssue is the reason of the validation failures due to an invalid issue action
+	ReasonIssue = "issue"
+	// ReasonTransfer is the reason of the validation failures due to an invalid transfer action
+	ReasonTransfer = "transfer"
+	// ReasonProof is the reason of the validation failures due to an invalid zero-knowledge proof
+	ReasonProof = "proof"
+)
+
+var (
+	selectionDurationOpts = metrics.HistogramOpts{
+		Namespace:    "token",
+		Subsystem:    "selector",
+		Name:         "selection_duration",
+		Help:         "The time, in seconds, taken to select the tokens to spend.",
+		LabelNames:   []string{"outcome"},
+		StatsdFormat: "%{#fqname}.%{outcome}",
+	}
+	selectionRetriesOpts = metrics.CounterOpts{
+		Namespace:    "token",
+		Subsystem:    "selector",
+		Name:         "selection_retries",
+		Help:         "The number of times a token selection has been retried.",
+		StatsdFormat: "%{#fqname}",
+	}
+	lockContentionOpts = metrics.CounterOpts{
+		Namespace:    "token",
+		Subsystem:    "selector",
+		Name:         "lock_contention",
+		Help:         "The number of tokens that could not be locked because another transaction held them.",
+		StatsdFormat: "%{#fqname}",
+	}
+	proofGenerationDurationOpts = metrics.HistogramOpts{
+		Namespace:    "token",
+		Subsystem:    "driver",
+		Name:         "proof_generation_duration",
+		Help:         "The time, in seconds, taken to generate the proofs of an action.",
+		LabelNames:   []string{"driver", "action"},
+		StatsdFormat: "%{#fqname}.%{driver}.%{action}",
+	}
+	validationDurationOpts = metrics.HistogramOpts{
+		Namespace:    "token",
+		Subsystem:    "driver",
+		Name:         "validation_duration",
+		Help:         "The time, in seconds, taken to validate a token request, including the verification of its proofs.",
+		LabelNames:   []string{"driver"},
+		StatsdFormat: "%{#fqname}.%{driver}",
+	}
+	validationFailuresOpts = metrics.CounterOpts{
+		Namespace:    "token",
+		Subsystem:    "driver",
+		Name:         "validation_failures",
+		Help:         "The number of token requests found invalid.",
+		LabelNames:   []string{"driver", "reason"},
+		StatsdFormat: "%{#fqname}.%{driver}.%{reason}",
+	}
+	endorsementDurationOpts = metrics.HistogramOpts{
+		Namespace:    "token",
+		Subsystem:    "ttx",
+		Name:         "endorsement_duration",
+		Help:         "The time, in seconds, taken to collect the endorsements of a token transaction.",
+		LabelNames:   []string{"outcome"},
+		StatsdFormat: "%{#fqname}.%{outcome}",
+	}
+	pendingTransactionsOpts = metrics.GaugeOpts{
+		Namespace:    "token",
+		Subsystem:    "ttx",
+		Name:         "pending_transactions",
+		Help:         "The number of token transactions assembled by this node and not final yet.",
+		StatsdFormat: "%{#fqname}",
+	}
+	dbWriteDurationOpts = metrics.HistogramOpts{
+		Namespace:    "token",
+		Subsystem:    "ttxdb",
+		Name:         "write_duration",
+		Help:         "The time, in seconds, taken to write to the token transaction database.",
+		LabelNames:   []string{"operation"},
+		StatsdFormat: "%{#fqname}.%{operation}",
+	}
+	chaincodeRequestDurationOpts = metrics.HistogramOpts{
+		Namespace:    "token",
+		Subsystem:    "tcc",
+		Name:         "request_duration",
+		Help:         "The time, in seconds, taken by the token chaincode to process an invocation.",
+		LabelNames:   []string{"function", "outcome"},
+		StatsdFormat: "%{#fqname}.%{function}.%{outcome}",
+	}
+)
+
+// Metrics are the metrics of the token operations
+type Metrics struct {
+	SelectionDuration        metrics.Histogram
+	SelectionRetries         metrics.Counter
+	LockContention           metrics.Counter
+	ProofGenerationDuration  metrics.Histogram
+	ValidationDuration       metrics.Histogram
+	ValidationFailures       metrics.Counter
+	EndorsementDuration      metrics.Histogram
+	PendingTransactions      metrics.Gauge
+	DBWriteDuration          metrics.Histogram
+	ChaincodeRequestDuration metrics.Histogram
+}
+
+// New creates the token metrics with the passed provider
+func New(p metrics.Provider) *Metrics {
+	return &Metrics{
+		SelectionDuration:        p.NewHistogram(selectionDurationOpts),
+		SelectionRetries:         p.NewCounter(selectionRetriesOpts),
+		LockContention:           p.NewCounter(lockContentionOpts),
+		ProofGenerationDuration:  p.NewHistogram(proofGenerationDurationOpts),
+		ValidationDuration:       p.NewHistogram(validationDurationOpts),
+		ValidationFailures:       p.NewCounter(validationFailuresOpts),
+		EndorsementDuration:      p.NewHistogram(endorsementDurationOpts),
+		PendingTransactions:      p.NewGauge(pendingTransactionsOpts),
+		DBWriteDuration:          p.NewHistogram(dbWriteDurationOpts),
+		ChaincodeRequestDuration: p.NewHistogram(chaincodeRequestDurationOpts),
+	}
+}
+
+var (
+	lock      sync.RWMutex
+	installed bool
+	current   = New(&disabled.Provider{})
+)
+
+// Install creates the token metrics with the passed provider, for instance the Prometheus or the StatsD one,
+// and makes them the metrics returned by Get.
+// Only the first installation takes effect, because some providers register their metrics globally.
+func Install(p metrics.Provider) *Metrics {
+	lock.Lock()
+	defer lock.Unlock()
+	if !installed {
+		current = New(p)
+		installed = true
+	}
+	return current
+}
+
+// Get returns the token metrics. They are disabled until Install is called.
+func Get() *Metrics {
+	lock.RLock()
+	defer lock.RUnlock()
+	return current
+}
+
+// Since returns the seconds elapsed since the passed time
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+// Outcome returns the outcome label of an operation that returned the passed error
+func Outcome(err error) string {
+	if err != nil {
+		return Failure
+	}
+	return Success
+}
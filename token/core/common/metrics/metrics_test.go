@@ -0,0 +1,74 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	provider := &metricsfakes.Provider{}
+	provider.NewCounterReturns(&metricsfakes.Counter{})
+	provider.NewGaugeReturns(&metricsfakes.Gauge{})
+	provider.NewHistogramReturns(&metricsfakes.Histogram{})
+
+	m := New(provider)
+	assert.Equal(t, 3, provider.NewCounterCallCount())
+	assert.Equal(t, 1, provider.NewGaugeCallCount())
+	assert.Equal(t, 6, provider.NewHistogramCallCount())
+	assert.Equal(t, selectionDurationOpts, provider.NewHistogramArgsForCall(0))
+	assert.Equal(t, validationFailuresOpts, provider.NewCounterArgsForCall(2))
+	assert.Equal(t, pendingTransactionsOpts, provider.NewGaugeArgsForCall(0))
+	assert.NotNil(t, m.ChaincodeRequestDuration)
+}
+
+func TestInstall(t *testing.T) {
+	reset()
+	defer reset()
+	counter := &metricsfakes.Counter{}
+	counter.WithReturns(counter)
+	provider := &metricsfakes.Provider{}
+	provider.NewCounterReturns(counter)
+	provider.NewGaugeReturns(&metricsfakes.Gauge{})
+	provider.NewHistogramReturns(&metricsfakes.Histogram{})
+
+	// the metrics are disabled until installed
+	disabled := Get()
+	disabled.ValidationFailures.With("driver", "fabtoken", "reason", ReasonIssue).Add(1)
+
+	m := Install(provider)
+	assert.Equal(t, m, Get())
+	assert.NotEqual(t, disabled, m)
+
+	Get().ValidationFailures.With("driver", "fabtoken", "reason", ReasonIssue).Add(1)
+	assert.Equal(t, 1, counter.WithCallCount())
+	assert.Equal(t, []string{"driver", "fabtoken", "reason", ReasonIssue}, counter.WithArgsForCall(0))
+	assert.Equal(t, 1, counter.AddCallCount())
+	assert.Equal(t, float64(1), counter.AddArgsForCall(0))
+
+	// only the first installation takes effect
+	other := &metricsfakes.Provider{}
+	assert.Equal(t, m, Install(other))
+	assert.Equal(t, 0, other.NewCounterCallCount())
+}
+
+func TestOutcome(t *testing.T) {
+	assert.Equal(t, Success, Outcome(nil))
+	assert.Equal(t, Failure, Outcome(errors.New("boom")))
+}
+
+func reset() {
+	lock.Lock()
+	defer lock.Unlock()
+	installed = false
+	current = New(&disabled.Provider{})
+}
@@ -0,0 +1,163 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultEndpoint is the endpoint of an OpenTelemetry collector running locally with the OTLP/HTTP receiver
+	DefaultEndpoint = "http://localhost:4318"
+
+	defaultExportTimeout = 10 * time.Second
+	tracesPath           = "/v1/traces"
+
+	spanKindInternal = 1
+	statusCodeOk     = 1
+	statusCodeError  = 2
+)
+
+// OTLPExporter exports the spans to an OpenTelemetry collector using OTLP over HTTP, with the JSON encoding
+type OTLPExporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+}
+
+// NewOTLPExporter returns an exporter to the collector at the passed endpoint, for example `http://localhost:4318`.
+// The spans are reported as produced by the passed service, usually the identifier of the node.
+func NewOTLPExporter(endpoint, serviceName string, timeout time.Duration) *OTLPExporter {
+	if len(endpoint) == 0 {
+		endpoint = DefaultEndpoint
+	}
+	if timeout <= 0 {
+		timeout = defaultExportTimeout
+	}
+	return &OTLPExporter{
+		url:         strings.TrimSuffix(endpoint, "/") + tracesPath,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: timeout},
+	}
+}
+
+// ExportSpans posts the passed spans to the collector
+func (e *OTLPExporter) ExportSpans(spans []*SpanData) error {
+	payload, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return errors.Wrap(err, "failed marshalling spans")
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return errors.Wrapf(err, "failed posting spans to [%s]", e.url)
+	}
+	defer resp.Body.Close()
+	// drain the body to reuse the connection
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("unexpected status [%s] from [%s]", resp.Status, e.url)
+	}
+	return nil
+}
+
+func (e *OTLPExporter) request(spans []*SpanData) *otlpRequest {
+	otlpSpans := make([]*otlpSpan, len(spans))
+	for i, s := range spans {
+		span := &otlpSpan{
+			TraceID:           hex.EncodeToString(s.Context.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.Context.SpanID[:]),
+			Name:              s.Name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        attributes(s.Attributes),
+			Status:            otlpStatus{Code: statusCodeOk},
+		}
+		if s.Parent != (SpanID{}) {
+			span.ParentSpanID = hex.EncodeToString(s.Parent[:])
+		}
+		if len(s.Error) != 0 {
+			span.Status = otlpStatus{Code: statusCodeError, Message: s.Error}
+		}
+		otlpSpans[i] = span
+	}
+	return &otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: attributes([]Attribute{{Key: "service.name", Value: e.serviceName}})},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "github.com/hyperledger-labs/fabric-token-sdk"},
+			Spans: otlpSpans,
+		}},
+	}}}
+}
+
+func attributes(attrs []Attribute) []otlpAttribute {
+	res := make([]otlpAttribute, len(attrs))
+	for i, a := range attrs {
+		res[i] = otlpAttribute{Key: a.Key, Value: otlpValue{StringValue: a.Value}}
+	}
+	return res
+}
+
+// The following types model the JSON encoding of the OTLP ExportTraceServiceRequest
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope   `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,76 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tracing
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func hexOf(b []byte) string {
+	return hex.EncodeToString(b)
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var path string
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		raw, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.NoError(t, json.Unmarshal(raw, &request))
+	}))
+	defer server.Close()
+
+	start := time.Unix(1, 500)
+	span := &SpanData{
+		Name:       "ttx.audit",
+		Context:    SpanContext{TraceID: TraceIDFromTxID("tx1"), SpanID: SpanID{1}},
+		Parent:     SpanID{2},
+		Start:      start,
+		End:        start.Add(time.Second),
+		Attributes: []Attribute{{Key: "tx.id", Value: "tx1"}},
+		Error:      "boom",
+	}
+	exporter := NewOTLPExporter(server.URL+"/", "alice", time.Second)
+	assert.NoError(t, exporter.ExportSpans([]*SpanData{span}))
+	assert.Equal(t, "/v1/traces", path)
+
+	resourceSpans := request["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	resource := resourceSpans["resource"].(map[string]interface{})
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"key":   "service.name",
+		"value": map[string]interface{}{"stringValue": "alice"},
+	}}, resource["attributes"])
+	s := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "ttx.audit", s["name"])
+	assert.Equal(t, hexOf(span.Context.TraceID[:]), s["traceId"])
+	assert.Equal(t, "0100000000000000", s["spanId"])
+	assert.Equal(t, "0200000000000000", s["parentSpanId"])
+	assert.Equal(t, "1000000500", s["startTimeUnixNano"])
+	assert.Equal(t, "2000000500", s["endTimeUnixNano"])
+	assert.Equal(t, map[string]interface{}{"code": float64(statusCodeError), "message": "boom"}, s["status"])
+}
+
+func TestOTLPExporterFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter(server.URL, "alice", time.Second)
+	err := exporter.ExportSpans([]*SpanData{{Name: "span"}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+}
@@ -0,0 +1,347 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tracing
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.tracing")
+
+const (
+	defaultBatchSize     = 512
+	defaultQueueSize     = 4096
+	defaultFlushInterval = 5 * time.Second
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// TraceIDFromTxID returns the trace identifier of the passed transaction.
+// All the nodes derive the same identifier, so their spans for the same transaction belong to the same trace
+// even if no span context reached them.
+func TraceIDFromTxID(txID string) TraceID {
+	var id TraceID
+	h := sha256.Sum256([]byte(txID))
+	copy(id[:], h[:len(id)])
+	return id
+}
+
+// SpanContext is the part of a span that crosses the process boundaries
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// IsValid returns true if both the trace and the span identifiers are set
+func (c SpanContext) IsValid() bool {
+	return c.TraceID != TraceID{} && c.SpanID != SpanID{}
+}
+
+// Traceparent returns the span context in the format of the W3C traceparent header
+func (c SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%x-%x-01", c.TraceID[:], c.SpanID[:])
+}
+
+// ParseTraceparent parses a span context in the format of the W3C traceparent header
+func ParseTraceparent(s string) (SpanContext, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return SpanContext{}, errors.Errorf("invalid traceparent [%s]", s)
+	}
+	var c SpanContext
+	if n, err := hex.Decode(c.TraceID[:], []byte(parts[1])); err != nil || n != len(c.TraceID) || len(parts[1]) != 2*len(c.TraceID) {
+		return SpanContext{}, errors.Errorf("invalid trace id in traceparent [%s]", s)
+	}
+	if n, err := hex.Decode(c.SpanID[:], []byte(parts[2])); err != nil || n != len(c.SpanID) || len(parts[2]) != 2*len(c.SpanID) {
+		return SpanContext{}, errors.Errorf("invalid span id in traceparent [%s]", s)
+	}
+	if !c.IsValid() {
+		return SpanContext{}, errors.Errorf("invalid traceparent [%s]", s)
+	}
+	return c, nil
+}
+
+// Attribute is a key-value pair attached to a span
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// SpanData is a finished span, as handed to the exporter
+type SpanData struct {
+	Name       string
+	Context    SpanContext
+	Parent     SpanID
+	Start      time.Time
+	End        time.Time
+	Attributes []Attribute
+	// Error is the message of the error the span ended with, if any
+	Error string
+}
+
+// Exporter sends the finished spans to a tracing backend
+type Exporter interface {
+	ExportSpans(spans []*SpanData) error
+}
+
+// Span is an operation within a trace
+type Span struct {
+	tracer *Tracer
+	txID   string
+	data   SpanData
+	once   sync.Once
+}
+
+// Context returns the span context, to be propagated to the other nodes
+func (s *Span) Context() SpanContext {
+	return s.data.Context
+}
+
+// SetAttributes attaches the passed attributes to the span
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	s.tracer.lock.Lock()
+	defer s.tracer.lock.Unlock()
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+// End ends the span with the passed error, if any. Only the first call has effect.
+func (s *Span) End(err error) {
+	s.once.Do(func() {
+		s.data.End = time.Now()
+		if err != nil {
+			s.data.Error = err.Error()
+		}
+		s.tracer.end(s)
+	})
+}
+
+// Tracer creates the spans and hands them, in batches, to an exporter
+type Tracer struct {
+	exporter      Exporter
+	batchSize     int
+	flushInterval time.Duration
+
+	lock    sync.Mutex
+	active  map[string][]SpanContext
+	queue   []*SpanData
+	dropped int
+	flush   chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewTracer returns a tracer exporting the spans with the passed exporter.
+// If the exporter is nil, the spans are still created, so that their context is propagated, but they are not exported.
+func NewTracer(exporter Exporter) *Tracer {
+	t := &Tracer{
+		exporter:      exporter,
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+		active:        map[string][]SpanContext{},
+		flush:         make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	if exporter != nil {
+		go t.run()
+	} else {
+		close(t.done)
+	}
+	return t
+}
+
+// StartTxSpan starts a span of the passed transaction.
+// The parent of the span is the innermost span of the same transaction not yet ended on this node, if any,
+// otherwise the passed remote span context, if valid.
+// The span becomes the innermost span of the transaction until it ends.
+func (t *Tracer) StartTxSpan(name, txID string, remote SpanContext, attrs ...Attribute) *Span {
+	s := &Span{
+		tracer: t,
+		txID:   txID,
+		data: SpanData{
+			Name:       name,
+			Start:      time.Now(),
+			Attributes: append([]Attribute{{Key: "tx.id", Value: txID}}, attrs...),
+		},
+	}
+	s.data.Context.TraceID = TraceIDFromTxID(txID)
+	s.data.Context.SpanID = newSpanID()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if stack := t.active[txID]; len(stack) != 0 {
+		s.data.Parent = stack[len(stack)-1].SpanID
+	} else if remote.IsValid() {
+		s.data.Context.TraceID = remote.TraceID
+		s.data.Parent = remote.SpanID
+	}
+	t.active[txID] = append(t.active[txID], s.data.Context)
+	return s
+}
+
+// Current returns the context of the innermost span of the passed transaction not yet ended on this node, if any
+func (t *Tracer) Current(txID string) (SpanContext, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	stack := t.active[txID]
+	if len(stack) == 0 {
+		return SpanContext{}, false
+	}
+	return stack[len(stack)-1], true
+}
+
+// Shutdown exports the spans still queued and stops the tracer
+func (t *Tracer) Shutdown() {
+	t.lock.Lock()
+	select {
+	case <-t.stop:
+	default:
+		close(t.stop)
+	}
+	t.lock.Unlock()
+	<-t.done
+}
+
+func (t *Tracer) end(s *Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	stack := t.active[s.txID]
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == s.data.Context {
+			stack = append(stack[:i], stack[i+1:]...)
+			break
+		}
+	}
+	if len(stack) == 0 {
+		delete(t.active, s.txID)
+	} else {
+		t.active[s.txID] = stack
+	}
+
+	if t.exporter == nil {
+		return
+	}
+	if len(t.queue) >= defaultQueueSize {
+		t.dropped++
+		return
+	}
+	data := s.data
+	t.queue = append(t.queue, &data)
+	if len(t.queue) >= t.batchSize {
+		select {
+		case t.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.flush:
+		case <-t.stop:
+			t.export()
+			return
+		}
+		t.export()
+	}
+}
+
+func (t *Tracer) export() {
+	t.lock.Lock()
+	spans := t.queue
+	dropped := t.dropped
+	t.queue = nil
+	t.dropped = 0
+	t.lock.Unlock()
+
+	if dropped != 0 {
+		logger.Warnf("dropped [%d] spans, the exporter does not keep up", dropped)
+	}
+	for len(spans) != 0 {
+		n := t.batchSize
+		if n > len(spans) {
+			n = len(spans)
+		}
+		if err := t.exporter.ExportSpans(spans[:n]); err != nil {
+			logger.Warnf("failed exporting [%d] spans: [%s]", n, err)
+		}
+		spans = spans[n:]
+	}
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(err)
+	}
+	return id
+}
+
+var (
+	lock    sync.RWMutex
+	current = NewTracer(nil)
+)
+
+// Install makes the passed tracer the one returned by Get. The previous tracer is shut down.
+func Install(t *Tracer) {
+	lock.Lock()
+	previous := current
+	current = t
+	lock.Unlock()
+	previous.Shutdown()
+}
+
+// Get returns the tracer. Until a tracer is installed, the spans are not exported.
+func Get() *Tracer {
+	lock.RLock()
+	defer lock.RUnlock()
+	return current
+}
+
+// TraceparentKey is the key of the span context in the carriers of the token transactions, such as their transient map
+const TraceparentKey = "tracing.traceparent"
+
+// Inject stores the passed span context in the passed carrier
+func Inject(carrier map[string][]byte, c SpanContext) {
+	if carrier == nil || !c.IsValid() {
+		return
+	}
+	carrier[TraceparentKey] = []byte(c.Traceparent())
+}
+
+// Extract returns the span context stored in the passed carrier, if any
+func Extract(carrier map[string][]byte) SpanContext {
+	raw, ok := carrier[TraceparentKey]
+	if !ok {
+		return SpanContext{}
+	}
+	c, err := ParseTraceparent(string(raw))
+	if err != nil {
+		logger.Debugf("ignoring span context: [%s]", err)
+		return SpanContext{}
+	}
+	return c
+}
@@ -0,0 +1,145 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tracing
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeExporter struct {
+	lock  sync.Mutex
+	spans []*SpanData
+}
+
+func (f *fakeExporter) ExportSpans(spans []*SpanData) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.spans = append(f.spans, spans...)
+	return nil
+}
+
+func TestTraceparent(t *testing.T) {
+	c := SpanContext{TraceID: TraceIDFromTxID("tx1"), SpanID: SpanID{1, 2, 3, 4, 5, 6, 7, 8}}
+	s := c.Traceparent()
+	assert.Equal(t, "00-"+hexOf(c.TraceID[:])+"-0102030405060708-01", s)
+
+	parsed, err := ParseTraceparent(s)
+	assert.NoError(t, err)
+	assert.Equal(t, c, parsed)
+
+	for _, invalid := range []string{
+		"",
+		"00-abc-0102030405060708-01",
+		"01-" + hexOf(c.TraceID[:]) + "-0102030405060708-01",
+		"00-" + hexOf(c.TraceID[:]) + "-01020304050607-01",
+		"00-" + hexOf(c.TraceID[:]) + "-010203040506070z-01",
+		"00-00000000000000000000000000000000-0102030405060708-01",
+		"00-" + hexOf(c.TraceID[:]) + "-0000000000000000-01",
+	} {
+		_, err := ParseTraceparent(invalid)
+		assert.Error(t, err, "expected [%s] to be invalid", invalid)
+	}
+}
+
+func TestStartTxSpan(t *testing.T) {
+	exporter := &fakeExporter{}
+	tracer := NewTracer(exporter)
+
+	_, ok := tracer.Current("tx1")
+	assert.False(t, ok)
+
+	// without spans in progress, the trace is the one of the transaction and there is no parent
+	root := tracer.StartTxSpan("root", "tx1", SpanContext{})
+	assert.Equal(t, TraceIDFromTxID("tx1"), root.Context().TraceID)
+	current, ok := tracer.Current("tx1")
+	assert.True(t, ok)
+	assert.Equal(t, root.Context(), current)
+
+	// the spans in progress are the parents of the new ones, even if a remote context is passed
+	child := tracer.StartTxSpan("child", "tx1", SpanContext{TraceID: TraceID{1}, SpanID: SpanID{1}}, Attribute{Key: "k", Value: "v"})
+	assert.Equal(t, root.Context().TraceID, child.Context().TraceID)
+	current, _ = tracer.Current("tx1")
+	assert.Equal(t, child.Context(), current)
+
+	// spans of other transactions are unrelated
+	other := tracer.StartTxSpan("other", "tx2", SpanContext{})
+	assert.Equal(t, TraceIDFromTxID("tx2"), other.Context().TraceID)
+
+	child.End(errors.New("boom"))
+	child.End(nil)
+	current, _ = tracer.Current("tx1")
+	assert.Equal(t, root.Context(), current)
+	root.End(nil)
+	other.End(nil)
+	_, ok = tracer.Current("tx1")
+	assert.False(t, ok)
+
+	tracer.Shutdown()
+	assert.Len(t, exporter.spans, 3)
+	byName := map[string]*SpanData{}
+	for _, s := range exporter.spans {
+		byName[s.Name] = s
+	}
+	assert.Equal(t, SpanID{}, byName["root"].Parent)
+	assert.Equal(t, "", byName["root"].Error)
+	assert.Equal(t, root.Context().SpanID, byName["child"].Parent)
+	assert.Equal(t, "boom", byName["child"].Error)
+	assert.Equal(t, []Attribute{{Key: "tx.id", Value: "tx1"}, {Key: "k", Value: "v"}}, byName["child"].Attributes)
+	assert.False(t, byName["child"].End.Before(byName["child"].Start))
+}
+
+func TestRemoteParent(t *testing.T) {
+	tracer := NewTracer(nil)
+	defer tracer.Shutdown()
+
+	remote := SpanContext{TraceID: TraceID{1, 2}, SpanID: SpanID{3, 4}}
+	s := tracer.StartTxSpan("remote", "tx1", remote)
+	assert.Equal(t, remote.TraceID, s.Context().TraceID)
+	assert.Equal(t, remote.SpanID, s.data.Parent)
+	assert.NotEqual(t, remote.SpanID, s.Context().SpanID)
+	s.End(nil)
+}
+
+func TestInjectExtract(t *testing.T) {
+	c := SpanContext{TraceID: TraceIDFromTxID("tx1"), SpanID: SpanID{1}}
+	carrier := map[string][]byte{}
+	Inject(carrier, c)
+	assert.Equal(t, c, Extract(carrier))
+
+	// invalid contexts are neither injected nor extracted
+	carrier = map[string][]byte{}
+	Inject(carrier, SpanContext{})
+	assert.Empty(t, carrier)
+	carrier[TraceparentKey] = []byte("invalid")
+	assert.Equal(t, SpanContext{}, Extract(carrier))
+	assert.Equal(t, SpanContext{}, Extract(nil))
+	Inject(nil, c)
+}
+
+func TestInstall(t *testing.T) {
+	previous := Get()
+	exporter := &fakeExporter{}
+	tracer := NewTracer(exporter)
+	Install(tracer)
+	defer Install(NewTracer(nil))
+	assert.Equal(t, tracer, Get())
+
+	// the previous tracer has been shut down
+	select {
+	case <-previous.done:
+	default:
+		assert.Fail(t, "previous tracer still running")
+	}
+
+	Get().StartTxSpan("span", "tx1", SpanContext{}).End(nil)
+	Install(NewTracer(nil))
+	assert.Len(t, exporter.spans, 1)
+}
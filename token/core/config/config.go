@@ -112,6 +112,18 @@ func (m *TokenSDK) GetTMSs() ([]*TMS, error) {
 	return tms, nil
 }
 
+// Tracing returns the tracing configuration, nil if not set
+func (m *TokenSDK) Tracing() (*config.Tracing, error) {
+	if !m.cp.IsSet("token.tracing") {
+		return nil, nil
+	}
+	var tracing *config.Tracing
+	if err := m.cp.UnmarshalKey("token.tracing", &tracing); err != nil {
+		return nil, errors.WithMessagef(err, "cannot load token-sdk tracing configuration")
+	}
+	return tracing, nil
+}
+
 func (m *TokenSDK) tmss() (map[string]*config.TMS, error) {
 	var boxedConfig map[interface{}]interface{}
 	if err := m.cp.UnmarshalKey("token.tms", &boxedConfig); err != nil {
@@ -8,6 +8,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/core/config"
 	"github.com/stretchr/testify/assert"
@@ -33,4 +34,25 @@ func TestGetTMSs(t *testing.T) {
 
 		assert.Len(t, tms2.TMS().Wallets.Owners, 2)
 	}
+
+	tms, err := tokenSDKConfig.GetTMS("n1", "c1", "ns1")
+	assert.NoError(t, err)
+	sinks := tms.TMS().Sinks
+	assert.NotNil(t, sinks)
+	assert.Len(t, sinks.Webhooks, 1)
+	assert.Equal(t, "https://backoffice.example.com/token-events", sinks.Webhooks[0].URL)
+	assert.Equal(t, "alice", sinks.Webhooks[0].Wallet)
+	assert.Equal(t, 5*time.Second, sinks.Webhooks[0].Timeout)
+	assert.Equal(t, 10, sinks.Webhooks[0].Retry.MaxAttempts)
+	assert.Equal(t, time.Minute, sinks.Webhooks[0].Retry.MaxBackoff)
+	assert.Len(t, sinks.Publishers, 1)
+	assert.Equal(t, "kafka", sinks.Publishers[0].Type)
+	assert.NotNil(t, sinks.Publishers[0].Opts)
+
+	tracing, err := tokenSDKConfig.Tracing()
+	assert.NoError(t, err)
+	assert.True(t, tracing.Enabled)
+	assert.Equal(t, "http://otel-collector:4318", tracing.Endpoint)
+	assert.Equal(t, 2*time.Second, tracing.Timeout)
+	assert.Empty(t, tracing.ServiceName)
 }
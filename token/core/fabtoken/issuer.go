@@ -16,7 +16,7 @@ import (
 // Issue returns an IssueAction as a function of the passed arguments
 // Issue also returns a serialization OutputMetadata associated with issued tokens
 // and the identity of the issuer
-func (s *Service) Issue(issuerIdentity view.Identity, typ string, values []uint64, owners [][]byte, opts *driver.IssueOptions) (driver.IssueAction, [][]byte, view.Identity, error) {
+func (s *Service) Issue(issuerIdentity view.Identity, typ string, values []token2.Quantity, owners [][]byte, opts *driver.IssueOptions) (driver.IssueAction, [][]byte, view.Identity, error) {
 	for _, owner := range owners {
 		// a recipient cannot be empty
 		if len(owner) == 0 {
@@ -28,9 +28,9 @@ func (s *Service) Issue(issuerIdentity view.Identity, typ string, values []uint6
 	var metas [][]byte
 	precision := s.PublicParamsManager().PublicParameters().Precision()
 	for i, v := range values {
-		q, err := token2.UInt64ToQuantity(v, precision)
+		q, err := token2.ToQuantity(v.Decimal(), precision)
 		if err != nil {
-			return nil, nil, nil, errors.Wrapf(err, "failed to convert [%d] to quantity of precision [%d]", v, precision)
+			return nil, nil, nil, errors.Wrapf(err, "failed to convert [%s] to quantity of precision [%d]", v.Decimal(), precision)
 		}
 		outs = append(outs, &Output{
 			Output: &token2.Token{
@@ -34,6 +34,8 @@ type PublicParams struct {
 	Issuers [][]byte
 	// MaxToken is the maximum quantity a token can hold
 	MaxToken uint64
+	// Revoked is the list of enrollment IDs that are not allowed to transact
+	Revoked []string `json:",omitempty"`
 }
 
 // NewPublicParamsFromBytes deserializes the raw bytes into public parameters
@@ -126,6 +128,16 @@ func (pp *PublicParams) Auditors() []view.Identity {
 	return []view.Identity{pp.Auditor}
 }
 
+// AddRevokedEnrollmentID adds the passed enrollment ID to the list of revoked enrollment IDs
+func (pp *PublicParams) AddRevokedEnrollmentID(eid string) {
+	pp.Revoked = append(pp.Revoked, eid)
+}
+
+// RevokedEnrollmentIDs returns the list of revoked enrollment IDs
+func (pp *PublicParams) RevokedEnrollmentIDs() []string {
+	return pp.Revoked
+}
+
 // Precision returns the quantity precision encoded in PublicParams
 func (pp *PublicParams) Precision() uint64 {
 	return pp.QuantityPrecision
@@ -9,9 +9,12 @@ package fabtoken
 import (
 	"bytes"
 	"encoding/json"
+	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/metrics"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/tracing"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
@@ -53,6 +56,14 @@ func NewValidator(pp *PublicParams, deserializer driver.Deserializer, extraValid
 
 // VerifyTokenRequest validates the passed token request against data in the ledger, the signature provided and the binding
 func (v *Validator) VerifyTokenRequest(ledger driver.Ledger, signatureProvider driver.SignatureProvider, binding string, tr *driver.TokenRequest) ([]interface{}, error) {
+	defer observeValidation(time.Now())
+	span := tracing.Get().StartTxSpan("fabtoken.verify", binding, tracing.SpanContext{})
+	actions, err := v.verifyTokenRequest(ledger, signatureProvider, binding, tr)
+	span.End(err)
+	return actions, err
+}
+
+func (v *Validator) verifyTokenRequest(ledger driver.Ledger, signatureProvider driver.SignatureProvider, binding string, tr *driver.TokenRequest) ([]interface{}, error) {
 	// validate arguments
 	if ledger == nil {
 		return nil, errors.New("please provide a non-nil ledger")
@@ -69,21 +80,25 @@ func (v *Validator) VerifyTokenRequest(ledger driver.Ledger, signatureProvider d
 
 	// check if the token request is signed by the authorized auditor
 	if err := v.VerifyAuditorSignature(signatureProvider); err != nil {
+		validationFailed(metrics.ReasonAuditorSignature)
 		return nil, errors.Wrapf(err, "failed to verifier auditor's signature [%s]", binding)
 	}
 	// get issue and transfer actions from the token request
 	ia, ta, err := UnmarshalIssueTransferActions(tr)
 	if err != nil {
+		validationFailed(metrics.ReasonMalformed)
 		return nil, errors.Wrapf(err, "failed to unmarshal actions [%s]", binding)
 	}
 	// verify issue actions
 	err = v.VerifyIssues(ia, signatureProvider)
 	if err != nil {
+		validationFailed(metrics.ReasonIssue)
 		return nil, errors.Wrapf(err, "failed to verify issuers' signatures [%s]", binding)
 	}
 	// verify transfer actions
 	err = v.VerifyTransfers(ledger, ta, signatureProvider)
 	if err != nil {
+		validationFailed(metrics.ReasonTransfer)
 		return nil, errors.Wrapf(err, "failed to verify senders' signatures [%s]", binding)
 	}
 
@@ -101,6 +116,14 @@ func (v *Validator) VerifyTokenRequest(ledger driver.Ledger, signatureProvider d
 
 // VerifyTokenRequestFromRaw validates the raw token request
 func (v *Validator) VerifyTokenRequestFromRaw(getState driver.GetStateFnc, binding string, raw []byte) ([]interface{}, error) {
+	defer observeValidation(time.Now())
+	span := tracing.Get().StartTxSpan("fabtoken.verify", binding, tracing.SpanContext{})
+	actions, err := v.verifyTokenRequestFromRaw(getState, binding, raw)
+	span.End(err)
+	return actions, err
+}
+
+func (v *Validator) verifyTokenRequestFromRaw(getState driver.GetStateFnc, binding string, raw []byte) ([]interface{}, error) {
 	if getState == nil {
 		return nil, errors.New("please provide a non-nil get state function")
 	}
@@ -108,12 +131,14 @@ func (v *Validator) VerifyTokenRequestFromRaw(getState driver.GetStateFnc, bindi
 		return nil, errors.New("please provide a non-empty binding")
 	}
 	if len(raw) == 0 {
+		validationFailed(metrics.ReasonMalformed)
 		return nil, errors.New("empty token request")
 	}
 	// un-marshal token request
 	tr := &driver.TokenRequest{}
 	err := tr.FromBytes(raw)
 	if err != nil {
+		validationFailed(metrics.ReasonMalformed)
 		return nil, errors.Wrap(err, "failed to unmarshal token request")
 	}
 
@@ -124,6 +149,7 @@ func (v *Validator) VerifyTokenRequestFromRaw(getState driver.GetStateFnc, bindi
 	req.Issues = tr.Issues
 	bytes, err := req.Bytes()
 	if err != nil {
+		validationFailed(metrics.ReasonMalformed)
 		return nil, errors.Wrap(err, "failed to marshal signed token request"+err.Error())
 	}
 
@@ -141,7 +167,7 @@ func (v *Validator) VerifyTokenRequestFromRaw(getState driver.GetStateFnc, bindi
 	}
 
 	backend := common.NewBackend(getState, signed, signatures)
-	return v.VerifyTokenRequest(backend, backend, binding, tr)
+	return v.verifyTokenRequest(backend, backend, binding, tr)
 }
 
 // UnmarshalActions returns the actions contained in the serialized token request
@@ -315,3 +341,11 @@ func RetrieveInputsFromTransferAction(t *TransferAction, ledger driver.Ledger) (
 	}
 	return inputTokens, nil
 }
+
+func observeValidation(start time.Time) {
+	metrics.Get().ValidationDuration.With("driver", PublicParameters).Observe(metrics.Since(start))
+}
+
+func validationFailed(reason string) {
+	metrics.Get().ValidationFailures.With("driver", PublicParameters, "reason", reason).Add(1)
+}
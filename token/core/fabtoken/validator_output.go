@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabtoken
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	htlc2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/htlc"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/htlc"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// OpenOutput returns the token encoded by the passed serialized output and the enrollment ID of its owner,
+// extracted from the passed audit info after checking that it matches the owner.
+// The outputs of fabtoken are in the clear, then the output metadata is not needed to open them.
+func (v *Validator) OpenOutput(output []byte, outputMetadata []byte, ownerAuditInfo []byte) (*token2.Token, string, error) {
+	tok := &token2.Token{}
+	if err := json.Unmarshal(output, tok); err != nil {
+		return nil, "", errors.Wrap(err, "failed unmarshalling output")
+	}
+	if tok.Owner == nil || len(tok.Owner.Raw) == 0 {
+		// redeem
+		return tok, "", nil
+	}
+	if err := v.matchOwner(tok.Owner.Raw, ownerAuditInfo); err != nil {
+		return nil, "", err
+	}
+	eID, err := NewEnrollmentIDDeserializer().GetEnrollmentID(ownerAuditInfo)
+	if err != nil {
+		return nil, "", errors.WithMessage(err, "failed getting enrollment id of the owner")
+	}
+	return tok, eID, nil
+}
+
+// matchOwner checks that the passed audit info matches the passed owner, an identity or a script
+func (v *Validator) matchOwner(owner view.Identity, auditInfo []byte) error {
+	if len(auditInfo) == 0 {
+		return errors.New("no audit info for the owner")
+	}
+	ro, err := identity.UnmarshallRawOwner(owner)
+	if err != nil {
+		return errors.WithMessage(err, "failed unmarshalling owner")
+	}
+	if ro.Type != htlc.ScriptType {
+		return v.matchIdentity(owner, auditInfo)
+	}
+	scriptInfo := &htlc2.ScriptInfo{}
+	if err := json.Unmarshal(auditInfo, scriptInfo); err != nil {
+		return errors.Wrap(err, "failed unmarshalling script info")
+	}
+	sender, recipient, err := htlc2.GetScriptSenderAndRecipient(ro)
+	if err != nil {
+		return errors.WithMessage(err, "failed getting script sender and recipient")
+	}
+	if err := v.matchIdentity(sender, scriptInfo.Sender); err != nil {
+		return errors.WithMessage(err, "script sender")
+	}
+	if err := v.matchIdentity(recipient, scriptInfo.Recipient); err != nil {
+		return errors.WithMessage(err, "script recipient")
+	}
+	return nil
+}
+
+// matchIdentity checks that the passed audit info matches the passed owner identity
+func (v *Validator) matchIdentity(owner view.Identity, auditInfo []byte) error {
+	ro, err := identity.UnmarshallRawOwner(owner)
+	if err != nil {
+		return errors.WithMessage(err, "failed unmarshalling owner")
+	}
+	if ro.Type != identity.SerializedIdentityType {
+		return errors.Errorf("unsupported owner type [%s]", ro.Type)
+	}
+	matcher, err := v.deserializer.GetOwnerMatcher(auditInfo)
+	if err != nil {
+		return errors.WithMessage(err, "failed getting owner matcher")
+	}
+	if err := matcher.Match(ro.Identity); err != nil {
+		return errors.Wrap(err, "the audit info does not match the owner")
+	}
+	return nil
+}
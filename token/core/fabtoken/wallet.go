@@ -102,7 +102,7 @@ func (s *Service) OwnerWalletByID(id interface{}) (driver.OwnerWallet, error) {
 	defer s.OwnerWalletsRegistry.Unlock()
 
 	// check if there is already a wallet
-	w, idInfo, wID, err := s.OwnerWalletsRegistry.Lookup(id)
+	w, idInfo, wID, _, err := s.OwnerWalletsRegistry.Lookup(id)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to lookup identity for owner wallet [%v]", id)
 	}
@@ -142,7 +142,7 @@ func (s *Service) issuerWallet(id interface{}) (driver.IssuerWallet, error) {
 	defer s.IssuerWalletsRegistry.Unlock()
 
 	// check if there is already a wallet
-	w, idInfo, wID, err := s.IssuerWalletsRegistry.Lookup(id)
+	w, idInfo, wID, _, err := s.IssuerWalletsRegistry.Lookup(id)
 	if err != nil {
 		logger.Errorf("failed to lookup identity for issuer wallet [%s]", err)
 		return nil, nil
@@ -179,7 +179,7 @@ func (s *Service) auditorWallet(id interface{}) (driver.AuditorWallet, error) {
 	defer s.AuditorWalletsRegistry.Unlock()
 
 	// check if there is already a wallet
-	w, idInfo, wID, err := s.AuditorWalletsRegistry.Lookup(id)
+	w, idInfo, wID, _, err := s.AuditorWalletsRegistry.Lookup(id)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to lookup identity for auditor wallet [%v]", id)
 	}
@@ -275,6 +275,21 @@ func (w *ownerWallet) GetTokenMetadata(id view.Identity) ([]byte, error) {
 	return nil, nil
 }
 
+func (w *ownerWallet) ListIdentities() ([]view.Identity, error) {
+	return []view.Identity{w.wrappedID}, nil
+}
+
+func (w *ownerWallet) RestoreIdentity(id view.Identity, auditInfo []byte, metadata []byte) error {
+	// the wallet is bound to a single long-term identity, only its audit info needs to be restored
+	if !w.wrappedID.Equal(id) {
+		return errors.Errorf("identity does not belong to this wallet [%s]", id.String())
+	}
+	if err := w.tokenService.RegisterRecipientIdentity(id, auditInfo, metadata); err != nil {
+		return errors.WithMessagef(err, "failed restoring recipient identity in wallet [%s]", w.ID())
+	}
+	return nil
+}
+
 func (w *ownerWallet) GetSigner(identity view.Identity) (driver.Signer, error) {
 	if !w.wrappedID.Equal(identity) {
 		return nil, errors.Errorf("identity does not belong to this wallet [%s]", identity.String())
@@ -37,17 +37,9 @@ func NewDeserializer(ipk []byte, curveID math.CurveID) (*Deserializer, error) {
 func newDeserializer(ipk []byte, verType bccsp.VerificationType, nymEID []byte, curveID math.CurveID) (*Deserializer, error) {
 	logger.Debugf("Setting up Idemix-based MSP instance")
 
-	curve := math.Curves[curveID]
-	var tr idemix2.Translator
-	switch curveID {
-	case math.BN254:
-		tr = &amcl.Gurvy{C: curve}
-	case math.FP256BN_AMCL:
-		tr = &amcl.Fp256bn{C: curve}
-	case math.FP256BN_AMCL_MIRACL:
-		tr = &amcl.Fp256bnMiracl{C: curve}
-	default:
-		return nil, errors.Errorf("unsupported curve ID: %d", curveID)
+	curve, tr, err := getCurveAndTranslator(curveID)
+	if err != nil {
+		return nil, err
 	}
 
 	cryptoProvider, err := idemix.New(&keystore.Dummy{}, curve, tr, true)
@@ -85,6 +77,21 @@ func newDeserializer(ipk []byte, verType bccsp.VerificationType, nymEID []byte,
 	}, nil
 }
 
+// getCurveAndTranslator returns the curve and the translator for the passed curve identifier
+func getCurveAndTranslator(curveID math.CurveID) (*math.Curve, idemix2.Translator, error) {
+	curve := math.Curves[curveID]
+	switch curveID {
+	case math.BN254:
+		return curve, &amcl.Gurvy{C: curve}, nil
+	case math.FP256BN_AMCL:
+		return curve, &amcl.Fp256bn{C: curve}, nil
+	case math.FP256BN_AMCL_MIRACL:
+		return curve, &amcl.Fp256bnMiracl{C: curve}, nil
+	default:
+		return nil, nil, errors.Errorf("unsupported curve ID: %d", curveID)
+	}
+}
+
 func (i *Deserializer) DeserializeVerifier(raw view.Identity) (driver.Verifier, error) {
 	r, err := i.Common.Deserialize(raw, false)
 	if err != nil {
@@ -0,0 +1,478 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package idemix
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"sync"
+
+	msp "github.com/IBM/idemix"
+	idemix "github.com/IBM/idemix/bccsp"
+	"github.com/IBM/idemix/bccsp/keystore"
+	bccsp "github.com/IBM/idemix/bccsp/schemes"
+	idemix2 "github.com/IBM/idemix/bccsp/schemes/dlog/crypto"
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-smart-client/pkg/utils/proto"
+	driver2 "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/driver"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/common"
+	m "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultLookahead is the default number of pseudonyms, past the last one known to be in use,
+	// checked when recognizing the identities of a deterministic provider
+	DefaultLookahead = 100
+
+	seedLabel = "fabric-token-sdk/idemix/seed"
+	nymLabel  = "nym"
+	eidLabel  = "eid"
+)
+
+// DeterministicProvider generates idemix identities whose pseudonyms are derived from a wallet seed and an index.
+// The seed is derived from the user secret key, therefore the pseudonyms can be regenerated from the MSP folder alone.
+// The randomness of each pseudonym is the output of a PRF keyed by the seed, then pseudonyms at different indices
+// are as unlinkable as the randomly generated ones to anyone not holding the user secret key.
+// The association proofs are still randomized.
+type DeterministicProvider struct {
+	*Common
+	curve         *math.Curve
+	translator    idemix2.Translator
+	userKey       bccsp.Key
+	conf          m.IdemixMSPConfig
+	role          *m.MSPRole
+	ou            *m.OrganizationUnit
+	sk            *math.Zr
+	eid           *math.Zr
+	hSk           *math.G1
+	hRand         *math.G1
+	hEID          *math.G1
+	seed          []byte
+	kvs           common.KVS
+	signerService common.SignerService
+	indexKey      string
+	lookahead     uint64
+
+	mutex sync.Mutex
+	// next is the index of the next pseudonym to hand out
+	next uint64
+	// nyms maps the pseudonyms derived so far to their index
+	nyms map[string]uint64
+}
+
+// NewDeterministicProvider returns a new deterministic provider for the passed idemix MSP configuration.
+// The derivation index is persisted in the passed KVS, where the pseudonym secret keys are stored as well.
+// If lookahead is not positive, DefaultLookahead is used.
+func NewDeterministicProvider(conf1 *m.MSPConfig, kvss common.KVS, signerService common.SignerService, curveID math.CurveID, lookahead int) (*DeterministicProvider, error) {
+	if conf1 == nil {
+		return nil, errors.Errorf("setup error: nil conf reference")
+	}
+	var conf m.IdemixMSPConfig
+	if err := proto.Unmarshal(conf1.Config, &conf); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling idemix provider config")
+	}
+	if conf.Signer == nil {
+		return nil, errors.Errorf("idemix provider setup as verification only provider (no key material found)")
+	}
+	if lookahead <= 0 {
+		lookahead = DefaultLookahead
+	}
+
+	curve, tr, err := getCurveAndTranslator(curveID)
+	if err != nil {
+		return nil, err
+	}
+	cryptoProvider, err := idemix.New(&keystore.KVSStore{KVS: kvss, Curve: curve, Translator: tr}, curve, tr, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed getting crypto provider")
+	}
+	issuerPublicKey, err := cryptoProvider.KeyImport(
+		conf.Ipk,
+		&bccsp.IdemixIssuerPublicKeyImportOpts{
+			Temporary: true,
+			AttributeNames: []string{
+				msp.AttributeNameOU,
+				msp.AttributeNameRole,
+				msp.AttributeNameEnrollmentId,
+				msp.AttributeNameRevocationHandle,
+			},
+		})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to import issuer public key")
+	}
+	revocationPublicKey, err := cryptoProvider.KeyImport(
+		conf.RevocationPk,
+		&bccsp.IdemixRevocationPublicKeyImportOpts{Temporary: true},
+	)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to import revocation public key")
+	}
+	userKey, err := cryptoProvider.KeyImport(conf.Signer.Sk, &bccsp.IdemixUserSecretKeyImportOpts{Temporary: true})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed importing signer secret key")
+	}
+
+	// bases of the pseudonyms and of the enrollment id commitments
+	ipk := &idemix2.IssuerPublicKey{}
+	if err := proto.Unmarshal(conf.Ipk, ipk); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling issuer public key")
+	}
+	if len(ipk.HAttrs) <= EIDIndex {
+		return nil, errors.Errorf("invalid issuer public key, expected at least [%d] attributes", EIDIndex+1)
+	}
+	hSk, err := tr.G1FromProto(ipk.HSk)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid issuer public key")
+	}
+	hRand, err := tr.G1FromProto(ipk.HRand)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid issuer public key")
+	}
+	hEID, err := tr.G1FromProto(ipk.HAttrs[EIDIndex])
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid issuer public key")
+	}
+	cred := &idemix2.Credential{}
+	if err := proto.Unmarshal(conf.Signer.Cred, cred); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling credential")
+	}
+	if len(cred.Attrs) <= EIDIndex {
+		return nil, errors.Errorf("invalid credential, expected at least [%d] attributes", EIDIndex+1)
+	}
+
+	role := &m.MSPRole{
+		MspIdentifier: conf.Name,
+		Role:          m.MSPRole_MEMBER,
+	}
+	if int(conf.Signer.Role)&ADMIN.getValue() == ADMIN.getValue() {
+		role.Role = m.MSPRole_ADMIN
+	}
+
+	// the seed is bound to the user secret key and to the issuer
+	mac := hmac.New(sha256.New, conf.Signer.Sk)
+	mac.Write([]byte(seedLabel))
+	mac.Write(conf.Ipk)
+	seed := mac.Sum(nil)
+	seedHash := sha256.Sum256(seed)
+	indexKey, err := kvs.CreateCompositeKey("token-sdk", []string{"msp", "idemix", "derivationIndex", hex.EncodeToString(seedHash[:])})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create derivation index key")
+	}
+
+	p := &DeterministicProvider{
+		Common: &Common{
+			Name:            conf.Name,
+			IPK:             conf.Ipk,
+			CSP:             cryptoProvider,
+			IssuerPublicKey: issuerPublicKey,
+			RevocationPK:    revocationPublicKey,
+			Epoch:           0,
+			VerType:         bccsp.BestEffort,
+		},
+		curve:      curve,
+		translator: tr,
+		userKey:    userKey,
+		conf:       conf,
+		role:       role,
+		ou: &m.OrganizationUnit{
+			MspIdentifier:                conf.Name,
+			OrganizationalUnitIdentifier: conf.Signer.OrganizationalUnitIdentifier,
+			CertifiersIdentifier:         issuerPublicKey.SKI(),
+		},
+		sk:            curve.NewZrFromBytes(conf.Signer.Sk),
+		eid:           curve.NewZrFromBytes(cred.Attrs[EIDIndex]),
+		hSk:           hSk,
+		hRand:         hRand,
+		hEID:          hEID,
+		seed:          seed,
+		kvs:           kvss,
+		signerService: signerService,
+		indexKey:      indexKey,
+		lookahead:     uint64(lookahead),
+		nyms:          map[string]uint64{},
+	}
+	if kvss.Exists(indexKey) {
+		if err := kvss.Get(indexKey, &p.next); err != nil {
+			return nil, errors.Wrapf(err, "failed to load derivation index")
+		}
+	}
+	return p, nil
+}
+
+// EnrollmentID returns the enrollment ID of the identity
+func (p *DeterministicProvider) EnrollmentID() string {
+	return p.conf.Signer.EnrollmentId
+}
+
+// Index returns the index of the next pseudonym to hand out
+func (p *DeterministicProvider) Index() uint64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.next
+}
+
+// Identity returns the identity whose pseudonym is derived at the next index, and its audit info.
+// The signer of the identity is registered with the signer service.
+func (p *DeterministicProvider) Identity(opts *driver2.IdentityOptions) (view.Identity, []byte, error) {
+	eidNym := false
+	var auditData *bccsp.NymEIDAuditData
+	if opts != nil {
+		eidNym = opts.EIDExtension
+		if len(opts.AuditInfo) != 0 {
+			ai, err := p.DeserializeAuditInfo(opts.AuditInfo)
+			if err != nil {
+				return nil, nil, err
+			}
+			auditData = ai.NymEIDAuditData
+		}
+	}
+
+	p.mutex.Lock()
+	index := p.next
+	if err := p.setNext(index + 1); err != nil {
+		p.mutex.Unlock()
+		return nil, nil, err
+	}
+	p.mutex.Unlock()
+
+	return p.IdentityAt(index, eidNym, auditData)
+}
+
+// IdentityAt returns the identity whose pseudonym is derived at the passed index, and its audit info.
+// If eidNym is true, the identity carries a commitment to the enrollment ID.
+// If auditData is nil, the opening of the commitment is derived at the passed index as well.
+func (p *DeterministicProvider) IdentityAt(index uint64, eidNym bool, auditData *bccsp.NymEIDAuditData) (view.Identity, []byte, error) {
+	nymKey, err := p.nymKey(index)
+	if err != nil {
+		return nil, nil, err
+	}
+	nymPublicKey, err := nymKey.PublicKey()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed getting public nym key")
+	}
+
+	sigType := bccsp.Standard
+	var signerMetadata *bccsp.IdemixSignerMetadata
+	if eidNym {
+		sigType = bccsp.EidNym
+		if auditData == nil {
+			auditData = p.nymEIDAuditData(index)
+		}
+		signerMetadata = &bccsp.IdemixSignerMetadata{NymEIDAuditData: auditData}
+	}
+
+	// Create the cryptographic evidence that this identity is valid
+	proof, err := p.CSP.Sign(
+		p.userKey,
+		nil,
+		&bccsp.IdemixSignerOpts{
+			Credential: p.conf.Signer.Cred,
+			Nym:        nymKey,
+			IssuerPK:   p.IssuerPublicKey,
+			Attributes: []bccsp.IdemixAttribute{
+				{Type: bccsp.IdemixBytesAttribute},
+				{Type: bccsp.IdemixIntAttribute},
+				{Type: bccsp.IdemixHiddenAttribute},
+				{Type: bccsp.IdemixHiddenAttribute},
+			},
+			RhIndex:  RHIndex,
+			EidIndex: EIDIndex,
+			CRI:      p.conf.Signer.CredentialRevocationInformation,
+			SigType:  sigType,
+			Metadata: signerMetadata,
+		},
+	)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to setup cryptographic proof of identity")
+	}
+
+	id, err := NewIdentityWithVerType(p.Common, nymPublicKey, p.role, p.ou, proof, p.VerType)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := p.registerSigner(id, nymKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !eidNym {
+		return raw, nil, nil
+	}
+	auditInfo, err := p.auditInfo(auditData).Bytes()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed marshalling audit info")
+	}
+	return raw, auditInfo, nil
+}
+
+// Recover checks if the passed identity carries a pseudonym derived by this provider.
+// Pseudonyms are searched up to the lookahead past the next index.
+// If a match is found, the signer of the identity is registered with the signer service,
+// the next index is moved past the matched one, and the audit info of the identity, if any, is returned.
+func (p *DeterministicProvider) Recover(raw view.Identity) (*SigningIdentity, []byte, error) {
+	si := &m.SerializedIdentity{}
+	if err := proto.Unmarshal(raw, si); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to unmarshal to msp.SerializedIdentity{}")
+	}
+	if si.Mspid != p.Name {
+		return nil, nil, errors.Errorf("msp id mismatch, expected [%s], got [%s]", p.Name, si.Mspid)
+	}
+	serialized := &m.SerializedIdemixIdentity{}
+	if err := proto.Unmarshal(si.IdBytes, serialized); err != nil {
+		return nil, nil, errors.Wrap(err, "could not deserialize a SerializedIdemixIdentity")
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// extend the derived pseudonyms up to the lookahead
+	for i := uint64(len(p.nyms)); i < p.next+p.lookahead; i++ {
+		nym, _ := p.nym(i)
+		p.nyms[p.nymID(nym)] = i
+	}
+	index, ok := p.nyms[hex.EncodeToString(append(append([]byte{}, serialized.NymX...), serialized.NymY...))]
+	if !ok {
+		return nil, nil, errors.New("pseudonym not derived by this provider")
+	}
+
+	d, err := p.Deserialize(raw, true)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "invalid identity")
+	}
+	nymKey, err := p.nymKey(index)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := p.registerSigner(d.id, nymKey); err != nil {
+		return nil, nil, err
+	}
+
+	// regenerate the audit info, if the identity carries a commitment to the enrollment ID
+	var auditInfo []byte
+	sig := &idemix2.Signature{}
+	if err := proto.Unmarshal(serialized.Proof, sig); err != nil {
+		return nil, nil, errors.Wrap(err, "failed unmarshalling association proof")
+	}
+	if sig.EidNym != nil {
+		ai := p.auditInfo(p.nymEIDAuditData(index))
+		if err := ai.Match(raw); err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed matching regenerated audit info")
+		}
+		auditInfo, err = ai.Bytes()
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed marshalling audit info")
+		}
+	}
+
+	if index >= p.next {
+		if err := p.setNext(index + 1); err != nil {
+			return nil, nil, err
+		}
+	}
+	logger.Debugf("recovered pseudonym at index [%d] for [%s]", index, p.EnrollmentID())
+
+	return &SigningIdentity{
+		Identity:     d.id,
+		Cred:         p.conf.Signer.Cred,
+		UserKey:      p.userKey,
+		NymKey:       nymKey,
+		EnrollmentId: p.EnrollmentID(),
+	}, auditInfo, nil
+}
+
+// derive returns the scalar derived from the seed for the passed label and index
+func (p *DeterministicProvider) derive(label string, index uint64) *math.Zr {
+	mac := hmac.New(sha256.New, p.seed)
+	mac.Write([]byte(label))
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], index)
+	mac.Write(buf[:])
+	return p.curve.HashToZr(mac.Sum(nil))
+}
+
+// nym returns the pseudonym at the passed index and its randomness.
+// Nym = HSk^sk \cdot HRand^r
+func (p *DeterministicProvider) nym(index uint64) (*math.G1, *math.Zr) {
+	r := p.derive(nymLabel, index)
+	return p.hSk.Mul2(p.sk, p.hRand, r), r
+}
+
+func (p *DeterministicProvider) nymID(nym *math.G1) string {
+	ecp := p.translator.G1ToProto(nym)
+	return hex.EncodeToString(append(append([]byte{}, ecp.X...), ecp.Y...))
+}
+
+// nymKey imports the pseudonym at the passed index, the key is stored to let the signer be deserialized later on
+func (p *DeterministicProvider) nymKey(index uint64) (bccsp.Key, error) {
+	nym, r := p.nym(index)
+	ecp := p.translator.G1ToProto(nym)
+	var raw []byte
+	raw = append(raw, r.Bytes()...)
+	raw = append(raw, ecp.X...)
+	raw = append(raw, ecp.Y...)
+	nymKey, err := p.CSP.KeyImport(raw, &bccsp.IdemixNymKeyImportOpts{Temporary: false})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed importing nym at index [%d]", index)
+	}
+	return nymKey, nil
+}
+
+// nymEIDAuditData returns the commitment to the enrollment ID at the passed index and its opening.
+// NymEID = HEID^eid \cdot HRand^r
+func (p *DeterministicProvider) nymEIDAuditData(index uint64) *bccsp.NymEIDAuditData {
+	r := p.derive(eidLabel, index)
+	return &bccsp.NymEIDAuditData{
+		Nym:     p.hEID.Mul2(p.eid, p.hRand, r),
+		RNymEid: r,
+		EID:     p.eid,
+	}
+}
+
+func (p *DeterministicProvider) auditInfo(auditData *bccsp.NymEIDAuditData) *AuditInfo {
+	return &AuditInfo{
+		Csp:             p.CSP,
+		IssuerPublicKey: p.IssuerPublicKey,
+		NymEIDAuditData: auditData,
+		Attributes: [][]byte{
+			[]byte(p.conf.Signer.OrganizationalUnitIdentifier),
+			[]byte(strconv.Itoa(getIdemixRoleFromMSPRole(p.role))),
+			[]byte(p.conf.Signer.EnrollmentId),
+		},
+	}
+}
+
+func (p *DeterministicProvider) registerSigner(id *Identity, nymKey bccsp.Key) (view.Identity, error) {
+	sID := &SigningIdentity{
+		Identity:     id,
+		Cred:         p.conf.Signer.Cred,
+		UserKey:      p.userKey,
+		NymKey:       nymKey,
+		EnrollmentId: p.EnrollmentID(),
+	}
+	raw, err := sID.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.signerService.RegisterSigner(raw, sID, sID); err != nil {
+		return nil, errors.WithMessagef(err, "failed registering signer")
+	}
+	return raw, nil
+}
+
+// setNext persists the next index, the caller must hold the lock
+func (p *DeterministicProvider) setNext(next uint64) error {
+	if err := p.kvs.Put(p.indexKey, next); err != nil {
+		return errors.Wrapf(err, "failed to store derivation index")
+	}
+	p.next = next
+	return nil
+}
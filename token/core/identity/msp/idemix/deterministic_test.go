@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package idemix
+
+import (
+	"testing"
+
+	math "github.com/IBM/mathlib"
+	driver "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/driver"
+	_ "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/memory"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs/mock"
+	registry2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/stretchr/testify/assert"
+)
+
+type signerService struct {
+	signers map[string]driver.Signer
+}
+
+func (s *signerService) IsMe(id view.Identity) bool {
+	_, ok := s.signers[id.UniqueID()]
+	return ok
+}
+
+func (s *signerService) RegisterSigner(id view.Identity, signer driver.Signer, verifier driver.Verifier) error {
+	s.signers[id.UniqueID()] = signer
+	return nil
+}
+
+func newDeterministicProvider(t *testing.T, kvss *kvs.KVS, lookahead int) (*DeterministicProvider, *signerService) {
+	conf, err := msp.GetLocalMspConfigWithType("./testdata/idemix", nil, "idemix", "idemix")
+	assert.NoError(t, err)
+	signers := &signerService{signers: map[string]driver.Signer{}}
+	p, err := NewDeterministicProvider(conf, kvss, signers, math.FP256BN_AMCL, lookahead)
+	assert.NoError(t, err)
+	return p, signers
+}
+
+func newKVS(t *testing.T) *kvs.KVS {
+	cp := &mock.ConfigProvider{}
+	cp.IsSetReturns(false)
+	kvss, err := kvs.NewWithConfig(registry2.New(), "memory", "_default", cp)
+	assert.NoError(t, err)
+	return kvss
+}
+
+func TestDeterministicProvider(t *testing.T) {
+	kvs1 := newKVS(t)
+	p1, signers := newDeterministicProvider(t, kvs1, 10)
+
+	id0, ai0, err := p1.Identity(&driver.IdentityOptions{EIDExtension: true})
+	assert.NoError(t, err)
+	id1, ai1, err := p1.Identity(&driver.IdentityOptions{EIDExtension: true})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), p1.Index())
+	assert.True(t, signers.IsMe(id0))
+	assert.True(t, signers.IsMe(id1))
+
+	// pseudonyms and audit info differ across indices
+	d0, err := p1.Deserialize(id0, true)
+	assert.NoError(t, err)
+	d1, err := p1.Deserialize(id1, true)
+	assert.NoError(t, err)
+	assert.NotEqual(t, d0.NymPublicKey.SKI(), d1.NymPublicKey.SKI())
+	assert.NotEqual(t, ai0, ai1)
+	for _, pair := range []struct {
+		id view.Identity
+		ai []byte
+	}{{id0, ai0}, {id1, ai1}} {
+		ai, err := p1.DeserializeAuditInfo(pair.ai)
+		assert.NoError(t, err)
+		assert.NoError(t, ai.Match(pair.id))
+		assert.Equal(t, p1.EnrollmentID(), ai.EnrollmentID())
+	}
+
+	// the derivation index survives restarts
+	p, _ := newDeterministicProvider(t, kvs1, 10)
+	assert.Equal(t, uint64(2), p.Index())
+
+	// the same pseudonym and audit info are regenerated from the msp folder alone
+	p2, signers2 := newDeterministicProvider(t, newKVS(t), 10)
+	assert.Equal(t, uint64(0), p2.Index())
+	id, ai, err := p2.IdentityAt(1, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, ai1, ai)
+	d, err := p2.Deserialize(id, true)
+	assert.NoError(t, err)
+	assert.Equal(t, d1.NymPublicKey.SKI(), d.NymPublicKey.SKI())
+	assert.Equal(t, uint64(0), p2.Index())
+
+	// recover an identity handed out before the restore
+	signer, ai, err := p2.Recover(id1)
+	assert.NoError(t, err)
+	assert.Equal(t, ai1, ai)
+	assert.Equal(t, uint64(2), p2.Index())
+	assert.True(t, signers2.IsMe(id1))
+	sigma, err := signer.Sign([]byte("hello world"))
+	assert.NoError(t, err)
+	assert.NoError(t, d1.id.Verify([]byte("hello world"), sigma))
+
+	// identities beyond the lookahead are not recognized
+	far, _, err := p1.IdentityAt(30, true, nil)
+	assert.NoError(t, err)
+	_, _, err = p2.Recover(far)
+	assert.Error(t, err)
+	_, _, err = p2.Recover([]byte("hello world"))
+	assert.Error(t, err)
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	config2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
@@ -39,11 +40,13 @@ type LocalMembership struct {
 	deserializerManager    common.DeserializerManager
 	kvs                    common.KVS
 	mspID                  string
+	curveID                math3.CurveID
 
 	resolversMutex          sync.RWMutex
 	resolvers               []*common.Resolver
 	resolversByName         map[string]*common.Resolver
 	resolversByEnrollmentID map[string]*common.Resolver
+	deterministicProviders  map[string]*DeterministicProvider
 }
 
 func NewLocalMembership(
@@ -55,6 +58,7 @@ func NewLocalMembership(
 	deserializerManager common.DeserializerManager,
 	kvs common.KVS,
 	mspID string,
+	curveID math3.CurveID,
 ) *LocalMembership {
 	return &LocalMembership{
 		sp:                      sp,
@@ -65,8 +69,10 @@ func NewLocalMembership(
 		deserializerManager:     deserializerManager,
 		kvs:                     kvs,
 		mspID:                   mspID,
+		curveID:                 curveID,
 		resolversByEnrollmentID: map[string]*common.Resolver{},
 		resolversByName:         map[string]*common.Resolver{},
+		deterministicProviders:  map[string]*DeterministicProvider{},
 	}
 }
 
@@ -123,6 +129,50 @@ func (lm *LocalMembership) GetIdentifier(id view.Identity) (string, error) {
 	return r.Name, nil
 }
 
+// Recover checks if the passed identity, possibly wrapped in a raw owner, carries a pseudonym derived
+// by one of the deterministic providers. If so, the signer and the audit info of the identity are registered,
+// and the identifier of the matching resolver is returned.
+func (lm *LocalMembership) Recover(id view.Identity) (string, error) {
+	lm.resolversMutex.RLock()
+	providers := make(map[string]*DeterministicProvider, len(lm.deterministicProviders))
+	for name, dp := range lm.deterministicProviders {
+		providers[name] = dp
+	}
+	lm.resolversMutex.RUnlock()
+	if len(providers) == 0 {
+		return "", errors.New("no deterministic provider available")
+	}
+
+	inner := id
+	if ro, err := identity.UnmarshallRawOwner(id); err == nil && ro.Type == identity.SerializedIdentityType {
+		inner = ro.Identity
+	}
+	for name, dp := range providers {
+		signer, auditInfo, err := dp.Recover(inner)
+		if err != nil {
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Debugf("identity [%s] not recovered by [%s]: [%s]", id, name, err)
+			}
+			continue
+		}
+		sigService := view2.GetSigService(lm.sp)
+		if !inner.Equal(id) {
+			if err := lm.signerService.RegisterSigner(id, signer, signer); err != nil {
+				return "", errors.WithMessagef(err, "failed registering signer for [%s]", id)
+			}
+		}
+		if len(auditInfo) != 0 {
+			for _, recipient := range []view.Identity{inner, id} {
+				if err := sigService.RegisterAuditInfo(recipient, auditInfo); err != nil {
+					return "", errors.WithMessagef(err, "failed registering audit info for [%s]", recipient)
+				}
+			}
+		}
+		return name, nil
+	}
+	return "", errors.Errorf("identity [%s] not derived by any deterministic provider", id)
+}
+
 func (lm *LocalMembership) GetDefaultIdentifier() string {
 	for _, resolver := range lm.resolvers {
 		if resolver.Default {
@@ -163,7 +213,7 @@ func (lm *LocalMembership) RegisterIdentity(id string, path string) error {
 func (lm *LocalMembership) registerIdentity(id string, path string, setDefault bool) error {
 	// Try to register the MSP provider
 	translatedPath := lm.configManager.TranslatePath(path)
-	curveID := math3.BN254
+	curveID := lm.curveID
 	if err := lm.registerMSPProvider(id, translatedPath, curveID, setDefault); err != nil {
 		// Does path correspond to a holder containing multiple MSP identities?
 		if err := lm.registerMSPProviders(translatedPath, curveID); err != nil {
@@ -184,14 +234,32 @@ func (lm *LocalMembership) registerMSPProvider(id, translatedPath string, curveI
 		return errors.Wrapf(err, "failed instantiating idemix msp provider from [%s]", translatedPath)
 	}
 
-	cacheSize, err := lm.cacheSizeForID(id)
+	identityConfig, err := lm.identityConfigForID(id)
 	if err != nil {
 		return err
 	}
 
 	lm.deserializerManager.AddDeserializer(provider)
+	if identityConfig != nil && identityConfig.Derivation != nil {
+		dp, err := NewDeterministicProvider(conf, lm.kvs, lm.signerService, curveID, identityConfig.Derivation.Lookahead)
+		if err != nil {
+			return errors.WithMessagef(err, "failed instantiating deterministic idemix provider from [%s]", translatedPath)
+		}
+		lm.resolversMutex.Lock()
+		lm.deterministicProviders[id] = dp
+		lm.resolversMutex.Unlock()
+		// no cache here, pseudonyms are derived on demand to not burn indices
+		lm.addResolver(id, provider.EnrollmentID(), setDefault, dp.Identity)
+		logger.Debugf("added %s deterministic resolver for id %s at index %d", MSP, id+"@"+provider.EnrollmentID(), dp.Index())
+		return nil
+	}
+
+	cacheSize := DefaultCacheSize
+	if identityConfig != nil {
+		cacheSize = identityConfig.CacheSize
+	}
 	lm.addResolver(id, provider.EnrollmentID(), setDefault, NewIdentityCache(provider.Identity, cacheSize).Identity)
-	logger.Debugf("added %s resolver for id %s with cache of size %d", MSP, id+"@"+provider.EnrollmentID(), DefaultCacheSize)
+	logger.Debugf("added %s resolver for id %s with cache of size %d", MSP, id+"@"+provider.EnrollmentID(), cacheSize)
 	return nil
 }
 
@@ -248,24 +316,25 @@ func (lm *LocalMembership) getResolver(label string) *common.Resolver {
 	return nil
 }
 
-func (lm *LocalMembership) cacheSizeForID(id string) (int, error) {
+// identityConfigForID returns the configuration of the owner identity with the passed identifier, nil if not found
+func (lm *LocalMembership) identityConfigForID(id string) (*config.Identity, error) {
 	tmss, err := config2.NewTokenSDK(view2.GetConfigService(lm.sp)).GetTMSs()
 	if err != nil {
-		return 0, errors.WithMessage(err, "failed to obtain token management system instances")
+		return nil, errors.WithMessage(err, "failed to obtain token management system instances")
 	}
 
 	for _, tms := range tmss {
 		for _, owner := range tms.TMS().Wallets.Owners {
 			if owner.ID == id {
-				logger.Debugf("Cache size for %s is set to be %d", id, owner.CacheSize)
-				return owner.CacheSize, nil
+				logger.Debugf("configuration found for owner identity %s", id)
+				return owner, nil
 			}
 		}
 	}
 
-	logger.Debugf("cache size for %s not configured, using default (%d)", id, DefaultCacheSize)
+	logger.Debugf("owner identity %s not configured", id)
 
-	return DefaultCacheSize, nil
+	return nil, nil
 }
 
 func (lm *LocalMembership) storeEntryInKVS(id string, path string) error {
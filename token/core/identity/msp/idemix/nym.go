@@ -0,0 +1,93 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package idemix
+
+import (
+	idemix2 "github.com/IBM/idemix/bccsp/schemes/dlog/crypto"
+	"github.com/IBM/idemix/bccsp/schemes/dlog/crypto/translator/amcl"
+	"github.com/IBM/idemix/bccsp/schemes/dlog/handlers"
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-smart-client/pkg/utils/proto"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	m "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+)
+
+// NymFromIdentity returns the pseudonym carried by the passed serialized idemix identity.
+// Nym = HSk^sk \cdot HRand^r
+func NymFromIdentity(raw view.Identity, curveID math.CurveID) (*math.G1, error) {
+	_, tr, err := getCurveAndTranslator(curveID)
+	if err != nil {
+		return nil, err
+	}
+	si := &m.SerializedIdentity{}
+	if err := proto.Unmarshal(raw, si); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal to msp.SerializedIdentity{}")
+	}
+	serialized := &m.SerializedIdemixIdentity{}
+	if err := proto.Unmarshal(si.IdBytes, serialized); err != nil {
+		return nil, errors.Wrap(err, "could not deserialize a SerializedIdemixIdentity")
+	}
+	if serialized.NymX == nil || serialized.NymY == nil {
+		return nil, errors.Errorf("invalid idemix identity: pseudonym is invalid")
+	}
+	nym, err := tr.G1FromProto(&amcl.ECP{X: serialized.NymX, Y: serialized.NymY})
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid idemix identity: failed to decode pseudonym")
+	}
+	return nym, nil
+}
+
+// HRand returns the base used for the randomness of the pseudonyms of the passed issuer public key
+func HRand(ipk []byte, curveID math.CurveID) (*math.G1, error) {
+	return issuerPublicKeyBase(ipk, curveID, (*idemix2.IssuerPublicKey).GetHRand)
+}
+
+// HSk returns the base used for the user secret key in the pseudonyms of the passed issuer public key
+func HSk(ipk []byte, curveID math.CurveID) (*math.G1, error) {
+	return issuerPublicKeyBase(ipk, curveID, (*idemix2.IssuerPublicKey).GetHSk)
+}
+
+func issuerPublicKeyBase(ipk []byte, curveID math.CurveID, base func(*idemix2.IssuerPublicKey) *amcl.ECP) (*math.G1, error) {
+	_, tr, err := getCurveAndTranslator(curveID)
+	if err != nil {
+		return nil, err
+	}
+	pk := &idemix2.IssuerPublicKey{}
+	if err := proto.Unmarshal(ipk, pk); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling issuer public key")
+	}
+	b := base(pk)
+	if b == nil {
+		return nil, errors.New("invalid issuer public key: missing base")
+	}
+	g, err := tr.G1FromProto(b)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid issuer public key")
+	}
+	return g, nil
+}
+
+// NymRandomness returns the randomness of the pseudonym of this signing identity.
+// Only the identities whose pseudonym secret key is held in memory can disclose it.
+func (id *SigningIdentity) NymRandomness() (*math.Zr, error) {
+	nymKey, ok := id.NymKey.(*handlers.NymSecretKey)
+	if !ok {
+		return nil, errors.Errorf("pseudonym secret key of type [%T] does not disclose its randomness", id.NymKey)
+	}
+	return nymKey.Sk.Copy(), nil
+}
+
+// UserSecret returns the user secret key of this signing identity.
+// Only the identities whose user secret key is held in memory can disclose it.
+func (id *SigningIdentity) UserSecret() (*math.Zr, error) {
+	userKey, ok := id.UserKey.(*handlers.UserSecretKey)
+	if !ok {
+		return nil, errors.Errorf("user secret key of type [%T] does not disclose its value", id.UserKey)
+	}
+	return userKey.Sk.Copy(), nil
+}
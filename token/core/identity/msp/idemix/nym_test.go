@@ -0,0 +1,37 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package idemix
+
+import (
+	"testing"
+
+	math "github.com/IBM/mathlib"
+	driver "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNymOpening(t *testing.T) {
+	p, signers := newDeterministicProvider(t, newKVS(t), 10)
+	id, _, err := p.Identity(&driver.IdentityOptions{EIDExtension: true})
+	assert.NoError(t, err)
+	signer, ok := signers.signers[id.UniqueID()].(*SigningIdentity)
+	assert.True(t, ok)
+
+	sk, err := signer.UserSecret()
+	assert.NoError(t, err)
+	r, err := signer.NymRandomness()
+	assert.NoError(t, err)
+	hSk, err := HSk(p.IPK, math.FP256BN_AMCL)
+	assert.NoError(t, err)
+	hRand, err := HRand(p.IPK, math.FP256BN_AMCL)
+	assert.NoError(t, err)
+
+	// Nym = HSk^sk \cdot HRand^r
+	nym, err := NymFromIdentity(id, math.FP256BN_AMCL)
+	assert.NoError(t, err)
+	assert.True(t, nym.Equals(hSk.Mul2(sk, hRand, r)))
+}
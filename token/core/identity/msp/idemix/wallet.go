@@ -20,6 +20,7 @@ type localMembership interface {
 	GetIdentifier(id view.Identity) (string, error)
 	GetDefaultIdentifier() string
 	RegisterIdentity(id string, path string) error
+	Recover(id view.Identity) (string, error)
 }
 
 // wallet maps an identifier to an identity
@@ -101,6 +102,12 @@ func (w *wallet) MapToID(v interface{}) (view.Identity, string, error) {
 			}
 			return id, "", nil
 		}
+		if idIdentifier, err := w.localMembership.Recover(id); err == nil {
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Debugf("[AnonymousIdentity] passed identity has been recovered by [%s]", idIdentifier)
+			}
+			return id, idIdentifier, nil
+		}
 		label := string(id)
 		if logger.IsEnabledFor(zapcore.DebugLevel) {
 			logger.Debugf("[AnonymousIdentity] looking up identifier for identity as label [%d,%s]", label)
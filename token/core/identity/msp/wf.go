@@ -8,6 +8,7 @@ package msp
 
 import (
 	"github.com/IBM/idemix/common/flogging"
+	math3 "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
@@ -73,8 +74,8 @@ func NewWalletFactory(
 	}
 }
 
-// NewIdemixWallet creates a new Idemix wallet
-func (f *WalletFactory) NewIdemixWallet(role driver.IdentityRole) (identity.Wallet, error) {
+// NewIdemixWallet creates a new Idemix wallet whose identities are on the passed curve
+func (f *WalletFactory) NewIdemixWallet(role driver.IdentityRole, curveID math3.CurveID) (identity.Wallet, error) {
 	identities, err := f.ConfigFor(role)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get identities for role [%d]", role)
@@ -93,6 +94,7 @@ func (f *WalletFactory) NewIdemixWallet(role driver.IdentityRole) (identity.Wall
 		dm,
 		kvs.GetService(f.SP),
 		RoleToMSPID[role],
+		curveID,
 	)
 	if err := lm.Load(identities); err != nil {
 		return nil, errors.WithMessage(err, "failed to load owners")
@@ -106,6 +108,13 @@ func (f *WalletFactory) NewX509Wallet(role driver.IdentityRole) (identity.Wallet
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get identities for role [%d]", role)
 	}
+	// the anonymous issuers are not backed by an MSP, the driver loads them
+	var msps []*config.Identity
+	for _, identity := range identities {
+		if !identity.Anonymous {
+			msps = append(msps, identity)
+		}
+	}
 	dm, err := common.GetDeserializerManager(f.SP)
 	if err != nil {
 		return nil, err
@@ -119,7 +128,7 @@ func (f *WalletFactory) NewX509Wallet(role driver.IdentityRole) (identity.Wallet
 		kvs.GetService(f.SP),
 		RoleToMSPID[role],
 	)
-	if err := lm.Load(identities); err != nil {
+	if err := lm.Load(msps); err != nil {
 		return nil, errors.WithMessage(err, "failed to load owners")
 	}
 	return x509.NewWallet(f.NetworkID, f.FSCIdentity, lm), nil
@@ -123,6 +123,11 @@ func NewSigner() (view.Identity, driver.Signer, driver.Verifier, error) {
 	return idRaw, &edsaSigner{sk: sk}, &edsaVerifier{pk: &sk.PublicKey}, nil
 }
 
+// NewSignerFromKey returns a signer producing low-S signatures with the passed secret key
+func NewSignerFromKey(sk *ecdsa.PrivateKey) driver.Signer {
+	return &edsaSigner{sk: sk}
+}
+
 func NewVerifier(pk *ecdsa.PublicKey) *edsaVerifier {
 	return &edsaVerifier{pk: pk}
 }
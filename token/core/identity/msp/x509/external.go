@@ -0,0 +1,112 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package x509
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"path/filepath"
+
+	"github.com/hyperledger-labs/fabric-smart-client/pkg/utils/proto"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/x509"
+	fdriver "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/driver"
+	vdriver "github.com/hyperledger-labs/fabric-smart-client/platform/view/driver"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/signer"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+)
+
+// externalSignerProvider is an x509 identity whose certificate is loaded from an MSP folder,
+// and whose secret key is managed by an external signer (an HSM, a remote signing service, and so on).
+type externalSignerProvider struct {
+	id           view.Identity
+	enrollmentID string
+	signer       vdriver.Signer
+	verifier     vdriver.Verifier
+}
+
+func newExternalSignerProvider(c *config.Identity, translatedPath string, mspID string) (*externalSignerProvider, error) {
+	// Try without "msp"
+	certRaw, err := x509.LoadLocalMSPSignerCert(translatedPath)
+	if err != nil {
+		// Try with "msp"
+		certRaw, err = x509.LoadLocalMSPSignerCert(filepath.Join(translatedPath, "msp"))
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to load signer certificate from [%s]", translatedPath)
+		}
+	}
+	cert, err := x509.PemDecodeCert(certRaw)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to decode signer certificate from [%s]", translatedPath)
+	}
+	pk, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("expected *ecdsa.PublicKey, got [%T]", cert.PublicKey)
+	}
+
+	s, err := signer.NewSigner(c.Signer, pk)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to create external signer for [%s]", c.ID)
+	}
+	id, err := proto.Marshal(&msp.SerializedIdentity{Mspid: mspID, IdBytes: certRaw})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshalling msp serialized identity")
+	}
+	enrollmentID, err := x509.GetEnrollmentID(id)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting enrollment id for [%s]", c.ID)
+	}
+
+	return &externalSignerProvider{
+		id:           id,
+		enrollmentID: enrollmentID,
+		signer:       s,
+		verifier:     NewVerifier(pk),
+	}, nil
+}
+
+func (p *externalSignerProvider) Identity(opts *fdriver.IdentityOptions) (view.Identity, []byte, error) {
+	return p.id, []byte(p.enrollmentID), nil
+}
+
+func (p *externalSignerProvider) EnrollmentID() string {
+	return p.enrollmentID
+}
+
+func (p *externalSignerProvider) DeserializeVerifier(raw []byte) (vdriver.Verifier, error) {
+	if !p.id.Equal(raw) {
+		return nil, errors.New("identity not recognized")
+	}
+	return p.verifier, nil
+}
+
+func (p *externalSignerProvider) DeserializeSigner(raw []byte) (vdriver.Signer, error) {
+	if !p.id.Equal(raw) {
+		return nil, errors.New("identity not recognized")
+	}
+	return p.signer, nil
+}
+
+func (p *externalSignerProvider) Info(raw []byte, auditInfo []byte) (string, error) {
+	return fmt.Sprintf("MSP.x509.External: [%s][%s]", view.Identity(raw).UniqueID(), p.enrollmentID), nil
+}
+
+func (lm *LocalMembership) registerExternalSignerProvider(c *config.Identity, translatedPath string, setDefault bool) error {
+	provider, err := newExternalSignerProvider(c, translatedPath, lm.mspID)
+	if err != nil {
+		return err
+	}
+	if err := lm.signerService.RegisterSigner(provider.id, provider.signer, provider.verifier); err != nil {
+		return errors.WithMessagef(err, "failed registering external signer for [%s]", c.ID)
+	}
+
+	logger.Debugf("Adding x509 wallet resolver with external signer [%s:%s:%s:%s]", c.ID, c.Signer.Type, provider.EnrollmentID(), provider.id.String())
+	lm.deserializerManager.AddDeserializer(provider)
+	return lm.addResolver(c.ID, provider.EnrollmentID(), setDefault, provider.Identity)
+}
@@ -147,8 +147,16 @@ func (lm *LocalMembership) RegisterIdentity(id string, path string) error {
 }
 
 func (lm *LocalMembership) registerIdentity(c *config.Identity, setDefault bool) error {
-	// Try to register the MSP provider
 	translatedPath := lm.configManager.TranslatePath(c.Path)
+	if c.Signer != nil {
+		// The secret key is kept by an external signer
+		if err := lm.registerExternalSignerProvider(c, translatedPath, setDefault); err != nil {
+			return errors.WithMessage(err, "failed to register x509 identity with external signer")
+		}
+		return nil
+	}
+
+	// Try to register the MSP provider
 	if err := lm.registerMSPProvider(c, translatedPath, setDefault); err != nil {
 		// Does path correspond to a holder containing multiple MSP identities?
 		if err := lm.registerMSPProviders(c, translatedPath); err != nil {
@@ -8,10 +8,12 @@ package identity
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
@@ -23,6 +25,7 @@ type KVS interface {
 	Exists(id string) bool
 	Put(id string, state interface{}) error
 	Get(id string, state interface{}) error
+	GetByPartialCompositeID(prefix string, attrs []string) (kvs.Iterator, error)
 }
 
 type WalletEntry struct {
@@ -54,17 +57,24 @@ func NewWalletsRegistry(id token.TMSID, identityProvider driver.IdentityProvider
 // Lookup searches the wallet corresponding to the passed id.
 // If a wallet is found, Lookup returns the wallet and its identifier.
 // If no wallet is found, Lookup returns the identity info and a potential wallet identifier for the passed id.
-// The identity info can be nil meaning that nothing has been found bound to the passed identifier
-func (r *WalletsRegistry) Lookup(id interface{}) (driver.Wallet, driver.IdentityInfo, string, error) {
+// The identity info can be nil meaning that nothing has been found bound to the passed identifier.
+// Lookup does not modify the registry. If the identity provider recognized the passed identity as belonging to a wallet,
+// for example a pseudonym regenerated after a restore, but the identity is not bound to that wallet yet,
+// Lookup returns it as recovered, and the caller can bind it with RegisterIdentity.
+func (r *WalletsRegistry) Lookup(id interface{}) (driver.Wallet, driver.IdentityInfo, string, view.Identity, error) {
 	identity, walletID, err := r.IdentityProvider.LookupIdentifier(r.IdentityRole, id)
 	if err != nil {
-		return nil, nil, "", errors.WithMessagef(err, "failed to lookup wallet [%s]", id)
+		return nil, nil, "", nil, errors.WithMessagef(err, "failed to lookup wallet [%s]", id)
 	}
 	logger.Debugf("looked-up identifier [%s:%s]", identity, walletIDToString(walletID))
 	wID := walletID
+	var recovered view.Identity
+	if len(identity) != 0 && len(wID) != 0 && !r.ContainsIdentity(identity, wID) {
+		recovered = identity
+	}
 	walletEntry, ok := r.Wallets[wID]
 	if ok {
-		return walletEntry.Wallet, nil, wID, nil
+		return walletEntry.Wallet, nil, wID, recovered, nil
 	}
 	if logger.IsEnabledFor(zapcore.DebugLevel) {
 		logger.Debugf("no wallet found for [%s] at [%s]", identity, walletIDToString(wID))
@@ -81,7 +91,7 @@ func (r *WalletsRegistry) Lookup(id interface{}) (driver.Wallet, driver.Identity
 				if logger.IsEnabledFor(zapcore.DebugLevel) {
 					logger.Debugf("found wallet [%s:%s:%s:%s]", identity, walletID, w.Wallet.ID(), identityWID)
 				}
-				return w.Wallet, nil, identityWID, nil
+				return w.Wallet, nil, identityWID, nil, nil
 			}
 		}
 	}
@@ -95,18 +105,18 @@ func (r *WalletsRegistry) Lookup(id interface{}) (driver.Wallet, driver.Identity
 		idInfo, err = r.IdentityProvider.GetIdentityInfo(r.IdentityRole, id)
 		if err == nil {
 			logger.Debugf("identity info found at [%s]", walletIDToString(id))
-			return nil, idInfo, id, nil
+			return nil, idInfo, id, recovered, nil
 		} else {
 			logger.Debugf("identity info not found at [%s]", walletIDToString(id))
 		}
 	}
-	return nil, nil, "", errors.Errorf("failed to get wallet info for [%s:%s]", walletIDToString(walletID), walletIDToString(identityWID))
+	return nil, nil, "", nil, errors.Errorf("failed to get wallet info for [%s:%s]", walletIDToString(walletID), walletIDToString(identityWID))
 }
 
 // RegisterWallet binds the passed wallet to the passed id
 func (r *WalletsRegistry) RegisterWallet(id string, w driver.Wallet) {
 	r.Wallets[id] = &WalletEntry{
-		Prefix: fmt.Sprintf("%s-%s-%s-%s", r.ID.Network, r.ID.Channel, r.ID.Namespace, id),
+		Prefix: r.walletPrefix(id),
 		Wallet: w,
 	}
 }
@@ -120,12 +130,37 @@ func (r *WalletsRegistry) RegisterIdentity(identity view.Identity, wID string) e
 	if err := r.KVS.Put(idHash, wID); err != nil {
 		return err
 	}
-	if err := r.KVS.Put(r.Wallets[wID].Prefix+idHash, wID); err != nil {
+	if err := r.KVS.Put(r.walletPrefix(wID)+idHash, wID); err != nil {
+		return err
+	}
+	k, err := kvs.CreateCompositeKey("token-sdk", r.walletIdentitiesAttributes(wID, identity.UniqueID()))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create identity key for wallet [%s]", wID)
+	}
+	if err := r.KVS.Put(k, identity); err != nil {
 		return err
 	}
 	return nil
 }
 
+// WalletIdentities returns the identities bound to the passed wallet identifier
+func (r *WalletsRegistry) WalletIdentities(wID string) ([]view.Identity, error) {
+	it, err := r.KVS.GetByPartialCompositeID("token-sdk", r.walletIdentitiesAttributes(wID))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get identities of wallet [%s]", wID)
+	}
+	defer it.Close()
+	var identities []view.Identity
+	for it.HasNext() {
+		var identity view.Identity
+		if _, err := it.Next(&identity); err != nil {
+			return nil, errors.WithMessagef(err, "failed to get next identity of wallet [%s]", wID)
+		}
+		identities = append(identities, identity)
+	}
+	return identities, nil
+}
+
 // GetWallet returns the wallet identifier bound to the passed identity
 func (r *WalletsRegistry) GetWallet(identity view.Identity) (string, error) {
 	var wID string
@@ -141,7 +176,15 @@ func (r *WalletsRegistry) GetWallet(identity view.Identity) (string, error) {
 // ContainsIdentity returns true if the passed identity belongs to the passed wallet,
 // false otherwise
 func (r *WalletsRegistry) ContainsIdentity(identity view.Identity, wID string) bool {
-	return r.KVS.Exists(r.Wallets[wID].Prefix + identity.Hash())
+	return r.KVS.Exists(r.walletPrefix(wID) + identity.Hash())
+}
+
+func (r *WalletsRegistry) walletPrefix(wID string) string {
+	return fmt.Sprintf("%s-%s-%s-%s", r.ID.Network, r.ID.Channel, r.ID.Namespace, wID)
+}
+
+func (r *WalletsRegistry) walletIdentitiesAttributes(wID string, attrs ...string) []string {
+	return append([]string{"wallet", r.ID.Network, r.ID.Channel, r.ID.Namespace, strconv.Itoa(int(r.IdentityRole)), wID, "identity"}, attrs...)
 }
 
 func walletIDToString(w string) string {
@@ -33,4 +33,46 @@ func TestGetWallet(t *testing.T) {
 	wID, err := wr.GetWallet(alice)
 	assert.NoError(t, err)
 	assert.Equal(t, "hello", wID)
+
+	bob := view.Identity("bob")
+	assert.NoError(t, wr.RegisterIdentity(bob, "hello"))
+	ids, err := wr.WalletIdentities("hello")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []view.Identity{alice, bob}, ids)
+	ids, err = wr.WalletIdentities("world")
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+type recoveringProvider struct {
+	driver.IdentityProvider
+}
+
+func (p *recoveringProvider) LookupIdentifier(role driver.IdentityRole, v interface{}) (view.Identity, string, error) {
+	return v.(view.Identity), "hello", nil
+}
+
+func TestLookupRecovered(t *testing.T) {
+	cp := &mock.ConfigProvider{}
+	cp.IsSetReturns(false)
+	registry := registry2.New()
+	kvstore, err := kvs.NewWithConfig(registry, "memory", "_default", cp)
+	assert.NoError(t, err)
+
+	wr := NewWalletsRegistry(token.TMSID{Network: "testnetwork", Channel: "testchannel", Namespace: "tns"}, &recoveringProvider{}, driver.OwnerRole, kvstore)
+	wr.RegisterWallet("hello", nil)
+
+	// the lookup reports the recovered identity without binding it
+	alice := view.Identity("alice")
+	_, _, wID, recovered, err := wr.Lookup(alice)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", wID)
+	assert.Equal(t, alice, recovered)
+	assert.False(t, wr.ContainsIdentity(alice, "hello"))
+
+	// once bound, the identity is not reported anymore
+	assert.NoError(t, wr.RegisterIdentity(alice, "hello"))
+	_, _, _, recovered, err = wr.Lookup(alice)
+	assert.NoError(t, err)
+	assert.Nil(t, recovered)
 }
@@ -0,0 +1,386 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package offline
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/signer"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+const (
+	ProviderName = "offline"
+	// Version is the version of the format of the signature requests and of the signatures
+	Version = 2
+)
+
+var logger = flogging.MustGetLogger("token-sdk.core.identity.signer.offline")
+
+// ErrOffline is returned when a signature is requested to an offline signer.
+// The signature must be produced by the standalone offline signer and imported instead.
+var ErrOffline = errors.New("the secret key is offline, import a signature produced by the offline signer")
+
+// Signer is the signer of an identity whose secret key is kept on an offline machine. It cannot sign.
+type Signer struct{}
+
+// Sign returns ErrOffline
+func (s *Signer) Sign(message []byte) ([]byte, error) {
+	return nil, ErrOffline
+}
+
+// Provider creates offline signers. It takes no options.
+type Provider struct{}
+
+// NewProvider returns a new offline signer provider
+func NewProvider() *Provider {
+	return &Provider{}
+}
+
+// NewSigner returns an offline signer for the passed public key
+func (p *Provider) NewSigner(opts interface{}, pk *ecdsa.PublicKey) (driver.Signer, error) {
+	if pk == nil {
+		return nil, errors.New("no public key provided")
+	}
+	logger.Debugf("offline signer ready")
+	return &Signer{}, nil
+}
+
+func init() {
+	signer.Register(ProviderName, NewProvider())
+}
+
+// Token describes an input or an output of a token request, in the clear
+type Token struct {
+	// ID identifies the token, set only for the inputs
+	ID string `json:"id,omitempty"`
+	// Owner is the enrollment ID of the owner of the token
+	Owner    string `json:"owner"`
+	Type     string `json:"type"`
+	Quantity string `json:"quantity"`
+	// Metadata opens the output, set only for the outputs
+	Metadata []byte `json:"metadata,omitempty"`
+	// AuditInfo is the audit info of the owner of the output, set only for the outputs that are not redeemed
+	AuditInfo []byte `json:"audit_info,omitempty"`
+}
+
+// SignatureRequest is exported by the initiator of a token transaction to have the token request signed
+// by an identity whose secret key is offline
+type SignatureRequest struct {
+	Version   int    `json:"version"`
+	Network   string `json:"network"`
+	Channel   string `json:"channel"`
+	Namespace string `json:"namespace"`
+	TxID      string `json:"tx_id"`
+	// Signer is the identity whose signature is requested
+	Signer []byte `json:"signer"`
+	// Request is the token request, as marshalled to be signed
+	Request []byte `json:"request"`
+	// Digest is the SHA256 digest of the message to sign, the request followed by the transaction ID
+	Digest []byte `json:"digest"`
+	// PublicParameters are the public parameters of the token management service,
+	// used to open the outputs of the request. The signer accepts only the public parameters it trusts.
+	PublicParameters []byte `json:"public_parameters"`
+	// Inputs and Outputs describe, in the clear, the tokens the request spends and creates
+	Inputs  []*Token `json:"inputs,omitempty"`
+	Outputs []*Token `json:"outputs,omitempty"`
+	// Metadata is the metadata of the actions of the request, signed with them
+	Metadata map[string][]byte `json:"metadata,omitempty"`
+}
+
+// NewSignatureRequest returns a request for the signature of the passed signer on the passed token request
+func NewSignatureRequest(network, channel, namespace, txID string, signer []byte, request []byte) *SignatureRequest {
+	r := &SignatureRequest{
+		Version:   Version,
+		Network:   network,
+		Channel:   channel,
+		Namespace: namespace,
+		TxID:      txID,
+		Signer:    signer,
+		Request:   request,
+	}
+	r.Digest = r.digest()
+	return r
+}
+
+// MessageToSign returns the message the signer signs, the request followed by the transaction ID
+func (r *SignatureRequest) MessageToSign() []byte {
+	return MessageToSign(r.Request, r.TxID)
+}
+
+// Verify checks that the request is well-formed, that its digest matches the message to sign,
+// that its public parameters are the passed ones, that the verifier trusts,
+// and that the inputs, the outputs, and the metadata shown in the clear are those of the token request
+func (r *SignatureRequest) Verify(publicParameters []byte) error {
+	if r.Version != Version {
+		return errors.Errorf("unsupported version [%d], expected [%d]", r.Version, Version)
+	}
+	if len(r.TxID) == 0 {
+		return errors.New("no transaction id")
+	}
+	if len(r.Signer) == 0 {
+		return errors.New("no signer")
+	}
+	if len(r.Inputs) == 0 && len(r.Outputs) == 0 {
+		return errors.New("no inputs and outputs")
+	}
+	tr := &driver.TokenRequest{}
+	if err := tr.FromBytes(r.Request); err != nil {
+		return errors.Wrap(err, "failed unmarshalling token request")
+	}
+	if len(tr.Issues) == 0 && len(tr.Transfers) == 0 {
+		return errors.New("no actions in the token request")
+	}
+	if len(tr.Signatures) != 0 || len(tr.AuditorSignatures) != 0 {
+		return errors.New("the token request to sign must not carry signatures")
+	}
+	if !bytes.Equal(r.Digest, r.digest()) {
+		return errors.New("digest does not match the message to sign")
+	}
+	// the outputs are opened with the public parameters, then they cannot be taken from the request
+	if len(publicParameters) == 0 {
+		return errors.New("no trusted public parameters")
+	}
+	if !bytes.Equal(r.PublicParameters, publicParameters) {
+		return errors.New("the public parameters of the request do not match the trusted ones")
+	}
+	return r.verifyTokens()
+}
+
+// verifyTokens opens the outputs of the token request and checks that they are those shown in the clear.
+// The inputs are ledger keys that cannot be opened offline, then their identifiers are checked against the keys,
+// and their total quantity per type against the transfers of the request, which must balance.
+func (r *SignatureRequest) verifyTokens() error {
+	pp, err := core.PublicParametersFromBytes(r.PublicParameters)
+	if err != nil {
+		return errors.WithMessage(err, "failed loading public parameters")
+	}
+	validator, err := core.NewValidator(pp)
+	if err != nil {
+		return errors.WithMessage(err, "failed creating validator")
+	}
+	opener, ok := validator.(driver.OutputOpener)
+	if !ok {
+		return errors.Errorf("driver [%s] cannot open the outputs of a token request", pp.Identifier())
+	}
+	actions, err := validator.UnmarshalActions(r.Request)
+	if err != nil {
+		return errors.WithMessage(err, "failed unmarshalling actions")
+	}
+
+	var outputs [][]byte
+	var inputs []string
+	numIssued := 0
+	metadata := map[string][]byte{}
+	for _, action := range actions {
+		var serialized [][]byte
+		var actionMetadata map[string][]byte
+		switch a := action.(type) {
+		case driver.IssueAction:
+			serialized, err = a.GetSerializedOutputs()
+			numIssued += len(serialized)
+			actionMetadata = a.GetMetadata()
+		case driver.TransferAction:
+			var ids []string
+			ids, err = a.GetInputs()
+			if err != nil {
+				return errors.WithMessage(err, "failed getting inputs")
+			}
+			inputs = append(inputs, ids...)
+			serialized, err = a.GetSerializedOutputs()
+			actionMetadata = a.GetMetadata()
+		default:
+			return errors.Errorf("unknown action type [%T]", action)
+		}
+		if err != nil {
+			return errors.WithMessage(err, "failed getting outputs")
+		}
+		outputs = append(outputs, serialized...)
+		for k, v := range actionMetadata {
+			metadata[k] = v
+		}
+	}
+
+	if len(outputs) != len(r.Outputs) {
+		return errors.Errorf("the token request has [%d] outputs, [%d] shown", len(outputs), len(r.Outputs))
+	}
+	spent := map[string]*big.Int{}
+	for i, output := range outputs {
+		shown := r.Outputs[i]
+		tok, eID, err := opener.OpenOutput(output, shown.Metadata, shown.AuditInfo)
+		if err != nil {
+			return errors.WithMessagef(err, "failed opening output [%d]", i)
+		}
+		q, err := token2.ToQuantity(tok.Quantity, pp.Precision())
+		if err != nil {
+			return errors.WithMessagef(err, "invalid quantity of output [%d]", i)
+		}
+		if tok.Type != shown.Type || q.Decimal() != shown.Quantity || eID != shown.Owner {
+			return errors.Errorf("output [%d] is [%s:%s:%s], [%s:%s:%s] shown", i, eID, tok.Type, q.Decimal(), shown.Owner, shown.Type, shown.Quantity)
+		}
+		if i >= numIssued {
+			spent[tok.Type] = add(spent[tok.Type], q)
+		}
+	}
+
+	if len(inputs) != len(r.Inputs) {
+		return errors.Errorf("the token request has [%d] inputs, [%d] shown", len(inputs), len(r.Inputs))
+	}
+	available := map[string]*big.Int{}
+	for i, input := range r.Inputs {
+		if err := checkInputID(inputs[i], input.ID); err != nil {
+			return errors.WithMessagef(err, "invalid input [%d]", i)
+		}
+		q, err := token2.ToQuantity(input.Quantity, pp.Precision())
+		if err != nil {
+			return errors.WithMessagef(err, "invalid quantity of input [%d]", i)
+		}
+		available[input.Type] = add(available[input.Type], q)
+	}
+	if len(available) != len(spent) {
+		return errors.New("the inputs shown do not match the transfers of the token request")
+	}
+	for typ, q := range available {
+		if spent[typ] == nil || q.Cmp(spent[typ]) != 0 {
+			return errors.Errorf("the inputs shown do not match the transfers of the token request for type [%s]", typ)
+		}
+	}
+
+	if len(metadata) != len(r.Metadata) {
+		return errors.New("the metadata shown do not match those of the token request")
+	}
+	for k, v := range metadata {
+		if shown, ok := r.Metadata[k]; !ok || !bytes.Equal(shown, v) {
+			return errors.Errorf("the metadata [%s] shown do not match those of the token request", k)
+		}
+	}
+	return nil
+}
+
+// checkInputID checks that the passed identifier shown is the one of the token with the passed ledger key
+func checkInputID(key string, shown string) error {
+	if _, components, err := keys.SplitCompositeKey(key); err == nil && len(components) == 2 && components[0] == keys.SerialNumber {
+		return errors.New("the spent token is hidden and cannot be shown")
+	}
+	id, err := keys.GetTokenIdFromKey(key)
+	if err != nil {
+		return errors.Wrapf(err, "invalid token key [%s]", key)
+	}
+	if expected, err := keys.CreateTokenKey(id.TxId, id.Index); err != nil || expected != key {
+		return errors.Errorf("invalid token key [%s]", key)
+	}
+	if id.String() != shown {
+		return errors.Errorf("the token request spends %s, %s shown", id, shown)
+	}
+	return nil
+}
+
+// add returns the sum of the passed values, the passed sum might be nil
+func add(sum *big.Int, q token2.Quantity) *big.Int {
+	if sum == nil {
+		sum = big.NewInt(0)
+	}
+	return sum.Add(sum, q.ToBigInt())
+}
+
+func (r *SignatureRequest) digest() []byte {
+	h := sha256.Sum256(r.MessageToSign())
+	return h[:]
+}
+
+// Signature is the signature of an offline signer on a SignatureRequest
+type Signature struct {
+	Version int    `json:"version"`
+	TxID    string `json:"tx_id"`
+	Signer  []byte `json:"signer"`
+	// Digest is the digest of the signed message, as in the SignatureRequest
+	Digest    []byte `json:"digest"`
+	Signature []byte `json:"signature"`
+}
+
+// Sign verifies the passed request against the passed trusted public parameters and signs it with the passed signer
+func Sign(r *SignatureRequest, publicParameters []byte, s driver.Signer) (*Signature, error) {
+	if err := r.Verify(publicParameters); err != nil {
+		return nil, errors.WithMessage(err, "invalid signature request")
+	}
+	sigma, err := s.Sign(r.MessageToSign())
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed signing request")
+	}
+	return &Signature{
+		Version:   Version,
+		TxID:      r.TxID,
+		Signer:    r.Signer,
+		Digest:    r.Digest,
+		Signature: sigma,
+	}, nil
+}
+
+// MessageToSign returns the message signed by the issuers and the senders of a token request
+func MessageToSign(request []byte, txID string) []byte {
+	return append(append([]byte{}, request...), txID...)
+}
+
+// ReadSignatureRequest reads a signature request from the passed file
+func ReadSignatureRequest(path string) (*SignatureRequest, error) {
+	r := &SignatureRequest{}
+	if err := readJSON(path, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// WriteSignatureRequest writes the passed signature request to the passed file
+func WriteSignatureRequest(path string, r *SignatureRequest) error {
+	return writeJSON(path, r)
+}
+
+// ReadSignature reads a signature from the passed file
+func ReadSignature(path string) (*Signature, error) {
+	s := &Signature{}
+	if err := readJSON(path, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// WriteSignature writes the passed signature to the passed file
+func WriteSignature(path string, s *Signature) error {
+	return writeJSON(path, s)
+}
+
+func readJSON(path string, v interface{}) error {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed reading [%s]", path)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return errors.Wrapf(err, "failed unmarshalling [%s]", path)
+	}
+	return nil
+}
+
+func writeJSON(path string, v interface{}) error {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed marshalling")
+	}
+	if err := ioutil.WriteFile(path, raw, 0600); err != nil {
+		return errors.Wrapf(err, "failed writing [%s]", path)
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package offline
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/x509"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/signer"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDriverName = "offline-test"
+
+func init() {
+	core.Register(testDriverName, &testDriver{})
+}
+
+// newRequest returns a request to sign a token request that issues 10 USD to alice,
+// and transfers 10 USD of carol to bob, 7 USD, and to the redeem, 3 USD
+func newRequest(t *testing.T) *SignatureRequest {
+	issue := &testAction{
+		Outputs: [][]byte{testOutput(t, "alice", "USD", 10)},
+	}
+	transfer := &testAction{
+		Inputs:   []string{tokenKey(t, "tx0", 0), tokenKey(t, "tx0", 1)},
+		Outputs:  [][]byte{testOutput(t, "bob", "USD", 7), testOutput(t, "", "USD", 3)},
+		Metadata: map[string][]byte{"reference": []byte("invoice-42")},
+	}
+	rawIssue, err := json.Marshal(issue)
+	assert.NoError(t, err)
+	rawTransfer, err := json.Marshal(transfer)
+	assert.NoError(t, err)
+	raw, err := (&driver.TokenRequest{Issues: [][]byte{rawIssue}, Transfers: [][]byte{rawTransfer}}).Bytes()
+	assert.NoError(t, err)
+
+	r := NewSignatureRequest("network", "channel", "namespace", "tx1", []byte("issuer"), raw)
+	r.PublicParameters = testPP(t)
+	r.Inputs = []*Token{
+		{ID: "[tx0:0]", Owner: "carol", Type: "USD", Quantity: "4"},
+		{ID: "[tx0:1]", Owner: "carol", Type: "USD", Quantity: "6"},
+	}
+	r.Outputs = []*Token{
+		{Owner: "alice", Type: "USD", Quantity: "10", Metadata: testOpening(issue.Outputs[0]), AuditInfo: []byte("alice")},
+		{Owner: "bob", Type: "USD", Quantity: "7", Metadata: testOpening(transfer.Outputs[0]), AuditInfo: []byte("bob")},
+		{Type: "USD", Quantity: "3", Metadata: testOpening(transfer.Outputs[1])},
+	}
+	r.Metadata = map[string][]byte{"reference": []byte("invoice-42")}
+	return r
+}
+
+// testPP returns the public parameters of the test driver, trusted by the signer
+func testPP(t *testing.T) []byte {
+	raw, err := driver.Marshal(&driver.SerializedPublicParameters{Identifier: testDriverName})
+	assert.NoError(t, err)
+	return raw
+}
+
+func tokenKey(t *testing.T, txID string, index uint64) string {
+	key, err := keys.CreateTokenKey(txID, index)
+	assert.NoError(t, err)
+	return key
+}
+
+func TestSigner(t *testing.T) {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	s, err := signer.NewSigner(&config.Signer{Type: ProviderName}, &sk.PublicKey)
+	assert.NoError(t, err)
+	_, err = s.Sign([]byte("message"))
+	assert.Equal(t, ErrOffline, err)
+
+	_, err = NewProvider().NewSigner(nil, nil)
+	assert.Error(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	r := newRequest(t)
+	assert.NoError(t, r.Verify(testPP(t)))
+	assert.Equal(t, append(r.Request, "tx1"...), r.MessageToSign())
+
+	r = newRequest(t)
+	r.Version = 1
+	assert.EqualError(t, r.Verify(testPP(t)), "unsupported version [1], expected [2]")
+
+	r = newRequest(t)
+	r.Signer = nil
+	assert.EqualError(t, r.Verify(testPP(t)), "no signer")
+
+	r = newRequest(t)
+	r.Request = []byte("garbage")
+	assert.Contains(t, r.Verify(testPP(t)).Error(), "failed unmarshalling token request")
+
+	r = newRequest(t)
+	r.Request, _ = (&driver.TokenRequest{Transfers: [][]byte{[]byte("transfer")}, Signatures: [][]byte{[]byte("sigma")}}).Bytes()
+	assert.EqualError(t, r.Verify(testPP(t)), "the token request to sign must not carry signatures")
+
+	// the digest binds the transaction id
+	r = newRequest(t)
+	r.TxID = "tx2"
+	assert.EqualError(t, r.Verify(testPP(t)), "digest does not match the message to sign")
+
+	// the outputs are opened with the trusted public parameters, not with those chosen by the initiator
+	r = newRequest(t)
+	r.PublicParameters, _ = driver.Marshal(&driver.SerializedPublicParameters{Identifier: testDriverName, Raw: []byte("other")})
+	assert.EqualError(t, r.Verify(testPP(t)), "the public parameters of the request do not match the trusted ones")
+	assert.EqualError(t, r.Verify(nil), "no trusted public parameters")
+
+	// the outputs are opened with the driver of the public parameters
+	r = newRequest(t)
+	r.PublicParameters, _ = driver.Marshal(&driver.SerializedPublicParameters{Identifier: "unknown"})
+	assert.EqualError(t, r.Verify(r.PublicParameters), "failed loading public parameters: cannot load public paramenters, driver [unknown] not found")
+}
+
+func TestVerifyTamperedOutputs(t *testing.T) {
+	r := newRequest(t)
+	r.Outputs[1].Quantity = "70"
+	assert.EqualError(t, r.Verify(testPP(t)), "output [1] is [bob:USD:7], [bob:USD:70] shown")
+
+	r = newRequest(t)
+	r.Outputs[0].Type = "EUR"
+	assert.EqualError(t, r.Verify(testPP(t)), "output [0] is [alice:USD:10], [alice:EUR:10] shown")
+
+	// the enrollment id shown must be that of the audit info matching the owner
+	r = newRequest(t)
+	r.Outputs[1].Owner = "mallory"
+	assert.EqualError(t, r.Verify(testPP(t)), "output [1] is [bob:USD:7], [mallory:USD:7] shown")
+	r.Outputs[1].AuditInfo = []byte("mallory")
+	assert.EqualError(t, r.Verify(testPP(t)), "failed opening output [1]: the audit info does not match the owner")
+
+	r = newRequest(t)
+	r.Outputs[2].Metadata = testOpening([]byte("another output"))
+	assert.EqualError(t, r.Verify(testPP(t)), "failed opening output [2]: the metadata does not open the output")
+
+	r = newRequest(t)
+	r.Outputs = r.Outputs[:2]
+	assert.EqualError(t, r.Verify(testPP(t)), "the token request has [3] outputs, [2] shown")
+}
+
+func TestVerifyTamperedInputsAndMetadata(t *testing.T) {
+	r := newRequest(t)
+	r.Inputs = r.Inputs[:1]
+	assert.EqualError(t, r.Verify(testPP(t)), "the token request has [2] inputs, [1] shown")
+
+	// the inputs shown must be those spent by the token request, in the same order
+	r = newRequest(t)
+	r.Inputs[0].ID = "[tx0:5]"
+	assert.EqualError(t, r.Verify(testPP(t)), "invalid input [0]: the token request spends [tx0:0], [tx0:5] shown")
+	r = newRequest(t)
+	r.Inputs[0], r.Inputs[1] = r.Inputs[1], r.Inputs[0]
+	assert.EqualError(t, r.Verify(testPP(t)), "invalid input [0]: the token request spends [tx0:0], [tx0:1] shown")
+
+	r = newRequest(t)
+	r.Inputs[0].Quantity = "40"
+	assert.EqualError(t, r.Verify(testPP(t)), "the inputs shown do not match the transfers of the token request for type [USD]")
+
+	r = newRequest(t)
+	r.Inputs[0].Type = "EUR"
+	assert.EqualError(t, r.Verify(testPP(t)), "the inputs shown do not match the transfers of the token request")
+
+	r = newRequest(t)
+	r.Metadata["reference"] = []byte("invoice-43")
+	assert.EqualError(t, r.Verify(testPP(t)), "the metadata [reference] shown do not match those of the token request")
+
+	r = newRequest(t)
+	r.Metadata = nil
+	assert.EqualError(t, r.Verify(testPP(t)), "the metadata shown do not match those of the token request")
+}
+
+func TestSignAndFiles(t *testing.T) {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	dir := t.TempDir()
+
+	r := newRequest(t)
+	requestPath := filepath.Join(dir, "request.json")
+	assert.NoError(t, WriteSignatureRequest(requestPath, r))
+	r2, err := ReadSignatureRequest(requestPath)
+	assert.NoError(t, err)
+	assert.Equal(t, r, r2)
+
+	s, err := Sign(r2, testPP(t), x509.NewSignerFromKey(sk))
+	assert.NoError(t, err)
+	assert.Equal(t, "tx1", s.TxID)
+	assert.Equal(t, r.Digest, s.Digest)
+	assert.NoError(t, x509.NewVerifier(&sk.PublicKey).Verify(r.MessageToSign(), s.Signature))
+
+	signaturePath := filepath.Join(dir, "signature.json")
+	assert.NoError(t, WriteSignature(signaturePath, s))
+	s2, err := ReadSignature(signaturePath)
+	assert.NoError(t, err)
+	assert.Equal(t, s, s2)
+
+	// invalid requests are not signed
+	r2.Outputs[0].Quantity = "100"
+	_, err = Sign(r2, testPP(t), x509.NewSignerFromKey(sk))
+	assert.Error(t, err)
+	_, err = ReadSignature(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+// testAction is an issue or a transfer of the test driver, whose outputs are tokens in the clear.
+// The metadata opening an output is its hash, and the audit info of an owner is its enrollment id.
+type testAction struct {
+	Inputs   []string
+	Outputs  [][]byte
+	Metadata map[string][]byte
+}
+
+func testOutput(t *testing.T, owner, typ string, quantity uint64) []byte {
+	q, err := token2.UInt64ToQuantity(quantity, 64)
+	assert.NoError(t, err)
+	raw, err := json.Marshal(&token2.Token{Owner: &token2.Owner{Raw: []byte(owner)}, Type: typ, Quantity: q.Hex()})
+	assert.NoError(t, err)
+	return raw
+}
+
+func testOpening(output []byte) []byte {
+	h := sha256.Sum256(output)
+	return h[:]
+}
+
+type testDriver struct {
+	driver.Driver
+}
+
+func (d *testDriver) PublicParametersFromBytes(params []byte) (driver.PublicParameters, error) {
+	return &testPublicParameters{}, nil
+}
+
+func (d *testDriver) NewValidator(pp driver.PublicParameters) (driver.Validator, error) {
+	return &testValidator{}, nil
+}
+
+type testPublicParameters struct {
+	driver.PublicParameters
+}
+
+func (p *testPublicParameters) Identifier() string {
+	return testDriverName
+}
+
+func (p *testPublicParameters) Precision() uint64 {
+	return 64
+}
+
+type testValidator struct {
+	driver.Validator
+}
+
+func (v *testValidator) UnmarshalActions(raw []byte) ([]interface{}, error) {
+	tr := &driver.TokenRequest{}
+	if err := tr.FromBytes(raw); err != nil {
+		return nil, err
+	}
+	var actions []interface{}
+	for _, raw := range tr.Issues {
+		a := &testAction{}
+		if err := json.Unmarshal(raw, a); err != nil {
+			return nil, err
+		}
+		actions = append(actions, &testIssueAction{a: a})
+	}
+	for _, raw := range tr.Transfers {
+		a := &testAction{}
+		if err := json.Unmarshal(raw, a); err != nil {
+			return nil, err
+		}
+		actions = append(actions, &testTransferAction{a: a})
+	}
+	return actions, nil
+}
+
+func (v *testValidator) OpenOutput(output []byte, outputMetadata []byte, ownerAuditInfo []byte) (*token2.Token, string, error) {
+	if !bytes.Equal(outputMetadata, testOpening(output)) {
+		return nil, "", errors.New("the metadata does not open the output")
+	}
+	tok := &token2.Token{}
+	if err := json.Unmarshal(output, tok); err != nil {
+		return nil, "", err
+	}
+	if !bytes.Equal(tok.Owner.Raw, ownerAuditInfo) {
+		return nil, "", errors.New("the audit info does not match the owner")
+	}
+	return tok, string(ownerAuditInfo), nil
+}
+
+type testIssueAction struct {
+	driver.IssueAction
+	a *testAction
+}
+
+func (i *testIssueAction) GetSerializedOutputs() ([][]byte, error) {
+	return i.a.Outputs, nil
+}
+
+func (i *testIssueAction) GetMetadata() map[string][]byte {
+	return i.a.Metadata
+}
+
+type testTransferAction struct {
+	driver.TransferAction
+	a *testAction
+}
+
+func (t *testTransferAction) GetInputs() ([]string, error) {
+	return t.a.Inputs, nil
+}
+
+func (t *testTransferAction) GetSerializedOutputs() ([][]byte, error) {
+	return t.a.Outputs, nil
+}
+
+func (t *testTransferAction) GetMetadata() map[string][]byte {
+	return t.a.Metadata
+}
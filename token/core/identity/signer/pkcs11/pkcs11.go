@@ -0,0 +1,96 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pkcs11
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/config"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/x509"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/signer"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/pkg/errors"
+)
+
+const ProviderName = "pkcs11"
+
+var logger = flogging.MustGetLogger("token-sdk.core.identity.signer.pkcs11")
+
+// Signer signs with an ECDSA secret key that never leaves the HSM
+type Signer struct {
+	csp bccsp.BCCSP
+	key bccsp.Key
+}
+
+// Sign hashes the passed message with SHA256 and signs the digest inside the HSM.
+// The signature is in low-S ASN.1 DER format.
+func (s *Signer) Sign(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	sigma, err := s.csp.Sign(s.key, digest[:], nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed signing with pkcs11")
+	}
+	return sigma, nil
+}
+
+// Provider creates signers backed by a PKCS#11 token.
+// The options follow the PKCS11 section of the BCCSP configuration (Library, Label, Pin, Hash, Security, ...).
+type Provider struct {
+	lock sync.Mutex
+	csps map[string]bccsp.BCCSP
+}
+
+func NewProvider() *Provider {
+	return &Provider{csps: map[string]bccsp.BCCSP{}}
+}
+
+// NewSigner returns a signer for the HSM key matching the passed public key.
+func (p *Provider) NewSigner(opts interface{}, pk *ecdsa.PublicKey) (driver.Signer, error) {
+	p11Opts := &config.PKCS11{}
+	if err := signer.UnmarshalOpts(opts, p11Opts); err != nil {
+		return nil, errors.WithMessage(err, "failed to extract pkcs11 options")
+	}
+	csp, err := p.getCSP(p11Opts)
+	if err != nil {
+		return nil, err
+	}
+	ski := signer.SKI(pk)
+	key, err := csp.GetKey(ski)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find key [%x] in token [%s]", ski, p11Opts.Label)
+	}
+	if !key.Private() {
+		return nil, errors.Errorf("key [%x] in token [%s] is not a private key", ski, p11Opts.Label)
+	}
+	logger.Debugf("pkcs11 signer for key [%x] in token [%s] ready", ski, p11Opts.Label)
+	return &Signer{csp: csp, key: key}, nil
+}
+
+func (p *Provider) getCSP(opts *config.PKCS11) (bccsp.BCCSP, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	k := opts.Library + ":" + opts.Label
+	csp, ok := p.csps[k]
+	if ok {
+		return csp, nil
+	}
+	csp, _, err := x509.GetPKCS11BCCSP(&config.BCCSP{Default: "PKCS11", PKCS11: opts})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to initialize pkcs11 library [%s]", opts.Library)
+	}
+	p.csps[k] = csp
+	return csp, nil
+}
+
+func init() {
+	signer.Register(ProviderName, NewProvider())
+}
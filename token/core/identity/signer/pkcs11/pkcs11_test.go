@@ -0,0 +1,58 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pkcs11
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	pkcs112 "github.com/hyperledger-labs/fabric-smart-client/integration/nwo/common/pkcs11"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/x509"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/signer"
+	config2 "github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/hyperledger/fabric/bccsp"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSignWithSoftHSM requires SoftHSM, it is skipped if the library cannot be found.
+// The library location can be set with the PKCS11_LIB environment variable.
+func TestSignWithSoftHSM(t *testing.T) {
+	lib, pin, label, err := pkcs112.FindPKCS11Lib()
+	if err != nil {
+		t.Skipf("skipping, pkcs11 library not available [%s]", err)
+	}
+	opts := map[string]interface{}{
+		"Library":  lib,
+		"Pin":      pin,
+		"Label":    label,
+		"Hash":     "SHA2",
+		"Security": 256,
+	}
+
+	// generate a key inside the HSM
+	csp, _, err := pkcs112.GetBCCSP(&config.BCCSP{
+		Default: "PKCS11",
+		PKCS11:  &config.PKCS11{Library: lib, Pin: pin, Label: label, Hash: "SHA2", Security: 256},
+	})
+	assert.NoError(t, err)
+	key, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+	pub, err := key.PublicKey()
+	assert.NoError(t, err)
+	raw, err := pub.Bytes()
+	assert.NoError(t, err)
+	pk, err := pkcs112.DERToPublicKey(raw)
+	assert.NoError(t, err)
+
+	s, err := signer.NewSigner(&config2.Signer{Type: ProviderName, Opts: opts}, pk.(*ecdsa.PublicKey))
+	assert.NoError(t, err)
+	sigma, err := s.Sign([]byte("hello world"))
+	assert.NoError(t, err)
+	assert.NoError(t, x509.NewVerifier(pk.(*ecdsa.PublicKey)).Verify([]byte("hello world"), sigma))
+	assert.Error(t, x509.NewVerifier(pk.(*ecdsa.PublicKey)).Verify([]byte("hello world!"), sigma))
+}
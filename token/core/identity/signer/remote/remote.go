@@ -0,0 +1,161 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package remote
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	x5092 "github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/x509"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/signer"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
+)
+
+const (
+	ProviderName = "remote"
+	// SignPath is the HTTP path of the sign operation
+	SignPath = "/v1/sign"
+	// DefaultTimeout is the default timeout of a sign operation
+	DefaultTimeout = 30 * time.Second
+)
+
+var logger = flogging.MustGetLogger("token-sdk.core.identity.signer.remote")
+
+// SignRequest is sent by the client to ask for a signature on a digest
+type SignRequest struct {
+	// KeyID identifies the secret key to use
+	KeyID string `json:"key_id"`
+	// Digest is the SHA256 digest of the message to be signed
+	Digest []byte `json:"digest"`
+}
+
+// SignResponse carries the signature, in low-S ASN.1 DER format, back to the client
+type SignResponse struct {
+	Signature []byte `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Opts are the options of a remote signer
+type Opts struct {
+	// Endpoint is the base URL of the remote signing service, for example `https://signer.example.com:9443`
+	Endpoint string `yaml:"endpoint"`
+	// KeyID identifies the key at the remote signing service.
+	// If empty, the hex-encoded subject key identifier of the public key is used.
+	KeyID string `yaml:"keyId,omitempty"`
+	// Token, if set, is sent as bearer token to authenticate to the remote signing service
+	Token string `yaml:"token,omitempty"`
+	// TLSRootCertFile, if set, is the path of the PEM-encoded CA certificate used to verify the service
+	TLSRootCertFile string `yaml:"tlsRootCertFile,omitempty"`
+	// Timeout of a sign operation
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// Signer delegates signing to a remote signing service.
+// Each signature is checked against the expected public key before being returned.
+type Signer struct {
+	client   *http.Client
+	url      string
+	keyID    string
+	token    string
+	verifier driver.Verifier
+}
+
+// Sign hashes the passed message with SHA256 and asks the remote service to sign the digest
+func (s *Signer) Sign(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	raw, err := json.Marshal(&SignRequest{KeyID: s.keyID, Digest: digest[:]})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshalling sign request")
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(raw))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating sign request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(s.token) != 0 {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed calling remote signer [%s]", s.url)
+	}
+	defer resp.Body.Close()
+
+	response := &SignResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return nil, errors.Wrapf(err, "failed decoding response from remote signer, status [%d]", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("remote signer [%s] failed with status [%d]: [%s]", s.url, resp.StatusCode, response.Error)
+	}
+	if err := s.verifier.Verify(message, response.Signature); err != nil {
+		return nil, errors.WithMessagef(err, "invalid signature returned by remote signer for key [%s]", s.keyID)
+	}
+	return response.Signature, nil
+}
+
+// Provider creates signers that delegate to a remote signing service speaking the protocol served by Server
+type Provider struct{}
+
+func NewProvider() *Provider {
+	return &Provider{}
+}
+
+// NewSigner returns a signer for the remote key matching the passed public key
+func (p *Provider) NewSigner(opts interface{}, pk *ecdsa.PublicKey) (driver.Signer, error) {
+	o := &Opts{}
+	if err := signer.UnmarshalOpts(opts, o); err != nil {
+		return nil, errors.WithMessage(err, "failed to extract remote signer options")
+	}
+	if len(o.Endpoint) == 0 {
+		return nil, errors.New("remote signer endpoint not specified")
+	}
+	keyID := o.KeyID
+	if len(keyID) == 0 {
+		keyID = hex.EncodeToString(signer.SKI(pk))
+	}
+	timeout := o.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	if len(o.TLSRootCertFile) != 0 {
+		caRaw, err := ioutil.ReadFile(o.TLSRootCertFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed reading tls root certificate [%s]", o.TLSRootCertFile)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caRaw) {
+			return nil, errors.Errorf("no valid certificate found in [%s]", o.TLSRootCertFile)
+		}
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}}
+	}
+	logger.Debugf("remote signer for key [%s] at [%s] ready", keyID, o.Endpoint)
+
+	return &Signer{
+		client:   client,
+		url:      strings.TrimSuffix(o.Endpoint, "/") + SignPath,
+		keyID:    keyID,
+		token:    o.Token,
+		verifier: x5092.NewVerifier(pk),
+	}, nil
+}
+
+func init() {
+	signer.Register(ProviderName, NewProvider())
+}
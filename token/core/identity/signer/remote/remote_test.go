@@ -0,0 +1,85 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package remote
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/x509"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/signer"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteSigner(t *testing.T) {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	server := NewServer("secret")
+	server.AddKey(sk)
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	s, err := signer.NewSigner(&config.Signer{
+		Type: ProviderName,
+		Opts: map[string]interface{}{"endpoint": ts.URL, "token": "secret"},
+	}, &sk.PublicKey)
+	assert.NoError(t, err)
+
+	sigma, err := s.Sign([]byte("hello world"))
+	assert.NoError(t, err)
+	verifier := x509.NewVerifier(&sk.PublicKey)
+	assert.NoError(t, verifier.Verify([]byte("hello world"), sigma))
+	assert.Error(t, verifier.Verify([]byte("hello world!"), sigma))
+}
+
+func TestRemoteSignerFailures(t *testing.T) {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	server := NewServer("secret")
+	keyID := server.AddKey(sk)
+	// a key id pointing to a key that does not match the expected public key
+	server.AddKeyWithID("wrong", other)
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	// wrong token
+	s, err := NewProvider().NewSigner(map[string]interface{}{"endpoint": ts.URL, "token": "guess"}, &sk.PublicKey)
+	assert.NoError(t, err)
+	_, err = s.Sign([]byte("hello world"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unauthorized")
+
+	// unknown key
+	s, err = NewProvider().NewSigner(map[string]interface{}{"endpoint": ts.URL, "token": "secret"}, &other.PublicKey)
+	assert.NoError(t, err)
+	_, err = s.Sign([]byte("hello world"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "key not found")
+
+	// signature does not verify under the expected public key
+	s, err = NewProvider().NewSigner(map[string]interface{}{"endpoint": ts.URL, "token": "secret", "keyId": "wrong"}, &sk.PublicKey)
+	assert.NoError(t, err)
+	_, err = s.Sign([]byte("hello world"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid signature")
+
+	// explicit key id
+	s, err = NewProvider().NewSigner(map[string]interface{}{"endpoint": ts.URL, "token": "secret", "keyId": keyID}, &sk.PublicKey)
+	assert.NoError(t, err)
+	_, err = s.Sign([]byte("hello world"))
+	assert.NoError(t, err)
+
+	// missing endpoint
+	_, err = NewProvider().NewSigner(map[string]interface{}{"token": "secret"}, &sk.PublicKey)
+	assert.Error(t, err)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package remote
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/x509"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/signer"
+	"github.com/hyperledger/fabric/bccsp/utils"
+)
+
+// Server is a reference implementation of a remote signing service.
+// It keeps ECDSA secret keys in memory and it is meant for testing and as a blueprint for production services.
+type Server struct {
+	token string
+
+	lock sync.RWMutex
+	keys map[string]*ecdsa.PrivateKey
+}
+
+// NewServer returns a new Server. If token is not empty, clients must present it as bearer token.
+func NewServer(token string) *Server {
+	return &Server{
+		token: token,
+		keys:  map[string]*ecdsa.PrivateKey{},
+	}
+}
+
+// AddKey registers the passed secret key and returns its key identifier,
+// namely the hex-encoded subject key identifier of the corresponding public key.
+func (s *Server) AddKey(sk *ecdsa.PrivateKey) string {
+	keyID := hex.EncodeToString(signer.SKI(&sk.PublicKey))
+	s.AddKeyWithID(keyID, sk)
+	return keyID
+}
+
+// AddKeyWithID registers the passed secret key under the passed key identifier
+func (s *Server) AddKeyWithID(keyID string, sk *ecdsa.PrivateKey) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.keys[keyID] = sk
+}
+
+// Handler returns the HTTP handler serving the signing protocol
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(SignPath, s.sign)
+	return mux
+}
+
+func (s *Server) sign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.reply(w, http.StatusMethodNotAllowed, &SignResponse{Error: "method not allowed"})
+		return
+	}
+	if len(s.token) != 0 {
+		expected := []byte("Bearer " + s.token)
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			s.reply(w, http.StatusUnauthorized, &SignResponse{Error: "unauthorized"})
+			return
+		}
+	}
+
+	request := &SignRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		s.reply(w, http.StatusBadRequest, &SignResponse{Error: "invalid request"})
+		return
+	}
+	if len(request.Digest) != sha256.Size {
+		s.reply(w, http.StatusBadRequest, &SignResponse{Error: "invalid digest length"})
+		return
+	}
+	s.lock.RLock()
+	sk, ok := s.keys[request.KeyID]
+	s.lock.RUnlock()
+	if !ok {
+		s.reply(w, http.StatusNotFound, &SignResponse{Error: "key not found"})
+		return
+	}
+
+	r2, s2, err := ecdsa.Sign(rand.Reader, sk, request.Digest)
+	if err != nil {
+		logger.Errorf("failed signing with key [%s]: [%s]", request.KeyID, err)
+		s.reply(w, http.StatusInternalServerError, &SignResponse{Error: "failed signing"})
+		return
+	}
+	s2, _, err = x509.ToLowS(&sk.PublicKey, s2)
+	if err != nil {
+		s.reply(w, http.StatusInternalServerError, &SignResponse{Error: "failed signing"})
+		return
+	}
+	sigma, err := utils.MarshalECDSASignature(r2, s2)
+	if err != nil {
+		s.reply(w, http.StatusInternalServerError, &SignResponse{Error: "failed marshalling signature"})
+		return
+	}
+	s.reply(w, http.StatusOK, &SignResponse{Signature: sigma})
+}
+
+func (s *Server) reply(w http.ResponseWriter, status int, response *SignResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Errorf("failed writing response: [%s]", err)
+	}
+}
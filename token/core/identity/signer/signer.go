@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"sort"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Provider creates signers whose secret keys are kept outside the token-sdk,
+// for example in an HSM or in a remote signing service.
+type Provider interface {
+	// NewSigner returns a signer for the secret key matching the passed public key.
+	// The passed options are provider-specific.
+	NewSigner(opts interface{}, pk *ecdsa.PublicKey) (driver.Signer, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]Provider)
+)
+
+// Register makes a signer provider available by the provided name.
+// If Register is called twice with the same name or if provider is nil,
+// it panics.
+func Register(name string, provider Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if provider == nil {
+		panic("Register provider is nil")
+	}
+	if _, dup := providers[name]; dup {
+		panic("Register called twice for provider " + name)
+	}
+	providers[name] = provider
+}
+
+// Providers returns a sorted list of the names of the registered providers.
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	list := make([]string, 0, len(providers))
+	for name := range providers {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// NewSigner returns a signer for the passed public key using the provider selected by the passed configuration
+func NewSigner(c *config.Signer, pk *ecdsa.PublicKey) (driver.Signer, error) {
+	if c == nil {
+		return nil, errors.New("no signer configuration provided")
+	}
+	providersMu.RLock()
+	provider, ok := providers[c.Type]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("signer provider [%s] not found, available [%v]", c.Type, Providers())
+	}
+	s, err := provider.NewSigner(c.Opts, pk)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed creating signer with provider [%s]", c.Type)
+	}
+	return s, nil
+}
+
+// UnmarshalOpts converts the passed provider-specific options to the passed structure
+func UnmarshalOpts(opts interface{}, out interface{}) error {
+	if opts == nil {
+		return errors.New("no options provided")
+	}
+	raw, err := yaml.Marshal(opts)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal [%v]", opts)
+	}
+	if err := yaml.Unmarshal(raw, out); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal [%v]", opts)
+	}
+	return nil
+}
+
+// SKI returns the subject key identifier of the passed public key, computed as the SHA256 hash of the
+// uncompressed encoding of the public key. This is the same identifier used by the Fabric BCCSP.
+func SKI(pk *ecdsa.PublicKey) []byte {
+	raw := elliptic.Marshal(pk.Curve, pk.X, pk.Y)
+	hash := sha256.Sum256(raw)
+	return hash[:]
+}
@@ -9,6 +9,7 @@ package audit_test
 import (
 	"encoding/json"
 	"io/ioutil"
+	"math/big"
 	"time"
 
 	math "github.com/IBM/mathlib"
@@ -18,6 +19,7 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
 	registry2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/curvetest"
 	msp2 "github.com/hyperledger/fabric/msp"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -124,6 +126,32 @@ var _ = Describe("Auditor", func() {
 	})
 })
 
+var _ = DescribeTable("Auditor on every supported curve",
+	func(curveID math.CurveID) {
+		fakeSigningIdentity := &mock.SigningIdentity{}
+		ipk, err := ioutil.ReadFile("./testdata/idemix/msp/IssuerPublicKey")
+		Expect(err).NotTo(HaveOccurred())
+		pp, err := crypto.SetupWithCurves(100, 2, ipk, crypto.DLogPublicParameters, curveID, math.FP256BN_AMCL)
+		Expect(err).NotTo(HaveOccurred())
+		des, err := idemix2.NewDeserializer(pp.IdemixIssuerPK)
+		Expect(err).NotTo(HaveOccurred())
+		auditor := audit.NewAuditor(&deserializer{idemix: des}, pp.PedParams, nil, fakeSigningIdentity, math.Curves[pp.Curve])
+
+		transfer, metadata, tokens := createTransfer(pp)
+		raw, err := transfer.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		err = auditor.Check(&driver.TokenRequest{Transfers: [][]byte{raw}}, &driver.TokenRequestMetadata{Transfers: []driver.TransferMetadata{metadata}}, tokens, "1")
+		Expect(err).NotTo(HaveOccurred())
+
+		transfer, metadata, tokens = createTransferWithBogusOutput(pp)
+		raw, err = transfer.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		err = auditor.Check(&driver.TokenRequest{Transfers: [][]byte{raw}}, &driver.TokenRequestMetadata{Transfers: []driver.TransferMetadata{metadata}}, tokens, "1")
+		Expect(err).To(HaveOccurred())
+	},
+	curvetest.Entries(),
+)
+
 func createTransfer(pp *crypto.PublicParams) (*transfer2.TransferAction, driver.TransferMetadata, [][]*token.Token) {
 	id, auditInfo := getIdemixInfo("./testdata/idemix")
 	transfer, inf, inputs := prepareTransfer(pp, id)
@@ -319,7 +347,7 @@ func prepareTransfer(pp *crypto.PublicParams, id view.Identity) (*transfer2.Tran
 	fakeSigner := &mock.SigningIdentity{}
 	sender, err := transfer2.NewSender([]driver.Signer{fakeSigner, fakeSigner}, inputs, []string{"0", "1"}, tokenInfos, pp)
 	Expect(err).NotTo(HaveOccurred())
-	transfer, inf, err := sender.GenerateZKTransfer([]uint64{40, 20}, [][]byte{id, id})
+	transfer, inf, err := sender.GenerateZKTransfer([]*big.Int{big.NewInt(40), big.NewInt(20)}, [][]byte{id, id})
 	Expect(err).NotTo(HaveOccurred())
 
 	return transfer, inf, inputs
@@ -0,0 +1,41 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/elgamal"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// OpenFromLedger returns the passed serialized output in the clear, decrypting its type and value with the
+// encryption key of the auditor, without any metadata from the transacting parties.
+// The type is recovered by matching against the passed candidate types.
+// The output must have been created under public parameters with audit encryption enabled for the key.
+func OpenFromLedger(raw []byte, sk *elgamal.SecretKey, types []string, pp *crypto.PublicParams) (*token2.Token, error) {
+	output := &token.Token{}
+	if err := output.Deserialize(raw); err != nil {
+		return nil, errors.Wrap(err, "failed to deserialize output")
+	}
+	if output.Audit == nil {
+		return nil, errors.New("output is not encrypted for the auditor")
+	}
+	if err := output.Audit.Verify(output.Data, pp); err != nil {
+		return nil, err
+	}
+	ttype, value, err := output.Audit.Decrypt(sk, types, pp)
+	if err != nil {
+		return nil, err
+	}
+	return &token2.Token{
+		Type:     ttype,
+		Quantity: "0x" + value.String(),
+		Owner:    &token2.Owner{Raw: output.Owner},
+	}, nil
+}
@@ -0,0 +1,202 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package batch
+
+import (
+	"io"
+	"math/big"
+	"math/bits"
+
+	math "github.com/IBM/mathlib"
+	"github.com/pkg/errors"
+)
+
+// Equation is a multi-exponentiation \prod_k Bases[k]^{Exponents[k]} that is expected to be the identity
+type Equation struct {
+	Bases     []*math.G1
+	Exponents []*math.Zr
+}
+
+// NewEquation returns an empty Equation with room for the passed number of terms
+func NewEquation(size int) *Equation {
+	return &Equation{
+		Bases:     make([]*math.G1, 0, size),
+		Exponents: make([]*math.Zr, 0, size),
+	}
+}
+
+// Add appends the term base^exponent to the Equation
+func (e *Equation) Add(base *math.G1, exponent *math.Zr) {
+	e.Bases = append(e.Bases, base)
+	e.Exponents = append(e.Exponents, exponent)
+}
+
+// Holds returns true if the Equation evaluates to the identity
+func (e *Equation) Holds(curve *math.Curve) bool {
+	return MultiExp(curve, e.Bases, e.Exponents).IsInfinity()
+}
+
+// Verifier checks many Equations at once.
+// Each Equation is weighted by a fresh random scalar, so that a combination of Equations
+// that do not all hold evaluates to the identity only with negligible probability.
+// The terms of all the Equations are merged by base, then bases shared across Equations,
+// such as the Pedersen and the Bulletproofs generators, cost a single exponentiation.
+type Verifier struct {
+	curve     *math.Curve
+	rand      io.Reader
+	index     map[string]int
+	bases     []*math.G1
+	exponents []*math.Zr
+	size      int
+}
+
+// NewVerifier returns an empty Verifier over the passed curve
+func NewVerifier(curve *math.Curve) (*Verifier, error) {
+	if curve == nil {
+		return nil, errors.New("cannot create batch verifier: please initialize curve")
+	}
+	rand, err := curve.Rand()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create batch verifier")
+	}
+	return &Verifier{curve: curve, rand: rand, index: map[string]int{}}, nil
+}
+
+// Add adds the passed Equations to the batch
+func (v *Verifier) Add(equations ...*Equation) error {
+	for _, e := range equations {
+		if e == nil || len(e.Bases) != len(e.Exponents) {
+			return errors.New("cannot add equation to batch: malformed equation")
+		}
+		for i, base := range e.Bases {
+			if base == nil || e.Exponents[i] == nil {
+				return errors.Errorf("cannot add equation to batch: nil term at index [%d]", i)
+			}
+		}
+	}
+	for _, e := range equations {
+		weight := v.curve.NewRandomZr(v.rand)
+		for i, base := range e.Bases {
+			v.add(base, v.curve.ModMul(weight, e.Exponents[i], v.curve.GroupOrder))
+		}
+		v.size++
+	}
+	return nil
+}
+
+// Merge adds the Equations collected by the passed Verifier to this batch
+func (v *Verifier) Merge(other *Verifier) {
+	for i, base := range other.bases {
+		v.add(base, other.exponents[i])
+	}
+	v.size += other.size
+}
+
+// Len returns the number of Equations in the batch
+func (v *Verifier) Len() int {
+	return v.size
+}
+
+// Check returns an error if any of the Equations in the batch does not hold
+func (v *Verifier) Check() error {
+	if !MultiExp(v.curve, v.bases, v.exponents).IsInfinity() {
+		return errors.Errorf("batch verification of [%d] equations failed", v.size)
+	}
+	return nil
+}
+
+func (v *Verifier) add(base *math.G1, exponent *math.Zr) {
+	key := string(base.Bytes())
+	if i, ok := v.index[key]; ok {
+		v.exponents[i] = v.curve.ModAdd(v.exponents[i], exponent, v.curve.GroupOrder)
+		return
+	}
+	v.index[key] = len(v.bases)
+	v.bases = append(v.bases, base)
+	v.exponents = append(v.exponents, exponent)
+}
+
+// MultiExp returns \prod_i bases[i]^{exponents[i]}.
+// It uses the bucket method: the exponents are split in windows of c bits and, for each window,
+// the bases are summed up in the bucket of their digit, then the buckets are combined with running sums.
+// A window costs about len(bases) + 2^{c+1} additions, instead of an exponentiation per base.
+func MultiExp(curve *math.Curve, bases []*math.G1, exponents []*math.Zr) *math.G1 {
+	res := curve.NewG1()
+	if len(bases) < multiExpThreshold {
+		for i, base := range bases {
+			res.Add(base.Mul(exponents[i]))
+		}
+		return res
+	}
+
+	scalars := make([]*big.Int, len(exponents))
+	maxBits := 0
+	for i, e := range exponents {
+		// reduce first, the exponents might be represented by negative integers
+		reduced := e.Copy()
+		reduced.Mod(curve.GroupOrder)
+		scalars[i] = new(big.Int).SetBytes(reduced.Bytes())
+		if scalars[i].BitLen() > maxBits {
+			maxBits = scalars[i].BitLen()
+		}
+	}
+	c := windowSize(len(bases))
+	buckets := make([]*math.G1, 1<<c)
+	for w := (maxBits+c-1)/c - 1; w >= 0; w-- {
+		for k := 0; k < c; k++ {
+			res.Add(res.Copy())
+		}
+		for d := range buckets {
+			buckets[d] = nil
+		}
+		for i, s := range scalars {
+			d := digit(s, w*c, c)
+			if d == 0 {
+				continue
+			}
+			if buckets[d] == nil {
+				buckets[d] = bases[i].Copy()
+				continue
+			}
+			buckets[d].Add(bases[i])
+		}
+		// \sum_d d \cdot buckets[d] = \sum_d running_d, where running_d = \sum_{d' >= d} buckets[d']
+		running, sum := curve.NewG1(), curve.NewG1()
+		for d := len(buckets) - 1; d > 0; d-- {
+			if buckets[d] != nil {
+				running.Add(buckets[d])
+			}
+			sum.Add(running)
+		}
+		res.Add(sum)
+	}
+	return res
+}
+
+// multiExpThreshold is the number of bases below which the exponentiations are computed one by one
+const multiExpThreshold = 8
+
+// windowSize returns the number of bits of the windows of the bucket method for the passed number of bases
+func windowSize(n int) int {
+	c := bits.Len(uint(n)) - 2
+	if c < 2 {
+		return 2
+	}
+	if c > 16 {
+		return 16
+	}
+	return c
+}
+
+// digit returns the c bits of s starting from the passed offset
+func digit(s *big.Int, offset, c int) int {
+	d := 0
+	for k := c - 1; k >= 0; k-- {
+		d = d<<1 | int(s.Bit(offset+k))
+	}
+	return d
+}
@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package batch_test
+
+import (
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Batch verification", func() {
+	var (
+		c        *math.Curve
+		g, h     *math.G1
+		verifier *batch.Verifier
+	)
+	BeforeEach(func() {
+		var err error
+		c = math.Curves[math.BN254]
+		g = c.HashToG1([]byte("g"))
+		h = c.HashToG1([]byte("h"))
+		verifier, err = batch.NewVerifier(c)
+		Expect(err).NotTo(HaveOccurred())
+	})
+	Context("when all the equations hold", func() {
+		It("succeeds", func() {
+			for i := int64(1); i <= 3; i++ {
+				Expect(verifier.Add(equation(c, g, h, i, i))).To(Succeed())
+			}
+			Expect(verifier.Len()).To(Equal(3))
+			Expect(verifier.Check()).To(Succeed())
+		})
+	})
+	Context("when an equation does not hold", func() {
+		It("fails", func() {
+			Expect(verifier.Add(equation(c, g, h, 1, 1), equation(c, g, h, 2, 3))).To(Succeed())
+			Expect(equation(c, g, h, 2, 3).Holds(c)).To(BeFalse())
+			err := verifier.Check()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("batch verification of [2] equations failed"))
+		})
+	})
+	Context("when batches are merged", func() {
+		It("checks the equations of both", func() {
+			other, err := batch.NewVerifier(c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(other.Add(equation(c, g, h, 2, 2))).To(Succeed())
+			Expect(verifier.Add(equation(c, g, h, 1, 1))).To(Succeed())
+			verifier.Merge(other)
+			Expect(verifier.Len()).To(Equal(2))
+			Expect(verifier.Check()).To(Succeed())
+
+			invalid, err := batch.NewVerifier(c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(invalid.Add(equation(c, g, h, 1, 2))).To(Succeed())
+			verifier.Merge(invalid)
+			Expect(verifier.Check()).NotTo(Succeed())
+		})
+	})
+	Context("when an equation is malformed", func() {
+		It("fails", func() {
+			Expect(verifier.Add(&batch.Equation{Bases: []*math.G1{g}})).NotTo(Succeed())
+			Expect(verifier.Len()).To(Equal(0))
+		})
+	})
+})
+
+// equation returns the equation (g^a \cdot h)^x \cdot (g^b \cdot h)^{-x} = 1, that holds if and only if a = b
+func equation(c *math.Curve, g, h *math.G1, a, b int64) *batch.Equation {
+	x := c.NewZrFromInt(7)
+	left := g.Mul(c.NewZrFromInt(a))
+	left.Add(h)
+	right := g.Mul(c.NewZrFromInt(b))
+	right.Add(h)
+	e := batch.NewEquation(2)
+	e.Add(left, x)
+	e.Add(right, c.ModNeg(x, c.GroupOrder))
+	return e
+}
+
+var _ = Describe("Multi-exponentiation", func() {
+	It("matches the exponentiations one by one", func() {
+		for _, curveID := range []math.CurveID{math.BN254, math.FP256BN_AMCL} {
+			c := math.Curves[curveID]
+			rand, err := c.Rand()
+			Expect(err).NotTo(HaveOccurred())
+			for _, n := range []int{0, 1, 7, 8, 33, 130} {
+				bases := make([]*math.G1, n)
+				exponents := make([]*math.Zr, n)
+				expected := c.NewG1()
+				for i := range bases {
+					bases[i] = c.GenG1.Mul(c.NewRandomZr(rand))
+					exponents[i] = c.NewRandomZr(rand)
+					if i%5 == 0 {
+						// repeated bases and small exponents
+						bases[i] = c.GenG1
+						exponents[i] = c.NewZrFromInt(int64(i))
+					}
+					expected.Add(bases[i].Mul(exponents[i]))
+				}
+				Expect(batch.MultiExp(c, bases, exponents).Equals(expected)).To(BeTrue())
+			}
+		}
+	})
+})
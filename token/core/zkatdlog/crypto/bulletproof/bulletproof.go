@@ -0,0 +1,285 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bulletproof
+
+import (
+	"encoding/json"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/pkg/errors"
+)
+
+// MaxBitLength is the maximum number of bits of the values proven in range
+const MaxBitLength = 128
+
+// Proof shows that the values of an array of tokens are in [0, 2^BitLength).
+// Tokens are of the form G_0^type \cdot G_1^value \cdot G_2^bf, where (G_0, G_1, G_2) are the Pedersen parameters
+type Proof struct {
+	// ValueCommitments commit to the values of the tokens as G_1^value \cdot G_2^gamma
+	ValueCommitments []*math.G1
+	// Equality shows that the tokens and the value commitments commit to the same values,
+	// and that the tokens have the same type
+	Equality *EqualityProof
+	// Range shows that the values in the value commitments are in range
+	Range *RangeProof
+}
+
+// EqualityProof shows that the tokens and the value commitments commit to the same values
+type EqualityProof struct {
+	// Challenge is the challenge of the proof
+	Challenge *math.Zr
+	// Type is the proof of knowledge of the type of the tokens
+	Type *math.Zr
+	// Values are the proofs of knowledge of the values of the tokens
+	Values []*math.Zr
+	// TokenBlindingFactors are the proofs of knowledge of the blinding factors of the tokens
+	TokenBlindingFactors []*math.Zr
+	// ValueBlindingFactors are the proofs of knowledge of the blinding factors of the value commitments
+	ValueBlindingFactors []*math.Zr
+}
+
+// Serialize marshals Proof
+func (p *Proof) Serialize() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// Deserialize un-marshals Proof
+func (p *Proof) Deserialize(raw []byte) error {
+	return json.Unmarshal(raw, p)
+}
+
+// Verifier checks the validity of Bulletproofs range proofs
+type Verifier struct {
+	// Tokens are the commitments to the type and value of the tokens
+	Tokens []*math.G1
+	// BitLength is the number of bits of the values
+	BitLength int
+	// PedersenParams corresponds to the Pedersen commitment generators
+	PedersenParams []*math.G1
+	// Curve is an elliptic curve
+	Curve *math.Curve
+}
+
+// Prover produces Bulletproofs range proofs
+type Prover struct {
+	*Verifier
+	// tokenType is the type of the tokens
+	tokenType string
+	// values are the values of the tokens
+	values []*math.Zr
+	// blindingFactors are the blinding factors of the tokens
+	blindingFactors []*math.Zr
+	// WorkerPool bounds the goroutines used to generate the proof.
+	// If nil, as many goroutines as the available CPUs are used.
+	WorkerPool *common.WorkerPool
+}
+
+// NewVerifier returns a Verifier for the passed tokens
+func NewVerifier(tokens []*math.G1, bitLength int, pp []*math.G1, c *math.Curve) *Verifier {
+	return &Verifier{
+		Tokens:         tokens,
+		BitLength:      bitLength,
+		PedersenParams: pp,
+		Curve:          c,
+	}
+}
+
+// NewProver returns a Prover for the passed tokens, whose openings are the passed type, values and blinding factors
+func NewProver(tokenType string, values, blindingFactors []*math.Zr, tokens []*math.G1, bitLength int, pp []*math.G1, c *math.Curve) *Prover {
+	return &Prover{
+		Verifier:        NewVerifier(tokens, bitLength, pp, c),
+		tokenType:       tokenType,
+		values:          values,
+		blindingFactors: blindingFactors,
+	}
+}
+
+// Prove produces a serialized Proof
+func (p *Prover) Prove() ([]byte, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	if len(p.values) != len(p.Tokens) || len(p.blindingFactors) != len(p.Tokens) {
+		return nil, errors.Errorf("cannot generate range proof: expected [%d] token witnesses, got [%d]", len(p.Tokens), len(p.values))
+	}
+	c := p.Curve
+	order := c.GroupOrder
+	rand, err := c.Rand()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot generate range proof")
+	}
+
+	// commit to the values only
+	values := p.values
+	gammas := make([]*math.Zr, len(values))
+	commitments := make([]*math.G1, len(values))
+	for i := range values {
+		if values[i] == nil || p.blindingFactors[i] == nil {
+			return nil, errors.Errorf("cannot generate range proof: invalid token witness at index [%d]", i)
+		}
+		gammas[i] = c.NewRandomZr(rand)
+	}
+	_ = p.WorkerPool.Run(len(values), func(i int) error {
+		commitments[i] = p.PedersenParams[1].Mul2(values[i], p.PedersenParams[2], gammas[i])
+		return nil
+	})
+
+	// show that the tokens and the value commitments open to the same values
+	typ := c.HashToZr([]byte(p.tokenType))
+	rType := c.NewRandomZr(rand)
+	rValues := make([]*math.Zr, len(values))
+	rTokenBFs := make([]*math.Zr, len(values))
+	rValueBFs := make([]*math.Zr, len(values))
+	tokenCommitments := make([]*math.G1, len(values))
+	valueCommitments := make([]*math.G1, len(values))
+	for i := range values {
+		rValues[i] = c.NewRandomZr(rand)
+		rTokenBFs[i] = c.NewRandomZr(rand)
+		rValueBFs[i] = c.NewRandomZr(rand)
+	}
+	err = p.WorkerPool.Run(len(values), func(i int) error {
+		var err error
+		tokenCommitments[i], err = common.ComputePedersenCommitment([]*math.Zr{rType, rValues[i], rTokenBFs[i]}, p.PedersenParams, c)
+		if err != nil {
+			return err
+		}
+		valueCommitments[i] = p.PedersenParams[1].Mul2(rValues[i], p.PedersenParams[2], rValueBFs[i])
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot generate range proof")
+	}
+	t := p.transcript(commitments)
+	t.appendG1s(tokenCommitments)
+	t.appendG1s(valueCommitments)
+	chal := t.challenge()
+
+	equality := &EqualityProof{
+		Challenge:            chal,
+		Type:                 c.ModAdd(rType, c.ModMul(chal, typ, order), order),
+		Values:               make([]*math.Zr, len(values)),
+		TokenBlindingFactors: make([]*math.Zr, len(values)),
+		ValueBlindingFactors: make([]*math.Zr, len(values)),
+	}
+	for i := range values {
+		equality.Values[i] = c.ModAdd(rValues[i], c.ModMul(chal, values[i], order), order)
+		equality.TokenBlindingFactors[i] = c.ModAdd(rTokenBFs[i], c.ModMul(chal, p.blindingFactors[i], order), order)
+		equality.ValueBlindingFactors[i] = c.ModAdd(rValueBFs[i], c.ModMul(chal, gammas[i], order), order)
+	}
+
+	rp, err := proveRange(commitments, values, gammas, p.BitLength, p.PedersenParams[1], p.PedersenParams[2], t, p.WorkerPool, c)
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot generate range proof")
+	}
+
+	proof := &Proof{
+		ValueCommitments: commitments,
+		Equality:         equality,
+		Range:            rp,
+	}
+	return proof.Serialize()
+}
+
+// Verify checks the validity of the passed serialized Proof
+func (v *Verifier) Verify(raw []byte) error {
+	proof, t, err := v.verifyEquality(raw)
+	if err != nil {
+		return err
+	}
+	return verifyRange(proof.ValueCommitments, proof.Range, v.BitLength, v.PedersenParams[1], v.PedersenParams[2], t, v.Curve)
+}
+
+// VerifyBatch checks the validity of the passed serialized Proof, deferring to the passed batch
+// the checks of the range proof that are expressed as multi-exponentiations.
+// The proof is valid only if the batch verification succeeds too.
+func (v *Verifier) VerifyBatch(raw []byte, b *batch.Verifier) error {
+	proof, t, err := v.verifyEquality(raw)
+	if err != nil {
+		return err
+	}
+	polynomial, ipa, err := rangeEquations(proof.ValueCommitments, proof.Range, v.BitLength, v.PedersenParams[1], v.PedersenParams[2], t, v.Curve)
+	if err != nil {
+		return err
+	}
+	return b.Add(polynomial, ipa)
+}
+
+// verifyEquality deserializes the passed Proof and checks that its value commitments open to the values of the tokens.
+// It returns the proof and the transcript to be used to check the range proof.
+func (v *Verifier) verifyEquality(raw []byte) (*Proof, *transcript, error) {
+	if err := v.validate(); err != nil {
+		return nil, nil, err
+	}
+	proof := &Proof{}
+	if err := proof.Deserialize(raw); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid range proof")
+	}
+	n := len(v.Tokens)
+	eq := proof.Equality
+	if len(proof.ValueCommitments) != n || eq == nil || eq.Challenge == nil || eq.Type == nil ||
+		len(eq.Values) != n || len(eq.TokenBlindingFactors) != n || len(eq.ValueBlindingFactors) != n {
+		return nil, nil, errors.New("invalid range proof: malformed proof")
+	}
+	c := v.Curve
+
+	// recompute the commitments of the equality proof
+	minusChal := c.ModNeg(eq.Challenge, c.GroupOrder)
+	tokenCommitments := make([]*math.G1, n)
+	valueCommitments := make([]*math.G1, n)
+	for i := 0; i < n; i++ {
+		if proof.ValueCommitments[i] == nil || eq.Values[i] == nil || eq.TokenBlindingFactors[i] == nil || eq.ValueBlindingFactors[i] == nil {
+			return nil, nil, errors.Errorf("invalid range proof: nil elements at index [%d]", i)
+		}
+		com, err := common.ComputePedersenCommitment([]*math.Zr{eq.Type, eq.Values[i], eq.TokenBlindingFactors[i]}, v.PedersenParams, c)
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "invalid range proof")
+		}
+		com.Add(v.Tokens[i].Mul(minusChal))
+		tokenCommitments[i] = com
+		valueCommitments[i] = v.PedersenParams[1].Mul2(eq.Values[i], v.PedersenParams[2], eq.ValueBlindingFactors[i])
+		valueCommitments[i].Add(proof.ValueCommitments[i].Mul(minusChal))
+	}
+	t := v.transcript(proof.ValueCommitments)
+	t.appendG1s(tokenCommitments)
+	t.appendG1s(valueCommitments)
+	if !t.challenge().Equals(eq.Challenge) {
+		return nil, nil, errors.New("invalid range proof: tokens do not match value commitments")
+	}
+	return proof, t, nil
+}
+
+func (v *Verifier) validate() error {
+	if v.Curve == nil {
+		return errors.New("invalid range proof: please initialize curve")
+	}
+	if len(v.PedersenParams) != 3 {
+		return errors.Errorf("invalid range proof: length of Pedersen parameters != 3")
+	}
+	if v.BitLength <= 0 || v.BitLength > MaxBitLength || v.BitLength&(v.BitLength-1) != 0 {
+		return errors.Errorf("invalid range proof: bit length should be a power of 2 not larger than [%d], got [%d]", MaxBitLength, v.BitLength)
+	}
+	if len(v.Tokens) == 0 {
+		return errors.New("invalid range proof: no tokens")
+	}
+	for i, tok := range v.Tokens {
+		if tok == nil {
+			return errors.Errorf("invalid range proof: nil token at index [%d]", i)
+		}
+	}
+	return nil
+}
+
+// transcript binds the challenges to the tokens and the value commitments
+func (v *Verifier) transcript(commitments []*math.G1) *transcript {
+	t := newTranscript(generatorLabel, v.Curve)
+	t.appendG1s(v.PedersenParams)
+	t.appendG1s(v.Tokens)
+	t.appendG1s(commitments)
+	return t
+}
@@ -0,0 +1,152 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package bulletproof_test
+
+import (
+	"encoding/json"
+	"math/big"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/bulletproof"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bulletproofs range proof", func() {
+	var (
+		c      *math.Curve
+		pp     []*math.G1
+		values []uint64
+		typ    string
+
+		tw     []*token.TokenDataWitness
+		tokens []*math.G1
+		prover *bulletproof.Prover
+	)
+	BeforeEach(func() {
+		c = math.Curves[math.BN254]
+		pp = preparePedersenParameters(c)
+		values = []uint64{3, 1 << 40, ^uint64(0)}
+		typ = "ABC"
+	})
+	JustBeforeEach(func() {
+		rand, err := c.Rand()
+		Expect(err).NotTo(HaveOccurred())
+		tw = make([]*token.TokenDataWitness, len(values))
+		tokens = make([]*math.G1, len(values))
+		for i, v := range values {
+			tw[i] = &token.TokenDataWitness{Type: typ, Value: zrFromUint64(v, c), BlindingFactor: c.NewRandomZr(rand)}
+			tokens[i] = pp[0].Mul(c.HashToZr([]byte(typ)))
+			tokens[i].Add(pp[1].Mul(tw[i].Value))
+			tokens[i].Add(pp[2].Mul(tw[i].BlindingFactor))
+		}
+		prover = newProver(tw, tokens, 64, pp, c)
+	})
+	Context("when the values are in range", func() {
+		It("succeeds", func() {
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bulletproof.NewVerifier(tokens, 64, pp, c).Verify(proof)).To(Succeed())
+		})
+	})
+	Context("when a single token is proven", func() {
+		BeforeEach(func() {
+			values = []uint64{7}
+		})
+		It("succeeds", func() {
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bulletproof.NewVerifier(tokens, 64, pp, c).Verify(proof)).To(Succeed())
+		})
+	})
+	Context("when the proof is checked against other tokens", func() {
+		It("fails", func() {
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			others := []*math.G1{tokens[1], tokens[0], tokens[2]}
+			err = bulletproof.NewVerifier(others, 64, pp, c).Verify(proof)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("tokens do not match value commitments"))
+		})
+	})
+	Context("when the proof is checked against a shorter bit length", func() {
+		It("fails", func() {
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			err = bulletproof.NewVerifier(tokens, 32, pp, c).Verify(proof)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("when the range proof is tampered with", func() {
+		It("fails", func() {
+			raw, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			proof := &bulletproof.Proof{}
+			Expect(json.Unmarshal(raw, proof)).To(Succeed())
+			proof.Range.T = c.ModAdd(proof.Range.T, c.NewZrFromInt(1), c.GroupOrder)
+			raw, err = proof.Serialize()
+			Expect(err).NotTo(HaveOccurred())
+			err = bulletproof.NewVerifier(tokens, 64, pp, c).Verify(raw)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("polynomial evaluation does not match"))
+		})
+	})
+	Context("when a value is out of range", func() {
+		It("fails", func() {
+			prover = newProver(tw, tokens, 32, pp, c)
+			_, err := prover.Prove()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("value at index [1] is out of range"))
+		})
+	})
+	Context("when the values have more than 64 bits", func() {
+		It("succeeds", func() {
+			max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+			tokens, tw, err := token.GetTokensWithWitness([]*big.Int{big.NewInt(5), max}, typ, pp, c)
+			Expect(err).NotTo(HaveOccurred())
+			proof, err := newProver(tw, tokens, 128, pp, c).Prove()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bulletproof.NewVerifier(tokens, 128, pp, c).Verify(proof)).To(Succeed())
+
+			_, err = newProver(tw, tokens, 64, pp, c).Prove()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("value at index [1] is out of range"))
+		})
+	})
+	Context("when the bit length is not a power of 2", func() {
+		It("fails", func() {
+			prover = newProver(tw, tokens, 24, pp, c)
+			_, err := prover.Prove()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("bit length should be a power of 2"))
+		})
+	})
+})
+
+func zrFromUint64(v uint64, c *math.Curve) *math.Zr {
+	hi := c.NewZrFromInt(int64(v >> 32))
+	return c.ModAdd(c.ModMul(hi, c.NewZrFromInt(1<<32), c.GroupOrder), c.NewZrFromInt(int64(v&0xffffffff)), c.GroupOrder)
+}
+
+func preparePedersenParameters(c *math.Curve) []*math.G1 {
+	rand, err := c.Rand()
+	Expect(err).NotTo(HaveOccurred())
+	pp := make([]*math.G1, 3)
+	for i := 0; i < 3; i++ {
+		pp[i] = c.GenG1.Mul(c.NewRandomZr(rand))
+	}
+	return pp
+}
+
+func newProver(tw []*token.TokenDataWitness, tokens []*math.G1, bitLength int, pp []*math.G1, c *math.Curve) *bulletproof.Prover {
+	values := make([]*math.Zr, len(tw))
+	blindingFactors := make([]*math.Zr, len(tw))
+	for i, w := range tw {
+		values[i], blindingFactors[i] = w.Value, w.BlindingFactor
+	}
+	return bulletproof.NewProver(tw[0].Type, values, blindingFactors, tokens, bitLength, pp, c)
+}
@@ -0,0 +1,231 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bulletproof
+
+import (
+	"fmt"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/pkg/errors"
+)
+
+// generatorLabel is used to derive the generators of the proofs, no one knows their discrete logarithms
+const generatorLabel = "zkatdlog.bulletproof"
+
+// InnerProductProof shows that P = g^a \cdot h^b \cdot u^<a, b> for vectors a and b known to the prover.
+// Its size is logarithmic in the length of the vectors.
+type InnerProductProof struct {
+	// L and R are the cross terms of each round of the argument
+	L []*math.G1
+	R []*math.G1
+	// A and B are the vectors a and b folded into a single element
+	A *math.Zr
+	B *math.Zr
+}
+
+// transcript computes the Fiat-Shamir challenges of the proofs.
+// Each challenge depends on all the elements appended before it.
+type transcript struct {
+	state []byte
+	curve *math.Curve
+}
+
+func newTranscript(label string, curve *math.Curve) *transcript {
+	return &transcript{state: []byte(label), curve: curve}
+}
+
+type bytesMarshaller interface {
+	Bytes() []byte
+}
+
+func (t *transcript) append(elements ...bytesMarshaller) {
+	for _, e := range elements {
+		t.state = append(t.state, e.Bytes()...)
+	}
+}
+
+func (t *transcript) appendG1s(elements []*math.G1) {
+	for _, e := range elements {
+		t.state = append(t.state, e.Bytes()...)
+	}
+}
+
+func (t *transcript) challenge() *math.Zr {
+	c := t.curve.HashToZr(t.state)
+	t.state = c.Bytes()
+	return c
+}
+
+// generators returns the vectors g and h of the passed size, and the generator u.
+func generators(size int, curve *math.Curve) ([]*math.G1, []*math.G1, *math.G1) {
+	g := make([]*math.G1, size)
+	h := make([]*math.G1, size)
+	for i := 0; i < size; i++ {
+		g[i] = curve.HashToG1([]byte(fmt.Sprintf("%s.g.%d", generatorLabel, i)))
+		h[i] = curve.HashToG1([]byte(fmt.Sprintf("%s.h.%d", generatorLabel, i)))
+	}
+	return g, h, curve.HashToG1([]byte(generatorLabel + ".u"))
+}
+
+// proveInnerProduct folds the vectors in half at each round, committing to the cross terms in L and R
+func proveInnerProduct(g, h []*math.G1, u *math.G1, a, b []*math.Zr, t *transcript, pool *common.WorkerPool, curve *math.Curve) *InnerProductProof {
+	order := curve.GroupOrder
+	proof := &InnerProductProof{}
+	for len(a) > 1 {
+		n := len(a) / 2
+		cL := innerProduct(a[:n], b[n:], curve)
+		cR := innerProduct(a[n:], b[:n], curve)
+
+		L := parallelMultiExp(g[n:], a[:n], pool, curve)
+		L.Add(parallelMultiExp(h[:n], b[n:], pool, curve))
+		L.Add(u.Mul(cL))
+		R := parallelMultiExp(g[:n], a[n:], pool, curve)
+		R.Add(parallelMultiExp(h[n:], b[:n], pool, curve))
+		R.Add(u.Mul(cR))
+		proof.L = append(proof.L, L)
+		proof.R = append(proof.R, R)
+
+		t.append(L, R)
+		x := t.challenge()
+		xInv := inverse(x, curve)
+
+		g2 := make([]*math.G1, n)
+		h2 := make([]*math.G1, n)
+		a2 := make([]*math.Zr, n)
+		b2 := make([]*math.Zr, n)
+		_ = pool.Run(n, func(i int) error {
+			g2[i] = g[i].Mul2(xInv, g[n+i], x)
+			h2[i] = h[i].Mul2(x, h[n+i], xInv)
+			a2[i] = curve.ModAdd(curve.ModMul(a[i], x, order), curve.ModMul(a[n+i], xInv, order), order)
+			b2[i] = curve.ModAdd(curve.ModMul(b[i], xInv, order), curve.ModMul(b[n+i], x, order), order)
+			return nil
+		})
+		g, h, a, b = g2, h2, a2, b2
+	}
+	proof.A = a[0]
+	proof.B = b[0]
+	return proof
+}
+
+// innerProductChallenges checks the shape of the passed InnerProductProof for vectors of length n,
+// and returns the challenges of its rounds
+func innerProductChallenges(n int, proof *InnerProductProof, t *transcript) ([]*math.Zr, error) {
+	if proof == nil || proof.A == nil || proof.B == nil {
+		return nil, errors.New("invalid inner product proof: nil elements")
+	}
+	rounds := 0
+	for ; n > 1; n /= 2 {
+		rounds++
+	}
+	if len(proof.L) != rounds || len(proof.R) != rounds {
+		return nil, errors.Errorf("invalid inner product proof: expected [%d] rounds, got [%d,%d]", rounds, len(proof.L), len(proof.R))
+	}
+	challenges := make([]*math.Zr, rounds)
+	for i := 0; i < rounds; i++ {
+		if proof.L[i] == nil || proof.R[i] == nil {
+			return nil, errors.Errorf("invalid inner product proof: nil elements at round [%d]", i)
+		}
+		t.append(proof.L[i], proof.R[i])
+		challenges[i] = t.challenge()
+	}
+	return challenges, nil
+}
+
+// foldingScalars returns the vectors s and s^{-1} such that folding the generators g and h
+// with the passed challenges, as the prover does, yields g^s and h^{s^{-1}}.
+// The i-th element of s is the product of the challenges of the rounds where i falls in the upper half,
+// and of the inverses of the other challenges.
+func foldingScalars(challenges []*math.Zr, n int, curve *math.Curve) ([]*math.Zr, []*math.Zr) {
+	order := curve.GroupOrder
+	rounds := len(challenges)
+	s := make([]*math.Zr, n)
+	sInv := make([]*math.Zr, n)
+	squares := make([]*math.Zr, rounds)
+	inverseSquares := make([]*math.Zr, rounds)
+	s[0] = curve.NewZrFromInt(1)
+	sInv[0] = curve.NewZrFromInt(1)
+	for j, x := range challenges {
+		xInv := inverse(x, curve)
+		s[0] = curve.ModMul(s[0], xInv, order)
+		sInv[0] = curve.ModMul(sInv[0], x, order)
+		squares[j] = curve.ModMul(x, x, order)
+		inverseSquares[j] = curve.ModMul(xInv, xInv, order)
+	}
+	// s_i is s_{i - 2^b} where the challenge of bit b is swapped with its inverse, for the highest bit b of i
+	for i, b := 1, 0; i < n; i++ {
+		if i == 1<<(b+1) {
+			b++
+		}
+		j := rounds - 1 - b
+		s[i] = curve.ModMul(s[i-1<<b], squares[j], order)
+		sInv[i] = curve.ModMul(sInv[i-1<<b], inverseSquares[j], order)
+	}
+	return s, sInv
+}
+
+func innerProduct(a, b []*math.Zr, curve *math.Curve) *math.Zr {
+	res := curve.NewZrFromInt(0)
+	for i := range a {
+		res = curve.ModAdd(res, curve.ModMul(a[i], b[i], curve.GroupOrder), curve.GroupOrder)
+	}
+	return res
+}
+
+func multiExp(bases []*math.G1, exponents []*math.Zr, curve *math.Curve) *math.G1 {
+	res := curve.NewG1()
+	for i := range bases {
+		res.Add(bases[i].Mul(exponents[i]))
+	}
+	return res
+}
+
+// parallelMultiExp returns the same value as multiExp.
+// The terms are split in as many chunks as the workers of the passed pool, and the chunks are computed concurrently.
+func parallelMultiExp(bases []*math.G1, exponents []*math.Zr, pool *common.WorkerPool, curve *math.Curve) *math.G1 {
+	chunks := pool.Workers()
+	if chunks > len(bases) {
+		chunks = len(bases)
+	}
+	if chunks <= 1 {
+		return multiExp(bases, exponents, curve)
+	}
+	size := (len(bases) + chunks - 1) / chunks
+	partials := make([]*math.G1, chunks)
+	_ = pool.Run(chunks, func(i int) error {
+		start, end := i*size, (i+1)*size
+		if start > len(bases) {
+			start = len(bases)
+		}
+		if end > len(bases) {
+			end = len(bases)
+		}
+		partials[i] = multiExp(bases[start:end], exponents[start:end], curve)
+		return nil
+	})
+	res := curve.NewG1()
+	for _, partial := range partials {
+		res.Add(partial)
+	}
+	return res
+}
+
+// powers returns [1, x, ..., x^{n-1}]
+func powers(x *math.Zr, n int, curve *math.Curve) []*math.Zr {
+	res := make([]*math.Zr, n)
+	res[0] = curve.NewZrFromInt(1)
+	for i := 1; i < n; i++ {
+		res[i] = curve.ModMul(res[i-1], x, curve.GroupOrder)
+	}
+	return res
+}
+
+func inverse(x *math.Zr, curve *math.Curve) *math.Zr {
+	inv := x.Copy()
+	inv.InvModP(curve.GroupOrder)
+	return inv
+}
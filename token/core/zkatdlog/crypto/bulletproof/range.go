@@ -0,0 +1,304 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bulletproof
+
+import (
+	"math/big"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/pkg/errors"
+)
+
+// RangeProof shows that the values committed in an array of commitments V_j = G^{v_j} \cdot H^{gamma_j}
+// are in [0, 2^n). The proof is aggregated: its size is logarithmic in n times the number of commitments.
+type RangeProof struct {
+	// A commits to the bits of the values
+	A *math.G1
+	// S commits to the blinding vectors of the bits
+	S *math.G1
+	// T1 and T2 commit to the coefficients of the polynomial t(X)
+	T1 *math.G1
+	T2 *math.G1
+	// Tau is the blinding factor of t(x)
+	Tau *math.Zr
+	// Mu is the blinding factor of A and S
+	Mu *math.Zr
+	// T is the evaluation t(x) = <l(x), r(x)>
+	T *math.Zr
+	// InnerProduct shows that T is the inner product of l(x) and r(x)
+	InnerProduct *InnerProductProof
+}
+
+// ProveRange returns a RangeProof that the values committed in the passed commitments, V_j = G^{v_j} \cdot H^{gamma_j},
+// are in [0, 2^bitLength). The challenges are bound to the passed label and to G and H.
+func ProveRange(commitments []*math.G1, values, blindingFactors []*math.Zr, bitLength int, G, H *math.G1, label string, pool *common.WorkerPool, curve *math.Curve) (*RangeProof, error) {
+	if err := validateRange(commitments, bitLength, G, H, curve); err != nil {
+		return nil, err
+	}
+	if len(values) != len(commitments) || len(blindingFactors) != len(commitments) {
+		return nil, errors.Errorf("cannot generate range proof: expected [%d] openings, got [%d]", len(commitments), len(values))
+	}
+	return proveRange(commitments, values, blindingFactors, bitLength, G, H, rangeTranscript(label, G, H, curve), pool, curve)
+}
+
+// VerifyRange checks that the passed RangeProof, generated with ProveRange, is valid for the passed commitments
+func VerifyRange(commitments []*math.G1, proof *RangeProof, bitLength int, G, H *math.G1, label string, curve *math.Curve) error {
+	if err := validateRange(commitments, bitLength, G, H, curve); err != nil {
+		return err
+	}
+	return verifyRange(commitments, proof, bitLength, G, H, rangeTranscript(label, G, H, curve), curve)
+}
+
+func validateRange(commitments []*math.G1, bitLength int, G, H *math.G1, curve *math.Curve) error {
+	if curve == nil || G == nil || H == nil {
+		return errors.New("invalid range proof: please initialize curve and generators")
+	}
+	if bitLength <= 0 || bitLength > MaxBitLength || bitLength&(bitLength-1) != 0 {
+		return errors.Errorf("invalid range proof: bit length should be a power of 2 not larger than [%d], got [%d]", MaxBitLength, bitLength)
+	}
+	if len(commitments) == 0 {
+		return errors.New("invalid range proof: no commitments")
+	}
+	for i, V := range commitments {
+		if V == nil {
+			return errors.Errorf("invalid range proof: nil commitment at index [%d]", i)
+		}
+	}
+	return nil
+}
+
+func rangeTranscript(label string, G, H *math.G1, curve *math.Curve) *transcript {
+	t := newTranscript(label, curve)
+	t.append(G, H)
+	return t
+}
+
+// rangeSetup returns the number of commitments padded to a power of two, and the generators of a range proof
+func rangeSetup(m int, bitLength int, curve *math.Curve) (int, []*math.G1, []*math.G1, *math.G1) {
+	padded := 1
+	for padded < m {
+		padded *= 2
+	}
+	g, h, u := generators(padded*bitLength, curve)
+	return padded, g, h, u
+}
+
+// proveRange produces a RangeProof for the passed commitments, whose openings are values and blindingFactors
+func proveRange(commitments []*math.G1, values, blindingFactors []*math.Zr, bitLength int, G, H *math.G1, t *transcript, pool *common.WorkerPool, curve *math.Curve) (*RangeProof, error) {
+	rand, err := curve.Rand()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get random generator")
+	}
+	order := curve.GroupOrder
+	m, g, h, u := rangeSetup(len(commitments), bitLength, curve)
+	N := m * bitLength
+
+	// A = H^alpha \cdot g^aL \cdot h^aR, where aL are the bits of the values and aR = aL - 1
+	zero := curve.NewZrFromInt(0)
+	one := curve.NewZrFromInt(1)
+	minusOne := curve.ModNeg(one, order)
+	aL := make([]*math.Zr, N)
+	aR := make([]*math.Zr, N)
+	alpha := curve.NewRandomZr(rand)
+	A := H.Mul(alpha)
+	for j := 0; j < m; j++ {
+		v := new(big.Int)
+		if j < len(values) {
+			v.SetBytes(values[j].Bytes())
+		}
+		if v.BitLen() > bitLength {
+			return nil, errors.Errorf("value at index [%d] is out of range", j)
+		}
+		for i := 0; i < bitLength; i++ {
+			k := j*bitLength + i
+			if v.Bit(i) == 1 {
+				aL[k], aR[k] = one, zero
+				A.Add(g[k])
+			} else {
+				aL[k], aR[k] = zero, minusOne
+				A.Sub(h[k])
+			}
+		}
+	}
+	// S = H^rho \cdot g^sL \cdot h^sR
+	sL := make([]*math.Zr, N)
+	sR := make([]*math.Zr, N)
+	for k := 0; k < N; k++ {
+		sL[k] = curve.NewRandomZr(rand)
+		sR[k] = curve.NewRandomZr(rand)
+	}
+	rho := curve.NewRandomZr(rand)
+	S := H.Mul(rho)
+	S.Add(parallelMultiExp(g, sL, pool, curve))
+	S.Add(parallelMultiExp(h, sR, pool, curve))
+
+	t.appendG1s(commitments)
+	t.append(A, S)
+	y := t.challenge()
+	z := t.challenge()
+
+	// l(X) = aL - z + sL X
+	// r(X) = y^N \circ (aR + z + sR X) + z^{2+j} 2^n for the j-th block
+	yPow := powers(y, N, curve)
+	zPow := powers(z, m+2, curve)
+	twoPow := powers(curve.NewZrFromInt(2), bitLength, curve)
+	l0 := make([]*math.Zr, N)
+	r0 := make([]*math.Zr, N)
+	r1 := make([]*math.Zr, N)
+	for j := 0; j < m; j++ {
+		for i := 0; i < bitLength; i++ {
+			k := j*bitLength + i
+			l0[k] = curve.ModSub(aL[k], z, order)
+			r0[k] = curve.ModMul(yPow[k], curve.ModAdd(aR[k], z, order), order)
+			r0[k] = curve.ModAdd(r0[k], curve.ModMul(zPow[2+j], twoPow[i], order), order)
+			r1[k] = curve.ModMul(yPow[k], sR[k], order)
+		}
+	}
+	// t(X) = <l(X), r(X)> = t0 + t1 X + t2 X^2
+	t1 := curve.ModAdd(innerProduct(l0, r1, curve), innerProduct(sL, r0, curve), order)
+	t2 := innerProduct(sL, r1, curve)
+	tau1 := curve.NewRandomZr(rand)
+	tau2 := curve.NewRandomZr(rand)
+	T1 := G.Mul2(t1, H, tau1)
+	T2 := G.Mul2(t2, H, tau2)
+
+	t.append(T1, T2)
+	x := t.challenge()
+
+	// tau = tau2 x^2 + tau1 x + \sum_j z^{2+j} gamma_j
+	tau := curve.ModAdd(curve.ModMul(tau2, curve.ModMul(x, x, order), order), curve.ModMul(tau1, x, order), order)
+	for j, gamma := range blindingFactors {
+		tau = curve.ModAdd(tau, curve.ModMul(zPow[2+j], gamma, order), order)
+	}
+	mu := curve.ModAdd(alpha, curve.ModMul(rho, x, order), order)
+	l := make([]*math.Zr, N)
+	r := make([]*math.Zr, N)
+	for k := 0; k < N; k++ {
+		l[k] = curve.ModAdd(l0[k], curve.ModMul(sL[k], x, order), order)
+		r[k] = curve.ModAdd(r0[k], curve.ModMul(r1[k], x, order), order)
+	}
+	tx := innerProduct(l, r, curve)
+
+	t.append(tau, mu, tx)
+	w := t.challenge()
+
+	// h'_k = h_k^{y^{-k}}
+	yInv := powers(inverse(y, curve), N, curve)
+	hPrime := make([]*math.G1, N)
+	_ = pool.Run(N, func(k int) error {
+		hPrime[k] = h[k].Mul(yInv[k])
+		return nil
+	})
+
+	return &RangeProof{
+		A:            A,
+		S:            S,
+		T1:           T1,
+		T2:           T2,
+		Tau:          tau,
+		Mu:           mu,
+		T:            tx,
+		InnerProduct: proveInnerProduct(g, hPrime, u.Mul(w), l, r, t, pool, curve),
+	}, nil
+}
+
+// verifyRange checks that the passed RangeProof is valid for the passed commitments
+func verifyRange(commitments []*math.G1, proof *RangeProof, bitLength int, G, H *math.G1, t *transcript, curve *math.Curve) error {
+	polynomial, ipa, err := rangeEquations(commitments, proof, bitLength, G, H, t, curve)
+	if err != nil {
+		return err
+	}
+	if !polynomial.Holds(curve) {
+		return errors.New("invalid range proof: polynomial evaluation does not match")
+	}
+	if !ipa.Holds(curve) {
+		return errors.New("invalid range proof: invalid inner product proof")
+	}
+	return nil
+}
+
+// rangeEquations checks the shape of the passed RangeProof and returns the equations that hold
+// if it is valid for the passed commitments: the first one checks the evaluation of t(X),
+// the second one checks the inner product argument on l(x) and r(x).
+func rangeEquations(commitments []*math.G1, proof *RangeProof, bitLength int, G, H *math.G1, t *transcript, curve *math.Curve) (*batch.Equation, *batch.Equation, error) {
+	if proof == nil || proof.A == nil || proof.S == nil || proof.T1 == nil || proof.T2 == nil || proof.Tau == nil || proof.Mu == nil || proof.T == nil {
+		return nil, nil, errors.New("invalid range proof: nil elements")
+	}
+	order := curve.GroupOrder
+	m, g, h, u := rangeSetup(len(commitments), bitLength, curve)
+	N := m * bitLength
+
+	t.appendG1s(commitments)
+	t.append(proof.A, proof.S)
+	y := t.challenge()
+	z := t.challenge()
+	t.append(proof.T1, proof.T2)
+	x := t.challenge()
+	t.append(proof.Tau, proof.Mu, proof.T)
+	w := t.challenge()
+	challenges, err := innerProductChallenges(N, proof.InnerProduct, t)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "invalid range proof")
+	}
+
+	yPow := powers(y, N, curve)
+	zPow := powers(z, m+3, curve)
+	twoPow := powers(curve.NewZrFromInt(2), bitLength, curve)
+	xSquare := curve.ModMul(x, x, order)
+
+	// G^T \cdot H^Tau = \prod_j V_j^{z^{2+j}} \cdot G^delta \cdot T1^x \cdot T2^{x^2}, where
+	// delta = (z - z^2) <1, y^N> - \sum_j z^{3+j} <1, 2^n>
+	sumY := curve.NewZrFromInt(0)
+	for _, e := range yPow {
+		sumY = curve.ModAdd(sumY, e, order)
+	}
+	sumTwo := curve.NewZrFromInt(0)
+	for _, e := range twoPow {
+		sumTwo = curve.ModAdd(sumTwo, e, order)
+	}
+	delta := curve.ModMul(curve.ModSub(z, zPow[2], order), sumY, order)
+	for j := 0; j < m; j++ {
+		delta = curve.ModSub(delta, curve.ModMul(zPow[3+j], sumTwo, order), order)
+	}
+	polynomial := batch.NewEquation(len(commitments) + 4)
+	polynomial.Add(G, curve.ModSub(proof.T, delta, order))
+	polynomial.Add(H, proof.Tau)
+	for j, V := range commitments {
+		polynomial.Add(V, curve.ModNeg(zPow[2+j], order))
+	}
+	polynomial.Add(proof.T1, curve.ModNeg(x, order))
+	polynomial.Add(proof.T2, curve.ModNeg(xSquare, order))
+
+	// P = A \cdot S^x \cdot g^{-z} \cdot h'^{z y^N + z^{2+j} 2^n} \cdot H^{-Mu} \cdot u^{wT}, where h'_k = h_k^{y^{-k}},
+	// must open to l(x), r(x): folding the generators and P with the challenges of the inner product argument,
+	// g^{a s} \cdot h'^{b s^{-1}} \cdot u^{wab} = P \cdot \prod_j L_j^{x_j^2} \cdot R_j^{x_j^{-2}}
+	ipp := proof.InnerProduct
+	s, sInv := foldingScalars(challenges, N, curve)
+	yInv := powers(inverse(y, curve), N, curve)
+	ipa := batch.NewEquation(2*N + 2*len(challenges) + 4)
+	for j := 0; j < m; j++ {
+		for i := 0; i < bitLength; i++ {
+			k := j*bitLength + i
+			// h'^{z y^k + z^{2+j} 2^i} = h^{z + z^{2+j} 2^i y^{-k}}
+			e := curve.ModAdd(z, curve.ModMul(curve.ModMul(zPow[2+j], twoPow[i], order), yInv[k], order), order)
+			ipa.Add(g[k], curve.ModAdd(curve.ModMul(ipp.A, s[k], order), z, order))
+			ipa.Add(h[k], curve.ModSub(curve.ModMul(curve.ModMul(ipp.B, sInv[k], order), yInv[k], order), e, order))
+		}
+	}
+	ipa.Add(u, curve.ModMul(w, curve.ModSub(curve.ModMul(ipp.A, ipp.B, order), proof.T, order), order))
+	ipa.Add(proof.A, curve.ModNeg(curve.NewZrFromInt(1), order))
+	ipa.Add(proof.S, curve.ModNeg(x, order))
+	ipa.Add(H, proof.Mu)
+	for j, xj := range challenges {
+		xjInv := inverse(xj, curve)
+		ipa.Add(ipp.L[j], curve.ModNeg(curve.ModMul(xj, xj, order), order))
+		ipa.Add(ipp.R[j], curve.ModNeg(curve.ModMul(xjInv, xjInv, order), order))
+	}
+	return polynomial, ipa, nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"runtime"
+	"sync"
+)
+
+var defaultWorkerPool = NewWorkerPool(0)
+
+// WorkerPool bounds the number of goroutines used to generate proofs.
+// A task is handed to a new goroutine only if one of the pool slots is free, otherwise it runs on the
+// goroutine of the caller. Then, nested calls to Run never deadlock and share the same bound.
+type WorkerPool struct {
+	workers int
+	slots   chan struct{}
+}
+
+// NewWorkerPool returns a WorkerPool that runs at most the passed number of tasks at once per caller.
+// Zero or a negative number means the number of available CPUs.
+func NewWorkerPool(workers int) *WorkerPool {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &WorkerPool{
+		workers: workers,
+		// the goroutine of the caller counts as a worker
+		slots: make(chan struct{}, workers-1),
+	}
+}
+
+// Workers returns the maximum number of tasks run at once per caller
+func (p *WorkerPool) Workers() int {
+	return p.pool().workers
+}
+
+// Run executes task(i), for 0 <= i < n, and waits for all of them to complete.
+// Run returns the error of the task with the smallest index that failed, if any.
+// A nil WorkerPool uses as many workers as the available CPUs.
+func (p *WorkerPool) Run(n int, task func(i int) error) error {
+	p = p.pool()
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case p.slots <- struct{}{}:
+			wg.Add(1)
+			go func(i int) {
+				defer func() {
+					<-p.slots
+					wg.Done()
+				}()
+				errs[i] = task(i)
+			}(i)
+		default:
+			errs[i] = task(i)
+		}
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *WorkerPool) pool() *WorkerPool {
+	if p == nil {
+		return defaultWorkerPool
+	}
+	return p
+}
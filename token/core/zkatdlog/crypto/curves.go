@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"sort"
+
+	mathlib "github.com/IBM/mathlib"
+	"github.com/pkg/errors"
+)
+
+// DefaultCurve is the curve used when none is specified
+const DefaultCurve = mathlib.BN254
+
+// curveNames maps the names of the supported pairing-friendly curves to their identifiers.
+// A curve provided by mathlib becomes available to the public parameters once it is listed here.
+var curveNames = map[string]mathlib.CurveID{
+	"FP256BN_AMCL":        mathlib.FP256BN_AMCL,
+	"BN254":               mathlib.BN254,
+	"FP256BN_AMCL_MIRACL": mathlib.FP256BN_AMCL_MIRACL,
+}
+
+// CurveIDByName returns the identifier of the curve with the passed name
+func CurveIDByName(name string) (mathlib.CurveID, error) {
+	id, ok := curveNames[name]
+	if !ok {
+		return 0, errors.Errorf("unsupported curve [%s], expected one of %v", name, CurveNames())
+	}
+	return id, nil
+}
+
+// CurveName returns the name of the curve with the passed identifier
+func CurveName(id mathlib.CurveID) (string, error) {
+	for name, cid := range curveNames {
+		if cid == id {
+			return name, nil
+		}
+	}
+	return "", errors.Errorf("unsupported curve [%d]", id)
+}
+
+// CurveNames returns the sorted names of the supported curves
+func CurveNames() []string {
+	names := make([]string, 0, len(curveNames))
+	for name := range curveNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CurveIDs returns the identifiers of the supported curves, in ascending order
+func CurveIDs() []mathlib.CurveID {
+	ids := make([]mathlib.CurveID, 0, len(curveNames))
+	for _, id := range curveNames {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// validCurve returns true if the passed curve identifier is supported
+func validCurve(id mathlib.CurveID) bool {
+	_, err := CurveName(id)
+	return err == nil && int(id) < len(mathlib.Curves)
+}
@@ -0,0 +1,41 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package curvetest provides the matrix of curves the tests of the zkatdlog cryptography run on
+package curvetest
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	. "github.com/onsi/ginkgo/v2"
+)
+
+// Entries returns an entry of a DescribeTable for each curve supported by the public parameters.
+// The body of the table receives the identifier of the curve.
+func Entries() []TableEntry {
+	var entries []TableEntry
+	for _, id := range crypto.CurveIDs() {
+		name, _ := crypto.CurveName(id)
+		entries = append(entries, Entry(name, id))
+	}
+	return entries
+}
+
+// EntriesWithBulletproofs returns two entries of a DescribeTable for each curve supported by the public parameters,
+// one without and one with Bulletproofs range proofs.
+// The body of the table receives the identifier of the curve and whether Bulletproofs are enabled.
+func EntriesWithBulletproofs() []TableEntry {
+	var entries []TableEntry
+	for _, bulletproofs := range []bool{false, true} {
+		for _, id := range crypto.CurveIDs() {
+			name, _ := crypto.CurveName(id)
+			if bulletproofs {
+				name += " with Bulletproofs"
+			}
+			entries = append(entries, Entry(name, id, bulletproofs))
+		}
+	}
+	return entries
+}
@@ -0,0 +1,374 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package disclosure
+
+import (
+	"math/big"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/bulletproof"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/elgamal"
+	"github.com/pkg/errors"
+)
+
+// rangeLabel binds the range proofs of the chunks of the values to the encryption for the auditor
+const rangeLabel = "zkatdlog.audit-encryption.range"
+
+// Ciphertexts carries the Elgamal encryption of the type and the value of a token under the key of the auditor,
+// and a proof that they match the Pedersen commitment in the token and that each chunk of the value is in range.
+// The type is encrypted as Gen^{H(type)}, the value as Gen^{v_j} for each chunk v_j of LimbBitLength bits,
+// the least significant first.
+type Ciphertexts struct {
+	Type   *elgamal.Ciphertext
+	Values []*elgamal.Ciphertext
+	Proof  *Proof
+}
+
+// Proof shows that Ciphertexts encrypt the opening of a token data TokenData = G^{H(type)} * H^value * F^r
+type Proof struct {
+	// Proof of the hash of the type
+	Type *math.Zr
+	// Proof of the chunks of the value
+	Values []*math.Zr
+	// Proof of the blinding factor of the token data
+	BlindingFactor *math.Zr
+	// Proof of the randomness of the encryption of the type
+	TypeRandomness *math.Zr
+	// Proof of the randomness of the encryption of the chunks
+	ValueRandomness []*math.Zr
+	// Challenge computed using the Fiat-Shamir Heuristic
+	Challenge *math.Zr
+	// Range shows that each chunk of the value is in [0, 2^LimbBitLength).
+	// The second component of the encryption of a chunk, Gen^{v_j} * PK^{r_j}, is a Pedersen commitment to it.
+	Range *bulletproof.RangeProof
+}
+
+// Encrypt returns the encryption of the passed opening of the passed token data for the auditor
+func Encrypt(data *math.G1, ttype string, value, bf *math.Zr, pp *crypto.PublicParams) (*Ciphertexts, error) {
+	if err := checkParams(pp); err != nil {
+		return nil, errors.WithMessage(err, "cannot encrypt token for the auditor")
+	}
+	if data == nil || value == nil || bf == nil {
+		return nil, errors.New("cannot encrypt token for the auditor: invalid opening")
+	}
+	limbs, err := split(value, pp)
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot encrypt token for the auditor")
+	}
+	res, p, err := encrypt(data, ttype, limbs, bf, pp)
+	if err != nil {
+		return nil, err
+	}
+	res.Proof.Range, err = bulletproof.ProveRange(p.valueCommitments(), limbs, p.valueRandomness, int(pp.AuditEncryptionParams.LimbBitLength), p.gen, p.pk, rangeLabel, nil, p.curve)
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot prove range of the chunks of token value for the auditor")
+	}
+	return res, nil
+}
+
+// encrypt returns the encryption of the passed type and chunks of the value, with the proof that they match
+// the passed token data, but without the range proof of the chunks.
+// It returns also the prover, that holds the randomness of the encryption.
+func encrypt(data *math.G1, ttype string, limbs []*math.Zr, bf *math.Zr, pp *crypto.PublicParams) (*Ciphertexts, *prover, error) {
+	c := math.Curves[pp.Curve]
+	pk := publicKey(pp)
+	typeHash := c.HashToZr([]byte(ttype))
+	var err error
+
+	res := &Ciphertexts{Values: make([]*elgamal.Ciphertext, len(limbs))}
+	var typeRandomness *math.Zr
+	res.Type, typeRandomness, err = pk.EncryptZr(typeHash)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot encrypt token type for the auditor")
+	}
+	valueRandomness := make([]*math.Zr, len(limbs))
+	for j, limb := range limbs {
+		res.Values[j], valueRandomness[j], err = pk.EncryptZr(limb)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot encrypt chunk [%d] of token value for the auditor", j)
+		}
+	}
+
+	p := &prover{
+		verifier:        newVerifier(data, res, pp),
+		typeHash:        typeHash,
+		limbs:           limbs,
+		bf:              bf,
+		typeRandomness:  typeRandomness,
+		valueRandomness: valueRandomness,
+	}
+	res.Proof, err = p.prove()
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "cannot prove encryption of token for the auditor")
+	}
+	return res, p, nil
+}
+
+// Verify returns an error if Ciphertexts do not encrypt the opening of the passed token data
+func (ct *Ciphertexts) Verify(data *math.G1, pp *crypto.PublicParams) error {
+	if err := checkParams(pp); err != nil {
+		return errors.WithMessage(err, "cannot verify token encryption for the auditor")
+	}
+	if data == nil || ct.Type == nil || ct.Proof == nil || len(ct.Values) != pp.AuditLimbs() {
+		return errors.New("invalid token encryption for the auditor: malformed ciphertexts")
+	}
+	v := newVerifier(data, ct, pp)
+	if err := v.verify(ct.Proof); err != nil {
+		return err
+	}
+	if err := bulletproof.VerifyRange(v.valueCommitments(), ct.Proof.Range, int(pp.AuditEncryptionParams.LimbBitLength), v.gen, v.pk, rangeLabel, v.curve); err != nil {
+		return errors.WithMessage(err, "invalid token encryption for the auditor: chunks of the value out of range")
+	}
+	return nil
+}
+
+// Decrypt returns the type and the value encrypted in Ciphertexts.
+// The type is recovered by matching against the passed candidate types.
+func (ct *Ciphertexts) Decrypt(sk *elgamal.SecretKey, types []string, pp *crypto.PublicParams) (string, *math.Zr, error) {
+	if err := checkParams(pp); err != nil {
+		return "", nil, errors.WithMessage(err, "cannot decrypt token")
+	}
+	if ct.Type == nil || len(ct.Values) != pp.AuditLimbs() {
+		return "", nil, errors.New("cannot decrypt token: malformed ciphertexts")
+	}
+	c := math.Curves[pp.Curve]
+	gen := pp.AuditEncryptionParams.Gen
+
+	M, err := decrypt(sk, ct.Type)
+	if err != nil {
+		return "", nil, errors.WithMessage(err, "cannot decrypt token type")
+	}
+	ttype := ""
+	found := false
+	for _, t := range types {
+		if gen.Mul(c.HashToZr([]byte(t))).Equals(M) {
+			ttype = t
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", nil, errors.New("cannot decrypt token type: no candidate type matches")
+	}
+
+	table := newDLogTable(gen, pp.AuditEncryptionParams.LimbBitLength, c)
+	value := c.NewZrFromInt(0)
+	shift := c.NewZrFromInt(1)
+	base := c.NewZrFromInt(1 << pp.AuditEncryptionParams.LimbBitLength)
+	for j, v := range ct.Values {
+		M, err := decrypt(sk, v)
+		if err != nil {
+			return "", nil, errors.WithMessagef(err, "cannot decrypt chunk [%d] of token value", j)
+		}
+		limb, err := table.dlog(M)
+		if err != nil {
+			return "", nil, errors.WithMessagef(err, "cannot decrypt chunk [%d] of token value", j)
+		}
+		value = c.ModAdd(value, c.ModMul(c.NewZrFromInt(limb), shift, c.GroupOrder), c.GroupOrder)
+		shift = c.ModMul(shift, base, c.GroupOrder)
+	}
+	return ttype, value, nil
+}
+
+// prover produces the Proof of Ciphertexts
+type prover struct {
+	*verifier
+	typeHash        *math.Zr
+	limbs           []*math.Zr
+	bf              *math.Zr
+	typeRandomness  *math.Zr
+	valueRandomness []*math.Zr
+}
+
+func (p *prover) prove() (*Proof, error) {
+	c := p.curve
+	rand, err := c.Rand()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get RNG")
+	}
+	// randomness for the proof
+	rType := c.NewRandomZr(rand)
+	rBF := c.NewRandomZr(rand)
+	rTypeRandomness := c.NewRandomZr(rand)
+	rValues := make([]*math.Zr, len(p.limbs))
+	rValueRandomness := make([]*math.Zr, len(p.limbs))
+	for j := range p.limbs {
+		rValues[j] = c.NewRandomZr(rand)
+		rValueRandomness[j] = c.NewRandomZr(rand)
+	}
+	commitments := p.commitments(rType, rValues, rBF, rTypeRandomness, rValueRandomness)
+	raw, err := common.GetG1Array(commitments, p.statements()).Bytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compute challenge")
+	}
+	chal := c.HashToZr(raw)
+
+	proof := &Proof{Challenge: chal}
+	sp := &common.SchnorrProver{
+		Witness:         common.GetZrArray([]*math.Zr{p.typeHash, p.bf, p.typeRandomness}, p.limbs, p.valueRandomness),
+		Randomness:      common.GetZrArray([]*math.Zr{rType, rBF, rTypeRandomness}, rValues, rValueRandomness),
+		Challenge:       chal,
+		SchnorrVerifier: &common.SchnorrVerifier{Curve: c},
+	}
+	responses, err := sp.Prove()
+	if err != nil {
+		return nil, err
+	}
+	proof.Type, proof.BlindingFactor, proof.TypeRandomness = responses[0], responses[1], responses[2]
+	proof.Values = responses[3 : 3+len(p.limbs)]
+	proof.ValueRandomness = responses[3+len(p.limbs):]
+	return proof, nil
+}
+
+// verifier checks the Proof of Ciphertexts
+type verifier struct {
+	data        *math.G1
+	ciphertexts *Ciphertexts
+	pedParams   []*math.G1
+	gen         *math.G1
+	pk          *math.G1
+	limbBase    *math.Zr
+	curve       *math.Curve
+}
+
+func newVerifier(data *math.G1, ct *Ciphertexts, pp *crypto.PublicParams) *verifier {
+	c := math.Curves[pp.Curve]
+	return &verifier{
+		data:        data,
+		ciphertexts: ct,
+		pedParams:   pp.PedParams,
+		gen:         pp.AuditEncryptionParams.Gen,
+		pk:          pp.AuditEncryptionParams.PK,
+		limbBase:    c.NewZrFromInt(1 << pp.AuditEncryptionParams.LimbBitLength),
+		curve:       c,
+	}
+}
+
+func (v *verifier) verify(proof *Proof) error {
+	n := len(v.ciphertexts.Values)
+	if proof.Challenge == nil || proof.Type == nil || proof.BlindingFactor == nil || proof.TypeRandomness == nil ||
+		len(proof.Values) != n || len(proof.ValueRandomness) != n {
+		return errors.New("invalid token encryption for the auditor: malformed proof")
+	}
+	for j := 0; j < n; j++ {
+		if proof.Values[j] == nil || proof.ValueRandomness[j] == nil || v.ciphertexts.Values[j] == nil ||
+			v.ciphertexts.Values[j].C1 == nil || v.ciphertexts.Values[j].C2 == nil {
+			return errors.Errorf("invalid token encryption for the auditor: malformed chunk [%d]", j)
+		}
+	}
+	if v.ciphertexts.Type.C1 == nil || v.ciphertexts.Type.C2 == nil {
+		return errors.New("invalid token encryption for the auditor: malformed type ciphertext")
+	}
+	// recompute the commitments as Statement^{-challenge} times the responses on the bases
+	commitments := v.commitments(proof.Type, proof.Values, proof.BlindingFactor, proof.TypeRandomness, proof.ValueRandomness)
+	statements := v.statements()
+	for i, s := range statements {
+		commitments[i].Sub(s.Mul(proof.Challenge))
+	}
+	raw, err := common.GetG1Array(commitments, statements).Bytes()
+	if err != nil {
+		return errors.Wrap(err, "failed to verify token encryption for the auditor")
+	}
+	if !v.curve.HashToZr(raw).Equals(proof.Challenge) {
+		return errors.New("invalid token encryption for the auditor")
+	}
+	return nil
+}
+
+// valueCommitments returns the second components of the encryptions of the chunks of the value,
+// Gen^{v_j} * PK^{r_j}, that commit to the chunks
+func (v *verifier) valueCommitments() []*math.G1 {
+	res := make([]*math.G1, len(v.ciphertexts.Values))
+	for j, ct := range v.ciphertexts.Values {
+		res[j] = ct.C2
+	}
+	return res
+}
+
+// statements returns TokenData and the components of the ciphertexts, in the order of commitments
+func (v *verifier) statements() []*math.G1 {
+	res := []*math.G1{v.data, v.ciphertexts.Type.C1, v.ciphertexts.Type.C2}
+	for _, ct := range v.ciphertexts.Values {
+		res = append(res, ct.C1, ct.C2)
+	}
+	return res
+}
+
+// commitments evaluates the relations of the statements on the passed exponents:
+// G^{t} * H^{\sum_j 2^{j*LimbBitLength} v_j} * F^{r}, Gen^{rt}, Gen^{t} * PK^{rt}, and Gen^{rv_j}, Gen^{v_j} * PK^{rv_j} for each j
+func (v *verifier) commitments(t *math.Zr, values []*math.Zr, r, rt *math.Zr, rvs []*math.Zr) []*math.G1 {
+	c := v.curve
+	value := c.NewZrFromInt(0)
+	shift := c.NewZrFromInt(1)
+	for _, vj := range values {
+		value = c.ModAdd(value, c.ModMul(vj, shift, c.GroupOrder), c.GroupOrder)
+		shift = c.ModMul(shift, v.limbBase, c.GroupOrder)
+	}
+	data := v.pedParams[0].Mul(t)
+	data.Add(v.pedParams[1].Mul(value))
+	data.Add(v.pedParams[2].Mul(r))
+	res := []*math.G1{data, v.gen.Mul(rt), v.gen.Mul2(t, v.pk, rt)}
+	for j, vj := range values {
+		res = append(res, v.gen.Mul(rvs[j]), v.gen.Mul2(vj, v.pk, rvs[j]))
+	}
+	return res
+}
+
+// split returns the chunks of LimbBitLength bits of the passed value, the least significant first
+func split(value *math.Zr, pp *crypto.PublicParams) ([]*math.Zr, error) {
+	c := math.Curves[pp.Curve]
+	l := pp.AuditEncryptionParams.LimbBitLength
+	n := pp.AuditLimbs()
+	v := new(big.Int).SetBytes(value.Bytes())
+	if v.BitLen() > n*int(l) {
+		return nil, errors.Errorf("value does not fit in [%d] chunks of [%d] bits", n, l)
+	}
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), l), big.NewInt(1))
+	limbs := make([]*math.Zr, n)
+	for j := 0; j < n; j++ {
+		limbs[j] = c.NewZrFromInt(new(big.Int).And(v, mask).Int64())
+		v.Rsh(v, l)
+	}
+	return limbs, nil
+}
+
+func publicKey(pp *crypto.PublicParams) *elgamal.PublicKey {
+	return &elgamal.PublicKey{
+		Gen:   pp.AuditEncryptionParams.Gen,
+		H:     pp.AuditEncryptionParams.PK,
+		Curve: math.Curves[pp.Curve],
+	}
+}
+
+func decrypt(sk *elgamal.SecretKey, ct *elgamal.Ciphertext) (*math.G1, error) {
+	if ct == nil || ct.C1 == nil || ct.C2 == nil {
+		return nil, errors.New("malformed ciphertext")
+	}
+	// Decrypt works in place
+	return sk.Decrypt(&elgamal.Ciphertext{C1: ct.C1, C2: ct.C2.Copy()})
+}
+
+func checkParams(pp *crypto.PublicParams) error {
+	if pp == nil {
+		return errors.New("nil public parameters")
+	}
+	if !pp.AuditEncryption() {
+		return errors.New("audit encryption is not enabled in public parameters")
+	}
+	if err := pp.AuditEncryptionParams.Validate(); err != nil {
+		return err
+	}
+	if len(math.Curves) < int(pp.Curve)+1 {
+		return errors.New("please initialize public parameters with an admissible curve")
+	}
+	if len(pp.PedParams) != 3 {
+		return errors.New("invalid Pedersen parameters")
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package disclosure_test
+
+import (
+	"math/big"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/disclosure"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/elgamal"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Audit encryption", func() {
+	var (
+		pp     *crypto.PublicParams
+		sk     *elgamal.SecretKey
+		data   []*math.G1
+		tw     []*token.TokenDataWitness
+		values []*big.Int
+	)
+
+	BeforeEach(func() {
+		var err error
+		pp, err = crypto.Setup(100, 2, nil, math.BN254)
+		Expect(err).NotTo(HaveOccurred())
+		sk, err = disclosure.NewSecretKey(pp)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pp.EnableAuditEncryption(sk.H)).To(Succeed())
+
+		values = []*big.Int{big.NewInt(0), big.NewInt(50), new(big.Int).SetUint64(1<<63 + 12345)}
+	})
+	JustBeforeEach(func() {
+		var err error
+		data, tw, err = token.GetTokensWithWitness(values, "ABC", pp.PedParams, math.Curves[pp.Curve])
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("lets the auditor recover type and value", func() {
+		for i := range data {
+			ct, err := disclosure.Encrypt(data[i], tw[i].Type, tw[i].Value, tw[i].BlindingFactor, pp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ct.Values).To(HaveLen(pp.AuditLimbs()))
+			Expect(ct.Verify(data[i], pp)).To(Succeed())
+
+			ttype, value, err := ct.Decrypt(sk, []string{"XYZ", "ABC"}, pp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ttype).To(Equal("ABC"))
+			Expect(value.Equals(tw[i].Value)).To(BeTrue())
+		}
+	})
+
+	When("the key of the auditor is serialized", func() {
+		It("decrypts with the deserialized key", func() {
+			ct, err := disclosure.Encrypt(data[1], tw[1].Type, tw[1].Value, tw[1].BlindingFactor, pp)
+			Expect(err).NotTo(HaveOccurred())
+			sk2, err := disclosure.SecretKeyFromBytes(sk.Bytes(), pp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sk2.H.Equals(sk.H)).To(BeTrue())
+			_, value, err := ct.Decrypt(sk2, []string{"ABC"}, pp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(value.Equals(tw[1].Value)).To(BeTrue())
+		})
+	})
+
+	When("the values go beyond 64 bits", func() {
+		BeforeEach(func() {
+			Expect(pp.EnableBulletproofs(128)).To(Succeed())
+			values = []*big.Int{new(big.Int).Lsh(big.NewInt(3), 100)}
+		})
+		It("succeeds", func() {
+			ct, err := disclosure.Encrypt(data[0], tw[0].Type, tw[0].Value, tw[0].BlindingFactor, pp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ct.Values).To(HaveLen(8))
+			Expect(ct.Verify(data[0], pp)).To(Succeed())
+			_, value, err := ct.Decrypt(sk, []string{"ABC"}, pp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(value.Equals(tw[0].Value)).To(BeTrue())
+		})
+	})
+
+	When("the ciphertexts do not match the token", func() {
+		It("fails", func() {
+			ct, err := disclosure.Encrypt(data[1], tw[1].Type, tw[1].Value, tw[1].BlindingFactor, pp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ct.Verify(data[2], pp)).NotTo(Succeed())
+
+			// encrypt another value
+			other, err := disclosure.Encrypt(data[2], tw[2].Type, tw[2].Value, tw[2].BlindingFactor, pp)
+			Expect(err).NotTo(HaveOccurred())
+			ct.Values[0] = other.Values[0]
+			err = ct.Verify(data[1], pp)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid token encryption for the auditor"))
+		})
+	})
+
+	When("a chunk of the value is out of range", func() {
+		It("fails", func() {
+			// the chunks sum up to the value of the token, but the second one cannot be decrypted
+			c := math.Curves[pp.Curve]
+			limbs := make([]*math.Zr, pp.AuditLimbs())
+			for j := range limbs {
+				limbs[j] = c.NewZrFromInt(0)
+			}
+			limbs[0] = c.ModAdd(tw[1].Value, c.NewZrFromInt(1<<pp.AuditEncryptionParams.LimbBitLength), c.GroupOrder)
+			limbs[1] = c.ModNeg(c.NewZrFromInt(1), c.GroupOrder)
+			ct, err := disclosure.EncryptLimbs(data[1], tw[1].Type, limbs, tw[1].BlindingFactor, pp)
+			Expect(err).NotTo(HaveOccurred())
+			err = ct.Verify(data[1], pp)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("chunks of the value out of range"))
+
+			// the range proof of another encryption does not help
+			other, err := disclosure.Encrypt(data[1], tw[1].Type, tw[1].Value, tw[1].BlindingFactor, pp)
+			Expect(err).NotTo(HaveOccurred())
+			ct.Proof.Range = other.Proof.Range
+			err = ct.Verify(data[1], pp)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("chunks of the value out of range"))
+		})
+	})
+
+	When("the ciphertexts are malformed", func() {
+		It("fails", func() {
+			ct, err := disclosure.Encrypt(data[1], tw[1].Type, tw[1].Value, tw[1].BlindingFactor, pp)
+			Expect(err).NotTo(HaveOccurred())
+			ct.Values = ct.Values[1:]
+			Expect(ct.Verify(data[1], pp)).NotTo(Succeed())
+		})
+	})
+
+	When("the type is not among the candidates", func() {
+		It("fails", func() {
+			ct, err := disclosure.Encrypt(data[1], tw[1].Type, tw[1].Value, tw[1].BlindingFactor, pp)
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = ct.Decrypt(sk, []string{"XYZ"}, pp)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("no candidate type matches"))
+		})
+	})
+
+	When("audit encryption is not enabled", func() {
+		It("fails", func() {
+			pp.AuditEncryptionParams = nil
+			_, err := disclosure.Encrypt(data[1], tw[1].Type, tw[1].Value, tw[1].BlindingFactor, pp)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,45 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package disclosure
+
+import (
+	math "github.com/IBM/mathlib"
+	"github.com/pkg/errors"
+)
+
+// dLogTable computes discrete logarithms in [0, 2^bitLength) with the baby-step giant-step algorithm
+type dLogTable struct {
+	babySteps map[string]int64
+	giantStep *math.G1
+	steps     int64
+	curve     *math.Curve
+}
+
+func newDLogTable(gen *math.G1, bitLength uint, c *math.Curve) *dLogTable {
+	steps := int64(1) << ((bitLength + 1) / 2)
+	t := &dLogTable{babySteps: make(map[string]int64, steps), steps: steps, curve: c}
+	acc := c.NewG1()
+	for i := int64(0); i < steps; i++ {
+		t.babySteps[string(acc.Bytes())] = i
+		acc.Add(gen)
+	}
+	// acc = gen^steps
+	t.giantStep = acc
+	return t
+}
+
+// dlog returns x such that M = gen^x
+func (t *dLogTable) dlog(M *math.G1) (int64, error) {
+	acc := M.Copy()
+	for i := int64(0); i < t.steps; i++ {
+		if j, ok := t.babySteps[string(acc.Bytes())]; ok {
+			return i*t.steps + j, nil
+		}
+		acc.Sub(t.giantStep)
+	}
+	return 0, errors.New("discrete logarithm out of range")
+}
@@ -0,0 +1,18 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package disclosure
+
+import (
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+)
+
+// EncryptLimbs encrypts the passed chunks of a value as they are, without proving that they are in range
+func EncryptLimbs(data *math.G1, ttype string, limbs []*math.Zr, bf *math.Zr, pp *crypto.PublicParams) (*Ciphertexts, error) {
+	ct, _, err := encrypt(data, ttype, limbs, bf, pp)
+	return ct, err
+}
@@ -0,0 +1,45 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package disclosure
+
+import (
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/elgamal"
+	"github.com/pkg/errors"
+)
+
+// NewSecretKey returns a fresh Elgamal key pair for an auditor of tokens under the passed public parameters.
+// The public key, sk.H, is enabled with PublicParams#EnableAuditEncryption.
+func NewSecretKey(pp *crypto.PublicParams) (*elgamal.SecretKey, error) {
+	if pp == nil || len(math.Curves) < int(pp.Curve)+1 {
+		return nil, errors.New("cannot generate auditor encryption key: invalid public parameters")
+	}
+	c := math.Curves[pp.Curve]
+	rand, err := c.Rand()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot generate auditor encryption key")
+	}
+	x := c.NewRandomZr(rand)
+	gen := crypto.AuditEncryptionGenerator(pp.Curve)
+	return elgamal.NewSecretKey(x, gen, gen.Mul(x), c), nil
+}
+
+// SecretKeyFromBytes returns the Elgamal key pair of an auditor from the serialization of its secret key,
+// see elgamal.SecretKey#Bytes
+func SecretKeyFromBytes(raw []byte, pp *crypto.PublicParams) (*elgamal.SecretKey, error) {
+	if pp == nil || len(math.Curves) < int(pp.Curve)+1 {
+		return nil, errors.New("cannot load auditor encryption key: invalid public parameters")
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("cannot load auditor encryption key: empty key")
+	}
+	c := math.Curves[pp.Curve]
+	x := c.NewZrFromBytes(raw)
+	gen := crypto.AuditEncryptionGenerator(pp.Curve)
+	return elgamal.NewSecretKey(x, gen, gen.Mul(x), c), nil
+}
@@ -97,3 +97,8 @@ func (pk *PublicKey) EncryptZr(m *math.Zr) (*Ciphertext, *math.Zr, error) {
 	c.C2.Add(pk.Gen.Mul(m))
 	return c, r, nil
 }
+
+// Bytes returns the serialization of the secret key x
+func (sk *SecretKey) Bytes() []byte {
+	return sk.x.Bytes()
+}
@@ -0,0 +1,18 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package anonym_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAnonymousIssuer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Anonymous Issuer Suite")
+}
@@ -0,0 +1,96 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package anonym
+
+import (
+	"math/big"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	issue2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/pkg/errors"
+)
+
+// Issuer produces anonymous IssueActions.
+// The actions do not carry the identity of the issuer, nor the type of the issued tokens,
+// and their signature only shows that the issuer is one of the anonymous issuers in the public parameters.
+// The identity of the issuer is disclosed only in the metadata of the issued tokens.
+type Issuer struct {
+	// Signer is expected to sign as one of the anonymous issuers, see Signer
+	Signer       common.SigningIdentity
+	PublicParams *crypto.PublicParams
+	Type         string
+	// WorkerPool bounds the goroutines used to generate the zero-knowledge proofs.
+	// If nil, as many goroutines as the available CPUs are used.
+	WorkerPool *common.WorkerPool
+}
+
+func (i *Issuer) New(ttype string, signer common.SigningIdentity, pp *crypto.PublicParams) {
+	i.Signer = signer
+	i.Type = ttype
+	i.PublicParams = pp
+}
+
+func (i *Issuer) GenerateZKIssue(values []*big.Int, owners [][]byte) (*issue2.IssueAction, []*token.Metadata, error) {
+	if i.PublicParams == nil {
+		return nil, nil, errors.New("failed to generate anonymous ZK Issue: nil public parameters")
+	}
+	if len(math.Curves) < int(i.PublicParams.Curve)+1 {
+		return nil, nil, errors.New("failed to generate anonymous ZK Issue: please initialize public parameters with an admissible curve")
+	}
+	if i.Signer == nil {
+		return nil, nil, errors.New("failed to generate anonymous ZK Issue: please initialize signer")
+	}
+	signerRaw, err := i.Signer.Serialize()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !IsIdentity(signerRaw) {
+		return nil, nil, errors.New("failed to generate anonymous ZK Issue: signer is not an anonymous issuer")
+	}
+
+	tokens, tw, err := token.GetTokensWithWitness(values, i.Type, i.PublicParams.PedParams, math.Curves[i.PublicParams.Curve])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prover := issue2.NewProver(tw, tokens, true, i.PublicParams, i.WorkerPool)
+	proof, err := prover.Prove()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate zero knowledge proof for anonymous issue")
+	}
+
+	issue, err := issue2.NewIssue(nil, tokens, owners, proof, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := token.EncryptForAuditor(issue.OutputTokens, tw, i.PublicParams, i.WorkerPool); err != nil {
+		return nil, nil, err
+	}
+
+	inf := make([]*token.Metadata, len(values))
+	for j := 0; j < len(inf); j++ {
+		inf[j] = &token.Metadata{
+			Type:           i.Type,
+			Value:          tw[j].Value,
+			BlindingFactor: tw[j].BlindingFactor,
+			Owner:          owners[j],
+			Issuer:         signerRaw,
+		}
+	}
+
+	return issue, inf, nil
+}
+
+func (i *Issuer) SignTokenActions(raw []byte, txID string) ([]byte, error) {
+	if i.Signer == nil {
+		return nil, errors.New("failed to sign Token Actions: please initialize signer")
+	}
+	return i.Signer.Sign(append(raw, []byte(txID)...))
+}
@@ -0,0 +1,151 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package anonym_test
+
+import (
+	"math/big"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	issue2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/anonym"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/nonanonym/mock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Anonymous Issuer", func() {
+	var (
+		pp *crypto.PublicParams
+
+		keys   []*anonym.SecretKey
+		issuer *anonym.Issuer
+
+		values []*big.Int
+		owners [][]byte
+	)
+	BeforeEach(func() {
+		var err error
+		pp, err = crypto.Setup(100, 2, nil, math.BN254)
+		Expect(err).NotTo(HaveOccurred())
+
+		keys = make([]*anonym.SecretKey, 3)
+		for i := 0; i < len(keys); i++ {
+			keys[i], err = anonym.NewSecretKey(pp)
+			Expect(err).NotTo(HaveOccurred())
+			id, err := keys[i].Identity()
+			Expect(err).NotTo(HaveOccurred())
+			pp.AddIssuer(id)
+		}
+
+		owners = [][]byte{[]byte("alice"), []byte("bob"), []byte("charlie")}
+		values = []*big.Int{big.NewInt(50), big.NewInt(30), big.NewInt(20)}
+
+		issuer = &anonym.Issuer{}
+		issuer.New("ABC", anonym.NewSigner(keys[1], pp), pp)
+	})
+
+	Describe("Ring", func() {
+		It("is padded to a power of two", func() {
+			ring, bitLength, err := anonym.Ring(pp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bitLength).To(Equal(2))
+			Expect(ring).To(HaveLen(4))
+			for i, key := range keys {
+				Expect(ring[i].Equals(key.PK)).To(BeTrue())
+			}
+		})
+		When("there are no anonymous issuers", func() {
+			It("fails", func() {
+				pp.Issuers = [][]byte{[]byte("issuer")}
+				_, _, err := anonym.Ring(pp)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("no anonymous issuers in public parameters"))
+			})
+		})
+	})
+
+	Describe("Sign", func() {
+		var message []byte
+		BeforeEach(func() {
+			message = []byte("message")
+		})
+		It("succeeds", func() {
+			sig, err := anonym.NewSigner(keys[2], pp).Sign(message)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(anonym.NewVerifier(pp).Verify(message, sig)).To(Succeed())
+		})
+		When("the message is tampered with", func() {
+			It("fails", func() {
+				sig, err := anonym.NewSigner(keys[0], pp).Sign(message)
+				Expect(err).NotTo(HaveOccurred())
+				err = anonym.NewVerifier(pp).Verify([]byte("another message"), sig)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid anonymous issuer signature"))
+			})
+		})
+		When("the key is not among the issuers", func() {
+			It("fails", func() {
+				key, err := anonym.NewSecretKey(pp)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = anonym.NewSigner(key, pp).Sign(message)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("public key is not among the issuers"))
+			})
+		})
+		When("the signature was produced under other public parameters", func() {
+			It("fails", func() {
+				other, err := crypto.Setup(100, 2, nil, math.BN254)
+				Expect(err).NotTo(HaveOccurred())
+				key, err := anonym.NewSecretKey(other)
+				Expect(err).NotTo(HaveOccurred())
+				id, err := key.Identity()
+				Expect(err).NotTo(HaveOccurred())
+				other.AddIssuer(id)
+				sig, err := anonym.NewSigner(key, other).Sign(message)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(anonym.NewVerifier(pp).Verify(message, sig)).NotTo(Succeed())
+			})
+		})
+	})
+
+	Describe("Issue", func() {
+		When("issue is computed correctly", func() {
+			It("succeeds", func() {
+				issue, metadata, err := issuer.GenerateZKIssue(values, owners)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(issue.IsAnonymous()).To(BeTrue())
+				Expect(issue.Issuer).To(BeEmpty())
+				commitments, err := issue.GetCommitments()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(issue2.NewVerifier(commitments, true, pp).Verify(issue.GetProof())).To(Succeed())
+
+				id, err := keys[1].Identity()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(metadata).To(HaveLen(3))
+				for _, m := range metadata {
+					Expect(m.Issuer).To(Equal([]byte(id)))
+				}
+
+				raw, err := issue.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				sig, err := issuer.SignTokenActions(raw, "0")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(anonym.NewVerifier(pp).Verify(append(raw, []byte("0")...), sig)).To(Succeed())
+			})
+		})
+		When("the signer is not an anonymous issuer", func() {
+			It("fails", func() {
+				signer := &mock.SigningIdentity{}
+				signer.SerializeReturns([]byte("issuer"), nil)
+				issuer.Signer = signer
+				_, _, err := issuer.GenerateZKIssue(values, owners)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("signer is not an anonymous issuer"))
+			})
+		})
+	})
+})
@@ -0,0 +1,138 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package anonym
+
+import (
+	"encoding/json"
+	"strconv"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/pkg/errors"
+)
+
+const (
+	// IdentityType is the type of the identities of anonymous issuers
+	IdentityType = "ai"
+	// paddingLabel is used to derive the public keys that pad the ring of the anonymous issuers,
+	// no one knows their secret keys
+	paddingLabel = "zkatdlog.anonymous-issuer.padding"
+)
+
+// SecretKey is the key pair of an anonymous issuer.
+// The public key is PK = Q^SK, where Q is the third Pedersen generator of the public parameters.
+type SecretKey struct {
+	SK *math.Zr
+	PK *math.G1
+}
+
+// NewSecretKey returns a fresh key pair for an anonymous issuer under the passed public parameters
+func NewSecretKey(pp *crypto.PublicParams) (*SecretKey, error) {
+	if err := checkParams(pp); err != nil {
+		return nil, errors.WithMessage(err, "cannot generate anonymous issuer key")
+	}
+	curve := math.Curves[pp.Curve]
+	rand, err := curve.Rand()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot generate anonymous issuer key")
+	}
+	sk := curve.NewRandomZr(rand)
+	return &SecretKey{SK: sk, PK: pp.PedParams[2].Mul(sk)}, nil
+}
+
+// Serialize marshals SecretKey
+func (k *SecretKey) Serialize() ([]byte, error) {
+	return json.Marshal(k)
+}
+
+// Deserialize un-marshals SecretKey
+func (k *SecretKey) Deserialize(raw []byte) error {
+	return json.Unmarshal(raw, k)
+}
+
+// Identity returns the identity of the anonymous issuer. This is what is listed in PublicParams.Issuers.
+func (k *SecretKey) Identity() (view.Identity, error) {
+	if k.PK == nil {
+		return nil, errors.New("cannot get anonymous issuer identity: nil public key")
+	}
+	return identity.MarshallRawOwner(&identity.RawOwner{Type: IdentityType, Identity: k.PK.Bytes()})
+}
+
+// IsIdentity returns true if the passed identity is the identity of an anonymous issuer
+func IsIdentity(id view.Identity) bool {
+	ro, err := identity.UnmarshallRawOwner(id)
+	return err == nil && ro.Type == IdentityType
+}
+
+// PublicKey returns the public key in the passed anonymous issuer identity
+func PublicKey(id view.Identity, curve *math.Curve) (*math.G1, error) {
+	ro, err := identity.UnmarshallRawOwner(id)
+	if err != nil {
+		return nil, err
+	}
+	if ro.Type != IdentityType {
+		return nil, errors.Errorf("identity of type [%s] is not an anonymous issuer", ro.Type)
+	}
+	pk, err := curve.NewG1FromBytes(ro.Identity)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid anonymous issuer public key")
+	}
+	return pk, nil
+}
+
+// Ring returns the public keys of the anonymous issuers listed in PublicParams.Issuers, in order,
+// and the logarithm of the size of the ring.
+// The ring is padded to a power of two, at least two, with public keys whose secret keys are unknown.
+func Ring(pp *crypto.PublicParams) ([]*math.G1, int, error) {
+	if err := checkParams(pp); err != nil {
+		return nil, 0, err
+	}
+	curve := math.Curves[pp.Curve]
+	var ring []*math.G1
+	for i, issuer := range pp.Issuers {
+		if !IsIdentity(issuer) {
+			continue
+		}
+		pk, err := PublicKey(issuer, curve)
+		if err != nil {
+			return nil, 0, errors.WithMessagef(err, "invalid issuer at index [%d]", i)
+		}
+		ring = append(ring, pk)
+	}
+	if len(ring) == 0 {
+		return nil, 0, errors.New("no anonymous issuers in public parameters")
+	}
+	bitLength := 1
+	for 1<<bitLength < len(ring) {
+		bitLength++
+	}
+	for i := len(ring); i < 1<<bitLength; i++ {
+		ring = append(ring, curve.HashToG1([]byte(paddingLabel+strconv.Itoa(i))))
+	}
+	return ring, bitLength, nil
+}
+
+// bases returns the generators of the one-out-of-many proofs: the public keys are commitments to zero
+// in the second one
+func bases(pp *crypto.PublicParams) []*math.G1 {
+	return []*math.G1{pp.PedParams[0], pp.PedParams[2]}
+}
+
+func checkParams(pp *crypto.PublicParams) error {
+	if pp == nil {
+		return errors.New("nil public parameters")
+	}
+	if len(math.Curves) < int(pp.Curve)+1 {
+		return errors.New("please initialize public parameters with an admissible curve")
+	}
+	if len(pp.PedParams) != 3 {
+		return errors.New("invalid Pedersen parameters")
+	}
+	return nil
+}
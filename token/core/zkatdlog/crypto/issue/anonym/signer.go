@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package anonym
+
+import (
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/o2omp"
+	"github.com/pkg/errors"
+)
+
+// Signer signs messages on behalf of one of the anonymous issuers, without revealing which one.
+// A signature is a one-out-of-many proof, bound to the message, that the signer knows the secret key
+// of one of the public keys in the ring of the anonymous issuers.
+type Signer struct {
+	Key          *SecretKey
+	PublicParams *crypto.PublicParams
+}
+
+// NewSigner returns a Signer for the passed key.
+// The ring of the anonymous issuers is taken from the passed public parameters.
+func NewSigner(key *SecretKey, pp *crypto.PublicParams) *Signer {
+	return &Signer{Key: key, PublicParams: pp}
+}
+
+// Sign returns a signature on the passed message
+func (s *Signer) Sign(message []byte) ([]byte, error) {
+	if s.Key == nil || s.Key.SK == nil || s.Key.PK == nil {
+		return nil, errors.New("cannot sign as anonymous issuer: please initialize key")
+	}
+	ring, bitLength, err := Ring(s.PublicParams)
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot sign as anonymous issuer")
+	}
+	index := -1
+	for i, pk := range ring {
+		if pk.Equals(s.Key.PK) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, errors.New("cannot sign as anonymous issuer: public key is not among the issuers")
+	}
+	return o2omp.NewProver(ring, message, bases(s.PublicParams), bitLength, index, s.Key.SK, math.Curves[s.PublicParams.Curve]).Prove()
+}
+
+// Serialize returns the identity of the anonymous issuer
+func (s *Signer) Serialize() ([]byte, error) {
+	if s.Key == nil {
+		return nil, errors.New("cannot serialize anonymous issuer: please initialize key")
+	}
+	return s.Key.Identity()
+}
+
+// Verifier checks signatures produced by any of the anonymous issuers
+type Verifier struct {
+	PublicParams *crypto.PublicParams
+}
+
+// NewVerifier returns a Verifier for the anonymous issuers listed in the passed public parameters
+func NewVerifier(pp *crypto.PublicParams) *Verifier {
+	return &Verifier{PublicParams: pp}
+}
+
+// Verify returns an error if the passed signature is not a valid signature on the passed message
+// by one of the anonymous issuers
+func (v *Verifier) Verify(message, sigma []byte) error {
+	ring, bitLength, err := Ring(v.PublicParams)
+	if err != nil {
+		return errors.WithMessage(err, "cannot verify anonymous issuer signature")
+	}
+	if err := o2omp.NewVerifier(ring, message, bases(v.PublicParams), bitLength, math.Curves[v.PublicParams.Curve]).Verify(sigma); err != nil {
+		return errors.Wrap(err, "invalid anonymous issuer signature")
+	}
+	return nil
+}
@@ -11,6 +11,8 @@ import (
 
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	rp "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/range"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
@@ -146,25 +148,17 @@ type Prover struct {
 	// WellFormedness encodes the WellFormedness Prover
 	WellFormedness *WellFormednessProver
 	// RangeCorrectness encodes the range proof Prover
-	RangeCorrectness *rp.Prover
+	RangeCorrectness rp.RangeProver
 }
 
-func NewProver(tw []*token.TokenDataWitness, tokens []*math.G1, anonymous bool, pp *crypto.PublicParams) *Prover {
+// NewProver returns a Prover for an IssueAction with the passed outputs.
+// The range proof is generated using the passed WorkerPool.
+func NewProver(tw []*token.TokenDataWitness, tokens []*math.G1, anonymous bool, pp *crypto.PublicParams, pool *common.WorkerPool) *Prover {
 	c := math.Curves[pp.Curve]
 	p := &Prover{}
 	p.WellFormedness = NewWellFormednessProver(tw, tokens, anonymous, pp.PedParams, c)
 
-	p.RangeCorrectness = rp.NewProver(
-		tw,
-		tokens,
-		pp.RangeProofParams.SignedValues,
-		int(pp.RangeProofParams.Exponent),
-		pp.PedParams,
-		pp.RangeProofParams.SignPK,
-		pp.PedGen,
-		pp.RangeProofParams.Q,
-		math.Curves[pp.Curve],
-	)
+	p.RangeCorrectness = rp.NewRangeProver(tw, tokens, pp, pool)
 
 	return p
 }
@@ -199,27 +193,24 @@ type Verifier struct {
 	// WellFormedness encodes the WellFormedness Verifier
 	WellFormedness *WellFormednessVerifier
 	// RangeCorrectness encodes the range proof verifier
-	RangeCorrectness *rp.Verifier
+	RangeCorrectness rp.RangeVerifier
 }
 
 func NewVerifier(tokens []*math.G1, anonymous bool, pp *crypto.PublicParams) *Verifier {
 	v := &Verifier{}
 	v.WellFormedness = NewWellFormednessVerifier(tokens, anonymous, pp.PedParams, math.Curves[pp.Curve])
-	v.RangeCorrectness = rp.NewVerifier(
-		tokens,
-		uint64(len(pp.RangeProofParams.SignedValues)),
-		int(pp.RangeProofParams.Exponent),
-		pp.PedParams,
-		pp.RangeProofParams.SignPK,
-		pp.PedGen,
-		pp.RangeProofParams.Q,
-		math.Curves[pp.Curve],
-	)
+	v.RangeCorrectness = rp.NewRangeVerifier(tokens, pp)
 	return v
 }
 
 // Verify returns an error if Proof of an IssueAction is invalid
 func (v *Verifier) Verify(proof []byte) error {
+	return v.VerifyBatch(proof, nil)
+}
+
+// VerifyBatch returns an error if Proof of an IssueAction is invalid, deferring to the passed batch
+// the checks of the range proof that support it. If the batch is nil, the proof is fully checked.
+func (v *Verifier) VerifyBatch(proof []byte, b *batch.Verifier) error {
 	if v.RangeCorrectness == nil || v.WellFormedness == nil {
 		return errors.New("please initialize issue action verifier correctly")
 	}
@@ -235,7 +226,7 @@ func (v *Verifier) Verify(proof []byte) error {
 		return errors.Wrapf(err, "invalid issue proof")
 	}
 	// verify RangeCorrectness proof
-	err = v.RangeCorrectness.Verify(ip.RangeCorrectness)
+	err = rp.VerifyBatch(v.RangeCorrectness, ip.RangeCorrectness, b)
 	if err != nil {
 		return errors.Wrapf(err, "invalid issue proof")
 	}
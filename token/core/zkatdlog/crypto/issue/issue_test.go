@@ -8,6 +8,7 @@ package issue_test
 import (
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/curvetest"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	. "github.com/onsi/ginkgo/v2"
@@ -35,6 +36,18 @@ var _ = Describe("Issue Correctness", func() {
 	})
 })
 
+var _ = DescribeTable("Issue Correctness on every supported curve",
+	func(curveID math.CurveID) {
+		pp, err := crypto.SetupWithCurves(100, 2, nil, crypto.DLogPublicParameters, curveID, curveID)
+		Expect(err).NotTo(HaveOccurred())
+		tw, tokens := prepareInputsForZKIssue(pp)
+		proof, err := issue.NewProver(tw, tokens, true, pp, nil).Prove()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(issue.NewVerifier(tokens, true, pp).Verify(proof)).To(Succeed())
+	},
+	curvetest.Entries(),
+)
+
 func prepareInputsForZKIssue(pp *crypto.PublicParams) ([]*token.TokenDataWitness, []*math.G1) {
 	values := make([]*math.Zr, 2)
 	values[0] = math.Curves[pp.Curve].NewZrFromInt(120)
@@ -47,7 +60,7 @@ func prepareInputsForZKIssue(pp *crypto.PublicParams) ([]*token.TokenDataWitness
 	}
 	ttype := "ABC"
 
-	tokens := PrepareTokens(values, bF, ttype, pp.PedParams)
+	tokens := PrepareTokens(values, bF, ttype, pp.PedParams, math.Curves[pp.Curve])
 	return token.NewTokenDataWitness(ttype, values, bF), tokens
 }
 
@@ -57,7 +70,7 @@ func prepareZKIssue() (*issue.Prover, *issue.Verifier) {
 
 	tw, tokens := prepareInputsForZKIssue(pp)
 
-	prover := issue.NewProver(tw, tokens, true, pp)
+	prover := issue.NewProver(tw, tokens, true, pp, nil)
 	verifier := issue.NewVerifier(tokens, true, pp)
 
 	return prover, verifier
@@ -6,13 +6,15 @@ SPDX-License-Identifier: Apache-2.0
 package issue
 
 import (
+	"math/big"
+
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 )
 
 type Issuer interface {
-	GenerateZKIssue(values []uint64, owners [][]byte) (*IssueAction, []*token.Metadata, error)
+	GenerateZKIssue(values []*big.Int, owners [][]byte) (*IssueAction, []*token.Metadata, error)
 
 	SignTokenActions(raw []byte, txID string) ([]byte, error)
 
@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package nonanonym
 
 import (
+	"math/big"
+
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
@@ -26,6 +28,9 @@ type Issuer struct {
 	Signer       SigningIdentity
 	PublicParams *crypto.PublicParams
 	Type         string
+	// WorkerPool bounds the goroutines used to generate the zero-knowledge proofs.
+	// If nil, as many goroutines as the available CPUs are used.
+	WorkerPool *common.WorkerPool
 }
 
 func (i *Issuer) New(ttype string, signer common.SigningIdentity, pp *crypto.PublicParams) {
@@ -34,7 +39,7 @@ func (i *Issuer) New(ttype string, signer common.SigningIdentity, pp *crypto.Pub
 	i.PublicParams = pp
 }
 
-func (i *Issuer) GenerateZKIssue(values []uint64, owners [][]byte) (*issue2.IssueAction, []*token.Metadata, error) {
+func (i *Issuer) GenerateZKIssue(values []*big.Int, owners [][]byte) (*issue2.IssueAction, []*token.Metadata, error) {
 	if i.PublicParams == nil {
 		return nil, nil, errors.New("failed to generate ZK Issue: nil public parameters")
 	}
@@ -46,7 +51,7 @@ func (i *Issuer) GenerateZKIssue(values []uint64, owners [][]byte) (*issue2.Issu
 		return nil, nil, err
 	}
 
-	prover := issue2.NewProver(tw, tokens, false, i.PublicParams)
+	prover := issue2.NewProver(tw, tokens, false, i.PublicParams, i.WorkerPool)
 	proof, err := prover.Prove()
 	if err != nil {
 		return nil, nil, errors.Errorf("failed to generate zero knwoledge proof for issue")
@@ -64,6 +69,9 @@ func (i *Issuer) GenerateZKIssue(values []uint64, owners [][]byte) (*issue2.Issu
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := token.EncryptForAuditor(issue.OutputTokens, tw, i.PublicParams, i.WorkerPool); err != nil {
+		return nil, nil, err
+	}
 
 	inf := make([]*token.Metadata, len(values))
 	for j := 0; j < len(inf); j++ {
@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package nonanonym_test
 
 import (
+	"math/big"
+
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	issue2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
@@ -24,7 +26,7 @@ var _ = Describe("Issuer", func() {
 
 		signer *mock.SigningIdentity
 
-		values []uint64
+		values []*big.Int
 		bf     []*math.Zr
 		owners [][]byte
 	)
@@ -37,7 +39,7 @@ var _ = Describe("Issuer", func() {
 		owners[1] = []byte("bob")
 		owners[2] = []byte("charlie")
 
-		values = []uint64{50, 30, 20}
+		values = []*big.Int{big.NewInt(50), big.NewInt(30), big.NewInt(20)}
 
 		bf = make([]*math.Zr, 3)
 		rand, err := math.Curves[pp.Curve].Rand()
@@ -61,12 +61,11 @@ func PrepareTokenWitness(pp []*math.G1) ([]*token.TokenDataWitness, []*math.G1,
 	values[0] = curve.NewZrFromInt(100)
 	values[1] = curve.NewZrFromInt(50)
 
-	tokens := PrepareTokens(values, bF, ttype, pp)
+	tokens := PrepareTokens(values, bF, ttype, pp, curve)
 	return token.NewTokenDataWitness(ttype, values, bF), tokens, bF
 }
 
-func PrepareTokens(values, bf []*math.Zr, ttype string, pp []*math.G1) []*math.G1 {
-	curve := math.Curves[1]
+func PrepareTokens(values, bf []*math.Zr, ttype string, pp []*math.G1, curve *math.Curve) []*math.G1 {
 	tokens := make([]*math.G1, len(values))
 	for i := 0; i < len(values); i++ {
 		tokens[i] = NewToken(values[i], bf[i], ttype, pp, curve)
@@ -9,6 +9,7 @@ import (
 	"io"
 
 	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/curvetest"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/o2omp"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -82,6 +83,27 @@ var _ = Describe("One out of many proof", func() {
 	})
 })
 
+var _ = DescribeTable("One out of many proof on every supported curve",
+	func(curveID math.CurveID) {
+		curve := math.Curves[curveID]
+		pp := getPedersenParameters(2, curve)
+		rand, err := curve.Rand()
+		Expect(err).NotTo(HaveOccurred())
+		randomness := curve.NewRandomZr(rand)
+		commitments := computePedersenCommitments(pp, 2, 4, randomness, curve)
+		verifier := o2omp.NewVerifier(commitments, []byte("message to be signed"), pp, 2, curve)
+
+		proof, err := o2omp.NewProver(commitments, []byte("message to be signed"), pp, 2, 2, randomness, curve).Prove()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verifier.Verify(proof)).To(Succeed())
+
+		proof, err = o2omp.NewProver(commitments, []byte("message to be signed"), pp, 2, 2, curve.NewRandomZr(rand), curve).Prove()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verifier.Verify(proof)).NotTo(Succeed())
+	},
+	curvetest.Entries(),
+)
+
 func getPedersenParameters(l int, curve *math.Curve) []*math.G1 {
 	rand, err := curve.Rand()
 	Expect(err).NotTo(HaveOccurred())
@@ -7,6 +7,7 @@ package pssign_test
 
 import (
 	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/curvetest"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -82,6 +83,31 @@ var _ = Describe("Pointcheval Sanders signatures", func() {
 	})
 })
 
+var _ = DescribeTable("Pointcheval Sanders signatures on every supported curve",
+	func(curveID math.CurveID) {
+		curve := math.Curves[curveID]
+		signer := pssign.NewSigner(nil, nil, nil, curve)
+		Expect(signer.KeyGen(2)).To(Succeed())
+		m := getMessages(2, curve)
+		sig, err := signer.Sign(m)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signer.Randomize(sig)).To(Succeed())
+		Expect(signer.SignVerifier.Verify(append(m, hashMessages(m, curve)), sig)).To(Succeed())
+
+		raw, err := signer.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		deserialized := pssign.NewSigner(nil, nil, nil, curve)
+		Expect(deserialized.Deserialize(raw)).To(Succeed())
+		Expect(deserialized.SignVerifier.Verify(append(m, hashMessages(m, curve)), sig)).To(Succeed())
+
+		m[0] = getMessages(1, curve)[0]
+		err = signer.SignVerifier.Verify(append(m, hashMessages(m, curve)), sig)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("invalid Pointcheval-Sanders signature"))
+	},
+	curvetest.Entries(),
+)
+
 func getMessages(length int, curve *math.Curve) []*math.Zr {
 	rand, err := curve.Rand()
 	Expect(err).NotTo(HaveOccurred())
@@ -0,0 +1,91 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rangeproof
+
+import (
+	mathlib "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/bulletproof"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+)
+
+// RangeProver produces proofs that the values of an array of tokens are in the authorized range
+type RangeProver interface {
+	Prove() ([]byte, error)
+}
+
+// RangeVerifier checks the proofs produced by a RangeProver
+type RangeVerifier interface {
+	Verify(proof []byte) error
+}
+
+// BatchRangeVerifier is a RangeVerifier that can defer part of its checks to a batch
+type BatchRangeVerifier interface {
+	RangeVerifier
+	VerifyBatch(proof []byte, b *batch.Verifier) error
+}
+
+// VerifyBatch checks the passed proof with the passed RangeVerifier.
+// If the verifier supports it and the batch is not nil, part of the checks are deferred to the batch,
+// otherwise the proof is fully checked.
+func VerifyBatch(v RangeVerifier, proof []byte, b *batch.Verifier) error {
+	if bv, ok := v.(BatchRangeVerifier); ok && b != nil {
+		return bv.VerifyBatch(proof, b)
+	}
+	return v.Verify(proof)
+}
+
+// NewRangeProver returns the RangeProver selected by the passed public parameters.
+// The prover generates its sub-proofs concurrently using the passed WorkerPool.
+func NewRangeProver(tw []*token.TokenDataWitness, tokens []*mathlib.G1, pp *crypto.PublicParams, pool *common.WorkerPool) RangeProver {
+	if pp.Bulletproofs() {
+		tokenType := ""
+		values := make([]*mathlib.Zr, len(tw))
+		blindingFactors := make([]*mathlib.Zr, len(tw))
+		for i, w := range tw {
+			if w == nil {
+				continue
+			}
+			tokenType, values[i], blindingFactors[i] = w.Type, w.Value, w.BlindingFactor
+		}
+		p := bulletproof.NewProver(tokenType, values, blindingFactors, tokens, int(pp.BulletproofParams.BitLength), pp.PedParams, mathlib.Curves[pp.Curve])
+		p.WorkerPool = pool
+		return p
+	}
+	p := NewProver(
+		tw,
+		tokens,
+		pp.RangeProofParams.SignedValues,
+		int(pp.RangeProofParams.Exponent),
+		pp.PedParams,
+		pp.RangeProofParams.SignPK,
+		pp.PedGen,
+		pp.RangeProofParams.Q,
+		mathlib.Curves[pp.Curve],
+	)
+	p.WorkerPool = pool
+	return p
+}
+
+// NewRangeVerifier returns the RangeVerifier selected by the passed public parameters
+func NewRangeVerifier(tokens []*mathlib.G1, pp *crypto.PublicParams) RangeVerifier {
+	if pp.Bulletproofs() {
+		return bulletproof.NewVerifier(tokens, int(pp.BulletproofParams.BitLength), pp.PedParams, mathlib.Curves[pp.Curve])
+	}
+	return NewVerifier(
+		tokens,
+		uint64(len(pp.RangeProofParams.SignedValues)),
+		int(pp.RangeProofParams.Exponent),
+		pp.PedParams,
+		pp.RangeProofParams.SignPK,
+		pp.PedGen,
+		pp.RangeProofParams.Q,
+		mathlib.Curves[pp.Curve],
+	)
+}
@@ -64,6 +64,9 @@ type Prover struct {
 	tokenWitness []*token.TokenDataWitness
 	// Signatures are an array of Pointcheval-Sanders signatures
 	Signatures []*pssign.Signature
+	// WorkerPool bounds the goroutines used to generate the membership proofs.
+	// If nil, as many goroutines as the available CPUs are used.
+	WorkerPool *common.WorkerPool
 }
 
 // NewProver returns a Prover
@@ -147,36 +150,22 @@ func (p *Prover) Prove() ([]byte, error) {
 		return nil, err
 	}
 
-	var wg sync.WaitGroup
-	wg.Add(len(p.Tokens) * p.Exponent)
-
-	parallelErr := &atomic.Value{}
-
 	// produce proof that each committed value is signed
 	proof.MembershipProofs = make([]*MembershipProof, len(p.Tokens))
 	for k := 0; k < len(proof.MembershipProofs); k++ {
 		proof.MembershipProofs[k] = &MembershipProof{}
-		proof.MembershipProofs[k].Commitments = make([]*mathlib.G1, p.Exponent)
+		proof.MembershipProofs[k].Commitments = preProcessed.commitmentsToValues[k]
 		proof.MembershipProofs[k].SignatureProofs = make([]*sigproof.MembershipProof, p.Exponent)
-		for i := 0; i < p.Exponent; i++ {
-			proof.MembershipProofs[k].Commitments[i] = preProcessed.commitmentsToValues[k][i]
-			mp := sigproof.NewMembershipProver(preProcessed.membershipWitnesses[k][i], proof.MembershipProofs[k].Commitments[i], p.P, p.Q, p.PK, p.PedersenParams[:2], p.Curve)
-
-			go func(k, i int) {
-				defer wg.Done()
-				var err error
-				proof.MembershipProofs[k].SignatureProofs[i], err = mp.Prove()
-				if err != nil {
-					parallelErr.Store(err)
-				}
-			}(k, i)
-		}
 	}
-
-	wg.Wait()
-
-	if parallelErr.Load() != nil {
-		return nil, parallelErr.Load().(error)
+	err = p.WorkerPool.Run(len(p.Tokens)*p.Exponent, func(j int) error {
+		k, i := j/p.Exponent, j%p.Exponent
+		mp := sigproof.NewMembershipProver(preProcessed.membershipWitnesses[k][i], proof.MembershipProofs[k].Commitments[i], p.P, p.Q, p.PK, p.PedersenParams[:2], p.Curve)
+		var err error
+		proof.MembershipProofs[k].SignatureProofs[i], err = mp.Prove()
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// show that value in token = \prod_{i=0}^Exponent com_i^{Base^i}
@@ -294,25 +283,28 @@ func (p *Prover) preProcess() (*rangeProofWitness, error) {
 	if int(p.Base) > len(p.Signatures) {
 		return nil, errors.New("invalid range proof parameters")
 	}
-	rand, err := p.Curve.Rand()
-	if err != nil {
-		return nil, err
+	if len(p.PedersenParams) != 3 {
+		return nil, errors.New("invalid Pedersen parameters")
 	}
 	membershipWitness := make([][]*sigproof.MembershipWitness, len(p.tokenWitness))
 	commitmentBlindingFactor := make([]*mathlib.Zr, len(p.tokenWitness))
 	coms := make([][]*mathlib.G1, len(p.tokenWitness))
 
-	for k := 0; k < len(p.tokenWitness); k++ {
+	err := p.WorkerPool.Run(len(p.tokenWitness), func(k int) error {
+		rand, err := p.Curve.Rand()
+		if err != nil {
+			return err
+		}
 		values := make([]int, p.Exponent)
 		if p.tokenWitness[k] == nil || p.tokenWitness[k].Value == nil {
-			return nil, errors.New("can't compute range proof: please provide valid token witness")
+			return errors.New("can't compute range proof: please provide valid token witness")
 		}
 		v, err := p.tokenWitness[k].Value.Int()
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if v >= int64(math.Pow(float64(p.Base), float64(p.Exponent))) {
-			return nil, errors.Errorf("can't compute range proof: value of token outside authorized range")
+			return errors.Errorf("can't compute range proof: value of token outside authorized range")
 		}
 		// v = \sum_{i=0}^Exponent values[i] Base^i
 		values[0] = int(v % int64(p.Base))
@@ -326,16 +318,13 @@ func (p *Prover) preProcess() (*rangeProofWitness, error) {
 		membershipWitness[k] = make([]*sigproof.MembershipWitness, p.Exponent)
 		commitmentBlindingFactor[k] = p.Curve.NewZrFromInt(0)
 		coms[k] = make([]*mathlib.G1, p.Exponent)
-		if len(p.PedersenParams) != 3 {
-			return nil, errors.New("invalid Pedersen parameters")
-		}
 
 		for i := 0; i < p.Exponent; i++ {
 			bf := p.Curve.NewRandomZr(rand)
 			// compute Pedersen commitment to values[i]
 			coms[k][i], err = common.ComputePedersenCommitment([]*mathlib.Zr{p.Curve.NewZrFromInt(int64(values[i])), bf}, p.PedersenParams[:2], p.Curve)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			// membershipWitness contains Pointcheval-Sanders signature of values[i], values[i] and the blinding factor used to compute the commitment to values[i]
 			membershipWitness[k][i] = sigproof.NewMembershipWitness(p.Signatures[values[i]], p.Curve.NewZrFromInt(int64(values[i])), bf)
@@ -343,6 +332,10 @@ func (p *Prover) preProcess() (*rangeProofWitness, error) {
 			// this is the blinding factor to commitment  c = \prod_{i=0}^Exponent com[k][i]^{Base^i}
 			commitmentBlindingFactor[k] = p.Curve.ModAdd(commitmentBlindingFactor[k], p.Curve.ModMul(bf, pow, p.Curve.GroupOrder), p.Curve.GroupOrder)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return &rangeProofWitness{
 		commitmentsToValues:                coms,
@@ -6,7 +6,14 @@ SPDX-License-Identifier: Apache-2.0
 package rangeproof_test
 
 import (
+	"fmt"
+	"math/big"
+	"testing"
+
 	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/curvetest"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
 	rp "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/range"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
@@ -32,8 +39,80 @@ var _ = Describe("range proof", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 	})
+	Context("when the proof is generated by a bounded worker pool", func() {
+		It("Succeeds with any number of workers", func() {
+			pp, err := crypto.Setup(100, 2, nil, math.FP256BN_AMCL)
+			Expect(err).NotTo(HaveOccurred())
+			for _, workers := range []int{1, 3} {
+				prover, verifier, err := prepareRangeProof(pp, 20, common.NewWorkerPool(workers))
+				Expect(err).NotTo(HaveOccurred())
+				proof, err := prover.Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(verifier.Verify(proof)).To(Succeed())
+			}
+		})
+	})
 })
 
+var _ = DescribeTable("range proof on every supported curve",
+	func(curveID math.CurveID, bulletproofs bool) {
+		pp, err := crypto.SetupWithCurves(100, 2, nil, crypto.DLogPublicParameters, curveID, curveID)
+		Expect(err).NotTo(HaveOccurred())
+		if bulletproofs {
+			Expect(pp.EnableBulletproofs(64)).To(Succeed())
+		}
+		prover, verifier, err := prepareRangeProof(pp, 3, nil)
+		Expect(err).NotTo(HaveOccurred())
+		proof, err := prover.Prove()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verifier.Verify(proof)).To(Succeed())
+	},
+	curvetest.EntriesWithBulletproofs(),
+)
+
+func BenchmarkRangeProve(b *testing.B) {
+	for _, bulletproofs := range []bool{false, true} {
+		pp, err := crypto.Setup(100, 2, nil, math.FP256BN_AMCL)
+		if err != nil {
+			b.Fatal(err)
+		}
+		name := "signatures"
+		if bulletproofs {
+			name = "bulletproofs"
+			if err := pp.EnableBulletproofs(64); err != nil {
+				b.Fatal(err)
+			}
+		}
+		for _, workers := range []int{1, 2, 4, 8} {
+			b.Run(fmt.Sprintf("%s/outputs=20/workers=%d", name, workers), func(b *testing.B) {
+				prover, _, err := prepareRangeProof(pp, 20, common.NewWorkerPool(workers))
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := prover.Prove(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// prepareRangeProof returns a prover and a verifier for the range proof of the passed number of tokens
+func prepareRangeProof(pp *crypto.PublicParams, tokens int, pool *common.WorkerPool) (rp.RangeProver, rp.RangeVerifier, error) {
+	values := make([]*big.Int, tokens)
+	for i := 0; i < tokens; i++ {
+		values[i] = big.NewInt(int64(100 * i))
+	}
+	toks, tw, err := token.GetTokensWithWitness(values, "ABC", pp.PedParams, math.Curves[pp.Curve])
+	if err != nil {
+		return nil, nil, err
+	}
+	return rp.NewRangeProver(tw, toks, pp, pool), rp.NewRangeVerifier(toks, pp), nil
+}
+
 func getRangeProver() *rp.Prover {
 	c := math.Curves[1]
 	signatures := make([]*pssign.Signature, 2)
@@ -10,17 +10,36 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"math"
+	"time"
 
 	mathlib "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/idemix"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/pkg/errors"
 )
 
 const (
-	DLogPublicParameters = "zkatdlog"
-	DefaultPrecision     = uint64(64)
+	DLogPublicParameters            = "zkatdlog"
+	DLogGraphHidingPublicParameters = "zkatdloggh"
+	DefaultPrecision                = uint64(64)
+	// MaxAnonymitySetBitLength bounds the size of the anonymity sets of graph hiding transfers
+	MaxAnonymitySetBitLength = 10
+	// MaxBulletproofBitLength bounds the number of bits of the values proven in range with Bulletproofs.
+	// Values are elements of a group whose order is smaller than 2^256,
+	// larger values would let the sums of inputs and outputs wrap around.
+	MaxBulletproofBitLength = 128
+	// DefaultAuditLimbBitLength is the default bit length of the chunks of the values encrypted for the auditor
+	DefaultAuditLimbBitLength = 16
+	// MaxAuditLimbBitLength bounds the bit length of the chunks of the values encrypted for the auditor,
+	// the auditor recovers each chunk by exhaustive search
+	MaxAuditLimbBitLength = 32
+	// auditEncryptionGenLabel is used to derive the generator of the Elgamal scheme of the auditor
+	auditEncryptionGenLabel = "zkatdlog.audit-encryption.generator"
+	// FabTokenPredecessor is the identifier of the fabtoken public parameters,
+	// the driver whose tokens can be migrated to zkatdlog
+	FabTokenPredecessor = "fabtoken"
 )
 
 type PublicParams struct {
@@ -35,6 +54,9 @@ type PublicParams struct {
 	PedParams []*mathlib.G1
 	// RangeProofParams contains the public parameters for the range proof scheme.
 	RangeProofParams *RangeProofParams
+	// BulletproofParams contains the public parameters for the Bulletproofs range proofs.
+	// If set, they are used in place of RangeProofParams.
+	BulletproofParams *BulletproofParams `json:",omitempty"`
 	// IdemixCurveID is the pairing-friendly curve used for the idemix scheme.
 	IdemixCurveID mathlib.CurveID
 	// IdemixIssuerPK is the public key of the issuer of the idemix scheme.
@@ -47,6 +69,87 @@ type PublicParams struct {
 	MaxToken uint64
 	// QuantityPrecision is the precision used to represent quantities
 	QuantityPrecision uint64
+	// Revoked is the list of enrollment IDs that are not allowed to transact
+	Revoked []string `json:",omitempty"`
+	// GraphHidingParams contains the public parameters of graph hiding transfers, if enabled.
+	GraphHidingParams *GraphHidingParams `json:",omitempty"`
+	// AuditEncryptionParams contains the public parameters used to encrypt the type and the value of the outputs
+	// for the auditor, if enabled.
+	AuditEncryptionParams *AuditEncryptionParams `json:",omitempty"`
+	// MigrationParams declare the driver these public parameters succeed, if a migration is in progress.
+	MigrationParams *MigrationParams `json:",omitempty"`
+}
+
+// MigrationParams declare that the public parameters succeed those of another driver.
+// During the migration window, the outputs created with the predecessor are spent in the clear by upgrade transfers,
+// whose outputs are zkatdlog tokens.
+type MigrationParams struct {
+	// Predecessor is the identifier of the public parameters of the driver the tokens are migrated from
+	Predecessor string
+	// Deadline closes the migration window. If zero, the window stays open until the public parameters
+	// are updated without MigrationParams.
+	Deadline time.Time
+}
+
+func (mp *MigrationParams) Validate() error {
+	if mp.Predecessor != FabTokenPredecessor {
+		return errors.Errorf("invalid migration parameters: cannot migrate from [%s], only [%s] is supported", mp.Predecessor, FabTokenPredecessor)
+	}
+	return nil
+}
+
+// AuditEncryptionParams contains the public parameters used to encrypt the type and the value of the outputs
+// under the Elgamal key of an auditor.
+type AuditEncryptionParams struct {
+	// Gen is the generator of the Elgamal scheme
+	Gen *mathlib.G1
+	// PK is the Elgamal public key of the auditor, PK = Gen^x
+	PK *mathlib.G1
+	// LimbBitLength is the bit length of the chunks of the values encrypted separately,
+	// so that the auditor can recover each of them by exhaustive search
+	LimbBitLength uint
+}
+
+func (aep *AuditEncryptionParams) Validate() error {
+	if aep.Gen == nil || aep.PK == nil {
+		return errors.New("invalid audit encryption parameters: nil generator or public key")
+	}
+	if aep.LimbBitLength == 0 || aep.LimbBitLength > MaxAuditLimbBitLength || aep.LimbBitLength&(aep.LimbBitLength-1) != 0 {
+		return errors.Errorf("invalid audit encryption parameters: limb bit length must be a power of 2 in (0, %d], got [%d]", MaxAuditLimbBitLength, aep.LimbBitLength)
+	}
+	return nil
+}
+
+// GraphHidingParams contains the public parameters used to spend tokens without revealing which ones.
+type GraphHidingParams struct {
+	// SerialNumberGen is the generator used to commit to the serial nonces, and to derive the serial numbers, of the tokens.
+	SerialNumberGen *mathlib.G1
+	// AnonymitySetBitLength is the logarithm of the size of the anonymity sets the spent tokens are hidden in.
+	AnonymitySetBitLength uint
+}
+
+func (ghp *GraphHidingParams) Validate() error {
+	if ghp.SerialNumberGen == nil {
+		return errors.New("invalid graph hiding parameters: nil serial number generator")
+	}
+	if ghp.AnonymitySetBitLength == 0 || ghp.AnonymitySetBitLength > MaxAnonymitySetBitLength {
+		return errors.Errorf("invalid graph hiding parameters: anonymity set bit length should be in [1, %d], instead it is %d", MaxAnonymitySetBitLength, ghp.AnonymitySetBitLength)
+	}
+	return nil
+}
+
+// BulletproofParams contains the public parameters of the Bulletproofs range proofs.
+// The generators of the proofs are derived by hashing, then no trusted setup is needed.
+type BulletproofParams struct {
+	// BitLength is the number of bits of the values proven in range
+	BitLength uint
+}
+
+func (bpp *BulletproofParams) Validate() error {
+	if bpp.BitLength == 0 || bpp.BitLength > MaxBulletproofBitLength || bpp.BitLength&(bpp.BitLength-1) != 0 {
+		return errors.Errorf("invalid bulletproof parameters: bit length should be a power of 2 in [1, %d], instead it is %d", MaxBulletproofBitLength, bpp.BitLength)
+	}
+	return nil
 }
 
 type RangeProofParams struct {
@@ -104,7 +207,85 @@ func (pp *PublicParams) TokenDataHiding() bool {
 }
 
 func (pp *PublicParams) GraphHiding() bool {
-	return false
+	return pp.GraphHidingParams != nil
+}
+
+// Bulletproofs returns true if the range proofs are Bulletproofs
+func (pp *PublicParams) Bulletproofs() bool {
+	return pp.BulletproofParams != nil
+}
+
+// EnableBulletproofs replaces the range proofs based on signed values with Bulletproofs
+// over values of the passed number of bits.
+// Bit lengths larger than DefaultPrecision raise the quantity precision accordingly.
+func (pp *PublicParams) EnableBulletproofs(bitLength uint) error {
+	bpp := &BulletproofParams{BitLength: bitLength}
+	if err := bpp.Validate(); err != nil {
+		return err
+	}
+	pp.BulletproofParams = bpp
+	pp.RangeProofParams = nil
+	pp.QuantityPrecision = pp.ComputeQuantityPrecision()
+	pp.MaxToken = pp.ComputeMaxTokenValue()
+	return nil
+}
+
+// AuditEncryption returns true if the outputs carry the encryption of their type and value for the auditor
+func (pp *PublicParams) AuditEncryption() bool {
+	return pp.AuditEncryptionParams != nil
+}
+
+// AuditEncryptionGenerator returns the generator of the Elgamal scheme used to encrypt the outputs for the auditor
+func AuditEncryptionGenerator(curveID mathlib.CurveID) *mathlib.G1 {
+	return mathlib.Curves[curveID].HashToG1([]byte(auditEncryptionGenLabel))
+}
+
+// EnableAuditEncryption instructs the outputs to carry the encryption of their type and value under the passed
+// Elgamal public key, PK = AuditEncryptionGenerator(pp.Curve)^x.
+// Replacing the key lets a different auditor reconstruct the outputs created from then on.
+func (pp *PublicParams) EnableAuditEncryption(pk *mathlib.G1) error {
+	aep := &AuditEncryptionParams{
+		Gen:           AuditEncryptionGenerator(pp.Curve),
+		PK:            pk,
+		LimbBitLength: DefaultAuditLimbBitLength,
+	}
+	if err := aep.Validate(); err != nil {
+		return err
+	}
+	pp.AuditEncryptionParams = aep
+	return nil
+}
+
+// AuditLimbs returns the number of chunks the values are split into when encrypted for the auditor
+func (pp *PublicParams) AuditLimbs() int {
+	if pp.AuditEncryptionParams == nil || pp.AuditEncryptionParams.LimbBitLength == 0 {
+		return 0
+	}
+	l := uint64(pp.AuditEncryptionParams.LimbBitLength)
+	return int((pp.QuantityPrecision + l - 1) / l)
+}
+
+// Migration returns true if the public parameters succeed those of another driver and the migration
+// window is open at the passed time
+func (pp *PublicParams) Migration(now time.Time) bool {
+	if pp.MigrationParams == nil {
+		return false
+	}
+	return pp.MigrationParams.Deadline.IsZero() || now.Before(pp.MigrationParams.Deadline)
+}
+
+// EnableMigration declares that the public parameters succeed those of the passed driver.
+// Upgrade transfers are accepted until the passed deadline, or until the migration is disabled if the deadline is zero.
+func (pp *PublicParams) EnableMigration(predecessor string, deadline time.Time) error {
+	mp := &MigrationParams{Predecessor: predecessor, Deadline: deadline}
+	if err := mp.Validate(); err != nil {
+		return err
+	}
+	if pp.GraphHiding() {
+		return errors.New("cannot migrate to graph hiding public parameters")
+	}
+	pp.MigrationParams = mp
+	return nil
 }
 
 func (pp *PublicParams) MaxTokenValue() uint64 {
@@ -189,6 +370,14 @@ func (pp *PublicParams) AddIssuer(id view.Identity) {
 	pp.Issuers = append(pp.Issuers, id)
 }
 
+func (pp *PublicParams) AddRevokedEnrollmentID(eid string) {
+	pp.Revoked = append(pp.Revoked, eid)
+}
+
+func (pp *PublicParams) RevokedEnrollmentIDs() []string {
+	return pp.Revoked
+}
+
 func (pp *PublicParams) ComputeHash() ([]byte, error) {
 	raw, err := pp.Bytes()
 	if err != nil {
@@ -205,7 +394,24 @@ func (pp *PublicParams) ComputeHash() ([]byte, error) {
 	return hash.Sum(nil), nil
 }
 
+// ComputeQuantityPrecision returns the precision of the quantities the range proofs can handle.
+// It is DefaultPrecision, unless Bulletproofs cover more bits.
+func (pp *PublicParams) ComputeQuantityPrecision() uint64 {
+	if pp.BulletproofParams != nil && uint64(pp.BulletproofParams.BitLength) > DefaultPrecision {
+		return uint64(pp.BulletproofParams.BitLength)
+	}
+	return DefaultPrecision
+}
+
+// ComputeMaxTokenValue returns the maximum value the range proofs can handle.
+// If it does not fit in an uint64, it returns math.MaxUint64 and the maximum is bound by the quantity precision.
 func (pp *PublicParams) ComputeMaxTokenValue() uint64 {
+	if pp.BulletproofParams != nil {
+		if pp.BulletproofParams.BitLength >= 64 {
+			return math.MaxUint64
+		}
+		return 1<<pp.BulletproofParams.BitLength - 1
+	}
 	return uint64(math.Pow(float64(len(pp.RangeProofParams.SignedValues)), float64(pp.RangeProofParams.Exponent))) - 1
 }
 
@@ -222,12 +428,24 @@ func Setup(base uint, exponent uint, nymPK []byte, idemixCurveID mathlib.CurveID
 }
 
 func SetupWithCustomLabel(base uint, exponent uint, nymPK []byte, label string, idemixCurveID mathlib.CurveID) (*PublicParams, error) {
-	signer := pssign.NewSigner(nil, nil, nil, mathlib.Curves[mathlib.BN254])
+	return SetupWithCurves(base, exponent, nymPK, label, DefaultCurve, idemixCurveID)
+}
+
+// SetupWithCurves generates public parameters whose commitments and range proofs are on the passed curve,
+// and whose owners are idemix identities on the passed idemix curve.
+func SetupWithCurves(base uint, exponent uint, nymPK []byte, label string, curveID mathlib.CurveID, idemixCurveID mathlib.CurveID) (*PublicParams, error) {
+	if !validCurve(curveID) {
+		return nil, errors.Errorf("unsupported curve [%d]", curveID)
+	}
+	if !validCurve(idemixCurveID) {
+		return nil, errors.Errorf("unsupported idemix curve [%d]", idemixCurveID)
+	}
+	signer := pssign.NewSigner(nil, nil, nil, mathlib.Curves[curveID])
 	err := signer.KeyGen(1)
 	if err != nil {
 		return nil, err
 	}
-	pp := &PublicParams{Curve: mathlib.BN254}
+	pp := &PublicParams{Curve: curveID}
 	pp.Label = label
 	if err := pp.GeneratePedersenParameters(); err != nil {
 		return nil, errors.Wrapf(err, "failed to generated pedersen parameters")
@@ -243,12 +461,37 @@ func SetupWithCustomLabel(base uint, exponent uint, nymPK []byte, label string,
 	return pp, nil
 }
 
+// SetupGraphHiding generates public parameters for graph hiding transfers.
+// The Pedersen commitments use hRand, the base of the randomness of the idemix pseudonyms, to blind the tokens,
+// then the spent tokens and the pseudonyms of their owners are proven to be in the anonymity set together.
+// The tokens are on the same curve as the idemix pseudonyms.
+func SetupGraphHiding(base uint, exponent uint, nymPK []byte, idemixCurveID mathlib.CurveID, hRand *mathlib.G1, bitLength uint) (*PublicParams, error) {
+	if hRand == nil {
+		return nil, errors.New("nil pseudonym randomness base")
+	}
+	pp, err := SetupWithCurves(base, exponent, nymPK, DLogGraphHidingPublicParameters, idemixCurveID, idemixCurveID)
+	if err != nil {
+		return nil, err
+	}
+	curve := mathlib.Curves[pp.Curve]
+	rand, err := curve.Rand()
+	if err != nil {
+		return nil, errors.Errorf("failed to get RNG")
+	}
+	pp.PedParams[2] = hRand
+	pp.GraphHidingParams = &GraphHidingParams{
+		SerialNumberGen:       curve.GenG1.Mul(curve.NewRandomZr(rand)),
+		AnonymitySetBitLength: bitLength,
+	}
+	return pp, nil
+}
+
 func (pp *PublicParams) Validate() error {
-	if int(pp.Curve) > len(mathlib.Curves)-1 {
-		return errors.Errorf("invalid public parameters: invalid curveID [%d > %d]", int(pp.Curve), len(mathlib.Curves)-1)
+	if !validCurve(pp.Curve) {
+		return errors.Errorf("invalid public parameters: invalid curveID [%d]", int(pp.Curve))
 	}
-	if int(pp.IdemixCurveID) > len(mathlib.Curves)-1 {
-		return errors.Errorf("invalid public parameters: invalid idemix curveID [%d > %d]", int(pp.Curve), len(mathlib.Curves)-1)
+	if !validCurve(pp.IdemixCurveID) {
+		return errors.Errorf("invalid public parameters: invalid idemix curveID [%d]", int(pp.IdemixCurveID))
 	}
 	if pp.PedGen == nil {
 		return errors.New("invalid public parameters: nil Pedersen generator")
@@ -261,19 +504,56 @@ func (pp *PublicParams) Validate() error {
 			return errors.Errorf("invalid public parameters: nil Pedersen parameter at index %d", i)
 		}
 	}
-	if pp.RangeProofParams == nil {
-		return errors.New("invalid public parameters: nil range proof parameters")
-	}
-	err := pp.RangeProofParams.Validate()
-	if err != nil {
-		return errors.Wrap(err, "invalid public parameters")
+	if pp.BulletproofParams != nil {
+		if pp.RangeProofParams != nil {
+			return errors.New("invalid public parameters: both range proof and bulletproof parameters are set")
+		}
+		if err := pp.BulletproofParams.Validate(); err != nil {
+			return errors.Wrap(err, "invalid public parameters")
+		}
+	} else {
+		if pp.RangeProofParams == nil {
+			return errors.New("invalid public parameters: nil range proof parameters")
+		}
+		if err := pp.RangeProofParams.Validate(); err != nil {
+			return errors.Wrap(err, "invalid public parameters")
+		}
 	}
-	if pp.QuantityPrecision != DefaultPrecision {
-		return errors.Errorf("invalid public parameters: quantity precision should be %d instead it is %d", DefaultPrecision, pp.QuantityPrecision)
+	if precision := pp.ComputeQuantityPrecision(); pp.QuantityPrecision != precision {
+		return errors.Errorf("invalid public parameters: quantity precision should be %d instead it is %d", precision, pp.QuantityPrecision)
 	}
 	if len(pp.IdemixIssuerPK) == 0 {
 		return errors.New("invalid public parameters: empty idemix issuer")
 	}
+	if pp.GraphHidingParams != nil {
+		if pp.IdemixCurveID != pp.Curve {
+			return errors.Errorf("invalid public parameters: graph hiding requires idemix on curve [%d], got [%d]", pp.Curve, pp.IdemixCurveID)
+		}
+		if err := pp.GraphHidingParams.Validate(); err != nil {
+			return errors.Wrap(err, "invalid public parameters")
+		}
+		// the spend proofs hold only if the tokens and the pseudonyms share the base of the randomness
+		hRand, err := idemix.HRand(pp.IdemixIssuerPK, pp.IdemixCurveID)
+		if err != nil {
+			return errors.WithMessage(err, "invalid public parameters: failed getting the pseudonym randomness base")
+		}
+		if !hRand.Equals(pp.PedParams[2]) {
+			return errors.New("invalid public parameters: the third Pedersen parameter must be the HRand base of the idemix issuer public key")
+		}
+	}
+	if pp.AuditEncryptionParams != nil {
+		if err := pp.AuditEncryptionParams.Validate(); err != nil {
+			return errors.Wrap(err, "invalid public parameters")
+		}
+	}
+	if pp.MigrationParams != nil {
+		if pp.GraphHidingParams != nil {
+			return errors.New("invalid public parameters: cannot migrate to graph hiding public parameters")
+		}
+		if err := pp.MigrationParams.Validate(); err != nil {
+			return errors.Wrap(err, "invalid public parameters")
+		}
+	}
 	maxToken := pp.ComputeMaxTokenValue()
 	if maxToken != pp.MaxToken {
 		return errors.Errorf("invalid maxt token, [%d]!=[%d]", maxToken, pp.MaxToken)
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	math3 "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/idemix"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -50,3 +51,126 @@ func TestSerialization(t *testing.T) {
 	assert.NoError(t, pp.Validate())
 
 }
+
+func TestBulletproofs(t *testing.T) {
+	raw, err := ioutil.ReadFile("./testdata/idemix/msp/IssuerPublicKey")
+	assert.NoError(t, err)
+	pp, err := Setup(100, 2, raw, math3.BN254)
+	assert.NoError(t, err)
+	assert.Error(t, pp.EnableBulletproofs(48))
+	assert.NoError(t, pp.EnableBulletproofs(64))
+	assert.True(t, pp.Bulletproofs())
+	assert.Nil(t, pp.RangeProofParams)
+	assert.Equal(t, uint64(1<<64-1), pp.MaxToken)
+	assert.NoError(t, pp.Validate())
+
+	ser, err := pp.Serialize()
+	assert.NoError(t, err)
+	pp2, err := NewPublicParamsFromBytes(ser, DLogPublicParameters)
+	assert.NoError(t, err)
+	assert.Equal(t, pp, pp2)
+
+	assert.NoError(t, pp.EnableBulletproofs(16))
+	assert.Equal(t, uint64(1<<16-1), pp.MaxToken)
+	assert.Equal(t, DefaultPrecision, pp.QuantityPrecision)
+	assert.NoError(t, pp.Validate())
+
+	// values beyond 64 bits raise the quantity precision
+	assert.Error(t, pp.EnableBulletproofs(256))
+	assert.NoError(t, pp.EnableBulletproofs(128))
+	assert.Equal(t, uint64(128), pp.QuantityPrecision)
+	assert.Equal(t, uint64(1<<64-1), pp.MaxToken)
+	assert.NoError(t, pp.Validate())
+	pp.QuantityPrecision = DefaultPrecision
+	assert.Error(t, pp.Validate())
+}
+
+func TestMigration(t *testing.T) {
+	raw, err := ioutil.ReadFile("./testdata/idemix/msp/IssuerPublicKey")
+	assert.NoError(t, err)
+	pp, err := Setup(100, 2, raw, math3.BN254)
+	assert.NoError(t, err)
+	assert.False(t, pp.Migration(time.Now()))
+	assert.Error(t, pp.EnableMigration(DLogPublicParameters, time.Time{}))
+
+	// without deadline, the window stays open
+	assert.NoError(t, pp.EnableMigration(FabTokenPredecessor, time.Time{}))
+	assert.True(t, pp.Migration(time.Now()))
+	assert.NoError(t, pp.Validate())
+
+	deadline := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, pp.EnableMigration(FabTokenPredecessor, deadline))
+	assert.True(t, pp.Migration(deadline.Add(-time.Second)))
+	assert.False(t, pp.Migration(deadline))
+	assert.NoError(t, pp.Validate())
+
+	ser, err := pp.Serialize()
+	assert.NoError(t, err)
+	pp2, err := NewPublicParamsFromBytes(ser, DLogPublicParameters)
+	assert.NoError(t, err)
+	assert.Equal(t, pp, pp2)
+
+	pp.MigrationParams.Predecessor = DLogPublicParameters
+	assert.Error(t, pp.Validate())
+}
+
+func TestCurves(t *testing.T) {
+	raw, err := ioutil.ReadFile("./testdata/idemix/msp/IssuerPublicKey")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"BN254", "FP256BN_AMCL", "FP256BN_AMCL_MIRACL"}, CurveNames())
+
+	for _, id := range CurveIDs() {
+		name, err := CurveName(id)
+		assert.NoError(t, err)
+		id2, err := CurveIDByName(name)
+		assert.NoError(t, err)
+		assert.Equal(t, id, id2)
+
+		pp, err := SetupWithCurves(100, 2, raw, DLogPublicParameters, id, math3.FP256BN_AMCL)
+		assert.NoError(t, err)
+		assert.Equal(t, id, pp.Curve)
+		assert.NoError(t, pp.Validate())
+		ser, err := pp.Serialize()
+		assert.NoError(t, err)
+		pp2, err := NewPublicParamsFromBytes(ser, DLogPublicParameters)
+		assert.NoError(t, err)
+		assert.NoError(t, pp2.Validate())
+		ser2, err := pp2.Serialize()
+		assert.NoError(t, err)
+		assert.Equal(t, ser, ser2)
+	}
+
+	_, err = CurveIDByName("BLS12_381")
+	assert.EqualError(t, err, "unsupported curve [BLS12_381], expected one of [BN254 FP256BN_AMCL FP256BN_AMCL_MIRACL]")
+	_, err = SetupWithCurves(100, 2, raw, DLogPublicParameters, math3.CurveID(len(math3.Curves)), math3.FP256BN_AMCL)
+	assert.EqualError(t, err, fmt.Sprintf("unsupported curve [%d]", len(math3.Curves)))
+
+	pp, err := SetupWithCurves(100, 2, raw, DLogPublicParameters, math3.FP256BN_AMCL_MIRACL, math3.FP256BN_AMCL)
+	assert.NoError(t, err)
+	pp.IdemixCurveID = -1
+	assert.EqualError(t, pp.Validate(), "invalid public parameters: invalid idemix curveID [-1]")
+}
+
+func TestGraphHiding(t *testing.T) {
+	raw, err := ioutil.ReadFile("./testdata/idemix/msp/IssuerPublicKey")
+	assert.NoError(t, err)
+	hRand, err := idemix.HRand(raw, math3.FP256BN_AMCL)
+	assert.NoError(t, err)
+	pp, err := SetupGraphHiding(100, 2, raw, math3.FP256BN_AMCL, hRand, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, pp.Validate())
+
+	ser, err := pp.Serialize()
+	assert.NoError(t, err)
+	pp2, err := NewPublicParamsFromBytes(ser, DLogGraphHidingPublicParameters)
+	assert.NoError(t, err)
+	assert.NoError(t, pp2.Validate())
+
+	// the blinding factors of the tokens must use the base of the randomness of the pseudonyms
+	curve := math3.Curves[math3.FP256BN_AMCL]
+	rand, err := curve.Rand()
+	assert.NoError(t, err)
+	pp, err = SetupGraphHiding(100, 2, raw, math3.FP256BN_AMCL, curve.GenG1.Mul(curve.NewRandomZr(rand)), 2)
+	assert.NoError(t, err)
+	assert.EqualError(t, pp.Validate(), "invalid public parameters: the third Pedersen parameter must be the HRand base of the idemix issuer public key")
+}
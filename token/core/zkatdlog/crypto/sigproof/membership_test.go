@@ -8,6 +8,7 @@ package sigproof_test
 import (
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/curvetest"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/sigproof"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -20,7 +21,7 @@ var _ = Describe("membership", func() {
 	)
 	Context("when the proof is computed correctly", func() {
 		BeforeEach(func() {
-			prover = getMembershipProver()
+			prover = getMembershipProver(math.Curves[1])
 			verifier = prover.MembershipVerifier
 		})
 		It("Succeeds ", func() {
@@ -33,7 +34,7 @@ var _ = Describe("membership", func() {
 	})
 	Context("when value does not correspond to signature", func() {
 		BeforeEach(func() {
-			prover = getBogusProver()
+			prover = getBogusProver(math.Curves[1])
 			verifier = prover.MembershipVerifier
 		})
 		It("fails", func() {
@@ -47,8 +48,22 @@ var _ = Describe("membership", func() {
 	})
 })
 
-func getMembershipProver() *sigproof.MembershipProver {
-	c := math.Curves[1]
+var _ = DescribeTable("membership on every supported curve",
+	func(curveID math.CurveID) {
+		prover := getMembershipProver(math.Curves[curveID])
+		proof, err := prover.Prove()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prover.MembershipVerifier.Verify(proof)).To(Succeed())
+
+		prover = getBogusProver(math.Curves[curveID])
+		proof, err = prover.Prove()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prover.MembershipVerifier.Verify(proof)).NotTo(Succeed())
+	},
+	curvetest.Entries(),
+)
+
+func getMembershipProver(c *math.Curve) *sigproof.MembershipProver {
 	signer := getSigner(1, c)
 	sig, err := signer.Sign([]*math.Zr{c.NewZrFromInt(120)})
 	Expect(err).NotTo(HaveOccurred())
@@ -66,8 +81,7 @@ func getMembershipProver() *sigproof.MembershipProver {
 	return sigproof.NewMembershipProver(witness, com, P, signer.Q, signer.PK, pp, c)
 }
 
-func getBogusProver() *sigproof.MembershipProver {
-	c := math.Curves[1]
+func getBogusProver(c *math.Curve) *sigproof.MembershipProver {
 	signer := getSigner(1, c)
 	sig, err := signer.Sign([]*math.Zr{c.NewZrFromInt(120)})
 	Expect(err).NotTo(HaveOccurred())
@@ -0,0 +1,401 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package spend
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/idemix"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/o2omp"
+	"github.com/pkg/errors"
+)
+
+// serialNonceLabel separates the serial nonces from other values derived from the same pseudonyms and commitments
+const serialNonceLabel = "zkatdloggh.serialnonce"
+
+// Spend shows that one of the tokens in an anonymity set is spent, without revealing which one.
+// The spent token is replaced in the transfer by a fresh commitment to the same type and value,
+// and its owner by a fresh pseudonym of the same idemix credential.
+type Spend struct {
+	// AnonymitySet contains the ledger keys of the tokens the spent one is hidden among
+	AnonymitySet []string
+	// SerialNumber is the serial number of the spent token. It is revealed to prevent double spending.
+	// It is derived from the serial nonce of the token and from the user secret key of its owner,
+	// then only the owner can compute it.
+	SerialNumber *math.G1
+	// Serial is a fresh commitment to the serial nonce of the spent token
+	Serial *math.G1
+	// Owner is a fresh pseudonym of the owner of the spent token. It signs the transfer in place of the owner of the spent token.
+	Owner []byte
+	// Proof shows that the spent token is in the anonymity set, and that the serial number is the one of the spent token
+	Proof []byte
+}
+
+// Serialize marshals Spend
+func (s *Spend) Serialize() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// Deserialize un-marshals Spend
+func (s *Spend) Deserialize(raw []byte) error {
+	return json.Unmarshal(raw, s)
+}
+
+// SerialNumberID returns the string representation of the serial number, used to mark it as spent
+func (s *Spend) SerialNumberID() string {
+	return SerialNumberID(s.SerialNumber)
+}
+
+// message binds the proof to the anonymity set, the serial number, the fresh commitment to the serial nonce, and the new owner
+func (s *Spend) message() []byte {
+	var msg []byte
+	for _, key := range s.AnonymitySet {
+		msg = append(msg, []byte(key)...)
+	}
+	msg = append(msg, s.SerialNumber.Bytes()...)
+	msg = append(msg, s.Serial.Bytes()...)
+	return append(msg, s.Owner...)
+}
+
+// SerialNumberID returns the string representation of the passed serial number
+func SerialNumberID(sn *math.G1) string {
+	return hex.EncodeToString(sn.Bytes())
+}
+
+// DeriveSerialNonce returns the serial nonce of the token with the passed commitment, owned by the passed pseudonym.
+// The sender of the token cannot choose it, the recipient checks the derivation when the token is received.
+func DeriveSerialNonce(nym, commitment *math.G1, pp *crypto.PublicParams) (*math.Zr, error) {
+	if nym == nil || commitment == nil {
+		return nil, errors.New("cannot derive serial nonce: missing pseudonym or commitment")
+	}
+	var msg []byte
+	msg = append(msg, []byte(serialNonceLabel)...)
+	msg = append(msg, nym.Bytes()...)
+	msg = append(msg, commitment.Bytes()...)
+	return math.Curves[pp.Curve].HashToZr(msg), nil
+}
+
+// CommitSerialNonce returns the commitment to the passed serial nonce, to be attached to a token,
+// and its fresh blinding factor.
+func CommitSerialNonce(nonce *math.Zr, pp *crypto.PublicParams) (*math.G1, *math.Zr, error) {
+	if pp.GraphHidingParams == nil {
+		return nil, nil, errors.New("cannot commit to serial nonce: graph hiding is not enabled")
+	}
+	curve := math.Curves[pp.Curve]
+	rand, err := curve.Rand()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot commit to serial nonce")
+	}
+	bf := curve.NewRandomZr(rand)
+	return pp.GraphHidingParams.SerialNumberGen.Mul2(nonce, pp.PedParams[2], bf), bf, nil
+}
+
+// SerialNumber returns the serial number of the token with the passed serial nonce, owned by
+// a pseudonym of the passed user secret key: SerialNumberGen^{1/(sk+nonce)}.
+// The sender of the token knows the nonce but not the user secret key of the recipient,
+// then it cannot tell when the token is spent.
+func SerialNumber(sk, nonce *math.Zr, pp *crypto.PublicParams) (*math.G1, error) {
+	if pp.GraphHidingParams == nil {
+		return nil, errors.New("cannot compute serial number: graph hiding is not enabled")
+	}
+	if sk == nil || nonce == nil {
+		return nil, errors.New("cannot compute serial number: missing user secret key or nonce")
+	}
+	curve := math.Curves[pp.Curve]
+	e := curve.ModAdd(sk, nonce, curve.GroupOrder)
+	if e.Equals(curve.NewZrFromInt(0)) {
+		return nil, errors.New("cannot compute serial number: invalid nonce")
+	}
+	e.InvModP(curve.GroupOrder)
+	return pp.GraphHidingParams.SerialNumberGen.Mul(e), nil
+}
+
+// Element is a token in an anonymity set
+type Element struct {
+	// Commitment is the commitment to the type and value of the token
+	Commitment *math.G1
+	// Serial is the commitment to the serial nonce of the token
+	Serial *math.G1
+	// Nym is the pseudonym of the owner of the token
+	Nym *math.G1
+}
+
+// Witness contains the secrets of the spent token
+type Witness struct {
+	// Index is the position of the spent token in the anonymity set
+	Index int
+	// BlindingFactor is the blinding factor of the commitment in the spent token
+	BlindingFactor *math.Zr
+	// SerialBlindingFactor is the blinding factor of the commitment to the serial nonce of the spent token
+	SerialBlindingFactor *math.Zr
+	// NymRandomness is the randomness of the pseudonym owning the spent token
+	NymRandomness *math.Zr
+	// CommitmentBlindingFactor is the blinding factor of the fresh commitment replacing the spent token
+	CommitmentBlindingFactor *math.Zr
+	// OwnerNymRandomness is the randomness of the fresh pseudonym replacing the owner of the spent token
+	OwnerNymRandomness *math.Zr
+	// UserSecret is the user secret key of the owner of the spent token
+	UserSecret *math.Zr
+	// SerialNonce is the serial nonce of the spent token
+	SerialNonce *math.Zr
+	// OwnerSerialBlindingFactor is the blinding factor of the fresh commitment to the serial nonce
+	OwnerSerialBlindingFactor *math.Zr
+}
+
+// Verifier checks the validity of Spend proofs
+type Verifier struct {
+	AnonymitySet []*Element
+	// Commitment is the fresh commitment to the type and value of the spent token
+	Commitment *math.G1
+	// Nym is the fresh pseudonym of the owner of the spent token
+	Nym             *math.G1
+	Spend           *Spend
+	PedParams       []*math.G1
+	SerialNumberGen *math.G1
+	// HSk is the base of the user secret keys in the idemix pseudonyms
+	HSk       *math.G1
+	BitLength int
+	Curve     *math.Curve
+}
+
+// Prover produces Spend proofs
+type Prover struct {
+	*Verifier
+	witness *Witness
+}
+
+// proof carries the proofs of a Spend
+type proof struct {
+	// Membership shows that the spent token is in the anonymity set
+	Membership []byte
+	// SerialNumber shows that the serial number is the one of the spent token
+	SerialNumber *SerialNumberProof
+}
+
+// SerialNumberProof shows that the serial number of a spend is SerialNumberGen^{1/(sk+nonce)},
+// where nonce is the opening of the fresh commitment to the serial nonce,
+// and sk is the user secret key in the fresh pseudonym.
+type SerialNumberProof struct {
+	// Challenge computed using the Fiat-Shamir Heuristic
+	Challenge *math.Zr
+	// Nonce is the proof of knowledge of the serial nonce
+	Nonce *math.Zr
+	// SerialBlindingFactor is the proof of knowledge of the blinding factor of the fresh commitment to the serial nonce
+	SerialBlindingFactor *math.Zr
+	// UserSecret is the proof of knowledge of the user secret key
+	UserSecret *math.Zr
+	// NymRandomness is the proof of knowledge of the randomness of the fresh pseudonym
+	NymRandomness *math.Zr
+}
+
+// NewVerifier returns a Verifier for the passed spend, whose fresh commitment and pseudonym are the passed ones
+func NewVerifier(set []*Element, commitment, nym *math.G1, spend *Spend, pp *crypto.PublicParams) *Verifier {
+	v := &Verifier{
+		AnonymitySet: set,
+		Commitment:   commitment,
+		Nym:          nym,
+		Spend:        spend,
+		PedParams:    pp.PedParams,
+		Curve:        math.Curves[pp.Curve],
+	}
+	if pp.GraphHidingParams != nil {
+		v.SerialNumberGen = pp.GraphHidingParams.SerialNumberGen
+		v.BitLength = int(pp.GraphHidingParams.AnonymitySetBitLength)
+	}
+	if hSk, err := idemix.HSk(pp.IdemixIssuerPK, pp.IdemixCurveID); err == nil {
+		v.HSk = hSk
+	}
+	return v
+}
+
+// NewProver returns a Prover for the passed spend
+func NewProver(witness *Witness, set []*Element, commitment, nym *math.G1, spend *Spend, pp *crypto.PublicParams) *Prover {
+	return &Prover{
+		Verifier: NewVerifier(set, commitment, nym, spend, pp),
+		witness:  witness,
+	}
+}
+
+// Prove produces a one-out-of-many proof that one of the elements of the anonymity set,
+// once the fresh commitment, the fresh commitment to the serial nonce, and the fresh pseudonym are removed,
+// is a commitment to zero in the base of the blinding factors.
+// This holds only for the spent token, whose type, value, serial nonce, and owner secret key
+// are those in the fresh commitments and in the fresh pseudonym.
+// Then it proves that the serial number is derived from the serial nonce and the user secret key
+// in the fresh commitment to the serial nonce and in the fresh pseudonym.
+func (p *Prover) Prove() ([]byte, error) {
+	statement, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	w := p.witness
+	if w == nil || w.BlindingFactor == nil || w.SerialBlindingFactor == nil || w.NymRandomness == nil || w.CommitmentBlindingFactor == nil || w.OwnerNymRandomness == nil ||
+		w.UserSecret == nil || w.SerialNonce == nil || w.OwnerSerialBlindingFactor == nil {
+		return nil, errors.New("cannot generate spend proof: invalid witness")
+	}
+	// randomness = bf - bf' + sbf - sbf' + r - r'
+	order := p.Curve.GroupOrder
+	randomness := p.Curve.ModSub(w.BlindingFactor, w.CommitmentBlindingFactor, order)
+	randomness = p.Curve.ModAdd(randomness, w.SerialBlindingFactor, order)
+	randomness = p.Curve.ModSub(randomness, w.OwnerSerialBlindingFactor, order)
+	randomness = p.Curve.ModAdd(randomness, w.NymRandomness, order)
+	randomness = p.Curve.ModSub(randomness, w.OwnerNymRandomness, order)
+
+	membership, err := o2omp.NewProver(
+		statement,
+		p.Spend.message(),
+		[]*math.G1{p.PedParams[0], p.PedParams[2]},
+		p.BitLength,
+		w.Index,
+		randomness,
+		p.Curve,
+	).Prove()
+	if err != nil {
+		return nil, err
+	}
+	sn, err := p.proveSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&proof{Membership: membership, SerialNumber: sn})
+}
+
+// proveSerialNumber proves knowledge of (nonce, sbf', sk, r') such that
+// Serial' = SerialNumberGen^nonce \cdot PedParams[2]^sbf', Nym' = HSk^sk \cdot PedParams[2]^r',
+// and SerialNumberGen = SerialNumber^{sk+nonce}
+func (p *Prover) proveSerialNumber() (*SerialNumberProof, error) {
+	rand, err := p.Curve.Rand()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot generate serial number proof")
+	}
+	w := p.witness
+	order := p.Curve.GroupOrder
+	rNonce := p.Curve.NewRandomZr(rand)
+	rSerialBF := p.Curve.NewRandomZr(rand)
+	rUserSecret := p.Curve.NewRandomZr(rand)
+	rNymRandomness := p.Curve.NewRandomZr(rand)
+
+	commitments := []*math.G1{
+		p.SerialNumberGen.Mul2(rNonce, p.PedParams[2], rSerialBF),
+		p.HSk.Mul2(rUserSecret, p.PedParams[2], rNymRandomness),
+		p.Spend.SerialNumber.Mul(p.Curve.ModAdd(rUserSecret, rNonce, order)),
+	}
+	challenge, err := p.serialNumberChallenge(commitments)
+	if err != nil {
+		return nil, err
+	}
+	return &SerialNumberProof{
+		Challenge:            challenge,
+		Nonce:                p.Curve.ModAdd(rNonce, p.Curve.ModMul(challenge, w.SerialNonce, order), order),
+		SerialBlindingFactor: p.Curve.ModAdd(rSerialBF, p.Curve.ModMul(challenge, w.OwnerSerialBlindingFactor, order), order),
+		UserSecret:           p.Curve.ModAdd(rUserSecret, p.Curve.ModMul(challenge, w.UserSecret, order), order),
+		NymRandomness:        p.Curve.ModAdd(rNymRandomness, p.Curve.ModMul(challenge, w.OwnerNymRandomness, order), order),
+	}, nil
+}
+
+// Verify checks the proof of the spend
+func (v *Verifier) Verify() error {
+	statement, err := v.statement()
+	if err != nil {
+		return err
+	}
+	pr := &proof{}
+	if err := json.Unmarshal(v.Spend.Proof, pr); err != nil {
+		return errors.Wrap(err, "invalid spend proof")
+	}
+	if err := o2omp.NewVerifier(
+		statement,
+		v.Spend.message(),
+		[]*math.G1{v.PedParams[0], v.PedParams[2]},
+		v.BitLength,
+		v.Curve,
+	).Verify(pr.Membership); err != nil {
+		return errors.Wrap(err, "invalid spend proof")
+	}
+	if err := v.verifySerialNumber(pr.SerialNumber); err != nil {
+		return errors.Wrap(err, "invalid spend proof")
+	}
+	return nil
+}
+
+func (v *Verifier) verifySerialNumber(sn *SerialNumberProof) error {
+	if sn == nil || sn.Challenge == nil || sn.Nonce == nil || sn.SerialBlindingFactor == nil || sn.UserSecret == nil || sn.NymRandomness == nil {
+		return errors.New("invalid serial number proof")
+	}
+	order := v.Curve.GroupOrder
+	commitments := []*math.G1{
+		v.SerialNumberGen.Mul2(sn.Nonce, v.PedParams[2], sn.SerialBlindingFactor),
+		v.HSk.Mul2(sn.UserSecret, v.PedParams[2], sn.NymRandomness),
+		v.Spend.SerialNumber.Mul(v.Curve.ModAdd(sn.UserSecret, sn.Nonce, order)),
+	}
+	commitments[0].Sub(v.Spend.Serial.Mul(sn.Challenge))
+	commitments[1].Sub(v.Nym.Mul(sn.Challenge))
+	commitments[2].Sub(v.SerialNumberGen.Mul(sn.Challenge))
+	challenge, err := v.serialNumberChallenge(commitments)
+	if err != nil {
+		return err
+	}
+	if !challenge.Equals(sn.Challenge) {
+		return errors.New("invalid serial number proof")
+	}
+	return nil
+}
+
+// serialNumberChallenge returns the challenge of the serial number proof
+func (v *Verifier) serialNumberChallenge(commitments []*math.G1) (*math.Zr, error) {
+	raw, err := common.GetG1Array(
+		[]*math.G1{v.SerialNumberGen, v.PedParams[2], v.HSk, v.Spend.Serial, v.Nym, v.Spend.SerialNumber},
+		commitments,
+	).Bytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot compute serial number challenge")
+	}
+	return v.Curve.HashToZr(append(raw, v.Spend.message()...)), nil
+}
+
+// statement returns, for each element of the anonymity set, Commitment_i / Commitment' * Serial_i / Serial' * Nym_i / Nym'
+func (v *Verifier) statement() ([]*math.G1, error) {
+	if v.Curve == nil {
+		return nil, errors.New("invalid spend: please initialize curve")
+	}
+	if v.SerialNumberGen == nil || v.BitLength == 0 {
+		return nil, errors.New("invalid spend: graph hiding is not enabled")
+	}
+	if v.HSk == nil {
+		return nil, errors.New("invalid spend: missing the base of the user secret keys")
+	}
+	if len(v.PedParams) != 3 {
+		return nil, errors.Errorf("invalid spend: length of Pedersen parameters != 3")
+	}
+	if v.Spend == nil || v.Spend.SerialNumber == nil || v.Spend.Serial == nil || v.Spend.SerialNumber.IsInfinity() {
+		return nil, errors.New("invalid spend: missing serial number")
+	}
+	if v.Commitment == nil || v.Nym == nil {
+		return nil, errors.New("invalid spend: missing commitment or owner")
+	}
+	if len(v.AnonymitySet) != 1<<v.BitLength || len(v.Spend.AnonymitySet) != len(v.AnonymitySet) {
+		return nil, errors.Errorf("invalid spend: the anonymity set should contain [%d] tokens, got [%d]", 1<<v.BitLength, len(v.AnonymitySet))
+	}
+
+	statement := make([]*math.G1, len(v.AnonymitySet))
+	for i, e := range v.AnonymitySet {
+		if e == nil || e.Commitment == nil || e.Serial == nil || e.Nym == nil {
+			return nil, errors.Errorf("invalid spend: invalid element at index [%d] of the anonymity set", i)
+		}
+		statement[i] = e.Commitment.Copy()
+		statement[i].Sub(v.Commitment)
+		statement[i].Add(e.Serial)
+		statement[i].Sub(v.Spend.Serial)
+		statement[i].Add(e.Nym)
+		statement[i].Sub(v.Nym)
+	}
+	return statement, nil
+}
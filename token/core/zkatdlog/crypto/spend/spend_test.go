@@ -0,0 +1,281 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package spend_test
+
+import (
+	"io"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/spend"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Spend", func() {
+	var (
+		pp    *crypto.PublicParams
+		curve *math.Curve
+		rand  io.Reader
+		hSk   *math.G1
+
+		set        []*spend.Element
+		witness    *spend.Witness
+		commitment *math.G1
+		nym        *math.G1
+		sp         *spend.Spend
+		prover     *spend.Prover
+	)
+	verify := func(sp *spend.Spend) error {
+		v := spend.NewVerifier(set, commitment, nym, sp, pp)
+		v.HSk = hSk
+		return v.Verify()
+	}
+	BeforeEach(func() {
+		var err error
+		curve = math.Curves[math.BN254]
+		rand, err = curve.Rand()
+		Expect(err).NotTo(HaveOccurred())
+		pp, err = crypto.SetupGraphHiding(16, 2, nil, math.BN254, curve.GenG1.Mul(curve.NewRandomZr(rand)), 2)
+		Expect(err).NotTo(HaveOccurred())
+
+		// the owner pseudonyms share the secret key base
+		hSk = curve.GenG1.Mul(curve.NewRandomZr(rand))
+		sk := curve.NewRandomZr(rand)
+		newNym := func(sk *math.Zr) (*math.G1, *math.Zr) {
+			r := curve.NewRandomZr(rand)
+			return hSk.Mul2(sk, pp.PedParams[2], r), r
+		}
+		commit := func(typ string, value int64, bf *math.Zr) *math.G1 {
+			c := pp.PedParams[0].Mul(curve.HashToZr([]byte(typ)))
+			c.Add(pp.PedParams[1].Mul(curve.NewZrFromInt(value)))
+			c.Add(pp.PedParams[2].Mul(bf))
+			return c
+		}
+
+		// the anonymity set contains the spent token at index 2, and decoys owned by someone else
+		set = make([]*spend.Element, 4)
+		for i := range set {
+			n, _ := newNym(curve.NewRandomZr(rand))
+			c := commit("ABC", 10, curve.NewRandomZr(rand))
+			nonce, err := spend.DeriveSerialNonce(n, c, pp)
+			Expect(err).NotTo(HaveOccurred())
+			serial, _, err := spend.CommitSerialNonce(nonce, pp)
+			Expect(err).NotTo(HaveOccurred())
+			set[i] = &spend.Element{Commitment: c, Serial: serial, Nym: n}
+		}
+		bf := curve.NewRandomZr(rand)
+		owner, r := newNym(sk)
+		c := commit("ABC", 50, bf)
+		nonce, err := spend.DeriveSerialNonce(owner, c, pp)
+		Expect(err).NotTo(HaveOccurred())
+		serial, sbf, err := spend.CommitSerialNonce(nonce, pp)
+		Expect(err).NotTo(HaveOccurred())
+		set[2] = &spend.Element{Commitment: c, Serial: serial, Nym: owner}
+
+		// fresh commitments and pseudonym
+		bf2 := curve.NewRandomZr(rand)
+		commitment = commit("ABC", 50, bf2)
+		var r2 *math.Zr
+		nym, r2 = newNym(sk)
+		serial2, sbf2, err := spend.CommitSerialNonce(nonce, pp)
+		Expect(err).NotTo(HaveOccurred())
+		sn, err := spend.SerialNumber(sk, nonce, pp)
+		Expect(err).NotTo(HaveOccurred())
+
+		sp = &spend.Spend{
+			AnonymitySet: []string{"a", "b", "c", "d"},
+			SerialNumber: sn,
+			Serial:       serial2,
+			Owner:        []byte("owner"),
+		}
+		witness = &spend.Witness{
+			Index:                     2,
+			BlindingFactor:            bf,
+			SerialBlindingFactor:      sbf,
+			NymRandomness:             r,
+			CommitmentBlindingFactor:  bf2,
+			OwnerNymRandomness:        r2,
+			UserSecret:                sk,
+			SerialNonce:               nonce,
+			OwnerSerialBlindingFactor: sbf2,
+		}
+	})
+	JustBeforeEach(func() {
+		prover = spend.NewProver(witness, set, commitment, nym, sp, pp)
+		prover.HSk = hSk
+	})
+	When("the spend is generated correctly", func() {
+		It("succeeds", func() {
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			sp.Proof = proof
+			raw, err := sp.Serialize()
+			Expect(err).NotTo(HaveOccurred())
+			sp2 := &spend.Spend{}
+			Expect(sp2.Deserialize(raw)).To(Succeed())
+			Expect(verify(sp2)).To(Succeed())
+		})
+	})
+	When("the serial number is not the one of the spent token", func() {
+		It("fails", func() {
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			sp.Proof = proof
+			sp.SerialNumber = curve.GenG1.Mul(curve.NewRandomZr(rand))
+			err = verify(sp)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid spend proof"))
+		})
+	})
+	When("the serial number is derived from another user secret key", func() {
+		BeforeEach(func() {
+			var err error
+			sp.SerialNumber, err = spend.SerialNumber(curve.NewRandomZr(rand), witness.SerialNonce, pp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("fails", func() {
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			sp.Proof = proof
+			err = verify(sp)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid serial number proof"))
+		})
+	})
+	When("the fresh commitment to the serial nonce does not match the spent token", func() {
+		BeforeEach(func() {
+			var err error
+			sp.Serial, witness.OwnerSerialBlindingFactor, err = spend.CommitSerialNonce(curve.NewRandomZr(rand), pp)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("fails", func() {
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			sp.Proof = proof
+			err = verify(sp)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid spend proof"))
+		})
+	})
+	When("the fresh commitment does not match the spent token", func() {
+		It("fails", func() {
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			sp.Proof = proof
+			v := spend.NewVerifier(set, commitment.Mul(curve.NewZrFromInt(2)), nym, sp, pp)
+			v.HSk = hSk
+			err = v.Verify()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid spend proof"))
+		})
+	})
+	When("the fresh owner is changed", func() {
+		It("fails", func() {
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			sp.Proof = proof
+			sp.Owner = []byte("another owner")
+			err = verify(sp)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid spend proof"))
+		})
+	})
+	When("the prover does not own the spent token", func() {
+		BeforeEach(func() {
+			witness.Index = 1
+		})
+		It("fails", func() {
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			sp.Proof = proof
+			err = verify(sp)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid spend proof"))
+		})
+	})
+	When("the anonymity set has the wrong size", func() {
+		BeforeEach(func() {
+			set = set[:3]
+			sp.AnonymitySet = sp.AnonymitySet[:3]
+		})
+		It("fails", func() {
+			_, err := prover.Prove()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("the anonymity set should contain [4] tokens, got [3]"))
+		})
+	})
+})
+
+var _ = Describe("Serial number", func() {
+	var (
+		pp         *crypto.PublicParams
+		curve      *math.Curve
+		rand       io.Reader
+		nym        *math.G1
+		commitment *math.G1
+	)
+	BeforeEach(func() {
+		var err error
+		curve = math.Curves[math.BN254]
+		rand, err = curve.Rand()
+		Expect(err).NotTo(HaveOccurred())
+		pp, err = crypto.SetupGraphHiding(16, 2, nil, math.BN254, curve.GenG1.Mul(curve.NewRandomZr(rand)), 2)
+		Expect(err).NotTo(HaveOccurred())
+		nym = curve.GenG1.Mul(curve.NewRandomZr(rand))
+		commitment = curve.GenG1.Mul(curve.NewRandomZr(rand))
+	})
+	It("has a nonce bound to the pseudonym and the commitment", func() {
+		nonce, err := spend.DeriveSerialNonce(nym, commitment, pp)
+		Expect(err).NotTo(HaveOccurred())
+		nonce2, err := spend.DeriveSerialNonce(nym, commitment, pp)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nonce.Equals(nonce2)).To(BeTrue())
+
+		nonce2, err = spend.DeriveSerialNonce(commitment, commitment, pp)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nonce.Equals(nonce2)).To(BeFalse())
+		nonce2, err = spend.DeriveSerialNonce(nym, nym, pp)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nonce.Equals(nonce2)).To(BeFalse())
+	})
+	It("cannot be predicted by the creator of the token", func() {
+		// the creator of the token knows the nonce and the opening of the commitment to it,
+		// but not the user secret key of the owner
+		nonce, err := spend.DeriveSerialNonce(nym, commitment, pp)
+		Expect(err).NotTo(HaveOccurred())
+		serial, sbf, err := spend.CommitSerialNonce(nonce, pp)
+		Expect(err).NotTo(HaveOccurred())
+		sk := curve.NewRandomZr(rand)
+		sn, err := spend.SerialNumber(sk, nonce, pp)
+		Expect(err).NotTo(HaveOccurred())
+
+		// the owner always gets the same serial number
+		sn2, err := spend.SerialNumber(sk, nonce, pp)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sn.Equals(sn2)).To(BeTrue())
+
+		// what the creator can compute from its view differs from the serial number
+		gen := pp.GraphHidingParams.SerialNumberGen
+		Expect(sn.Equals(serial)).To(BeFalse())
+		Expect(sn.Equals(gen.Mul(nonce))).To(BeFalse())
+		Expect(sn.Equals(gen.Mul2(nonce, pp.PedParams[2], sbf))).To(BeFalse())
+		inverse := nonce.Copy()
+		inverse.InvModP(curve.GroupOrder)
+		Expect(sn.Equals(gen.Mul(inverse))).To(BeFalse())
+		for i := 0; i < 10; i++ {
+			guess, err := spend.SerialNumber(curve.NewRandomZr(rand), nonce, pp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sn.Equals(guess)).To(BeFalse())
+		}
+	})
+	It("requires the user secret key and the nonce", func() {
+		_, err := spend.SerialNumber(nil, curve.NewRandomZr(rand), pp)
+		Expect(err).To(MatchError("cannot compute serial number: missing user secret key or nonce"))
+		_, err = spend.DeriveSerialNonce(nil, commitment, pp)
+		Expect(err).To(MatchError("cannot derive serial nonce: missing pseudonym or commitment"))
+	})
+})
@@ -0,0 +1,112 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+
+	math "github.com/IBM/mathlib"
+	"github.com/pkg/errors"
+)
+
+// EncryptedMetadata is Metadata encrypted for the owner of a token.
+// The owner key is a multi-base public key PK = \prod_i Bases[i]^{SK[i]}, for instance an idemix pseudonym.
+// The encryption key is derived from PK^t, where R[i] = Bases[i]^t for a random t,
+// then the owner recomputes it as \prod_i R[i]^{SK[i]}.
+type EncryptedMetadata struct {
+	R          []*math.G1
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// Serialize marshals EncryptedMetadata
+func (e *EncryptedMetadata) Serialize() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Deserialize un-marshals EncryptedMetadata
+func (e *EncryptedMetadata) Deserialize(raw []byte) error {
+	return json.Unmarshal(raw, e)
+}
+
+// EncryptMetadata encrypts the passed Metadata for the owner of the passed multi-base public key
+func EncryptMetadata(meta *Metadata, pk *math.G1, bases []*math.G1, c *math.Curve) (*EncryptedMetadata, error) {
+	if meta == nil || pk == nil || len(bases) == 0 || c == nil {
+		return nil, errors.New("cannot encrypt metadata: invalid arguments")
+	}
+	raw, err := meta.Serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot encrypt metadata")
+	}
+	rng, err := c.Rand()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot encrypt metadata")
+	}
+	t := c.NewRandomZr(rng)
+	e := &EncryptedMetadata{R: make([]*math.G1, len(bases))}
+	for i, base := range bases {
+		e.R[i] = base.Mul(t)
+	}
+	aead, err := newAEAD(pk.Mul(t))
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot encrypt metadata")
+	}
+	e.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(e.Nonce); err != nil {
+		return nil, errors.Wrap(err, "cannot encrypt metadata")
+	}
+	e.Ciphertext = aead.Seal(nil, e.Nonce, raw, nil)
+	return e, nil
+}
+
+// Decrypt returns the Metadata encrypted in EncryptedMetadata using the passed secret keys,
+// one for each base of the public key of the owner
+func (e *EncryptedMetadata) Decrypt(sks []*math.Zr, c *math.Curve) (*Metadata, error) {
+	if len(sks) != len(e.R) {
+		return nil, errors.Errorf("cannot decrypt metadata: expected [%d] secret keys, got [%d]", len(e.R), len(sks))
+	}
+	shared := c.NewG1()
+	for i, r := range e.R {
+		if r == nil || sks[i] == nil {
+			return nil, errors.Errorf("cannot decrypt metadata: invalid key at index [%d]", i)
+		}
+		shared.Add(r.Mul(sks[i]))
+	}
+	aead, err := newAEAD(shared)
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot decrypt metadata")
+	}
+	if len(e.Nonce) != aead.NonceSize() {
+		return nil, errors.New("cannot decrypt metadata: invalid nonce")
+	}
+	raw, err := aead.Open(nil, e.Nonce, e.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decrypt metadata")
+	}
+	meta := &Metadata{}
+	if err := meta.Deserialize(raw); err != nil {
+		return nil, errors.Wrap(err, "cannot decrypt metadata")
+	}
+	return meta, nil
+}
+
+func newAEAD(shared *math.G1) (cipher.AEAD, error) {
+	key := sha256.Sum256(shared.Bytes())
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialize cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to initialize cipher")
+	}
+	return aead, nil
+}
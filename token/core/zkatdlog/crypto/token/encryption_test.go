@@ -0,0 +1,76 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token_test
+
+import (
+	math "github.com/IBM/mathlib"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metadata encryption", func() {
+	var (
+		c     *math.Curve
+		meta  *token2.Metadata
+		bases []*math.G1
+		sks   []*math.Zr
+		pk    *math.G1
+	)
+
+	BeforeEach(func() {
+		c = math.Curves[math.FP256BN_AMCL]
+		rand, err := c.Rand()
+		Expect(err).NotTo(HaveOccurred())
+		meta = &token2.Metadata{
+			Type:           "ABC",
+			Value:          c.NewZrFromInt(50),
+			BlindingFactor: c.NewRandomZr(rand),
+			Owner:          []byte("alice"),
+		}
+		// a pseudonym-like key HSk^sk \cdot HRand^r
+		bases = []*math.G1{c.HashToG1([]byte("HSk")), c.HashToG1([]byte("HRand"))}
+		sks = []*math.Zr{c.NewRandomZr(rand), c.NewRandomZr(rand)}
+		pk = bases[0].Mul2(sks[0], bases[1], sks[1])
+	})
+
+	When("the owner decrypts", func() {
+		It("succeeds", func() {
+			e, err := token2.EncryptMetadata(meta, pk, bases, c)
+			Expect(err).NotTo(HaveOccurred())
+			raw, err := e.Serialize()
+			Expect(err).NotTo(HaveOccurred())
+			e2 := &token2.EncryptedMetadata{}
+			Expect(e2.Deserialize(raw)).To(Succeed())
+
+			decrypted, err := e2.Decrypt(sks, c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decrypted.Type).To(Equal(meta.Type))
+			Expect(decrypted.Value.Equals(meta.Value)).To(BeTrue())
+			Expect(decrypted.BlindingFactor.Equals(meta.BlindingFactor)).To(BeTrue())
+			Expect(decrypted.Owner).To(Equal(meta.Owner))
+		})
+	})
+	When("someone else decrypts", func() {
+		It("fails", func() {
+			e, err := token2.EncryptMetadata(meta, pk, bases, c)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = e.Decrypt([]*math.Zr{sks[1], sks[0]}, c)
+			Expect(err).To(HaveOccurred())
+			_, err = e.Decrypt(sks[:1], c)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	When("the ciphertext is tampered with", func() {
+		It("fails", func() {
+			e, err := token2.EncryptMetadata(meta, pk, bases, c)
+			Expect(err).NotTo(HaveOccurred())
+			e.Ciphertext[0] ^= 1
+			_, err = e.Decrypt(sks, c)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
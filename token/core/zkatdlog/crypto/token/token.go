@@ -8,10 +8,12 @@ package token
 
 import (
 	"encoding/json"
+	"math/big"
 
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/disclosure"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
 )
@@ -22,6 +24,15 @@ type Token struct {
 	Owner []byte
 	// Data is the Pedersen commitment to type and value
 	Data *math.G1
+	// Serial is the Pedersen commitment to the serial nonce of the token.
+	// It is set only when graph hiding is enabled.
+	Serial *math.G1 `json:",omitempty"`
+	// Metadata is the metadata of the token encrypted for its owner.
+	// It is set only when the metadata of the outputs is put on the ledger.
+	Metadata *EncryptedMetadata `json:",omitempty"`
+	// Audit is the encryption of type and value of the token for the auditor.
+	// It is set only when audit encryption is enabled in the public parameters.
+	Audit *disclosure.Ciphertexts `json:",omitempty"`
 }
 
 // IsRedeem returns true if the token has an empty owner field
@@ -54,6 +65,15 @@ func (t *Token) GetTokenInTheClear(meta *Metadata, pp *crypto.PublicParams) (*to
 	if !com.Equals(t.Data) {
 		return nil, errors.New("cannot retrieve token in the clear: output does not match provided opening")
 	}
+	if pp.GraphHiding() && !t.IsRedeem() {
+		if t.Serial == nil || meta.SerialNonce == nil || meta.SerialBlindingFactor == nil {
+			return nil, errors.New("cannot retrieve token in the clear: missing serial nonce")
+		}
+		serial := pp.GraphHidingParams.SerialNumberGen.Mul2(meta.SerialNonce, pp.PedParams[2], meta.SerialBlindingFactor)
+		if !serial.Equals(t.Serial) {
+			return nil, errors.New("cannot retrieve token in the clear: serial nonce does not match provided opening")
+		}
+	}
 	return &token2.Token{
 		Type:     meta.Type,
 		Quantity: "0x" + meta.Value.String(),
@@ -75,7 +95,9 @@ func computeTokens(tw []*TokenDataWitness, pp []*math.G1, c *math.Curve) ([]*mat
 	return tokens, nil
 }
 
-func GetTokensWithWitness(values []uint64, ttype string, pp []*math.G1, c *math.Curve) ([]*math.G1, []*TokenDataWitness, error) {
+// GetTokensWithWitness returns tokens of the passed type committing to the passed values,
+// together with their openings. The values must be non-negative and smaller than the group order.
+func GetTokensWithWitness(values []*big.Int, ttype string, pp []*math.G1, c *math.Curve) ([]*math.G1, []*TokenDataWitness, error) {
 	if c == nil {
 		return nil, nil, errors.New("cannot get tokens with witness: please initialize curve")
 	}
@@ -83,11 +105,15 @@ func GetTokensWithWitness(values []uint64, ttype string, pp []*math.G1, c *math.
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "cannot get tokens with witness")
 	}
+	order := new(big.Int).SetBytes(c.GroupOrder.Bytes())
 	tw := make([]*TokenDataWitness, len(values))
 	for i, v := range values {
+		if v == nil || v.Sign() < 0 || v.Cmp(order) >= 0 {
+			return nil, nil, errors.Errorf("cannot get tokens with witness: invalid value at index [%d]", i)
+		}
 		tw[i] = &TokenDataWitness{}
 		tw[i].BlindingFactor = c.NewRandomZr(rand)
-		tw[i].Value = c.NewZrFromInt(int64(v))
+		tw[i].Value = newZrFromBigInt(v, c)
 		tw[i].Type = ttype
 	}
 	tokens, err := computeTokens(tw, pp, c)
@@ -97,6 +123,47 @@ func GetTokensWithWitness(values []uint64, ttype string, pp []*math.G1, c *math.
 	return tokens, tw, nil
 }
 
+// EncryptForAuditor sets on each of the passed tokens the encryption of its type and value for the auditor,
+// if audit encryption is enabled in the passed public parameters.
+// The i-th witness is the opening of the i-th token.
+func EncryptForAuditor(tokens []*Token, tw []*TokenDataWitness, pp *crypto.PublicParams, pool *common.WorkerPool) error {
+	if !pp.AuditEncryption() {
+		return nil
+	}
+	if len(tokens) != len(tw) {
+		return errors.Errorf("cannot encrypt tokens for the auditor: expected [%d] witnesses, got [%d]", len(tokens), len(tw))
+	}
+	return pool.Run(len(tokens), func(i int) error {
+		if tokens[i] == nil || tw[i] == nil {
+			return errors.Errorf("cannot encrypt token [%d] for the auditor: nil token or witness", i)
+		}
+		var err error
+		tokens[i].Audit, err = disclosure.Encrypt(tokens[i].Data, tw[i].Type, tw[i].Value, tw[i].BlindingFactor, pp)
+		if err != nil {
+			return errors.WithMessagef(err, "cannot encrypt token [%d] for the auditor", i)
+		}
+		return nil
+	})
+}
+
+// newZrFromBigInt returns the element of Zr corresponding to the passed non-negative value.
+// The value is processed in 32-bit limbs, so that it can go beyond the range of int64.
+func newZrFromBigInt(v *big.Int, c *math.Curve) *math.Zr {
+	base := c.NewZrFromInt(1 << 32)
+	res := c.NewZrFromInt(0)
+	words := new(big.Int).Set(v)
+	var limbs []int64
+	mask := big.NewInt(0xffffffff)
+	for words.Sign() > 0 {
+		limbs = append(limbs, new(big.Int).And(words, mask).Int64())
+		words.Rsh(words, 32)
+	}
+	for i := len(limbs) - 1; i >= 0; i-- {
+		res = c.ModAdd(c.ModMul(res, base, c.GroupOrder), c.NewZrFromInt(limbs[i]), c.GroupOrder)
+	}
+	return res
+}
+
 // Metadata contains the metadata of a token
 type Metadata struct {
 	// Type is the type of the token
@@ -109,6 +176,11 @@ type Metadata struct {
 	Owner []byte
 	// Issuer is the issuer of the token, if defined
 	Issuer []byte
+	// SerialNonce is the nonce the serial number of the token is derived from, if graph hiding is enabled.
+	// The serial number, revealed when the token is spent, also depends on the user secret key of the owner.
+	SerialNonce *math.Zr `json:",omitempty"`
+	// SerialBlindingFactor is the blinding factor used to commit to the serial nonce
+	SerialBlindingFactor *math.Zr `json:",omitempty"`
 }
 
 // Deserialize un-marshals Metadata
@@ -6,9 +6,12 @@ SPDX-License-Identifier: Apache-2.0
 package token_test
 
 import (
+	"math/big"
+
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -48,4 +51,27 @@ var _ = Describe("Token", func() {
 			})
 		})
 	})
+	Describe("get tokens with witness", func() {
+		When("values go beyond 64 bits", func() {
+			It("succeeds", func() {
+				v, ok := new(big.Int).SetString("0xffffffffffffffffffffffffffffffff", 0)
+				Expect(ok).To(BeTrue())
+				tokens, tw, err := token2.GetTokensWithWitness([]*big.Int{v}, "ABC", pp.PedParams, math.Curves[pp.Curve])
+				Expect(err).NotTo(HaveOccurred())
+				tok := &token2.Token{Data: tokens[0]}
+				t, err := tok.GetTokenInTheClear(&token2.Metadata{Type: "ABC", Value: tw[0].Value, BlindingFactor: tw[0].BlindingFactor}, pp)
+				Expect(err).NotTo(HaveOccurred())
+				q, err := token3.ToQuantity(t.Quantity, 128)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(q.ToBigInt()).To(Equal(v))
+			})
+		})
+		When("a value is negative", func() {
+			It("fails", func() {
+				_, _, err := token2.GetTokensWithWitness([]*big.Int{big.NewInt(-1)}, "ABC", pp.PedParams, math.Curves[pp.Curve])
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid value at index [0]"))
+			})
+		})
+	})
 })
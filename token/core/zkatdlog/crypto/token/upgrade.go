@@ -0,0 +1,47 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	math2 "math"
+	"math/big"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// CommitToClearToken returns a Token that commits to the type and the quantity of the passed token in the clear,
+// together with its opening. The blinding factor is zero, then anyone can recompute the commitment.
+// It is used to spend the outputs of a predecessor driver in upgrade transfers.
+func CommitToClearToken(tok *token2.Token, pp *crypto.PublicParams) (*Token, *Metadata, error) {
+	if tok == nil || tok.Owner == nil || len(tok.Owner.Raw) == 0 {
+		return nil, nil, errors.New("cannot commit to token: nil token or owner")
+	}
+	q, err := token2.ToQuantity(tok.Quantity, pp.QuantityPrecision)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "cannot commit to token: invalid quantity [%s]", tok.Quantity)
+	}
+	// a transfer of ownership carries no range proof, then the quantity must already be in range
+	if pp.MaxToken != math2.MaxUint64 && q.ToBigInt().Cmp(new(big.Int).SetUint64(pp.MaxToken)) > 0 {
+		return nil, nil, errors.Errorf("cannot commit to token: quantity [%s] exceeds the maximum token value [%d]", tok.Quantity, pp.MaxToken)
+	}
+	c := math.Curves[pp.Curve]
+	meta := &Metadata{
+		Type:           tok.Type,
+		Value:          newZrFromBigInt(q.ToBigInt(), c),
+		BlindingFactor: c.NewZrFromInt(0),
+		Owner:          tok.Owner.Raw,
+	}
+	com, err := common.ComputePedersenCommitment([]*math.Zr{c.HashToZr([]byte(meta.Type)), meta.Value, meta.BlindingFactor}, pp.PedParams, c)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot commit to token")
+	}
+	return &Token{Owner: tok.Owner.Raw, Data: com}, meta, nil
+}
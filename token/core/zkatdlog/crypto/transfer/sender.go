@@ -8,9 +8,12 @@ package transfer
 
 import (
 	"encoding/json"
+	"math/big"
 
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/spend"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/pkg/errors"
@@ -37,6 +40,11 @@ type Sender struct {
 	// PublicParams refers to the public cryptographic parameters to be used
 	// to produce the TokenRequest
 	PublicParams *crypto.PublicParams
+	// WorkerPool bounds the goroutines used to generate the zero-knowledge proofs.
+	// If nil, as many goroutines as the available CPUs are used.
+	WorkerPool *common.WorkerPool
+	// Upgrade is true if the inputs are outputs of a predecessor driver, spent in the clear
+	Upgrade bool
 }
 
 // NewSender returns a Sender
@@ -49,7 +57,7 @@ func NewSender(signers []driver.Signer, tokens []*token.Token, ids []string, inf
 
 // GenerateZKTransfer produces a TransferAction and an array of Metadata
 // that corresponds to the openings of the newly created outputs
-func (s *Sender) GenerateZKTransfer(values []uint64, owners [][]byte) (*TransferAction, []*token.Metadata, error) {
+func (s *Sender) GenerateZKTransfer(values []*big.Int, owners [][]byte) (*TransferAction, []*token.Metadata, error) {
 	if len(values) != len(owners) {
 		return nil, nil, errors.Errorf("cannot generate transfer: number of values [%d] does not match number of recipients [%d]", len(values), len(owners))
 	}
@@ -65,7 +73,7 @@ func (s *Sender) GenerateZKTransfer(values []uint64, owners [][]byte) (*Transfer
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "cannot generate transfer")
 	}
-	prover := NewProver(intw, outtw, in, out, s.PublicParams)
+	prover := NewProver(intw, outtw, in, out, s.PublicParams, s.WorkerPool)
 	proof, err := prover.Prove()
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "cannot generate zero-knowledge proof for transfer")
@@ -75,6 +83,10 @@ func (s *Sender) GenerateZKTransfer(values []uint64, owners [][]byte) (*Transfer
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to produce transfer action")
 	}
+	transfer.Upgrade = s.Upgrade
+	if err := token.EncryptForAuditor(transfer.OutputTokens, outtw, s.PublicParams, s.WorkerPool); err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to produce transfer action")
+	}
 	inf := make([]*token.Metadata, len(owners))
 	for i := 0; i < len(inf); i++ {
 		inf[i] = &token.Metadata{
@@ -112,6 +124,13 @@ type TransferAction struct {
 	Proof []byte
 	// Metadata contains the transfer action's metadata
 	Metadata map[string][]byte
+	// Spends hide the spent tokens in anonymity sets, if graph hiding is enabled.
+	// In this case, Inputs contains the keys of the serial numbers of the spent tokens
+	// and InputCommitments the fresh commitments replacing them.
+	Spends []*spend.Spend `json:",omitempty"`
+	// Upgrade is true if the inputs are outputs of the driver these public parameters succeed.
+	// In this case, the inputs are in the clear and InputCommitments commit to them with a zero blinding factor.
+	Upgrade bool `json:",omitempty"`
 }
 
 // NewTransfer returns the TransferAction that matches the passed arguments
@@ -201,10 +220,9 @@ func (t *TransferAction) GetOutputCommitments() []*math.G1 {
 	return com
 }
 
-// IsGraphHiding returns false
-// zkatdlog is not graph hiding
+// IsGraphHiding returns true if the TransferAction hides the spent tokens
 func (t *TransferAction) IsGraphHiding() bool {
-	return false
+	return len(t.Spends) != 0
 }
 
 // GetMetadata returns metadata of the TransferAction
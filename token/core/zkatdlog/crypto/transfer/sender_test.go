@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package transfer_test
 
 import (
+	"math/big"
+
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
@@ -27,7 +29,7 @@ var _ = Describe("Sender", func() {
 		sender   *transfer2.Sender
 
 		invalues  []*math.Zr
-		outvalues []uint64
+		outvalues []*big.Int
 		inBF      []*math.Zr
 		tokens    []*token.Token
 
@@ -63,9 +65,9 @@ var _ = Describe("Sender", func() {
 		for i := 0; i < 3; i++ {
 			inBF[i] = c.NewRandomZr(rand)
 		}
-		outvalues = make([]uint64, 2)
-		outvalues[0] = 65
-		outvalues[1] = 35
+		outvalues = make([]*big.Int, 2)
+		outvalues[0] = big.NewInt(65)
+		outvalues[1] = big.NewInt(35)
 
 		ids = make([]string, 3)
 		ids[0] = "0"
@@ -12,6 +12,8 @@ import (
 
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	rangeproof "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/range"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/pkg/errors"
@@ -30,18 +32,21 @@ type Proof struct {
 // Verifier verifies if a TransferAction is valid
 type Verifier struct {
 	WellFormedness   *WellFormednessVerifier
-	RangeCorrectness *rangeproof.Verifier
+	RangeCorrectness rangeproof.RangeVerifier
 }
 
 // Prover produces a proof that a TransferAction is valid
 type Prover struct {
 	WellFormedness   *WellFormednessProver
-	RangeCorrectness *rangeproof.Prover
+	RangeCorrectness rangeproof.RangeProver
+	// WorkerPool bounds the goroutines used to generate the proof
+	WorkerPool *common.WorkerPool
 }
 
-// NewProver returns a TransferAction Prover that corresponds to the passed arguments
-func NewProver(inputWitness, outputWitness []*token.TokenDataWitness, inputs, outputs []*math.G1, pp *crypto.PublicParams) *Prover {
-	p := &Prover{}
+// NewProver returns a TransferAction Prover that corresponds to the passed arguments.
+// The sub-proofs, and the range proofs of the outputs, are generated concurrently using the passed WorkerPool.
+func NewProver(inputWitness, outputWitness []*token.TokenDataWitness, inputs, outputs []*math.G1, pp *crypto.PublicParams, pool *common.WorkerPool) *Prover {
+	p := &Prover{WorkerPool: pool}
 
 	inW := make([]*token.TokenDataWitness, len(inputWitness))
 	outW := make([]*token.TokenDataWitness, len(outputWitness))
@@ -56,17 +61,7 @@ func NewProver(inputWitness, outputWitness []*token.TokenDataWitness, inputs, ou
 	// check if this is an ownership transfer
 	// if so, skip range proof, well-formedness proof is enough
 	if len(inputWitness) != 1 || len(outputWitness) != 1 {
-		p.RangeCorrectness = rangeproof.NewProver(
-			outW,
-			outputs,
-			pp.RangeProofParams.SignedValues,
-			int(pp.RangeProofParams.Exponent),
-			pp.PedParams,
-			pp.RangeProofParams.SignPK,
-			pp.PedGen,
-			pp.RangeProofParams.Q,
-			math.Curves[pp.Curve],
-		)
+		p.RangeCorrectness = rangeproof.NewRangeProver(outW, outputs, pp, pool)
 	}
 	wfw := NewWellFormednessWitness(inW, outW)
 	p.WellFormedness = NewWellFormednessProver(wfw, pp.PedParams, inputs, outputs, math.Curves[pp.Curve])
@@ -79,16 +74,7 @@ func NewVerifier(inputs, outputs []*math.G1, pp *crypto.PublicParams) *Verifier
 	// check if this is an ownership transfer
 	// if so, skip range proof, well-formedness proof is enough
 	if len(inputs) != 1 || len(outputs) != 1 {
-		v.RangeCorrectness = rangeproof.NewVerifier(
-			outputs,
-			uint64(len(pp.RangeProofParams.SignedValues)),
-			int(pp.RangeProofParams.Exponent),
-			pp.PedParams,
-			pp.RangeProofParams.SignPK,
-			pp.PedGen,
-			pp.RangeProofParams.Q,
-			math.Curves[pp.Curve],
-		)
+		v.RangeCorrectness = rangeproof.NewRangeVerifier(outputs, pp)
 	}
 	v.WellFormedness = NewWellFormednessVerifier(pp.PedParams, inputs, outputs, math.Curves[pp.Curve])
 
@@ -107,29 +93,21 @@ func (p *Proof) Deserialize(bytes []byte) error {
 
 // Prove produces a serialized Proof
 func (p *Prover) Prove() ([]byte, error) {
-	var wg sync.WaitGroup
-	wg.Add(1)
-
 	var wfProof, rangeProof []byte
-	var wfErr, rangeErr error
-
-	go func() {
-		defer wg.Done()
-		if p.RangeCorrectness != nil {
-			rangeProof, rangeErr = p.RangeCorrectness.Prove()
+	err := p.WorkerPool.Run(2, func(i int) error {
+		var err error
+		if i == 0 {
+			wfProof, err = p.WellFormedness.Prove()
+			return errors.Wrapf(err, "failed to generate transfer proof")
 		}
-	}()
-
-	wfProof, wfErr = p.WellFormedness.Prove()
-
-	wg.Wait()
-
-	if wfErr != nil {
-		return nil, errors.Wrapf(wfErr, "failed to generate transfer proof")
-	}
-
-	if rangeErr != nil {
-		return nil, errors.Wrapf(rangeErr, "failed to generate range proof for transfer")
+		if p.RangeCorrectness == nil {
+			return nil
+		}
+		rangeProof, err = p.RangeCorrectness.Prove()
+		return errors.Wrapf(err, "failed to generate range proof for transfer")
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	proof := &Proof{
@@ -142,6 +120,12 @@ func (p *Prover) Prove() ([]byte, error) {
 
 // Verify checks validity of serialized Proof
 func (v *Verifier) Verify(proof []byte) error {
+	return v.VerifyBatch(proof, nil)
+}
+
+// VerifyBatch checks validity of serialized Proof, deferring to the passed batch
+// the checks of the range proof that support it. If the batch is nil, the proof is fully checked.
+func (v *Verifier) VerifyBatch(proof []byte, b *batch.Verifier) error {
 	tp := Proof{}
 	err := tp.Deserialize(proof)
 	if err != nil {
@@ -160,7 +144,7 @@ func (v *Verifier) Verify(proof []byte) error {
 		defer wg.Done()
 		// verify range proof
 		if v.RangeCorrectness != nil {
-			rangeErr = v.RangeCorrectness.Verify(tp.RangeCorrectness)
+			rangeErr = rangeproof.VerifyBatch(v.RangeCorrectness, tp.RangeCorrectness, b)
 		}
 	}()
 
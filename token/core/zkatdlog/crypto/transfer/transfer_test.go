@@ -6,18 +6,52 @@ SPDX-License-Identifier: Apache-2.0
 package transfer_test
 
 import (
+	"fmt"
+	"math/big"
 	"sync"
 	"testing"
 
 	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/curvetest"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
 )
 
+func BenchmarkTransferProve(b *testing.B) {
+	for _, bulletproofs := range []bool{false, true} {
+		pp, err := crypto.Setup(100, 2, nil, math.FP256BN_AMCL)
+		if err != nil {
+			b.Fatal(err)
+		}
+		name := "signatures"
+		if bulletproofs {
+			name = "bulletproofs"
+			if err := pp.EnableBulletproofs(64); err != nil {
+				b.Fatal(err)
+			}
+		}
+		for _, workers := range []int{1, 2, 4, 8} {
+			b.Run(fmt.Sprintf("%s/outputs=20/workers=%d", name, workers), func(b *testing.B) {
+				prover, _, err := prepareLargeTransfer(pp, 20, common.NewWorkerPool(workers))
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := prover.Prove(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
 func TestParallelProveVerify(t *testing.T) {
 	parallelism := 1000
 
@@ -58,6 +92,57 @@ var _ = Describe("Transfer", func() {
 				Expect(err).NotTo(HaveOccurred())
 			})
 		})
+		Context("range proofs are Bulletproofs", func() {
+			BeforeEach(func() {
+				prover, verifier = prepareZKTransferWithBulletproofs(64)
+			})
+			It("Succeeds", func() {
+				proof, err := prover.Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(proof).NotTo(BeNil())
+				err = verifier.Verify(proof)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+		Context("range proofs are Bulletproofs over 128 bits", func() {
+			BeforeEach(func() {
+				prover, verifier = prepareZKTransferWithBulletproofs(128)
+			})
+			It("Succeeds", func() {
+				proof, err := prover.Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(proof).NotTo(BeNil())
+				err = verifier.Verify(proof)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+		Context("the proofs are generated by a bounded worker pool", func() {
+			var pp *crypto.PublicParams
+			BeforeEach(func() {
+				var err error
+				pp, err = crypto.Setup(100, 2, nil, math.FP256BN_AMCL)
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("Succeeds with any number of workers", func() {
+				for _, workers := range []int{1, 3} {
+					var err error
+					prover, verifier, err = prepareLargeTransfer(pp, 20, common.NewWorkerPool(workers))
+					Expect(err).NotTo(HaveOccurred())
+					proof, err := prover.Prove()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(verifier.Verify(proof)).To(Succeed())
+				}
+			})
+			It("Succeeds with Bulletproofs", func() {
+				Expect(pp.EnableBulletproofs(64)).To(Succeed())
+				var err error
+				prover, verifier, err = prepareLargeTransfer(pp, 20, common.NewWorkerPool(3))
+				Expect(err).NotTo(HaveOccurred())
+				proof, err := prover.Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(verifier.Verify(proof)).To(Succeed())
+			})
+		})
 		Context("Output Values > Input Values", func() {
 			BeforeEach(func() {
 				prover, verifier = prepareZKTransferWithWrongSum()
@@ -85,10 +170,35 @@ var _ = Describe("Transfer", func() {
 	})
 })
 
+var _ = DescribeTable("Zero Knowledge Transfer on every supported curve",
+	func(curveID math.CurveID, bulletproofs bool) {
+		pp, err := crypto.SetupWithCurves(100, 2, nil, crypto.DLogPublicParameters, curveID, math.FP256BN_AMCL)
+		Expect(err).NotTo(HaveOccurred())
+		if bulletproofs {
+			Expect(pp.EnableBulletproofs(64)).To(Succeed())
+		}
+		prover, verifier := prepareZKTransferWithParams(pp)
+		proof, err := prover.Prove()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verifier.Verify(proof)).To(Succeed())
+	},
+	curvetest.EntriesWithBulletproofs(),
+)
+
 func prepareZKTransfer() (*transfer.Prover, *transfer.Verifier) {
 	pp, err := crypto.Setup(100, 2, nil, math.FP256BN_AMCL)
 	Expect(err).NotTo(HaveOccurred())
+	return prepareZKTransferWithParams(pp)
+}
+
+func prepareZKTransferWithBulletproofs(bitLength uint) (*transfer.Prover, *transfer.Verifier) {
+	pp, err := crypto.Setup(100, 2, nil, math.FP256BN_AMCL)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(pp.EnableBulletproofs(bitLength)).To(Succeed())
+	return prepareZKTransferWithParams(pp)
+}
 
+func prepareZKTransferWithParams(pp *crypto.PublicParams) (*transfer.Prover, *transfer.Verifier) {
 	wfw, in, out := prepareInputsForZKTransfer(pp)
 
 	inBF := wfw.GetInBlindingFactors()
@@ -107,7 +217,7 @@ func prepareZKTransfer() (*transfer.Prover, *transfer.Verifier) {
 	for i := 0; i < len(outtw); i++ {
 		outtw[i] = &token.TokenDataWitness{BlindingFactor: outBF[i], Value: outValues[i], Type: ttype}
 	}
-	prover := transfer.NewProver(intw, outtw, in, out, pp)
+	prover := transfer.NewProver(intw, outtw, in, out, pp, nil)
 	verifier := transfer.NewVerifier(in, out, pp)
 
 	return prover, verifier
@@ -136,7 +246,7 @@ func prepareZKTransferWithWrongSum() (*transfer.Prover, *transfer.Verifier) {
 		outtw[i] = &token.TokenDataWitness{BlindingFactor: outBF[i], Value: outValues[i], Type: ttype}
 	}
 
-	prover := transfer.NewProver(intw, outtw, in, out, pp)
+	prover := transfer.NewProver(intw, outtw, in, out, pp, nil)
 	verifier := transfer.NewVerifier(in, out, pp)
 
 	return prover, verifier
@@ -165,7 +275,7 @@ func prepareZKTransferWithInvalidRange() (*transfer.Prover, *transfer.Verifier)
 		outtw[i] = &token.TokenDataWitness{BlindingFactor: outBF[i], Value: outValues[i], Type: ttype}
 	}
 
-	prover := transfer.NewProver(intw, outtw, in, out, pp)
+	prover := transfer.NewProver(intw, outtw, in, out, pp, nil)
 	verifier := transfer.NewVerifier(in, out, pp)
 	return prover, verifier
 }
@@ -286,8 +396,27 @@ func prepareOwnershipTransfer() (*transfer.Prover, *transfer.Verifier) {
 	for i := 0; i < len(outtw); i++ {
 		outtw[i] = &token.TokenDataWitness{BlindingFactor: outBF[i], Value: outValues[i], Type: ttype}
 	}
-	prover := transfer.NewProver(intw, outtw, in, out, pp)
+	prover := transfer.NewProver(intw, outtw, in, out, pp, nil)
 	verifier := transfer.NewVerifier(in, out, pp)
 
 	return prover, verifier
 }
+
+// prepareLargeTransfer returns a prover and a verifier for a transfer of two inputs to the passed number of outputs
+func prepareLargeTransfer(pp *crypto.PublicParams, outputs int, pool *common.WorkerPool) (*transfer.Prover, *transfer.Verifier, error) {
+	c := math.Curves[pp.Curve]
+	inValues := []*big.Int{big.NewInt(int64(10 * outputs)), big.NewInt(int64(10 * outputs))}
+	outValues := make([]*big.Int, outputs)
+	for i := 0; i < outputs; i++ {
+		outValues[i] = big.NewInt(20)
+	}
+	in, intw, err := token.GetTokensWithWitness(inValues, "ABC", pp.PedParams, c)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, outtw, err := token.GetTokensWithWitness(outValues, "ABC", pp.PedParams, c)
+	if err != nil {
+		return nil, nil, err
+	}
+	return transfer.NewProver(intw, outtw, in, out, pp, pool), transfer.NewVerifier(in, out, pp), nil
+}
@@ -0,0 +1,36 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package transfer
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// NewUpgradeSender returns a Sender that spends the passed outputs of the predecessor driver, in the clear,
+// and creates zkatdlog outputs. The public parameters must declare the migration.
+func NewUpgradeSender(signers []driver.Signer, tokens []*token2.Token, ids []string, pp *crypto.PublicParams) (*Sender, error) {
+	if pp.MigrationParams == nil {
+		return nil, errors.New("cannot upgrade tokens: migration is not enabled")
+	}
+	if len(signers) != len(tokens) || len(tokens) != len(ids) {
+		return nil, errors.Errorf("number of tokens to be upgraded does not match number of signers or identifiers")
+	}
+	inputs := make([]*token.Token, len(tokens))
+	inf := make([]*token.Metadata, len(tokens))
+	for i, tok := range tokens {
+		var err error
+		inputs[i], inf[i], err = token.CommitToClearToken(tok, pp)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "cannot upgrade token [%s]", ids[i])
+		}
+	}
+	return &Sender{Signers: signers, Inputs: inputs, InputIDs: ids, InputInformation: inf, PublicParams: pp, Upgrade: true}, nil
+}
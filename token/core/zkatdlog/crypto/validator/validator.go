@@ -8,13 +8,19 @@ package validator
 
 import (
 	"bytes"
+	"time"
 
+	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/metrics"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/tracing"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
 	issue2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/anonym"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/pkg/errors"
@@ -26,6 +32,7 @@ type Validator struct {
 	pp                 *crypto.PublicParams
 	deserializer       driver.Deserializer
 	transferValidators []ValidateTransferFunc
+	batchVerification  bool
 }
 
 func New(pp *crypto.PublicParams, deserializer driver.Deserializer, extraValidators ...ValidateTransferFunc) *Validator {
@@ -34,6 +41,15 @@ func New(pp *crypto.PublicParams, deserializer driver.Deserializer, extraValidat
 		TransferZKProofValidate,
 		TransferHTLCValidate,
 	}
+	if pp.GraphHiding() {
+		transferValidators[0] = TransferSpendValidate
+	}
+	if pp.MigrationParams != nil {
+		transferValidators[0] = TransferUpgradeValidate
+	}
+	if pp.AuditEncryption() {
+		transferValidators = append(transferValidators, TransferAuditEncryptionValidate)
+	}
 	transferValidators = append(transferValidators, extraValidators...)
 	return &Validator{
 		pp:                 pp,
@@ -42,13 +58,111 @@ func New(pp *crypto.PublicParams, deserializer driver.Deserializer, extraValidat
 	}
 }
 
+// SetBatchVerification enables or disables batch verification, it is disabled by default.
+// When enabled, the proofs of all the actions of a token request, and of all the token requests
+// passed to VerifyTokenRequestsFromRaw, are checked at once with random linear combinations
+// of their verification equations. If the batch fails, the proofs are checked one by one to find the invalid ones.
+// Only Bulletproofs range proofs are batched, the other proofs are checked one by one
+// since their challenges are recomputed from their commitments.
+// Then, with public parameters without Bulletproofs, batch verification has no effect.
+func (v *Validator) SetBatchVerification(enabled bool) {
+	if enabled && !v.pp.Bulletproofs() {
+		logger.Warnf("batch verification has no effect, the range proofs of the public parameters are not Bulletproofs")
+	}
+	v.batchVerification = enabled
+}
+
+// VerifyTokenRequestFromRaw verifies the passed marshalled token request.
+// The time constraints of the request are checked against the local clock.
 func (v *Validator) VerifyTokenRequestFromRaw(getState driver.GetStateFnc, binding string, raw []byte) ([]interface{}, error) {
+	return v.VerifyTokenRequestFromRawAt(getState, binding, raw, time.Time{})
+}
+
+// VerifyTokenRequestFromRawAt verifies the passed marshalled token request.
+// The time constraints of the request, such as the deadline of a migration, are checked against the passed timestamp
+// of the transaction, or the local clock if it is zero.
+func (v *Validator) VerifyTokenRequestFromRawAt(getState driver.GetStateFnc, binding string, raw []byte, timestamp time.Time) (actions []interface{}, err error) {
+	defer observeValidation(time.Now(), 1)
+	span := tracing.Get().StartTxSpan("zkatdlog.verify", binding, tracing.SpanContext{})
+	defer func() { span.End(err) }()
+	b, err := v.newProofBatch()
+	if err != nil {
+		return nil, err
+	}
+	actions, err = v.verifyTokenRequestFromRaw(getState, binding, raw, timestamp, b)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.check(); err != nil {
+		validationFailed(metrics.ReasonProof)
+		return nil, errors.Wrapf(err, "failed to verify proofs [%s]", binding)
+	}
+	return actions, nil
+}
+
+// VerifyTokenRequestsFromRaw verifies the passed marshalled token requests, for instance those of a block,
+// against the passed ledger and their anchors.
+// The time constraints of the requests are checked against the local clock.
+// It returns, for each request, its actions if it is valid, or the reason why it is not.
+// With batch verification, the proofs of all the requests are checked at once.
+func (v *Validator) VerifyTokenRequestsFromRaw(getState driver.GetStateFnc, bindings []string, raws [][]byte) ([][]interface{}, []error) {
+	defer observeValidation(time.Now(), len(raws))
+	actions := make([][]interface{}, len(raws))
+	errs := make([]error, len(raws))
+	if len(bindings) != len(raws) {
+		for i := range errs {
+			errs[i] = errors.Errorf("number of anchors [%d] does not match number of token requests [%d]", len(bindings), len(raws))
+		}
+		return actions, errs
+	}
+	block, err := v.newProofBatch()
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return actions, errs
+	}
+	batches := make([]*proofBatch, len(raws))
+	for i, raw := range raws {
+		b, err := v.newProofBatch()
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		actions[i], errs[i] = v.verifyTokenRequestFromRaw(getState, bindings[i], raw, time.Time{}, b)
+		if errs[i] != nil {
+			actions[i] = nil
+			continue
+		}
+		block.merge(b)
+		batches[i] = b
+	}
+	if block == nil || block.verifier.Check() == nil {
+		return actions, errs
+	}
+	// find the requests with invalid proofs
+	for i, b := range batches {
+		if b == nil {
+			continue
+		}
+		if err := b.check(); err != nil {
+			validationFailed(metrics.ReasonProof)
+			actions[i] = nil
+			errs[i] = errors.Wrapf(err, "failed to verify proofs [%s]", bindings[i])
+		}
+	}
+	return actions, errs
+}
+
+func (v *Validator) verifyTokenRequestFromRaw(getState driver.GetStateFnc, binding string, raw []byte, timestamp time.Time, b *proofBatch) ([]interface{}, error) {
 	if len(raw) == 0 {
+		validationFailed(metrics.ReasonMalformed)
 		return nil, errors.New("empty token request")
 	}
 	tr := &driver.TokenRequest{}
 	err := tr.FromBytes(raw)
 	if err != nil {
+		validationFailed(metrics.ReasonMalformed)
 		return nil, errors.Wrap(err, "failed to unmarshal token request")
 	}
 
@@ -58,6 +172,7 @@ func (v *Validator) VerifyTokenRequestFromRaw(getState driver.GetStateFnc, bindi
 	req.Issues = tr.Issues
 	raqRaw, err := req.Bytes()
 	if err != nil {
+		validationFailed(metrics.ReasonMalformed)
 		return nil, errors.Wrap(err, "failed to marshal signed token request")
 	}
 
@@ -72,23 +187,46 @@ func (v *Validator) VerifyTokenRequestFromRaw(getState driver.GetStateFnc, bindi
 	}
 
 	backend := common.NewBackend(getState, signed, signatures)
-	return v.VerifyTokenRequest(backend, backend, binding, tr)
+	return v.verifyTokenRequest(backend, backend, binding, tr, timestamp, b)
+}
+
+func (v *Validator) VerifyTokenRequest(ledger driver.Ledger, signatureProvider driver.SignatureProvider, binding string, tr *driver.TokenRequest) (actions []interface{}, err error) {
+	defer observeValidation(time.Now(), 1)
+	span := tracing.Get().StartTxSpan("zkatdlog.verify", binding, tracing.SpanContext{})
+	defer func() { span.End(err) }()
+	b, err := v.newProofBatch()
+	if err != nil {
+		return nil, err
+	}
+	actions, err = v.verifyTokenRequest(ledger, signatureProvider, binding, tr, time.Time{}, b)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.check(); err != nil {
+		validationFailed(metrics.ReasonProof)
+		return nil, errors.Wrapf(err, "failed to verify proofs [%s]", binding)
+	}
+	return actions, nil
 }
 
-func (v *Validator) VerifyTokenRequest(ledger driver.Ledger, signatureProvider driver.SignatureProvider, binding string, tr *driver.TokenRequest) ([]interface{}, error) {
+func (v *Validator) verifyTokenRequest(ledger driver.Ledger, signatureProvider driver.SignatureProvider, binding string, tr *driver.TokenRequest, timestamp time.Time, b *proofBatch) ([]interface{}, error) {
 	if err := v.verifyAuditorSignature(signatureProvider); err != nil {
+		validationFailed(metrics.ReasonAuditorSignature)
 		return nil, errors.Wrapf(err, "failed to verifier auditor's signature [%s]", binding)
 	}
 	ia, ta, err := v.UnmarshalIssueTransferActions(tr)
 	if err != nil {
+		validationFailed(metrics.ReasonMalformed)
 		return nil, errors.Wrapf(err, "failed to unmarshal actions [%s]", binding)
 	}
-	err = v.verifyIssues(ia, signatureProvider)
+	err = v.verifyIssues(ia, signatureProvider, b)
 	if err != nil {
+		validationFailed(metrics.ReasonIssue)
 		return nil, errors.Wrapf(err, "failed to verify issuers' signatures [%s]", binding)
 	}
-	err = v.verifyTransfers(ledger, ta, signatureProvider)
+	err = v.verifyTransfers(ledger, ta, signatureProvider, timestamp, b)
 	if err != nil {
+		validationFailed(metrics.ReasonTransfer)
 		return nil, errors.Wrapf(err, "failed to verify senders' signatures [%s]", binding)
 	}
 
@@ -174,14 +312,25 @@ func (v *Validator) verifyAuditorSignature(signatureProvider driver.SignaturePro
 	return nil
 }
 
-func (v *Validator) verifyIssues(issues []driver.IssueAction, signatureProvider driver.SignatureProvider) error {
+func (v *Validator) verifyIssues(issues []driver.IssueAction, signatureProvider driver.SignatureProvider, b *proofBatch) error {
 	for _, issue := range issues {
 		a := issue.(*issue2.IssueAction)
 
-		if err := v.verifyIssue(a); err != nil {
+		if err := v.verifyIssue(a, b); err != nil {
 			return errors.Wrapf(err, "failed to verify issue action")
 		}
 
+		if a.IsAnonymous() {
+			// the issuer is one of the anonymous issuers, the signature proves it without revealing which one
+			if len(a.Issuer) != 0 {
+				return errors.New("anonymous issue action must not disclose the issuer")
+			}
+			if _, err := signatureProvider.HasBeenSignedBy(a.Issuer, anonym.NewVerifier(v.pp)); err != nil {
+				return errors.Wrapf(err, "failed verifying anonymous issuer signature")
+			}
+			continue
+		}
+
 		issuers := v.pp.Issuers
 		if len(issuers) != 0 {
 			// Check the issuer is among those known
@@ -208,32 +357,56 @@ func (v *Validator) verifyIssues(issues []driver.IssueAction, signatureProvider
 	return nil
 }
 
-func (v *Validator) verifyIssue(issue driver.IssueAction) error {
+func (v *Validator) verifyIssue(issue driver.IssueAction, b *proofBatch) error {
 	action := issue.(*issue2.IssueAction)
 	commitments, err := action.GetCommitments()
 	if err != nil {
 		return errors.New("failed to verify issue")
 	}
-	return issue2.NewVerifier(
+	if v.pp.GraphHiding() {
+		// new tokens must be spendable
+		for i, out := range action.OutputTokens {
+			if out.Serial == nil {
+				return errors.Errorf("failed to verify issue: output at index [%d] has no serial number", i)
+			}
+		}
+	}
+	if v.pp.AuditEncryption() {
+		if err := verifyAuditEncryption(action.OutputTokens, v.pp); err != nil {
+			return errors.WithMessage(err, "failed to verify issue")
+		}
+	}
+	verifier := issue2.NewVerifier(
 		commitments,
 		action.IsAnonymous(),
-		v.pp).Verify(action.GetProof())
+		v.pp)
+	if b == nil {
+		return verifier.Verify(action.GetProof())
+	}
+	b.fallbacks = append(b.fallbacks, func() error {
+		return errors.Wrapf(verifier.Verify(action.GetProof()), "failed to verify issue action")
+	})
+	return verifier.VerifyBatch(action.GetProof(), b.verifier)
 }
 
-func (v *Validator) verifyTransfers(ledger driver.Ledger, transferActions []driver.TransferAction, signatureProvider driver.SignatureProvider) error {
+func (v *Validator) verifyTransfers(ledger driver.Ledger, transferActions []driver.TransferAction, signatureProvider driver.SignatureProvider, timestamp time.Time, b *proofBatch) error {
 	logger.Debugf("check sender start...")
 	defer logger.Debugf("check sender finished.")
 	for _, t := range transferActions {
-		if err := v.verifyTransfer(t, ledger, signatureProvider); err != nil {
+		if err := v.verifyTransfer(t, ledger, signatureProvider, timestamp, b); err != nil {
 			return errors.Wrapf(err, "failed to verify transfer action")
 		}
 	}
 	return nil
 }
 
-func (v *Validator) verifyTransfer(tr driver.TransferAction, ledger driver.Ledger, signatureProvider driver.SignatureProvider) error {
+func (v *Validator) verifyTransfer(tr driver.TransferAction, ledger driver.Ledger, signatureProvider driver.SignatureProvider, timestamp time.Time, b *proofBatch) error {
 	action := tr.(*transfer.TransferAction)
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
 	context := &Context{
+		Timestamp:         timestamp,
 		PP:                v.pp,
 		Deserializer:      v.deserializer,
 		Action:            action,
@@ -241,11 +414,21 @@ func (v *Validator) verifyTransfer(tr driver.TransferAction, ledger driver.Ledge
 		SignatureProvider: signatureProvider,
 		MetadataCounter:   map[string]int{},
 	}
+	if b != nil {
+		context.Batch = b.verifier
+	}
 	for _, v := range v.transferValidators {
 		if err := v(context); err != nil {
 			return err
 		}
 	}
+	if b != nil {
+		b.fallbacks = append(b.fallbacks, func() error {
+			individual := *context
+			individual.Batch = nil
+			return errors.Wrapf(TransferZKProofValidate(&individual), "failed to verify transfer action")
+		})
+	}
 
 	// Check that all metadata have been validated
 	counter := 0
@@ -261,3 +444,63 @@ func (v *Validator) verifyTransfer(tr driver.TransferAction, ledger driver.Ledge
 
 	return nil
 }
+
+// proofBatch collects the checks of the proofs of token requests that can be verified at once
+type proofBatch struct {
+	verifier *batch.Verifier
+	// fallbacks check the proofs one by one, to find the invalid ones when the batch fails
+	fallbacks []func() error
+}
+
+// newProofBatch returns an empty proofBatch, or nil if batch verification is disabled
+// or there is nothing to batch
+func (v *Validator) newProofBatch() (*proofBatch, error) {
+	if !v.batchVerification || !v.pp.Bulletproofs() {
+		return nil, nil
+	}
+	verifier, err := batch.NewVerifier(math.Curves[v.pp.Curve])
+	if err != nil {
+		return nil, err
+	}
+	return &proofBatch{verifier: verifier}, nil
+}
+
+// merge adds the proofs collected by the passed batch to this batch
+func (b *proofBatch) merge(other *proofBatch) {
+	if b == nil || other == nil {
+		return
+	}
+	b.verifier.Merge(other.verifier)
+	b.fallbacks = append(b.fallbacks, other.fallbacks...)
+}
+
+// check returns nil if all the proofs in the batch are valid,
+// otherwise it returns the error of the first invalid proof
+func (b *proofBatch) check() error {
+	if b == nil || b.verifier.Len() == 0 || b.verifier.Check() == nil {
+		return nil
+	}
+	logger.Debugf("batch verification failed, verify proofs one by one")
+	for _, fallback := range b.fallbacks {
+		if err := fallback(); err != nil {
+			return err
+		}
+	}
+	return errors.New("batch verification failed")
+}
+
+// observeValidation records the time taken to validate the passed number of token requests, amortized over them
+func observeValidation(start time.Time, requests int) {
+	if requests == 0 {
+		return
+	}
+	elapsed := metrics.Since(start) / float64(requests)
+	h := metrics.Get().ValidationDuration.With("driver", crypto.DLogPublicParameters)
+	for i := 0; i < requests; i++ {
+		h.Observe(elapsed)
+	}
+}
+
+func validationFailed(reason string) {
+	metrics.Get().ValidationFailures.With("driver", crypto.DLogPublicParameters, "reason", reason).Add(1)
+}
@@ -0,0 +1,33 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package validator
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/pkg/errors"
+)
+
+// TransferAuditEncryptionValidate checks that the outputs of the transfer carry the encryption of their
+// type and value for the auditor
+func TransferAuditEncryptionValidate(ctx *Context) error {
+	return verifyAuditEncryption(ctx.Action.OutputTokens, ctx.PP)
+}
+
+// verifyAuditEncryption checks that each of the passed outputs carries the encryption of its type and value
+// for the auditor, that it matches the commitment in the output, and that the chunks of its value are in range
+func verifyAuditEncryption(outputs []*token.Token, pp *crypto.PublicParams) error {
+	for i, out := range outputs {
+		if out == nil || out.Audit == nil {
+			return errors.Errorf("output at index [%d] is not encrypted for the auditor", i)
+		}
+		if err := out.Audit.Verify(out.Data, pp); err != nil {
+			return errors.WithMessagef(err, "output at index [%d]", i)
+		}
+	}
+	return nil
+}
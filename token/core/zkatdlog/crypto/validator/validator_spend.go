@@ -0,0 +1,147 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package validator
+
+import (
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/idemix"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/spend"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	"github.com/pkg/errors"
+)
+
+// TransferSpendValidate replaces TransferSignatureValidate when graph hiding is enabled.
+// It checks that each spent token is hidden in an anonymity set of tokens on the ledger,
+// that the inputs of the action are the serial numbers of the spent tokens,
+// and that the fresh owners of the spent tokens signed the request.
+// The fresh commitments and owners become the input tokens of the context.
+func TransferSpendValidate(ctx *Context) error {
+	action := ctx.Action
+	if !action.IsGraphHiding() {
+		return errors.New("invalid transfer action: the spent tokens must be hidden")
+	}
+	inputs, err := action.GetInputs()
+	if err != nil {
+		return errors.Wrapf(err, "failed to retrieve inputs to spend")
+	}
+	if len(action.Spends) != len(inputs) || len(action.InputCommitments) != len(inputs) {
+		return errors.Errorf("invalid transfer action: number of spends [%d], inputs [%d], and input commitments [%d] do not match", len(action.Spends), len(inputs), len(action.InputCommitments))
+	}
+
+	var tokens []*token.Token
+	var signatures [][]byte
+	serialNumbers := map[string]bool{}
+	for i, s := range action.Spends {
+		if s == nil || s.SerialNumber == nil {
+			return errors.Errorf("invalid spend at index [%d]", i)
+		}
+		snKey, err := keys.CreateSNKey(s.SerialNumberID())
+		if err != nil {
+			return errors.Wrapf(err, "failed creating serial number key at index [%d]", i)
+		}
+		if inputs[i] != snKey {
+			return errors.Errorf("input at index [%d] does not match the serial number of the spend", i)
+		}
+		if serialNumbers[snKey] {
+			return errors.Errorf("serial number at index [%d] spent more than once", i)
+		}
+		serialNumbers[snKey] = true
+
+		set, err := loadAnonymitySet(ctx.Ledger, s.AnonymitySet, ctx.PP.IdemixCurveID)
+		if err != nil {
+			return errors.WithMessagef(err, "failed loading anonymity set of spend [%d]", i)
+		}
+		nym, err := ownerNym(s.Owner, ctx.PP.IdemixCurveID)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid owner of spend [%d]", i)
+		}
+		if err := spend.NewVerifier(set, action.InputCommitments[i], nym, s, ctx.PP).Verify(); err != nil {
+			return errors.WithMessagef(err, "failed verifying spend [%d]", i)
+		}
+
+		verifier, err := ctx.Deserializer.GetOwnerVerifier(s.Owner)
+		if err != nil {
+			return errors.Wrapf(err, "failed deserializing owner of spend [%d]", i)
+		}
+		sigma, err := ctx.SignatureProvider.HasBeenSignedBy(s.Owner, verifier)
+		if err != nil {
+			return errors.Wrapf(err, "failed signature verification of spend [%d]", i)
+		}
+		tokens = append(tokens, &token.Token{Owner: s.Owner, Data: action.InputCommitments[i]})
+		signatures = append(signatures, sigma)
+	}
+
+	// new tokens must be spendable
+	for i, out := range action.OutputTokens {
+		if !out.IsRedeem() && out.Serial == nil {
+			return errors.Errorf("output at index [%d] has no serial number", i)
+		}
+	}
+
+	ctx.InputTokens = tokens
+	ctx.Signatures = signatures
+
+	return nil
+}
+
+// loadAnonymitySet loads from the ledger the tokens with the passed keys.
+// The keys must be distinct, a repeated token would shrink the anonymity set.
+func loadAnonymitySet(ledger driver.Ledger, ids []string, curveID math.CurveID) ([]*spend.Element, error) {
+	seen := map[string]bool{}
+	for _, key := range ids {
+		if seen[key] {
+			return nil, errors.Errorf("token [%s] appears more than once in the anonymity set", key)
+		}
+		seen[key] = true
+	}
+	set := make([]*spend.Element, len(ids))
+	for i, key := range ids {
+		id, err := keys.GetTokenIdFromKey(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid token key [%s]", key)
+		}
+		expected, err := keys.CreateTokenKey(id.TxId, id.Index)
+		if err != nil || expected != key || id.TxId == keys.SerialNumber {
+			return nil, errors.Errorf("invalid token key [%s]", key)
+		}
+		raw, err := ledger.GetState(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to retrieve token [%s]", key)
+		}
+		if len(raw) == 0 {
+			return nil, errors.Errorf("token [%s] does not exist", key)
+		}
+		tok := &token.Token{}
+		if err := tok.Deserialize(raw); err != nil {
+			return nil, errors.Wrapf(err, "failed to deserialize token [%s]", key)
+		}
+		if tok.IsRedeem() || tok.Serial == nil {
+			return nil, errors.Errorf("token [%s] cannot be spent", key)
+		}
+		nym, err := ownerNym(tok.Owner, curveID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid owner of token [%s]", key)
+		}
+		set[i] = &spend.Element{Commitment: tok.Data, Serial: tok.Serial, Nym: nym}
+	}
+	return set, nil
+}
+
+// ownerNym returns the pseudonym of the passed owner, that must be an idemix identity
+func ownerNym(owner []byte, curveID math.CurveID) (*math.G1, error) {
+	ro, err := identity.UnmarshallRawOwner(owner)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal owner")
+	}
+	if ro.Type != identity.SerializedIdentityType {
+		return nil, errors.Errorf("owner of type [%s] cannot be hidden", ro.Type)
+	}
+	return idemix.NymFromIdentity(ro.Identity, curveID)
+}
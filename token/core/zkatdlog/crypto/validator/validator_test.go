@@ -10,6 +10,7 @@ import (
 	"encoding/asn1"
 	"encoding/json"
 	"io/ioutil"
+	"math/big"
 	"time"
 
 	math "github.com/IBM/mathlib"
@@ -19,22 +20,30 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
 	registry2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/curvetest"
 	msp2 "github.com/hyperledger/fabric/msp"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/x509"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/audit"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/bulletproof"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/disclosure"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/ecdsa"
 	issue2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/anonym"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/nonanonym"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/spend"
 	tokn "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
 	enginedlog "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/validator"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/validator/mock"
 	zkatdlog "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 var fakeldger *mock.Ledger
@@ -148,6 +157,36 @@ var _ = Describe("validator", func() {
 			})
 		})
 
+		Context("Validator is called correctly with an anonymous issue action", func() {
+			var (
+				err error
+				raw []byte
+			)
+			BeforeEach(func() {
+				_, air, _ := prepareAnonymousIssueRequest(pp, auditor)
+				raw, err = asn1.Marshal(*air)
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("succeeds", func() {
+				actions, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(actions)).To(Equal(1))
+			})
+			Context("when the issuer is no longer among the anonymous issuers", func() {
+				BeforeEach(func() {
+					key, err := anonym.NewSecretKey(pp)
+					Expect(err).NotTo(HaveOccurred())
+					id, err := key.Identity()
+					Expect(err).NotTo(HaveOccurred())
+					pp.Issuers = [][]byte{id}
+				})
+				It("fails", func() {
+					_, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("failed verifying anonymous issuer signature"))
+				})
+			})
+		})
 		Context("validator is called correctly with a transfer action", func() {
 			var (
 				err error
@@ -278,6 +317,395 @@ var _ = Describe("validator", func() {
 	})
 })
 
+var _ = Describe("batch verification", func() {
+	var (
+		engine *enginedlog.Validator
+		pp     *crypto.PublicParams
+
+		auditor *audit.Auditor
+		valid   [][]byte
+		invalid []byte
+	)
+	BeforeEach(func() {
+		ipk, err := ioutil.ReadFile("./testdata/idemix/msp/IssuerPublicKey")
+		Expect(err).NotTo(HaveOccurred())
+		pp, err = crypto.Setup(100, 2, ipk, math.FP256BN_AMCL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pp.EnableBulletproofs(64)).To(Succeed())
+
+		asigner, _ := prepareECDSASigner()
+		des, err := idemix2.NewDeserializer(pp.IdemixIssuerPK)
+		Expect(err).NotTo(HaveOccurred())
+		auditor = audit.NewAuditor(&deserializer{idemix: des}, pp.PedParams, pp.IdemixIssuerPK, asigner, math.Curves[pp.Curve])
+		araw, err := asigner.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		pp.Auditor = araw
+
+		deserializer, err := zkatdlog.NewDeserializer(pp)
+		Expect(err).NotTo(HaveOccurred())
+		engine = enginedlog.New(pp, deserializer)
+		engine.SetBatchVerification(true)
+
+		valid = nil
+		for i := 0; i < 2; i++ {
+			_, ir, _ := prepareNonAnonymousIssueRequest(pp, auditor)
+			raw, err := asn1.Marshal(*ir)
+			Expect(err).NotTo(HaveOccurred())
+			valid = append(valid, raw)
+		}
+
+		// the issuer signs an issue action whose range proof is invalid
+		signer, err := ecdsa.NewECDSASigner()
+		Expect(err).NotTo(HaveOccurred())
+		issuer := &tamperingIssuer{Issuer: &nonanonym.Issuer{}}
+		issuer.New("ABC", signer, pp)
+		ir, _ := prepareIssue(auditor, issuer)
+		invalid, err = asn1.Marshal(*ir)
+		Expect(err).NotTo(HaveOccurred())
+	})
+	Context("when the proofs are valid", func() {
+		It("succeeds", func() {
+			for _, raw := range valid {
+				actions, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(actions)).To(Equal(1))
+			}
+
+			all, errs := engine.VerifyTokenRequestsFromRaw(getState, []string{"1", "1"}, valid)
+			Expect(errs).To(Equal([]error{nil, nil}))
+			Expect(len(all[0])).To(Equal(1))
+			Expect(len(all[1])).To(Equal(1))
+		})
+	})
+	Context("when a range proof is invalid", func() {
+		It("fails", func() {
+			_, err := engine.VerifyTokenRequestFromRaw(getState, "1", invalid)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("polynomial evaluation does not match"))
+		})
+		It("finds the invalid request", func() {
+			all, errs := engine.VerifyTokenRequestsFromRaw(getState, []string{"1", "1", "1"}, [][]byte{valid[0], invalid, valid[1]})
+			Expect(errs[0]).NotTo(HaveOccurred())
+			Expect(errs[1]).To(HaveOccurred())
+			Expect(errs[1].Error()).To(ContainSubstring("failed to verify issue action"))
+			Expect(errs[1].Error()).To(ContainSubstring("polynomial evaluation does not match"))
+			Expect(errs[2]).NotTo(HaveOccurred())
+			Expect(all[1]).To(BeNil())
+			Expect(len(all[2])).To(Equal(1))
+		})
+		It("fails also without batch verification", func() {
+			engine.SetBatchVerification(false)
+			_, err := engine.VerifyTokenRequestFromRaw(getState, "1", invalid)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("polynomial evaluation does not match"))
+		})
+	})
+})
+
+var _ = Describe("audit encryption", func() {
+	var (
+		engine *enginedlog.Validator
+		pp     *crypto.PublicParams
+
+		auditor *audit.Auditor
+	)
+	BeforeEach(func() {
+		fakeldger = &mock.Ledger{}
+		ipk, err := ioutil.ReadFile("./testdata/idemix/msp/IssuerPublicKey")
+		Expect(err).NotTo(HaveOccurred())
+		pp, err = crypto.Setup(100, 2, ipk, math.FP256BN_AMCL)
+		Expect(err).NotTo(HaveOccurred())
+		sk, err := disclosure.NewSecretKey(pp)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pp.EnableAuditEncryption(sk.H)).To(Succeed())
+
+		asigner, _ := prepareECDSASigner()
+		des, err := idemix2.NewDeserializer(pp.IdemixIssuerPK)
+		Expect(err).NotTo(HaveOccurred())
+		auditor = audit.NewAuditor(&deserializer{idemix: des}, pp.PedParams, pp.IdemixIssuerPK, asigner, math.Curves[pp.Curve])
+		araw, err := asigner.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		pp.Auditor = araw
+
+		deserializer, err := zkatdlog.NewDeserializer(pp)
+		Expect(err).NotTo(HaveOccurred())
+		engine = enginedlog.New(pp, deserializer)
+	})
+	Context("when the outputs of an issue are encrypted for the auditor", func() {
+		It("succeeds", func() {
+			_, ir, _ := prepareNonAnonymousIssueRequest(pp, auditor)
+			Expect(ir.Issues).To(HaveLen(1))
+			raw, err := asn1.Marshal(*ir)
+			Expect(err).NotTo(HaveOccurred())
+			actions, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(actions)).To(Equal(1))
+		})
+	})
+	Context("when the outputs of a transfer are encrypted for the auditor", func() {
+		It("succeeds", func() {
+			_, tr, _, inputs := prepareTransferRequest(pp, auditor)
+			for i := 0; i < 2; i++ {
+				raw, err := inputs[i].Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				fakeldger.GetStateReturnsOnCall(i, raw, nil)
+				fakeldger.GetStateReturnsOnCall(i+2, raw, nil)
+			}
+			fakeldger.GetStateReturnsOnCall(4, nil, nil)
+			fakeldger.GetStateReturnsOnCall(5, nil, nil)
+
+			raw, err := asn1.Marshal(*tr)
+			Expect(err).NotTo(HaveOccurred())
+			actions, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(actions)).To(Equal(1))
+		})
+	})
+	Context("when an output is not encrypted for the auditor", func() {
+		It("fails", func() {
+			signer, err := ecdsa.NewECDSASigner()
+			Expect(err).NotTo(HaveOccurred())
+			issuer := &strippingIssuer{Issuer: &nonanonym.Issuer{}}
+			issuer.New("ABC", signer, pp)
+			ir, _ := prepareIssue(auditor, issuer)
+			raw, err := asn1.Marshal(*ir)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("is not encrypted for the auditor"))
+		})
+	})
+})
+
+var _ = Describe("spend", func() {
+	It("rejects an anonymity set with repeated tokens", func() {
+		fakeldger = &mock.Ledger{}
+		curve := math.Curves[math.BN254]
+		rand, err := curve.Rand()
+		Expect(err).NotTo(HaveOccurred())
+		pp, err := crypto.SetupGraphHiding(16, 2, nil, math.BN254, curve.GenG1.Mul(curve.NewRandomZr(rand)), 2)
+		Expect(err).NotTo(HaveOccurred())
+
+		sp := &spend.Spend{SerialNumber: curve.GenG1.Mul(curve.NewRandomZr(rand))}
+		for _, index := range []uint64{0, 1, 2, 1} {
+			key, err := keys.CreateTokenKey("tx", index)
+			Expect(err).NotTo(HaveOccurred())
+			sp.AnonymitySet = append(sp.AnonymitySet, key)
+		}
+		snKey, err := keys.CreateSNKey(sp.SerialNumberID())
+		Expect(err).NotTo(HaveOccurred())
+		action := &transfer.TransferAction{
+			Inputs:           []string{snKey},
+			InputCommitments: []*math.G1{curve.GenG1.Mul(curve.NewRandomZr(rand))},
+			Spends:           []*spend.Spend{sp},
+		}
+		err = enginedlog.TransferSpendValidate(&enginedlog.Context{PP: pp, Action: action, Ledger: fakeldger})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("appears more than once in the anonymity set"))
+		Expect(fakeldger.GetStateCallCount()).To(Equal(0))
+	})
+})
+
+var _ = Describe("upgrade", func() {
+	var (
+		engine *enginedlog.Validator
+		pp     *crypto.PublicParams
+
+		legacy []*token2.Token
+		ur     []byte // upgrade request
+	)
+	BeforeEach(func() {
+		fakeldger = &mock.Ledger{}
+		ipk, err := ioutil.ReadFile("./testdata/idemix/msp/IssuerPublicKey")
+		Expect(err).NotTo(HaveOccurred())
+		pp, err = crypto.Setup(100, 2, ipk, math.FP256BN_AMCL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pp.EnableMigration(crypto.FabTokenPredecessor, time.Time{})).To(Succeed())
+	})
+	JustBeforeEach(func() {
+		deserializer, err := zkatdlog.NewDeserializer(pp)
+		Expect(err).NotTo(HaveOccurred())
+		engine = enginedlog.New(pp, deserializer)
+
+		// the outputs of fabtoken are owned by x509 identities
+		id, signer, _, err := x509.NewSigner()
+		Expect(err).NotTo(HaveOccurred())
+		owner, err := identity.MarshallRawOwner(&identity.RawOwner{Type: identity.SerializedIdentityType, Identity: id})
+		Expect(err).NotTo(HaveOccurred())
+		legacy = []*token2.Token{
+			{Owner: &token2.Owner{Raw: owner}, Type: "ABC", Quantity: "0x46"},
+			{Owner: &token2.Owner{Raw: owner}, Type: "ABC", Quantity: "0x1e"},
+		}
+		sender, err := transfer.NewUpgradeSender([]driver.Signer{signer, signer}, legacy, []string{"0", "1"}, pp)
+		Expect(err).NotTo(HaveOccurred())
+
+		recipient, _, _ := getIdemixInfo("./testdata/idemix")
+		action, _, err := sender.GenerateZKTransfer([]*big.Int{big.NewInt(65), big.NewInt(35)}, [][]byte{recipient, recipient})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(action.Upgrade).To(BeTrue())
+		raw, err := action.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+
+		tr := &driver.TokenRequest{Transfers: [][]byte{raw}}
+		raw, err = asn1.Marshal(*tr)
+		Expect(err).NotTo(HaveOccurred())
+		signatures, err := sender.SignTokenActions(raw, "1")
+		Expect(err).NotTo(HaveOccurred())
+		tr.Signatures = append(tr.Signatures, signatures...)
+		ur, err = asn1.Marshal(*tr)
+		Expect(err).NotTo(HaveOccurred())
+
+		for i, tok := range legacy {
+			raw, err := json.Marshal(tok)
+			Expect(err).NotTo(HaveOccurred())
+			fakeldger.GetStateReturnsOnCall(i, raw, nil)
+		}
+	})
+	Context("when the migration window is open", func() {
+		It("succeeds", func() {
+			actions, err := engine.VerifyTokenRequestFromRaw(getState, "1", ur)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(actions)).To(Equal(1))
+		})
+	})
+	Context("when the migration window is closed", func() {
+		BeforeEach(func() {
+			Expect(pp.EnableMigration(crypto.FabTokenPredecessor, time.Now().Add(-time.Hour))).To(Succeed())
+		})
+		It("fails", func() {
+			_, err := engine.VerifyTokenRequestFromRaw(getState, "1", ur)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("the migration window is closed"))
+		})
+	})
+	Context("when the deadline is checked against the timestamp of the transaction", func() {
+		var deadline time.Time
+		BeforeEach(func() {
+			deadline = time.Now().Add(-time.Hour)
+			Expect(pp.EnableMigration(crypto.FabTokenPredecessor, deadline)).To(Succeed())
+		})
+		It("succeeds if the transaction precedes the deadline", func() {
+			actions, err := engine.VerifyTokenRequestFromRawAt(getState, "1", ur, deadline.Add(-time.Minute))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(actions)).To(Equal(1))
+		})
+		It("fails if the transaction follows the deadline", func() {
+			_, err := engine.VerifyTokenRequestFromRawAt(getState, "1", ur, deadline.Add(time.Minute))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("the migration window is closed"))
+		})
+	})
+	Context("when the migration is over", func() {
+		It("fails", func() {
+			pp.MigrationParams = nil
+			deserializer, err := zkatdlog.NewDeserializer(pp)
+			Expect(err).NotTo(HaveOccurred())
+			engine = enginedlog.New(pp, deserializer)
+			_, err = engine.VerifyTokenRequestFromRaw(getState, "1", ur)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("migration is not enabled"))
+		})
+	})
+	Context("when an input on the ledger does not match the transfer", func() {
+		It("fails", func() {
+			legacy[0].Quantity = "0x47"
+			raw, err := json.Marshal(legacy[0])
+			Expect(err).NotTo(HaveOccurred())
+			fakeldger.GetStateReturnsOnCall(0, raw, nil)
+			_, err = engine.VerifyTokenRequestFromRaw(getState, "1", ur)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("input commitment [0] does not match input [0]"))
+		})
+	})
+})
+
+// strippingIssuer generates issue actions whose outputs are not encrypted for the auditor
+type strippingIssuer struct {
+	*nonanonym.Issuer
+}
+
+func (i *strippingIssuer) GenerateZKIssue(values []*big.Int, owners [][]byte) (*issue2.IssueAction, []*tokn.Metadata, error) {
+	action, metadata, err := i.Issuer.GenerateZKIssue(values, owners)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, out := range action.OutputTokens {
+		Expect(out.Audit).NotTo(BeNil())
+		out.Audit = nil
+	}
+	return action, metadata, nil
+}
+
+// tamperingIssuer generates issue actions whose range proof is invalid
+type tamperingIssuer struct {
+	*nonanonym.Issuer
+}
+
+func (i *tamperingIssuer) GenerateZKIssue(values []*big.Int, owners [][]byte) (*issue2.IssueAction, []*tokn.Metadata, error) {
+	action, metadata, err := i.Issuer.GenerateZKIssue(values, owners)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof := &issue2.Proof{}
+	Expect(proof.Deserialize(action.Proof)).To(Succeed())
+	rp := &bulletproof.Proof{}
+	Expect(rp.Deserialize(proof.RangeCorrectness)).To(Succeed())
+	c := math.Curves[i.PublicParams.Curve]
+	rp.Range.T = c.ModAdd(rp.Range.T, c.NewZrFromInt(1), c.GroupOrder)
+	proof.RangeCorrectness, err = rp.Serialize()
+	Expect(err).NotTo(HaveOccurred())
+	action.Proof, err = proof.Serialize()
+	Expect(err).NotTo(HaveOccurred())
+	return action, metadata, nil
+}
+
+var _ = DescribeTable("validator on every supported curve",
+	func(curveID math.CurveID, bulletproofs bool) {
+		fakeldger = &mock.Ledger{}
+		ipk, err := ioutil.ReadFile("./testdata/idemix/msp/IssuerPublicKey")
+		Expect(err).NotTo(HaveOccurred())
+		pp, err := crypto.SetupWithCurves(100, 2, ipk, crypto.DLogPublicParameters, curveID, math.FP256BN_AMCL)
+		Expect(err).NotTo(HaveOccurred())
+		if bulletproofs {
+			Expect(pp.EnableBulletproofs(64)).To(Succeed())
+		}
+		asigner, _ := prepareECDSASigner()
+		des, err := idemix2.NewDeserializer(pp.IdemixIssuerPK)
+		Expect(err).NotTo(HaveOccurred())
+		auditor := audit.NewAuditor(&deserializer{idemix: des}, pp.PedParams, pp.IdemixIssuerPK, asigner, math.Curves[pp.Curve])
+		pp.Auditor, err = asigner.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		deserializer, err := zkatdlog.NewDeserializer(pp)
+		Expect(err).NotTo(HaveOccurred())
+		engine := enginedlog.New(pp, deserializer)
+
+		// issue
+		_, ir, _ := prepareNonAnonymousIssueRequest(pp, auditor)
+		raw, err := asn1.Marshal(*ir)
+		Expect(err).NotTo(HaveOccurred())
+		actions, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions).To(HaveLen(1))
+
+		// transfer
+		_, tr, _, inputs := prepareTransferRequest(pp, auditor)
+		for i, input := range inputs {
+			raw, err := input.Serialize()
+			Expect(err).NotTo(HaveOccurred())
+			fakeldger.GetStateReturnsOnCall(i, raw, nil)
+			fakeldger.GetStateReturnsOnCall(i+len(inputs), raw, nil)
+		}
+		fakeldger.GetStateReturnsOnCall(2*len(inputs), nil, nil)
+		fakeldger.GetStateReturnsOnCall(2*len(inputs)+1, nil, nil)
+		raw, err = asn1.Marshal(*tr)
+		Expect(err).NotTo(HaveOccurred())
+		actions, err = engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions).To(HaveLen(1))
+	},
+	curvetest.EntriesWithBulletproofs(),
+)
+
 func prepareECDSASigner() (*ecdsa.ECDSASigner, *ecdsa.ECDSAVerifier) {
 	signer, err := ecdsa.NewECDSASigner()
 	Expect(err).NotTo(HaveOccurred())
@@ -295,6 +723,20 @@ func prepareNonAnonymousIssueRequest(pp *crypto.PublicParams, auditor *audit.Aud
 	return issuer, ir, metadata
 }
 
+func prepareAnonymousIssueRequest(pp *crypto.PublicParams, auditor *audit.Auditor) (*anonym.Issuer, *driver.TokenRequest, *driver.TokenRequestMetadata) {
+	key, err := anonym.NewSecretKey(pp)
+	Expect(err).NotTo(HaveOccurred())
+	id, err := key.Identity()
+	Expect(err).NotTo(HaveOccurred())
+	pp.AddIssuer(id)
+
+	issuer := &anonym.Issuer{}
+	issuer.New("ABC", anonym.NewSigner(key, pp), pp)
+	ir, metadata := prepareIssue(auditor, issuer)
+
+	return issuer, ir, metadata
+}
+
 func prepareRedeemRequest(pp *crypto.PublicParams, auditor *audit.Auditor) (*transfer.Sender, *driver.TokenRequest, *driver.TokenRequestMetadata, []*tokn.Token) {
 	id, auditInfo, signer := getIdemixInfo("./testdata/idemix")
 	owners := make([][]byte, 2)
@@ -434,7 +876,7 @@ func prepareIssue(auditor *audit.Auditor, issuer issue2.Issuer) (*driver.TokenRe
 	ir := &driver.TokenRequest{}
 	owners := make([][]byte, 1)
 	owners[0] = id
-	values := []uint64{40}
+	values := []*big.Int{big.NewInt(40)}
 
 	issue, inf, err := issuer.GenerateZKIssue(values, owners)
 	Expect(err).NotTo(HaveOccurred())
@@ -496,9 +938,9 @@ func prepareTransfer(pp *crypto.PublicParams, signer driver.SigningIdentity, aud
 	for i := 0; i < 2; i++ {
 		inBF[i] = c.NewRandomZr(rand)
 	}
-	outvalues := make([]uint64, 2)
-	outvalues[0] = 65
-	outvalues[1] = 35
+	outvalues := make([]*big.Int, 2)
+	outvalues[0] = big.NewInt(65)
+	outvalues[1] = big.NewInt(35)
 
 	ids := make([]string, 2)
 	ids[0] = "0"
@@ -15,6 +15,7 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
 	htlc2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/htlc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/batch"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
@@ -31,6 +32,11 @@ type Context struct {
 	Action            *transfer.TransferAction
 	Ledger            driver.Ledger
 	MetadataCounter   map[string]int
+	// Batch collects the checks of the proofs that are verified at once, if batch verification is enabled
+	Batch *batch.Verifier
+	// Timestamp is the time the time constraints of the action are checked against,
+	// the timestamp of the transaction when known
+	Timestamp time.Time
 }
 
 func (c *Context) CountMetadataKey(key string) {
@@ -43,6 +49,12 @@ func TransferSignatureValidate(ctx *Context) error {
 	var tokens []*token.Token
 	var signatures [][]byte
 
+	if ctx.Action.IsGraphHiding() {
+		return errors.New("invalid transfer action: graph hiding is not enabled")
+	}
+	if ctx.Action.Upgrade {
+		return errors.New("invalid transfer action: migration is not enabled")
+	}
 	inputs, err := ctx.Action.GetInputs()
 	if err != nil {
 		return errors.Wrapf(err, "failed to retrieve inputs to spend")
@@ -90,7 +102,7 @@ func TransferZKProofValidate(ctx *Context) error {
 	if err := transfer.NewVerifier(
 		in,
 		ctx.Action.GetOutputCommitments(),
-		ctx.PP).Verify(ctx.Action.GetProof()); err != nil {
+		ctx.PP).VerifyBatch(ctx.Action.GetProof(), ctx.Batch); err != nil {
 		return err
 	}
 
@@ -0,0 +1,80 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package validator
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/x509"
+	htlc2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/htlc"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// TransferUpgradeValidate checks the inputs of upgrade transfers, the outputs of the predecessor driver spent
+// in the clear, and the signatures of their owners. The inputs are then committed to with a zero blinding factor,
+// so that the transfer proof applies to them as well.
+// The other transfers are checked by TransferSignatureValidate.
+func TransferUpgradeValidate(ctx *Context) error {
+	if !ctx.Action.Upgrade {
+		return TransferSignatureValidate(ctx)
+	}
+	if !ctx.PP.Migration(ctx.Timestamp) {
+		return errors.New("invalid upgrade transfer action: the migration window is closed")
+	}
+	inputs, err := ctx.Action.GetInputs()
+	if err != nil {
+		return errors.Wrapf(err, "failed to retrieve inputs to upgrade")
+	}
+	if len(ctx.Action.InputCommitments) != len(inputs) {
+		return errors.Errorf("invalid upgrade transfer action: number of inputs [%d] and input commitments [%d] do not match", len(inputs), len(ctx.Action.InputCommitments))
+	}
+	// the owners of the outputs of the predecessor are x509 identities
+	deserializer := htlc2.NewDeserializer(identity.NewRawOwnerIdentityDeserializer(&x509.MSPIdentityDeserializer{}))
+
+	var tokens []*token.Token
+	var signatures [][]byte
+	for i, in := range inputs {
+		raw, err := ctx.Ledger.GetState(in)
+		if err != nil {
+			return errors.Wrapf(err, "failed to retrieve input to upgrade [%s]", in)
+		}
+		if len(raw) == 0 {
+			return errors.Errorf("input to upgrade [%s] does not exists", in)
+		}
+		legacy := &token2.Token{}
+		if err := json.Unmarshal(raw, legacy); err != nil {
+			return errors.Wrapf(err, "failed to deserialize input to upgrade [%s]", in)
+		}
+		tok, _, err := token.CommitToClearToken(legacy, ctx.PP)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid input to upgrade [%s]", in)
+		}
+		if !tok.Data.Equals(ctx.Action.InputCommitments[i]) {
+			return errors.Errorf("invalid upgrade transfer action: input commitment [%d] does not match input [%s]", i, in)
+		}
+		tokens = append(tokens, tok)
+
+		verifier, err := deserializer.DeserializeVerifier(tok.Owner)
+		if err != nil {
+			return errors.Wrapf(err, "failed deserializing owner [%d][%s][%s]", i, in, view.Identity(tok.Owner).UniqueID())
+		}
+		sigma, err := ctx.SignatureProvider.HasBeenSignedBy(tok.Owner, verifier)
+		if err != nil {
+			return errors.Wrapf(err, "failed signature verification [%d][%s][%s]", i, in, view.Identity(tok.Owner).UniqueID())
+		}
+		signatures = append(signatures, sigma)
+	}
+
+	ctx.InputTokens = tokens
+	ctx.Signatures = signatures
+
+	return nil
+}
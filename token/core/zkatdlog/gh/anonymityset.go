@@ -0,0 +1,122 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	math "github.com/IBM/mathlib"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/spend"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// AnonymitySetAttribute is the transfer attribute carrying the identifiers, as []*token.ID,
+// of the ledger tokens the spent tokens are hidden among.
+const AnonymitySetAttribute = "zkatdloggh.anonymityset"
+
+// WithAnonymitySet sets the identifiers of the ledger tokens the spent tokens are hidden among.
+// It is required by graph hiding transfers. The vault knows only the tokens of this node,
+// then the application must pick the decoys among the unspent tokens of the whole ledger,
+// as large and as varied as possible, and never among the tokens of the sender's wallet, that would reveal the sender.
+// Each spent token is hidden among 2^AnonymitySetBitLength-1 distinct decoys chosen at random among the passed ones.
+func WithAnonymitySet(ids ...*token3.ID) token2.TransferOption {
+	return token2.WithTransferAttribute(AnonymitySetAttribute, ids)
+}
+
+// anonymitySetCandidates returns the identifiers of the tokens the spent tokens can be hidden among,
+// passed by the application with WithAnonymitySet
+func anonymitySetCandidates(opts *driver.TransferOptions) ([]*token3.ID, error) {
+	if opts == nil {
+		return nil, errors.New("no anonymity set passed, use gh.WithAnonymitySet")
+	}
+	boxed, ok := opts.Attributes[AnonymitySetAttribute]
+	if !ok {
+		return nil, errors.New("no anonymity set passed, use gh.WithAnonymitySet")
+	}
+	ids, ok := boxed.([]*token3.ID)
+	if !ok {
+		return nil, errors.Errorf("expected anonymity set attribute of type []*token.ID, got [%T]", boxed)
+	}
+	return ids, nil
+}
+
+// anonymitySet hides the passed token among 2^bitLength-1 distinct decoys chosen at random among the candidates.
+// It returns the elements of the set, their ledger keys, and the position of the passed token.
+func (s *Service) anonymitySet(id *token3.ID, candidates []*token3.ID, bitLength int, curveID math.CurveID) ([]*spend.Element, []string, int, error) {
+	var decoys []*token3.ID
+	seen := map[token3.ID]bool{*id: true}
+	for _, candidate := range candidates {
+		if candidate == nil || (candidate.TxId == id.TxId && candidate.Index == id.Index) {
+			continue
+		}
+		if seen[*candidate] {
+			return nil, nil, 0, errors.Errorf("token [%s] appears more than once among the anonymity set candidates", candidate)
+		}
+		seen[*candidate] = true
+		decoys = append(decoys, candidate)
+	}
+	size := 1 << bitLength
+	if len(decoys) < size-1 {
+		return nil, nil, 0, errors.Errorf("not enough decoys to hide token [%s], expected [%d], got [%d]", id, size-1, len(decoys))
+	}
+
+	index, err := randomIndex(size)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	ids := make([]*token3.ID, size)
+	for i := range ids {
+		if i == index {
+			ids[i] = id
+			continue
+		}
+		// pick a decoy among those not picked yet
+		j, err := randomIndex(len(decoys))
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		ids[i] = decoys[j]
+		decoys[j] = decoys[len(decoys)-1]
+		decoys = decoys[:len(decoys)-1]
+	}
+
+	tokens, err := s.TokenCommitmentLoader.GetTokenOutputs(ids)
+	if err != nil {
+		return nil, nil, 0, errors.WithMessagef(err, "failed loading anonymity set")
+	}
+	set := make([]*spend.Element, size)
+	keySet := make([]string, size)
+	for i, tok := range tokens {
+		if tok.IsRedeem() || tok.Serial == nil {
+			return nil, nil, 0, errors.Errorf("token [%s] cannot be part of an anonymity set", ids[i])
+		}
+		nym, err := ownerNym(tok.Owner, curveID)
+		if err != nil {
+			return nil, nil, 0, errors.WithMessagef(err, "invalid owner of token [%s]", ids[i])
+		}
+		set[i] = &spend.Element{Commitment: tok.Data, Serial: tok.Serial, Nym: nym}
+		keySet[i], err = keys.CreateTokenKey(ids[i].TxId, ids[i].Index)
+		if err != nil {
+			return nil, nil, 0, errors.Wrapf(err, "failed creating key for token [%s]", ids[i])
+		}
+	}
+	return set, keySet, index, nil
+}
+
+// randomIndex returns a random integer in [0, n)
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed generating random index")
+	}
+	return int(i.Int64()), nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package driver
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/idemix"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/ppm"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/gh"
+	zkatdlog "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh"
+	nogh "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
+)
+
+type Driver struct {
+}
+
+func (d *Driver) PublicParametersFromBytes(params []byte) (driver.PublicParameters, error) {
+	pp, err := crypto.NewPublicParamsFromBytes(params, crypto.DLogGraphHidingPublicParameters)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal public parameters")
+	}
+	if err := checkPublicParams(pp); err != nil {
+		return nil, err
+	}
+	return pp, nil
+}
+
+func (d *Driver) NewTokenService(sp view.ServiceProvider, publicParamsFetcher driver.PublicParamsFetcher, networkID string, channel string, namespace string) (driver.TokenManagerService, error) {
+	service, err := nogh.NewTokenService(sp, publicParamsFetcher, networkID, channel, namespace, crypto.DLogGraphHidingPublicParameters)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.LoadPublicParams(); err != nil {
+		return nil, errors.WithMessage(err, "failed to fetch public parameters")
+	}
+	if err := checkPublicParams(service.PublicParams()); err != nil {
+		return nil, err
+	}
+	if err := service.LoadAnonymousIssuerWallets(); err != nil {
+		return nil, errors.WithMessage(err, "failed to load anonymous issuer wallets")
+	}
+	return gh.NewTokenService(service), nil
+}
+
+func (d *Driver) NewValidator(params driver.PublicParameters) (driver.Validator, error) {
+	pp, ok := params.(*crypto.PublicParams)
+	if !ok {
+		return nil, errors.Errorf("invalid public parameters type [%T]", params)
+	}
+	if err := checkPublicParams(pp); err != nil {
+		return nil, err
+	}
+	deserializer, err := zkatdlog.NewDeserializer(pp)
+	if err != nil {
+		return nil, err
+	}
+	return zkatdlog.NewValidator(pp, deserializer), nil
+}
+
+func (d *Driver) NewPublicParametersManager(params driver.PublicParameters) (driver.PublicParamsManager, error) {
+	pp, ok := params.(*crypto.PublicParams)
+	if !ok {
+		return nil, errors.Errorf("invalid public parameters type [%T]", params)
+	}
+	return ppm.NewFromParams(pp)
+}
+
+// checkPublicParams checks that graph hiding is enabled and that the blinding factors of the tokens
+// share the base of the randomness of the owner pseudonyms
+func checkPublicParams(pp *crypto.PublicParams) error {
+	if pp == nil || !pp.GraphHiding() {
+		return errors.New("invalid public parameters: graph hiding is not enabled")
+	}
+	hRand, err := idemix.HRand(pp.IdemixIssuerPK, pp.IdemixCurveID)
+	if err != nil {
+		return errors.WithMessage(err, "invalid public parameters")
+	}
+	if len(pp.PedParams) != 3 || !hRand.Equals(pp.PedParams[2]) {
+		return errors.New("invalid public parameters: pedersen parameters do not match the idemix issuer public key")
+	}
+	return nil
+}
+
+func init() {
+	core.Register(crypto.DLogGraphHidingPublicParameters, &Driver{})
+}
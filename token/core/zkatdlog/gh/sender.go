@@ -0,0 +1,283 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	"math/big"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/idemix"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/htlc"
+	common2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/spend"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// nymOpener is implemented by the signers that can open the pseudonym of their identity
+type nymOpener interface {
+	UserSecret() (*math.Zr, error)
+	NymRandomness() (*math.Zr, error)
+}
+
+// Transfer returns a TransferAction as a function of the passed arguments.
+// Each spent token is hidden in an anonymity set and replaced by a fresh commitment to its type and value,
+// owned by a fresh pseudonym of the wallet. The inputs of the action are the serial numbers of the spent tokens.
+// It also returns the corresponding TransferMetadata
+func (s *Service) Transfer(txID string, wallet driver.OwnerWallet, ids []*token3.ID, outputTokens []*token3.Token, opts *driver.TransferOptions) (driver.TransferAction, *driver.TransferMetadata, error) {
+	logger.Debugf("Prepare Transfer Action [%s,%v]", txID, ids)
+	pp := s.PublicParams()
+	if pp == nil {
+		return nil, nil, errors.Errorf("public parameters not inizialized")
+	}
+	if !pp.GraphHiding() {
+		return nil, nil, errors.Errorf("graph hiding is not enabled")
+	}
+	// load tokens with the passed token identifiers
+	_, tokens, inputInf, _, err := s.TokenLoader.LoadTokens(ids)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to load tokens")
+	}
+	candidates, err := anonymitySetCandidates(opts)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "failed getting anonymity set candidates")
+	}
+
+	curve := math.Curves[pp.Curve]
+	rand, err := curve.Rand()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed getting random generator")
+	}
+	var signers []driver.Signer
+	var senders []view.Identity
+	var inputs []*token.Token
+	var inputIDs []string
+	var inputMetadata []*token.Metadata
+	var spends []*spend.Spend
+	for i, t := range tokens {
+		if inputInf[i].SerialNonce == nil || inputInf[i].SerialBlindingFactor == nil {
+			return nil, nil, errors.Errorf("token [%s] has no serial nonce", ids[i])
+		}
+		set, keySet, index, err := s.anonymitySet(ids[i], candidates, int(pp.GraphHidingParams.AnonymitySetBitLength), pp.IdemixCurveID)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed building anonymity set for token [%s]", ids[i])
+		}
+		userSecret, nymRandomness, err := s.openNym(t.Owner)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed getting pseudonym of the owner of token [%s]", ids[i])
+		}
+
+		// the spent token is replaced by a fresh commitment owned by a fresh pseudonym
+		owner, err := wallet.GetRecipientIdentity()
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed getting fresh pseudonym for token [%s]", ids[i])
+		}
+		si, err := s.IdentityProvider().GetSigner(owner)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed getting signing identity for id [%v]", owner)
+		}
+		_, ownerNymRandomness, err := s.openNym(owner)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed getting fresh pseudonym for token [%s]", ids[i])
+		}
+		nym, err := ownerNym(owner, pp.IdemixCurveID)
+		if err != nil {
+			return nil, nil, err
+		}
+		bf := curve.NewRandomZr(rand)
+		commitment, err := common2.ComputePedersenCommitment([]*math.Zr{curve.HashToZr([]byte(inputInf[i].Type)), inputInf[i].Value, bf}, pp.PedParams, curve)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed computing commitment for token [%s]", ids[i])
+		}
+
+		// only the owner can compute the serial number, the sender of the token knows the nonce but not the user secret key
+		sn, err := spend.SerialNumber(userSecret, inputInf[i].SerialNonce, pp)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed computing serial number of token [%s]", ids[i])
+		}
+		serial, sbf, err := spend.CommitSerialNonce(inputInf[i].SerialNonce, pp)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed committing to serial nonce of token [%s]", ids[i])
+		}
+
+		sp := &spend.Spend{
+			AnonymitySet: keySet,
+			SerialNumber: sn,
+			Serial:       serial,
+			Owner:        owner,
+		}
+		sp.Proof, err = spend.NewProver(&spend.Witness{
+			Index:                     index,
+			BlindingFactor:            inputInf[i].BlindingFactor,
+			SerialBlindingFactor:      inputInf[i].SerialBlindingFactor,
+			NymRandomness:             nymRandomness,
+			CommitmentBlindingFactor:  bf,
+			OwnerNymRandomness:        ownerNymRandomness,
+			UserSecret:                userSecret,
+			SerialNonce:               inputInf[i].SerialNonce,
+			OwnerSerialBlindingFactor: sbf,
+		}, set, commitment, nym, sp, pp).Prove()
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed generating spend proof for token [%s]", ids[i])
+		}
+		snKey, err := keys.CreateSNKey(sp.SerialNumberID())
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed creating serial number key for token [%s]", ids[i])
+		}
+
+		signers = append(signers, si)
+		senders = append(senders, owner)
+		inputs = append(inputs, &token.Token{Owner: owner, Data: commitment})
+		inputIDs = append(inputIDs, snKey)
+		inputMetadata = append(inputMetadata, &token.Metadata{Type: inputInf[i].Type, Value: inputInf[i].Value, BlindingFactor: bf, Owner: owner})
+		spends = append(spends, sp)
+	}
+
+	// get sender
+	sender, err := transfer.NewSender(signers, inputs, inputIDs, inputMetadata, pp)
+	if err != nil {
+		return nil, nil, err
+	}
+	sender.WorkerPool = s.WorkerPool
+	var values []*big.Int
+	var owners [][]byte
+	var ownerIdentities []view.Identity
+	// get values and owners of outputs
+	for i, output := range outputTokens {
+		q, err := token3.ToQuantity(output.Quantity, pp.Precision())
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to get value for %dth output", i)
+		}
+		values = append(values, q.ToBigInt())
+		if output.Owner == nil {
+			return nil, nil, errors.Errorf("failed to get owner for %dth output: nil owner", i)
+		}
+		owners = append(owners, output.Owner.Raw)
+		if len(output.Owner.Raw) == 0 { // redeem
+			ownerIdentities = append(ownerIdentities, output.Owner.Raw)
+			continue
+		}
+		if _, err := ownerNym(output.Owner.Raw, pp.IdemixCurveID); err != nil {
+			return nil, nil, errors.WithMessagef(err, "invalid owner for %dth output", i)
+		}
+		ownerIdentities = append(ownerIdentities, output.Owner.Raw)
+	}
+	// produce zkatdlog transfer action
+	// return for each output its information in the clear
+	transfer, outputMetadata, err := sender.GenerateZKTransfer(values, owners)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to generate zkatdlog transfer action for txid [%s]", txID)
+	}
+	transfer.Spends = spends
+	for i, output := range transfer.OutputTokens {
+		if output.IsRedeem() {
+			continue
+		}
+		if err := addSerialNonce(output, outputMetadata[i], pp); err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed adding serial nonce to output [%d]", i)
+		}
+	}
+	if err := s.EncryptMetadata(transfer.OutputTokens, outputMetadata); err != nil {
+		return nil, nil, err
+	}
+
+	// add transfer action's metadata
+	common.SetTransferActionMetadata(opts.Attributes, transfer.Metadata)
+
+	// prepare metadata
+	var outputMetadataRaw [][]byte
+	for _, information := range outputMetadata {
+		raw, err := information.Serialize()
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "failed serializing token info for zkatdlog transfer action")
+		}
+		outputMetadataRaw = append(outputMetadataRaw, raw)
+	}
+	// audit info for receivers
+	var receiverAuditInfos [][]byte
+	for _, output := range outputTokens {
+		auditInfo, err := htlc.GetOwnerAuditInfo(output.Owner.Raw, s)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed getting audit info for recipient identity [%s]", view.Identity(output.Owner.Raw).String())
+		}
+		receiverAuditInfos = append(receiverAuditInfos, auditInfo)
+	}
+
+	// audit info for senders, the auditor sees the owners of the spent tokens
+	var senderAuditInfos [][]byte
+	for _, t := range tokens {
+		auditInfo, err := htlc.GetOwnerAuditInfo(t.Owner, s)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed getting audit info for sender identity [%s]", view.Identity(t.Owner).String())
+		}
+		senderAuditInfos = append(senderAuditInfos, auditInfo)
+	}
+
+	outputs, err := transfer.GetSerializedOutputs()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed getting serialized outputs")
+	}
+
+	receiverIsSender := make([]bool, len(ownerIdentities))
+	for i, receiver := range ownerIdentities {
+		_, err := s.OwnerWalletByID(receiver)
+		receiverIsSender[i] = err == nil
+	}
+
+	metadata := &driver.TransferMetadata{
+		Outputs:            outputs,
+		Senders:            senders,
+		SenderAuditInfos:   senderAuditInfos,
+		TokenIDs:           ids,
+		OutputsMetadata:    outputMetadataRaw,
+		Receivers:          ownerIdentities,
+		ReceiverAuditInfos: receiverAuditInfos,
+		ReceiverIsSender:   receiverIsSender,
+	}
+
+	return transfer, metadata, nil
+}
+
+// openNym returns the user secret key and the randomness of the pseudonym of the passed owner, that must be one of ours
+func (s *Service) openNym(owner view.Identity) (*math.Zr, *math.Zr, error) {
+	signer, err := s.IdentityProvider().GetSigner(owner)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed getting signing identity for id [%v]", owner)
+	}
+	opener, ok := signer.(nymOpener)
+	if !ok {
+		return nil, nil, errors.Errorf("signer of type [%T] cannot spend hidden tokens, a deterministic idemix wallet is required", signer)
+	}
+	sk, err := opener.UserSecret()
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := opener.NymRandomness()
+	if err != nil {
+		return nil, nil, err
+	}
+	return sk, r, nil
+}
+
+// ownerNym returns the pseudonym of the passed owner, that must be an idemix identity
+func ownerNym(owner []byte, curveID math.CurveID) (*math.G1, error) {
+	ro, err := identity.UnmarshallRawOwner(owner)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal owner")
+	}
+	if ro.Type != identity.SerializedIdentityType {
+		return nil, errors.Errorf("owner of type [%s] is not supported with graph hiding", ro.Type)
+	}
+	return idemix.NymFromIdentity(ro.Identity, curveID)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gh
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/spend"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.driver.zkatdloggh")
+
+// Service is the graph hiding zkatdlog token service.
+// It extends the zkatdlog service by attaching a serial nonce to each new token,
+// and by spending tokens without revealing which ones.
+type Service struct {
+	*nogh.Service
+}
+
+// NewTokenService returns a graph hiding token service on top of the passed zkatdlog service
+func NewTokenService(service *nogh.Service) *Service {
+	return &Service{Service: service}
+}
+
+// DeserializeToken un-marshals a token and token info from raw bytes, like the zkatdlog service.
+// In addition, if the token is owned by this node, it checks that its serial nonce has been derived
+// from its owner and commitment, otherwise the sender could have chosen it.
+func (s *Service) DeserializeToken(tok []byte, infoRaw []byte) (*token3.Token, view.Identity, error) {
+	to, issuer, err := s.Service.DeserializeToken(tok, infoRaw)
+	if err != nil {
+		return nil, nil, err
+	}
+	output := &token.Token{}
+	if err := output.Deserialize(tok); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to deserialize zkatdlog token")
+	}
+	if output.IsRedeem() {
+		return to, issuer, nil
+	}
+	if _, err := s.OwnerWalletByIdentity(output.Owner); err != nil {
+		// not ours, the serial nonce can be checked only by the owner
+		return to, issuer, nil
+	}
+	meta := &token.Metadata{}
+	if err := meta.Deserialize(infoRaw); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to deserialize token information")
+	}
+	pp := s.PublicParams()
+	nym, err := ownerNym(output.Owner, pp.IdemixCurveID)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce, err := spend.DeriveSerialNonce(nym, output.Data, pp)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !nonce.Equals(meta.SerialNonce) {
+		return nil, nil, errors.New("failed to deserialize token: serial nonce has not been derived from the owner and the commitment")
+	}
+	return to, issuer, nil
+}
+
+// Issue returns an IssueAction as a function of the passed arguments.
+// Each issued token carries a commitment to its serial nonce whose opening is added to the token metadata.
+func (s *Service) Issue(issuerIdentity view.Identity, typ string, values []token3.Quantity, owners [][]byte, opts *driver.IssueOptions) (driver.IssueAction, [][]byte, view.Identity, error) {
+	action, outputMetadataRaw, issuer, err := s.Service.Issue(issuerIdentity, typ, values, owners, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ia, ok := action.(*issue.IssueAction)
+	if !ok {
+		return nil, nil, nil, errors.Errorf("expected *issue.IssueAction, got [%T]", action)
+	}
+	pp := s.PublicParams()
+	outputMetadata := make([]*token.Metadata, len(ia.OutputTokens))
+	for i, output := range ia.OutputTokens {
+		meta := &token.Metadata{}
+		if err := meta.Deserialize(outputMetadataRaw[i]); err != nil {
+			return nil, nil, nil, errors.Wrapf(err, "failed deserializing metadata of output [%d]", i)
+		}
+		if err := addSerialNonce(output, meta, pp); err != nil {
+			return nil, nil, nil, errors.WithMessagef(err, "failed adding serial nonce to output [%d]", i)
+		}
+		outputMetadataRaw[i], err = meta.Serialize()
+		if err != nil {
+			return nil, nil, nil, errors.WithMessage(err, "failed serializing token info")
+		}
+		outputMetadata[i] = meta
+	}
+	// encrypt again, the metadata now carries the serial nonces
+	if err := s.EncryptMetadata(ia.OutputTokens, outputMetadata); err != nil {
+		return nil, nil, nil, err
+	}
+	return ia, outputMetadataRaw, issuer, nil
+}
+
+// addSerialNonce attaches to the passed token a commitment to the serial nonce derived from
+// its owner and commitment, and adds the opening to the passed metadata.
+// The serial number of the token depends also on the user secret key of the owner, then the sender cannot compute it.
+func addSerialNonce(output *token.Token, meta *token.Metadata, pp *crypto.PublicParams) error {
+	nym, err := ownerNym(output.Owner, pp.IdemixCurveID)
+	if err != nil {
+		return err
+	}
+	nonce, err := spend.DeriveSerialNonce(nym, output.Data, pp)
+	if err != nil {
+		return err
+	}
+	serial, bf, err := spend.CommitSerialNonce(nonce, pp)
+	if err != nil {
+		return err
+	}
+	output.Serial = serial
+	meta.SerialNonce = nonce
+	meta.SerialBlindingFactor = bf
+	return nil
+}
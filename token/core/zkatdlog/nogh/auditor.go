@@ -10,6 +10,7 @@ import (
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/audit"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/pkg/errors"
 )
@@ -17,22 +18,35 @@ import (
 // AuditorCheck verifies if the passed tokenRequest matches the tokenRequestMetadata
 func (s *Service) AuditorCheck(tokenRequest *driver.TokenRequest, tokenRequestMetadata *driver.TokenRequestMetadata, txID string) error {
 	logger.Debugf("check token request validity...")
+	pp := s.PublicParams()
+	if pp == nil {
+		return errors.Errorf("public parameters not inizialized")
+	}
 	var inputTokens [][]*token.Token
-	for _, transfer := range tokenRequestMetadata.Transfers {
-		inputs, err := s.TokenCommitmentLoader.GetTokenOutputs(transfer.TokenIDs)
+	upgrade := false
+	for i, t := range tokenRequestMetadata.Transfers {
+		var inputs []*token.Token
+		var err error
+		if i < len(tokenRequest.Transfers) && isUpgrade(tokenRequest.Transfers[i]) {
+			upgrade = true
+			inputs, err = s.loadUpgradedTokens(t.TokenIDs, pp)
+		} else {
+			inputs, err = s.TokenCommitmentLoader.GetTokenOutputs(t.TokenIDs)
+		}
 		if err != nil {
 			return errors.Wrapf(err, "failed getting token outputs to perform auditor check")
 		}
 		inputTokens = append(inputTokens, inputs)
 	}
 
-	des, err := s.Deserializer()
+	d, err := s.Deserializer()
 	if err != nil {
 		return errors.WithMessagef(err, "failed getting deserializer for auditor check")
 	}
-	pp := s.PublicParams()
-	if pp == nil {
-		return errors.Errorf("public parameters not inizialized")
+	var des audit.Deserializer = d
+	if upgrade && pp.MigrationParams != nil {
+		// the owners of the upgraded tokens are identified by their enrollment IDs
+		des = &legacyAuditDES{Deserializer: d}
 	}
 	if err := audit.NewAuditor(des, pp.PedParams, pp.IdemixIssuerPK, nil, math.Curves[pp.Curve]).Check(
 		tokenRequest,
@@ -44,3 +58,12 @@ func (s *Service) AuditorCheck(tokenRequest *driver.TokenRequest, tokenRequestMe
 	}
 	return nil
 }
+
+// isUpgrade returns true if the passed serialized transfer action is an upgrade transfer
+func isUpgrade(raw []byte) bool {
+	action := &transfer.TransferAction{}
+	if err := action.Deserialize(raw); err != nil {
+		return false
+	}
+	return action.Upgrade
+}
@@ -9,6 +9,7 @@ package driver
 import (
 	"time"
 
+	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
@@ -18,7 +19,6 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/ppm"
-	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/validator"
 	zkatdlog "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
@@ -37,6 +37,22 @@ func (d *Driver) PublicParametersFromBytes(params []byte) (driver.PublicParamete
 }
 
 func (d *Driver) NewTokenService(sp view.ServiceProvider, publicParamsFetcher driver.PublicParamsFetcher, networkID string, channel string, namespace string) (driver.TokenManagerService, error) {
+	service, err := NewTokenService(sp, publicParamsFetcher, networkID, channel, namespace, crypto.DLogPublicParameters)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.LoadPublicParams(); err != nil {
+		return nil, errors.WithMessage(err, "failed to fetch public parameters")
+	}
+	if err := service.LoadAnonymousIssuerWallets(); err != nil {
+		return nil, errors.WithMessage(err, "failed to load anonymous issuer wallets")
+	}
+	return service, nil
+}
+
+// NewTokenService returns a zkatdlog token service whose public parameters carry the passed label.
+// The public parameters are not loaded yet.
+func NewTokenService(sp view.ServiceProvider, publicParamsFetcher driver.PublicParamsFetcher, networkID string, channel string, namespace string, ppLabel string) (*zkatdlog.Service, error) {
 	n := network.GetInstance(sp, networkID, channel)
 	if n == nil {
 		return nil, errors.Errorf("network [%s] does not exists", networkID)
@@ -52,6 +68,15 @@ func (d *Driver) NewTokenService(sp view.ServiceProvider, publicParamsFetcher dr
 		return nil, errors.WithMessage(err, "failed to create config manager")
 	}
 
+	// The owner wallet needs the idemix curve of the public parameters
+	qe := v.TokenVault().QueryEngine()
+	ppLoader := zkatdlog.NewPublicParamsLoader(publicParamsFetcher, ppLabel)
+	publicParamsManager := ppm.NewPublicParamsManager(ppLabel, qe, ppLoader)
+	idemixCurveID, err := idemixCurve(publicParamsManager, ppLoader)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get the idemix curve of the public parameters")
+	}
+
 	// Prepare wallets
 	wallets := identity.NewWallets()
 	mspWalletFactory := msp.NewWalletFactory(
@@ -63,7 +88,7 @@ func (d *Driver) NewTokenService(sp view.ServiceProvider, publicParamsFetcher dr
 		msp.NewSigService(view.GetSigService(sp)),      // signer service
 		view.GetEndpointService(sp),                    // endpoint service
 	)
-	wallet, err := mspWalletFactory.NewIdemixWallet(driver.OwnerRole)
+	wallet, err := mspWalletFactory.NewIdemixWallet(driver.OwnerRole, idemixCurveID)
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed to create owner wallet")
 	}
@@ -90,31 +115,39 @@ func (d *Driver) NewTokenService(sp view.ServiceProvider, publicParamsFetcher dr
 		Channel:   channel,
 		Namespace: namespace,
 	}
-	qe := v.TokenVault().QueryEngine()
 	service, err := zkatdlog.NewTokenService(
 		sp,
 		tmsID,
-		ppm.NewPublicParamsManager(
-			crypto.DLogPublicParameters,
-			v.TokenVault().QueryEngine(),
-			zkatdlog.NewPublicParamsLoader(publicParamsFetcher, crypto.DLogPublicParameters)),
+		publicParamsManager,
 		&zkatdlog.VaultTokenLoader{TokenVault: qe},
 		zkatdlog.NewVaultTokenCommitmentLoader(qe, 3, 3*time.Second),
 		qe,
 		identity.NewProvider(sp, zkatdlog.NewEnrollmentIDDeserializer(), wallets),
 		zkatdlog.NewDeserializerProvider().Deserialize,
-		crypto.DLogPublicParameters,
+		ppLabel,
 		tmsConfig,
 		kvs.GetService(sp),
 	)
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed to create token service")
 	}
-	if err := service.LoadPublicParams(); err != nil {
-		return nil, errors.WithMessage(err, "failed to fetch public parameters")
-	}
+	return service, nil
+}
 
-	return service, err
+// idemixCurve returns the idemix curve of the public parameters stored in the vault, if any.
+// Otherwise, it returns the idemix curve of the public parameters fetched from the backend.
+func idemixCurve(publicParamsManager *ppm.PublicParamsManager, ppLoader *zkatdlog.PublicParamsLoader) (math.CurveID, error) {
+	if err := publicParamsManager.Load(); err != nil {
+		return 0, errors.WithMessage(err, "failed to load public parameters")
+	}
+	if pp := publicParamsManager.PublicParams(); pp != nil {
+		return pp.IdemixCurveID, nil
+	}
+	pp, err := ppLoader.FetchParams()
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to fetch public parameters")
+	}
+	return pp.IdemixCurveID, nil
 }
 
 func (d *Driver) NewValidator(params driver.PublicParameters) (driver.Validator, error) {
@@ -126,7 +159,7 @@ func (d *Driver) NewValidator(params driver.PublicParameters) (driver.Validator,
 	if err != nil {
 		return nil, err
 	}
-	return validator.New(pp, deserializer), nil
+	return zkatdlog.NewValidator(pp, deserializer), nil
 }
 
 func (d *Driver) NewPublicParametersManager(params driver.PublicParameters) (driver.PublicParamsManager, error) {
@@ -7,18 +7,25 @@ SPDX-License-Identifier: Apache-2.0
 package nogh
 
 import (
+	"math/big"
+	"time"
+
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/metrics"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/anonym"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/nonanonym"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
 )
 
 // Issue returns an IssueAction as a function of the passed arguments
 // Issue also returns a serialization TokenInformation associated with issued tokens
 // and the identity of the issuer
-func (s *Service) Issue(issuerIdentity view.Identity, typ string, values []uint64, owners [][]byte, opts *driver.IssueOptions) (driver.IssueAction, [][]byte, view.Identity, error) {
+func (s *Service) Issue(issuerIdentity view.Identity, typ string, values []token2.Quantity, owners [][]byte, opts *driver.IssueOptions) (driver.IssueAction, [][]byte, view.Identity, error) {
 	for _, owner := range owners {
 		// a recipient cannot be empty
 		if len(owner) == 0 {
@@ -39,16 +46,30 @@ func (s *Service) Issue(issuerIdentity view.Identity, typ string, values []uint6
 	if pp == nil {
 		return nil, nil, nil, errors.Errorf("public parameters not inizialized")
 	}
-	issuer := &nonanonym.Issuer{}
+	var issuer issue.Issuer
+	if anonym.IsIdentity(issuerIdentity) {
+		issuer = &anonym.Issuer{WorkerPool: s.WorkerPool}
+	} else {
+		issuer = &nonanonym.Issuer{WorkerPool: s.WorkerPool}
+	}
 	issuer.New(typ, &common.WrappedSigningIdentity{
 		Identity: issuerIdentity,
 		Signer:   signer,
 	}, pp)
 
-	issue, outputMetadata, err := issuer.GenerateZKIssue(values, owners)
+	bigValues := make([]*big.Int, len(values))
+	for i, v := range values {
+		bigValues[i] = v.ToBigInt()
+	}
+	start := time.Now()
+	issue, outputMetadata, err := issuer.GenerateZKIssue(bigValues, owners)
+	metrics.Get().ProofGenerationDuration.With("driver", crypto.DLogPublicParameters, "action", "issue").Observe(metrics.Since(start))
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	if err := s.EncryptMetadata(issue.OutputTokens, outputMetadata); err != nil {
+		return nil, nil, nil, err
+	}
 
 	var outputMetadataRaw [][]byte
 	for _, meta := range outputMetadata {
@@ -59,12 +80,7 @@ func (s *Service) Issue(issuerIdentity view.Identity, typ string, values []uint6
 		outputMetadataRaw = append(outputMetadataRaw, raw)
 	}
 
-	fid, err := issuer.Signer.Serialize()
-	if err != nil {
-		return nil, nil, nil, err
-	}
-
-	return issue, outputMetadataRaw, fid, err
+	return issue, outputMetadataRaw, issuerIdentity, err
 }
 
 // VerifyIssue checks if the outputs of an IssueAction match the passed metadata
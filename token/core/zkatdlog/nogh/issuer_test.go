@@ -0,0 +1,141 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nogh
+
+import (
+	"encoding/asn1"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	math "github.com/IBM/mathlib"
+	_ "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/memory"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs/mock"
+	registry2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/anonym"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/ppm"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/stretchr/testify/assert"
+)
+
+type signersProvider struct {
+	driver.IdentityProvider
+	signers map[string]driver.Signer
+}
+
+func (p *signersProvider) LookupIdentifier(role driver.IdentityRole, v interface{}) (view.Identity, string, error) {
+	switch vv := v.(type) {
+	case string:
+		return nil, vv, nil
+	case view.Identity:
+		return vv, "", nil
+	}
+	return nil, "", nil
+}
+
+func (p *signersProvider) RegisterSigner(identity view.Identity, signer driver.Signer, verifier driver.Verifier) error {
+	p.signers[identity.UniqueID()] = signer
+	return nil
+}
+
+func (p *signersProvider) GetSigner(identity view.Identity) (driver.Signer, error) {
+	return p.signers[identity.UniqueID()], nil
+}
+
+type configManager struct {
+	tms *config.TMS
+}
+
+func (c *configManager) TMS() *config.TMS                                  { return c.tms }
+func (c *configManager) TranslatePath(path string) string                  { return path }
+func (c *configManager) IsSet(key string) bool                             { return false }
+func (c *configManager) UnmarshalKey(key string, rawVal interface{}) error { return nil }
+
+func TestAnonymousIssue(t *testing.T) {
+	ipk, err := ioutil.ReadFile("../crypto/validator/testdata/idemix/msp/IssuerPublicKey")
+	assert.NoError(t, err)
+	pp, err := crypto.Setup(100, 2, ipk, math.FP256BN_AMCL)
+	assert.NoError(t, err)
+
+	// the key of the anonymous issuer, as generated by tokengen
+	key, err := anonym.NewSecretKey(pp)
+	assert.NoError(t, err)
+	issuerIdentity, err := key.Identity()
+	assert.NoError(t, err)
+	pp.AddIssuer(issuerIdentity)
+	raw, err := key.Serialize()
+	assert.NoError(t, err)
+	keyPath := filepath.Join(t.TempDir(), "anonymous_issuer_0.json")
+	assert.NoError(t, ioutil.WriteFile(keyPath, raw, 0600))
+
+	ppManager, err := ppm.NewFromParams(pp)
+	assert.NoError(t, err)
+	cp := &mock.ConfigProvider{}
+	cp.IsSetReturns(false)
+	kvstore, err := kvs.NewWithConfig(registry2.New(), "memory", "_default", cp)
+	assert.NoError(t, err)
+	cm := &configManager{tms: &config.TMS{
+		Wallets: &config.Wallets{
+			Issuers: []*config.Identity{{ID: "anon", Path: keyPath, Anonymous: true}},
+		},
+	}}
+	service, err := NewTokenService(
+		nil,
+		token2.TMSID{Network: "testnetwork", Channel: "testchannel", Namespace: "tns"},
+		ppManager,
+		nil,
+		nil,
+		nil,
+		&signersProvider{signers: map[string]driver.Signer{}},
+		NewDeserializerProvider().Deserialize,
+		crypto.DLogPublicParameters,
+		cm,
+		kvstore,
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, service.LoadAnonymousIssuerWallets())
+
+	wallet, err := service.IssuerWallet("anon")
+	assert.NoError(t, err)
+	id, err := wallet.GetIssuerIdentity("ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, view.Identity(issuerIdentity), id)
+
+	q, err := token3.ToQuantity("10", 64)
+	assert.NoError(t, err)
+	action, _, issuer, err := service.Issue(id, "ABC", []token3.Quantity{q}, [][]byte{[]byte("alice")}, nil)
+	assert.NoError(t, err)
+	assert.True(t, action.IsAnonymous())
+	assert.Empty(t, action.GetIssuer())
+	assert.Equal(t, id, issuer)
+
+	// sign the request as the token request service does, and validate it
+	actionRaw, err := action.Serialize()
+	assert.NoError(t, err)
+	req := &driver.TokenRequest{Issues: [][]byte{actionRaw}}
+	reqRaw, err := req.Bytes()
+	assert.NoError(t, err)
+	signer, err := wallet.GetSigner(id)
+	assert.NoError(t, err)
+	sigma, err := signer.Sign(append(reqRaw, []byte("1")...))
+	assert.NoError(t, err)
+	req.Signatures = [][]byte{sigma}
+	raw, err = asn1.Marshal(*req)
+	assert.NoError(t, err)
+
+	validator, err := service.Validator()
+	assert.NoError(t, err)
+	actions, err := validator.VerifyTokenRequestFromRaw(func(id string) ([]byte, error) { return nil, nil }, "1", raw)
+	assert.NoError(t, err)
+	assert.Len(t, actions, 1)
+}
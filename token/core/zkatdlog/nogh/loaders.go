@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package nogh
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
@@ -133,6 +134,38 @@ func (s *VaultTokenLoader) LoadTokens(ids []*token3.ID) ([]string, []*token.Toke
 	return inputIDs, tokens, inputInf, signerIds, nil
 }
 
+// LoadLegacyTokens takes an array of token identifiers (txID, index) of outputs of the predecessor driver
+// and returns the keys in the vault matching the token identifiers, the corresponding tokens in the clear
+// and the identities of their owners
+func (s *VaultTokenLoader) LoadLegacyTokens(ids []*token3.ID) ([]string, []*token3.Token, []view.Identity, error) {
+	var tokens []*token3.Token
+	var inputIDs []string
+	var signerIds []view.Identity
+
+	if err := s.TokenVault.GetTokenInfoAndOutputs(ids, func(id *token3.ID, key string, raw, info []byte) error {
+		if len(raw) == 0 {
+			return errors.Errorf("failed getting state for id [%v], nil value", id)
+		}
+		tok := &token3.Token{}
+		if err := json.Unmarshal(raw, tok); err != nil {
+			return errors.Wrapf(err, "failed unmarshalling token for id [%v]", id)
+		}
+		if tok.Owner == nil {
+			return errors.Errorf("token for id [%v] has no owner", id)
+		}
+
+		inputIDs = append(inputIDs, key)
+		tokens = append(tokens, tok)
+		signerIds = append(signerIds, tok.Owner.Raw)
+
+		return nil
+	}); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return inputIDs, tokens, signerIds, nil
+}
+
 type PublicParamsLoader struct {
 	PublicParamsFetcher driver.PublicParamsFetcher
 	PPLabel             string
@@ -0,0 +1,116 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nogh
+
+import (
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/idemix"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/pkg/errors"
+)
+
+// nymOpener is implemented by the idemix signers that can open the pseudonym of their identity
+type nymOpener interface {
+	UserSecret() (*math.Zr, error)
+	NymRandomness() (*math.Zr, error)
+}
+
+// EncryptMetadata puts on each of the passed outputs its metadata, encrypted for its owner,
+// if the TMS is configured to do so.
+// The metadata is encrypted for the pseudonym of the owner, then only the outputs owned by idemix identities
+// carry it. The others are left untouched.
+func (s *Service) EncryptMetadata(outputs []*token.Token, metadata []*token.Metadata) error {
+	if s.configManager == nil || s.configManager.TMS() == nil || s.configManager.TMS().Outputs == nil || !s.configManager.TMS().Outputs.EncryptMetadata {
+		return nil
+	}
+	if len(outputs) != len(metadata) {
+		return errors.Errorf("cannot encrypt metadata: expected [%d] metadata, got [%d]", len(outputs), len(metadata))
+	}
+	pp := s.PublicParams()
+	if pp == nil {
+		return errors.Errorf("public parameters not inizialized")
+	}
+	bases, err := nymBases(pp)
+	if err != nil {
+		return errors.WithMessage(err, "cannot encrypt metadata")
+	}
+	for i, output := range outputs {
+		if output.IsRedeem() {
+			continue
+		}
+		ro, err := identity.UnmarshallRawOwner(output.Owner)
+		if err != nil {
+			return errors.Wrapf(err, "cannot encrypt metadata: failed to unmarshal owner of output [%d]", i)
+		}
+		if ro.Type != identity.SerializedIdentityType {
+			logger.Debugf("owner of output [%d] is of type [%s], the metadata is not put on the ledger", i, ro.Type)
+			continue
+		}
+		nym, err := idemix.NymFromIdentity(ro.Identity, pp.IdemixCurveID)
+		if err != nil {
+			return errors.WithMessagef(err, "cannot encrypt metadata for output [%d]", i)
+		}
+		output.Metadata, err = token.EncryptMetadata(metadata[i], nym, bases, math.Curves[pp.IdemixCurveID])
+		if err != nil {
+			return errors.WithMessagef(err, "cannot encrypt metadata for output [%d]", i)
+		}
+	}
+	return nil
+}
+
+// DecryptTokenInfo returns the metadata carried by the passed output, if it is encrypted for one of the
+// local owner wallets. It returns nil if the output carries no metadata, or if it is not for us.
+func (s *Service) DecryptTokenInfo(raw []byte) ([]byte, error) {
+	output := &token.Token{}
+	if err := output.Deserialize(raw); err != nil {
+		return nil, errors.Wrap(err, "failed to deserialize zkatdlog token")
+	}
+	if output.Metadata == nil || output.IsRedeem() {
+		return nil, nil
+	}
+	signer, err := s.identityProvider.GetSigner(output.Owner)
+	if err != nil {
+		// not for us
+		return nil, nil
+	}
+	opener, ok := signer.(nymOpener)
+	if !ok {
+		return nil, errors.Errorf("signer of type [%T] cannot decrypt token metadata, a deterministic idemix wallet is required", signer)
+	}
+	sk, err := opener.UserSecret()
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot decrypt token metadata")
+	}
+	r, err := opener.NymRandomness()
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot decrypt token metadata")
+	}
+	pp := s.PublicParams()
+	if pp == nil {
+		return nil, errors.Errorf("public parameters not inizialized")
+	}
+	meta, err := output.Metadata.Decrypt([]*math.Zr{sk, r}, math.Curves[pp.IdemixCurveID])
+	if err != nil {
+		return nil, err
+	}
+	return meta.Serialize()
+}
+
+// nymBases returns the bases of the idemix pseudonyms, Nym = HSk^sk \cdot HRand^r
+func nymBases(pp *crypto.PublicParams) ([]*math.G1, error) {
+	hSk, err := idemix.HSk(pp.IdemixIssuerPK, pp.IdemixCurveID)
+	if err != nil {
+		return nil, err
+	}
+	hRand, err := idemix.HRand(pp.IdemixIssuerPK, pp.IdemixCurveID)
+	if err != nil {
+		return nil, err
+	}
+	return []*math.G1{hSk, hRand}, nil
+}
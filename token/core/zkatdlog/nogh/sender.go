@@ -7,11 +7,17 @@ SPDX-License-Identifier: Apache-2.0
 package nogh
 
 import (
+	"math/big"
+	"time"
+
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/metrics"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/tracing"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/interop/htlc"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
@@ -47,7 +53,16 @@ func (s *Service) Transfer(txID string, wallet driver.OwnerWallet, ids []*token3
 	if err != nil {
 		return nil, nil, err
 	}
-	var values []uint64
+	return s.transfer(txID, sender, signerIds, ids, outputTokens, opts)
+}
+
+// transfer returns the TransferAction generated by the passed sender, that spends the passed token identifiers
+// and creates the passed outputs, and the corresponding TransferMetadata
+func (s *Service) transfer(txID string, sender *transfer.Sender, signerIds []view.Identity, ids []*token3.ID, outputTokens []*token3.Token, opts *driver.TransferOptions) (driver.TransferAction, *driver.TransferMetadata, error) {
+	pp := sender.PublicParams
+	tokens := sender.Inputs
+	sender.WorkerPool = s.WorkerPool
+	var values []*big.Int
 	var owners [][]byte
 	var ownerIdentities []view.Identity
 	// get values and owners of outputs
@@ -56,7 +71,7 @@ func (s *Service) Transfer(txID string, wallet driver.OwnerWallet, ids []*token3
 		if err != nil {
 			return nil, nil, errors.Wrapf(err, "failed to get value for %dth output", i)
 		}
-		values = append(values, q.ToBigInt().Uint64())
+		values = append(values, q.ToBigInt())
 		if output.Owner == nil {
 			return nil, nil, errors.Errorf("failed to get owner for %dth output: nil owner", i)
 		}
@@ -81,10 +96,17 @@ func (s *Service) Transfer(txID string, wallet driver.OwnerWallet, ids []*token3
 	}
 	// produce zkatdlog transfer action
 	// return for each output its information in the clear
+	start := time.Now()
+	span := tracing.Get().StartTxSpan("zkatdlog.prove_transfer", txID, tracing.SpanContext{})
 	transfer, outputMetadata, err := sender.GenerateZKTransfer(values, owners)
+	span.End(err)
+	metrics.Get().ProofGenerationDuration.With("driver", crypto.DLogPublicParameters, "action", "transfer").Observe(metrics.Since(start))
 	if err != nil {
 		return nil, nil, errors.Wrapf(err, "failed to generate zkatdlog transfer action for txid [%s]", txID)
 	}
+	if err := s.EncryptMetadata(transfer.OutputTokens, outputMetadata); err != nil {
+		return nil, nil, err
+	}
 
 	// add transfer action's metadata
 	common.SetTransferActionMetadata(opts.Attributes, transfer.Metadata)
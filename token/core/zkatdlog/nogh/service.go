@@ -8,12 +8,13 @@ package nogh
 
 import (
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
-	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/validator"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
 	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
@@ -50,6 +51,7 @@ type KVS interface {
 	Exists(id string) bool
 	Put(id string, state interface{}) error
 	Get(id string, state interface{}) error
+	GetByPartialCompositeID(prefix string, attrs []string) (kvs.Iterator, error)
 }
 
 type Service struct {
@@ -63,7 +65,9 @@ type Service struct {
 	TokenCommitmentLoader TokenCommitmentLoader
 	QE                    QueryEngine
 	DeserializerProvider  DeserializerProviderFunc
-	configManager         config.Manager
+	// WorkerPool bounds the goroutines used to generate zero-knowledge proofs
+	WorkerPool    *common.WorkerPool
+	configManager config.Manager
 
 	identityProvider       driver.IdentityProvider
 	OwnerWalletsRegistry   *identity.WalletsRegistry
@@ -99,6 +103,11 @@ func NewTokenService(
 		IssuerWalletsRegistry:  identity.NewWalletsRegistry(tmsID, identityProvider, driver.IssuerRole, kvs),
 		AuditorWalletsRegistry: identity.NewWalletsRegistry(tmsID, identityProvider, driver.AuditorRole, kvs),
 	}
+	workers := 0
+	if configManager != nil && configManager.TMS() != nil && configManager.TMS().Prover != nil {
+		workers = configManager.TMS().Prover.Workers
+	}
+	s.WorkerPool = common.NewWorkerPool(workers)
 	return s, nil
 }
 
@@ -145,7 +154,11 @@ func (s *Service) Validator() (driver.Validator, error) {
 	if pp == nil {
 		return nil, errors.Errorf("public parameters not inizialized")
 	}
-	return validator.New(pp, d), nil
+	v := NewValidator(pp, d)
+	if s.configManager != nil && s.configManager.TMS() != nil && s.configManager.TMS().Validator != nil {
+		v.SetBatchVerification(s.configManager.TMS().Validator.BatchVerification)
+	}
+	return v, nil
 }
 
 // PublicParamsManager returns the manager of the public parameters associated with the service
@@ -0,0 +1,116 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nogh
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/x509"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/audit"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// LegacyTokenLoader loads the outputs of the driver the public parameters succeed
+type LegacyTokenLoader interface {
+	// LoadLegacyTokens returns the keys in the vault matching the passed token identifiers,
+	// the corresponding tokens in the clear and the identities of their owners
+	LoadLegacyTokens(ids []*token3.ID) ([]string, []*token3.Token, []view.Identity, error)
+}
+
+// Upgrade returns an upgrade TransferAction that spends the passed outputs of the predecessor driver, in the clear,
+// and creates the passed zkatdlog outputs. It also returns the corresponding TransferMetadata.
+// The public parameters must declare the migration.
+func (s *Service) Upgrade(txID string, ids []*token3.ID, outputTokens []*token3.Token, opts *driver.TransferOptions) (driver.TransferAction, *driver.TransferMetadata, error) {
+	logger.Debugf("Prepare Upgrade Action [%s,%v]", txID, ids)
+	loader, ok := s.TokenLoader.(LegacyTokenLoader)
+	if !ok {
+		return nil, nil, errors.Errorf("token loader of type [%T] cannot load legacy tokens", s.TokenLoader)
+	}
+	inputIDs, tokens, signerIds, err := loader.LoadLegacyTokens(ids)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to load tokens to upgrade")
+	}
+	pp := s.PublicParams()
+	if pp == nil {
+		return nil, nil, errors.Errorf("public parameters not inizialized")
+	}
+	var signers []driver.Signer
+	for _, id := range signerIds {
+		si, err := s.identityProvider.GetSigner(id)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed getting signing identity for id [%v]", id)
+		}
+		signers = append(signers, si)
+	}
+	sender, err := transfer.NewUpgradeSender(signers, tokens, inputIDs, pp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.transfer(txID, sender, signerIds, ids, outputTokens, opts)
+}
+
+// loadUpgradedTokens returns the outputs of the predecessor driver with the passed identifiers,
+// committed to as the inputs of an upgrade transfer
+func (s *Service) loadUpgradedTokens(ids []*token3.ID, pp *crypto.PublicParams) ([]*token.Token, error) {
+	loader, ok := s.TokenLoader.(LegacyTokenLoader)
+	if !ok {
+		return nil, errors.Errorf("token loader of type [%T] cannot load legacy tokens", s.TokenLoader)
+	}
+	_, legacy, _, err := loader.LoadLegacyTokens(ids)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]*token.Token, len(legacy))
+	for i, tok := range legacy {
+		tokens[i], _, err = token.CommitToClearToken(tok, pp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tokens, nil
+}
+
+// maxEnrollmentIDLength is the maximum length of the common name of an x509 certificate, see RFC 5280
+const maxEnrollmentIDLength = 64
+
+// legacyAuditDES deserializes the audit information of the owners of zkatdlog tokens
+// and, for the requests that upgrade the outputs of the predecessor driver, the enrollment IDs of their x509 owners
+type legacyAuditDES struct {
+	audit.Deserializer
+}
+
+func (d *legacyAuditDES) GetOwnerMatcher(raw []byte) (driver.Matcher, error) {
+	matcher, err := d.Deserializer.GetOwnerMatcher(raw)
+	if err == nil {
+		return matcher, nil
+	}
+	if !isEnrollmentID(raw) {
+		return nil, err
+	}
+	return &x509.AuditInfoDeserializer{CommonName: string(raw)}, nil
+}
+
+// isEnrollmentID returns true if the passed bytes can be the enrollment ID of an x509 identity,
+// the audit information of the owners of the outputs of the predecessor driver
+func isEnrollmentID(raw []byte) bool {
+	if len(raw) == 0 || len(raw) > maxEnrollmentIDLength || !utf8.Valid(raw) {
+		return false
+	}
+	for _, r := range string(raw) {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
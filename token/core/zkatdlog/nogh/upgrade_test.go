@@ -0,0 +1,37 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nogh
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/x509"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type idemixOnlyDES struct{}
+
+func (d *idemixOnlyDES) GetOwnerMatcher(raw []byte) (driver.Matcher, error) {
+	return nil, errors.New("not an idemix audit info")
+}
+
+func TestLegacyAuditDES(t *testing.T) {
+	des := &legacyAuditDES{Deserializer: &idemixOnlyDES{}}
+
+	// the enrollment ID of an x509 owner
+	matcher, err := des.GetOwnerMatcher([]byte("alice@org1.example.com"))
+	assert.NoError(t, err)
+	assert.Equal(t, &x509.AuditInfoDeserializer{CommonName: "alice@org1.example.com"}, matcher)
+
+	// malformed audit information is not taken for an enrollment ID
+	for _, raw := range [][]byte{nil, {0x0a, 0x20, 0xff, 0x01}, []byte("alice\n"), make([]byte, maxEnrollmentIDLength+1)} {
+		_, err = des.GetOwnerMatcher(raw)
+		assert.EqualError(t, err, "not an idemix audit info")
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package nogh
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/audit"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/validator"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// Validator checks the validity of zkatdlog token requests and opens their outputs
+type Validator struct {
+	*validator.Validator
+	pp           *crypto.PublicParams
+	deserializer driver.Deserializer
+}
+
+// NewValidator returns a new Validator for the passed public parameters and deserializer
+func NewValidator(pp *crypto.PublicParams, deserializer driver.Deserializer) *Validator {
+	return &Validator{
+		Validator:    validator.New(pp, deserializer),
+		pp:           pp,
+		deserializer: deserializer,
+	}
+}
+
+// OpenOutput returns the token in the clear encoded by the passed serialized output, opened with the passed output
+// metadata, and the enrollment ID of its owner, extracted from the passed audit info after checking that it matches the owner
+func (v *Validator) OpenOutput(output []byte, outputMetadata []byte, ownerAuditInfo []byte) (*token2.Token, string, error) {
+	tok := &token.Token{}
+	if err := tok.Deserialize(output); err != nil {
+		return nil, "", errors.Wrap(err, "failed deserializing output")
+	}
+	meta := &token.Metadata{}
+	if err := meta.Deserialize(outputMetadata); err != nil {
+		return nil, "", errors.Wrap(err, "failed deserializing output metadata")
+	}
+	clear, err := tok.GetTokenInTheClear(meta, v.pp)
+	if err != nil {
+		return nil, "", err
+	}
+	if tok.IsRedeem() {
+		return clear, "", nil
+	}
+	auditable := &audit.AuditableToken{Token: tok, Owner: &audit.OwnerOpening{OwnerInfo: ownerAuditInfo}}
+	if err := audit.InspectTokenOwner(v.deserializer, auditable, 0); err != nil {
+		return nil, "", errors.WithMessage(err, "the audit info does not match the owner")
+	}
+	eID, err := NewEnrollmentIDDeserializer().GetEnrollmentID(ownerAuditInfo)
+	if err != nil {
+		return nil, "", errors.WithMessage(err, "failed getting enrollment id of the owner")
+	}
+	return clear, eID, nil
+}
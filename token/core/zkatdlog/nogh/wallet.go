@@ -7,11 +7,14 @@ SPDX-License-Identifier: Apache-2.0
 package nogh
 
 import (
+	"io/ioutil"
+
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/idemix"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/anonym"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
@@ -26,6 +29,56 @@ func (s *Service) RegisterIssuerWallet(id string, path string) error {
 	return s.identityProvider.RegisterIssuerWallet(id, path)
 }
 
+// RegisterAnonymousIssuerWallet registers an issuer wallet, with the passed id, for the passed anonymous issuer key.
+// The issue actions produced with this wallet do not disclose the issuer on the ledger.
+// The identity of the key must be listed among the issuers of the public parameters.
+func (s *Service) RegisterAnonymousIssuerWallet(id string, key *anonym.SecretKey) error {
+	pp := s.PublicParams()
+	if pp == nil {
+		return errors.Errorf("public parameters not inizialized")
+	}
+	issuerIdentity, err := key.Identity()
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get identity of anonymous issuer wallet [%s]", id)
+	}
+	if err := s.identityProvider.RegisterSigner(issuerIdentity, anonym.NewSigner(key, pp), anonym.NewVerifier(pp)); err != nil {
+		return errors.WithMessagef(err, "failed to register signer of anonymous issuer wallet [%s]", id)
+	}
+
+	s.IssuerWalletsRegistry.Lock()
+	defer s.IssuerWalletsRegistry.Unlock()
+	s.IssuerWalletsRegistry.RegisterWallet(id, newIssuerWallet(s, id, issuerIdentity))
+	if err := s.IssuerWalletsRegistry.RegisterIdentity(issuerIdentity, id); err != nil {
+		return errors.WithMessagef(err, "failed to register identity of anonymous issuer wallet [%s]", id)
+	}
+	return nil
+}
+
+// LoadAnonymousIssuerWallets registers an issuer wallet for each anonymous issuer in the configuration.
+// The key of each anonymous issuer is read from the path of its configuration entry.
+func (s *Service) LoadAnonymousIssuerWallets() error {
+	if s.configManager == nil || s.configManager.TMS() == nil || s.configManager.TMS().Wallets == nil {
+		return nil
+	}
+	for _, issuer := range s.configManager.TMS().Wallets.Issuers {
+		if !issuer.Anonymous {
+			continue
+		}
+		raw, err := ioutil.ReadFile(s.configManager.TranslatePath(issuer.Path))
+		if err != nil {
+			return errors.Wrapf(err, "failed reading key of anonymous issuer wallet [%s]", issuer.ID)
+		}
+		key := &anonym.SecretKey{}
+		if err := key.Deserialize(raw); err != nil {
+			return errors.Wrapf(err, "failed unmarshalling key of anonymous issuer wallet [%s]", issuer.ID)
+		}
+		if err := s.RegisterAnonymousIssuerWallet(issuer.ID, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *Service) GetAuditInfo(id view.Identity) ([]byte, error) {
 	return s.identityProvider.GetAuditInfo(id)
 }
@@ -100,10 +153,17 @@ func (s *Service) OwnerWalletByID(id interface{}) (driver.OwnerWallet, error) {
 	defer s.OwnerWalletsRegistry.Unlock()
 
 	// check if there is already a wallet
-	w, idInfo, wID, err := s.OwnerWalletsRegistry.Lookup(id)
+	w, idInfo, wID, recovered, err := s.OwnerWalletsRegistry.Lookup(id)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to lookup identity for owner wallet [%v]", id)
 	}
+	if len(recovered) != 0 {
+		// the identity provider recognized the identity as belonging to the wallet, for example, a pseudonym
+		// regenerated after a restore. Bind them.
+		if err := s.OwnerWalletsRegistry.RegisterIdentity(recovered, wID); err != nil {
+			return nil, errors.WithMessagef(err, "failed to bind recovered identity to owner wallet [%s]", wID)
+		}
+	}
 	if w != nil {
 		return w.(driver.OwnerWallet), nil
 	}
@@ -127,7 +187,7 @@ func (s *Service) issuerWallet(id interface{}) (driver.IssuerWallet, error) {
 	defer s.IssuerWalletsRegistry.Unlock()
 
 	// check if there is already a wallet
-	w, idInfo, wID, err := s.IssuerWalletsRegistry.Lookup(id)
+	w, idInfo, wID, _, err := s.IssuerWalletsRegistry.Lookup(id)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to lookup identity for issuer wallet [%v]", id)
 	}
@@ -162,7 +222,7 @@ func (s *Service) auditorWallet(id interface{}) (driver.AuditorWallet, error) {
 	defer s.AuditorWalletsRegistry.Unlock()
 
 	// check if there is already a wallet
-	w, idInfo, wID, err := s.AuditorWalletsRegistry.Lookup(id)
+	w, idInfo, wID, _, err := s.AuditorWalletsRegistry.Lookup(id)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to lookup identity for auditor wallet [%v]", id)
 	}
@@ -283,6 +343,21 @@ func (w *ownerWallet) EnrollmentID() string {
 	return w.identityInfo.EnrollmentID()
 }
 
+func (w *ownerWallet) ListIdentities() ([]view.Identity, error) {
+	return w.tokenService.OwnerWalletsRegistry.WalletIdentities(w.id)
+}
+
+func (w *ownerWallet) RestoreIdentity(id view.Identity, auditInfo []byte, metadata []byte) error {
+	// check that the identity matches the audit info and register them, together with the token metadata
+	if err := w.tokenService.RegisterRecipientIdentity(id, auditInfo, metadata); err != nil {
+		return errors.WithMessagef(err, "failed restoring recipient identity in wallet [%s]", w.ID())
+	}
+	if err := w.tokenService.OwnerWalletsRegistry.RegisterIdentity(id, w.id); err != nil {
+		return errors.WithMessagef(err, "failed storing recipient identity in wallet [%s]", w.ID())
+	}
+	return nil
+}
+
 func (w *ownerWallet) GetSigner(identity view.Identity) (driver.Signer, error) {
 	if !w.Contains(identity) {
 		return nil, errors.Errorf("identity [%s] does not belong to this wallet [%s]", identity, w.ID())
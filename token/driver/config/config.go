@@ -6,20 +6,53 @@ SPDX-License-Identifier: Apache-2.0
 
 package config
 
+import "time"
+
 type InteractiveCertification struct {
 	IDs []string `yaml:"ids,omitempty"`
+	// MaxQueueSize is the maximum number of pending certification requests. Zero means the default value.
+	MaxQueueSize int `yaml:"maxQueueSize,omitempty"`
+	// BatchSize is the maximum number of tokens certified in a single interaction with a certifier.
+	// Zero means the default value.
+	BatchSize int `yaml:"batchSize,omitempty"`
+	// Timeout is the maximum time a caller waits for the certification of its tokens. Zero means the default value.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
 }
 
 type Certification struct {
 	Interactive *InteractiveCertification `yaml:"interactive,omitempty"`
 }
 
+// Signer tells where the signing key of an identity is kept.
+type Signer struct {
+	// Type is the name of the signer provider, for example `pkcs11` or `remote`
+	Type string `yaml:"type"`
+	// Opts are the provider-specific options
+	Opts interface{} `yaml:"opts,omitempty"`
+}
+
+// Derivation configures the deterministic derivation of the pseudonyms of an idemix identity.
+type Derivation struct {
+	// Lookahead is the number of pseudonyms, past the last one known to be in use, checked when recognizing
+	// the identities of the wallet. Zero means the default value.
+	Lookahead int `yaml:"lookahead,omitempty"`
+}
+
 type Identity struct {
 	ID        string      `yaml:"id"`
 	Default   bool        `yaml:"default,omitempty"`
 	Path      string      `yaml:"path"`
 	CacheSize int         `yaml:"cacheSize"`
 	Opts      interface{} `yaml:"opts,omitempty"`
+	// Signer, if set, instructs to use an external signer instead of the key stored in Path
+	Signer *Signer `yaml:"signer,omitempty"`
+	// Derivation, if set, instructs to derive the pseudonyms of an idemix identity from the wallet seed and an index
+	Derivation *Derivation `yaml:"derivation,omitempty"`
+	// Policy, if set, restricts the transfers an owner wallet signs
+	Policy *Policy `yaml:"policy,omitempty"`
+	// Anonymous, if true, instructs to load from Path the key of an anonymous issuer, as generated by tokengen,
+	// instead of an MSP. Only issuer wallets of the dlog driver can be anonymous
+	Anonymous bool `yaml:"anonymous,omitempty"`
 }
 
 func (i *Identity) String() string {
@@ -33,6 +66,121 @@ type Wallets struct {
 	Auditors   []*Identity `yaml:"auditors,omitempty"`
 }
 
+// Policy is the spending policy of an owner wallet, enforced by the wallet before it signs a transfer.
+type Policy struct {
+	// Limits bounds the quantities the wallet transfers, per token type.
+	Limits []*Limit `yaml:"limits,omitempty"`
+	// Counterparties, if not empty, are the enrollment IDs the wallet is allowed to transfer to.
+	Counterparties []string `yaml:"counterparties,omitempty"`
+	// Approvers are the labels of the FSC identities allowed to approve the transfers above an approval threshold.
+	Approvers []string `yaml:"approvers,omitempty"`
+}
+
+// Limit bounds the quantities of a token type an owner wallet transfers.
+// The quantities are decimal strings, an empty quantity means no bound.
+type Limit struct {
+	// TokenType is the type the limit applies to. If empty, the limit applies to the types without a limit of their own.
+	TokenType string `yaml:"type,omitempty"`
+	// PerTransaction bounds the quantity transferred by a single transaction.
+	PerTransaction string `yaml:"perTransaction,omitempty"`
+	// Daily bounds the quantity transferred in the last 24 hours, the transaction being signed included.
+	Daily string `yaml:"daily,omitempty"`
+	// ApprovalThreshold is the quantity above which a transaction needs the approval of one of the approvers.
+	ApprovalThreshold string `yaml:"approvalThreshold,omitempty"`
+}
+
+// Prover configures the generation of the zero-knowledge proofs of the token requests.
+type Prover struct {
+	// Workers is the maximum number of goroutines used to generate the proofs of a token request.
+	// Zero means the number of available CPUs.
+	Workers int `yaml:"workers,omitempty"`
+}
+
+// Validator configures the verification of the token requests.
+type Validator struct {
+	// BatchVerification, if true, instructs to verify the proofs of a token request at once, when the driver supports it.
+	BatchVerification bool `yaml:"batchVerification,omitempty"`
+}
+
+// Outputs configures the outputs of the token requests.
+type Outputs struct {
+	// EncryptMetadata, if true, instructs to put the metadata of the outputs on the ledger, encrypted for their owners.
+	// Then, the owners can recover their tokens from the ledger alone.
+	EncryptMetadata bool `yaml:"encryptMetadata,omitempty"`
+}
+
+// Retry configures the redelivery of the events a sink failed to forward, and the retries of the failed runs of a scheduled transfer.
+type Retry struct {
+	// MaxAttempts is the number of attempts. Once they are exhausted, the event of a sink is handed back to the event stream,
+	// that delivers it again later, and the run of a scheduled transfer is recorded as failed. Zero means the default value.
+	MaxAttempts int `yaml:"maxAttempts,omitempty"`
+	// InitialBackoff is the time waited after the first failed attempt, it doubles after each further failure.
+	// Zero means the default value.
+	InitialBackoff time.Duration `yaml:"initialBackoff,omitempty"`
+	// MaxBackoff bounds the time waited between two attempts. Zero means the default value.
+	MaxBackoff time.Duration `yaml:"maxBackoff,omitempty"`
+}
+
+// Webhook configures a sink that posts the token events to an HTTP endpoint.
+type Webhook struct {
+	ID  string `yaml:"id"`
+	URL string `yaml:"url"`
+	// Secret, if set, is the key used to sign the payloads with HMAC-SHA256
+	Secret string `yaml:"secret,omitempty"`
+	// Wallet, if set, restricts the forwarded events to those of this wallet
+	Wallet string `yaml:"wallet,omitempty"`
+	// Timeout bounds each HTTP request. Zero means the default value.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	Retry   *Retry        `yaml:"retry,omitempty"`
+}
+
+// Publisher configures a sink that publishes the token events to a message queue.
+type Publisher struct {
+	ID string `yaml:"id"`
+	// Type is the name of the publisher provider, for example `kafka`
+	Type string `yaml:"type"`
+	// Wallet, if set, restricts the forwarded events to those of this wallet
+	Wallet string `yaml:"wallet,omitempty"`
+	// Opts are the provider-specific options
+	Opts  interface{} `yaml:"opts,omitempty"`
+	Retry *Retry      `yaml:"retry,omitempty"`
+}
+
+// EventStream configures the retention of the events of the wallets of the TMS.
+// The events the subscriptions have not processed yet are retained in any case.
+type EventStream struct {
+	// MaxEvents bounds the number of events retained, the oldest ones are removed first. Zero means no bound.
+	MaxEvents uint64 `yaml:"maxEvents,omitempty"`
+	// MaxAge bounds the age of the events retained. Zero means no bound.
+	MaxAge time.Duration `yaml:"maxAge,omitempty"`
+}
+
+// Sinks configures the forwarding of the token events of the TMS to external systems.
+type Sinks struct {
+	Webhooks   []*Webhook   `yaml:"webhooks,omitempty"`
+	Publishers []*Publisher `yaml:"publishers,omitempty"`
+}
+
+// Scheduler configures the execution of the scheduled transfers of the TMS.
+type Scheduler struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Interval is the time between two checks for the due schedules. Zero means the default value.
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// Retry configures the retries of the failed runs.
+	Retry *Retry `yaml:"retry,omitempty"`
+}
+
+// Tracing configures the export of the spans of the token transactions to an OpenTelemetry collector.
+type Tracing struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Endpoint is the base URL of the OTLP/HTTP receiver of the collector, `http://localhost:4318` if not set.
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// ServiceName identifies the node in the traces. The FSC node identifier is used if not set.
+	ServiceName string `yaml:"serviceName,omitempty"`
+	// Timeout bounds each export request. Zero means the default value.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
 type TMS struct {
 	Network       string         `yaml:"network,omitempty"`
 	Channel       string         `yaml:"channel,omitempty"`
@@ -40,6 +188,12 @@ type TMS struct {
 	Driver        string         `yaml:"driver,omitempty"`
 	Certification *Certification `yaml:"certification,omitempty"`
 	Wallets       *Wallets       `yaml:"wallets,omitempty"`
+	Prover        *Prover        `yaml:"prover,omitempty"`
+	Validator     *Validator     `yaml:"validator,omitempty"`
+	Outputs       *Outputs       `yaml:"outputs,omitempty"`
+	EventStream   *EventStream   `yaml:"eventStream,omitempty"`
+	Sinks         *Sinks         `yaml:"sinks,omitempty"`
+	Scheduler     *Scheduler     `yaml:"scheduler,omitempty"`
 }
 
 type Manager interface {
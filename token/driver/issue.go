@@ -6,7 +6,10 @@ SPDX-License-Identifier: Apache-2.0
 
 package driver
 
-import "github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
 
 // IssueOptions models the options that can be passed to the issue command
 type IssueOptions struct {
@@ -18,11 +21,12 @@ type IssueOptions struct {
 type IssueService interface {
 	// Issue generates an IssuerAction whose tokens are issued by the passed identity.
 	// The tokens to be issued are passed as pairs (value, owner).
+	// The values are expressed at the precision of the public parameters.
 	// In addition, a set of options can be specified to further customize the issue command.
 	// The function returns an IssuerAction, the associated metadata, and the identity of the issuer (depending on the implementation, it can be different from
 	// the one passed in input).
 	// The metadata is an array with an entry for each output created by the action.
-	Issue(issuerIdentity view.Identity, tokenType string, values []uint64, owners [][]byte, opts *IssueOptions) (IssueAction, [][]byte, view.Identity, error)
+	Issue(issuerIdentity view.Identity, tokenType string, values []token.Quantity, owners [][]byte, opts *IssueOptions) (IssueAction, [][]byte, view.Identity, error)
 
 	// VerifyIssue checks the well-formedness of the passed IssuerAction with the respect to the passed metadata
 	VerifyIssue(tr IssueAction, metadata [][]byte) error
@@ -38,7 +38,8 @@ type PublicParameters interface {
 	TokenDataHiding() bool
 	// GraphHiding returns true if the token graph is hidden
 	GraphHiding() bool
-	// MaxTokenValue returns the maximum token value
+	// MaxTokenValue returns the maximum token value.
+	// If the maximum does not fit in an uint64, it returns math.MaxUint64 and the maximum is bound by Precision.
 	MaxTokenValue() uint64
 	// CertificationDriver returns the certification driver identifier
 	CertificationDriver() string
@@ -48,6 +49,8 @@ type PublicParameters interface {
 	Auditors() []view.Identity
 	// Precision returns the precision used to represent the token value.
 	Precision() uint64
+	// RevokedEnrollmentIDs returns the enrollment IDs that are not allowed to transact
+	RevokedEnrollmentIDs() []string
 	// String returns a readable version of the public parameters
 	String() string
 }
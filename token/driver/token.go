@@ -15,3 +15,10 @@ type TokenService interface {
 	// DeserializeToken unmarshals the passed output and uses the passed metadata to derive a token and its issuer (if any).
 	DeserializeToken(output []byte, outputMetadata []byte) (*token2.Token, view.Identity, error)
 }
+
+// TokenInfoDecrypter models a TokenService whose outputs can carry their metadata, encrypted for their owners
+type TokenInfoDecrypter interface {
+	// DecryptTokenInfo returns the metadata carried by the passed output, if it is encrypted for one of the local wallets.
+	// It returns nil if the output carries no metadata, or if it is not for us.
+	DecryptTokenInfo(output []byte) ([]byte, error)
+}
@@ -29,3 +29,10 @@ type TransferService interface {
 	// DeserializeTransferAction deserializes the passed bytes into an TransferAction
 	DeserializeTransferAction(raw []byte) (TransferAction, error)
 }
+
+// Upgrader is implemented by the TransferService of the drivers that can migrate the tokens of a predecessor driver
+type Upgrader interface {
+	// Upgrade generates a TransferAction that spends the passed outputs of the predecessor driver
+	// and creates the passed outputs. The function returns an TransferAction and the associated metadata.
+	Upgrade(txID string, ids []*token2.ID, Outputs []*token2.Token, opts *TransferOptions) (TransferAction, *TransferMetadata, error)
+}
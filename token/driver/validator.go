@@ -6,7 +6,12 @@ SPDX-License-Identifier: Apache-2.0
 
 package driver
 
-import "github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+import (
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
 
 // GetStateFnc models a function that returns the value for the given key from the ledger
 type GetStateFnc = func(key string) ([]byte, error)
@@ -31,3 +36,33 @@ type Validator interface {
 	// VerifyTokenRequestFromRaw verifies the passed marshalled token request against the passed ledger and anchor
 	VerifyTokenRequestFromRaw(getState GetStateFnc, anchor string, raw []byte) ([]interface{}, error)
 }
+
+// TimedValidator models a Validator that checks the time constraints of a token request, for instance the deadline
+// of a migration, against the passed timestamp of the transaction instead of the local clock.
+// Then, all the endorsers of the transaction reach the same result.
+type TimedValidator interface {
+	Validator
+	// VerifyTokenRequestFromRawAt verifies the passed marshalled token request against the passed ledger and anchor,
+	// at the passed timestamp
+	VerifyTokenRequestFromRawAt(getState GetStateFnc, anchor string, raw []byte, timestamp time.Time) ([]interface{}, error)
+}
+
+// BatchValidator models a Validator that can verify the proofs of a token request, or of many token requests, at once
+type BatchValidator interface {
+	Validator
+	// SetBatchVerification enables or disables batch verification
+	SetBatchVerification(enabled bool)
+	// VerifyTokenRequestsFromRaw verifies the passed marshalled token requests against the passed ledger and anchors.
+	// It returns, for each request, its actions if it is valid, or the reason why it is not.
+	VerifyTokenRequestsFromRaw(getState GetStateFnc, anchors []string, raws [][]byte) ([][]interface{}, []error)
+}
+
+// OutputOpener models a Validator that can open the outputs of a token request from the public parameters only,
+// for instance to show them to an offline signer that does not trust the node asking for the signature
+type OutputOpener interface {
+	Validator
+	// OpenOutput returns the token in the clear encoded by the passed serialized output and the enrollment ID of its owner.
+	// It fails if the passed output metadata does not open the output or if the passed audit info does not match the owner.
+	// The enrollment ID is empty for the redeemed tokens.
+	OpenOutput(output []byte, outputMetadata []byte, ownerAuditInfo []byte) (*token.Token, string, error)
+}
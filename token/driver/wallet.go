@@ -54,6 +54,14 @@ type OwnerWallet interface {
 
 	// EnrollmentID returns the enrollment ID of the owner wallet
 	EnrollmentID() string
+
+	// ListIdentities returns the recipient identities bound to this wallet so far
+	ListIdentities() ([]view.Identity, error)
+
+	// RestoreIdentity binds the passed recipient identity, previously generated by this wallet, back to it
+	// together with the passed audit information and token metadata.
+	// It is used to restore the wallet from a backup.
+	RestoreIdentity(id view.Identity, auditInfo []byte, metadata []byte) error
 }
 
 // IssuerWallet models the wallet of an issuer
@@ -30,6 +30,16 @@ type Metadata struct {
 // GetToken unmarshals the given bytes to extract the token and its issuer (if any).
 func (m *Metadata) GetToken(raw []byte) (*token.Token, view.Identity, []byte, error) {
 	tokenInfoRaw := m.TokenRequestMetadata.GetTokenInfo(raw)
+	if len(tokenInfoRaw) == 0 {
+		// fall back to the metadata carried by the output, if any
+		if d, ok := m.TMS.(driver.TokenInfoDecrypter); ok {
+			var err error
+			tokenInfoRaw, err = d.DecryptTokenInfo(raw)
+			if err != nil {
+				return nil, nil, nil, errors.WithMessagef(err, "failed decrypting metadata for [%s]", hash.Hashable(raw).String())
+			}
+		}
+	}
 	if len(tokenInfoRaw) == 0 {
 		logger.Debugf("metadata for [%s] not found", hash.Hashable(raw).String())
 		return nil, nil, nil, errors.Errorf("metadata for [%s] not found", hash.Hashable(raw).String())
@@ -320,3 +320,39 @@ func assertEqualTransferMetadata(t *testing.T, original, filtered *driver.Transf
 	assert.Equal(t, original.Receivers, filtered.Receivers)
 	assert.Equal(t, original.ReceiverAuditInfos, filtered.ReceiverAuditInfos)
 }
+
+type decrypterTMS struct {
+	*mock.TMS
+	tokenInfos map[string][]byte
+}
+
+func (d *decrypterTMS) DecryptTokenInfo(output []byte) ([]byte, error) {
+	return d.tokenInfos[string(output)], nil
+}
+
+func TestGetTokenFromLedger(t *testing.T) {
+	tms := &decrypterTMS{
+		TMS:        &mock.TMS{},
+		tokenInfos: map[string][]byte{"Alice's output": []byte("Alice's output's token info")},
+	}
+	tms.DeserializeTokenReturns(&token2.Token{Type: "ABC", Quantity: "0x10"}, view.Identity("Issuer"), nil)
+	metadata := &token.Metadata{
+		TMS:                  tms,
+		TokenRequestMetadata: &driver.TokenRequestMetadata{},
+	}
+
+	// the output carries its metadata
+	tok, issuer, tokenInfo, err := metadata.GetToken([]byte("Alice's output"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ABC", tok.Type)
+	assert.Equal(t, view.Identity("Issuer"), issuer)
+	assert.Equal(t, []byte("Alice's output's token info"), tokenInfo)
+	output, info := tms.DeserializeTokenArgsForCall(0)
+	assert.Equal(t, []byte("Alice's output"), output)
+	assert.Equal(t, []byte("Alice's output's token info"), info)
+
+	// the output is not for us
+	_, _, _, err = metadata.GetToken([]byte("Bob's output"))
+	assert.Error(t, err)
+	assert.Equal(t, 1, tms.DeserializeTokenCallCount())
+}
@@ -9,8 +9,12 @@ package token
 import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
 )
 
+// ErrRevoked is returned when an enrollment ID that is not allowed to transact is involved
+var ErrRevoked = errors.New("enrollment id revoked")
+
 // PublicParamsFetcher models the public parameters fetcher
 type PublicParamsFetcher interface {
 	// Fetch fetches the public parameters from the backend
@@ -42,7 +46,8 @@ func (c *PublicParametersManager) TokenDataHiding() bool {
 	return c.ppm.PublicParameters().TokenDataHiding()
 }
 
-// MaxTokenValue returns the maximum value a token can contain
+// MaxTokenValue returns the maximum value a token can contain.
+// If the maximum does not fit in an uint64, it returns math.MaxUint64 and the maximum is bound by Precision.
 func (c *PublicParametersManager) MaxTokenValue() uint64 {
 	return c.ppm.PublicParameters().MaxTokenValue()
 }
@@ -76,3 +81,28 @@ func (c *PublicParametersManager) Auditors() []view.Identity {
 func (c *PublicParametersManager) Fetch() ([]byte, error) {
 	return c.ppm.Fetch()
 }
+
+// RevokedEnrollmentIDs returns the enrollment IDs that are not allowed to transact
+func (c *PublicParametersManager) RevokedEnrollmentIDs() []string {
+	pp := c.ppm.PublicParameters()
+	if pp == nil {
+		return nil
+	}
+	return pp.RevokedEnrollmentIDs()
+}
+
+// CheckEnrollmentIDs returns an error wrapping ErrRevoked if any of the passed enrollment IDs is revoked
+func (c *PublicParametersManager) CheckEnrollmentIDs(eIDs ...string) error {
+	revoked := c.RevokedEnrollmentIDs()
+	if len(revoked) == 0 {
+		return nil
+	}
+	for _, eID := range eIDs {
+		for _, r := range revoked {
+			if eID == r {
+				return errors.Wrapf(ErrRevoked, "[%s]", eID)
+			}
+		}
+	}
+	return nil
+}
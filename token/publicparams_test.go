@@ -0,0 +1,92 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type revocationPP struct {
+	driver.PublicParameters
+	revoked []string
+}
+
+func (pp *revocationPP) RevokedEnrollmentIDs() []string {
+	return pp.revoked
+}
+
+type revocationPPM struct {
+	driver.PublicParamsManager
+	pp driver.PublicParameters
+}
+
+func (ppm *revocationPPM) PublicParameters() driver.PublicParameters {
+	return ppm.pp
+}
+
+func TestCheckEnrollmentIDs(t *testing.T) {
+	ppm := &PublicParametersManager{ppm: &revocationPPM{}}
+	assert.NoError(t, ppm.CheckEnrollmentIDs("alice"))
+
+	ppm = &PublicParametersManager{ppm: &revocationPPM{pp: &revocationPP{}}}
+	assert.NoError(t, ppm.CheckEnrollmentIDs("alice", "bob"))
+
+	ppm = &PublicParametersManager{ppm: &revocationPPM{pp: &revocationPP{revoked: []string{"bob"}}}}
+	assert.NoError(t, ppm.CheckEnrollmentIDs("alice", "charlie"))
+	assert.NoError(t, ppm.CheckEnrollmentIDs())
+	err := ppm.CheckEnrollmentIDs("alice", "bob")
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrRevoked))
+	assert.Contains(t, err.Error(), "[bob]")
+}
+
+type revocationTMS struct {
+	driver.TokenManagerService
+	ppm driver.PublicParamsManager
+}
+
+func (t *revocationTMS) PublicParamsManager() driver.PublicParamsManager {
+	return t.ppm
+}
+
+type revocationOwnerWallet struct {
+	driver.OwnerWallet
+	eID string
+}
+
+func (w *revocationOwnerWallet) ID() string {
+	return "wallet"
+}
+
+func (w *revocationOwnerWallet) EnrollmentID() string {
+	return w.eID
+}
+
+func (w *revocationOwnerWallet) GetRecipientIdentity() (view.Identity, error) {
+	return view.Identity("recipient"), nil
+}
+
+func TestGetRecipientIdentityRevoked(t *testing.T) {
+	tms := &ManagementService{tms: &revocationTMS{ppm: &revocationPPM{pp: &revocationPP{revoked: []string{"bob"}}}}}
+	newWallet := func(eID string) *OwnerWallet {
+		w := &revocationOwnerWallet{eID: eID}
+		return &OwnerWallet{Wallet: &Wallet{w: w, managementService: tms}, w: w}
+	}
+
+	id, err := newWallet("alice").GetRecipientIdentity()
+	assert.NoError(t, err)
+	assert.Equal(t, view.Identity("recipient"), id)
+
+	_, err = newWallet("bob").GetRecipientIdentity()
+	assert.True(t, errors.Is(err, ErrRevoked))
+	assert.EqualError(t, err, "wallet [wallet] cannot receive tokens: [bob]: enrollment id revoked")
+}
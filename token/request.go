@@ -8,6 +8,8 @@ package token
 
 import (
 	"encoding/asn1"
+	"math"
+	"math/big"
 
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
@@ -187,18 +189,34 @@ func (r *Request) ID() string {
 // The action issues to the receiver a token of the passed type and quantity.
 // Additional options can be passed to customize the action.
 func (r *Request) Issue(wallet *IssuerWallet, receiver view.Identity, typ string, q uint64, opts ...IssueOption) (*IssueAction, error) {
+	return r.IssueQuantity(wallet, receiver, typ, token.NewQuantityFromUInt64(q), opts...)
+}
+
+// IssueQuantity appends an issue action to the request. The action will be prepared using the provided issuer wallet.
+// The action issues to the receiver a token of the passed type and quantity.
+// Unlike Issue, the quantity can go beyond 64 bits, up to the precision of the public parameters.
+// Additional options can be passed to customize the action.
+func (r *Request) IssueQuantity(wallet *IssuerWallet, receiver view.Identity, typ string, q token.Quantity, opts ...IssueOption) (*IssueAction, error) {
 	if wallet == nil {
 		return nil, errors.Errorf("wallet is nil")
 	}
 	if typ == "" {
 		return nil, errors.Errorf("type is empty")
 	}
-	if q == 0 {
+	if q == nil || q.ToBigInt().Sign() == 0 {
 		return nil, errors.Errorf("q is zero")
 	}
-	maxTokenValue := r.TokenService.PublicParametersManager().MaxTokenValue()
-	if q > maxTokenValue {
-		return nil, errors.Errorf("q is larger than max token value [%d]", maxTokenValue)
+	precision := r.TokenService.PublicParametersManager().Precision()
+	quantity, err := token.ToQuantity(q.Decimal(), precision)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to convert [%s] to quantity of precision [%d]", q.Decimal(), precision)
+	}
+	maxTokenValue, err := r.maxTokenValue(precision)
+	if err != nil {
+		return nil, err
+	}
+	if quantity.Cmp(maxTokenValue) == 1 {
+		return nil, errors.Errorf("q is larger than max token value [%s]", maxTokenValue.Decimal())
 	}
 
 	if receiver.IsNone() {
@@ -219,7 +237,7 @@ func (r *Request) Issue(wallet *IssuerWallet, receiver view.Identity, typ string
 	issue, tokenInfos, issuer, err := r.TokenService.tms.Issue(
 		id,
 		typ,
-		[]uint64{q},
+		[]token.Quantity{quantity},
 		[][]byte{receiver},
 		&driver.IssueOptions{
 			Attributes: opt.Attributes,
@@ -265,8 +283,17 @@ func (r *Request) Issue(wallet *IssuerWallet, receiver view.Identity, typ string
 // In other words, owners[0] will receives values[0], and so on.
 // Additional options can be passed to customize the action.
 func (r *Request) Transfer(wallet *OwnerWallet, typ string, values []uint64, owners []view.Identity, opts ...TransferOption) (*TransferAction, error) {
+	return r.TransferQuantities(wallet, typ, uint64sToQuantities(values), owners, opts...)
+}
+
+// TransferQuantities appends a transfer action to the request. The action will be prepared using the provided owner wallet.
+// The action transfers tokens of the passed types to the receivers for the passed quantities.
+// In other words, owners[0] will receives values[0], and so on.
+// Unlike Transfer, the quantities can go beyond 64 bits, up to the precision of the public parameters.
+// Additional options can be passed to customize the action.
+func (r *Request) TransferQuantities(wallet *OwnerWallet, typ string, values []token.Quantity, owners []view.Identity, opts ...TransferOption) (*TransferAction, error) {
 	for _, v := range values {
-		if v == 0 {
+		if v == nil || v.ToBigInt().Sign() == 0 {
 			return nil, errors.Errorf("value is zero")
 		}
 	}
@@ -314,15 +341,77 @@ func (r *Request) Transfer(wallet *OwnerWallet, typ string, values []uint64, own
 	return &TransferAction{a: transfer}, nil
 }
 
+// Upgrade appends an upgrade transfer action to the request, if the token driver is migrating the tokens
+// of a predecessor driver. The action spends the passed outputs of the predecessor, of the passed type,
+// and transfers them to the receivers for the passed quantities.
+// Additional options can be passed to customize the action.
+func (r *Request) Upgrade(ids []*token.ID, typ string, values []token.Quantity, owners []view.Identity, opts ...TransferOption) (*TransferAction, error) {
+	if len(ids) == 0 {
+		return nil, errors.Errorf("no token to upgrade")
+	}
+	if len(values) != len(owners) {
+		return nil, errors.Errorf("number of values [%d] does not match number of owners [%d]", len(values), len(owners))
+	}
+	ts := r.TokenService.tms
+	upgrader, ok := ts.(driver.Upgrader)
+	if !ok {
+		return nil, errors.Errorf("the token driver does not support upgrading tokens")
+	}
+	opt, err := compileTransferOptions(opts...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed compiling options [%v]", opts)
+	}
+	outputTokens, _, err := r.genOutputs(values, owners, typ)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed preparing upgrade")
+	}
+
+	logger.Debugf("Prepare Upgrade Action [id:%s,ins:%d,outs:%d]", r.Anchor, len(ids), len(outputTokens))
+
+	transfer, transferMetadata, err := upgrader.Upgrade(
+		r.Anchor,
+		ids,
+		outputTokens,
+		&driver.TransferOptions{
+			Attributes: opt.Attributes,
+		},
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating upgrade action")
+	}
+	// double check
+	if err := ts.VerifyTransfer(transfer, transferMetadata.OutputsMetadata); err != nil {
+		return nil, errors.Wrap(err, "failed checking generated proof")
+	}
+
+	// Append
+	raw, err := transfer.Serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed serializing upgrade action")
+	}
+	r.Actions.Transfers = append(r.Actions.Transfers, raw)
+	r.Metadata.Transfers = append(r.Metadata.Transfers, *transferMetadata)
+
+	return &TransferAction{a: transfer}, nil
+}
+
 // Redeem appends a redeem action to the request. The action will be prepared using the provided owner wallet.
 // The action redeems tokens of the passed type for a total amount matching the passed value.
 // Additional options can be passed to customize the action.
 func (r *Request) Redeem(wallet *OwnerWallet, typ string, value uint64, opts ...TransferOption) error {
+	return r.RedeemQuantity(wallet, typ, token.NewQuantityFromUInt64(value), opts...)
+}
+
+// RedeemQuantity appends a redeem action to the request. The action will be prepared using the provided owner wallet.
+// The action redeems tokens of the passed type for a total amount matching the passed value.
+// Unlike Redeem, the value can go beyond 64 bits, up to the precision of the public parameters.
+// Additional options can be passed to customize the action.
+func (r *Request) RedeemQuantity(wallet *OwnerWallet, typ string, value token.Quantity, opts ...TransferOption) error {
 	opt, err := compileTransferOptions(opts...)
 	if err != nil {
 		return errors.WithMessagef(err, "failed compiling options [%v]", opts)
 	}
-	tokenIDs, outputTokens, err := r.prepareTransfer(true, wallet, typ, []uint64{value}, []view.Identity{nil}, opt)
+	tokenIDs, outputTokens, err := r.prepareTransfer(true, wallet, typ, []token.Quantity{value}, []view.Identity{nil}, opt)
 	if err != nil {
 		return errors.Wrap(err, "failed preparing transfer")
 	}
@@ -964,7 +1053,7 @@ func (r *Request) parseInputIDs(inputs []*token.ID) ([]*token.ID, token.Quantity
 	return inputs, sum, typ, nil
 }
 
-func (r *Request) prepareTransfer(redeem bool, wallet *OwnerWallet, tokenType string, values []uint64, owners []view.Identity, transferOpts *TransferOptions) ([]*token.ID, []*token.Token, error) {
+func (r *Request) prepareTransfer(redeem bool, wallet *OwnerWallet, tokenType string, values []token.Quantity, owners []view.Identity, transferOpts *TransferOptions) ([]*token.ID, []*token.Token, error) {
 	for _, owner := range owners {
 		if redeem {
 			if !owner.IsNone() {
@@ -1049,19 +1138,21 @@ func (r *Request) prepareTransfer(redeem bool, wallet *OwnerWallet, tokenType st
 	return tokenIDs, outputTokens, nil
 }
 
-func (r *Request) genOutputs(values []uint64, owners []view.Identity, tokenType string) ([]*token.Token, token.Quantity, error) {
+func (r *Request) genOutputs(values []token.Quantity, owners []view.Identity, tokenType string) ([]*token.Token, token.Quantity, error) {
 	precision := r.TokenService.PublicParametersManager().Precision()
-	maxTokenValue := r.TokenService.PublicParametersManager().MaxTokenValue()
-	maxTokenValueQ, err := token.UInt64ToQuantity(maxTokenValue, precision)
+	maxTokenValueQ, err := r.maxTokenValue(precision)
 	if err != nil {
-		return nil, nil, errors.Wrapf(err, "failed to convert [%d] to quantity of precision [%d]", maxTokenValue, precision)
+		return nil, nil, err
 	}
 	outputSum := token.NewZeroQuantity(precision)
 	var outputTokens []*token.Token
 	for i, value := range values {
-		q, err := token.UInt64ToQuantity(value, precision)
+		if value == nil {
+			return nil, nil, errors.Errorf("value at index [%d] is nil", i)
+		}
+		q, err := token.ToQuantity(value.Decimal(), precision)
 		if err != nil {
-			return nil, nil, errors.Wrapf(err, "failed to convert [%d] to quantity of precision [%d]", value, precision)
+			return nil, nil, errors.Wrapf(err, "failed to convert [%s] to quantity of precision [%d]", value.Decimal(), precision)
 		}
 		if q.Cmp(maxTokenValueQ) == 1 {
 			return nil, nil, errors.Errorf("cannot create output with value [%s], max [%s]", q.Decimal(), maxTokenValueQ.Decimal())
@@ -1078,6 +1169,30 @@ func (r *Request) genOutputs(values []uint64, owners []view.Identity, tokenType
 	return outputTokens, outputSum, nil
 }
 
+// maxTokenValue returns the maximum value a token can hold as a quantity of the passed precision.
+// Public parameters report math.MaxUint64 when the maximum does not fit in an uint64,
+// in that case the maximum is bound by the precision.
+func (r *Request) maxTokenValue(precision uint64) (token.Quantity, error) {
+	maxTokenValue := r.TokenService.PublicParametersManager().MaxTokenValue()
+	if maxTokenValue == math.MaxUint64 && precision > 64 {
+		max := new(big.Int).Lsh(big.NewInt(1), uint(precision))
+		return token.ToQuantity(max.Sub(max, big.NewInt(1)).String(), precision)
+	}
+	q, err := token.UInt64ToQuantity(maxTokenValue, precision)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to convert [%d] to quantity of precision [%d]", maxTokenValue, precision)
+	}
+	return q, nil
+}
+
+func uint64sToQuantities(values []uint64) []token.Quantity {
+	res := make([]token.Quantity, len(values))
+	for i, v := range values {
+		res[i] = token.NewQuantityFromUInt64(v)
+	}
+	return res
+}
+
 type requestSer struct {
 	TxID     string
 	Actions  []byte
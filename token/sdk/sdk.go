@@ -8,6 +8,7 @@ package token
 
 import (
 	"context"
+	"reflect"
 	"time"
 
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
@@ -16,24 +17,36 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	tms2 "github.com/hyperledger-labs/fabric-token-sdk/token/core"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/metrics"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/tracing"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/config"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken/driver"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/signer/offline"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/signer/pkcs11"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/signer/remote"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/gh/driver"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh/driver"
 	network2 "github.com/hyperledger-labs/fabric-token-sdk/token/sdk/network"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/sdk/tms"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/sdk/vault"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/backup"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/certifier/dummy"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/certifier/interactive"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/eventstream"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/network/fabric/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/orion"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/network/orion/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/owner"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/scheduler"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/selector"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/sink"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/db/badger"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/db/memory"
+	metrics2 "github.com/hyperledger/fabric/common/metrics"
 	"github.com/pkg/errors"
 )
 
@@ -46,9 +59,11 @@ type Registry interface {
 }
 
 type SDK struct {
-	registry       Registry
-	auditorManager *auditor.Manager
-	ownerManager   *owner.Manager
+	registry         Registry
+	auditorManager   *auditor.Manager
+	ownerManager     *owner.Manager
+	streamManager    *eventstream.Manager
+	schedulerManager *scheduler.Manager
 }
 
 func NewSDK(registry Registry) *SDK {
@@ -63,6 +78,13 @@ func (p *SDK) Install() error {
 	}
 	logger.Infof("Token platform enabled, installing...")
 
+	// Export the token metrics through the metrics provider of the node, if any
+	if provider, err := p.registry.GetService(reflect.TypeOf((*metrics2.Provider)(nil))); err == nil {
+		metrics.Install(provider.(metrics2.Provider))
+	} else {
+		logger.Infof("No metrics provider found, token metrics disabled")
+	}
+
 	logger.Infof("Set TMS TMSProvider")
 	vaultProvider := vault.NewProvider(p.registry)
 	tmsProvider := tms2.NewTMSProvider(
@@ -99,6 +121,15 @@ func (p *SDK) Install() error {
 	assert.NoError(p.registry.RegisterService(p.auditorManager))
 	p.ownerManager = owner.NewManager(p.registry, kvs.GetService(p.registry))
 	assert.NoError(p.registry.RegisterService(p.ownerManager))
+	p.streamManager = eventstream.NewManager(p.registry, kvs.GetService(p.registry))
+	assert.NoError(p.registry.RegisterService(p.streamManager))
+	p.schedulerManager = scheduler.NewManager(p.registry, kvs.GetService(p.registry))
+	assert.NoError(p.registry.RegisterService(p.schedulerManager))
+	assert.NoError(p.registry.RegisterService(backup.NewManager(p.registry)))
+
+	// Token transaction views
+	assert.NoError(ttx.InstallViews(p.registry), "failed to install token transaction views")
+	assert.NoError(scheduler.InstallViews(p.registry), "failed to install scheduled transfer views")
 
 	enabled, err := orion.IsCustodian(view2.GetConfigService(p.registry))
 	assert.NoError(err, "failed to get custodian status")
@@ -117,9 +148,27 @@ func (p *SDK) Start(ctx context.Context) error {
 		return nil
 	}
 	logger.Infof("Token platform enabled, starting...")
+	sdkConfig := config.NewTokenSDK(configProvider)
+
+	// export the spans of the token transactions, if configured
+	tracingConfig, err := sdkConfig.Tracing()
+	if err != nil {
+		return errors.WithMessagef(err, "failed get the tracing configuration")
+	}
+	if tracingConfig != nil && tracingConfig.Enabled {
+		serviceName := tracingConfig.ServiceName
+		if len(serviceName) == 0 {
+			serviceName = configProvider.GetString("fsc.id")
+		}
+		tracing.Install(tracing.NewTracer(tracing.NewOTLPExporter(tracingConfig.Endpoint, serviceName, tracingConfig.Timeout)))
+		go func() {
+			<-ctx.Done()
+			tracing.Get().Shutdown()
+		}()
+	}
 
 	// load the configured tms
-	tmsConfigs, err := config.NewTokenSDK(configProvider).GetTMSs()
+	tmsConfigs, err := sdkConfig.GetTMSs()
 	if err != nil {
 		return errors.WithMessagef(err, "failed get the TMS configurations")
 	}
@@ -133,6 +182,15 @@ func (p *SDK) Start(ctx context.Context) error {
 		if tms == nil {
 			return errors.Errorf("failed to load configured TMS [%s]", tmsID)
 		}
+		// record the events of the wallets from now on, and forward them to the configured sinks
+		stream, err := p.streamManager.Stream(tms)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to start the event stream of [%s]", tmsID)
+		}
+		stream.SetRetention(tmsConfig.TMS().EventStream)
+		if _, err := sink.Start(kvs.GetService(p.registry), tmsID, stream, tmsConfig.TMS().Sinks); err != nil {
+			return errors.WithMessagef(err, "failed to start the sinks of [%s]", tmsID)
+		}
 	}
 
 	// restore owner and auditor dbs, if any
@@ -143,6 +201,22 @@ func (p *SDK) Start(ctx context.Context) error {
 		return errors.WithMessagef(err, "failed to restore auditor dbs")
 	}
 
+	// rebroadcast or abort the transactions left pending
+	if _, err := ttx.Recover(p.registry); err != nil {
+		return errors.WithMessagef(err, "failed to recover pending transactions")
+	}
+
+	// run the scheduled transfers, if enabled
+	for _, tmsConfig := range tmsConfigs {
+		c := tmsConfig.TMS().Scheduler
+		if c == nil || !c.Enabled {
+			continue
+		}
+		tms := token.GetManagementService(p.registry, token.WithTMS(tmsConfig.TMS().Network, tmsConfig.TMS().Channel, tmsConfig.TMS().Namespace))
+		p.schedulerManager.Scheduler(tms).Start(ctx, c)
+		logger.Infof("scheduler of [%s] started", tms.ID())
+	}
+
 	logger.Infof("Token platform enabled, starting...done")
 	return nil
 }
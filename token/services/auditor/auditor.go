@@ -69,6 +69,7 @@ func (a *Auditor) Validate(request *token.Request) error {
 }
 
 // Audit extracts the list of inputs and outputs from the passed transaction.
+// Audit fails if any of the inputs or outputs is owned by a revoked enrollment ID.
 // In addition, the Audit locks the enrollment named ids.
 // Release must be invoked in case
 func (a *Auditor) Audit(tx Transaction) (*token.InputStream, *token.OutputStream, error) {
@@ -82,9 +83,10 @@ func (a *Auditor) Audit(tx Transaction) (*token.InputStream, *token.OutputStream
 		return nil, nil, errors.WithMessagef(err, "failed getting outputs")
 	}
 
-	var eids []string
-	eids = append(eids, inputs.EnrollmentIDs()...)
-	eids = append(eids, outputs.EnrollmentIDs()...)
+	eids, err := checkEnrollmentIDs(request.TokenService.PublicParametersManager(), request.Anchor, inputs, outputs)
+	if err != nil {
+		return nil, nil, err
+	}
 	if err := a.db.AcquireLocks(request.Anchor, eids...); err != nil {
 		return nil, nil, err
 	}
@@ -92,6 +94,18 @@ func (a *Auditor) Audit(tx Transaction) (*token.InputStream, *token.OutputStream
 	return inputs, outputs, nil
 }
 
+// checkEnrollmentIDs returns the enrollment IDs of the passed inputs and outputs of the request with the passed anchor.
+// It fails with an error wrapping token.ErrRevoked if any of them is revoked.
+func checkEnrollmentIDs(ppm *token.PublicParametersManager, anchor string, inputs *token.InputStream, outputs *token.OutputStream) ([]string, error) {
+	var eids []string
+	eids = append(eids, inputs.EnrollmentIDs()...)
+	eids = append(eids, outputs.EnrollmentIDs()...)
+	if err := ppm.CheckEnrollmentIDs(eids...); err != nil {
+		return nil, errors.WithMessagef(err, "request [%s] involves a revoked enrollment id", anchor)
+	}
+	return eids, nil
+}
+
 // Append adds the passed transaction to the auditor database.
 // It also releases the locks acquired by Audit.
 func (a *Auditor) Append(tx Transaction) error {
@@ -0,0 +1,83 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditor
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDriverName = "auditor-test"
+
+func init() {
+	core.Register(testDriverName, &testDriver{})
+}
+
+// testDriver loads public parameters that revoke mallory
+type testDriver struct {
+	driver.Driver
+}
+
+func (d *testDriver) PublicParametersFromBytes(params []byte) (driver.PublicParameters, error) {
+	return &testPublicParameters{revoked: []string{"mallory"}}, nil
+}
+
+func (d *testDriver) NewPublicParametersManager(pp driver.PublicParameters) (driver.PublicParamsManager, error) {
+	return &testPublicParamsManager{pp: pp}, nil
+}
+
+type testPublicParameters struct {
+	driver.PublicParameters
+	revoked []string
+}
+
+func (p *testPublicParameters) Identifier() string {
+	return testDriverName
+}
+
+func (p *testPublicParameters) RevokedEnrollmentIDs() []string {
+	return p.revoked
+}
+
+type testPublicParamsManager struct {
+	driver.PublicParamsManager
+	pp driver.PublicParameters
+}
+
+func (m *testPublicParamsManager) PublicParameters() driver.PublicParameters {
+	return m.pp
+}
+
+func TestCheckEnrollmentIDs(t *testing.T) {
+	raw, err := driver.Marshal(&driver.SerializedPublicParameters{Identifier: testDriverName})
+	assert.NoError(t, err)
+	ppm, err := token.NewPublicParametersManagerFromPublicParams(raw)
+	assert.NoError(t, err)
+
+	inputs := token.NewInputStream(nil, []*token.Input{{EnrollmentID: "alice"}}, 64)
+	outputs := token.NewOutputStream([]*token.Output{{EnrollmentID: "bob"}, {EnrollmentID: "alice"}}, 64)
+	eids, err := checkEnrollmentIDs(ppm, "tx1", inputs, outputs)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob", "alice"}, eids)
+
+	// a revoked enrollment id among the outputs
+	outputs = token.NewOutputStream([]*token.Output{{EnrollmentID: "bob"}, {EnrollmentID: "mallory"}}, 64)
+	_, err = checkEnrollmentIDs(ppm, "tx1", inputs, outputs)
+	assert.True(t, errors.Is(err, token.ErrRevoked))
+	assert.EqualError(t, err, "request [tx1] involves a revoked enrollment id: [mallory]: enrollment id revoked")
+
+	// a revoked enrollment id among the inputs
+	inputs = token.NewInputStream(nil, []*token.Input{{EnrollmentID: "mallory"}}, 64)
+	outputs = token.NewOutputStream([]*token.Output{{EnrollmentID: "bob"}}, 64)
+	_, err = checkEnrollmentIDs(ppm, "tx2", inputs, outputs)
+	assert.True(t, errors.Is(err, token.ErrRevoked))
+}
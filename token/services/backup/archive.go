@@ -0,0 +1,212 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// Version is the version of the archive format
+	Version = 1
+	// KDF is the key derivation function used to derive the encryption key from the passphrase
+	KDF = "scrypt"
+
+	// scrypt parameters, see https://pkg.go.dev/golang.org/x/crypto/scrypt
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	keyLength    = 32
+	saltLength   = 32
+	archiveLabel = "fabric-token-sdk/wallet-archive"
+)
+
+// Identity is a recipient identity of a wallet with its audit information and token metadata
+type Identity struct {
+	Identity      view.Identity
+	AuditInfo     []byte
+	TokenMetadata []byte
+}
+
+// Archive is the snapshot of an owner wallet
+type Archive struct {
+	// Version is the version of the archive format
+	Version int
+	// TMSID identifies the TMS the wallet belongs to. It is empty if the archive carries only the MSP folder.
+	TMSID token.TMSID
+	// WalletID is the identifier of the wallet
+	WalletID string
+	// EnrollmentID is the enrollment ID of the wallet
+	EnrollmentID string
+	// MSP contains the files of the MSP folder of the wallet indexed by their path relative to the folder
+	MSP map[string][]byte
+	// Identities are the recipient identities bound to the wallet
+	Identities []*Identity
+	// Transactions are the transaction records involving the wallet
+	Transactions []*ttxdb.TransactionRecord
+}
+
+// NewArchive returns a new empty archive for the passed wallet identifier
+func NewArchive(walletID string) *Archive {
+	return &Archive{
+		Version:  Version,
+		WalletID: walletID,
+		MSP:      map[string][]byte{},
+	}
+}
+
+// AddMSP adds to the archive the files found in the passed MSP folder
+func (a *Archive) AddMSP(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return errors.Errorf("[%s] is not a regular file", path)
+		}
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed reading [%s]", path)
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return errors.Wrapf(err, "failed computing relative path of [%s]", path)
+		}
+		a.MSP[filepath.ToSlash(rel)] = raw
+		return nil
+	})
+}
+
+// ExtractMSP writes the MSP files carried by the archive to the passed folder.
+// Files are readable only by the owner.
+func (a *Archive) ExtractMSP(dir string) error {
+	for rel, raw := range a.MSP {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if !strings.HasPrefix(path, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return errors.Errorf("invalid path [%s] in archive", rel)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return errors.Wrapf(err, "failed creating folder for [%s]", path)
+		}
+		if err := ioutil.WriteFile(path, raw, 0600); err != nil {
+			return errors.Wrapf(err, "failed writing [%s]", path)
+		}
+	}
+	return nil
+}
+
+// sealedArchive is the encrypted representation of an archive
+type sealedArchive struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Seal encrypts the archive under a key derived from the passed passphrase.
+// The archive is encrypted with AES-256-GCM, the key is derived with scrypt.
+func (a *Archive) Seal(passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase not specified")
+	}
+	plaintext, err := json.Marshal(a)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshalling archive")
+	}
+	sealed := &sealedArchive{
+		Version: Version,
+		KDF:     KDF,
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Salt:    make([]byte, saltLength),
+	}
+	if _, err := io.ReadFull(rand.Reader, sealed.Salt); err != nil {
+		return nil, errors.Wrap(err, "failed generating salt")
+	}
+	aead, err := sealed.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, sealed.Nonce); err != nil {
+		return nil, errors.Wrap(err, "failed generating nonce")
+	}
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, plaintext, []byte(archiveLabel))
+	return json.Marshal(sealed)
+}
+
+// OpenArchive decrypts and unmarshals an archive produced by Seal
+func OpenArchive(raw []byte, passphrase []byte) (*Archive, error) {
+	sealed := &sealedArchive{}
+	if err := json.Unmarshal(raw, sealed); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling sealed archive")
+	}
+	if sealed.Version != Version {
+		return nil, errors.Errorf("unsupported archive version [%d]", sealed.Version)
+	}
+	if sealed.KDF != KDF {
+		return nil, errors.Errorf("unsupported key derivation function [%s]", sealed.KDF)
+	}
+	aead, err := sealed.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return nil, errors.Errorf("invalid nonce length [%d]", len(sealed.Nonce))
+	}
+	plaintext, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, []byte(archiveLabel))
+	if err != nil {
+		return nil, errors.New("failed decrypting archive, wrong passphrase or corrupted archive")
+	}
+	archive := &Archive{}
+	if err := json.Unmarshal(plaintext, archive); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling archive")
+	}
+	if archive.Version != Version {
+		return nil, errors.Errorf("unsupported archive version [%d]", archive.Version)
+	}
+	return archive, nil
+}
+
+func (s *sealedArchive) aead(passphrase []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, s.Salt, s.N, s.R, s.P, keyLength)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed deriving key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating gcm")
+	}
+	return aead, nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backup
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealOpen(t *testing.T) {
+	archive := NewArchive("alice")
+	archive.TMSID = token.TMSID{Network: "n1", Channel: "c1", Namespace: "ns1"}
+	archive.EnrollmentID = "alice"
+	archive.Identities = []*Identity{{Identity: []byte("id1"), AuditInfo: []byte("ai1")}}
+	archive.Transactions = []*ttxdb.TransactionRecord{{
+		TxID:         "tx1",
+		ActionType:   ttxdb.Transfer,
+		SenderEID:    "bob",
+		RecipientEID: "alice",
+		TokenType:    "USD",
+		Amount:       big.NewInt(10),
+		Timestamp:    time.Now().UTC().Round(time.Second),
+		Status:       ttxdb.Confirmed,
+	}}
+
+	raw, err := archive.Seal([]byte("passphrase"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(raw), "alice")
+
+	opened, err := OpenArchive(raw, []byte("passphrase"))
+	assert.NoError(t, err)
+	assert.Equal(t, archive, opened)
+
+	_, err = OpenArchive(raw, []byte("wrong"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "wrong passphrase")
+
+	_, err = archive.Seal(nil)
+	assert.Error(t, err)
+}
+
+func TestMSP(t *testing.T) {
+	src, err := ioutil.TempDir("", "msp-src")
+	assert.NoError(t, err)
+	defer os.RemoveAll(src)
+	assert.NoError(t, os.MkdirAll(filepath.Join(src, "keystore"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(src, "keystore", "priv_sk"), []byte("secret"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(src, "config.yaml"), []byte("config"), 0600))
+
+	archive := NewArchive("alice")
+	assert.NoError(t, archive.AddMSP(src))
+	assert.Len(t, archive.MSP, 2)
+
+	dst, err := ioutil.TempDir("", "msp-dst")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dst)
+	assert.NoError(t, archive.ExtractMSP(dst))
+	raw, err := ioutil.ReadFile(filepath.Join(dst, "keystore", "priv_sk"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret"), raw)
+
+	// paths escaping the destination folder are rejected
+	archive.MSP["../evil"] = []byte("evil")
+	assert.Error(t, archive.ExtractMSP(dst))
+}
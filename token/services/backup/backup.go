@@ -0,0 +1,286 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backup
+
+import (
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/owner"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.backup")
+
+// Wallet models the owner wallet a backup is taken of
+type Wallet interface {
+	ID() string
+	EnrollmentID() string
+	ListIdentities() ([]view.Identity, error)
+	GetAuditInfo(id view.Identity) ([]byte, error)
+	GetTokenMetadata(id []byte) ([]byte, error)
+	RestoreIdentity(id view.Identity, auditInfo []byte, metadata []byte) error
+	ListUnspentTokensIterator(opts ...token.ListTokensOption) (*token.UnspentTokensIterator, error)
+}
+
+// WalletManager models the owner wallets of a TMS
+type WalletManager interface {
+	// OwnerWallet returns the owner wallet with the passed identifier, nil if not found
+	OwnerWallet(id string) Wallet
+	// RegisterOwnerWallet registers the owner wallet with the passed identifier and MSP folder
+	RegisterOwnerWallet(id string, path string) error
+	// OwnerWalletPath returns the MSP folder of the configured owner wallet with the passed identifier, if any
+	OwnerWalletPath(id string) string
+}
+
+// TransactionHistory models the transaction records of a TMS
+type TransactionHistory interface {
+	// Transactions returns the transaction records sent or received by the passed enrollment ID
+	Transactions(eID string) ([]*ttxdb.TransactionRecord, error)
+	// Restore appends the passed transaction records, skipping those of the known transactions
+	Restore(records ...*ttxdb.TransactionRecord) error
+}
+
+// Service exports and imports owner wallets of a given TMS.
+// An exported wallet carries its MSP folder, the recipient identities bound to it with their audit information
+// and token metadata, and its transaction history.
+type Service struct {
+	tmsID   token.TMSID
+	wallets WalletManager
+	history TransactionHistory
+}
+
+// NewService returns a new backup service for the passed TMS
+func NewService(sp view2.ServiceProvider, tms *token.ManagementService) *Service {
+	return &Service{
+		tmsID:   tms.ID(),
+		wallets: &walletManager{tms: tms},
+		history: &transactionHistory{sp: sp, tms: tms},
+	}
+}
+
+// Export snapshots the owner wallet with the passed identifier.
+// If mspPath is empty, the MSP folder of the wallet is taken from the configuration, if available.
+// The recipient identities that own unspent tokens of the wallet, but were bound to it before the wallet
+// kept track of its identities, are indexed first. The recipient identities without unspent tokens are exported
+// only if they have been bound to the wallet since then.
+func (s *Service) Export(walletID string, mspPath string) (*Archive, error) {
+	w := s.wallets.OwnerWallet(walletID)
+	if w == nil {
+		return nil, errors.Errorf("owner wallet [%s] not found", walletID)
+	}
+	archive := NewArchive(w.ID())
+	archive.TMSID = s.tmsID
+	archive.EnrollmentID = w.EnrollmentID()
+
+	// MSP folder
+	if len(mspPath) == 0 {
+		mspPath = s.wallets.OwnerWalletPath(walletID)
+	}
+	if len(mspPath) != 0 {
+		if err := archive.AddMSP(mspPath); err != nil {
+			return nil, errors.WithMessagef(err, "failed adding msp folder [%s] of wallet [%s]", mspPath, walletID)
+		}
+	} else {
+		logger.Warnf("msp folder of wallet [%s] not found, export the wallet state only", walletID)
+	}
+
+	// recipient identities
+	if err := s.backfill(w); err != nil {
+		return nil, err
+	}
+	ids, err := w.ListIdentities()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed listing identities of wallet [%s]", walletID)
+	}
+	for _, id := range ids {
+		auditInfo, err := w.GetAuditInfo(id)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed getting audit info for [%s]", id)
+		}
+		metadata, err := w.GetTokenMetadata(id)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed getting token metadata for [%s]", id)
+		}
+		archive.Identities = append(archive.Identities, &Identity{
+			Identity:      id,
+			AuditInfo:     auditInfo,
+			TokenMetadata: metadata,
+		})
+	}
+
+	// transaction history
+	archive.Transactions, err = s.history.Transactions(archive.EnrollmentID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting transactions of wallet [%s]", walletID)
+	}
+	logger.Debugf("exported wallet [%s] with [%d] identities and [%d] transaction records", walletID, len(archive.Identities), len(archive.Transactions))
+
+	return archive, nil
+}
+
+// backfill indexes the owners of the unspent tokens of the passed wallet that are not listed among its identities
+func (s *Service) backfill(w Wallet) error {
+	ids, err := w.ListIdentities()
+	if err != nil {
+		return errors.WithMessagef(err, "failed listing identities of wallet [%s]", w.ID())
+	}
+	known := map[string]bool{}
+	for _, id := range ids {
+		known[id.UniqueID()] = true
+	}
+	it, err := w.ListUnspentTokensIterator()
+	if err != nil {
+		return errors.WithMessagef(err, "failed listing unspent tokens of wallet [%s]", w.ID())
+	}
+	defer it.Close()
+	for {
+		tok, err := it.Next()
+		if err != nil {
+			return errors.WithMessagef(err, "failed getting next unspent token of wallet [%s]", w.ID())
+		}
+		if tok == nil {
+			return nil
+		}
+		if tok.Owner == nil || len(tok.Owner.Raw) == 0 {
+			continue
+		}
+		id := view.Identity(tok.Owner.Raw)
+		if known[id.UniqueID()] {
+			continue
+		}
+		auditInfo, err := w.GetAuditInfo(id)
+		if err != nil {
+			return errors.WithMessagef(err, "failed getting audit info for [%s]", id)
+		}
+		metadata, err := w.GetTokenMetadata(id)
+		if err != nil {
+			return errors.WithMessagef(err, "failed getting token metadata for [%s]", id)
+		}
+		if err := w.RestoreIdentity(id, auditInfo, metadata); err != nil {
+			return errors.WithMessagef(err, "failed indexing identity [%s] of wallet [%s]", id, w.ID())
+		}
+		known[id.UniqueID()] = true
+		logger.Debugf("identity [%s] of wallet [%s] indexed", id, w.ID())
+	}
+}
+
+// Import restores the wallet carried by the passed archive.
+// If the archive carries the MSP folder of the wallet, the folder is extracted to mspPath and the wallet is registered.
+// Importing the same archive twice has no further effect.
+func (s *Service) Import(archive *Archive, mspPath string) error {
+	if archive.Version != Version {
+		return errors.Errorf("unsupported archive version [%d]", archive.Version)
+	}
+	if archive.TMSID != (token.TMSID{}) && archive.TMSID != s.tmsID {
+		return errors.Errorf("archive belongs to tms [%s], expected [%s]", archive.TMSID, s.tmsID)
+	}
+
+	// MSP folder
+	if len(archive.MSP) != 0 {
+		if len(mspPath) == 0 {
+			return errors.Errorf("msp path not specified for wallet [%s]", archive.WalletID)
+		}
+		if err := archive.ExtractMSP(mspPath); err != nil {
+			return errors.WithMessagef(err, "failed extracting msp folder of wallet [%s]", archive.WalletID)
+		}
+		if s.wallets.OwnerWallet(archive.WalletID) == nil {
+			if err := s.wallets.RegisterOwnerWallet(archive.WalletID, mspPath); err != nil {
+				return errors.WithMessagef(err, "failed registering wallet [%s]", archive.WalletID)
+			}
+		}
+	}
+	w := s.wallets.OwnerWallet(archive.WalletID)
+	if w == nil {
+		return errors.Errorf("owner wallet [%s] not found", archive.WalletID)
+	}
+	if len(archive.EnrollmentID) != 0 && w.EnrollmentID() != archive.EnrollmentID {
+		return errors.Errorf("enrollment id mismatch for wallet [%s], expected [%s], got [%s]", archive.WalletID, archive.EnrollmentID, w.EnrollmentID())
+	}
+
+	// recipient identities
+	for _, id := range archive.Identities {
+		if err := w.RestoreIdentity(id.Identity, id.AuditInfo, id.TokenMetadata); err != nil {
+			return errors.WithMessagef(err, "failed restoring identity [%s] of wallet [%s]", id.Identity, archive.WalletID)
+		}
+	}
+
+	// transaction history
+	if len(archive.Transactions) != 0 {
+		if err := s.history.Restore(archive.Transactions...); err != nil {
+			return errors.WithMessagef(err, "failed restoring transactions of wallet [%s]", archive.WalletID)
+		}
+	}
+	logger.Debugf("imported wallet [%s] with [%d] identities and [%d] transaction records", archive.WalletID, len(archive.Identities), len(archive.Transactions))
+
+	return nil
+}
+
+// walletManager gives access to the owner wallets of a TMS
+type walletManager struct {
+	tms *token.ManagementService
+}
+
+func (m *walletManager) OwnerWallet(id string) Wallet {
+	w := m.tms.WalletManager().OwnerWallet(id)
+	if w == nil {
+		return nil
+	}
+	return w
+}
+
+func (m *walletManager) RegisterOwnerWallet(id string, path string) error {
+	return m.tms.WalletManager().RegisterOwnerWallet(id, path)
+}
+
+func (m *walletManager) OwnerWalletPath(id string) string {
+	return m.tms.ConfigManager().OwnerWalletPath(id)
+}
+
+// transactionHistory gives access to the transaction records in the owner db of a TMS
+type transactionHistory struct {
+	sp  view2.ServiceProvider
+	tms *token.ManagementService
+}
+
+func (h *transactionHistory) Transactions(eID string) ([]*ttxdb.TransactionRecord, error) {
+	o := owner.Get(h.sp, h.tms)
+	if o == nil {
+		return nil, errors.Errorf("failed getting owner db for [%s]", h.tms.ID())
+	}
+	qe := o.NewQueryExecutor()
+	defer qe.Done()
+	it, err := qe.Transactions(ttxdb.QueryTransactionsParams{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed querying transactions")
+	}
+	defer it.Close()
+	var records []*ttxdb.TransactionRecord
+	for {
+		tr, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed getting next transaction")
+		}
+		if tr == nil {
+			return records, nil
+		}
+		if tr.SenderEID != eID && tr.RecipientEID != eID {
+			continue
+		}
+		records = append(records, tr)
+	}
+}
+
+func (h *transactionHistory) Restore(records ...*ttxdb.TransactionRecord) error {
+	o := owner.Get(h.sp, h.tms)
+	if o == nil {
+		return errors.Errorf("failed getting owner db for [%s]", h.tms.ID())
+	}
+	return o.Restore(records...)
+}
@@ -0,0 +1,188 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backup
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var tmsID = token.TMSID{Network: "n1", Channel: "c1", Namespace: "ns1"}
+
+func TestExportImport(t *testing.T) {
+	src, err := ioutil.TempDir("", "msp-src")
+	assert.NoError(t, err)
+	defer os.RemoveAll(src)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(src, "priv_sk"), []byte("secret"), 0600))
+
+	// alice's wallet knows id1, and owns a token of id0, bound to the wallet before it kept track of its identities
+	alice := newFakeWallet()
+	alice.restore("id0", "ai0", "md0")
+	alice.restore("id1", "ai1", "md1")
+	alice.identities = alice.identities[1:]
+	alice.tokens = []*token2.UnspentToken{
+		{Owner: &token2.Owner{Raw: []byte("id0")}},
+		{Owner: &token2.Owner{Raw: []byte("id1")}},
+	}
+	exporter := &Service{
+		tmsID:   tmsID,
+		wallets: &fakeWalletManager{wallets: map[string]*fakeWallet{"alice": alice}, paths: map[string]string{"alice": src}},
+		history: &fakeHistory{records: []*ttxdb.TransactionRecord{
+			{TxID: "tx1", SenderEID: "bob", RecipientEID: "alice", TokenType: "USD", Amount: big.NewInt(10), Status: ttxdb.Confirmed, Timestamp: time.Unix(1, 0).UTC()},
+			{TxID: "tx2", SenderEID: "bob", RecipientEID: "charlie", TokenType: "USD", Amount: big.NewInt(5), Status: ttxdb.Confirmed, Timestamp: time.Unix(2, 0).UTC()},
+		}},
+	}
+	archive, err := exporter.Export("alice", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []view.Identity{view.Identity("id1"), view.Identity("id0")}, alice.identities, "the owners of the unspent tokens are indexed")
+	raw, err := archive.Seal([]byte("passphrase"))
+	assert.NoError(t, err)
+
+	// the archive is imported in a new node
+	dst, err := ioutil.TempDir("", "msp-dst")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dst)
+	wallets := &fakeWalletManager{wallets: map[string]*fakeWallet{}, paths: map[string]string{}}
+	history := &fakeHistory{}
+	importer := &Service{tmsID: tmsID, wallets: wallets, history: history}
+	opened, err := OpenArchive(raw, []byte("passphrase"))
+	assert.NoError(t, err)
+	assert.NoError(t, importer.Import(opened, dst))
+
+	restored := wallets.wallets["alice"]
+	assert.Equal(t, dst, wallets.paths["alice"])
+	content, err := ioutil.ReadFile(filepath.Join(dst, "priv_sk"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret"), content)
+	assert.ElementsMatch(t, []view.Identity{view.Identity("id0"), view.Identity("id1")}, restored.identities)
+	assert.Equal(t, []byte("ai0"), restored.auditInfos["id0"])
+	assert.Equal(t, []byte("md1"), restored.metadata["id1"])
+	assert.Len(t, history.records, 1)
+	assert.Equal(t, "tx1", history.records[0].TxID)
+	assert.Equal(t, ttxdb.Confirmed, history.records[0].Status)
+
+	// the archive of another tms or another version is rejected
+	opened.TMSID = token.TMSID{Network: "n2"}
+	assert.Error(t, importer.Import(opened, dst))
+	opened.TMSID = tmsID
+	opened.Version = Version + 1
+	assert.EqualError(t, importer.Import(opened, dst), "unsupported archive version [2]")
+
+	_, err = exporter.Export("bob", "")
+	assert.EqualError(t, err, "owner wallet [bob] not found")
+}
+
+type fakeWallet struct {
+	id         string
+	identities []view.Identity
+	auditInfos map[string][]byte
+	metadata   map[string][]byte
+	tokens     []*token2.UnspentToken
+}
+
+func newFakeWallet() *fakeWallet {
+	return &fakeWallet{id: "alice", auditInfos: map[string][]byte{}, metadata: map[string][]byte{}}
+}
+
+func (w *fakeWallet) restore(id, auditInfo, metadata string) {
+	_ = w.RestoreIdentity(view.Identity(id), []byte(auditInfo), []byte(metadata))
+}
+
+func (w *fakeWallet) ID() string { return w.id }
+
+func (w *fakeWallet) EnrollmentID() string { return w.id }
+
+func (w *fakeWallet) ListIdentities() ([]view.Identity, error) { return w.identities, nil }
+
+func (w *fakeWallet) GetAuditInfo(id view.Identity) ([]byte, error) {
+	auditInfo, ok := w.auditInfos[string(id)]
+	if !ok {
+		return nil, errors.Errorf("unknown identity [%s]", id)
+	}
+	return auditInfo, nil
+}
+
+func (w *fakeWallet) GetTokenMetadata(id []byte) ([]byte, error) { return w.metadata[string(id)], nil }
+
+func (w *fakeWallet) RestoreIdentity(id view.Identity, auditInfo []byte, metadata []byte) error {
+	w.identities = append(w.identities, id)
+	w.auditInfos[string(id)] = auditInfo
+	w.metadata[string(id)] = metadata
+	return nil
+}
+
+func (w *fakeWallet) ListUnspentTokensIterator(opts ...token.ListTokensOption) (*token.UnspentTokensIterator, error) {
+	return &token.UnspentTokensIterator{UnspentTokensIterator: &fakeIterator{tokens: w.tokens}}, nil
+}
+
+type fakeIterator struct {
+	tokens []*token2.UnspentToken
+}
+
+func (it *fakeIterator) Close() {}
+
+func (it *fakeIterator) Next() (*token2.UnspentToken, error) {
+	if len(it.tokens) == 0 {
+		return nil, nil
+	}
+	t := it.tokens[0]
+	it.tokens = it.tokens[1:]
+	return t, nil
+}
+
+type fakeWalletManager struct {
+	wallets map[string]*fakeWallet
+	paths   map[string]string
+}
+
+func (m *fakeWalletManager) OwnerWallet(id string) Wallet {
+	w, ok := m.wallets[id]
+	if !ok {
+		return nil
+	}
+	return w
+}
+
+func (m *fakeWalletManager) RegisterOwnerWallet(id string, path string) error {
+	w := newFakeWallet()
+	w.id = id
+	m.wallets[id] = w
+	m.paths[id] = path
+	return nil
+}
+
+func (m *fakeWalletManager) OwnerWalletPath(id string) string { return m.paths[id] }
+
+type fakeHistory struct {
+	records []*ttxdb.TransactionRecord
+}
+
+func (h *fakeHistory) Transactions(eID string) ([]*ttxdb.TransactionRecord, error) {
+	var records []*ttxdb.TransactionRecord
+	for _, r := range h.records {
+		if r.SenderEID == eID || r.RecipientEID == eID {
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+func (h *fakeHistory) Restore(records ...*ttxdb.TransactionRecord) error {
+	h.records = append(h.records, records...)
+	return nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backup
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+)
+
+// Manager handles the backup services of the TMSs
+type Manager struct {
+	sp       view.ServiceProvider
+	mutex    sync.Mutex
+	services map[string]*Service
+}
+
+// NewManager creates a new backup manager.
+func NewManager(sp view.ServiceProvider) *Manager {
+	return &Manager{
+		sp:       sp,
+		services: map[string]*Service{},
+	}
+}
+
+// Service returns the backup service for the given TMS
+func (m *Manager) Service(tms *token.ManagementService) *Service {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	id := tms.ID().String()
+	s, ok := m.services[id]
+	if !ok {
+		s = NewService(m.sp, tms)
+		m.services[id] = s
+	}
+	return s
+}
+
+var (
+	managerType = reflect.TypeOf((*Manager)(nil))
+)
+
+// Get returns the backup service for the passed TMS
+func Get(sp view.ServiceProvider, tms *token.ManagementService) *Service {
+	if tms == nil {
+		logger.Debugf("no TMS provided")
+		return nil
+	}
+	s, err := sp.GetService(managerType)
+	if err != nil {
+		logger.Errorf("failed to get manager service: [%s]", err)
+		return nil
+	}
+	return s.(*Manager).Service(tms)
+}
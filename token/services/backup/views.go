@@ -0,0 +1,128 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backup
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/pkg/errors"
+)
+
+// Export contains the input of the ExportView
+type Export struct {
+	// TMSID identifies the TMS of the wallet
+	TMSID token.TMSID
+	// WalletID is the identifier of the owner wallet to export
+	WalletID string
+	// MSPPath is the MSP folder of the wallet. If empty, it is taken from the configuration, if available.
+	MSPPath string
+	// Passphrase protects the archive
+	Passphrase []byte
+}
+
+// ExportView exports an owner wallet of this node to an encrypted archive, that the view returns
+type ExportView struct {
+	*Export
+}
+
+// NewExportView returns a new ExportView for the passed input
+func NewExportView(export *Export) *ExportView {
+	return &ExportView{Export: export}
+}
+
+func (e *ExportView) Call(context view.Context) (interface{}, error) {
+	s, err := service(context, e.TMSID)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := s.Export(e.WalletID, e.MSPPath)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed exporting wallet [%s]", e.WalletID)
+	}
+	raw, err := archive.Seal(e.Passphrase)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed sealing archive of wallet [%s]", e.WalletID)
+	}
+	return raw, nil
+}
+
+// ExportViewFactory creates ExportViews from their JSON encoded input.
+// The archive carries the secret keys of the wallet, then the application registers the factory only if the
+// clients allowed to invoke views on the node may export them.
+type ExportViewFactory struct{}
+
+func (f *ExportViewFactory) NewView(in []byte) (view.View, error) {
+	v := &ExportView{Export: &Export{}}
+	if err := json.Unmarshal(in, v.Export); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling input")
+	}
+	return v, nil
+}
+
+// Import contains the input of the ImportView
+type Import struct {
+	// TMSID identifies the TMS of the wallet
+	TMSID token.TMSID
+	// Archive is the encrypted archive, produced by the ExportView or by `tokengen wallet export`
+	Archive []byte
+	// Passphrase protects the archive
+	Passphrase []byte
+	// MSPPath is the folder where to extract the MSP folder carried by the archive, if any
+	MSPPath string
+}
+
+// ImportView restores, in this node, the owner wallet carried by an encrypted archive.
+// The view returns the identifier of the wallet.
+type ImportView struct {
+	*Import
+}
+
+// NewImportView returns a new ImportView for the passed input
+func NewImportView(in *Import) *ImportView {
+	return &ImportView{Import: in}
+}
+
+func (i *ImportView) Call(context view.Context) (interface{}, error) {
+	s, err := service(context, i.TMSID)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := OpenArchive(i.Archive, i.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Import(archive, i.MSPPath); err != nil {
+		return nil, errors.WithMessagef(err, "failed importing wallet [%s]", archive.WalletID)
+	}
+	return archive.WalletID, nil
+}
+
+// ImportViewFactory creates ImportViews from their JSON encoded input
+type ImportViewFactory struct{}
+
+func (f *ImportViewFactory) NewView(in []byte) (view.View, error) {
+	v := &ImportView{Import: &Import{}}
+	if err := json.Unmarshal(in, v.Import); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling input")
+	}
+	return v, nil
+}
+
+// service returns the backup service of the TMS with the passed id
+func service(context view.Context, tmsID token.TMSID) (*Service, error) {
+	tms := token.GetManagementService(context, token.WithTMSID(tmsID))
+	if tms == nil {
+		return nil, errors.Errorf("tms [%s] not found", tmsID)
+	}
+	s := Get(context, tms)
+	if s == nil {
+		return nil, errors.Errorf("backup service for [%s] not found", tmsID)
+	}
+	return s, nil
+}
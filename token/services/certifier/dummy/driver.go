@@ -14,8 +14,9 @@ import (
 )
 
 const (
-	ZKATDLog = "zkatdlog"
-	FabToken = "fabtoken"
+	ZKATDLog            = "zkatdlog"
+	ZKATDLogGraphHiding = "zkatdloggh"
+	FabToken            = "fabtoken"
 )
 
 type CertificationClient struct{}
@@ -55,4 +56,5 @@ func (d *Driver) NewCertificationService(sp view2.ServiceProvider, network, chan
 func init() {
 	certifier.Register(FabToken, &Driver{})
 	certifier.Register(ZKATDLog, &Driver{})
+	certifier.Register(ZKATDLogGraphHiding, &Driver{})
 }
@@ -46,7 +46,33 @@ type Resolver interface {
 	ResolveIdentities(endpoints ...string) []view.Identity
 }
 
-// CertificationClient scans the vault for tokens not yet certified and asks the certification.
+const (
+	// DefaultMaxQueueSize is the default maximum number of pending certification requests
+	DefaultMaxQueueSize = 100
+	// DefaultBatchSize is the default maximum number of tokens certified in a single interaction with a certifier
+	DefaultBatchSize = 50
+	// DefaultTimeout is the default maximum time a caller waits for the completion of its certification request
+	DefaultTimeout = time.Minute
+)
+
+var (
+	// ErrQueueFull is returned when the certification request queue is full
+	ErrQueueFull = errors.New("certification request queue is full")
+	// ErrTimeout is returned when a certification request is not completed in time
+	ErrTimeout = errors.New("certification request timed out")
+)
+
+// certificationRequest is a pending request of certification
+type certificationRequest struct {
+	ids  []*token.ID
+	done chan error
+}
+
+// CertificationClient requests the certification of tokens on demand.
+// Requests are processed by a background routine that batches them together.
+// The number of pending requests is bounded. When the bound is reached, new requests fail fast with ErrQueueFull,
+// giving backpressure to the caller (for example, the token selector that will retry later).
+// Optionally, the vault can be scanned for tokens not yet certified (see Scan).
 type CertificationClient struct {
 	ctx                  context.Context
 	channel, namespace   string
@@ -57,6 +83,11 @@ type CertificationClient struct {
 	certifiers           []view2.Identity
 	// waitTime is used in case of a failure. It tells how much time to wait before retrying.
 	waitTime time.Duration
+	// requests is the bounded queue of pending certification requests
+	requests  chan *certificationRequest
+	batchSize int
+	// timeout bounds the time a caller waits for the completion of its request
+	timeout time.Duration
 }
 
 func NewCertificationClient(
@@ -68,7 +99,19 @@ func NewCertificationClient(
 	cm CertificationStorage,
 	fm ViewManager,
 	certifiers []view2.Identity,
+	maxQueueSize int,
+	batchSize int,
+	timeout time.Duration,
 ) *CertificationClient {
+	if maxQueueSize <= 0 {
+		maxQueueSize = DefaultMaxQueueSize
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
 	return &CertificationClient{
 		ctx:                  ctx,
 		channel:              channel,
@@ -79,6 +122,9 @@ func NewCertificationClient(
 		viewManager:          fm,
 		certifiers:           certifiers,
 		waitTime:             10 * time.Second,
+		requests:             make(chan *certificationRequest, maxQueueSize),
+		batchSize:            batchSize,
+		timeout:              timeout,
 	}
 }
 
@@ -86,18 +132,93 @@ func (d *CertificationClient) IsCertified(id *token.ID) bool {
 	return d.certificationStorage.Exists(id)
 }
 
+// RequestCertification enqueues a request of certification for the passed token ids that are not yet certified
+// and waits for its completion.
+// If the queue of pending requests is full, RequestCertification returns ErrQueueFull immediately.
+// If the request is not completed within the timeout, RequestCertification returns ErrTimeout.
+// The request is still served, the caller can check later if the tokens got certified.
 func (d *CertificationClient) RequestCertification(ids ...*token.ID) error {
-	var toBeCertified []*token.ID
-	for _, id := range ids {
-		if !d.IsCertified(id) {
-			toBeCertified = append(toBeCertified, id)
+	toBeCertified := d.notCertified(ids)
+	if len(toBeCertified) == 0 {
+		// all tokens already certified.
+		return nil
+	}
+
+	request := &certificationRequest{ids: toBeCertified, done: make(chan error, 1)}
+	select {
+	case d.requests <- request:
+	default:
+		return errors.WithMessagef(ErrQueueFull, "cannot request certification of [%v]", toBeCertified)
+	}
+
+	timeout := time.NewTimer(d.timeout)
+	defer timeout.Stop()
+	select {
+	case err := <-request.done:
+		return err
+	case <-timeout.C:
+		return errors.WithMessagef(ErrTimeout, "cannot certify [%v] within [%s]", toBeCertified, d.timeout)
+	case <-d.ctx.Done():
+		return errors.Errorf("certification client stopped")
+	}
+}
+
+// Start starts the routine that processes the certification requests
+func (d *CertificationClient) Start() error {
+	go d.processRequests()
+	return nil
+}
+
+// processRequests serves the queue of certification requests until the context is done.
+// Pending requests are batched together up to batchSize tokens.
+func (d *CertificationClient) processRequests() {
+	for {
+		var request *certificationRequest
+		select {
+		case <-d.ctx.Done():
+			return
+		case request = <-d.requests:
+		}
+
+		batch := []*certificationRequest{request}
+		numIDs := len(request.ids)
+	Batching:
+		for numIDs < d.batchSize {
+			select {
+			case request = <-d.requests:
+				batch = append(batch, request)
+				numIDs += len(request.ids)
+			default:
+				break Batching
+			}
+		}
+
+		err := d.certify(batch)
+		for _, request := range batch {
+			request.done <- err
+		}
+	}
+}
+
+// certify requests the certification of the tokens in the passed batch that are still not certified
+func (d *CertificationClient) certify(batch []*certificationRequest) error {
+	var ids []*token.ID
+	selected := map[token.ID]bool{}
+	for _, request := range batch {
+		for _, id := range request.ids {
+			if selected[*id] {
+				continue
+			}
+			selected[*id] = true
+			ids = append(ids, id)
 		}
 	}
+	toBeCertified := d.notCertified(ids)
 	if len(toBeCertified) == 0 {
-		// all tokens already certified.
 		return nil
 	}
 
+	logger.Debugf("request certification of [%v]", toBeCertified)
 	resultBoxed, err := d.viewManager.InitiateView(NewCertificationRequestView(d.channel, d.namespace, d.certifiers[0], toBeCertified...))
 	if err != nil {
 		return err
@@ -109,14 +230,22 @@ func (d *CertificationClient) RequestCertification(ids ...*token.ID) error {
 	if err := d.certificationStorage.Store(certifications); err != nil {
 		return err
 	}
+	logger.Debugf("request certification of [%v] satisfied with no error", toBeCertified)
 	return nil
 }
 
-func (d *CertificationClient) Start() error {
-	go d.Scan()
-	return nil
+func (d *CertificationClient) notCertified(ids []*token.ID) []*token.ID {
+	var toBeCertified []*token.ID
+	for _, id := range ids {
+		if !d.IsCertified(id) {
+			toBeCertified = append(toBeCertified, id)
+		}
+	}
+	return toBeCertified
 }
 
+// Scan checks periodically the certification of all unspent tokens in the vault.
+// Scan is not started by default, tokens are certified on demand when selected.
 func (d *CertificationClient) Scan() {
 	var lastTXID string
 	var tokens driver.UnspentTokensIterator
@@ -0,0 +1,122 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package interactive
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+type fakeStorage struct {
+	lock      sync.Mutex
+	certified map[token.ID][]byte
+}
+
+func (f *fakeStorage) Exists(id *token.ID) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	_, ok := f.certified[*id]
+	return ok
+}
+
+func (f *fakeStorage) Store(certifications map[*token.ID][]byte) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for id, certification := range certifications {
+		f.certified[*id] = certification
+	}
+	return nil
+}
+
+type fakeViewManager struct {
+	lock  sync.Mutex
+	calls [][]*token.ID
+}
+
+func (f *fakeViewManager) InitiateView(v view.View) (interface{}, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	crv, ok := v.(*CertificationRequestView)
+	if !ok {
+		return nil, errors.Errorf("unexpected view [%T]", v)
+	}
+	f.calls = append(f.calls, crv.ids)
+	result := map[*token.ID][]byte{}
+	for _, id := range crv.ids {
+		result[id] = []byte("certified")
+	}
+	return result, nil
+}
+
+func newTestClient(ctx context.Context, maxQueueSize, batchSize int) (*CertificationClient, *fakeStorage, *fakeViewManager) {
+	storage := &fakeStorage{certified: map[token.ID][]byte{}}
+	vm := &fakeViewManager{}
+	c := NewCertificationClient(ctx, "channel", "namespace", nil, nil, storage, vm, []view2.Identity{[]byte("certifier")}, maxQueueSize, batchSize, 0)
+	return c, storage, vm
+}
+
+func TestRequestCertification(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, storage, vm := newTestClient(ctx, 10, 10)
+	assert.NoError(t, c.Start())
+
+	ids := []*token.ID{{TxId: "a", Index: 0}, {TxId: "b", Index: 1}}
+	assert.NoError(t, c.RequestCertification(ids...))
+	assert.True(t, storage.Exists(ids[0]))
+	assert.True(t, c.IsCertified(ids[1]))
+
+	// already certified tokens are not requested again
+	assert.NoError(t, c.RequestCertification(ids...))
+	assert.Len(t, vm.calls, 1)
+}
+
+func TestRequestCertificationQueueFull(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the client is not started, therefore the queue is never drained
+	c, _, _ := newTestClient(ctx, 1, 10)
+
+	c.requests <- &certificationRequest{ids: []*token.ID{{TxId: "a", Index: 0}}, done: make(chan error, 1)}
+	err := c.RequestCertification(&token.ID{TxId: "b", Index: 0})
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrQueueFull))
+}
+
+func TestRequestCertificationTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the client is not started, therefore the request is never served
+	c, _, _ := newTestClient(ctx, 10, 10)
+	c.timeout = 10 * time.Millisecond
+
+	err := c.RequestCertification(&token.ID{TxId: "a", Index: 0})
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrTimeout))
+}
+
+func TestCertifyBatch(t *testing.T) {
+	c, _, vm := newTestClient(context.Background(), 10, 10)
+
+	id := &token.ID{TxId: "a", Index: 0}
+	batch := []*certificationRequest{
+		{ids: []*token.ID{id}},
+		{ids: []*token.ID{{TxId: "a", Index: 0}, {TxId: "b", Index: 0}}},
+	}
+	assert.NoError(t, c.certify(batch))
+	assert.Len(t, vm.calls, 1)
+	assert.Len(t, vm.calls[0], 2)
+}
@@ -9,6 +9,7 @@ package interactive
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -63,6 +64,14 @@ func (d *Driver) NewCertificationClient(sp view2.ServiceProvider, networkID, cha
 			return nil, errors.Errorf("no certifier id configured")
 		}
 
+		var maxQueueSize, batchSize int
+		var timeout time.Duration
+		if c := tms.ConfigManager().InteractiveCertification(); c != nil {
+			maxQueueSize = c.MaxQueueSize
+			batchSize = c.BatchSize
+			timeout = c.Timeout
+		}
+
 		inst := NewCertificationClient(
 			context.Background(),
 			channel,
@@ -72,6 +81,9 @@ func (d *Driver) NewCertificationClient(sp view2.ServiceProvider, networkID, cha
 			v,
 			view2.GetManager(sp),
 			certifiers,
+			maxQueueSize,
+			batchSize,
+			timeout,
 		)
 		inst.Start()
 
@@ -0,0 +1,85 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package eventstream
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/events"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/processor"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/owner"
+	"github.com/pkg/errors"
+)
+
+// Manager handles the streams of the TMSs
+type Manager struct {
+	sp      view.ServiceProvider
+	kvs     KVS
+	mutex   sync.Mutex
+	streams map[string]*Stream
+}
+
+// NewManager creates a new stream manager.
+func NewManager(sp view.ServiceProvider, kvs KVS) *Manager {
+	return &Manager{
+		sp:      sp,
+		kvs:     kvs,
+		streams: map[string]*Stream{},
+	}
+}
+
+// Stream returns the stream for the given TMS.
+// The stream records the events of the TMS from the first time it is requested.
+func (m *Manager) Stream(tms *token.ManagementService) (*Stream, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	id := tms.ID().String()
+	s, ok := m.streams[id]
+	if ok {
+		return s, nil
+	}
+	s, err := NewStream(tms.ID(), m.kvs, tms.PublicParametersManager().Precision)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to instantiate stream for [%s]", tms.ID())
+	}
+	subscriber, err := events.GetSubscriber(m.sp)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get event subscriber")
+	}
+	for _, topic := range []string{processor.AddToken, processor.DeleteToken, owner.TxStatusTopic} {
+		subscriber.Subscribe(topic, s)
+	}
+	m.streams[id] = s
+	return s, nil
+}
+
+var (
+	managerType = reflect.TypeOf((*Manager)(nil))
+)
+
+// Get returns the Stream instance for the passed TMS
+func Get(sp view.ServiceProvider, tms *token.ManagementService) *Stream {
+	if tms == nil {
+		logger.Debugf("no TMS provided")
+		return nil
+	}
+	s, err := sp.GetService(managerType)
+	if err != nil {
+		logger.Errorf("failed to get manager service: [%s]", err)
+		return nil
+	}
+	stream, err := s.(*Manager).Stream(tms)
+	if err != nil {
+		logger.Errorf("failed to get stream for TMS [%s]: [%s]", tms.ID(), err)
+		return nil
+	}
+	return stream
+}
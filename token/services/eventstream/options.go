@@ -0,0 +1,46 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package eventstream
+
+// SubscribeOptions is used to configure a subscription
+type SubscribeOptions struct {
+	// WalletID restricts the delivery to the events of the wallet with this identifier, if not empty
+	WalletID string
+	// Cursor is the sequence number of the last event already processed, if set.
+	// The delivery starts from the event following it.
+	Cursor *uint64
+}
+
+// SubscribeOption is a function that configures a SubscribeOptions
+type SubscribeOption func(*SubscribeOptions) error
+
+func compileSubscribeOptions(opts ...SubscribeOption) (*SubscribeOptions, error) {
+	options := &SubscribeOptions{}
+	for _, opt := range opts {
+		if err := opt(options); err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
+// WithWallet restricts the delivery to the events of the passed wallet
+func WithWallet(walletID string) SubscribeOption {
+	return func(o *SubscribeOptions) error {
+		o.WalletID = walletID
+		return nil
+	}
+}
+
+// WithCursor replays the events following the passed sequence number.
+// Pass 0 to replay the stream from the beginning.
+func WithCursor(cursor uint64) SubscribeOption {
+	return func(o *SubscribeOptions) error {
+		o.Cursor = &cursor
+		return nil
+	}
+}
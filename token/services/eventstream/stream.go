@@ -0,0 +1,617 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package eventstream
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/events"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/processor"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/owner"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.eventstream")
+
+const (
+	eventPrefix  = "eventstream.event"
+	headPrefix   = "eventstream.head"
+	prunedPrefix = "eventstream.pruned"
+	txPrefix     = "eventstream.tx"
+)
+
+// retryDelay is the time waited before delivering again an event a listener failed to process,
+// and before appending again a notification the stream failed to persist
+var retryDelay = time.Second
+
+// EventType is the type of the events of a stream
+type EventType string
+
+const (
+	// TokenReceived is the type of the events reporting that a wallet received a token
+	TokenReceived EventType = "TokenReceived"
+	// TokenSpent is the type of the events reporting that a token owned by a wallet has been spent
+	TokenSpent EventType = "TokenSpent"
+	// BalanceChanged is the type of the events reporting the change of the balance of a wallet for a token type
+	BalanceChanged EventType = "BalanceChanged"
+	// TransactionConfirmed is the type of the events reporting that a transaction has been committed
+	TransactionConfirmed EventType = "TransactionConfirmed"
+	// TransactionDeleted is the type of the events reporting that a transaction failed to commit
+	TransactionDeleted EventType = "TransactionDeleted"
+)
+
+// Event is an event of a stream
+type Event struct {
+	// Sequence is the position of the event in the stream, starting from 1.
+	// It is the cursor to pass to resume the delivery after this event.
+	Sequence uint64
+	Type     EventType
+	TMSID    token.TMSID
+	// TxID is the transaction that caused the event
+	TxID string
+	// WalletID is the wallet the token events and the balance events refer to
+	WalletID string
+	// WalletIDs are the wallets of this node whose tokens have been received or spent by the transaction
+	// the transaction events refer to. They are empty if the transaction did not commit.
+	WalletIDs []string
+	// TokenID is the token the token events refer to
+	TokenID   *token2.ID
+	TokenType string
+	// Quantity is the quantity of the token, in decimal.
+	// For the balance events, it is the signed change of the balance.
+	Quantity  string
+	Timestamp time.Time
+}
+
+// Listener is notified of the events of a stream
+type Listener interface {
+	// OnEvent is called once for each event, in the order of the stream.
+	// If it returns an error, the event is delivered again.
+	OnEvent(event *Event) error
+}
+
+// KVS models the key-value store the events are persisted in
+type KVS interface {
+	Exists(id string) bool
+	Put(id string, state interface{}) error
+	Get(id string, state interface{}) error
+	Delete(id string) error
+}
+
+// Stream is the ordered and persistent sequence of the events of the wallets of a TMS
+type Stream struct {
+	tmsID     token.TMSID
+	kvs       KVS
+	precision func() uint64
+	now       func() time.Time
+
+	lock sync.RWMutex
+	head uint64
+	// pruned is the sequence number of the last event removed by the retention, 0 if none
+	pruned        uint64
+	retention     config.EventStream
+	subscriptions map[*Subscription]struct{}
+
+	// queue holds the notifications waiting to be appended, in the order they have been received
+	queueLock sync.Mutex
+	queue     []events.Event
+	wake      chan struct{}
+	// pending counts the notifications not appended yet
+	pending sync.WaitGroup
+}
+
+// NewStream returns the stream of the passed TMS, persisted in the passed key-value store.
+// The precision function returns the precision of the token quantities.
+// The stream retains all its events until a retention is set.
+func NewStream(tmsID token.TMSID, kvs KVS, precision func() uint64) (*Stream, error) {
+	s := &Stream{
+		tmsID:         tmsID,
+		kvs:           kvs,
+		precision:     precision,
+		now:           time.Now,
+		subscriptions: map[*Subscription]struct{}{},
+		wake:          make(chan struct{}, 1),
+	}
+	k, err := s.headKey()
+	if err != nil {
+		return nil, err
+	}
+	if kvs.Exists(k) {
+		if err := kvs.Get(k, &s.head); err != nil {
+			return nil, errors.WithMessagef(err, "failed loading the head of the stream [%s]", tmsID)
+		}
+	}
+	k, err = s.prunedKey()
+	if err != nil {
+		return nil, err
+	}
+	if kvs.Exists(k) {
+		if err := kvs.Get(k, &s.pruned); err != nil {
+			return nil, errors.WithMessagef(err, "failed loading the pruned events of the stream [%s]", tmsID)
+		}
+	}
+	go s.run()
+	return s, nil
+}
+
+// SetRetention bounds the number and the age of the events retained by the stream.
+// The oldest events are removed as new ones are appended, but those the subscriptions have not processed yet.
+func (s *Stream) SetRetention(c *config.EventStream) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.retention = config.EventStream{}
+	if c != nil {
+		s.retention = *c
+	}
+}
+
+// Head returns the sequence number of the last event of the stream, 0 if the stream is empty
+func (s *Stream) Head() uint64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.head
+}
+
+// Pruned returns the sequence number of the last event removed by the retention, 0 if none.
+// The oldest event retained by the stream follows it.
+func (s *Stream) Pruned() uint64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.pruned
+}
+
+// Event returns the event with the passed sequence number
+func (s *Stream) Event(sequence uint64) (*Event, error) {
+	if pruned := s.Pruned(); sequence <= pruned {
+		return nil, errors.Errorf("event [%d] of the stream [%s] has been pruned, the oldest event is [%d]", sequence, s.tmsID, pruned+1)
+	}
+	return s.event(sequence)
+}
+
+func (s *Stream) event(sequence uint64) (*Event, error) {
+	k, err := s.eventKey(sequence)
+	if err != nil {
+		return nil, err
+	}
+	event := &Event{}
+	if err := s.kvs.Get(k, event); err != nil {
+		return nil, errors.WithMessagef(err, "failed loading event [%d] of the stream [%s]", sequence, s.tmsID)
+	}
+	return event, nil
+}
+
+// Subscribe starts the delivery of the events of the stream to the passed listener.
+// By default, only the events appended after the subscription are delivered, and the events of all the wallets.
+func (s *Stream) Subscribe(listener Listener, opts ...SubscribeOption) (*Subscription, error) {
+	options, err := compileSubscribeOptions(opts...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed compiling options")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	cursor := s.head
+	if options.Cursor != nil {
+		cursor = *options.Cursor
+	}
+	if cursor > s.head {
+		return nil, errors.Errorf("cursor [%d] is ahead of the stream [%d]", cursor, s.head)
+	}
+	if cursor < s.pruned {
+		return nil, errors.Errorf("cursor [%d] is behind the stream, the events up to [%d] have been pruned", cursor, s.pruned)
+	}
+	sub := &Subscription{
+		stream:   s,
+		listener: listener,
+		walletID: options.WalletID,
+		cursor:   cursor,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	s.subscriptions[sub] = struct{}{}
+	go sub.run()
+	return sub, nil
+}
+
+// OnReceive queues the token and transaction notifications of this node, without blocking the notifier.
+// The events derived from them are appended to the stream in the order the notifications are received.
+func (s *Stream) OnReceive(event events.Event) {
+	s.pending.Add(1)
+	s.queueLock.Lock()
+	s.queue = append(s.queue, event)
+	s.queueLock.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run appends the queued notifications to the stream, one after the other
+func (s *Stream) run() {
+	for range s.wake {
+		for {
+			s.queueLock.Lock()
+			if len(s.queue) == 0 {
+				s.queueLock.Unlock()
+				break
+			}
+			event := s.queue[0]
+			s.queue[0] = nil
+			s.queue = s.queue[1:]
+			s.queueLock.Unlock()
+
+			s.receive(event)
+			s.pending.Done()
+		}
+	}
+}
+
+// receive appends the events derived from the passed notification. It returns only once the events are persisted,
+// retrying until then, so that no notification is lost. The following notifications wait in the queue meanwhile.
+func (s *Stream) receive(event events.Event) {
+	for {
+		var err error
+		switch msg := event.Message().(type) {
+		case processor.TokenMessage:
+			err = s.onToken(event.Topic(), &msg)
+		case owner.TxStatusMessage:
+			err = s.onTxStatus(&msg)
+		}
+		if err == nil {
+			return
+		}
+		logger.Errorf("failed appending [%s] event to the stream [%s], retrying: [%s]", event.Topic(), s.tmsID, err)
+		time.Sleep(retryDelay)
+	}
+}
+
+func (s *Stream) onToken(topic string, msg *processor.TokenMessage) error {
+	if msg.Namespace != s.tmsID.Namespace {
+		return nil
+	}
+	eventType, txID, sign := TokenReceived, msg.TxID, ""
+	switch topic {
+	case processor.AddToken:
+	case processor.DeleteToken:
+		eventType, txID, sign = TokenSpent, msg.DeletedBy, "-"
+	default:
+		return nil
+	}
+	q, err := token2.ToQuantity(msg.Quantity, s.precision())
+	if err != nil {
+		// retrying does not help, the notification is dropped
+		logger.Errorf("invalid quantity [%s] for token [%s:%d], skipping: [%s]", msg.Quantity, msg.TxID, msg.Index, err)
+		return nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.trackWallet(txID, msg.WalletID); err != nil {
+		return err
+	}
+	tokenID := &token2.ID{TxId: msg.TxID, Index: msg.Index}
+	return s.append(
+		&Event{
+			Type:      eventType,
+			TxID:      txID,
+			WalletID:  msg.WalletID,
+			TokenID:   tokenID,
+			TokenType: msg.TokenType,
+			Quantity:  q.Decimal(),
+		},
+		&Event{
+			Type:      BalanceChanged,
+			TxID:      txID,
+			WalletID:  msg.WalletID,
+			TokenID:   tokenID,
+			TokenType: msg.TokenType,
+			Quantity:  sign + q.Decimal(),
+		},
+	)
+}
+
+func (s *Stream) onTxStatus(msg *owner.TxStatusMessage) error {
+	if msg.TMSID != s.tmsID {
+		return nil
+	}
+	var eventType EventType
+	switch msg.Status {
+	case owner.Confirmed:
+		eventType = TransactionConfirmed
+	case owner.Deleted:
+		eventType = TransactionDeleted
+	default:
+		return nil
+	}
+	k, err := s.txKey(msg.TxID)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var walletIDs []string
+	if s.kvs.Exists(k) {
+		if err := s.kvs.Get(k, &walletIDs); err != nil {
+			return errors.WithMessagef(err, "failed loading the wallets of [%s]", msg.TxID)
+		}
+	}
+	if err := s.append(&Event{
+		Type:      eventType,
+		TxID:      msg.TxID,
+		WalletIDs: walletIDs,
+	}); err != nil {
+		return err
+	}
+	if len(walletIDs) != 0 {
+		// the event is already in the stream, a retry would append it twice
+		if err := s.kvs.Delete(k); err != nil {
+			logger.Warnf("failed removing the wallets of [%s]: [%s]", msg.TxID, err)
+		}
+	}
+	return nil
+}
+
+// trackWallet records that the passed transaction touched the passed wallet.
+// The caller must hold the lock of the stream.
+func (s *Stream) trackWallet(txID, walletID string) error {
+	k, err := s.txKey(txID)
+	if err != nil {
+		return err
+	}
+	var walletIDs []string
+	if s.kvs.Exists(k) {
+		if err := s.kvs.Get(k, &walletIDs); err != nil {
+			return errors.WithMessagef(err, "failed loading the wallets of [%s]", txID)
+		}
+	}
+	for _, id := range walletIDs {
+		if id == walletID {
+			return nil
+		}
+	}
+	if err := s.kvs.Put(k, append(walletIDs, walletID)); err != nil {
+		return errors.WithMessagef(err, "failed storing the wallets of [%s]", txID)
+	}
+	return nil
+}
+
+// append persists the passed events, assigning them the next sequence numbers, and wakes up the subscriptions.
+// Then, it prunes the events beyond the retention.
+// The caller must hold the lock of the stream.
+func (s *Stream) append(events ...*Event) error {
+	head := s.head
+	now := s.now()
+	for _, event := range events {
+		head++
+		event.Sequence = head
+		event.TMSID = s.tmsID
+		event.Timestamp = now
+		k, err := s.eventKey(head)
+		if err != nil {
+			return err
+		}
+		if err := s.kvs.Put(k, event); err != nil {
+			return errors.WithMessagef(err, "failed storing event [%d]", head)
+		}
+	}
+	k, err := s.headKey()
+	if err != nil {
+		return err
+	}
+	if err := s.kvs.Put(k, head); err != nil {
+		return errors.WithMessagef(err, "failed storing the head of the stream")
+	}
+	s.head = head
+	logger.Debugf("stream [%s] moved to [%d]", s.tmsID, head)
+
+	for sub := range s.subscriptions {
+		sub.notify()
+	}
+
+	// the events are appended already, a failure must not cause a retry
+	if err := s.prune(); err != nil {
+		logger.Warnf("failed pruning the stream [%s]: [%s]", s.tmsID, err)
+	}
+	return nil
+}
+
+// prune removes the events beyond the number and the age retained by the stream,
+// but those the subscriptions have not processed yet.
+// The caller must hold the lock of the stream.
+func (s *Stream) prune() error {
+	if s.retention.MaxEvents == 0 && s.retention.MaxAge == 0 {
+		return nil
+	}
+	limit := s.head
+	for sub := range s.subscriptions {
+		if cursor := sub.position(); cursor < limit {
+			limit = cursor
+		}
+	}
+	target := s.pruned
+	if s.retention.MaxEvents != 0 && s.head > s.retention.MaxEvents && s.head-s.retention.MaxEvents > target {
+		target = s.head - s.retention.MaxEvents
+	}
+	if target > limit {
+		target = limit
+	}
+	if s.retention.MaxAge != 0 {
+		cutoff := s.now().Add(-s.retention.MaxAge)
+		for target < limit {
+			event, err := s.event(target + 1)
+			if err != nil {
+				return err
+			}
+			if !event.Timestamp.Before(cutoff) {
+				break
+			}
+			target++
+		}
+	}
+	if target <= s.pruned {
+		return nil
+	}
+
+	// move the oldest event first, then a failure leaves no reference to a removed event
+	k, err := s.prunedKey()
+	if err != nil {
+		return err
+	}
+	if err := s.kvs.Put(k, target); err != nil {
+		return errors.WithMessagef(err, "failed storing the pruned events of the stream")
+	}
+	from := s.pruned + 1
+	s.pruned = target
+	for sequence := from; sequence <= target; sequence++ {
+		k, err := s.eventKey(sequence)
+		if err != nil {
+			return err
+		}
+		if err := s.kvs.Delete(k); err != nil {
+			return errors.WithMessagef(err, "failed removing event [%d]", sequence)
+		}
+	}
+	logger.Debugf("stream [%s] pruned up to [%d]", s.tmsID, target)
+	return nil
+}
+
+func (s *Stream) unsubscribe(sub *Subscription) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.subscriptions, sub)
+}
+
+func (s *Stream) eventKey(sequence uint64) (string, error) {
+	k, err := kvs.CreateCompositeKey(eventPrefix, []string{s.tmsID.String(), strconv.FormatUint(sequence, 10)})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed creating key for event [%d]", sequence)
+	}
+	return k, nil
+}
+
+func (s *Stream) headKey() (string, error) {
+	k, err := kvs.CreateCompositeKey(headPrefix, []string{s.tmsID.String()})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed creating key for the head of the stream [%s]", s.tmsID)
+	}
+	return k, nil
+}
+
+func (s *Stream) prunedKey() (string, error) {
+	k, err := kvs.CreateCompositeKey(prunedPrefix, []string{s.tmsID.String()})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed creating key for the pruned events of the stream [%s]", s.tmsID)
+	}
+	return k, nil
+}
+
+func (s *Stream) txKey(txID string) (string, error) {
+	k, err := kvs.CreateCompositeKey(txPrefix, []string{s.tmsID.String(), txID})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed creating key for transaction [%s]", txID)
+	}
+	return k, nil
+}
+
+// Subscription delivers the events of a stream to a listener, in order and at least once
+type Subscription struct {
+	stream   *Stream
+	listener Listener
+	walletID string
+	// cursor is the sequence number of the last event processed, it is accessed atomically
+	cursor uint64
+
+	wake      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Close stops the delivery of the events
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() {
+		s.stream.unsubscribe(s)
+		close(s.done)
+	})
+}
+
+// position returns the sequence number of the last event processed
+func (s *Subscription) position() uint64 {
+	return atomic.LoadUint64(&s.cursor)
+}
+
+func (s *Subscription) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Subscription) run() {
+	for {
+		for s.position() < s.stream.Head() {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			event, err := s.stream.Event(s.position() + 1)
+			if err != nil {
+				logger.Errorf("failed loading event [%d], retrying: [%s]", s.position()+1, err)
+				if !s.sleep() {
+					return
+				}
+				continue
+			}
+			if s.matches(event) {
+				if err := s.listener.OnEvent(event); err != nil {
+					logger.Warnf("listener failed processing event [%d], retrying: [%s]", event.Sequence, err)
+					if !s.sleep() {
+						return
+					}
+					continue
+				}
+			}
+			atomic.StoreUint64(&s.cursor, event.Sequence)
+		}
+		select {
+		case <-s.wake:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// sleep waits before a retry, it returns false if the subscription has been closed in the meantime
+func (s *Subscription) sleep() bool {
+	select {
+	case <-time.After(retryDelay):
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func (s *Subscription) matches(event *Event) bool {
+	if len(s.walletID) == 0 || event.WalletID == s.walletID {
+		return true
+	}
+	for _, id := range event.WalletIDs {
+		if id == s.walletID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package eventstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/processor"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/owner"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	retryDelay = 10 * time.Millisecond
+}
+
+func TestStream(t *testing.T) {
+	tmsID := token.TMSID{Network: "n1", Channel: "c1", Namespace: "zkat"}
+	kvs := &memKVS{m: map[string][]byte{}}
+	precision := func() uint64 { return 64 }
+
+	s, err := NewStream(tmsID, kvs, precision)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), s.Head())
+
+	// live delivery of the events of alice, the first delivery fails
+	alice := &listener{failures: 1}
+	sub, err := s.Subscribe(alice, WithWallet("alice"))
+	assert.NoError(t, err)
+
+	s.OnReceive(processor.NewTokenProcessorEvent(processor.AddToken, &processor.TokenMessage{
+		Namespace: "zkat", WalletID: "alice", TokenType: "USD", Quantity: "0x0a", TxID: "tx1", Index: 0,
+	}))
+	s.OnReceive(processor.NewTokenProcessorEvent(processor.AddToken, &processor.TokenMessage{
+		Namespace: "zkat", WalletID: "bob", TokenType: "USD", Quantity: "0x05", TxID: "tx1", Index: 1,
+	}))
+	s.OnReceive(&statusEvent{msg: owner.TxStatusMessage{TMSID: tmsID, TxID: "tx1", Status: owner.Confirmed}})
+	s.OnReceive(processor.NewTokenProcessorEvent(processor.DeleteToken, &processor.TokenMessage{
+		Namespace: "zkat", WalletID: "alice", TokenType: "USD", Quantity: "0x0a", TxID: "tx1", Index: 0, DeletedBy: "tx2",
+	}))
+	// events of other TMSs are ignored
+	s.OnReceive(processor.NewTokenProcessorEvent(processor.AddToken, &processor.TokenMessage{
+		Namespace: "other", WalletID: "alice", TokenType: "USD", Quantity: "0x01", TxID: "tx3",
+	}))
+	s.OnReceive(&statusEvent{msg: owner.TxStatusMessage{TMSID: token.TMSID{Network: "n2"}, TxID: "tx3", Status: owner.Confirmed}})
+	s.pending.Wait()
+	assert.Equal(t, uint64(7), s.Head())
+
+	events := alice.wait(t, 5)
+	sub.Close()
+	assert.Equal(t, []EventType{TokenReceived, BalanceChanged, TransactionConfirmed, TokenSpent, BalanceChanged}, types(events))
+	assert.Equal(t, []uint64{1, 2, 5, 6, 7}, sequences(events))
+	assert.Equal(t, "10", events[0].Quantity)
+	assert.Equal(t, "tx1", events[0].TokenID.TxId)
+	assert.Equal(t, tmsID, events[0].TMSID)
+	assert.Equal(t, []string{"alice", "bob"}, events[2].WalletIDs)
+	assert.Equal(t, "tx2", events[3].TxID)
+	assert.Equal(t, "tx1", events[3].TokenID.TxId)
+	assert.Equal(t, "-10", events[4].Quantity)
+
+	// after a restart, the stream continues from its head and can be replayed from a cursor
+	s, err = NewStream(tmsID, kvs, precision)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), s.Head())
+	all := &listener{}
+	sub, err = s.Subscribe(all, WithCursor(2))
+	assert.NoError(t, err)
+	s.OnReceive(&statusEvent{msg: owner.TxStatusMessage{TMSID: tmsID, TxID: "tx2", Status: owner.Deleted}})
+	events = all.wait(t, 6)
+	sub.Close()
+	assert.Equal(t, []uint64{3, 4, 5, 6, 7, 8}, sequences(events))
+	assert.Equal(t, TransactionDeleted, events[5].Type)
+	assert.Equal(t, []string{"alice"}, events[5].WalletIDs)
+
+	_, err = s.Subscribe(all, WithCursor(9))
+	assert.EqualError(t, err, "cursor [9] is ahead of the stream [8]")
+}
+
+func TestStreamPersistence(t *testing.T) {
+	tmsID := token.TMSID{Network: "n1", Channel: "c1", Namespace: "zkat"}
+	kvs := &memKVS{m: map[string][]byte{}}
+	s, err := NewStream(tmsID, kvs, func() uint64 { return 64 })
+	assert.NoError(t, err)
+
+	// the notifier is not blocked while the store fails, the notification is appended once the store recovers
+	kvs.setFailures(30)
+	received := make(chan struct{})
+	go func() {
+		s.OnReceive(processor.NewTokenProcessorEvent(processor.AddToken, &processor.TokenMessage{
+			Namespace: "zkat", WalletID: "alice", TokenType: "USD", Quantity: "0x0a", TxID: "tx1", Index: 0,
+		}))
+		close(received)
+	}()
+	select {
+	case <-received:
+	case <-time.After(10 * retryDelay):
+		t.Fatal("the notifier is blocked")
+	}
+	s.pending.Wait()
+	assert.Equal(t, uint64(2), s.Head())
+	event, err := s.Event(1)
+	assert.NoError(t, err)
+	assert.Equal(t, TokenReceived, event.Type)
+
+	// the wallets touched by a transaction are tracked under concurrent notifications
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s.OnReceive(processor.NewTokenProcessorEvent(processor.AddToken, &processor.TokenMessage{
+				Namespace: "zkat", WalletID: fmt.Sprintf("w%d", i), TokenType: "USD", Quantity: "0x01", TxID: "tx2", Index: uint64(i),
+			}))
+		}(i)
+	}
+	wg.Wait()
+	s.OnReceive(&statusEvent{msg: owner.TxStatusMessage{TMSID: tmsID, TxID: "tx2", Status: owner.Confirmed}})
+	s.pending.Wait()
+	assert.Equal(t, uint64(43), s.Head())
+	event, err = s.Event(43)
+	assert.NoError(t, err)
+	assert.Equal(t, TransactionConfirmed, event.Type)
+	assert.Len(t, event.WalletIDs, 20)
+}
+
+func TestStreamRetention(t *testing.T) {
+	tmsID := token.TMSID{Network: "n1", Channel: "c1", Namespace: "zkat"}
+	kvs := &memKVS{m: map[string][]byte{}}
+	s, err := NewStream(tmsID, kvs, func() uint64 { return 64 })
+	assert.NoError(t, err)
+	now := time.Date(2023, time.March, 1, 10, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	receive := func(txID string) {
+		s.OnReceive(processor.NewTokenProcessorEvent(processor.AddToken, &processor.TokenMessage{
+			Namespace: "zkat", WalletID: "alice", TokenType: "USD", Quantity: "0x01", TxID: txID,
+		}))
+		s.pending.Wait()
+	}
+
+	// the stream retains the last events
+	s.SetRetention(&config.EventStream{MaxEvents: 4})
+	receive("tx1")
+	receive("tx2")
+	receive("tx3")
+	assert.Equal(t, uint64(6), s.Head())
+	assert.Equal(t, uint64(2), s.Pruned())
+	_, err = s.Event(2)
+	assert.EqualError(t, err, "event [2] of the stream [n1,c1,zkat] has been pruned, the oldest event is [3]")
+	assert.False(t, kvs.Exists(eventKey(t, s, 2)))
+	event, err := s.Event(3)
+	assert.NoError(t, err)
+	assert.Equal(t, "tx2", event.TxID)
+	_, err = s.Subscribe(&listener{}, WithCursor(1))
+	assert.EqualError(t, err, "cursor [1] is behind the stream, the events up to [2] have been pruned")
+
+	// the events a subscription has not processed yet are retained
+	slow := &listener{failures: 1000}
+	sub, err := s.Subscribe(slow, WithCursor(2))
+	assert.NoError(t, err)
+	receive("tx4")
+	receive("tx5")
+	assert.Equal(t, uint64(2), s.Pruned())
+	sub.Close()
+	receive("tx6")
+	assert.Equal(t, uint64(8), s.Pruned())
+
+	// the stream retains the recent events
+	s.SetRetention(&config.EventStream{MaxAge: time.Hour})
+	now = now.Add(time.Hour + time.Minute)
+	receive("tx7")
+	assert.Equal(t, uint64(12), s.Pruned())
+	event, err = s.Event(13)
+	assert.NoError(t, err)
+	assert.Equal(t, "tx7", event.TxID)
+
+	// after a restart, the stream knows the pruned events
+	s, err = NewStream(tmsID, kvs, func() uint64 { return 64 })
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(14), s.Head())
+	assert.Equal(t, uint64(12), s.Pruned())
+	all := &listener{}
+	sub, err = s.Subscribe(all, WithCursor(12))
+	assert.NoError(t, err)
+	events := all.wait(t, 2)
+	sub.Close()
+	assert.Equal(t, []uint64{13, 14}, sequences(events))
+}
+
+func eventKey(t *testing.T, s *Stream, sequence uint64) string {
+	k, err := s.eventKey(sequence)
+	assert.NoError(t, err)
+	return k
+}
+
+type listener struct {
+	lock     sync.Mutex
+	failures int
+	events   []*Event
+}
+
+func (l *listener) OnEvent(event *Event) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.failures > 0 {
+		l.failures--
+		return errors.New("not now")
+	}
+	l.events = append(l.events, event)
+	return nil
+}
+
+func (l *listener) wait(t *testing.T, n int) []*Event {
+	assert.Eventually(t, func() bool {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		return len(l.events) >= n
+	}, 5*time.Second, 10*time.Millisecond)
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.events
+}
+
+func types(events []*Event) []EventType {
+	var res []EventType
+	for _, e := range events {
+		res = append(res, e.Type)
+	}
+	return res
+}
+
+func sequences(events []*Event) []uint64 {
+	var res []uint64
+	for _, e := range events {
+		res = append(res, e.Sequence)
+	}
+	return res
+}
+
+type statusEvent struct {
+	msg owner.TxStatusMessage
+}
+
+func (s *statusEvent) Topic() string {
+	return owner.TxStatusTopic
+}
+
+func (s *statusEvent) Message() interface{} {
+	return s.msg
+}
+
+type memKVS struct {
+	lock sync.Mutex
+	m    map[string][]byte
+	// failures is the number of the next writes that fail
+	failures int
+}
+
+func (k *memKVS) setFailures(n int) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	k.failures = n
+}
+
+func (k *memKVS) Exists(id string) bool {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	_, ok := k.m[id]
+	return ok
+}
+
+func (k *memKVS) Put(id string, state interface{}) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if k.failures > 0 {
+		k.failures--
+		return errors.New("disk full")
+	}
+	k.m[id] = raw
+	return nil
+}
+
+func (k *memKVS) Get(id string, state interface{}) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	raw, ok := k.m[id]
+	if !ok {
+		return errors.Errorf("state [%s] does not exist", id)
+	}
+	return json.Unmarshal(raw, state)
+}
+
+func (k *memKVS) Delete(id string) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	delete(k.m, id)
+	return nil
+}
@@ -227,7 +227,7 @@ func (t *Transaction) Reclaim(wallet *token.OwnerWallet, tok *token2.UnspentToke
 		return err
 	}
 
-	return t.Transfer(wallet, tok.Type, []uint64{q.ToBigInt().Uint64()}, []view.Identity{script.Sender}, token.WithTokenIDs(tok.Id))
+	return t.TransferQuantities(wallet, tok.Type, []token2.Quantity{q}, []view.Identity{script.Sender}, token.WithTokenIDs(tok.Id))
 }
 
 // Claim appends a claim (transfer) action to the token request of the transaction
@@ -295,10 +295,10 @@ func (t *Transaction) Claim(wallet *token.OwnerWallet, tok *token2.UnspentToken,
 		return err
 	}
 
-	return t.Transfer(
+	return t.TransferQuantities(
 		wallet,
 		tok.Type,
-		[]uint64{q.ToBigInt().Uint64()},
+		[]token2.Quantity{q},
 		[]view.Identity{script.Recipient},
 		token.WithTokenIDs(tok.Id),
 		token.WithTransferMetadata(ClaimKey(image), preImage),
@@ -121,31 +121,6 @@ func (r *RWSetProcessor) tokenRequest(req fabric.Request, tx fabric.ProcessTrans
 	if err != nil {
 		return errors.Wrapf(err, "failed getting channel [%s]", tx.Channel())
 	}
-	if !ch.MetadataService().Exists(txID) {
-		if logger.IsEnabledFor(zapcore.DebugLevel) {
-			logger.Debugf("transaction [%s] is not known to this node, no need to extract tokens", txID)
-		}
-		return nil
-	}
-
-	if logger.IsEnabledFor(zapcore.DebugLevel) {
-		logger.Debugf("transaction [%s] is known, extract tokens", txID)
-		logger.Debugf("transaction [%s], parsing writes [%d]", txID, rws.NumWrites(ns))
-	}
-	transientMap, err := ch.MetadataService().LoadTransient(txID)
-	if err != nil {
-		if logger.IsEnabledFor(zapcore.DebugLevel) {
-			logger.Debugf("transaction [%s], failed getting transient map", txID)
-		}
-		return err
-	}
-	if !transientMap.Exists(keys.TokenRequestMetadata) {
-		if logger.IsEnabledFor(zapcore.DebugLevel) {
-			logger.Debugf("transaction [%s], no transient map found", txID)
-		}
-		return nil
-	}
-
 	tms := token.GetManagementService(
 		r.sp,
 		token.WithNetwork(tx.Network()),
@@ -155,15 +130,50 @@ func (r *RWSetProcessor) tokenRequest(req fabric.Request, tx fabric.ProcessTrans
 	if tms == nil {
 		return errors.Errorf("failed getting token management service [%s:%s:%s]", tx.Network(), tx.Channel(), ns)
 	}
-	if logger.IsEnabledFor(zapcore.DebugLevel) {
-		logger.Debugf("transaction [%s on (%s)] is known, extract tokens", txID, tms.ID())
+
+	var metadata *token.Metadata
+	fromLedger := false
+	if ch.MetadataService().Exists(txID) {
+		if logger.IsEnabledFor(zapcore.DebugLevel) {
+			logger.Debugf("transaction [%s] is known, extract tokens", txID)
+			logger.Debugf("transaction [%s], parsing writes [%d]", txID, rws.NumWrites(ns))
+		}
+		transientMap, err := ch.MetadataService().LoadTransient(txID)
+		if err != nil {
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Debugf("transaction [%s], failed getting transient map", txID)
+			}
+			return err
+		}
+		if transientMap.Exists(keys.TokenRequestMetadata) {
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Debugf("transaction [%s on (%s)] is known, extract tokens", txID, tms.ID())
+			}
+			metadata, err = tms.NewMetadataFromBytes(transientMap.Get(keys.TokenRequestMetadata))
+			if err != nil {
+				if logger.IsEnabledFor(zapcore.DebugLevel) {
+					logger.Debugf("transaction [%s], failed getting zkat state from transient map [%s]", txID, err)
+				}
+				return err
+			}
+		} else if logger.IsEnabledFor(zapcore.DebugLevel) {
+			logger.Debugf("transaction [%s], no transient map found", txID)
+		}
 	}
-	metadata, err := tms.NewMetadataFromBytes(transientMap.Get(keys.TokenRequestMetadata))
-	if err != nil {
+	if metadata == nil {
+		// the outputs might carry their metadata on the ledger
+		var ok bool
+		metadata, ok = tms.NewLedgerMetadata()
+		fromLedger = true
+		if !ok {
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Debugf("transaction [%s] is not known to this node, no need to extract tokens", txID)
+			}
+			return nil
+		}
 		if logger.IsEnabledFor(zapcore.DebugLevel) {
-			logger.Debugf("transaction [%s], failed getting zkat state from transient map [%s]", txID, err)
+			logger.Debugf("transaction [%s] is not known to this node, extract tokens from the ledger", txID)
 		}
-		return err
 	}
 
 	wrappedRWS := &rwsWrapper{RWSet: rws}
@@ -254,6 +264,13 @@ func (r *RWSetProcessor) tokenRequest(req fabric.Request, tx fabric.ProcessTrans
 		// get token in the clear
 		tok, issuer, tokenInfoRaw, err := metadata.GetToken(val)
 		if err != nil {
+			if fromLedger {
+				// most of the outputs of the transactions unknown to this node are not for us
+				if logger.IsEnabledFor(zapcore.DebugLevel) {
+					logger.Debugf("transaction [%s], found a token but failed getting the clear version from the ledger, skipping it [%s]", txID, err)
+				}
+				continue
+			}
 			logger.Errorf("transaction [%s], found a token but failed getting the clear version, skipping it [%s]", txID, err)
 			continue
 		}
@@ -6,24 +6,36 @@ SPDX-License-Identifier: Apache-2.0
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
+	"github.com/go-kit/kit/log"
+	kitstatsd "github.com/go-kit/kit/metrics/statsd"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric/common/metrics/statsd"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/metrics"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken/driver"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/gh/driver"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/fabric/tcc"
 )
 
+// metricsWriteInterval is how often the token metrics are sent to the StatsD server
+const metricsWriteInterval = 10 * time.Second
+
 type serverConfig struct {
 	CCID           string
 	CCaddress      string
 	LogLevel       string
 	MetricsEnabled bool
 	MetricsServer  string
+	// BatchVerification enables the batch verification of the proofs of the token requests
+	BatchVerification bool
 }
 
 func main() {
@@ -38,18 +50,34 @@ func main() {
 		}
 	}
 
+	batchVerificationEnv := os.Getenv("CHAINCODE_BATCH_VERIFICATION")
+	batchVerification := false
+	if len(batchVerificationEnv) > 0 {
+		var err error
+		batchVerification, err = strconv.ParseBool(batchVerificationEnv)
+		if err != nil {
+			fmt.Printf("Error parsing CHAINCODE_BATCH_VERIFICATION: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
 	config := serverConfig{
 		CCID:           os.Getenv("CHAINCODE_ID"),
 		CCaddress:      os.Getenv("CHAINCODE_SERVER_ADDRESS"),
 		LogLevel:       os.Getenv("CHAINCODE_LOG_LEVEL"),
 		MetricsEnabled: metricsEnabled,
 		MetricsServer:  os.Getenv("CHAINCODE_METRICS_SERVER"),
+
+		BatchVerification: batchVerification,
 	}
 	if len(config.MetricsServer) == 0 {
 		config.MetricsServer = "localhost:8125"
 	}
 
 	fmt.Printf("metrics server at [%s], enabled [%v]", config.MetricsServer, config.MetricsEnabled)
+	if config.MetricsEnabled {
+		installMetrics(config.MetricsServer)
+	}
 
 	if config.CCID == "" || config.CCaddress == "" {
 		fmt.Println("CC ID or CC address is empty... Running as usual...")
@@ -58,11 +86,9 @@ func main() {
 		}
 		err := shim.Start(
 			&tcc.TokenChaincode{
-				TokenServicesFactory: func(bytes []byte) (tcc.PublicParametersManager, tcc.Validator, error) {
-					return token.NewServicesFromPublicParams(bytes)
-				},
-				MetricsEnabled: config.MetricsEnabled,
-				MetricsServer:  config.MetricsServer,
+				TokenServicesFactory: config.newTokenServices,
+				MetricsEnabled:       config.MetricsEnabled,
+				MetricsServer:        config.MetricsServer,
 			},
 		)
 		if err != nil {
@@ -77,12 +103,10 @@ func main() {
 			CCID:    config.CCID,
 			Address: config.CCaddress,
 			CC: &tcc.TokenChaincode{
-				TokenServicesFactory: func(bytes []byte) (tcc.PublicParametersManager, tcc.Validator, error) {
-					return token.NewServicesFromPublicParams(bytes)
-				},
-				LogLevel:       config.LogLevel,
-				MetricsEnabled: config.MetricsEnabled,
-				MetricsServer:  config.MetricsServer,
+				TokenServicesFactory: config.newTokenServices,
+				LogLevel:             config.LogLevel,
+				MetricsEnabled:       config.MetricsEnabled,
+				MetricsServer:        config.MetricsServer,
 			},
 			TLSProps: shim.TLSProperties{
 				// TODO : enable TLS
@@ -95,3 +119,23 @@ func main() {
 		}
 	}
 }
+
+// installMetrics makes the token metrics recorded in the chaincode process, such as the validation ones,
+// available on the passed StatsD server
+func installMetrics(server string) {
+	s := kitstatsd.New("", log.NewNopLogger())
+	metrics.Install(&statsd.Provider{Statsd: s})
+	go s.SendLoop(context.Background(), time.NewTicker(metricsWriteInterval).C, "udp", server)
+}
+
+// newTokenServices returns the public parameters manager and the validator of the passed public parameters
+func (c serverConfig) newTokenServices(params []byte) (tcc.PublicParametersManager, tcc.Validator, error) {
+	ppm, validator, err := token.NewServicesFromPublicParams(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.BatchVerification && !validator.SetBatchVerification(true) {
+		fmt.Println("batch verification is not supported by the driver of the public parameters")
+	}
+	return ppm, validator, nil
+}
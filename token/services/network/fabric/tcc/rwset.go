@@ -7,7 +7,10 @@ SPDX-License-Identifier: Apache-2.0
 package tcc
 
 import (
+	"time"
+
 	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/pkg/errors"
 )
 
 type rwsWrapper struct {
@@ -25,3 +28,25 @@ func (rwset *rwsWrapper) GetState(namespace string, key string) ([]byte, error)
 func (rwset *rwsWrapper) DeleteState(namespace string, key string) error {
 	return rwset.stub.DelState(key)
 }
+
+// ledger exposes to the validator the state and the timestamp of the transaction being validated
+type ledger struct {
+	stub shim.ChaincodeStubInterface
+}
+
+func (l *ledger) GetState(key string) ([]byte, error) {
+	return l.stub.GetState(key)
+}
+
+// TxTimestamp returns the timestamp set by the client in the transaction header,
+// the same for all the endorsers of the transaction
+func (l *ledger) TxTimestamp() (time.Time, error) {
+	ts, err := l.stub.GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "failed to get transaction timestamp")
+	}
+	if err := ts.CheckValid(); err != nil {
+		return time.Time{}, errors.Wrap(err, "invalid transaction timestamp")
+	}
+	return ts.AsTime(), nil
+}
@@ -14,11 +14,13 @@ import (
 	"os"
 	"runtime/debug"
 	"sync"
+	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/tracing"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/tracker/metrics"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	metrics2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/common/metrics"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
@@ -114,6 +116,14 @@ func (cc *TokenChaincode) Invoke(stub shim.ChaincodeStubInterface) (res pb.Respo
 		}
 		agent.EmitKey(0, "tcc", "start", "TokenChaincodeInvoke"+string(args[0]), stub.GetTxID())
 		defer agent.EmitKey(0, "tcc", "end", "TokenChaincodeInvoke"+string(args[0]), stub.GetTxID())
+		start := time.Now()
+		defer func() {
+			outcome := metrics2.Success
+			if res.Status >= shim.ERRORTHRESHOLD {
+				outcome = metrics2.Failure
+			}
+			metrics2.Get().ChaincodeRequestDuration.With("function", functionLabel(string(args[0])), "outcome", outcome).Observe(metrics2.Since(start))
+		}()
 
 		logger.Infof("running function [%s]", string(args[0]))
 		switch f := string(args[0]); f {
@@ -230,7 +240,7 @@ func (cc *TokenChaincode) ProcessRequest(raw []byte, stub shim.ChaincodeStubInte
 
 	// Verify
 	cc.MetricsAgent.EmitKey(0, "tcc", "start", "TokenChaincodeProcessRequestUnmarshallAndVerify", stub.GetTxID())
-	actions, err := validator.UnmarshallAndVerify(stub, stub.GetTxID(), raw)
+	actions, err := validator.UnmarshallAndVerify(&ledger{stub: stub}, stub.GetTxID(), raw)
 	if err != nil {
 		return shim.Error("failed to verify token request: " + err.Error())
 	}
@@ -341,3 +351,13 @@ func (cc *TokenChaincode) NewMetricsAgent(id string) (Agent, error) {
 	}
 	return cc.MetricsAgent, nil
 }
+
+// functionLabel returns the metrics label of the passed function, unknown functions share the same label
+func functionLabel(f string) string {
+	switch f {
+	case InvokeFunction, QueryPublicParamsFunction, QueryTokensFunctions, AreTokensSpent:
+		return f
+	default:
+		return "unknown"
+	}
+}
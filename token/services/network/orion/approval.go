@@ -12,6 +12,7 @@ import (
 	session2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/session"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/tracing"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
 	"github.com/pkg/errors"
@@ -22,6 +23,8 @@ type ApprovalRequest struct {
 	Namespace string
 	TxID      string
 	Request   []byte
+	// Traceparent is the context of the span that requested the approval, in the W3C format
+	Traceparent string `json:",omitempty"`
 }
 
 type ApprovalResponse struct {
@@ -41,6 +44,13 @@ func NewRequestApprovalView(network driver.Network, namespace string, requestRaw
 }
 
 func (r *RequestApprovalView) Call(context view.Context) (interface{}, error) {
+	span := tracing.Get().StartTxSpan("orion.request_approval", r.TxID, tracing.SpanContext{})
+	env, err := r.requestApproval(context, span.Context())
+	span.End(err)
+	return env, err
+}
+
+func (r *RequestApprovalView) requestApproval(context view.Context, spanContext tracing.SpanContext) (driver.Envelope, error) {
 	custodian, err := GetCustodian(view2.GetConfigService(context), r.Network.Name())
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get custodian identifier")
@@ -52,10 +62,11 @@ func (r *RequestApprovalView) Call(context view.Context) (interface{}, error) {
 	}
 	// TODO: Should we sign the approval request?
 	request := &ApprovalRequest{
-		Network:   r.Network.Name(),
-		Namespace: r.Namespace,
-		TxID:      r.TxID,
-		Request:   r.RequestRaw,
+		Network:     r.Network.Name(),
+		Namespace:   r.Namespace,
+		TxID:        r.TxID,
+		Request:     r.RequestRaw,
+		Traceparent: spanContext.Traceparent(),
 	}
 	if err := session.Send(request); err != nil {
 		return nil, errors.Wrapf(err, "failed to send request to custodian [%s]", custodian)
@@ -82,7 +93,10 @@ func (r *RequestApprovalResponderView) Call(context view.Context) (interface{},
 	}
 	logger.Debugf("request: %+v", request)
 
+	remote, _ := tracing.ParseTraceparent(request.Traceparent)
+	span := tracing.Get().StartTxSpan("orion.approve", request.TxID, remote)
 	txRaw, err := r.process(context, request)
+	span.End(err)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to process request")
 	}
@@ -101,6 +115,13 @@ func (r *RequestApprovalResponderView) process(context view.Context, request *Ap
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create validator")
 	}
+	batchVerification, err := BatchVerification(view2.GetConfigService(context), request.Network, request.Namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get validator configuration")
+	}
+	if batchVerification && !validator.SetBatchVerification(true) {
+		logger.Warnf("batch verification is not supported by the driver of the public parameters of [%s:%s]", request.Network, request.Namespace)
+	}
 
 	// Verify
 	ons := orion.GetOrionNetworkService(context, request.Network)
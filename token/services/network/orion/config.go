@@ -49,6 +49,22 @@ func GetCustodian(cp configProvider, network string) (string, error) {
 	return "", errors.Errorf("no token-sdk configuration for network %s", network)
 }
 
+// BatchVerification returns true if the custodian verifies the proofs of the token requests of the passed network
+// and namespace in batch
+func BatchVerification(cp configProvider, network, namespace string) (bool, error) {
+	tmsConfigs, err := tmss(cp)
+	if err != nil {
+		return false, err
+	}
+	for _, config := range tmsConfigs {
+		if config.Network != network || (len(config.Namespace) != 0 && config.Namespace != namespace) {
+			continue
+		}
+		return config.Validator != nil && config.Validator.BatchVerification, nil
+	}
+	return false, nil
+}
+
 func tmss(cp configProvider) (map[string]*TMS, error) {
 	var boxedConfig map[interface{}]interface{}
 	if err := cp.UnmarshalKey("token.tms", &boxedConfig); err != nil {
@@ -70,45 +70,55 @@ func (r *RWSetProcessor) Process(req orion.Request, tx orion.ProcessTransaction,
 func (r *RWSetProcessor) tokenRequest(req orion.Request, tx orion.ProcessTransaction, rws *orion.RWSet, ns string) error {
 	txID := tx.ID()
 
-	if !r.network.MetadataService().Exists(txID) {
-		if logger.IsEnabledFor(zapcore.DebugLevel) {
-			logger.Debugf("transaction [%s] is not known to this node, no need to extract tokens", txID)
-		}
-		return nil
-	}
-
-	if logger.IsEnabledFor(zapcore.DebugLevel) {
-		logger.Debugf("transaction [%s] is known, extract tokens", txID)
-		logger.Debugf("transaction [%s], parsing writes [%d]", txID, rws.NumWrites(ns))
-	}
-	transientMap, err := r.network.MetadataService().LoadTransient(txID)
-	if err != nil {
-		if logger.IsEnabledFor(zapcore.DebugLevel) {
-			logger.Debugf("transaction [%s], failed getting transient map", txID)
-		}
-		return err
-	}
-	if !transientMap.Exists(keys.TokenRequestMetadata) {
-		if logger.IsEnabledFor(zapcore.DebugLevel) {
-			logger.Debugf("transaction [%s], no transient map found", txID)
-		}
-		return nil
-	}
-
 	tms := token.GetManagementService(
 		r.sp,
 		token.WithNetwork(tx.Network()),
 		token.WithNamespace(ns),
 	)
-	if logger.IsEnabledFor(zapcore.DebugLevel) {
-		logger.Debugf("transaction [%s on (%s)] is known, extract tokens", txID, tms.ID())
+
+	var metadata *token.Metadata
+	fromLedger := false
+	if r.network.MetadataService().Exists(txID) {
+		if logger.IsEnabledFor(zapcore.DebugLevel) {
+			logger.Debugf("transaction [%s] is known, extract tokens", txID)
+			logger.Debugf("transaction [%s], parsing writes [%d]", txID, rws.NumWrites(ns))
+		}
+		transientMap, err := r.network.MetadataService().LoadTransient(txID)
+		if err != nil {
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Debugf("transaction [%s], failed getting transient map", txID)
+			}
+			return err
+		}
+		if transientMap.Exists(keys.TokenRequestMetadata) {
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Debugf("transaction [%s on (%s)] is known, extract tokens", txID, tms.ID())
+			}
+			metadata, err = tms.NewMetadataFromBytes(transientMap.Get(keys.TokenRequestMetadata))
+			if err != nil {
+				if logger.IsEnabledFor(zapcore.DebugLevel) {
+					logger.Debugf("transaction [%s], failed getting zkat state from transient map [%s]", txID, err)
+				}
+				return err
+			}
+		} else if logger.IsEnabledFor(zapcore.DebugLevel) {
+			logger.Debugf("transaction [%s], no transient map found", txID)
+		}
 	}
-	metadata, err := tms.NewMetadataFromBytes(transientMap.Get(keys.TokenRequestMetadata))
-	if err != nil {
+	if metadata == nil {
+		// the outputs might carry their metadata on the ledger
+		var ok bool
+		metadata, ok = tms.NewLedgerMetadata()
+		fromLedger = true
+		if !ok {
+			if logger.IsEnabledFor(zapcore.DebugLevel) {
+				logger.Debugf("transaction [%s] is not known to this node, no need to extract tokens", txID)
+			}
+			return nil
+		}
 		if logger.IsEnabledFor(zapcore.DebugLevel) {
-			logger.Debugf("transaction [%s], failed getting zkat state from transient map [%s]", txID, err)
+			logger.Debugf("transaction [%s] is not known to this node, extract tokens from the ledger", txID)
 		}
-		return err
 	}
 
 	wrappedRWS := &rwsWrapper{RWSet: rws}
@@ -205,6 +215,13 @@ func (r *RWSetProcessor) tokenRequest(req orion.Request, tx orion.ProcessTransac
 		// get token in the clear
 		tok, issuer, tokenInfoRaw, err := metadata.GetToken(val)
 		if err != nil {
+			if fromLedger {
+				// most of the outputs of the transactions unknown to this node are not for us
+				if logger.IsEnabledFor(zapcore.DebugLevel) {
+					logger.Debugf("transaction [%s], found a token but failed getting the clear version from the ledger, skipping it [%s]", txID, err)
+				}
+				continue
+			}
 			logger.Errorf("transaction [%s], found a token but failed getting the clear version, skipping it [%s]", txID, err)
 			continue
 		}
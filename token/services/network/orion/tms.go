@@ -6,6 +6,10 @@ SPDX-License-Identifier: Apache-2.0
 
 package orion
 
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+)
+
 type InteractiveCertification struct {
 	IDs []string `yaml:"ids,omitempty"`
 }
@@ -44,4 +48,6 @@ type TMS struct {
 	Orion         *Orion         `yaml:"orion,omitempty"`
 	Certification *Certification `yaml:"certification,omitempty"`
 	Wallets       *Wallets       `yaml:"wallets,omitempty"`
+	// Validator configures the verification of the token requests the custodian approves
+	Validator *config.Validator `yaml:"validator,omitempty"`
 }
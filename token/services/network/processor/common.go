@@ -87,7 +87,15 @@ func (cts *CommonTokenStore) DeleteFabToken(ns string, txID string, index uint64
 			}
 
 			logger.Debugf("post new delete-token event")
-			cts.Notify(DeleteToken, id, token.Type, txID, index)
+			cts.Notify(DeleteToken, &TokenMessage{
+				Namespace: ns,
+				WalletID:  id,
+				TokenType: token.Type,
+				Quantity:  token.Quantity,
+				TxID:      txID,
+				Index:     index,
+				DeletedBy: deletedBy,
+			})
 
 			outputID, err := keys.CreateExtendedFabTokenKey(id, token.Type, txID, index)
 			if err != nil {
@@ -177,7 +185,14 @@ func (cts *CommonTokenStore) StoreFabToken(ns string, txID string, index uint64,
 
 		// notify others
 		logger.Debugf("post new event!")
-		cts.Notify(AddToken, id, tok.Type, txID, index)
+		cts.Notify(AddToken, &TokenMessage{
+			Namespace: ns,
+			WalletID:  id,
+			TokenType: tok.Type,
+			Quantity:  tok.Quantity,
+			TxID:      txID,
+			Index:     index,
+		})
 	}
 
 	return nil
@@ -25,10 +25,15 @@ func NewTokenProcessorEvent(topic string, message *TokenMessage) *TokenProcessor
 }
 
 type TokenMessage struct {
+	Namespace string
 	WalletID  string
 	TokenType string
-	TxID      string
-	Index     uint64
+	// Quantity is the quantity of the token, as stored on the ledger
+	Quantity string
+	TxID     string
+	Index    uint64
+	// DeletedBy is the transaction that spent the token, if the token has been deleted
+	DeletedBy string
 }
 
 func (t *TokenProcessorEvent) Topic() string {
@@ -39,18 +44,13 @@ func (t *TokenProcessorEvent) Message() interface{} {
 	return t.message
 }
 
-func (cts *CommonTokenStore) Notify(topic string, walletID, tokenType, txID string, index uint64) {
+func (cts *CommonTokenStore) Notify(topic string, message *TokenMessage) {
 	if cts.notifier == nil {
 		logger.Warnf("cannot notify others!")
 		return
 	}
 
-	e := NewTokenProcessorEvent(topic, &TokenMessage{
-		WalletID:  walletID,
-		TokenType: tokenType,
-		TxID:      txID,
-		Index:     index,
-	})
+	e := NewTokenProcessorEvent(topic, message)
 
 	logger.Debugf("Publish new event %v", e)
 	cts.notifier.Publish(e)
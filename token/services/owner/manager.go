@@ -12,6 +12,7 @@ import (
 	"sync"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/events"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
@@ -93,7 +94,16 @@ func (cm *Manager) Restore() error {
 }
 
 func (cm *Manager) newOwner(tms *token.ManagementService) (*Owner, error) {
-	owner := &Owner{sp: cm.sp, db: ttxdb.Get(cm.sp, &tmsWallet{tms: tms})}
+	notifier, err := events.GetPublisher(cm.sp)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get event publisher")
+	}
+	owner := &Owner{
+		sp:       cm.sp,
+		tmsID:    tms.ID(),
+		db:       ttxdb.Get(cm.sp, &tmsWallet{tms: tms}),
+		notifier: notifier,
+	}
 	net := network.GetInstance(cm.sp, tms.ID().Network, tms.ID().Channel)
 	if net == nil {
 		return nil, errors.Errorf("failed to get network instance for [%s:%s]", tms.ID().Network, tms.ID().Channel)
@@ -194,7 +204,7 @@ func (cm *Manager) restore(tms *token.ManagementService) error {
 	qe.Done()
 
 	for _, updated := range toBeUpdated {
-		if err := owner.db.SetStatus(updated.TxID, updated.Status); err != nil {
+		if err := owner.SetStatus(updated.TxID, updated.Status); err != nil {
 			return errors.WithMessagef(err, "failed setting status for request %s", updated.TxID)
 		}
 		logger.Infof("found transaction [%s] in vault with status [%d], corresponding pending transaction updated", updated.TxID, updated.Status)
@@ -203,7 +213,7 @@ func (cm *Manager) restore(tms *token.ManagementService) error {
 	logger.Infof("ownerdb [%s:%s], found [%d] pending transactions", tms.ID().Network, tms.ID().Channel, len(pendingTXs))
 
 	for _, txID := range pendingTXs {
-		if err := net.SubscribeTxStatusChanges(txID, &TxStatusChangesListener{net, owner.db}); err != nil {
+		if err := net.SubscribeTxStatusChanges(txID, &TxStatusChangesListener{net, owner}); err != nil {
 			return errors.WithMessagef(err, "failed to subscribe event listener to network [%s:%s] for [%s]", tms.ID().Network, tms.ID().Channel, txID)
 		}
 	}
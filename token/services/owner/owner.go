@@ -8,6 +8,7 @@ package owner
 
 import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/events"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
@@ -31,6 +32,28 @@ const (
 	Deleted = ttxdb.Deleted
 )
 
+// TxStatusTopic is the topic of the events published when the status of a transaction changes
+const TxStatusTopic = "owner-tx-status"
+
+// TxStatusMessage is the message of the events published when the status of a transaction changes
+type TxStatusMessage struct {
+	TMSID  token.TMSID
+	TxID   string
+	Status TxStatus
+}
+
+type txStatusEvent struct {
+	message TxStatusMessage
+}
+
+func (t *txStatusEvent) Topic() string {
+	return TxStatusTopic
+}
+
+func (t *txStatusEvent) Message() interface{} {
+	return t.message
+}
+
 // Transaction models a token transaction
 type Transaction interface {
 	ID() string
@@ -61,8 +84,10 @@ func (a *QueryExecutor) Done() {
 
 // Owner is the interface for the owner service
 type Owner struct {
-	sp view.ServiceProvider
-	db *ttxdb.DB
+	sp       view.ServiceProvider
+	tmsID    token.TMSID
+	db       *ttxdb.DB
+	notifier events.Publisher
 }
 
 // NewQueryExecutor returns a new query executor
@@ -83,21 +108,68 @@ func (a *Owner) Append(tx Transaction) error {
 		return errors.Errorf("failed getting network instance for [%s:%s]", tx.Network(), tx.Channel())
 	}
 	logger.Debugf("register tx status listener for tx %s at network", tx.ID(), tx.Network())
-	if err := net.SubscribeTxStatusChanges(tx.ID(), &TxStatusChangesListener{net, a.db}); err != nil {
+	if err := net.SubscribeTxStatusChanges(tx.ID(), &TxStatusChangesListener{net, a}); err != nil {
 		return errors.WithMessagef(err, "failed listening to network [%s:%s]", tx.Network(), tx.Channel())
 	}
 	logger.Debugf("append done for request %s", tx.ID())
 	return nil
 }
 
+// Restore appends the passed transaction records, preserving their status and timestamp.
+// Records whose transaction is already known are skipped.
+func (a *Owner) Restore(records ...*ttxdb.TransactionRecord) error {
+	qe := a.db.NewQueryExecutor()
+	it, err := qe.Transactions(QueryTransactionsParams{})
+	if err != nil {
+		qe.Done()
+		return errors.WithMessagef(err, "failed to query existing transaction records")
+	}
+	known := map[string]bool{}
+	for {
+		tr, err := it.Next()
+		if err != nil {
+			it.Close()
+			qe.Done()
+			return errors.WithMessagef(err, "failed to get next transaction record")
+		}
+		if tr == nil {
+			break
+		}
+		known[tr.TxID] = true
+	}
+	it.Close()
+	qe.Done()
+
+	var toBeAppended []*ttxdb.TransactionRecord
+	for _, record := range records {
+		if known[record.TxID] {
+			logger.Debugf("transaction [%s] already known, skip it", record.TxID)
+			continue
+		}
+		toBeAppended = append(toBeAppended, record)
+	}
+	if len(toBeAppended) == 0 {
+		return nil
+	}
+	return a.db.AppendTransactionRecords(toBeAppended...)
+}
+
 // SetStatus sets the status of the audit records with the passed transaction id to the passed status
 func (a *Owner) SetStatus(txID string, status TxStatus) error {
-	return a.db.SetStatus(txID, status)
+	if err := a.db.SetStatus(txID, status); err != nil {
+		return err
+	}
+	a.notifier.Publish(&txStatusEvent{message: TxStatusMessage{
+		TMSID:  a.tmsID,
+		TxID:   txID,
+		Status: status,
+	}})
+	return nil
 }
 
 type TxStatusChangesListener struct {
-	net *network.Network
-	db  *ttxdb.DB
+	net   *network.Network
+	owner *Owner
 }
 
 func (t *TxStatusChangesListener) OnStatusChange(txID string, status int) error {
@@ -109,7 +181,7 @@ func (t *TxStatusChangesListener) OnStatusChange(txID string, status int) error
 	case network.Invalid:
 		txStatus = ttxdb.Deleted
 	}
-	if err := t.db.SetStatus(txID, txStatus); err != nil {
+	if err := t.owner.SetStatus(txID, txStatus); err != nil {
 		return errors.WithMessagef(err, "failed setting status for request %s", txID)
 	}
 	logger.Debugf("tx status changed for tx %s: %s done", txID, status)
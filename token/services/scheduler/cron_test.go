@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCronNext(t *testing.T) {
+	// Wednesday, 15th of March 2023
+	now := time.Date(2023, time.March, 15, 10, 30, 45, 0, time.UTC)
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2023, time.March, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2023, time.March, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2023, time.March, 16, 9, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2023, time.March, 15, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 25 * *", time.Date(2023, time.March, 25, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2023, time.March, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 8 * * 1-5", time.Date(2023, time.March, 16, 8, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2023, time.March, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1,15 * *", time.Date(2023, time.March, 15, 12, 0, 0, 0, time.UTC)},
+		// either the day of the month or the day of the week
+		{"0 0 1 * 5", time.Date(2023, time.March, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			c, err := ParseCron(test.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, test.next, c.Next(now))
+		})
+	}
+
+	// the activation is strictly after the passed time
+	c, err := ParseCron("30 10 * * *")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, time.March, 16, 10, 30, 0, 0, time.UTC), c.Next(time.Date(2023, time.March, 15, 10, 30, 0, 0, time.UTC)))
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@never",
+	} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
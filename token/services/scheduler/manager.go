@@ -0,0 +1,67 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package scheduler
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+)
+
+// Manager handles the schedulers of the TMSs
+type Manager struct {
+	sp         view.ServiceProvider
+	kvs        *kvs.KVS
+	mutex      sync.Mutex
+	schedulers map[string]*Scheduler
+}
+
+// NewManager creates a new scheduler manager.
+func NewManager(sp view.ServiceProvider, kvs *kvs.KVS) *Manager {
+	return &Manager{
+		sp:         sp,
+		kvs:        kvs,
+		schedulers: map[string]*Scheduler{},
+	}
+}
+
+// Scheduler returns the scheduler for the given TMS.
+// The schedules can be managed even if the scheduler is not started, they run only once it is.
+func (m *Manager) Scheduler(tms *token.ManagementService) *Scheduler {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	id := tms.ID().String()
+	s, ok := m.schedulers[id]
+	if ok {
+		return s
+	}
+	s = NewScheduler(tms.ID(), m.kvs, tms.PublicParametersManager().Precision, newExecutor(m.sp), newStatusChecker(m.sp, tms.ID()))
+	m.schedulers[id] = s
+	return s
+}
+
+var (
+	managerType = reflect.TypeOf((*Manager)(nil))
+)
+
+// Get returns the Scheduler instance for the passed TMS
+func Get(sp view.ServiceProvider, tms *token.ManagementService) *Scheduler {
+	if tms == nil {
+		logger.Debugf("no TMS provided")
+		return nil
+	}
+	s, err := sp.GetService(managerType)
+	if err != nil {
+		logger.Errorf("failed to get manager service: [%s]", err)
+		return nil
+	}
+	return s.(*Manager).Scheduler(tms)
+}
@@ -0,0 +1,188 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SchedulePrefix is the prefix of the keys of the schedules
+	SchedulePrefix = "scheduler.schedule"
+	// RunPrefix is the prefix of the keys of the runs of the schedules
+	RunPrefix = "scheduler.run"
+)
+
+// State is the state of a schedule
+type State string
+
+const (
+	// Active is the state of the schedules that run at each activation
+	Active State = "Active"
+	// Paused is the state of the schedules that do not run until they are resumed
+	Paused State = "Paused"
+	// Cancelled is the state of the schedules that do not run anymore, they are kept with their runs
+	Cancelled State = "Cancelled"
+)
+
+// Recipient is a recipient of a scheduled transfer
+type Recipient struct {
+	// Node is the identity of the FSC node of the recipient, it is asked for a recipient identity at each run
+	Node view.Identity
+	// Quantity is the amount transferred to the recipient at each run, in decimal
+	Quantity string
+}
+
+// Schedule is the definition of a recurring transfer
+type Schedule struct {
+	// ID identifies the schedule in its TMS
+	ID    string
+	TMSID token.TMSID
+	// Wallet is the identifier of the owner wallet the tokens are transferred from
+	Wallet    string
+	TokenType string
+	// Recipients are the recipients of each transfer, their nodes must be distinct
+	Recipients []*Recipient
+	// Auditor is the identity of the auditor of the transfers, if any
+	Auditor view.Identity
+	// Cron is the cron expression of the activations, evaluated in UTC
+	Cron  string
+	State State
+	// Next is the time of the next activation
+	Next time.Time
+	// Runs is the number of runs recorded so far
+	Runs uint64
+	// InProgress is the run being executed, persisted with its transaction before the endorsements are collected.
+	// A run left in progress, for instance by a crash, is resolved with the status of its transaction.
+	InProgress *Run
+	Created    time.Time
+	Updated    time.Time
+}
+
+// RunStatus is the outcome of a run of a schedule
+type RunStatus string
+
+const (
+	// Succeeded is the status of the runs whose transaction has been committed
+	Succeeded RunStatus = "Succeeded"
+	// Failed is the status of the runs whose transaction could not be assembled or has been rejected
+	Failed RunStatus = "Failed"
+	// Unconfirmed is the status of the runs whose transaction has been submitted for ordering without reaching finality.
+	// The transaction might still be committed, then the run is not retried.
+	Unconfirmed RunStatus = "Unconfirmed"
+)
+
+// Run is the record of a run of a schedule
+type Run struct {
+	ScheduleID string
+	// Sequence is the position of the run among the runs of the schedule, starting from 1
+	Sequence uint64
+	// Activation is the activation of the schedule the run is for
+	Activation time.Time
+	Started    time.Time
+	Ended      time.Time
+	// Attempts is the number of attempts, the failed attempts are retried
+	Attempts int
+	// TxID is the identifier of the transaction of the last attempt, if any
+	TxID   string
+	Status RunStatus
+	// Error is the error of the last attempt, if any
+	Error string
+}
+
+// store persists the schedules of a TMS and their runs
+type store struct {
+	tmsID token.TMSID
+	kvs   *kvs.KVS
+}
+
+func (s *store) get(id string) (*Schedule, error) {
+	k, err := s.scheduleKey(id)
+	if err != nil {
+		return nil, err
+	}
+	if !s.kvs.Exists(k) {
+		return nil, nil
+	}
+	schedule := &Schedule{}
+	if err := s.kvs.Get(k, schedule); err != nil {
+		return nil, errors.WithMessagef(err, "failed loading schedule [%s]", id)
+	}
+	return schedule, nil
+}
+
+func (s *store) put(schedule *Schedule) error {
+	k, err := s.scheduleKey(schedule.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.kvs.Put(k, schedule); err != nil {
+		return errors.WithMessagef(err, "failed storing schedule [%s]", schedule.ID)
+	}
+	return nil
+}
+
+func (s *store) list() ([]*Schedule, error) {
+	it, err := s.kvs.GetByPartialCompositeID(SchedulePrefix, []string{s.tmsID.String()})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed iterating over schedules")
+	}
+	defer it.Close()
+
+	var schedules []*Schedule
+	for it.HasNext() {
+		schedule := &Schedule{}
+		if _, err := it.Next(schedule); err != nil {
+			return nil, errors.WithMessage(err, "failed loading schedule")
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+func (s *store) putRun(run *Run) error {
+	k, err := kvs.CreateCompositeKey(RunPrefix, []string{s.tmsID.String(), run.ScheduleID, fmt.Sprintf("%020d", run.Sequence)})
+	if err != nil {
+		return errors.Wrapf(err, "failed creating key for run [%d] of schedule [%s]", run.Sequence, run.ScheduleID)
+	}
+	if err := s.kvs.Put(k, run); err != nil {
+		return errors.WithMessagef(err, "failed storing run [%d] of schedule [%s]", run.Sequence, run.ScheduleID)
+	}
+	return nil
+}
+
+func (s *store) runs(id string) ([]*Run, error) {
+	it, err := s.kvs.GetByPartialCompositeID(RunPrefix, []string{s.tmsID.String(), id})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed iterating over the runs of schedule [%s]", id)
+	}
+	defer it.Close()
+
+	var runs []*Run
+	for it.HasNext() {
+		run := &Run{}
+		if _, err := it.Next(run); err != nil {
+			return nil, errors.WithMessagef(err, "failed loading run of schedule [%s]", id)
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+func (s *store) scheduleKey(id string) (string, error) {
+	k, err := kvs.CreateCompositeKey(SchedulePrefix, []string{s.tmsID.String(), id})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed creating key for schedule [%s]", id)
+	}
+	return k, nil
+}
@@ -0,0 +1,425 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.scheduler")
+
+const (
+	defaultInterval       = 30 * time.Second
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = 10 * time.Second
+	defaultMaxBackoff     = 5 * time.Minute
+)
+
+// Executor executes an attempt of a run of the passed schedule.
+// It must call the passed function with the identifier of the transaction once assembled, before collecting
+// its endorsements, and give up the attempt if the function fails.
+// It returns the identifier of the transaction, if any, and whether the transaction has been submitted for ordering.
+type Executor func(schedule *Schedule, assembled func(txID string) error) (txID string, submitted bool, err error)
+
+// StatusChecker returns the status of the run whose transaction has the passed identifier:
+// Succeeded if the transaction has been committed, Unconfirmed if it might still be, and Failed otherwise.
+// It also returns whether the transaction might have been submitted for ordering.
+type StatusChecker func(txID string) (status RunStatus, submitted bool, err error)
+
+// Scheduler runs the recurring transfers of a TMS.
+// The schedules and the records of their runs are persisted in the key-value store.
+type Scheduler struct {
+	store     *store
+	precision func() uint64
+	execute   Executor
+	status    StatusChecker
+
+	// mutex serializes the updates of the schedules
+	mutex    sync.Mutex
+	interval time.Duration
+	retry    config.Retry
+	now      func() time.Time
+	sleep    func(time.Duration)
+}
+
+// NewScheduler returns a scheduler for the schedules of the passed TMS, whose runs are executed by the passed executor.
+// The runs left in progress, for instance by a crash, are resolved with the passed status checker.
+func NewScheduler(tmsID token.TMSID, kvs *kvs.KVS, precision func() uint64, execute Executor, status StatusChecker) *Scheduler {
+	return &Scheduler{
+		store:     &store{tmsID: tmsID, kvs: kvs},
+		precision: precision,
+		execute:   execute,
+		status:    status,
+		interval:  defaultInterval,
+		retry: config.Retry{
+			MaxAttempts:    defaultMaxAttempts,
+			InitialBackoff: defaultInitialBackoff,
+			MaxBackoff:     defaultMaxBackoff,
+		},
+		now:   time.Now,
+		sleep: time.Sleep,
+	}
+}
+
+// Add validates and stores the passed schedule, that becomes active from its next activation
+func (s *Scheduler) Add(schedule *Schedule) error {
+	if len(schedule.ID) == 0 {
+		return errors.New("no schedule id")
+	}
+	if len(schedule.Wallet) == 0 {
+		return errors.Errorf("no wallet in schedule [%s]", schedule.ID)
+	}
+	if len(schedule.TokenType) == 0 {
+		return errors.Errorf("no token type in schedule [%s]", schedule.ID)
+	}
+	if len(schedule.Recipients) == 0 {
+		return errors.Errorf("no recipients in schedule [%s]", schedule.ID)
+	}
+	for i, recipient := range schedule.Recipients {
+		if recipient.Node.IsNone() {
+			return errors.Errorf("no node for recipient [%d] in schedule [%s]", i, schedule.ID)
+		}
+		for _, other := range schedule.Recipients[:i] {
+			if other.Node.Equal(recipient.Node) {
+				return errors.Errorf("node [%s] appears twice in schedule [%s]", recipient.Node, schedule.ID)
+			}
+		}
+		q, err := token2.ToQuantity(recipient.Quantity, s.precision())
+		if err != nil {
+			return errors.Wrapf(err, "invalid quantity [%s] for recipient [%d] in schedule [%s]", recipient.Quantity, i, schedule.ID)
+		}
+		if q.Cmp(token2.NewZeroQuantity(s.precision())) <= 0 {
+			return errors.Errorf("quantity for recipient [%d] in schedule [%s] must be positive", i, schedule.ID)
+		}
+	}
+	cron, err := ParseCron(schedule.Cron)
+	if err != nil {
+		return errors.WithMessagef(err, "invalid schedule [%s]", schedule.ID)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing, err := s.store.get(schedule.ID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errors.Errorf("schedule [%s] already exists", schedule.ID)
+	}
+	now := s.now()
+	schedule.Next = cron.Next(now)
+	if schedule.Next.IsZero() {
+		return errors.Errorf("schedule [%s] never activates", schedule.ID)
+	}
+	schedule.TMSID = s.store.tmsID
+	schedule.State = Active
+	schedule.Runs = 0
+	schedule.InProgress = nil
+	schedule.Created = now
+	schedule.Updated = now
+	if err := s.store.put(schedule); err != nil {
+		return err
+	}
+	logger.Infof("schedule [%s] added, next activation at [%s]", schedule.ID, schedule.Next)
+	return nil
+}
+
+// Schedule returns the schedule with the passed identifier, nil if it does not exist
+func (s *Scheduler) Schedule(id string) (*Schedule, error) {
+	return s.store.get(id)
+}
+
+// List returns the schedules, including the paused and the cancelled ones
+func (s *Scheduler) List() ([]*Schedule, error) {
+	return s.store.list()
+}
+
+// Runs returns the records of the runs of the passed schedule, oldest first
+func (s *Scheduler) Runs(id string) ([]*Run, error) {
+	return s.store.runs(id)
+}
+
+// Pause stops running the passed schedule until it is resumed
+func (s *Scheduler) Pause(id string) error {
+	return s.update(id, func(schedule *Schedule) error {
+		if schedule.State != Active {
+			return errors.Errorf("schedule [%s] is [%s], it cannot be paused", id, schedule.State)
+		}
+		schedule.State = Paused
+		return nil
+	})
+}
+
+// Resume runs the passed paused schedule again, from its next activation. The activations missed while paused are skipped.
+func (s *Scheduler) Resume(id string) error {
+	return s.update(id, func(schedule *Schedule) error {
+		if schedule.State != Paused {
+			return errors.Errorf("schedule [%s] is [%s], it cannot be resumed", id, schedule.State)
+		}
+		cron, err := ParseCron(schedule.Cron)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid schedule [%s]", id)
+		}
+		schedule.State = Active
+		schedule.Next = cron.Next(s.now())
+		return nil
+	})
+}
+
+// Cancel stops running the passed schedule for good. The schedule and its runs are kept.
+func (s *Scheduler) Cancel(id string) error {
+	return s.update(id, func(schedule *Schedule) error {
+		if schedule.State == Cancelled {
+			return errors.Errorf("schedule [%s] is cancelled already", id)
+		}
+		schedule.State = Cancelled
+		return nil
+	})
+}
+
+func (s *Scheduler) update(id string, f func(schedule *Schedule) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	schedule, err := s.store.get(id)
+	if err != nil {
+		return err
+	}
+	if schedule == nil {
+		return errors.Errorf("schedule [%s] not found", id)
+	}
+	if err := f(schedule); err != nil {
+		return err
+	}
+	schedule.Updated = s.now()
+	return s.store.put(schedule)
+}
+
+// Start runs the due schedules now and then periodically, until the passed context is done.
+// The passed configuration, if any, overrides the default interval and retries.
+func (s *Scheduler) Start(ctx context.Context, c *config.Scheduler) {
+	if c != nil {
+		if c.Interval > 0 {
+			s.interval = c.Interval
+		}
+		if c.Retry != nil {
+			if c.Retry.MaxAttempts > 0 {
+				s.retry.MaxAttempts = c.Retry.MaxAttempts
+			}
+			if c.Retry.InitialBackoff > 0 {
+				s.retry.InitialBackoff = c.Retry.InitialBackoff
+			}
+			if c.Retry.MaxBackoff > 0 {
+				s.retry.MaxBackoff = c.Retry.MaxBackoff
+			}
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			s.RunDue()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunDue runs, one after the other, the active schedules whose next activation has passed.
+// A schedule whose activations have been missed, for instance because the node was down, runs once.
+// A run left in progress by a previous execution of the node is resolved with the status of its transaction.
+// It is executed again, within the retry budget, only if its transaction has not been submitted for ordering.
+func (s *Scheduler) RunDue() {
+	schedules, err := s.store.list()
+	if err != nil {
+		logger.Errorf("failed listing schedules: [%s]", err)
+		return
+	}
+	for _, schedule := range schedules {
+		if schedule.InProgress != nil {
+			if err := s.resolve(schedule.ID); err != nil {
+				logger.Errorf("failed resolving the run in progress of schedule [%s]: [%s]", schedule.ID, err)
+			}
+			continue
+		}
+		due, err := s.due(schedule.ID)
+		if err != nil {
+			logger.Errorf("failed loading schedule [%s]: [%s]", schedule.ID, err)
+			continue
+		}
+		if due == nil {
+			continue
+		}
+		if err := s.run(due); err != nil {
+			logger.Errorf("failed running schedule [%s]: [%s]", schedule.ID, err)
+		}
+	}
+}
+
+// due returns the passed schedule if it is active and its next activation has passed, nil otherwise.
+// The schedule is loaded under the mutex, then a schedule paused or cancelled meanwhile does not run.
+func (s *Scheduler) due(id string) (*Schedule, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	schedule, err := s.store.get(id)
+	if err != nil || schedule == nil {
+		return nil, err
+	}
+	if schedule.State != Active || schedule.InProgress != nil || schedule.Next.After(s.now()) {
+		return nil, nil
+	}
+	return schedule, nil
+}
+
+// run executes the passed schedule, retrying with an exponential backoff the attempts that failed before
+// the submission of the transaction, then records the run and moves the schedule to its next activation.
+// Before the endorsements of a transaction are collected, the run is persisted as in progress with the transaction.
+// A schedule paused or cancelled while a failed attempt waits to be retried is not retried.
+func (s *Scheduler) run(schedule *Schedule) error {
+	return s.attempt(schedule, &Run{
+		ScheduleID: schedule.ID,
+		Activation: schedule.Next,
+		Started:    s.now(),
+	})
+}
+
+// attempt executes the passed run of the passed schedule, from its next attempt, until it succeeds,
+// its transaction is submitted, or its attempts are exhausted
+func (s *Scheduler) attempt(schedule *Schedule, run *Run) error {
+	backoff := s.retry.InitialBackoff
+	for {
+		run.Attempts++
+		run.TxID = ""
+		txID, submitted, err := s.execute(schedule, func(txID string) error {
+			run.TxID = txID
+			return s.startRun(run)
+		})
+		run.TxID = txID
+		if err == nil {
+			run.Status = Succeeded
+			run.Error = ""
+			break
+		}
+		run.Error = err.Error()
+		if submitted {
+			run.Status = Unconfirmed
+			break
+		}
+		if run.Attempts >= s.retry.MaxAttempts {
+			run.Status = Failed
+			break
+		}
+		logger.Debugf("attempt [%d] of schedule [%s] failed, retry in [%s]: [%s]", run.Attempts, schedule.ID, backoff, err)
+		s.sleep(backoff)
+		backoff *= 2
+		if backoff > s.retry.MaxBackoff {
+			backoff = s.retry.MaxBackoff
+		}
+		if state, err := s.state(schedule.ID); err != nil || state != Active {
+			run.Status = Failed
+			if err == nil {
+				run.Error = fmt.Sprintf("schedule [%s] is [%s], the run is not retried", schedule.ID, state)
+			}
+			break
+		}
+	}
+	run.Ended = s.now()
+	return s.endRun(run)
+}
+
+// resolve records the run in progress of the passed schedule with the status of its transaction,
+// and moves the schedule to its next activation.
+// If the transaction has not been submitted, the run is attempted again, unless its attempts are exhausted
+// or the schedule is not active anymore.
+func (s *Scheduler) resolve(id string) error {
+	schedule, err := s.store.get(id)
+	if err != nil || schedule == nil || schedule.InProgress == nil {
+		return err
+	}
+	run := schedule.InProgress
+	status, submitted, err := s.status(run.TxID)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting status of transaction [%s]", run.TxID)
+	}
+	run.Status = status
+	switch {
+	case status == Succeeded:
+		run.Error = ""
+	case status == Unconfirmed:
+		run.Error = "the node stopped before the finality of the transaction"
+	case submitted:
+		run.Error = "the node stopped before the transaction was committed"
+	case run.Attempts < s.retry.MaxAttempts && schedule.State == Active:
+		logger.Debugf("transaction [%s] of schedule [%s] has not been submitted, attempt the run again", run.TxID, id)
+		return s.attempt(schedule, run)
+	default:
+		run.Status = Failed
+		run.Error = "the node stopped before the transaction was submitted"
+	}
+	run.Ended = s.now()
+	return s.endRun(run)
+}
+
+// state returns the state of the passed schedule
+func (s *Scheduler) state(id string) (State, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	schedule, err := s.store.get(id)
+	if err != nil {
+		return "", err
+	}
+	if schedule == nil {
+		return "", errors.Errorf("schedule [%s] not found", id)
+	}
+	return schedule.State, nil
+}
+
+// startRun persists the passed run as in progress, unless its schedule is not active anymore
+func (s *Scheduler) startRun(run *Run) error {
+	return s.update(run.ScheduleID, func(current *Schedule) error {
+		if current.State != Active {
+			return errors.Errorf("schedule [%s] is [%s], the run is interrupted", current.ID, current.State)
+		}
+		current.InProgress = run
+		return nil
+	})
+}
+
+// endRun records the passed run and moves its schedule to the next activation
+func (s *Scheduler) endRun(run *Run) error {
+	logger.Infof("run of schedule [%s] for activation [%s]: [%s] after [%d] attempts, tx [%s]", run.ScheduleID, run.Activation, run.Status, run.Attempts, run.TxID)
+	return s.update(run.ScheduleID, func(current *Schedule) error {
+		current.Runs++
+		run.Sequence = current.Runs
+		if err := s.store.putRun(run); err != nil {
+			return err
+		}
+		current.InProgress = nil
+		cron, err := ParseCron(current.Cron)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid schedule [%s]", current.ID)
+		}
+		current.Next = cron.Next(run.Ended)
+		return nil
+	})
+}
@@ -0,0 +1,388 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/memory"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	registry2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var tmsID = token.TMSID{Network: "n1", Channel: "c1", Namespace: "zkat"}
+
+type attempt struct {
+	txID      string
+	submitted bool
+	err       error
+}
+
+type testScheduler struct {
+	*Scheduler
+	kvs      *kvs.KVS
+	backoffs []time.Duration
+	// statuses are the statuses of the transactions, for the runs left in progress
+	statuses map[string]RunStatus
+	// notSubmitted are the transactions, of the runs left in progress, that have not been submitted for ordering
+	notSubmitted map[string]bool
+}
+
+func newTestScheduler(t *testing.T, now *time.Time, attempts map[string][]attempt) *testScheduler {
+	registry := registry2.New()
+	assert.NoError(t, registry.RegisterService(&fakeProv{typ: "memory"}))
+	kvss, err := kvs.New(registry, "memory", "")
+	assert.NoError(t, err)
+	return newTestSchedulerWithKVS(kvss, now, attempts)
+}
+
+func newTestSchedulerWithKVS(kvss *kvs.KVS, now *time.Time, attempts map[string][]attempt) *testScheduler {
+	ts := &testScheduler{kvs: kvss, statuses: map[string]RunStatus{}, notSubmitted: map[string]bool{}}
+	ts.Scheduler = NewScheduler(tmsID, kvss, func() uint64 { return 64 }, func(schedule *Schedule, assembled func(txID string) error) (string, bool, error) {
+		a := attempts[schedule.ID][0]
+		attempts[schedule.ID] = attempts[schedule.ID][1:]
+		if len(a.txID) != 0 {
+			if err := assembled(a.txID); err != nil {
+				return a.txID, false, err
+			}
+		}
+		return a.txID, a.submitted, a.err
+	}, func(txID string) (RunStatus, bool, error) {
+		if ts.notSubmitted[txID] {
+			return Failed, false, nil
+		}
+		status, ok := ts.statuses[txID]
+		if !ok {
+			return "", false, errors.Errorf("vault unavailable")
+		}
+		return status, true, nil
+	})
+	ts.now = func() time.Time { return *now }
+	ts.sleep = func(d time.Duration) { ts.backoffs = append(ts.backoffs, d) }
+	return ts
+}
+
+func newSchedule(id string) *Schedule {
+	return &Schedule{
+		ID:         id,
+		Wallet:     "treasury",
+		TokenType:  "EUR",
+		Recipients: []*Recipient{{Node: []byte("alice"), Quantity: "100"}, {Node: []byte("bob"), Quantity: "150"}},
+		Cron:       "0 9 1 * *",
+	}
+}
+
+func TestAdd(t *testing.T) {
+	now := time.Date(2023, time.March, 15, 10, 0, 0, 0, time.UTC)
+	s := newTestScheduler(t, &now, nil)
+
+	assert.NoError(t, s.Add(newSchedule("salaries")))
+	schedule, err := s.Schedule("salaries")
+	assert.NoError(t, err)
+	assert.Equal(t, tmsID, schedule.TMSID)
+	assert.Equal(t, Active, schedule.State)
+	assert.Equal(t, time.Date(2023, time.April, 1, 9, 0, 0, 0, time.UTC), schedule.Next)
+	schedule, err = s.Schedule("missing")
+	assert.NoError(t, err)
+	assert.Nil(t, schedule)
+	assert.EqualError(t, s.Add(newSchedule("salaries")), "schedule [salaries] already exists")
+
+	schedule = newSchedule("")
+	assert.EqualError(t, s.Add(schedule), "no schedule id")
+	schedule = newSchedule("other")
+	schedule.Wallet = ""
+	assert.EqualError(t, s.Add(schedule), "no wallet in schedule [other]")
+	schedule = newSchedule("other")
+	schedule.Recipients = nil
+	assert.EqualError(t, s.Add(schedule), "no recipients in schedule [other]")
+	schedule = newSchedule("other")
+	schedule.Recipients[1].Node = []byte("alice")
+	assert.Contains(t, s.Add(schedule).Error(), "appears twice in schedule [other]")
+	schedule = newSchedule("other")
+	schedule.Recipients[1].Quantity = "ten"
+	assert.Contains(t, s.Add(schedule).Error(), "invalid quantity [ten] for recipient [1]")
+	schedule = newSchedule("other")
+	schedule.Recipients[1].Quantity = "0"
+	assert.EqualError(t, s.Add(schedule), "quantity for recipient [1] in schedule [other] must be positive")
+	schedule = newSchedule("other")
+	schedule.Cron = "monthly"
+	assert.Contains(t, s.Add(schedule).Error(), "invalid cron expression")
+	schedule = newSchedule("other")
+	schedule.Cron = "0 0 30 2 *"
+	assert.EqualError(t, s.Add(schedule), "schedule [other] never activates")
+
+	schedules, err := s.List()
+	assert.NoError(t, err)
+	assert.Len(t, schedules, 1)
+}
+
+func TestRunDue(t *testing.T) {
+	now := time.Date(2023, time.March, 31, 10, 0, 0, 0, time.UTC)
+	attempts := map[string][]attempt{
+		"salaries": {
+			{err: errors.New("insufficient funds")},
+			{txID: "tx1"},
+			{txID: "tx2", err: errors.New("network down")},
+			{txID: "tx3", err: errors.New("network down")},
+			{txID: "tx4", err: errors.New("network down")},
+		},
+		"settlement": {
+			{txID: "tx5", submitted: true, err: errors.New("finality timeout")},
+		},
+	}
+	s := newTestScheduler(t, &now, attempts)
+	assert.NoError(t, s.Add(newSchedule("salaries")))
+	settlement := newSchedule("settlement")
+	settlement.Cron = "@daily"
+	assert.NoError(t, s.Add(settlement))
+
+	// nothing is due
+	s.RunDue()
+	runs, err := s.Runs("salaries")
+	assert.NoError(t, err)
+	assert.Empty(t, runs)
+
+	// the first attempt fails and it is retried
+	now = time.Date(2023, time.April, 1, 9, 0, 30, 0, time.UTC)
+	assert.NoError(t, s.Pause("settlement"))
+	s.RunDue()
+	runs, err = s.Runs("salaries")
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+	assert.Equal(t, &Run{
+		ScheduleID: "salaries",
+		Sequence:   1,
+		Activation: time.Date(2023, time.April, 1, 9, 0, 0, 0, time.UTC),
+		Started:    now,
+		Ended:      now,
+		Attempts:   2,
+		TxID:       "tx1",
+		Status:     Succeeded,
+	}, runs[0])
+	assert.Equal(t, []time.Duration{defaultInitialBackoff}, s.backoffs)
+	schedule, err := s.Schedule("salaries")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), schedule.Runs)
+	assert.Nil(t, schedule.InProgress)
+	assert.Equal(t, time.Date(2023, time.May, 1, 9, 0, 0, 0, time.UTC), schedule.Next)
+	runs, err = s.Runs("settlement")
+	assert.NoError(t, err)
+	assert.Empty(t, runs, "paused schedules do not run")
+
+	// the missed activations run once, the attempts are exhausted
+	now = time.Date(2023, time.July, 3, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, s.Resume("settlement"))
+	schedule, err = s.Schedule("settlement")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, time.July, 4, 0, 0, 0, 0, time.UTC), schedule.Next, "resuming skips the missed activations")
+	s.RunDue()
+	runs, err = s.Runs("salaries")
+	assert.NoError(t, err)
+	assert.Len(t, runs, 2)
+	assert.Equal(t, Failed, runs[1].Status)
+	assert.Equal(t, 3, runs[1].Attempts)
+	assert.Equal(t, "tx4", runs[1].TxID)
+	assert.Equal(t, "network down", runs[1].Error)
+	assert.Equal(t, time.Date(2023, time.May, 1, 9, 0, 0, 0, time.UTC), runs[1].Activation)
+	schedule, err = s.Schedule("salaries")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, time.August, 1, 9, 0, 0, 0, time.UTC), schedule.Next)
+
+	// a submitted transaction is not retried
+	now = time.Date(2023, time.July, 4, 0, 0, 10, 0, time.UTC)
+	s.RunDue()
+	runs, err = s.Runs("settlement")
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+	assert.Equal(t, Unconfirmed, runs[0].Status)
+	assert.Equal(t, 1, runs[0].Attempts)
+	assert.Equal(t, "tx5", runs[0].TxID)
+
+	// cancelled schedules do not run anymore
+	assert.NoError(t, s.Cancel("settlement"))
+	assert.Error(t, s.Cancel("settlement"))
+	assert.Error(t, s.Pause("settlement"))
+	assert.Error(t, s.Resume("settlement"))
+	assert.Error(t, s.Pause("missing"))
+	now = time.Date(2023, time.July, 5, 0, 0, 10, 0, time.UTC)
+	s.RunDue()
+	runs, err = s.Runs("settlement")
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+	schedules, err := s.List()
+	assert.NoError(t, err)
+	assert.Len(t, schedules, 2)
+}
+
+func TestPauseWhileRetrying(t *testing.T) {
+	now := time.Date(2023, time.March, 31, 10, 0, 0, 0, time.UTC)
+	attempts := map[string][]attempt{
+		"salaries": {
+			{err: errors.New("insufficient funds")},
+			{txID: "tx1"},
+		},
+	}
+	s := newTestScheduler(t, &now, attempts)
+	assert.NoError(t, s.Add(newSchedule("salaries")))
+
+	// the schedule is paused while the failed attempt waits to be retried
+	s.sleep = func(d time.Duration) {
+		assert.NoError(t, s.Pause("salaries"))
+	}
+	now = time.Date(2023, time.April, 1, 9, 0, 30, 0, time.UTC)
+	s.RunDue()
+	runs, err := s.Runs("salaries")
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+	assert.Equal(t, Failed, runs[0].Status)
+	assert.Equal(t, 1, runs[0].Attempts)
+	assert.Equal(t, "schedule [salaries] is [Paused], the run is not retried", runs[0].Error)
+	assert.Len(t, attempts["salaries"], 1, "the attempt is not retried")
+}
+
+func TestCancelBeforeEndorsements(t *testing.T) {
+	now := time.Date(2023, time.March, 31, 10, 0, 0, 0, time.UTC)
+	s := newTestScheduler(t, &now, nil)
+	assert.NoError(t, s.Add(newSchedule("salaries")))
+
+	// the schedule is cancelled while the transaction is assembled, the endorsements are not collected
+	collected := false
+	s.execute = func(schedule *Schedule, assembled func(txID string) error) (string, bool, error) {
+		assert.NoError(t, s.Cancel("salaries"))
+		if err := assembled("tx1"); err != nil {
+			return "tx1", false, err
+		}
+		collected = true
+		return "tx1", false, nil
+	}
+	now = time.Date(2023, time.April, 1, 9, 0, 30, 0, time.UTC)
+	s.RunDue()
+	assert.False(t, collected)
+	runs, err := s.Runs("salaries")
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+	assert.Equal(t, Failed, runs[0].Status)
+	assert.Equal(t, "schedule [salaries] is [Cancelled], the run is not retried", runs[0].Error)
+}
+
+func TestRunInProgress(t *testing.T) {
+	now := time.Date(2023, time.March, 31, 10, 0, 0, 0, time.UTC)
+	s := newTestScheduler(t, &now, nil)
+	assert.NoError(t, s.Add(newSchedule("salaries")))
+	settlement := newSchedule("settlement")
+	settlement.Cron = "@daily"
+	assert.NoError(t, s.Add(settlement))
+	assert.NoError(t, s.Add(newSchedule("rent")))
+
+	// the node stops while collecting the endorsements of the transactions of the schedules
+	now = time.Date(2023, time.April, 1, 9, 0, 30, 0, time.UTC)
+	crash := errors.New("crash")
+	s.execute = func(schedule *Schedule, assembled func(txID string) error) (string, bool, error) {
+		assert.NoError(t, assembled("tx-"+schedule.ID))
+		current, err := s.Schedule(schedule.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "tx-"+schedule.ID, current.InProgress.TxID)
+		panic(crash)
+	}
+	for _, id := range []string{"salaries", "settlement", "rent"} {
+		schedule, err := s.Schedule(id)
+		assert.NoError(t, err)
+		assert.PanicsWithValue(t, crash, func() { _ = s.run(schedule) })
+	}
+
+	// once the node restarts, the runs are resolved with the status of their transactions.
+	// Only the run whose transaction has not been submitted is executed again.
+	s = newTestSchedulerWithKVS(s.kvs, &now, map[string][]attempt{
+		"rent": {{txID: "tx-rent-2"}},
+	})
+	execute := s.execute
+	s.execute = func(schedule *Schedule, assembled func(txID string) error) (string, bool, error) {
+		if schedule.ID != "rent" {
+			t.Fatalf("schedule [%s] executed again", schedule.ID)
+		}
+		return execute(schedule, assembled)
+	}
+	s.statuses["tx-settlement"] = Unconfirmed
+	s.notSubmitted["tx-rent"] = true
+	s.RunDue()
+	runs, err := s.Runs("rent")
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+	assert.Equal(t, Succeeded, runs[0].Status)
+	assert.Equal(t, "tx-rent-2", runs[0].TxID)
+	assert.Equal(t, 2, runs[0].Attempts)
+	assert.Equal(t, time.Date(2023, time.April, 1, 9, 0, 0, 0, time.UTC), runs[0].Activation)
+
+	runs, err = s.Runs("salaries")
+	assert.NoError(t, err)
+	assert.Empty(t, runs, "the run is resolved once the status of its transaction is known")
+	runs, err = s.Runs("settlement")
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+	assert.Equal(t, Unconfirmed, runs[0].Status)
+	assert.Equal(t, "tx-settlement", runs[0].TxID)
+
+	s.statuses["tx-salaries"] = Succeeded
+	s.RunDue()
+	runs, err = s.Runs("salaries")
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+	assert.Equal(t, Succeeded, runs[0].Status)
+	assert.Equal(t, "", runs[0].Error)
+	schedule, err := s.Schedule("salaries")
+	assert.NoError(t, err)
+	assert.Nil(t, schedule.InProgress)
+	assert.Equal(t, time.Date(2023, time.May, 1, 9, 0, 0, 0, time.UTC), schedule.Next)
+}
+
+type fakeProv struct {
+	typ string
+}
+
+func (f *fakeProv) GetString(key string) string {
+	return f.typ
+}
+
+func (f *fakeProv) GetInt(key string) int {
+	return 0
+}
+
+func (f *fakeProv) GetDuration(key string) time.Duration {
+	return time.Duration(0)
+}
+
+func (f *fakeProv) GetBool(key string) bool {
+	return false
+}
+
+func (f *fakeProv) GetStringSlice(key string) []string {
+	return nil
+}
+
+func (f *fakeProv) IsSet(key string) bool {
+	return false
+}
+
+func (f *fakeProv) UnmarshalKey(key string, rawVal interface{}) error {
+	return nil
+}
+
+func (f *fakeProv) ConfigFileUsed() string {
+	return ""
+}
+
+func (f *fakeProv) GetPath(key string) string {
+	return ""
+}
+
+func (f *fakeProv) TranslatePath(path string) string {
+	return ""
+}
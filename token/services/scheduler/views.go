@@ -0,0 +1,184 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package scheduler
+
+import (
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// ScheduleIDKey is the key of the application metadata carrying the identifier of the schedule of a transfer
+const ScheduleIDKey = "scheduler.schedule"
+
+// TransferView executes a run of a schedule
+type TransferView struct {
+	schedule *Schedule
+	// assembled, if set, is called with the identifier of the transaction before collecting its endorsements
+	assembled func(txID string) error
+
+	txID      string
+	submitted bool
+}
+
+// NewTransferView returns an instance of the TransferView for the passed schedule.
+// The view does the following:
+// 1. It asks the node of each recipient for a recipient identity.
+// 2. It assembles a transaction transferring the quantity of each recipient from the wallet of the schedule,
+// with the identifier of the schedule as application metadata.
+// 3. It collects the endorsements, submits the transaction for ordering, and waits for its finality.
+// When run by the scheduler, the transaction is persisted with the run before the endorsements are collected.
+func NewTransferView(schedule *Schedule) *TransferView {
+	return &TransferView{schedule: schedule}
+}
+
+func (t *TransferView) Call(context view.Context) (interface{}, error) {
+	s := t.schedule
+	tms := token.GetManagementService(context, token.WithTMSID(s.TMSID))
+	if tms == nil {
+		return nil, errors.Errorf("failed getting TMS [%s]", s.TMSID)
+	}
+	wallet := ttx.GetWallet(context, s.Wallet, token.WithTMSID(s.TMSID))
+	if wallet == nil {
+		return nil, errors.Errorf("wallet [%s:%s] not found", s.Wallet, s.TMSID)
+	}
+
+	precision := tms.PublicParametersManager().Precision()
+	owners := make([]view.Identity, len(s.Recipients))
+	values := make([]token2.Quantity, len(s.Recipients))
+	for i, recipient := range s.Recipients {
+		q, err := token2.ToQuantity(recipient.Quantity, precision)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid quantity [%s]", recipient.Quantity)
+		}
+		values[i] = q
+		owners[i], err = ttx.RequestRecipientIdentity(context, recipient.Node, token.WithTMSID(s.TMSID))
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed getting recipient identity from [%s]", recipient.Node)
+		}
+	}
+
+	opts := []ttx.TxOption{ttx.WithTMSID(s.TMSID)}
+	if !s.Auditor.IsNone() {
+		opts = append(opts, ttx.WithAuditor(s.Auditor))
+	}
+	tx, err := ttx.NewAnonymousTransaction(context, opts...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed creating transaction")
+	}
+	t.txID = tx.ID()
+	if err := tx.TransferQuantities(wallet, s.TokenType, values, owners); err != nil {
+		return nil, errors.WithMessage(err, "failed adding transfer")
+	}
+	tx.SetApplicationMetadata(ScheduleIDKey, []byte(s.ID))
+	if t.assembled != nil {
+		if err := t.assembled(tx.ID()); err != nil {
+			return nil, errors.WithMessagef(err, "failed recording transaction [%s]", tx.ID())
+		}
+	}
+
+	if _, err := context.RunView(ttx.NewCollectEndorsementsView(tx)); err != nil {
+		return nil, errors.WithMessage(err, "failed collecting endorsements")
+	}
+	// from now on the transaction might be committed, a failure must not lead to a new transaction
+	t.submitted = true
+	if _, err := context.RunView(ttx.NewOrderingAndFinalityView(tx)); err != nil {
+		return nil, errors.WithMessage(err, "failed ordering transaction")
+	}
+	return tx.ID(), nil
+}
+
+// ReceiveTransferView accepts the tokens transferred by a run of a schedule to the default owner wallet
+type ReceiveTransferView struct{}
+
+func (r *ReceiveTransferView) Call(context view.Context) (interface{}, error) {
+	id, err := ttx.RespondRequestRecipientIdentity(context)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed responding to recipient identity request")
+	}
+	tx, err := ttx.ReceiveTransaction(context)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed receiving transaction")
+	}
+	outputs, err := tx.Outputs()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting outputs of [%s]", tx.ID())
+	}
+	if outputs.ByRecipient(id).Count() == 0 {
+		return nil, errors.Errorf("transaction [%s] transfers nothing to [%s]", tx.ID(), id)
+	}
+	if _, err := context.RunView(ttx.NewAcceptView(tx)); err != nil {
+		return nil, errors.WithMessagef(err, "failed accepting transaction [%s]", tx.ID())
+	}
+	if _, err := context.RunView(ttx.NewFinalityView(tx)); err != nil {
+		return nil, errors.WithMessagef(err, "transaction [%s] not committed", tx.ID())
+	}
+	return tx.ID(), nil
+}
+
+// InstallViews registers the responder of the scheduled transfers
+func InstallViews(sp view2.ServiceProvider) error {
+	if err := view2.GetRegistry(sp).RegisterResponder(&ReceiveTransferView{}, &TransferView{}); err != nil {
+		return errors.Wrap(err, "failed registering scheduled transfer responder")
+	}
+	return nil
+}
+
+// newExecutor returns an executor that runs the TransferView with the view manager of the passed service provider
+func newExecutor(sp view2.ServiceProvider) Executor {
+	return func(schedule *Schedule, assembled func(txID string) error) (string, bool, error) {
+		v := NewTransferView(schedule)
+		v.assembled = assembled
+		if _, err := view2.GetManager(sp).InitiateView(v); err != nil {
+			return v.txID, v.submitted, err
+		}
+		return v.txID, v.submitted, nil
+	}
+}
+
+// newStatusChecker returns a status checker that looks up the transactions in the vault of the passed TMS.
+// A transaction not yet final has been submitted only if its lifecycle reached the Distributed phase,
+// because, when the node starts, the recovery of the ttx service broadcasts these transactions again and aborts the others.
+func newStatusChecker(sp view2.ServiceProvider, tmsID token.TMSID) StatusChecker {
+	return func(txID string) (RunStatus, bool, error) {
+		net := network.GetInstance(sp, tmsID.Network, tmsID.Channel)
+		if net == nil {
+			return "", false, errors.Errorf("network [%s:%s] not found", tmsID.Network, tmsID.Channel)
+		}
+		v, err := net.Vault(tmsID.Namespace)
+		if err != nil {
+			return "", false, errors.WithMessagef(err, "failed getting vault [%s]", tmsID.Namespace)
+		}
+		status, err := v.Status(txID)
+		if err != nil {
+			return "", false, errors.WithMessagef(err, "failed getting status of [%s]", txID)
+		}
+		switch status {
+		case network.Valid:
+			return Succeeded, true, nil
+		case network.Invalid:
+			return Failed, true, nil
+		}
+		record, err := ttx.GetLifecycle(sp).Get(txID)
+		if err != nil {
+			return "", false, errors.WithMessagef(err, "failed getting lifecycle record of [%s]", txID)
+		}
+		switch {
+		case record != nil && record.Phase >= ttx.Distributed:
+			return Unconfirmed, true, nil
+		case record == nil && status == network.Busy:
+			// the vault knows the transaction, but not its lifecycle, it might have been submitted
+			return Unconfirmed, true, nil
+		default:
+			return Failed, false, nil
+		}
+	}
+}
@@ -20,6 +20,7 @@ type manager struct {
 	numRetry             int
 	timeout              time.Duration
 	requestCertification bool
+	certClient           CertificationClient
 	precision            uint64
 	metricsAgent         MetricsAgent
 }
@@ -30,6 +31,7 @@ func NewManager(
 	numRetry int,
 	timeout time.Duration,
 	requestCertification bool,
+	certClient CertificationClient,
 	precision uint64,
 	metricsAgent MetricsAgent,
 ) *manager {
@@ -39,6 +41,7 @@ func NewManager(
 		numRetry:             numRetry,
 		timeout:              timeout,
 		requestCertification: requestCertification,
+		certClient:           certClient,
 		precision:            precision,
 		metricsAgent:         metricsAgent,
 	}
@@ -53,6 +56,7 @@ func (m *manager) NewSelector(id string) (token.Selector, error) {
 		numRetry:             m.numRetry,
 		timeout:              m.timeout,
 		requestCertification: m.requestCertification,
+		certClient:           m.certClient,
 		metricsAgent:         m.metricsAgent,
 	}, nil
 }
@@ -89,6 +89,15 @@ func (s *selectorService) SelectorManager(network string, channel string, namesp
 		tms.Vault().NewQueryEngine(),
 		locker,
 	)
+	var certClient CertificationClient
+	if s.requestCertification && tms.PublicParametersManager().GraphHiding() {
+		cc, err := tms.CertificationClient()
+		if err != nil {
+			logger.Warnf("failed getting certification client for [%s:%s:%s], on-demand certification disabled [%s]", tms.Network(), tms.Channel(), tms.Namespace(), err)
+		} else {
+			certClient = cc
+		}
+	}
 	manager = NewManager(
 		locker,
 		func() QueryService {
@@ -97,6 +106,7 @@ func (s *selectorService) SelectorManager(network string, channel string, namesp
 		s.numRetry,
 		s.timeout,
 		s.requestCertification,
+		certClient,
 		tms.PublicParametersManager().Precision(),
 		metrics.Get(s.sp),
 	)
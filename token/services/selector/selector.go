@@ -16,6 +16,7 @@ import (
 	"go.uber.org/zap/zapcore"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/metrics"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
@@ -38,6 +39,12 @@ type MetricsAgent interface {
 	EmitKey(val float32, event ...string)
 }
 
+// CertificationClient is used to check and request the certification of tokens
+type CertificationClient interface {
+	IsCertified(id *token2.ID) bool
+	RequestCertification(ids ...*token2.ID) error
+}
+
 type selector struct {
 	txID         string
 	locker       Locker
@@ -47,12 +54,18 @@ type selector struct {
 	numRetry             int
 	timeout              time.Duration
 	requestCertification bool
+	certClient           CertificationClient
 
 	metricsAgent MetricsAgent
 }
 
 // Select selects tokens to be spent based on ownership, quantity, and type
-func (s *selector) Select(ownerFilter token.OwnerFilter, q, tokenType string) ([]*token2.ID, token2.Quantity, error) {
+func (s *selector) Select(ownerFilter token.OwnerFilter, q, tokenType string) (ids []*token2.ID, sum token2.Quantity, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.Get().SelectionDuration.With("outcome", metrics.Outcome(err)).Observe(metrics.Since(start))
+	}()
+
 	if ownerFilter == nil {
 		ownerFilter = &allOwners{}
 	}
@@ -111,6 +124,7 @@ func (s *selector) selectByID(ownerFilter token.OwnerFilter, q string, tokenType
 		potentialSumWithNonCertified = token2.NewZeroQuantity(s.precision)
 		toBeSpent = nil
 		var toBeCertified []*token2.ID
+		var toBeCertifiedQuantities []token2.Quantity
 
 		reclaim := s.numRetry == 1 || i > 0
 		numNext := 0
@@ -134,6 +148,7 @@ func (s *selector) selectByID(ownerFilter token.OwnerFilter, q string, tokenType
 			// lock the token
 			if _, err := s.locker.Lock(t.Id, s.txID, reclaim); err != nil {
 				potentialSumWithLocked = potentialSumWithLocked.Add(q)
+				metrics.Get().LockContention.Add(1)
 
 				if logger.IsEnabledFor(zapcore.DebugLevel) {
 					logger.Debugf("token [%s,%v] cannot be locked [%s]", q, tokenType, err)
@@ -141,6 +156,15 @@ func (s *selector) selectByID(ownerFilter token.OwnerFilter, q string, tokenType
 				continue
 			}
 
+			// check the certification, if needed
+			if !s.isCertified(t.Id) {
+				logger.Debugf("token [%s] is not certified yet, quantity [%s]", t.Id, q.Decimal())
+				toBeCertified = append(toBeCertified, t.Id)
+				toBeCertifiedQuantities = append(toBeCertifiedQuantities, q)
+				potentialSumWithNonCertified = potentialSumWithNonCertified.Add(q)
+				continue
+			}
+
 			// Append token
 			logger.Debugf("adding quantity [%s]", q.Decimal())
 			toBeSpent = append(toBeSpent, t.Id)
@@ -155,10 +179,25 @@ func (s *selector) selectByID(ownerFilter token.OwnerFilter, q string, tokenType
 
 		s.metricsAgent.EmitKey(0, "selector", "count", "selectByIDNumNext", uuid+strconv.Itoa(i), strconv.Itoa(numNext))
 
+		if target.Cmp(sum) > 0 && len(toBeCertified) != 0 && target.Cmp(potentialSumWithNonCertified) <= 0 {
+			// funds are enough only if the non-certified tokens are included, request their certification
+			logger.Debugf("token selection: sufficient funds but partially not certified, request certification")
+			certified, newSum, err := s.certify(target, sum, toBeCertified, toBeCertifiedQuantities)
+			if err != nil {
+				logger.Warnf("token selection: failed requesting certification, retry later [%s]", err)
+			} else {
+				toBeSpent = append(toBeSpent, certified...)
+				sum = newSum
+				toBeCertified = nil
+			}
+		}
+
 		concurrencyIssue := false
 		if target.Cmp(sum) <= 0 {
 			err := s.concurrencyCheck(toBeSpent)
 			if err == nil {
+				// the tokens waiting for certification are not needed
+				s.locker.UnlockIDs(toBeCertified...)
 				return toBeSpent, sum, nil
 			}
 			concurrencyIssue = true
@@ -199,8 +238,8 @@ func (s *selector) selectByID(ownerFilter token.OwnerFilter, q string, tokenType
 			}
 
 			if target.Cmp(potentialSumWithNonCertified) <= 0 && potentialSumWithNonCertified.Cmp(sum) != 0 {
-				// funds are potentially enough but they are locked
-				logger.Debugf("token selection: it is time to fail but how, sufficient funds but locked")
+				// funds are potentially enough, without considering the locked ones, but they are not certified
+				logger.Debugf("token selection: it is time to fail but how, sufficient funds but not certified")
 				return nil, nil, errors.WithMessagef(
 					token.SelectorSufficientButNotCertifiedFunds,
 					"token selection failed: sufficient but partially not certified, potential [%s] tokens of type [%s] are available", potentialSumWithNonCertified, tokenType,
@@ -216,6 +255,7 @@ func (s *selector) selectByID(ownerFilter token.OwnerFilter, q string, tokenType
 		}
 
 		logger.Debugf("token selection: let's wait [%v] before retry...", s.timeout)
+		metrics.Get().SelectionRetries.Add(1)
 		time.Sleep(s.timeout)
 	}
 }
@@ -246,6 +286,7 @@ func (s *selector) selectByOwner(ownerFilter token.OwnerFilter, q string, tokenT
 		potentialSumWithNonCertified = token2.NewZeroQuantity(s.precision)
 		toBeSpent = nil
 		var toBeCertified []*token2.ID
+		var toBeCertifiedQuantities []token2.Quantity
 
 		reclaim := s.numRetry == 1 || i > 0
 		for {
@@ -284,6 +325,7 @@ func (s *selector) selectByOwner(ownerFilter token.OwnerFilter, q string, tokenT
 			// lock the token
 			if _, err := s.locker.Lock(t.Id, s.txID, reclaim); err != nil {
 				potentialSumWithLocked = potentialSumWithLocked.Add(q)
+				metrics.Get().LockContention.Add(1)
 
 				if logger.IsEnabledFor(zapcore.DebugLevel) {
 					logger.Debugf("token [%s,%s,%v] cannot be locked [%s]", q, tokenType, rightOwner, err)
@@ -291,6 +333,15 @@ func (s *selector) selectByOwner(ownerFilter token.OwnerFilter, q string, tokenT
 				continue
 			}
 
+			// check the certification, if needed
+			if !s.isCertified(t.Id) {
+				logger.Debugf("token [%s] is not certified yet, quantity [%s]", t.Id, q.Decimal())
+				toBeCertified = append(toBeCertified, t.Id)
+				toBeCertifiedQuantities = append(toBeCertifiedQuantities, q)
+				potentialSumWithNonCertified = potentialSumWithNonCertified.Add(q)
+				continue
+			}
+
 			// Append token
 			logger.Debugf("adding quantity [%s]", q.Decimal())
 			toBeSpent = append(toBeSpent, t.Id)
@@ -303,10 +354,25 @@ func (s *selector) selectByOwner(ownerFilter token.OwnerFilter, q string, tokenT
 			}
 		}
 
+		if target.Cmp(sum) > 0 && len(toBeCertified) != 0 && target.Cmp(potentialSumWithNonCertified) <= 0 {
+			// funds are enough only if the non-certified tokens are included, request their certification
+			logger.Debugf("token selection: sufficient funds but partially not certified, request certification")
+			certified, newSum, err := s.certify(target, sum, toBeCertified, toBeCertifiedQuantities)
+			if err != nil {
+				logger.Warnf("token selection: failed requesting certification, retry later [%s]", err)
+			} else {
+				toBeSpent = append(toBeSpent, certified...)
+				sum = newSum
+				toBeCertified = nil
+			}
+		}
+
 		concurrencyIssue := false
 		if target.Cmp(sum) <= 0 {
 			err := s.concurrencyCheck(toBeSpent)
 			if err == nil {
+				// the tokens waiting for certification are not needed
+				s.locker.UnlockIDs(toBeCertified...)
 				return toBeSpent, sum, nil
 			}
 			concurrencyIssue = true
@@ -347,8 +413,8 @@ func (s *selector) selectByOwner(ownerFilter token.OwnerFilter, q string, tokenT
 			}
 
 			if target.Cmp(potentialSumWithNonCertified) <= 0 && potentialSumWithNonCertified.Cmp(sum) != 0 {
-				// funds are potentially enough but they are locked
-				logger.Debugf("token selection: it is time to fail but how, sufficient funds but locked")
+				// funds are potentially enough, without considering the locked ones, but they are not certified
+				logger.Debugf("token selection: it is time to fail but how, sufficient funds but not certified")
 				return nil, nil, errors.WithMessagef(
 					token.SelectorSufficientButNotCertifiedFunds,
 					"token selection failed: sufficient but partially not certified, potential [%s] tokens of type [%s] are available", potentialSumWithNonCertified, tokenType,
@@ -364,10 +430,38 @@ func (s *selector) selectByOwner(ownerFilter token.OwnerFilter, q string, tokenT
 		}
 
 		logger.Debugf("token selection: let's wait [%v] before retry...", s.timeout)
+		metrics.Get().SelectionRetries.Add(1)
 		time.Sleep(s.timeout)
 	}
 }
 
+// isCertified returns true if the passed token does not need any certification or if it has been already certified.
+func (s *selector) isCertified(id *token2.ID) bool {
+	if !s.requestCertification || s.certClient == nil {
+		return true
+	}
+	return s.certClient.IsCertified(id)
+}
+
+// certify requests the certification of the shortest prefix of the passed non-certified tokens that allows
+// the passed sum to reach the target.
+// On success, the tokens not needed are unlocked, and certify returns the ids of the tokens that have been certified
+// and the updated sum. On failure, the caller is responsible for unlocking all the passed tokens.
+func (s *selector) certify(target, sum token2.Quantity, ids []*token2.ID, quantities []token2.Quantity) ([]*token2.ID, token2.Quantity, error) {
+	newSum := token2.NewZeroQuantity(s.precision).Add(sum)
+	n := 0
+	for n < len(ids) && target.Cmp(newSum) > 0 {
+		newSum = newSum.Add(quantities[n])
+		n++
+	}
+
+	if err := s.certClient.RequestCertification(ids[:n]...); err != nil {
+		return nil, nil, errors.WithMessagef(err, "failed requesting certification of [%v]", ids[:n])
+	}
+	s.locker.UnlockIDs(ids[n:]...)
+	return ids[:n], newSum, nil
+}
+
 type allOwners struct{}
 
 func (a *allOwners) ID() string {
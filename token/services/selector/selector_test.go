@@ -0,0 +1,194 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package selector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+type fakeIterator struct {
+	tokens []*token2.UnspentToken
+}
+
+func (it *fakeIterator) Close() {}
+
+func (it *fakeIterator) Next() (*token2.UnspentToken, error) {
+	if len(it.tokens) == 0 {
+		return nil, nil
+	}
+	t := it.tokens[0]
+	it.tokens = it.tokens[1:]
+	return t, nil
+}
+
+type fakeQueryService struct {
+	tokens []*token2.UnspentToken
+}
+
+func (q *fakeQueryService) UnspentTokensIterator() (*token.UnspentTokensIterator, error) {
+	return &token.UnspentTokensIterator{UnspentTokensIterator: &fakeIterator{tokens: q.tokens}}, nil
+}
+
+func (q *fakeQueryService) UnspentTokensIteratorBy(id, typ string) (*token.UnspentTokensIterator, error) {
+	return q.UnspentTokensIterator()
+}
+
+func (q *fakeQueryService) GetTokens(inputs ...*token2.ID) ([]*token2.Token, error) {
+	return nil, nil
+}
+
+type fakeLocker struct {
+	locked map[token2.ID]string
+}
+
+func (l *fakeLocker) Lock(id *token2.ID, txID string, reclaim bool) (string, error) {
+	if other, ok := l.locked[*id]; ok {
+		return other, errors.Errorf("already locked by [%s]", other)
+	}
+	l.locked[*id] = txID
+	return "", nil
+}
+
+func (l *fakeLocker) UnlockIDs(ids ...*token2.ID) []*token2.ID {
+	for _, id := range ids {
+		delete(l.locked, *id)
+	}
+	return nil
+}
+
+func (l *fakeLocker) UnlockByTxID(txID string) {}
+
+func (l *fakeLocker) IsLocked(id *token2.ID) bool {
+	_, ok := l.locked[*id]
+	return ok
+}
+
+type fakeCertClient struct {
+	certified map[token2.ID]bool
+	requested [][]*token2.ID
+	err       error
+}
+
+func (c *fakeCertClient) IsCertified(id *token2.ID) bool {
+	return c.certified[*id]
+}
+
+func (c *fakeCertClient) RequestCertification(ids ...*token2.ID) error {
+	c.requested = append(c.requested, ids)
+	if c.err != nil {
+		return c.err
+	}
+	for _, id := range ids {
+		c.certified[*id] = true
+	}
+	return nil
+}
+
+type fakeMetricsAgent struct{}
+
+func (m *fakeMetricsAgent) EmitKey(val float32, event ...string) {}
+
+func newTestSelector(tokens []*token2.UnspentToken, cc *fakeCertClient) (*selector, *fakeLocker) {
+	l := &fakeLocker{locked: map[token2.ID]string{}}
+	return &selector{
+		txID:                 "tx",
+		locker:               l,
+		queryService:         &fakeQueryService{tokens: tokens},
+		precision:            64,
+		numRetry:             2,
+		timeout:              time.Millisecond,
+		requestCertification: true,
+		certClient:           cc,
+		metricsAgent:         &fakeMetricsAgent{},
+	}, l
+}
+
+func unspentTokens() []*token2.UnspentToken {
+	return []*token2.UnspentToken{
+		{Id: &token2.ID{TxId: "a", Index: 0}, Owner: &token2.Owner{}, Type: "ABC", Quantity: "0x5"},
+		{Id: &token2.ID{TxId: "b", Index: 0}, Owner: &token2.Owner{}, Type: "ABC", Quantity: "0x5"},
+		{Id: &token2.ID{TxId: "c", Index: 0}, Owner: &token2.Owner{}, Type: "ABC", Quantity: "0x5"},
+	}
+}
+
+func TestSelectRequestsCertificationOnDemand(t *testing.T) {
+	tokens := unspentTokens()
+	cc := &fakeCertClient{certified: map[token2.ID]bool{*tokens[0].Id: true}}
+	s, l := newTestSelector(tokens, cc)
+
+	ids, sum, err := s.Select(nil, "8", "ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, "10", sum.Decimal())
+	assert.Equal(t, []*token2.ID{tokens[0].Id, tokens[1].Id}, ids)
+
+	// only the missing token has been certified, the others have been released
+	assert.Equal(t, [][]*token2.ID{{tokens[1].Id}}, cc.requested)
+	assert.True(t, l.IsLocked(tokens[0].Id))
+	assert.True(t, l.IsLocked(tokens[1].Id))
+	assert.False(t, l.IsLocked(tokens[2].Id))
+}
+
+func TestSelectCertificationFailure(t *testing.T) {
+	tokens := unspentTokens()
+	cc := &fakeCertClient{certified: map[token2.ID]bool{}, err: errors.New("queue full")}
+	s, l := newTestSelector(tokens, cc)
+
+	_, _, err := s.Select(nil, "8", "ABC")
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, token.SelectorSufficientButNotCertifiedFunds))
+	assert.Len(t, cc.requested, 2)
+	assert.Empty(t, l.locked)
+}
+
+func TestSelectNoCertificationRequired(t *testing.T) {
+	tokens := unspentTokens()
+	cc := &fakeCertClient{certified: map[token2.ID]bool{}}
+	s, _ := newTestSelector(tokens, cc)
+	s.requestCertification = false
+
+	ids, sum, err := s.Select(nil, "8", "ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, "10", sum.Decimal())
+	assert.Len(t, ids, 2)
+	assert.Empty(t, cc.requested)
+}
+
+func TestSelectReleasesTokensNotCertified(t *testing.T) {
+	tokens := unspentTokens()
+	cc := &fakeCertClient{certified: map[token2.ID]bool{*tokens[1].Id: true, *tokens[2].Id: true}}
+	s, l := newTestSelector(tokens, cc)
+
+	// the certified tokens cover the target, the one locked while waiting for certification is released
+	ids, sum, err := s.Select(nil, "8", "ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, "10", sum.Decimal())
+	assert.Equal(t, []*token2.ID{tokens[1].Id, tokens[2].Id}, ids)
+	assert.Empty(t, cc.requested)
+	assert.False(t, l.IsLocked(tokens[0].Id))
+	assert.True(t, l.IsLocked(tokens[1].Id))
+	assert.True(t, l.IsLocked(tokens[2].Id))
+}
+
+func TestSelectLockedBeforeNotCertified(t *testing.T) {
+	tokens := unspentTokens()
+	cc := &fakeCertClient{certified: map[token2.ID]bool{*tokens[1].Id: true}, err: errors.New("queue full")}
+	s, l := newTestSelector(tokens, cc)
+	l.locked[*tokens[0].Id] = "other"
+
+	// the funds are enough both with the locked token and with the token not certified, the locked funds are reported
+	_, _, err := s.Select(nil, "8", "ABC")
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, token.SelectorSufficientButLockedFunds))
+	assert.Equal(t, map[token2.ID]string{*tokens[0].Id: "other"}, l.locked)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sink
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Publisher publishes payloads to a message queue
+type Publisher interface {
+	// Publish publishes the passed payload.
+	// The key is the identifier of the transaction the message refers to, it can be used to partition the queue.
+	Publish(key string, payload []byte) error
+}
+
+// PublisherProvider creates publishers, for example for Kafka or for an AMQP broker
+type PublisherProvider interface {
+	// NewPublisher returns a publisher for the passed provider-specific options
+	NewPublisher(opts interface{}) (Publisher, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]PublisherProvider)
+)
+
+// RegisterPublisher makes a publisher provider available by the provided name.
+// If RegisterPublisher is called twice with the same name or if provider is nil,
+// it panics.
+func RegisterPublisher(name string, provider PublisherProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if provider == nil {
+		panic("Register provider is nil")
+	}
+	if _, dup := providers[name]; dup {
+		panic("Register called twice for provider " + name)
+	}
+	providers[name] = provider
+}
+
+// Publishers returns a sorted list of the names of the registered publisher providers.
+func Publishers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	list := make([]string, 0, len(providers))
+	for name := range providers {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// NewPublisher returns a publisher using the provider selected by the passed configuration
+func NewPublisher(c *config.Publisher) (Publisher, error) {
+	providersMu.RLock()
+	provider, ok := providers[c.Type]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("publisher provider [%s] not found, available %v", c.Type, Publishers())
+	}
+	p, err := provider.NewPublisher(c.Opts)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed creating publisher with provider [%s]", c.Type)
+	}
+	return p, nil
+}
+
+// UnmarshalOpts converts the passed provider-specific options to the passed structure
+func UnmarshalOpts(opts interface{}, out interface{}) error {
+	raw, err := yaml.Marshal(opts)
+	if err != nil {
+		return errors.Wrap(err, "failed marshalling options")
+	}
+	if err := yaml.Unmarshal(raw, out); err != nil {
+		return errors.Wrap(err, "failed unmarshalling options")
+	}
+	return nil
+}
+
+// publisherTarget delivers the messages through a publisher
+type publisherTarget struct {
+	publisher Publisher
+}
+
+func (p *publisherTarget) Deliver(message *Message, payload []byte) error {
+	return p.publisher.Publish(message.TxID, payload)
+}
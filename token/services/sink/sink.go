@@ -0,0 +1,194 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sink
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/eventstream"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.sink")
+
+const (
+	cursorPrefix = "sink.cursor"
+
+	defaultMaxAttempts    = 5
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+)
+
+// Message is the JSON payload forwarded for each event
+type Message struct {
+	Sequence  uint64     `json:"sequence"`
+	Type      string     `json:"type"`
+	Network   string     `json:"network"`
+	Channel   string     `json:"channel,omitempty"`
+	Namespace string     `json:"namespace"`
+	TxID      string     `json:"txID"`
+	WalletID  string     `json:"walletID,omitempty"`
+	WalletIDs []string   `json:"walletIDs,omitempty"`
+	TokenID   *token2.ID `json:"tokenID,omitempty"`
+	TokenType string     `json:"tokenType,omitempty"`
+	Quantity  string     `json:"quantity,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+}
+
+// NewMessage returns the message of the passed event
+func NewMessage(event *eventstream.Event) *Message {
+	return &Message{
+		Sequence:  event.Sequence,
+		Type:      string(event.Type),
+		Network:   event.TMSID.Network,
+		Channel:   event.TMSID.Channel,
+		Namespace: event.TMSID.Namespace,
+		TxID:      event.TxID,
+		WalletID:  event.WalletID,
+		WalletIDs: event.WalletIDs,
+		TokenID:   event.TokenID,
+		TokenType: event.TokenType,
+		Quantity:  event.Quantity,
+		Timestamp: event.Timestamp,
+	}
+}
+
+// Target is the external system a sink forwards the messages to
+type Target interface {
+	// Deliver forwards the passed message, already marshalled in the passed payload
+	Deliver(message *Message, payload []byte) error
+}
+
+// KVS models the key-value store the cursors of the sinks are persisted in
+type KVS interface {
+	Exists(id string) bool
+	Put(id string, state interface{}) error
+	Get(id string, state interface{}) error
+}
+
+// Sink forwards the events of a stream to a target, in order and at least once.
+// It persists the sequence number of the last event forwarded, so that it resumes from there after a restart.
+type Sink struct {
+	id     string
+	tmsID  token.TMSID
+	kvs    KVS
+	target Target
+	retry  config.Retry
+	sleep  func(time.Duration)
+}
+
+// NewSink returns a sink with the passed identifier forwarding the events of the passed TMS to the passed target
+func NewSink(id string, tmsID token.TMSID, kvs KVS, target Target, retry *config.Retry) *Sink {
+	s := &Sink{
+		id:     id,
+		tmsID:  tmsID,
+		kvs:    kvs,
+		target: target,
+		retry: config.Retry{
+			MaxAttempts:    defaultMaxAttempts,
+			InitialBackoff: defaultInitialBackoff,
+			MaxBackoff:     defaultMaxBackoff,
+		},
+		sleep: time.Sleep,
+	}
+	if retry != nil {
+		if retry.MaxAttempts > 0 {
+			s.retry.MaxAttempts = retry.MaxAttempts
+		}
+		if retry.InitialBackoff > 0 {
+			s.retry.InitialBackoff = retry.InitialBackoff
+		}
+		if retry.MaxBackoff > 0 {
+			s.retry.MaxBackoff = retry.MaxBackoff
+		}
+	}
+	return s
+}
+
+// Start subscribes the sink to the passed stream.
+// The sink resumes after the last event it forwarded or, the first time, from the head of the stream.
+func (s *Sink) Start(stream *eventstream.Stream, walletID string) (*eventstream.Subscription, error) {
+	opts := []eventstream.SubscribeOption{eventstream.WithWallet(walletID)}
+	cursor, ok, err := s.Cursor()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		opts = append(opts, eventstream.WithCursor(cursor))
+	}
+	sub, err := stream.Subscribe(s, opts...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed subscribing sink [%s]", s.id)
+	}
+	return sub, nil
+}
+
+// Cursor returns the sequence number of the last event forwarded, if any
+func (s *Sink) Cursor() (uint64, bool, error) {
+	k, err := s.cursorKey()
+	if err != nil {
+		return 0, false, err
+	}
+	if !s.kvs.Exists(k) {
+		return 0, false, nil
+	}
+	var cursor uint64
+	if err := s.kvs.Get(k, &cursor); err != nil {
+		return 0, false, errors.WithMessagef(err, "failed loading the cursor of sink [%s]", s.id)
+	}
+	return cursor, true, nil
+}
+
+// OnEvent forwards the passed event, retrying with an exponential backoff.
+// If all the attempts fail, it returns an error and the stream delivers the event again later.
+func (s *Sink) OnEvent(event *eventstream.Event) error {
+	message := NewMessage(event)
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return errors.Wrapf(err, "failed marshalling event [%d]", event.Sequence)
+	}
+
+	backoff := s.retry.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err = s.target.Deliver(message, payload)
+		if err == nil {
+			break
+		}
+		if attempt >= s.retry.MaxAttempts {
+			return errors.WithMessagef(err, "failed forwarding event [%d] to sink [%s] after [%d] attempts", event.Sequence, s.id, attempt)
+		}
+		logger.Debugf("failed forwarding event [%d] to sink [%s], retry in [%s]: [%s]", event.Sequence, s.id, backoff, err)
+		s.sleep(backoff)
+		backoff *= 2
+		if backoff > s.retry.MaxBackoff {
+			backoff = s.retry.MaxBackoff
+		}
+	}
+
+	k, err := s.cursorKey()
+	if err != nil {
+		return err
+	}
+	if err := s.kvs.Put(k, event.Sequence); err != nil {
+		return errors.WithMessagef(err, "failed storing the cursor of sink [%s]", s.id)
+	}
+	return nil
+}
+
+func (s *Sink) cursorKey() (string, error) {
+	k, err := kvs.CreateCompositeKey(cursorPrefix, []string{s.tmsID.String(), s.id})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed creating key for the cursor of sink [%s]", s.id)
+	}
+	return k, nil
+}
@@ -0,0 +1,252 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sink
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/eventstream"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/processor"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	tmsID     = token.TMSID{Network: "n1", Channel: "c1", Namespace: "zkat"}
+	publisher = &fakePublisher{}
+)
+
+func init() {
+	RegisterPublisher("fake", &fakeProvider{publisher: publisher})
+}
+
+func TestWebhook(t *testing.T) {
+	secret := "secret"
+	var lock sync.Mutex
+	var received []*Message
+	failures := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		payload, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "sha256="+Sign([]byte(secret), payload), r.Header.Get(SignatureHeader))
+		m := &Message{}
+		assert.NoError(t, json.Unmarshal(payload, m))
+		assert.Equal(t, m.Type, r.Header.Get(EventHeader))
+		received = append(received, m)
+	}))
+	defer server.Close()
+
+	kvs := &memKVS{m: map[string][]byte{}}
+	stream, err := eventstream.NewStream(tmsID, kvs, func() uint64 { return 64 })
+	assert.NoError(t, err)
+	webhook, err := NewWebhook(&config.Webhook{ID: "backoffice", URL: server.URL, Secret: secret})
+	assert.NoError(t, err)
+	s := NewSink("backoffice", tmsID, kvs, webhook, &config.Retry{MaxAttempts: 3})
+	var backoffs []time.Duration
+	s.sleep = func(d time.Duration) {
+		lock.Lock()
+		defer lock.Unlock()
+		backoffs = append(backoffs, d)
+	}
+	sub, err := s.Start(stream, "alice")
+	assert.NoError(t, err)
+
+	snapshot := func() ([]*Message, []time.Duration) {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]*Message{}, received...), append([]time.Duration{}, backoffs...)
+	}
+
+	receive(stream, "alice", "0x0a", "tx1")
+	receive(stream, "bob", "0x01", "tx2")
+	assert.Eventually(t, func() bool {
+		messages, _ := snapshot()
+		return len(messages) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	sub.Close()
+
+	messages, delays := snapshot()
+	assert.Equal(t, []time.Duration{defaultInitialBackoff, 2 * defaultInitialBackoff}, delays)
+	assert.Equal(t, "TokenReceived", messages[0].Type)
+	assert.Equal(t, "10", messages[0].Quantity)
+	assert.Equal(t, "alice", messages[0].WalletID)
+	assert.Equal(t, "zkat", messages[0].Namespace)
+	assert.Equal(t, "BalanceChanged", messages[1].Type)
+	cursor, ok, err := s.Cursor()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), cursor)
+
+	// the sink resumes after the last event it forwarded
+	receive(stream, "alice", "0x02", "tx3")
+	s = NewSink("backoffice", tmsID, kvs, webhook, nil)
+	sub, err = s.Start(stream, "alice")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		messages, _ := snapshot()
+		return len(messages) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	sub.Close()
+	messages, _ = snapshot()
+	assert.Equal(t, uint64(5), messages[2].Sequence)
+	assert.Equal(t, "tx3", messages[2].TxID)
+
+	// the attempts are bounded, the event is handed back to the stream
+	server.Close()
+	s = NewSink("backoffice", tmsID, kvs, webhook, &config.Retry{MaxAttempts: 2})
+	s.sleep = func(time.Duration) {}
+	event, err := stream.Event(5)
+	assert.NoError(t, err)
+	err = s.OnEvent(event)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed forwarding event [5] to sink [backoffice] after [2] attempts")
+}
+
+func TestPublisher(t *testing.T) {
+	publisher.reset()
+	kvs := &memKVS{m: map[string][]byte{}}
+	stream, err := eventstream.NewStream(tmsID, kvs, func() uint64 { return 64 })
+	assert.NoError(t, err)
+
+	_, err = Start(kvs, tmsID, stream, &config.Sinks{Publishers: []*config.Publisher{{ID: "queue", Type: "kafka"}}})
+	assert.EqualError(t, err, "failed creating publisher for sink [queue]: publisher provider [kafka] not found, available [fake]")
+	_, err = Start(kvs, tmsID, stream, &config.Sinks{Publishers: []*config.Publisher{
+		{ID: "queue", Type: "fake", Opts: map[string]string{"topic": "t"}},
+		{ID: "queue", Type: "fake"},
+	}})
+	assert.EqualError(t, err, "duplicate sink [queue]")
+
+	subs, err := Start(kvs, tmsID, stream, &config.Sinks{Publishers: []*config.Publisher{
+		{ID: "queue", Type: "fake", Opts: map[string]string{"topic": "tokens"}},
+	}})
+	assert.NoError(t, err)
+	assert.Len(t, subs, 1)
+	assert.Equal(t, "tokens", publisher.topic)
+
+	receive(stream, "alice", "0x0a", "tx1")
+	assert.Eventually(t, func() bool { return publisher.count() == 2 }, 5*time.Second, 10*time.Millisecond)
+	subs[0].Close()
+	key, payload := publisher.at(0)
+	assert.Equal(t, "tx1", key)
+	m := &Message{}
+	assert.NoError(t, json.Unmarshal(payload, m))
+	assert.Equal(t, uint64(1), m.Sequence)
+	assert.Equal(t, "TokenReceived", m.Type)
+}
+
+func receive(stream *eventstream.Stream, walletID, quantity, txID string) {
+	stream.OnReceive(processor.NewTokenProcessorEvent(processor.AddToken, &processor.TokenMessage{
+		Namespace: tmsID.Namespace, WalletID: walletID, TokenType: "USD", Quantity: quantity, TxID: txID,
+	}))
+}
+
+type fakeProvider struct {
+	publisher *fakePublisher
+}
+
+func (f *fakeProvider) NewPublisher(opts interface{}) (Publisher, error) {
+	o := &struct {
+		Topic string `yaml:"topic"`
+	}{}
+	if err := UnmarshalOpts(opts, o); err != nil {
+		return nil, err
+	}
+	if len(o.Topic) == 0 {
+		return nil, errors.New("no topic")
+	}
+	f.publisher.topic = o.Topic
+	return f.publisher, nil
+}
+
+type fakePublisher struct {
+	lock     sync.Mutex
+	topic    string
+	keys     []string
+	payloads [][]byte
+}
+
+func (f *fakePublisher) Publish(key string, payload []byte) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.keys = append(f.keys, key)
+	f.payloads = append(f.payloads, payload)
+	return nil
+}
+
+func (f *fakePublisher) reset() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.keys = nil
+	f.payloads = nil
+}
+
+func (f *fakePublisher) count() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.keys)
+}
+
+func (f *fakePublisher) at(i int) (string, []byte) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.keys[i], f.payloads[i]
+}
+
+type memKVS struct {
+	lock sync.Mutex
+	m    map[string][]byte
+}
+
+func (k *memKVS) Exists(id string) bool {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	_, ok := k.m[id]
+	return ok
+}
+
+func (k *memKVS) Put(id string, state interface{}) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	k.m[id] = raw
+	return nil
+}
+
+func (k *memKVS) Get(id string, state interface{}) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	raw, ok := k.m[id]
+	if !ok {
+		return errors.Errorf("state [%s] does not exist", id)
+	}
+	return json.Unmarshal(raw, state)
+}
+
+func (k *memKVS) Delete(id string) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	delete(k.m, id)
+	return nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sink
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/eventstream"
+	"github.com/pkg/errors"
+)
+
+// Start starts the sinks configured for the TMS of the passed stream
+func Start(kvs KVS, tmsID token.TMSID, stream *eventstream.Stream, c *config.Sinks) ([]*eventstream.Subscription, error) {
+	if c == nil {
+		return nil, nil
+	}
+	var subs []*eventstream.Subscription
+	start := func(id string, walletID string, target Target, retry *config.Retry) error {
+		sub, err := NewSink(id, tmsID, kvs, target, retry).Start(stream, walletID)
+		if err != nil {
+			return err
+		}
+		logger.Infof("sink [%s] of [%s] started", id, tmsID)
+		subs = append(subs, sub)
+		return nil
+	}
+	fail := func(err error) ([]*eventstream.Subscription, error) {
+		for _, sub := range subs {
+			sub.Close()
+		}
+		return nil, err
+	}
+
+	ids := map[string]bool{}
+	for _, w := range c.Webhooks {
+		if ids[w.ID] {
+			return fail(errors.Errorf("duplicate sink [%s]", w.ID))
+		}
+		ids[w.ID] = true
+		webhook, err := NewWebhook(w)
+		if err != nil {
+			return fail(err)
+		}
+		if err := start(w.ID, w.Wallet, webhook, w.Retry); err != nil {
+			return fail(err)
+		}
+	}
+	for _, p := range c.Publishers {
+		if ids[p.ID] {
+			return fail(errors.Errorf("duplicate sink [%s]", p.ID))
+		}
+		ids[p.ID] = true
+		publisher, err := NewPublisher(p)
+		if err != nil {
+			return fail(errors.WithMessagef(err, "failed creating publisher for sink [%s]", p.ID))
+		}
+		if err := start(p.ID, p.Wallet, &publisherTarget{publisher: publisher}, p.Retry); err != nil {
+			return fail(err)
+		}
+	}
+	return subs, nil
+}
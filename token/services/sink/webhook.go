@@ -0,0 +1,89 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sink
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SignatureHeader carries the hex-encoded HMAC-SHA256 of the payload, prefixed by `sha256=`
+	SignatureHeader = "X-Token-Signature"
+	// EventHeader carries the type of the event
+	EventHeader = "X-Token-Event"
+	// SequenceHeader carries the sequence number of the event, receivers can use it to discard duplicates
+	SequenceHeader = "X-Token-Sequence"
+
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+// Webhook posts the messages to an HTTP endpoint
+type Webhook struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhook returns a webhook for the passed configuration
+func NewWebhook(c *config.Webhook) (*Webhook, error) {
+	if len(c.URL) == 0 {
+		return nil, errors.Errorf("no url provided for webhook [%s]", c.ID)
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	return &Webhook{
+		url:    c.URL,
+		secret: []byte(c.Secret),
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Deliver posts the passed payload, it fails if the endpoint does not answer with a 2xx status code
+func (w *Webhook) Deliver(message *Message, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return errors.Wrapf(err, "failed creating request for [%s]", w.url)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, message.Type)
+	req.Header.Set(SequenceHeader, strconv.FormatUint(message.Sequence, 10))
+	if len(w.secret) != 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(w.secret, payload))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed posting to [%s]", w.url)
+	}
+	defer resp.Body.Close()
+	// drain the body to reuse the connection
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("unexpected status [%s] from [%s]", resp.Status, w.url)
+	}
+	return nil
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of the passed payload under the passed secret
+func Sign(secret []byte, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
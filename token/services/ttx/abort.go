@@ -0,0 +1,320 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	session2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/session"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/tracker/metrics"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/pkg/errors"
+)
+
+const abortAckTimeout = 30 * time.Second
+
+// AbortRequest is the message the initiator of an aborted transaction sends to the other parties
+type AbortRequest struct {
+	Network   string
+	Channel   string
+	Namespace string
+	TxID      string
+	// Auditor is the identity of the auditor of the transaction, set only in the request sent to the auditor
+	Auditor view.Identity
+	// Reason describes why the transaction has been aborted
+	Reason string
+}
+
+// AbortResponse is the acknowledgement of an AbortRequest
+type AbortResponse struct {
+	TxID string
+}
+
+// AbortView aborts a token transaction assembled by this node that has not been ordered yet
+type AbortView struct {
+	tx     *Transaction
+	reason string
+}
+
+// NewAbortView returns an instance of the AbortView for the passed transaction.
+// The view does the following:
+// 1. It checks that the transaction has not been ordered, otherwise it might still be committed.
+// The check and the next step hold the lock of the transaction, that the ordering views hold while broadcasting it.
+// 2. It marks the transaction as aborted, from now on the ordering views refuse to broadcast it.
+// 3. It releases the tokens locked by the transaction, discards its envelope, if stored,
+// and marks the transaction as Deleted in the owner and auditor databases of this node.
+// 4. It notifies the other parties of the transaction and the auditor, so that they do the same.
+func NewAbortView(tx *Transaction, reason string) *AbortView {
+	return &AbortView{tx: tx, reason: reason}
+}
+
+// Call executes the view.
+// The abort is completed locally even if some parties cannot be notified, in that case an error listing them is returned
+// and the view can be called again to retry the notifications.
+func (a *AbortView) Call(context view.Context) (interface{}, error) {
+	agent := metrics.Get(context)
+	agent.EmitKey(0, "ttx", "start", "abortView", a.tx.ID())
+	defer agent.EmitKey(0, "ttx", "end", "abortView", a.tx.ID())
+
+	span := startSpan(a.tx, "ttx.abort")
+	err := a.abort(context)
+	span.End(err)
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (a *AbortView) abort(context view.Context) error {
+	auditorWallet, err := a.abortLocally(context)
+	if err != nil {
+		return err
+	}
+	logger.Debugf("transaction [%s] aborted locally: [%s]", a.tx.ID(), a.reason)
+
+	// notify the other parties
+	parties, err := a.counterparties(context)
+	if err != nil {
+		return errors.WithMessagef(err, "transaction [%s] aborted locally, failed listing the parties to notify", a.tx.ID())
+	}
+	var failed []string
+	for _, party := range parties {
+		if err := a.notify(context, party, nil); err != nil {
+			logger.Warnf("failed notifying [%s] of the abort of [%s]: [%s]", party, a.tx.ID(), err)
+			failed = append(failed, party.String())
+		}
+	}
+	if !a.tx.Opts.Auditor.IsNone() && auditorWallet == nil {
+		if err := a.notify(context, a.tx.Opts.Auditor, a.tx.Opts.Auditor); err != nil {
+			logger.Warnf("failed notifying auditor [%s] of the abort of [%s]: [%s]", a.tx.Opts.Auditor, a.tx.ID(), err)
+			failed = append(failed, a.tx.Opts.Auditor.String())
+		}
+	}
+	if len(failed) != 0 {
+		return errors.Errorf("transaction [%s] aborted locally, failed notifying %v", a.tx.ID(), failed)
+	}
+	return nil
+}
+
+// abortLocally releases the transaction on this node, unless it has been ordered.
+// It returns the wallet of the auditor of the transaction, if the auditor is this node.
+func (a *AbortView) abortLocally(context view.Context) (*token.AuditorWallet, error) {
+	lifecycle := GetLifecycle(context)
+	unlock := lifecycle.Lock(a.tx.ID())
+	defer unlock()
+
+	record, err := lifecycle.Get(a.tx.ID())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed loading lifecycle record of [%s]", a.tx.ID())
+	}
+	if record != nil && record.Phase == Ordered {
+		return nil, errors.Errorf("transaction [%s] has been ordered already, it cannot be aborted", a.tx.ID())
+	}
+
+	tms := a.tx.TokenService()
+	var auditorWallet *token.AuditorWallet
+	if !a.tx.Opts.Auditor.IsNone() {
+		auditorWallet = tms.WalletManager().AuditorWalletByIdentity(a.tx.Opts.Auditor)
+	}
+	if err := abortLocally(context, tms, a.tx.ID(), auditorWallet); err != nil {
+		return nil, err
+	}
+	a.tx.Release()
+	return auditorWallet, lifecycle.Remove(a.tx.ID())
+}
+
+// counterparties returns the long term identities of the issuers, senders and recipients of the transaction, but this node
+func (a *AbortView) counterparties(context view.Context) ([]view.Identity, error) {
+	var parties []view.Identity
+	for _, issue := range a.tx.TokenRequest.Issues() {
+		parties = append(parties, issue.Issuer)
+		parties = append(parties, issue.Receivers...)
+	}
+	for _, transfer := range a.tx.TokenRequest.Transfers() {
+		parties = append(parties, transfer.Senders...)
+		parties = append(parties, transfer.Receivers...)
+	}
+
+	sigService := a.tx.TokenService().SigService()
+	me := view2.GetIdentityProvider(context).DefaultIdentity()
+	var longTermIdentities []view.Identity
+	for _, party := range parties {
+		if party.IsNone() || sigService.IsMe(party) {
+			continue
+		}
+		longTermIdentity, _, _, err := view2.GetEndpointService(context).Resolve(party)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot resolve long term identity for [%s]", party.UniqueID())
+		}
+		if longTermIdentity.Equal(me) || containsIdentity(longTermIdentities, longTermIdentity) {
+			continue
+		}
+		longTermIdentities = append(longTermIdentities, longTermIdentity)
+	}
+	return longTermIdentities, nil
+}
+
+func (a *AbortView) notify(context view.Context, party view.Identity, auditor view.Identity) error {
+	session, err := session2.NewJSON(context, a, party)
+	if err != nil {
+		return errors.Wrap(err, "failed getting session")
+	}
+	request := &AbortRequest{
+		Network:   a.tx.Network(),
+		Channel:   a.tx.Channel(),
+		Namespace: a.tx.Namespace(),
+		TxID:      a.tx.ID(),
+		Auditor:   auditor,
+		Reason:    a.reason,
+	}
+	if err := session.Send(request); err != nil {
+		return errors.Wrap(err, "failed sending abort request")
+	}
+	response := &AbortResponse{}
+	if err := session.ReceiveWithTimeout(response, abortAckTimeout); err != nil {
+		return errors.Wrap(err, "failed receiving abort ack")
+	}
+	if response.TxID != a.tx.ID() {
+		return errors.Errorf("invalid abort ack, expected [%s], got [%s]", a.tx.ID(), response.TxID)
+	}
+	return nil
+}
+
+// AbortResponderView releases a token transaction aborted by its initiator
+type AbortResponderView struct{}
+
+// Call executes the view.
+// The view does the following:
+// 1. It checks that the request comes from the initiator of the transaction, as recorded when this node received it,
+// and that the aborted transaction has not been committed.
+// 2. It marks the transaction as aborted, releases the tokens it locked, discards its envelope, if stored,
+// and marks it as Deleted in the owner database and, if the request is for the auditor, in the auditor database.
+// 3. It sends back an acknowledgement.
+func (r *AbortResponderView) Call(context view.Context) (interface{}, error) {
+	session := session2.JSON(context)
+	request := &AbortRequest{}
+	if err := session.Receive(request); err != nil {
+		return nil, errors.Wrap(err, "failed receiving abort request")
+	}
+	logger.Debugf("received abort request for [%s]: [%s]", request.TxID, request.Reason)
+
+	if err := r.abort(context, request); err != nil {
+		if err2 := session.SendError(err.Error()); err2 != nil {
+			logger.Errorf("failed sending error back: [%s]", err2)
+		}
+		return nil, err
+	}
+	if err := session.Send(&AbortResponse{TxID: request.TxID}); err != nil {
+		return nil, errors.Wrap(err, "failed sending abort ack")
+	}
+	return nil, nil
+}
+
+func (r *AbortResponderView) abort(context view.Context, request *AbortRequest) error {
+	if err := r.authorize(context, request); err != nil {
+		return err
+	}
+	tms := token.GetManagementService(context, token.WithTMS(request.Network, request.Channel, request.Namespace))
+	if tms == nil {
+		return errors.Errorf("failed getting TMS for [%s:%s:%s]", request.Network, request.Channel, request.Namespace)
+	}
+	var auditorWallet *token.AuditorWallet
+	if !request.Auditor.IsNone() {
+		auditorWallet = tms.WalletManager().AuditorWalletByIdentity(request.Auditor)
+		if auditorWallet == nil {
+			return errors.Errorf("no auditor wallet found for [%s]", request.Auditor)
+		}
+	}
+	if err := abortLocally(context, tms, request.TxID, auditorWallet); err != nil {
+		return err
+	}
+	if err := tms.SelectorManager().Unlock(request.TxID); err != nil {
+		logger.Debugf("no tokens to release for [%s]: [%s]", request.TxID, err)
+	}
+	return nil
+}
+
+// authorize checks that the caller is the initiator of the transaction to abort
+func (r *AbortResponderView) authorize(context view.Context, request *AbortRequest) error {
+	initiator, err := GetLifecycle(context).Initiator(request.TxID)
+	if err != nil {
+		return err
+	}
+	if initiator.IsNone() {
+		return errors.Errorf("transaction [%s] is unknown, refusing to abort it", request.TxID)
+	}
+	caller := context.Session().Info().Caller
+	if !initiator.Equal(caller) {
+		return errors.Errorf("[%s] is not the initiator of transaction [%s], refusing to abort it", caller, request.TxID)
+	}
+	return nil
+}
+
+// abortLocally marks the passed transaction as aborted, discards its envelope, if stored, and marks it as Deleted
+// in the owner database and, if an auditor wallet is passed, in the auditor database, whose locks are released.
+// It fails if the transaction has been committed.
+func abortLocally(sp view2.ServiceProvider, tms *token.ManagementService, txID string, auditorWallet *token.AuditorWallet) error {
+	net := network.GetInstance(sp, tms.Network(), tms.Channel())
+	if net == nil {
+		return errors.Errorf("network [%s:%s] not found", tms.Network(), tms.Channel())
+	}
+	v, err := net.Vault(tms.Namespace())
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting vault [%s]", tms.Namespace())
+	}
+	status, err := v.Status(txID)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting status of [%s]", txID)
+	}
+	if status == network.Valid {
+		return errors.Errorf("transaction [%s] has been committed, it cannot be aborted", txID)
+	}
+	if err := GetLifecycle(sp).MarkAborted(txID); err != nil {
+		return errors.WithMessage(err, "failed marking transaction as aborted")
+	}
+	if status == network.Busy {
+		// the envelope has been stored during the distribution
+		if err := v.DiscardTx(txID); err != nil {
+			return errors.WithMessagef(err, "failed discarding transaction [%s]", txID)
+		}
+	}
+
+	spendReservations.release(txID)
+	if err := NewOwner(sp, tms).SetStatus(txID, Deleted); err != nil {
+		logger.Debugf("no transaction records to delete for [%s]: [%s]", txID, err)
+	}
+	if auditorWallet != nil {
+		auditor := NewAuditor(sp, auditorWallet)
+		if err := auditor.SetStatus(txID, Deleted); err != nil {
+			logger.Debugf("no audit records to delete for [%s]: [%s]", txID, err)
+		}
+		if auditor.db != nil {
+			auditor.db.ReleaseLocks(txID)
+		}
+	}
+	return nil
+}
+
+func containsIdentity(ids []view.Identity, id view.Identity) bool {
+	for _, i := range ids {
+		if i.Equal(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// InstallViews registers the responders of the views of the token transactions that do not need an application-defined responder
+func InstallViews(sp view2.ServiceProvider) error {
+	if err := view2.GetRegistry(sp).RegisterResponder(&AbortResponderView{}, &AbortView{}); err != nil {
+		return errors.Wrap(err, "failed registering abort responder")
+	}
+	return nil
+}
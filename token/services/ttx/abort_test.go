@@ -0,0 +1,146 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	_ "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/memory"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	registry2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/tracker/metrics"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbortView(t *testing.T) {
+	ctx := newAbortContext(t, nil)
+	lifecycle := GetLifecycle(ctx)
+	tx := &Transaction{Payload: &Payload{
+		ID:           "tx1",
+		Network:      "network",
+		Channel:      "channel",
+		Namespace:    "namespace",
+		Transient:    map[string][]byte{},
+		TokenRequest: token.NewRequest(nil, "tx1"),
+	}}
+
+	// the broadcast is in progress, the abort waits for it
+	unlock := lifecycle.Lock("tx1")
+	done := make(chan error, 1)
+	go func() {
+		_, err := NewAbortView(tx, "test").Call(ctx)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("abort did not wait for the broadcast: [%v]", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.NoError(t, lifecycle.Record(tx, Ordered))
+	unlock()
+
+	// once ordered, the transaction cannot be aborted
+	select {
+	case err := <-done:
+		assert.EqualError(t, err, "transaction [tx1] has been ordered already, it cannot be aborted")
+	case <-time.After(5 * time.Second):
+		t.Fatal("abort did not complete")
+	}
+	aborted, err := lifecycle.IsAborted("tx1")
+	assert.NoError(t, err)
+	assert.False(t, aborted)
+}
+
+func TestAbortResponderView(t *testing.T) {
+	alice, bob := view.Identity("alice"), view.Identity("bob")
+	request := &AbortRequest{Network: "network", Channel: "channel", Namespace: "namespace", TxID: "tx1"}
+
+	// this node did not take part in the transaction
+	session := &abortSession{caller: alice}
+	ctx := newAbortContext(t, session)
+	session.push(t, request)
+	_, err := (&AbortResponderView{}).Call(ctx)
+	assert.EqualError(t, err, "transaction [tx1] is unknown, refusing to abort it")
+	assert.Equal(t, "transaction [tx1] is unknown, refusing to abort it", string(session.sentError))
+
+	// only the initiator of the transaction can abort it
+	assert.NoError(t, GetLifecycle(ctx).RecordInitiator("tx1", alice))
+	session.caller = bob
+	session.push(t, request)
+	_, err = (&AbortResponderView{}).Call(ctx)
+	assert.EqualError(t, err, "["+bob.String()+"] is not the initiator of transaction [tx1], refusing to abort it")
+	aborted, err := GetLifecycle(ctx).IsAborted("tx1")
+	assert.NoError(t, err)
+	assert.False(t, aborted)
+
+	session.caller = alice
+	assert.NoError(t, (&AbortResponderView{}).authorize(ctx, request))
+}
+
+// viewContext lets abortContext embed view.Context and still define the Context method
+type viewContext = view.Context
+
+type abortContext struct {
+	viewContext
+	sp      view2.ServiceProvider
+	session *abortSession
+}
+
+func newAbortContext(t *testing.T, session *abortSession) *abortContext {
+	registry := registry2.New()
+	assert.NoError(t, registry.RegisterService(&fakeProv{typ: "memory"}))
+	kvss, err := kvs.New(registry, "memory", "")
+	assert.NoError(t, err)
+	assert.NoError(t, registry.RegisterService(kvss))
+	assert.NoError(t, registry.RegisterService(metrics.NewNullAgent()))
+	return &abortContext{sp: registry, session: session}
+}
+
+func (c *abortContext) GetService(v interface{}) (interface{}, error) {
+	return c.sp.GetService(v)
+}
+
+func (c *abortContext) Session() view.Session {
+	return c.session
+}
+
+func (c *abortContext) Context() context.Context {
+	return context.Background()
+}
+
+type abortSession struct {
+	view.Session
+	caller    view.Identity
+	ch        chan *view.Message
+	sentError []byte
+}
+
+func (s *abortSession) push(t *testing.T, request *AbortRequest) {
+	raw, err := json.Marshal(request)
+	assert.NoError(t, err)
+	s.ch = make(chan *view.Message, 1)
+	s.ch <- &view.Message{Status: view.OK, Payload: raw}
+}
+
+func (s *abortSession) Info() view.SessionInfo {
+	return view.SessionInfo{Caller: s.caller}
+}
+
+func (s *abortSession) Receive() <-chan *view.Message {
+	return s.ch
+}
+
+func (s *abortSession) SendError(payload []byte) error {
+	s.sentError = payload
+	return nil
+}
@@ -116,6 +116,13 @@ func newAuditingViewInitiator(tx *Transaction, local bool) *AuditingViewInitiato
 }
 
 func (a *AuditingViewInitiator) Call(context view.Context) (interface{}, error) {
+	span := startSpan(a.tx, "ttx.audit")
+	res, err := a.audit(context)
+	span.End(err)
+	return res, err
+}
+
+func (a *AuditingViewInitiator) audit(context view.Context) (interface{}, error) {
 	var err error
 	var session view.Session
 	if a.local {
@@ -189,6 +196,7 @@ func (a *AuditingViewInitiator) startRemote(context view.Context) (view.Session,
 	}
 
 	// Send transaction
+	propagate(a.tx)
 	txRaw, err := a.tx.Bytes()
 	if err != nil {
 		return nil, err
@@ -228,6 +236,7 @@ func (a *AuditingViewInitiator) startLocal(context view.Context) (view.Session,
 	right := biChannel.RightSession()
 
 	// Send transaction
+	propagate(a.tx)
 	txRaw, err := a.tx.Bytes()
 	if err != nil {
 		return nil, err
@@ -258,18 +267,28 @@ func NewAuditApproveView(w *token.AuditorWallet, tx *Transaction) *AuditApproveV
 }
 
 func (a *AuditApproveView) Call(context view.Context) (interface{}, error) {
-	// Append audit records
-	if err := auditor.New(context, a.w).Append(a.tx); err != nil {
-		return nil, errors.Wrapf(err, "failed appending audit records for transaction %s", a.tx.ID())
-	}
-
-	if err := a.signAndSendBack(context); err != nil {
+	span := startSpan(a.tx, "ttx.audit_approve")
+	err := a.approve(context)
+	span.End(err)
+	if err != nil {
 		return nil, err
 	}
-
 	return nil, nil
 }
 
+func (a *AuditApproveView) approve(context view.Context) error {
+	if session := context.Session(); session != nil {
+		if err := GetLifecycle(context).RecordInitiator(a.tx.ID(), session.Info().Caller); err != nil {
+			return err
+		}
+	}
+	// Append audit records
+	if err := auditor.New(context, a.w).Append(a.tx); err != nil {
+		return errors.Wrapf(err, "failed appending audit records for transaction %s", a.tx.ID())
+	}
+	return a.signAndSendBack(context)
+}
+
 func (a *AuditApproveView) signAndSendBack(context view.Context) error {
 	logger.Debugf("Signing and sending back transaction... [%s]", a.tx.ID())
 	// Sign
@@ -55,6 +55,13 @@ func NewCollectActionsView(tx *Transaction, actions ...*ActionTransfer) *collect
 }
 
 func (c *collectActionsView) Call(context view.Context) (interface{}, error) {
+	span := startSpan(c.tx, "ttx.collect_actions")
+	res, err := c.collect(context)
+	span.End(err)
+	return res, err
+}
+
+func (c *collectActionsView) collect(context view.Context) (interface{}, error) {
 	ts := token.GetManagementService(context, token.WithChannel(c.tx.Channel()))
 
 	for _, actionTransfer := range c.actions.Transfers {
@@ -102,6 +109,7 @@ func (c *collectActionsView) collectRemote(context view.Context, actionTransfer
 	}
 
 	// Send transaction, actions, action
+	propagate(c.tx)
 	txRaw, err := c.tx.Bytes()
 	assert.NoError(err)
 	assert.NoError(session.Send(txRaw), "failed sending transaction")
@@ -16,6 +16,8 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/tracker/metrics"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	metrics2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/common/metrics"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/tracing"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
 	"github.com/pkg/errors"
 	"go.uber.org/zap/zapcore"
@@ -25,6 +27,10 @@ type signatureRequest struct {
 	Request []byte
 	TxID    []byte
 	Signer  view.Identity
+	// Traceparent is the context of the span of the requester, in the W3C format
+	Traceparent string `json:",omitempty"`
+	// Approvals are the approvals of the transaction, required by the spending policy of the signer above a threshold
+	Approvals map[string][]byte `json:",omitempty"`
 }
 
 func (sr *signatureRequest) MessageToSign() []byte {
@@ -61,22 +67,39 @@ func (c *collectEndorsementsView) Call(context view.Context) (interface{}, error
 	agent.EmitKey(0, "ttx", "start", "collectEndorsements", c.tx.ID())
 	defer agent.EmitKey(0, "ttx", "end", "collectEndorsements", c.tx.ID())
 
+	start := time.Now()
+	span := startSpan(c.tx, "ttx.collect_endorsements")
+	res, err := c.collect(context)
+	span.End(err)
+	metrics2.Get().EndorsementDuration.With("outcome", metrics2.Outcome(err)).Observe(metrics2.Since(start))
+	return res, err
+}
+
+func (c *collectEndorsementsView) collect(context view.Context) (interface{}, error) {
 	// Store transient
 	err := c.tx.storeTransient()
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed storing transient")
 	}
+	lifecycle := GetLifecycle(context)
+	if err := lifecycle.Record(c.tx, Collecting); err != nil {
+		return nil, errors.WithMessage(err, "failed recording transaction phase")
+	}
 
 	// 1. First collect signatures on the token request
 	var distributionList []view.Identity
 
+	span := startSpan(c.tx, "ttx.request_signatures_on_issues")
 	parties, err := c.requestSignaturesOnIssues(context)
+	span.End(err)
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed requesting signatures on issues")
 	}
 	distributionList = append(distributionList, parties...)
 
+	span = startSpan(c.tx, "ttx.request_signatures_on_transfers")
 	parties, err = c.requestSignaturesOnTransfers(context)
+	span.End(err)
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed requesting signatures on transfers")
 	}
@@ -87,17 +110,33 @@ func (c *collectEndorsementsView) Call(context view.Context) (interface{}, error
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed requesting auditing")
 	}
+	if len(auditors) != 0 {
+		if err := lifecycle.Record(c.tx, Audited); err != nil {
+			return nil, errors.WithMessage(err, "failed recording transaction phase")
+		}
+	}
 
 	// 3. Endorse and return the transaction envelope
+	span = startSpan(c.tx, "ttx.request_approval")
 	env, err := c.requestApproval(context)
+	span.End(err)
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed requesting approval")
 	}
+	if err := lifecycle.Record(c.tx, Approved); err != nil {
+		return nil, errors.WithMessage(err, "failed recording transaction phase")
+	}
 
 	// Distribute Env to all parties
-	if err := c.distributeEnv(context, env, distributionList, auditors); err != nil {
+	span = startSpan(c.tx, "ttx.distribute_env")
+	err = c.distributeEnv(context, env, distributionList, auditors)
+	span.End(err)
+	if err != nil {
 		return nil, errors.WithMessage(err, "failed distributing envelope")
 	}
+	if err := lifecycle.Record(c.tx, Distributed); err != nil {
+		return nil, errors.WithMessage(err, "failed recording transaction phase")
+	}
 
 	// Cleanup audit
 	if err := c.cleanupAudit(context); err != nil {
@@ -139,6 +178,13 @@ func (c *collectEndorsementsView) requestSignaturesOnIssues(context view.Context
 		if logger.IsEnabledFor(zapcore.DebugLevel) {
 			logger.Debugf("collecting signature on request (issue) from [%s]", party.UniqueID())
 		}
+		// use the signature of the offline signer, if imported
+		if sigma, ok, err := importedSignature(c.tx, party); err != nil {
+			return nil, err
+		} else if ok {
+			c.tx.TokenRequest.AppendSignature(sigma)
+			continue
+		}
 		if signer, err := c.tx.TokenService().SigService().GetSigner(party); err == nil {
 			if logger.IsEnabledFor(zapcore.DebugLevel) {
 				logger.Debugf("signing [%s][%s]", hash.Hashable(requestRaw).String(), c.tx.ID())
@@ -162,9 +208,10 @@ func (c *collectEndorsementsView) requestSignaturesOnIssues(context view.Context
 		ch := session.Receive()
 
 		signatureRequest := &signatureRequest{
-			Request: requestRaw,
-			TxID:    []byte(c.tx.ID()),
-			Signer:  party,
+			Request:     requestRaw,
+			TxID:        []byte(c.tx.ID()),
+			Signer:      party,
+			Traceparent: traceparent(c.tx.ID()),
 		}
 		signatureRequestRaw, err := Marshal(signatureRequest)
 		if err != nil {
@@ -244,20 +291,32 @@ func (c *collectEndorsementsView) requestSignaturesOnTransfers(context view.Cont
 
 		for _, party := range signers {
 			signatureRequest := &signatureRequest{
-				Request: requestRaw,
-				TxID:    []byte(c.tx.ID()),
-				Signer:  party,
+				Request:     requestRaw,
+				TxID:        []byte(c.tx.ID()),
+				Signer:      party,
+				Traceparent: traceparent(c.tx.ID()),
+				Approvals:   spendApprovals(c.tx),
 			}
 
 			if logger.IsEnabledFor(zapcore.DebugLevel) {
 				logger.Debugf("collecting signature on request (transfer) from [%s]", party.UniqueID())
 			}
 
+			// use the signature of the offline signer, if imported
+			if sigma, ok, err := importedSignature(c.tx, party); err != nil {
+				return nil, err
+			} else if ok {
+				c.tx.TokenRequest.AppendSignature(sigma)
+				continue
+			}
 			if signer, err := c.tx.TokenService().SigService().GetSigner(party); err == nil {
 				if logger.IsEnabledFor(zapcore.DebugLevel) {
 					logger.Debugf("collecting signature on request (transfer) from [%s], it is me!", party.UniqueID())
 					logger.Debugf("signing tx-id [%s,nonce=%s]", c.tx.ID(), base64.StdEncoding.EncodeToString(c.tx.TxID.Nonce))
 				}
+				if err := checkSignaturePolicy(context, c.tx, party, signatureRequest.MessageToSign(), signatureRequest.Approvals); err != nil {
+					return nil, err
+				}
 				sigma, err := signer.Sign(signatureRequest.MessageToSign())
 				if err != nil {
 					return nil, err
@@ -307,7 +366,7 @@ func (c *collectEndorsementsView) requestSignaturesOnTransfers(context view.Cont
 				return nil, errors.Errorf("Timeout from party %s", party)
 			}
 			if msg.Status == view.ERROR {
-				return nil, errors.New(string(msg.Payload))
+				return nil, remoteError(msg.Payload)
 			}
 
 			sigma := msg.Payload
@@ -554,6 +613,7 @@ func (c *collectEndorsementsView) distributeEnv(context view.Context, env *netwo
 		// Otherwise, filter the metadata by Enrollment ID.
 		var txRaw []byte
 		var err error
+		propagate(c.tx)
 		if entry.Auditor {
 			if logger.IsEnabledFor(zapcore.DebugLevel) {
 				logger.Debugf("This is an auditor [%s], send the full set of metadata", entry.ID.UniqueID())
@@ -670,7 +730,10 @@ func (f *receiveTransactionView) Call(context view.Context) (interface{}, error)
 			return nil, errors.Wrap(err, "failed to receive transaction")
 		}
 		// Check that the transaction is valid
-		if err := tx.IsValid(); err != nil {
+		span := startSpan(tx, "ttx.receive_transaction")
+		err = tx.IsValid()
+		span.End(err)
+		if err != nil {
 			return nil, errors.WithMessagef(err, "invalid transaction %s", tx.ID())
 		}
 		return tx, nil
@@ -709,6 +772,9 @@ func (s *endorseView) Call(context view.Context) (interface{}, error) {
 	}
 
 	session := context.Session()
+	if err := GetLifecycle(context).RecordInitiator(s.tx.ID(), session.Info().Caller); err != nil {
+		return nil, err
+	}
 	for range requestsToBeSigned {
 		if logger.IsEnabledFor(zapcore.DebugLevel) {
 			logger.Debugf("Receiving signature request...")
@@ -737,28 +803,17 @@ func (s *endorseView) Call(context view.Context) (interface{}, error) {
 			return nil, errors.Wrap(err, "failed unmarshalling signature request")
 		}
 
-		tms := token.GetManagementService(context, token.WithTMS(s.tx.Network(), s.tx.Channel(), s.tx.Namespace()))
-		if tms == nil {
-			return nil, errors.Errorf("failed getting TMS for [%s:%s:%s]", s.tx.Network(), s.tx.Channel(), s.tx.Namespace())
-		}
-
-		if !tms.WalletManager().IsMe(signatureRequest.Signer) {
-			return nil, errors.Errorf("identity [%s] is not me", signatureRequest.Signer.UniqueID())
-		}
-		signer, err := s.tx.TokenService().SigService().GetSigner(signatureRequest.Signer)
+		remote, _ := tracing.ParseTraceparent(signatureRequest.Traceparent)
+		span := tracing.Get().StartTxSpan("ttx.sign_request", s.tx.ID(), remote)
+		err = s.sign(context, session, signatureRequest)
+		span.End(err)
 		if err != nil {
-			return nil, errors.Wrapf(err, "cannot find signer for [%s]", signatureRequest.Signer.UniqueID())
-		}
-		sigma, err := signer.Sign(signatureRequest.MessageToSign())
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed signing request")
-		}
-		if logger.IsEnabledFor(zapcore.DebugLevel) {
-			logger.Debugf("Send back signature...")
-		}
-		err = session.Send(sigma)
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed sending signature back")
+			if _, ok := IsPolicyViolation(err); ok {
+				if err2 := session.SendError(policyErrorPayload(err)); err2 != nil {
+					logger.Errorf("failed sending policy violation back: [%s]", err2)
+				}
+			}
+			return nil, err
 		}
 	}
 
@@ -773,14 +828,29 @@ func (s *endorseView) Call(context view.Context) (interface{}, error) {
 		return nil, errors.Wrapf(err, "failed receiving transaction")
 	}
 
+	span := startSpan(s.tx, "ttx.ack_transaction")
+	err = s.ack(context, session, rawRequest)
+	span.End(err)
+	if err != nil {
+		return nil, err
+	}
+
+	agent := metrics.Get(context)
+	agent.EmitKey(0, "ttx", "sent", "txAck", s.tx.ID())
+
+	return s.tx, nil
+}
+
+// ack stores the transaction and sends back an acknowledgement, the signature of the transaction
+func (s *endorseView) ack(context view.Context, session view.Session, rawRequest []byte) error {
 	// Store envelope
 	if err := StoreEnvelope(context, s.tx); err != nil {
-		return nil, errors.Wrapf(err, "failed storing envelope %s", s.tx.ID())
+		return errors.Wrapf(err, "failed storing envelope %s", s.tx.ID())
 	}
 
 	// Store transaction in the token transaction database
 	if err := StoreTransactionRecords(context, s.tx); err != nil {
-		return nil, errors.Wrapf(err, "failed storing transaction records %s", s.tx.ID())
+		return errors.Wrapf(err, "failed storing transaction records %s", s.tx.ID())
 	}
 
 	// Send back an acknowledgement
@@ -790,23 +860,53 @@ func (s *endorseView) Call(context view.Context) (interface{}, error) {
 	}
 	signer, err := view2.GetSigService(context).GetSigner(view2.GetIdentityProvider(context).DefaultIdentity())
 	if err != nil {
-		return nil, errors.WithMessagef(err, "failed to get signer for default identity")
+		return errors.WithMessagef(err, "failed to get signer for default identity")
 	}
 	sigma, err := signer.Sign(rawRequest)
 	if err != nil {
-		return nil, errors.WithMessage(err, "failed to sign ack response")
+		return errors.WithMessage(err, "failed to sign ack response")
 	}
 	if logger.IsEnabledFor(zapcore.DebugLevel) {
 		logger.Debugf("ack response: [%s] from [%s]", hash.Hashable(sigma), view2.GetIdentityProvider(context).DefaultIdentity())
 	}
 	if err := session.Send(sigma); err != nil {
-		return nil, errors.WithMessage(err, "failed sending ack")
+		return errors.WithMessage(err, "failed sending ack")
 	}
+	return nil
+}
 
-	agent := metrics.Get(context)
-	agent.EmitKey(0, "ttx", "sent", "txAck", s.tx.ID())
+// sign signs the passed request, if the signer is me and its spending policy allows it, and sends back the signature
+func (s *endorseView) sign(context view.Context, session view.Session, signatureRequest *signatureRequest) error {
+	tms := token.GetManagementService(context, token.WithTMS(s.tx.Network(), s.tx.Channel(), s.tx.Namespace()))
+	if tms == nil {
+		return errors.Errorf("failed getting TMS for [%s:%s:%s]", s.tx.Network(), s.tx.Channel(), s.tx.Namespace())
+	}
 
-	return s.tx, nil
+	if !tms.WalletManager().IsMe(signatureRequest.Signer) {
+		return errors.Errorf("identity [%s] is not me", signatureRequest.Signer.UniqueID())
+	}
+	signer, err := s.tx.TokenService().SigService().GetSigner(signatureRequest.Signer)
+	if err != nil {
+		return errors.Wrapf(err, "cannot find signer for [%s]", signatureRequest.Signer.UniqueID())
+	}
+	approvals := spendApprovals(s.tx)
+	for k, v := range signatureRequest.Approvals {
+		approvals[k] = v
+	}
+	if err := checkSignaturePolicy(context, s.tx, signatureRequest.Signer, signatureRequest.MessageToSign(), approvals); err != nil {
+		return err
+	}
+	sigma, err := signer.Sign(signatureRequest.MessageToSign())
+	if err != nil {
+		return errors.Wrapf(err, "failed signing request")
+	}
+	if logger.IsEnabledFor(zapcore.DebugLevel) {
+		logger.Debugf("Send back signature...")
+	}
+	if err := session.Send(sigma); err != nil {
+		return errors.Wrapf(err, "failed sending signature back")
+	}
+	return nil
 }
 
 func (s *endorseView) requestsToBeSigned() ([]*token.Transfer, error) {
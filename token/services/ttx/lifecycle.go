@@ -0,0 +1,327 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"sync"
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/metrics"
+	"github.com/pkg/errors"
+)
+
+const (
+	// LifecyclePrefix is the prefix of the keys of the lifecycle records
+	LifecyclePrefix = "ttx.lifecycle"
+	// AbortedPrefix is the prefix of the keys marking the aborted transactions
+	AbortedPrefix = "ttx.aborted"
+	// InitiatorPrefix is the prefix of the keys of the initiators of the transactions this node took part in
+	InitiatorPrefix = "ttx.initiator"
+)
+
+// txLocks are the locks of the transactions whose broadcast or abort is in progress
+var txLocks = &lockMap{locks: map[string]*refLock{}}
+
+// Phase is the phase of the lifecycle of a token transaction assembled by this node
+type Phase int
+
+const (
+	// UnknownPhase is the phase of the transactions that are not tracked
+	UnknownPhase Phase = iota
+	// Collecting is the phase of the transactions whose token request is being signed by the issuers and the senders
+	Collecting
+	// Audited is the phase of the transactions whose token request has been endorsed by the auditor
+	Audited
+	// Approved is the phase of the transactions whose envelope has been endorsed by the backend
+	Approved
+	// Distributed is the phase of the transactions whose envelope has been acknowledged by all the parties
+	Distributed
+	// Ordered is the phase of the transactions that have been broadcast and wait for finality
+	Ordered
+)
+
+var phaseNames = map[Phase]string{
+	UnknownPhase: "Unknown",
+	Collecting:   "Collecting",
+	Audited:      "Audited",
+	Approved:     "Approved",
+	Distributed:  "Distributed",
+	Ordered:      "Ordered",
+}
+
+func (p Phase) String() string {
+	if name, ok := phaseNames[p]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// LifecycleRecord is the persisted state of a pending token transaction
+type LifecycleRecord struct {
+	TxID      string
+	Network   string
+	Channel   string
+	Namespace string
+	// Phase is the last phase the transaction reached
+	Phase Phase
+	// Signatures is the number of signatures collected on the token request
+	Signatures int
+	// Audited is true if the auditor endorsed the token request
+	Audited bool
+	// Transaction is the serialized transaction, including the collected signatures and the envelope, if any
+	Transaction []byte
+	// Updated is the time the phase was recorded
+	Updated time.Time
+}
+
+// Lifecycle persists the phase of the token transactions assembled by this node,
+// so that the transactions left pending by a crash can be recovered.
+type Lifecycle struct {
+	kvs *kvs.KVS
+}
+
+// GetLifecycle returns the Lifecycle backed by the key-value store of the passed service provider
+func GetLifecycle(sp view2.ServiceProvider) *Lifecycle {
+	return &Lifecycle{kvs: kvs.GetService(sp)}
+}
+
+// Record stores the passed transaction as having reached the passed phase
+func (l *Lifecycle) Record(tx *Transaction, phase Phase) error {
+	k, err := lifecycleKey(tx.ID())
+	if err != nil {
+		return err
+	}
+	raw, err := tx.Bytes()
+	if err != nil {
+		return errors.WithMessagef(err, "failed marshalling transaction [%s]", tx.ID())
+	}
+	exists := l.kvs.Exists(k)
+	audited := phase == Audited
+	if !audited && exists {
+		previous := &LifecycleRecord{}
+		if err := l.kvs.Get(k, previous); err != nil {
+			return errors.WithMessagef(err, "failed loading lifecycle record for [%s]", tx.ID())
+		}
+		audited = previous.Audited
+	}
+	record := &LifecycleRecord{
+		TxID:        tx.ID(),
+		Network:     tx.Network(),
+		Channel:     tx.Channel(),
+		Namespace:   tx.Namespace(),
+		Phase:       phase,
+		Audited:     audited,
+		Transaction: raw,
+		Updated:     time.Now(),
+	}
+	if tx.TokenRequest != nil && tx.TokenRequest.Actions != nil {
+		record.Signatures = len(tx.TokenRequest.Actions.Signatures)
+	}
+	if err := l.kvs.Put(k, record); err != nil {
+		return errors.WithMessagef(err, "failed storing lifecycle record for [%s]", tx.ID())
+	}
+	if !exists {
+		metrics.Get().PendingTransactions.Add(1)
+	}
+	logger.Debugf("transaction [%s] reached phase [%s]", tx.ID(), phase)
+	return nil
+}
+
+// Get returns the lifecycle record of the passed transaction, nil if the transaction is not pending
+func (l *Lifecycle) Get(txID string) (*LifecycleRecord, error) {
+	k, err := lifecycleKey(txID)
+	if err != nil {
+		return nil, err
+	}
+	if !l.kvs.Exists(k) {
+		return nil, nil
+	}
+	record := &LifecycleRecord{}
+	if err := l.kvs.Get(k, record); err != nil {
+		return nil, errors.WithMessagef(err, "failed loading lifecycle record for [%s]", txID)
+	}
+	return record, nil
+}
+
+// Remove forgets the passed transaction, because it is either final or aborted
+func (l *Lifecycle) Remove(txID string) error {
+	k, err := lifecycleKey(txID)
+	if err != nil {
+		return err
+	}
+	if !l.kvs.Exists(k) {
+		return nil
+	}
+	if err := l.kvs.Delete(k); err != nil {
+		return errors.WithMessagef(err, "failed removing lifecycle record for [%s]", txID)
+	}
+	metrics.Get().PendingTransactions.Add(-1)
+	return nil
+}
+
+// release forgets the passed transaction when it is aborted before being ordered.
+// Ordered transactions stay pending until they are final, because they might still be committed.
+func (l *Lifecycle) release(txID string) error {
+	record, err := l.Get(txID)
+	if err != nil {
+		return err
+	}
+	if record == nil || record.Phase == Ordered {
+		return nil
+	}
+	return l.Remove(txID)
+}
+
+// MarkAborted records that the passed transaction has been aborted, it must not be broadcast anymore
+func (l *Lifecycle) MarkAborted(txID string) error {
+	k, err := abortedKey(txID)
+	if err != nil {
+		return err
+	}
+	if err := l.kvs.Put(k, true); err != nil {
+		return errors.WithMessagef(err, "failed marking [%s] as aborted", txID)
+	}
+	return nil
+}
+
+// IsAborted returns true if the passed transaction has been aborted
+func (l *Lifecycle) IsAborted(txID string) (bool, error) {
+	k, err := abortedKey(txID)
+	if err != nil {
+		return false, err
+	}
+	return l.kvs.Exists(k), nil
+}
+
+// Lock acquires the lock of the passed transaction and returns the function that releases it.
+// The ordering views hold it from checking that the transaction has not been aborted to recording that it has been ordered,
+// and the AbortView from checking that the transaction has not been ordered to marking it as aborted,
+// so that a transaction cannot be both broadcast and aborted.
+func (l *Lifecycle) Lock(txID string) func() {
+	return txLocks.acquire(txID)
+}
+
+// RecordInitiator stores the passed identity as the initiator of the passed transaction,
+// the only party allowed to ask this node to abort it
+func (l *Lifecycle) RecordInitiator(txID string, initiator view.Identity) error {
+	k, err := initiatorKey(txID)
+	if err != nil {
+		return err
+	}
+	if err := l.kvs.Put(k, initiator); err != nil {
+		return errors.WithMessagef(err, "failed storing the initiator of [%s]", txID)
+	}
+	return nil
+}
+
+// Initiator returns the initiator of the passed transaction, nil if this node did not take part in it
+func (l *Lifecycle) Initiator(txID string) (view.Identity, error) {
+	k, err := initiatorKey(txID)
+	if err != nil {
+		return nil, err
+	}
+	if !l.kvs.Exists(k) {
+		return nil, nil
+	}
+	var initiator view.Identity
+	if err := l.kvs.Get(k, &initiator); err != nil {
+		return nil, errors.WithMessagef(err, "failed loading the initiator of [%s]", txID)
+	}
+	return initiator, nil
+}
+
+// Pending returns the records of the pending transactions in the passed phases, or in any phase if none is passed
+func (l *Lifecycle) Pending(phases ...Phase) ([]*LifecycleRecord, error) {
+	it, err := l.kvs.GetByPartialCompositeID(LifecyclePrefix, nil)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed iterating over lifecycle records")
+	}
+	defer it.Close()
+
+	var records []*LifecycleRecord
+	for it.HasNext() {
+		record := &LifecycleRecord{}
+		if _, err := it.Next(record); err != nil {
+			return nil, errors.WithMessage(err, "failed loading lifecycle record")
+		}
+		if len(phases) != 0 && !containsPhase(phases, record.Phase) {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func lifecycleKey(txID string) (string, error) {
+	k, err := kvs.CreateCompositeKey(LifecyclePrefix, []string{txID})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed creating lifecycle key for [%s]", txID)
+	}
+	return k, nil
+}
+
+func abortedKey(txID string) (string, error) {
+	k, err := kvs.CreateCompositeKey(AbortedPrefix, []string{txID})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed creating aborted key for [%s]", txID)
+	}
+	return k, nil
+}
+
+func initiatorKey(txID string) (string, error) {
+	k, err := kvs.CreateCompositeKey(InitiatorPrefix, []string{txID})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed creating initiator key for [%s]", txID)
+	}
+	return k, nil
+}
+
+func containsPhase(phases []Phase, phase Phase) bool {
+	for _, p := range phases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// lockMap holds a lock for each key in use
+type lockMap struct {
+	lock  sync.Mutex
+	locks map[string]*refLock
+}
+
+type refLock struct {
+	sync.Mutex
+	refs int
+}
+
+// acquire acquires the lock of the passed key and returns the function that releases it
+func (m *lockMap) acquire(key string) func() {
+	m.lock.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &refLock{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.lock.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		m.lock.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(m.locks, key)
+		}
+		m.lock.Unlock()
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/memory"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	registry2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifecycle(t *testing.T) {
+	registry := registry2.New()
+	assert.NoError(t, registry.RegisterService(&fakeProv{typ: "memory"}))
+	kvss, err := kvs.New(registry, "memory", "")
+	assert.NoError(t, err)
+	assert.NoError(t, registry.RegisterService(kvss))
+	lifecycle := GetLifecycle(registry)
+
+	newTx := func(id string) *Transaction {
+		return &Transaction{Payload: &Payload{
+			ID:           id,
+			Network:      "network",
+			Channel:      "channel",
+			Namespace:    "namespace",
+			Transient:    map[string][]byte{},
+			TokenRequest: token.NewRequest(nil, id),
+		}}
+	}
+	tx1, tx2 := newTx("tx1"), newTx("tx2")
+
+	record, err := lifecycle.Get("tx1")
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+
+	assert.NoError(t, lifecycle.Record(tx1, Collecting))
+	tx1.TokenRequest.AppendSignature([]byte("sigma"))
+	assert.NoError(t, lifecycle.Record(tx1, Audited))
+	assert.NoError(t, lifecycle.Record(tx1, Approved))
+	assert.NoError(t, lifecycle.Record(tx2, Collecting))
+
+	record, err = lifecycle.Get("tx1")
+	assert.NoError(t, err)
+	assert.Equal(t, Approved, record.Phase)
+	assert.Equal(t, 1, record.Signatures)
+	assert.True(t, record.Audited)
+	assert.Equal(t, "namespace", record.Namespace)
+	raw, err := tx1.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, raw, record.Transaction)
+
+	records, err := lifecycle.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	records, err = lifecycle.Pending(Collecting, Audited)
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "tx2", records[0].TxID)
+	assert.False(t, records[0].Audited)
+
+	// ordered transactions stay pending when released, until they are final
+	assert.NoError(t, lifecycle.Record(tx1, Ordered))
+	assert.NoError(t, lifecycle.release("tx1"))
+	assert.NoError(t, lifecycle.release("tx2"))
+	records, err = lifecycle.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "tx1", records[0].TxID)
+	assert.Equal(t, "Ordered", records[0].Phase.String())
+
+	assert.NoError(t, lifecycle.Remove("tx1"))
+	assert.NoError(t, lifecycle.Remove("tx1"))
+	records, err = lifecycle.Pending()
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	// aborted transactions are remembered, but they are not pending
+	aborted, err := lifecycle.IsAborted("tx1")
+	assert.NoError(t, err)
+	assert.False(t, aborted)
+	assert.NoError(t, lifecycle.MarkAborted("tx1"))
+	assert.NoError(t, lifecycle.MarkAborted("tx1"))
+	aborted, err = lifecycle.IsAborted("tx1")
+	assert.NoError(t, err)
+	assert.True(t, aborted)
+	aborted, err = lifecycle.IsAborted("tx2")
+	assert.NoError(t, err)
+	assert.False(t, aborted)
+	records, err = lifecycle.Pending()
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+type fakeProv struct {
+	typ string
+}
+
+func (f *fakeProv) GetString(key string) string {
+	return f.typ
+}
+
+func (f *fakeProv) GetInt(key string) int {
+	return 0
+}
+
+func (f *fakeProv) GetDuration(key string) time.Duration {
+	return time.Duration(0)
+}
+
+func (f *fakeProv) GetBool(key string) bool {
+	return false
+}
+
+func (f *fakeProv) GetStringSlice(key string) []string {
+	return nil
+}
+
+func (f *fakeProv) IsSet(key string) bool {
+	return false
+}
+
+func (f *fakeProv) UnmarshalKey(key string, rawVal interface{}) error {
+	return nil
+}
+
+func (f *fakeProv) ConfigFileUsed() string {
+	return ""
+}
+
+func (f *fakeProv) GetPath(key string) string {
+	return ""
+}
+
+func (f *fakeProv) TranslatePath(path string) string {
+	return ""
+}
@@ -0,0 +1,199 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/signer/offline"
+	"github.com/pkg/errors"
+)
+
+// OfflineSignaturePrefix is the prefix of the keys, in the transaction's transient, of the imported offline signatures
+const OfflineSignaturePrefix = "ttx.offline.signature."
+
+// ExportSignatureRequest returns the request for the signature of the passed issuer or sender on the token request
+// of the passed transaction, to be signed by the standalone offline signer.
+// The transaction must be complete: adding actions afterwards invalidates the signature.
+func ExportSignatureRequest(tx *Transaction, signer view.Identity) (*offline.SignatureRequest, error) {
+	if _, err := signatureVerifier(tx, signer); err != nil {
+		return nil, err
+	}
+	raw, err := tx.TokenRequest.MarshalToSign()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed marshalling token request of [%s]", tx.ID())
+	}
+	r := offline.NewSignatureRequest(tx.Network(), tx.Channel(), tx.Namespace(), tx.ID(), signer, raw)
+
+	inputs, err := tx.TokenRequest.Inputs()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting inputs of [%s]", tx.ID())
+	}
+	for i := 0; i < inputs.Count(); i++ {
+		input := inputs.At(i)
+		r.Inputs = append(r.Inputs, &offline.Token{
+			ID:       input.Id.String(),
+			Owner:    input.EnrollmentID,
+			Type:     input.Type,
+			Quantity: input.Quantity.Decimal(),
+		})
+	}
+	outputs, err := tx.TokenRequest.Outputs()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting outputs of [%s]", tx.ID())
+	}
+	outputsMetadata := outputsMetadata(tx)
+	if len(outputsMetadata) != outputs.Count() {
+		return nil, errors.Errorf("the metadata of the outputs of [%s] are incomplete", tx.ID())
+	}
+	for i, output := range outputs.Outputs() {
+		r.Outputs = append(r.Outputs, &offline.Token{
+			Owner:     output.EnrollmentID,
+			Type:      output.Type,
+			Quantity:  output.Quantity.Decimal(),
+			Metadata:  outputsMetadata[i],
+			AuditInfo: output.OwnerAuditInfo,
+		})
+	}
+
+	// the signer opens the outputs and reads the metadata of the actions from the token request itself
+	r.PublicParameters, err = tx.TokenService().PublicParametersManager().SerializePublicParameters()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed serializing public parameters")
+	}
+	validator, err := tx.TokenService().Validator()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting validator")
+	}
+	actions, err := validator.UnmarshalActions(raw)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed unmarshalling actions of [%s]", tx.ID())
+	}
+	for _, action := range actions {
+		a, ok := action.(interface{ GetMetadata() map[string][]byte })
+		if !ok {
+			continue
+		}
+		for k, v := range a.GetMetadata() {
+			if r.Metadata == nil {
+				r.Metadata = map[string][]byte{}
+			}
+			r.Metadata[k] = v
+		}
+	}
+	// the initiator trusts the public parameters of its token management service
+	if err := r.Verify(r.PublicParameters); err != nil {
+		return nil, errors.WithMessagef(err, "invalid signature request for [%s]", tx.ID())
+	}
+	return r, nil
+}
+
+// outputsMetadata returns the metadata that open the outputs of the passed transaction, in the order of its outputs
+func outputsMetadata(tx *Transaction) [][]byte {
+	if tx.TokenRequest.Metadata == nil {
+		return nil
+	}
+	var res [][]byte
+	for _, issue := range tx.TokenRequest.Metadata.Issues {
+		res = append(res, issue.TokenInfo...)
+	}
+	for _, transfer := range tx.TokenRequest.Metadata.Transfers {
+		res = append(res, transfer.OutputsMetadata...)
+	}
+	return res
+}
+
+// ImportSignature verifies the passed offline signature against the token request of the passed transaction
+// and stores it in the transaction.
+// When collecting the endorsements, the imported signature is used instead of contacting the signer.
+func ImportSignature(tx *Transaction, s *offline.Signature) error {
+	if s.Version != offline.Version {
+		return errors.Errorf("unsupported version [%d], expected [%d]", s.Version, offline.Version)
+	}
+	if s.TxID != tx.ID() {
+		return errors.Errorf("signature for transaction [%s], expected [%s]", s.TxID, tx.ID())
+	}
+	signer := view.Identity(s.Signer)
+	message, err := messageToSign(tx)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(message)
+	if !bytes.Equal(digest[:], s.Digest) {
+		return errors.Errorf("the token request of [%s] changed since the signature request has been exported", tx.ID())
+	}
+	if err := verifySignature(tx, signer, message, s.Signature); err != nil {
+		return err
+	}
+	if tx.Payload.Transient == nil {
+		tx.Payload.Transient = map[string][]byte{}
+	}
+	tx.Payload.Transient[OfflineSignaturePrefix+signer.UniqueID()] = s.Signature
+	return nil
+}
+
+// importedSignature returns the offline signature of the passed party imported in the passed transaction, if any
+func importedSignature(tx *Transaction, party view.Identity) ([]byte, bool, error) {
+	sigma, ok := tx.Payload.Transient[OfflineSignaturePrefix+party.UniqueID()]
+	if !ok {
+		return nil, false, nil
+	}
+	message, err := messageToSign(tx)
+	if err != nil {
+		return nil, false, err
+	}
+	// the token request might have changed after the import
+	if err := verifySignature(tx, party, message, sigma); err != nil {
+		return nil, false, errors.WithMessage(err, "invalid imported signature")
+	}
+	return sigma, true, nil
+}
+
+func messageToSign(tx *Transaction) ([]byte, error) {
+	raw, err := tx.TokenRequest.MarshalToSign()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed marshalling token request of [%s]", tx.ID())
+	}
+	return offline.MessageToSign(raw, tx.ID()), nil
+}
+
+func verifySignature(tx *Transaction, signer view.Identity, message, sigma []byte) error {
+	verifier, err := signatureVerifier(tx, signer)
+	if err != nil {
+		return err
+	}
+	if err := verifier.Verify(message, sigma); err != nil {
+		return errors.Wrapf(err, "failed verifying signature from [%s]", signer)
+	}
+	return nil
+}
+
+// signatureVerifier returns the verifier of the signatures of the passed signer, an issuer or a sender of the transaction
+func signatureVerifier(tx *Transaction, signer view.Identity) (token.Verifier, error) {
+	for _, issue := range tx.TokenRequest.Issues() {
+		if issue.Issuer.Equal(signer) {
+			verifier, err := tx.TokenService().SigService().IssuerVerifier(signer)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed getting verifier for [%s]", signer)
+			}
+			return verifier, nil
+		}
+	}
+	for _, transfer := range tx.TokenRequest.Transfers() {
+		if containsIdentity(transfer.Senders, signer) || containsIdentity(transfer.ExtraSigners, signer) {
+			verifier, err := tx.TokenService().SigService().OwnerVerifier(signer)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed getting verifier for [%s]", signer)
+			}
+			return verifier, nil
+		}
+	}
+	return nil, errors.Errorf("[%s] is not a signer of [%s]", signer, tx.ID())
+}
@@ -43,8 +43,9 @@ func (o *orderingView) Call(context view.Context) (interface{}, error) {
 		return nil, errors.Errorf("txID is empty for token transaction [%s]", o.tx.ID())
 	}
 
-	if err := network.GetInstance(context, o.tx.Network(), "").Broadcast(o.tx.Payload.Envelope); err != nil {
-		return nil, errors.WithMessagef(err, "failed to broadcast token transaction [%s]", o.tx.ID())
+	nw := network.GetInstance(context, o.tx.Network(), "")
+	if err := broadcast(context, nw, o.tx); err != nil {
+		return nil, err
 	}
 	return nil, nil
 }
@@ -109,9 +110,56 @@ func (o *orderingAndFinalityView) Call(ctx view.Context) (interface{}, error) {
 		}
 	}
 
-	if err := nw.Broadcast(env); err != nil {
-		return nil, errors.WithMessagef(err, "failed to broadcast token transaction [%s]", o.tx.ID())
+	if err := broadcast(ctx, nw, o.tx); err != nil {
+		return nil, err
 	}
 
 	return ctx.RunView(NewFinalityWithTimeoutView(o.tx, o.timeout))
 }
+
+// broadcast sends the passed transaction for ordering, unless it has been aborted, and records that it has been ordered.
+// It holds the lock of the transaction, so that the transaction cannot be aborted in the meantime.
+func broadcast(context view.Context, nw *network.Network, tx *Transaction) error {
+	unlock := GetLifecycle(context).Lock(tx.ID())
+	defer unlock()
+
+	if err := checkNotAborted(context, tx); err != nil {
+		return err
+	}
+	if err := nw.Broadcast(tx.Payload.Envelope); err != nil {
+		return errors.WithMessagef(err, "failed to broadcast token transaction [%s]", tx.ID())
+	}
+	return recordOrdered(context, nw, tx)
+}
+
+// checkNotAborted returns an error if the passed transaction has been aborted
+func checkNotAborted(context view.Context, tx *Transaction) error {
+	aborted, err := GetLifecycle(context).IsAborted(tx.ID())
+	if err != nil {
+		return errors.WithMessagef(err, "failed checking if [%s] has been aborted", tx.ID())
+	}
+	if aborted {
+		return errors.Errorf("token transaction [%s] has been aborted, it cannot be broadcast", tx.ID())
+	}
+	return nil
+}
+
+// recordOrdered records that the passed transaction waits for finality, if its lifecycle is tracked.
+// The record is removed once the transaction is final.
+func recordOrdered(context view.Context, nw *network.Network, tx *Transaction) error {
+	lifecycle := GetLifecycle(context)
+	record, err := lifecycle.Get(tx.ID())
+	if err != nil {
+		return errors.WithMessagef(err, "failed loading lifecycle record of [%s]", tx.ID())
+	}
+	if record == nil {
+		return nil
+	}
+	if err := lifecycle.Record(tx, Ordered); err != nil {
+		return errors.WithMessage(err, "failed recording transaction phase")
+	}
+	if err := nw.SubscribeTxStatusChanges(tx.ID(), newLifecycleListener(nw, tx)); err != nil {
+		return errors.WithMessagef(err, "failed subscribing to status changes of [%s]", tx.ID())
+	}
+	return nil
+}
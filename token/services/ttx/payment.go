@@ -0,0 +1,282 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PaymentRequestVersion is the version of the format of the payment requests
+	PaymentRequestVersion = 1
+	// PaymentReferenceKey is the key of the application metadata carrying the reference of the paid payment request
+	PaymentReferenceKey = "ttx.payment.reference"
+)
+
+// PaymentRequest is created and signed by a payee to be paid without an interactive exchange of identities.
+// It can be shared out of band, the payer uses it to build the transfer.
+type PaymentRequest struct {
+	Version int
+	TMSID   token.TMSID
+	// Payee is the identity of the FSC node of the payee, the transaction is distributed to it
+	Payee view.Identity
+	// Recipient carries a fresh identity of the payee, its audit info and token metadata
+	Recipient *RecipientData
+	TokenType string
+	// Quantity is the requested amount, in decimal
+	Quantity string
+	// Expiry is the time after which the request cannot be paid anymore
+	Expiry time.Time
+	// Reference identifies the payment, it is stored as application metadata of the transaction
+	Reference string
+	// Signature is the signature of the payee's node, with its long-term identity, on the request
+	Signature []byte
+}
+
+// NewPaymentRequest returns a new payment request, for a fresh recipient identity of the passed wallet,
+// for the passed quantity of the passed token type, to be paid before the passed expiry.
+// The request is signed by the long-term identity of this node, the payee.
+func NewPaymentRequest(sp view2.ServiceProvider, wallet *token.OwnerWallet, tokenType string, quantity uint64, expiry time.Time, reference string) (*PaymentRequest, error) {
+	if len(reference) == 0 {
+		return nil, errors.New("no reference")
+	}
+	tms := wallet.TMS()
+	q, err := token2.UInt64ToQuantity(quantity, tms.PublicParametersManager().Precision())
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid quantity [%d]", quantity)
+	}
+	recipient, err := wallet.GetRecipientIdentity()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get recipient identity from wallet [%s]", wallet.ID())
+	}
+	auditInfo, err := wallet.GetAuditInfo(recipient)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get audit info")
+	}
+	metadata, err := wallet.GetTokenMetadata(recipient)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get token metadata")
+	}
+
+	me := view2.GetIdentityProvider(sp).DefaultIdentity()
+	// bind the recipient identity to this node, the transaction paying the request is distributed to it
+	if err := view2.GetEndpointService(sp).Bind(me, recipient); err != nil {
+		return nil, errors.WithMessagef(err, "failed to bind me to recipient identity")
+	}
+
+	r := &PaymentRequest{
+		Version: PaymentRequestVersion,
+		TMSID:   tms.ID(),
+		Payee:   me,
+		Recipient: &RecipientData{
+			Identity:  recipient,
+			AuditInfo: auditInfo,
+			Metadata:  metadata,
+		},
+		TokenType: tokenType,
+		Quantity:  q.Decimal(),
+		Expiry:    expiry,
+		Reference: reference,
+	}
+	signer, err := view2.GetSigService(sp).GetSigner(me)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get signer of [%s]", me)
+	}
+	message, err := r.MessageToSign()
+	if err != nil {
+		return nil, err
+	}
+	r.Signature, err = signer.Sign(message)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed signing payment request")
+	}
+	return r, nil
+}
+
+func (r *PaymentRequest) Bytes() ([]byte, error) {
+	return Marshal(r)
+}
+
+func (r *PaymentRequest) FromBytes(raw []byte) error {
+	return Unmarshal(raw, r)
+}
+
+// MessageToSign returns the message signed by the payee, the request without the signature
+func (r *PaymentRequest) MessageToSign() ([]byte, error) {
+	request := *r
+	request.Signature = nil
+	raw, err := request.Bytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshalling payment request")
+	}
+	return raw, nil
+}
+
+// Verify checks that the request is well-formed, that it is meant for the passed TMS, that it has not expired,
+// and that it is signed by the long-term identity of the payee's node
+func (r *PaymentRequest) Verify(sp view2.ServiceProvider, tms *token.ManagementService) error {
+	if err := r.verify(tms.ID(), time.Now()); err != nil {
+		return err
+	}
+	if _, err := token2.ToQuantity(r.Quantity, tms.PublicParametersManager().Precision()); err != nil {
+		return errors.Wrapf(err, "invalid quantity [%s]", r.Quantity)
+	}
+	verifier, err := view2.GetSigService(sp).GetVerifier(r.Payee)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting verifier for payee [%s]", r.Payee)
+	}
+	return r.verifySignature(verifier)
+}
+
+func (r *PaymentRequest) verify(tmsID token.TMSID, now time.Time) error {
+	if r.Version != PaymentRequestVersion {
+		return errors.Errorf("unsupported version [%d], expected [%d]", r.Version, PaymentRequestVersion)
+	}
+	if r.TMSID != tmsID {
+		return errors.Errorf("payment request for TMS [%s], expected [%s]", r.TMSID, tmsID)
+	}
+	if r.Payee.IsNone() {
+		return errors.New("no payee")
+	}
+	if r.Recipient == nil || r.Recipient.Identity.IsNone() {
+		return errors.New("no recipient identity")
+	}
+	if len(r.TokenType) == 0 {
+		return errors.New("no token type")
+	}
+	if len(r.Reference) == 0 {
+		return errors.New("no reference")
+	}
+	if !now.Before(r.Expiry) {
+		return errors.Errorf("payment request [%s] expired at [%s]", r.Reference, r.Expiry)
+	}
+	return nil
+}
+
+func (r *PaymentRequest) verifySignature(verifier view2.Verifier) error {
+	message, err := r.MessageToSign()
+	if err != nil {
+		return err
+	}
+	if err := verifier.Verify(message, r.Signature); err != nil {
+		return errors.Wrapf(err, "invalid signature on payment request [%s]", r.Reference)
+	}
+	return nil
+}
+
+// Pay verifies the passed payment request and appends to the transaction a transfer, from the passed wallet,
+// of the requested quantity to the recipient identity of the request.
+// The reference of the request is stored as application metadata of the transaction.
+// A request whose reference is recorded in the transaction db as paid, by a pending or confirmed transaction,
+// is refused.
+// The transaction is distributed to the payee's node when collecting the endorsements.
+func (t *Transaction) Pay(wallet *token.OwnerWallet, request *PaymentRequest, opts ...token.TransferOption) error {
+	tms := t.TokenService()
+	if err := request.Verify(t.SP, tms); err != nil {
+		return errors.WithMessage(err, "invalid payment request")
+	}
+	if reference := t.PaymentReference(); len(reference) != 0 {
+		return errors.Errorf("transaction [%s] already pays [%s]", t.ID(), reference)
+	}
+	txID, err := paidBy(t.SP, tms, request.Reference)
+	if err != nil {
+		return errors.WithMessagef(err, "failed checking if [%s] has been paid", request.Reference)
+	}
+	if len(txID) != 0 {
+		return errors.Errorf("payment request [%s] has been paid already by transaction [%s]", request.Reference, txID)
+	}
+	q, err := token2.ToQuantity(request.Quantity, tms.PublicParametersManager().Precision())
+	if err != nil {
+		return errors.Wrapf(err, "invalid quantity [%s]", request.Quantity)
+	}
+
+	recipient := request.Recipient
+	if err := tms.WalletManager().RegisterRecipientIdentity(recipient.Identity, recipient.AuditInfo, recipient.Metadata); err != nil {
+		return errors.WithMessage(err, "failed to register recipient identity")
+	}
+	if err := view2.GetEndpointService(t.SP).Bind(request.Payee, recipient.Identity); err != nil {
+		return errors.WithMessagef(err, "failed binding [%s] to [%s]", recipient.Identity, request.Payee)
+	}
+
+	if err := t.TransferQuantities(wallet, request.TokenType, []token2.Quantity{q}, []view.Identity{recipient.Identity}, opts...); err != nil {
+		return err
+	}
+	t.SetApplicationMetadata(PaymentReferenceKey, []byte(request.Reference))
+	return nil
+}
+
+// PaymentReference returns the reference of the payment request paid by this transaction, if any
+func (t *Transaction) PaymentReference() string {
+	return string(t.ApplicationMetadata(PaymentReferenceKey))
+}
+
+// IsPaidBy checks that the passed transaction pays this request, the payee calls it before accepting the transaction.
+// The transaction must carry the reference of the request and transfer at least the requested quantity
+// to the recipient identity of the request.
+func (r *PaymentRequest) IsPaidBy(tx *Transaction) error {
+	if reference := tx.PaymentReference(); reference != r.Reference {
+		return errors.Errorf("transaction [%s] pays [%s], expected [%s]", tx.ID(), reference, r.Reference)
+	}
+	q, err := token2.ToQuantity(r.Quantity, tx.TokenService().PublicParametersManager().Precision())
+	if err != nil {
+		return errors.Wrapf(err, "invalid quantity [%s]", r.Quantity)
+	}
+	outputs, err := tx.Outputs()
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting outputs of [%s]", tx.ID())
+	}
+	paid := outputs.ByRecipient(r.Recipient.Identity).ByType(r.TokenType).Sum()
+	if paid.Cmp(q.ToBigInt()) < 0 {
+		return errors.Errorf("transaction [%s] pays [%s] of [%s], expected [%s]", tx.ID(), paid, r.TokenType, r.Quantity)
+	}
+	return nil
+}
+
+// paidBy returns the id of the pending or confirmed transaction paying the request with the passed reference, if any
+func paidBy(sp view2.ServiceProvider, tms *token.ManagementService, reference string) (string, error) {
+	qe := NewOwner(sp, tms).NewQueryExecutor()
+	defer qe.Done()
+	it, err := qe.Transactions(ttxdb.QueryTransactionsParams{Statuses: []TxStatus{Pending, Confirmed}})
+	if err != nil {
+		return "", errors.WithMessage(err, "failed querying transactions")
+	}
+	defer it.Close()
+	return findPayment(it, reference)
+}
+
+// recordIterator iterates over transaction records, Next returns nil when there are no more records
+type recordIterator interface {
+	Next() (*ttxdb.TransactionRecord, error)
+}
+
+// findPayment returns the id of the transaction, among the passed records, paying the request with the passed reference
+func findPayment(it recordIterator, reference string) (string, error) {
+	for {
+		record, err := it.Next()
+		if err != nil {
+			return "", errors.WithMessage(err, "failed iterating over transactions")
+		}
+		if record == nil {
+			return "", nil
+		}
+		if PaymentReferenceOf(record) == reference {
+			return record.TxID, nil
+		}
+	}
+}
+
+// PaymentReferenceOf returns the reference of the payment request paid by the transaction of the passed record, if any
+func PaymentReferenceOf(record *ttxdb.TransactionRecord) string {
+	return string(record.ApplicationMetadata[PaymentReferenceKey])
+}
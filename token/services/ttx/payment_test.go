@@ -0,0 +1,131 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/msp/x509"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPaymentRequest(now time.Time) *PaymentRequest {
+	return &PaymentRequest{
+		Version:   PaymentRequestVersion,
+		TMSID:     token.TMSID{Network: "network", Channel: "channel", Namespace: "namespace"},
+		Payee:     []byte("payee"),
+		Recipient: &RecipientData{Identity: []byte("recipient"), AuditInfo: []byte("audit info")},
+		TokenType: "USD",
+		Quantity:  "10",
+		Expiry:    now.Add(time.Hour),
+		Reference: "invoice-42",
+	}
+}
+
+func TestPaymentRequest(t *testing.T) {
+	now := time.Now()
+	r := newTestPaymentRequest(now)
+	tmsID := r.TMSID
+	assert.NoError(t, r.verify(tmsID, now))
+
+	r.TMSID.Channel = "other"
+	assert.Contains(t, r.verify(tmsID, now).Error(), "payment request for TMS")
+
+	r = newTestPaymentRequest(now)
+	r.Payee = nil
+	assert.EqualError(t, r.verify(tmsID, now), "no payee")
+
+	r = newTestPaymentRequest(now)
+	r.Recipient = nil
+	assert.EqualError(t, r.verify(tmsID, now), "no recipient identity")
+
+	r = newTestPaymentRequest(now)
+	r.Reference = ""
+	assert.EqualError(t, r.verify(tmsID, now), "no reference")
+
+	r = newTestPaymentRequest(now)
+	assert.Contains(t, r.verify(tmsID, r.Expiry).Error(), "payment request [invoice-42] expired")
+}
+
+func TestPaymentRequestSignature(t *testing.T) {
+	// the payee's node signs the request with its long-term identity
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	r := newTestPaymentRequest(time.Now())
+	message, err := r.MessageToSign()
+	assert.NoError(t, err)
+	r.Signature, err = x509.NewSignerFromKey(sk).Sign(message)
+	assert.NoError(t, err)
+
+	// the request is shared out of band
+	raw, err := r.Bytes()
+	assert.NoError(t, err)
+	r2 := &PaymentRequest{}
+	assert.NoError(t, r2.FromBytes(raw))
+	assert.NoError(t, r2.verifySignature(x509.NewVerifier(&sk.PublicKey)))
+
+	// the signature binds all the fields, the recipient identity included
+	r2.Recipient.Identity = []byte("mallory")
+	assert.Error(t, r2.verifySignature(x509.NewVerifier(&sk.PublicKey)))
+	r2.Recipient.Identity = r.Recipient.Identity
+	r2.Quantity = "100"
+	assert.Error(t, r2.verifySignature(x509.NewVerifier(&sk.PublicKey)))
+
+	// only the payee's node can sign it
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	assert.Error(t, r.verifySignature(x509.NewVerifier(&other.PublicKey)))
+}
+
+func TestFindPayment(t *testing.T) {
+	records := []*ttxdb.TransactionRecord{
+		{TxID: "tx1"},
+		{TxID: "tx2", ApplicationMetadata: map[string][]byte{PaymentReferenceKey: []byte("invoice-41")}},
+		{TxID: "tx3", ApplicationMetadata: map[string][]byte{PaymentReferenceKey: []byte("invoice-42")}},
+	}
+	txID, err := findPayment(&recordsIterator{records: records}, "invoice-42")
+	assert.NoError(t, err)
+	assert.Equal(t, "tx3", txID)
+
+	txID, err = findPayment(&recordsIterator{records: records[:2]}, "invoice-42")
+	assert.NoError(t, err)
+	assert.Equal(t, "", txID)
+
+	_, err = findPayment(&recordsIterator{err: errors.New("db down")}, "invoice-42")
+	assert.EqualError(t, err, "failed iterating over transactions: db down")
+}
+
+func TestPaymentReferenceOf(t *testing.T) {
+	assert.Equal(t, "", PaymentReferenceOf(&ttxdb.TransactionRecord{}))
+	assert.Equal(t, "invoice-42", PaymentReferenceOf(&ttxdb.TransactionRecord{
+		ApplicationMetadata: map[string][]byte{PaymentReferenceKey: []byte("invoice-42")},
+	}))
+}
+
+type recordsIterator struct {
+	records []*ttxdb.TransactionRecord
+	err     error
+}
+
+func (it *recordsIterator) Next() (*ttxdb.TransactionRecord, error) {
+	if it.err != nil {
+		return nil, it.err
+	}
+	if len(it.records) == 0 {
+		return nil, nil
+	}
+	record := it.records[0]
+	it.records = it.records[1:]
+	return record, nil
+}
@@ -0,0 +1,696 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/events"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/owner"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SpendApprovalPrefix is the prefix of the keys, in the transaction's transient, of the approvals of the transfers
+	// above the approval threshold of a spending policy
+	SpendApprovalPrefix = "ttx.policy.approval."
+	// policyViolationPrefix marks the error payloads, sent back to the initiator, that carry a policy violation
+	policyViolationPrefix = "ttx.policy.violation:"
+	// policyWindow is the period covered by the daily limits
+	policyWindow = 24 * time.Hour
+)
+
+// PolicyRule identifies a rule of the spending policy of an owner wallet
+type PolicyRule string
+
+const (
+	// PerTransactionLimit bounds the quantity of a token type transferred by a single transaction
+	PerTransactionLimit PolicyRule = "PerTransactionLimit"
+	// DailyLimit bounds the quantity of a token type transferred in the last 24 hours
+	DailyLimit PolicyRule = "DailyLimit"
+	// AllowedCounterparties restricts the recipients of the transfers
+	AllowedCounterparties PolicyRule = "AllowedCounterparties"
+	// ApprovalRequired requires the approval of one of the approvers for the transfers above a threshold
+	ApprovalRequired PolicyRule = "ApprovalRequired"
+)
+
+// PolicyViolation is the error returned when a transfer breaks the spending policy of an owner wallet
+type PolicyViolation struct {
+	// Wallet is the identifier of the owner wallet
+	Wallet string
+	// Rule is the broken rule
+	Rule PolicyRule
+	// TokenType is the token type the broken limit applies to, if any
+	TokenType string `json:",omitempty"`
+	// Counterparty is the enrollment ID of the recipient that is not allowed, if any.
+	// It is empty when the rule is AllowedCounterparties and the recipient has no enrollment ID.
+	Counterparty string `json:",omitempty"`
+	// Amount is the quantity that breaks the limit, if any
+	Amount string `json:",omitempty"`
+	// Limit is the configured quantity, if any
+	Limit string `json:",omitempty"`
+}
+
+func (v *PolicyViolation) Error() string {
+	switch v.Rule {
+	case PerTransactionLimit:
+		return fmt.Sprintf("wallet [%s]: transfer of [%s] [%s] exceeds the per-transaction limit [%s]", v.Wallet, v.Amount, v.TokenType, v.Limit)
+	case DailyLimit:
+		return fmt.Sprintf("wallet [%s]: transfers of [%s] [%s] in the last 24 hours exceed the daily limit [%s]", v.Wallet, v.Amount, v.TokenType, v.Limit)
+	case AllowedCounterparties:
+		if len(v.Counterparty) == 0 {
+			return fmt.Sprintf("wallet [%s]: recipients without enrollment id are not allowed", v.Wallet)
+		}
+		return fmt.Sprintf("wallet [%s]: counterparty [%s] is not allowed", v.Wallet, v.Counterparty)
+	case ApprovalRequired:
+		return fmt.Sprintf("wallet [%s]: transfer of [%s] [%s] above [%s] requires the approval of a second approver", v.Wallet, v.Amount, v.TokenType, v.Limit)
+	default:
+		return fmt.Sprintf("wallet [%s]: rule [%s] violated", v.Wallet, v.Rule)
+	}
+}
+
+// IsPolicyViolation returns the policy violation the passed error is caused by, if any.
+// The violations detected by a remote signer are restored as well.
+func IsPolicyViolation(err error) (*PolicyViolation, bool) {
+	v, ok := errors.Cause(err).(*PolicyViolation)
+	return v, ok
+}
+
+// policyErrorPayload returns the payload of the error to send back to the initiator for the passed error
+func policyErrorPayload(err error) []byte {
+	if v, ok := IsPolicyViolation(err); ok {
+		raw, err := json.Marshal(v)
+		if err == nil {
+			return append([]byte(policyViolationPrefix), raw...)
+		}
+	}
+	return []byte(err.Error())
+}
+
+// remoteError returns the error sent back by a remote party, restoring the policy violations
+func remoteError(payload []byte) error {
+	if raw := bytes.TrimPrefix(payload, []byte(policyViolationPrefix)); len(raw) != len(payload) {
+		v := &PolicyViolation{}
+		if err := json.Unmarshal(raw, v); err == nil {
+			return v
+		}
+	}
+	return errors.New(string(payload))
+}
+
+// spend is what a wallet transfers to others in a transaction
+type spend struct {
+	// types are the transferred token types, in order of appearance
+	types   []string
+	amounts map[string]*big.Int
+	// counterparties are the enrollment IDs of the recipients
+	counterparties []string
+	// unidentified is true if a recipient, other than a redemption, has no enrollment ID
+	unidentified bool
+}
+
+func newSpend() *spend {
+	return &spend{amounts: map[string]*big.Int{}}
+}
+
+// add records the transfer of the passed quantity to the passed counterparty, the empty string for a redemption
+func (s *spend) add(typ string, q *big.Int, counterparty string) {
+	amount, ok := s.amounts[typ]
+	if !ok {
+		amount = big.NewInt(0)
+		s.amounts[typ] = amount
+		s.types = append(s.types, typ)
+	}
+	amount.Add(amount, q)
+	if len(counterparty) == 0 {
+		return
+	}
+	for _, c := range s.counterparties {
+		if c == counterparty {
+			return
+		}
+	}
+	s.counterparties = append(s.counterparties, counterparty)
+}
+
+// addUnidentified records the transfer of the passed quantity to a recipient whose enrollment ID is unknown
+func (s *spend) addUnidentified(typ string, q *big.Int) {
+	s.add(typ, q, "")
+	s.unidentified = true
+}
+
+// walletSpend returns what the wallet with the passed enrollment ID, that contains the identities accepted by the passed
+// function, transfers to others in the actions that spend its tokens. The redemptions are included.
+func walletSpend(contains func(view.Identity) bool, eid string, inputs *token.InputStream, outputs *token.OutputStream) *spend {
+	actions := map[int]bool{}
+	for i := 0; i < inputs.Count(); i++ {
+		input := inputs.At(i)
+		if contains(input.Owner) {
+			actions[input.ActionIndex] = true
+		}
+	}
+	s := newSpend()
+	for _, output := range outputs.Outputs() {
+		if !actions[output.ActionIndex] {
+			continue
+		}
+		if len(output.Owner) == 0 {
+			s.add(output.Type, output.Quantity.ToBigInt(), "")
+			continue
+		}
+		if contains(output.Owner) || (len(eid) != 0 && output.EnrollmentID == eid) {
+			// the change and the transfers to the same enrollment ID are not spent
+			continue
+		}
+		if len(output.EnrollmentID) == 0 {
+			s.addUnidentified(output.Type, output.Quantity.ToBigInt())
+			continue
+		}
+		s.add(output.Type, output.Quantity.ToBigInt(), output.EnrollmentID)
+	}
+	return s
+}
+
+// spendingPolicy enforces the spending policy of an owner wallet
+type spendingPolicy struct {
+	wallet string
+	// key identifies the wallet among those of all the TMSs, it indexes the reservations
+	key       string
+	policy    *config.Policy
+	precision uint64
+	// spent returns the quantity of the passed token type transferred by the wallet in the last 24 hours,
+	// the reservations included and the transaction being checked excluded
+	spent func(typ string) (*big.Int, error)
+}
+
+// newSpendingPolicy returns the spending policy of the passed owner wallet for the transaction with the passed id,
+// nil if the wallet has no policy
+func newSpendingPolicy(sp view2.ServiceProvider, tms *token.ManagementService, wallet *token.OwnerWallet, txID string) *spendingPolicy {
+	policy := tms.ConfigManager().OwnerWalletPolicy(wallet.ID())
+	if policy == nil {
+		return nil
+	}
+	eid := wallet.EnrollmentID()
+	key := tms.ID().String() + ":" + wallet.ID()
+	return &spendingPolicy{
+		wallet:    wallet.ID(),
+		key:       key,
+		policy:    policy,
+		precision: tms.PublicParametersManager().Precision(),
+		spent: func(typ string) (*big.Int, error) {
+			from := time.Now().Add(-policyWindow)
+			spent, recorded, err := spentSince(sp, tms, eid, typ, txID, from)
+			if err != nil {
+				return nil, err
+			}
+			return spent.Add(spent, spendReservations.reserved(key, typ, txID, recorded, from)), nil
+		},
+	}
+}
+
+// check returns a PolicyViolation if the passed spend breaks the policy.
+// The approval thresholds are checked only if approved is not nil, it tells whether the transaction has been approved.
+func (p *spendingPolicy) check(s *spend, approved func() (bool, error)) error {
+	if len(p.policy.Counterparties) != 0 {
+		if s.unidentified {
+			// the recipient cannot be checked, then it is not allowed
+			return &PolicyViolation{Wallet: p.wallet, Rule: AllowedCounterparties}
+		}
+		for _, counterparty := range s.counterparties {
+			if !containsString(p.policy.Counterparties, counterparty) {
+				return &PolicyViolation{Wallet: p.wallet, Rule: AllowedCounterparties, Counterparty: counterparty}
+			}
+		}
+	}
+	for _, typ := range s.types {
+		limit := p.limit(typ)
+		if limit == nil {
+			continue
+		}
+		amount := s.amounts[typ]
+
+		perTransaction, err := p.quantity(limit.PerTransaction)
+		if err != nil {
+			return err
+		}
+		if perTransaction != nil && amount.Cmp(perTransaction) > 0 {
+			return p.violation(PerTransactionLimit, typ, amount, limit.PerTransaction)
+		}
+
+		daily, err := p.quantity(limit.Daily)
+		if err != nil {
+			return err
+		}
+		if daily != nil {
+			spent, err := p.spent(typ)
+			if err != nil {
+				return errors.WithMessagef(err, "failed getting the quantity of [%s] transferred by wallet [%s] in the last 24 hours", typ, p.wallet)
+			}
+			total := big.NewInt(0).Add(spent, amount)
+			if total.Cmp(daily) > 0 {
+				return p.violation(DailyLimit, typ, total, limit.Daily)
+			}
+		}
+
+		threshold, err := p.quantity(limit.ApprovalThreshold)
+		if err != nil {
+			return err
+		}
+		if approved != nil && threshold != nil && amount.Cmp(threshold) > 0 {
+			ok, err := approved()
+			if err != nil {
+				return errors.WithMessagef(err, "failed checking the approvals for wallet [%s]", p.wallet)
+			}
+			if !ok {
+				return p.violation(ApprovalRequired, typ, amount, limit.ApprovalThreshold)
+			}
+		}
+	}
+	return nil
+}
+
+// limit returns the limit of the passed token type, the default limit if the type has none, nil if there is no default
+func (p *spendingPolicy) limit(typ string) *config.Limit {
+	var def *config.Limit
+	for _, limit := range p.policy.Limits {
+		if limit.TokenType == typ {
+			return limit
+		}
+		if len(limit.TokenType) == 0 {
+			def = limit
+		}
+	}
+	return def
+}
+
+// quantity parses the passed quantity of the policy, nil if empty
+func (p *spendingPolicy) quantity(q string) (*big.Int, error) {
+	if len(q) == 0 {
+		return nil, nil
+	}
+	v, err := token2.ToQuantity(q, p.precision)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid quantity [%s] in the policy of wallet [%s]", q, p.wallet)
+	}
+	return v.ToBigInt(), nil
+}
+
+func (p *spendingPolicy) violation(rule PolicyRule, typ string, amount *big.Int, limit string) *PolicyViolation {
+	return &PolicyViolation{Wallet: p.wallet, Rule: rule, TokenType: typ, Amount: amount.String(), Limit: limit}
+}
+
+// approved returns true if one of the approvers, other than this node, signed the passed message
+func (p *spendingPolicy) approved(sp view2.ServiceProvider, message []byte, approvals map[string][]byte) (bool, error) {
+	me := view2.GetIdentityProvider(sp).DefaultIdentity()
+	for _, label := range p.policy.Approvers {
+		approver := view2.GetIdentityProvider(sp).Identity(label)
+		if approver.IsNone() {
+			logger.Warnf("approver [%s] of wallet [%s] not found", label, p.wallet)
+			continue
+		}
+		if approver.Equal(me) {
+			continue
+		}
+		sigma, ok := approvals[approver.UniqueID()]
+		if !ok {
+			continue
+		}
+		verifier, err := view2.GetSigService(sp).GetVerifier(approver)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed getting verifier for approver [%s]", label)
+		}
+		if err := verifier.Verify(message, sigma); err != nil {
+			logger.Warnf("invalid approval from [%s] for wallet [%s]: [%s]", label, p.wallet, err)
+			continue
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// spentSince returns the quantity of the passed token type transferred to others by the passed enrollment ID,
+// in the pending and confirmed transactions since the passed time, but the passed one.
+// It also returns the ids of the transactions with records of the passed token type sent by the enrollment ID.
+func spentSince(sp view2.ServiceProvider, tms *token.ManagementService, eid, typ, txID string, from time.Time) (*big.Int, map[string]bool, error) {
+	qe := NewOwner(sp, tms).NewQueryExecutor()
+	defer qe.Done()
+	it, err := qe.Transactions(ttxdb.QueryTransactionsParams{
+		From:     &from,
+		Statuses: []TxStatus{Pending, Confirmed},
+	})
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed querying transactions")
+	}
+	defer it.Close()
+
+	spent := big.NewInt(0)
+	recorded := map[string]bool{}
+	for {
+		record, err := it.Next()
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "failed iterating over transactions")
+		}
+		if record == nil {
+			return spent, recorded, nil
+		}
+		if record.SenderEID != eid || record.TokenType != typ || record.TxID == txID {
+			continue
+		}
+		recorded[record.TxID] = true
+		if record.RecipientEID == eid {
+			continue
+		}
+		spent.Add(spent, record.Amount)
+	}
+}
+
+// spendReservations are the quantities reserved by the transactions signed by the wallets of this node that have a
+// spending policy. A reservation counts towards the daily limit until the records of its transaction are in the ttxdb,
+// and is released when the transaction is aborted or deleted.
+var spendReservations = newReservations()
+
+// reservations holds the reservations of the wallets, and a lock for each wallet to check and reserve atomically
+type reservations struct {
+	locks *lockMap
+	mutex sync.Mutex
+	// reservations maps the key of a wallet to the reservations of its transactions, indexed by transaction id
+	reservations map[string]map[string]*reservation
+	subscribed   bool
+}
+
+// reservation is what a transaction transfers, per token type, as checked when its token request was signed
+type reservation struct {
+	amounts map[string]*big.Int
+	created time.Time
+}
+
+func newReservations() *reservations {
+	return &reservations{
+		locks:        &lockMap{locks: map[string]*refLock{}},
+		reservations: map[string]map[string]*reservation{},
+	}
+}
+
+// lock acquires the lock of the wallet with the passed key and returns the function that releases it
+func (r *reservations) lock(key string) func() {
+	return r.locks.acquire(key)
+}
+
+// reserve records that the transaction with the passed id, signed by the wallet with the passed key, transfers
+// the passed spend. It replaces the previous reservation of the transaction, if any.
+func (r *reservations) reserve(key, txID string, s *spend, now time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	wallet, ok := r.reservations[key]
+	if !ok {
+		wallet = map[string]*reservation{}
+		r.reservations[key] = wallet
+	}
+	amounts := map[string]*big.Int{}
+	for typ, amount := range s.amounts {
+		amounts[typ] = big.NewInt(0).Set(amount)
+	}
+	wallet[txID] = &reservation{amounts: amounts, created: now}
+}
+
+// reserved returns the quantity of the passed token type reserved by the wallet with the passed key since the passed
+// time, but by the passed transaction and those recorded already. The reservations older than the passed time are dropped.
+func (r *reservations) reserved(key, typ, txID string, recorded map[string]bool, from time.Time) *big.Int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	total := big.NewInt(0)
+	for id, reservation := range r.reservations[key] {
+		if reservation.created.Before(from) {
+			delete(r.reservations[key], id)
+			continue
+		}
+		if id == txID || recorded[id] {
+			continue
+		}
+		if amount, ok := reservation.amounts[typ]; ok {
+			total.Add(total, amount)
+		}
+	}
+	return total
+}
+
+// release drops the reservations of the transaction with the passed id
+func (r *reservations) release(txID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for key, wallet := range r.reservations {
+		delete(wallet, txID)
+		if len(wallet) == 0 {
+			delete(r.reservations, key)
+		}
+	}
+}
+
+// listen subscribes to the status changes of the transactions of this node, once, to release the reservations
+// of the deleted transactions
+func (r *reservations) listen(sp view2.ServiceProvider) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.subscribed {
+		return
+	}
+	subscriber, err := events.GetSubscriber(sp)
+	if err != nil {
+		logger.Warnf("failed getting event subscriber, the reservations of the deleted transactions are released when they expire: [%s]", err)
+		return
+	}
+	subscriber.Subscribe(owner.TxStatusTopic, r)
+	r.subscribed = true
+}
+
+// OnReceive releases the reservations of the deleted transactions
+func (r *reservations) OnReceive(event events.Event) {
+	msg, ok := event.Message().(owner.TxStatusMessage)
+	if !ok || msg.Status != Deleted {
+		return
+	}
+	r.release(msg.TxID)
+}
+
+// checkTransferPolicy checks, before the transfer is added to the transaction, that the passed wallet is allowed to
+// transfer the passed values to the passed owners, a none owner being a redemption.
+// The approval thresholds are checked when the wallet signs, once the token request is complete.
+func (t *Transaction) checkTransferPolicy(wallet *token.OwnerWallet, typ string, values []token2.Quantity, owners []view.Identity) error {
+	if wallet == nil || len(values) != len(owners) {
+		// the transfer reports the error
+		return nil
+	}
+	for _, v := range values {
+		if v == nil {
+			return nil
+		}
+	}
+	policy := newSpendingPolicy(t.SP, t.TokenService(), wallet, t.ID())
+	if policy == nil {
+		return nil
+	}
+	eid := wallet.EnrollmentID()
+	s := newSpend()
+	for i, owner := range owners {
+		if owner.IsNone() {
+			s.add(typ, values[i].ToBigInt(), "")
+			continue
+		}
+		if wallet.Contains(owner) {
+			continue
+		}
+		ownerEID, err := t.TokenService().WalletManager().GetEnrollmentID(owner)
+		if err != nil {
+			return errors.WithMessagef(err, "failed getting enrollment id of recipient [%s]", owner)
+		}
+		if len(ownerEID) == 0 {
+			s.addUnidentified(typ, values[i].ToBigInt())
+			continue
+		}
+		if ownerEID == eid {
+			continue
+		}
+		s.add(typ, values[i].ToBigInt(), ownerEID)
+	}
+	return policy.check(s, nil)
+}
+
+// checkSignaturePolicy checks that the owner wallet of the passed signer, if any and if it has a spending policy,
+// is allowed to sign the token request of the passed transaction.
+// The approvals must be signatures of the passed message, the one the signer signs.
+// If the check succeeds, what the transaction transfers is reserved, under the lock of the wallet,
+// so that the transactions signed concurrently count towards the daily limits of each other.
+func checkSignaturePolicy(sp view2.ServiceProvider, tx *Transaction, signer view.Identity, message []byte, approvals map[string][]byte) error {
+	tms := tx.TokenService()
+	wallet := tms.WalletManager().OwnerWalletByIdentity(signer)
+	if wallet == nil {
+		return nil
+	}
+	policy := newSpendingPolicy(sp, tms, wallet, tx.ID())
+	if policy == nil {
+		return nil
+	}
+	inputs, err := tx.TokenRequest.Inputs()
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting inputs of [%s]", tx.ID())
+	}
+	outputs, err := tx.TokenRequest.Outputs()
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting outputs of [%s]", tx.ID())
+	}
+	s := walletSpend(wallet.Contains, wallet.EnrollmentID(), inputs, outputs)
+
+	spendReservations.listen(sp)
+	unlock := spendReservations.lock(policy.key)
+	defer unlock()
+	if err := policy.check(s, func() (bool, error) {
+		return policy.approved(sp, message, approvals)
+	}); err != nil {
+		return err
+	}
+	spendReservations.reserve(policy.key, tx.ID(), s, time.Now())
+	return nil
+}
+
+// spendApprovals returns the approvals stored in the passed transaction, indexed by the unique id of their approver
+func spendApprovals(tx *Transaction) map[string][]byte {
+	approvals := map[string][]byte{}
+	for k, v := range tx.Payload.Transient {
+		if strings.HasPrefix(k, SpendApprovalPrefix) {
+			approvals[strings.TrimPrefix(k, SpendApprovalPrefix)] = v
+		}
+	}
+	return approvals
+}
+
+func containsString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// SpendApprovalView asks an approver for the approval of a transaction whose transfers are above the approval
+// threshold of a spending policy
+type SpendApprovalView struct {
+	tx       *Transaction
+	approver view.Identity
+}
+
+// NewSpendApprovalView returns an instance of the SpendApprovalView for the passed transaction and approver, the FSC identity
+// of a node listed among the approvers of the policy.
+// The view does the following:
+// 1. It sends the transaction to the approver.
+// 2. It waits for the approval, the signature of the approver on the token request and the transaction id.
+// 3. It verifies the approval and stores it in the transaction.
+// The token request must be complete: adding actions afterwards invalidates the approval.
+func NewSpendApprovalView(tx *Transaction, approver view.Identity) *SpendApprovalView {
+	return &SpendApprovalView{tx: tx, approver: approver}
+}
+
+func (a *SpendApprovalView) Call(context view.Context) (interface{}, error) {
+	message, err := messageToSign(a.tx)
+	if err != nil {
+		return nil, err
+	}
+	session, err := context.GetSession(context.Initiator(), a.approver)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed getting session")
+	}
+	// Wait to receive a content back
+	ch := session.Receive()
+
+	raw, err := a.tx.Bytes()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed marshalling transaction [%s]", a.tx.ID())
+	}
+	if err := session.Send(raw); err != nil {
+		return nil, errors.Wrap(err, "failed sending transaction")
+	}
+
+	timeout := time.NewTimer(time.Minute)
+	defer timeout.Stop()
+	var msg *view.Message
+	select {
+	case msg = <-ch:
+	case <-timeout.C:
+		return nil, errors.Errorf("timeout waiting for approval from [%s]", a.approver)
+	}
+	if msg.Status == view.ERROR {
+		return nil, errors.Errorf("approval refused by [%s]: [%s]", a.approver, string(msg.Payload))
+	}
+
+	verifier, err := view2.GetSigService(context).GetVerifier(a.approver)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting verifier for [%s]", a.approver)
+	}
+	if err := verifier.Verify(message, msg.Payload); err != nil {
+		return nil, errors.Wrapf(err, "failed verifying approval from [%s]", a.approver)
+	}
+	if a.tx.Payload.Transient == nil {
+		a.tx.Payload.Transient = map[string][]byte{}
+	}
+	a.tx.Payload.Transient[SpendApprovalPrefix+a.approver.UniqueID()] = msg.Payload
+	return a.tx, nil
+}
+
+// ReceiveSpendApprovalRequest receives the transaction an initiator asks to approve.
+// The application inspects the transaction and, if it agrees, runs the view returned by NewApproveSpendView.
+func ReceiveSpendApprovalRequest(context view.Context) (*Transaction, error) {
+	tx, err := ReceiveTransaction(context)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed receiving transaction to approve")
+	}
+	return tx, nil
+}
+
+type approveSpendView struct {
+	tx *Transaction
+}
+
+// NewApproveSpendView returns a view that approves the passed transaction, received with ReceiveSpendApprovalRequest.
+// The approval is the signature, with the default identity of this node, on the token request and the transaction id.
+func NewApproveSpendView(tx *Transaction) *approveSpendView {
+	return &approveSpendView{tx: tx}
+}
+
+func (a *approveSpendView) Call(context view.Context) (interface{}, error) {
+	message, err := messageToSign(a.tx)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := view2.GetSigService(context).GetSigner(view2.GetIdentityProvider(context).DefaultIdentity())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get signer for default identity")
+	}
+	sigma, err := signer.Sign(message)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed signing approval")
+	}
+	if err := context.Session().Send(sigma); err != nil {
+		return nil, errors.WithMessage(err, "failed sending approval")
+	}
+	return a.tx, nil
+}
@@ -0,0 +1,246 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/owner"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPolicy(spent int64) *spendingPolicy {
+	return &spendingPolicy{
+		wallet: "treasury",
+		policy: &config.Policy{
+			Limits: []*config.Limit{
+				{TokenType: "EUR", PerTransaction: "1000", Daily: "2500", ApprovalThreshold: "500"},
+				{PerTransaction: "10"},
+			},
+			Counterparties: []string{"alice", "bob"},
+		},
+		precision: 64,
+		spent: func(typ string) (*big.Int, error) {
+			if typ != "EUR" {
+				return nil, errors.Errorf("unexpected type [%s]", typ)
+			}
+			return big.NewInt(spent), nil
+		},
+	}
+}
+
+func TestSpendingPolicy(t *testing.T) {
+	approved := func() (bool, error) { return true, nil }
+	notApproved := func() (bool, error) { return false, nil }
+
+	tests := []struct {
+		name      string
+		spent     int64
+		spend     func(s *spend)
+		approved  func() (bool, error)
+		violation *PolicyViolation
+	}{
+		{
+			name:  "within limits",
+			spent: 1000,
+			spend: func(s *spend) {
+				s.add("EUR", big.NewInt(300), "alice")
+				s.add("EUR", big.NewInt(200), "bob")
+			},
+			approved: notApproved,
+		},
+		{
+			name:  "redemption",
+			spend: func(s *spend) { s.add("EUR", big.NewInt(300), "") },
+		},
+		{
+			name:      "per transaction",
+			spend:     func(s *spend) { s.add("EUR", big.NewInt(1001), "alice") },
+			violation: &PolicyViolation{Wallet: "treasury", Rule: PerTransactionLimit, TokenType: "EUR", Amount: "1001", Limit: "1000"},
+		},
+		{
+			name:      "default limit",
+			spend:     func(s *spend) { s.add("USD", big.NewInt(11), "alice") },
+			violation: &PolicyViolation{Wallet: "treasury", Rule: PerTransactionLimit, TokenType: "USD", Amount: "11", Limit: "10"},
+		},
+		{
+			name:      "daily",
+			spent:     2000,
+			spend:     func(s *spend) { s.add("EUR", big.NewInt(501), "alice") },
+			approved:  approved,
+			violation: &PolicyViolation{Wallet: "treasury", Rule: DailyLimit, TokenType: "EUR", Amount: "2501", Limit: "2500"},
+		},
+		{
+			name:      "counterparty",
+			spend:     func(s *spend) { s.add("EUR", big.NewInt(1), "charlie") },
+			violation: &PolicyViolation{Wallet: "treasury", Rule: AllowedCounterparties, Counterparty: "charlie"},
+		},
+		{
+			name:      "approval",
+			spend:     func(s *spend) { s.add("EUR", big.NewInt(501), "alice") },
+			approved:  notApproved,
+			violation: &PolicyViolation{Wallet: "treasury", Rule: ApprovalRequired, TokenType: "EUR", Amount: "501", Limit: "500"},
+		},
+		{
+			name:     "approved",
+			spend:    func(s *spend) { s.add("EUR", big.NewInt(501), "alice") },
+			approved: approved,
+		},
+		{
+			name:  "approval not checked",
+			spend: func(s *spend) { s.add("EUR", big.NewInt(501), "alice") },
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newSpend()
+			test.spend(s)
+			err := newTestPolicy(test.spent).check(s, test.approved)
+			if test.violation == nil {
+				assert.NoError(t, err)
+				return
+			}
+			v, ok := IsPolicyViolation(errors.WithMessage(err, "failed adding transfer"))
+			assert.True(t, ok)
+			assert.Equal(t, test.violation, v)
+		})
+	}
+
+	// the recipients without enrollment id are not allowed when the counterparties are restricted
+	s := newSpend()
+	s.add("EUR", big.NewInt(1), "alice")
+	s.addUnidentified("EUR", big.NewInt(1))
+	v, ok := IsPolicyViolation(newTestPolicy(0).check(s, nil))
+	assert.True(t, ok)
+	assert.Equal(t, &PolicyViolation{Wallet: "treasury", Rule: AllowedCounterparties}, v)
+	assert.Equal(t, "wallet [treasury]: recipients without enrollment id are not allowed", v.Error())
+	p := newTestPolicy(0)
+	p.policy.Counterparties = nil
+	assert.NoError(t, p.check(s, nil))
+
+	// an invalid policy is an error, not a violation
+	p = newTestPolicy(0)
+	p.policy.Limits[0].Daily = "lots"
+	s = newSpend()
+	s.add("EUR", big.NewInt(1), "alice")
+	err := p.check(s, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid quantity [lots] in the policy of wallet [treasury]")
+	_, ok = IsPolicyViolation(err)
+	assert.False(t, ok)
+}
+
+func TestWalletSpend(t *testing.T) {
+	mine := func(id view.Identity) bool {
+		return id.Equal(view.Identity("treasury1")) || id.Equal(view.Identity("treasury2"))
+	}
+	q := func(v uint64) token2.Quantity { return token2.NewQuantityFromUInt64(v) }
+	inputs := token.NewInputStream(nil, []*token.Input{
+		{ActionIndex: 0, Owner: view.Identity("treasury1")},
+		{ActionIndex: 1, Owner: view.Identity("charlie")},
+		{ActionIndex: 2, Owner: view.Identity("treasury2")},
+	}, 64)
+	outputs := token.NewOutputStream([]*token.Output{
+		{ActionIndex: 0, Owner: view.Identity("alice"), EnrollmentID: "alice", Type: "EUR", Quantity: q(100)},
+		{ActionIndex: 0, Owner: view.Identity("bob"), EnrollmentID: "bob", Type: "EUR", Quantity: q(50)},
+		{ActionIndex: 0, Owner: view.Identity("treasury2"), EnrollmentID: "treasury", Type: "EUR", Quantity: q(30)},
+		{ActionIndex: 0, Owner: view.Identity("cold"), EnrollmentID: "treasury", Type: "EUR", Quantity: q(10)},
+		{ActionIndex: 1, Owner: view.Identity("alice"), EnrollmentID: "alice", Type: "EUR", Quantity: q(1000)},
+		{ActionIndex: 2, Type: "USD", Quantity: q(20)},
+		{ActionIndex: 2, Owner: view.Identity("alice"), EnrollmentID: "alice", Type: "USD", Quantity: q(5)},
+	}, 64)
+
+	s := walletSpend(mine, "treasury", inputs, outputs)
+	assert.Equal(t, []string{"EUR", "USD"}, s.types)
+	assert.Equal(t, big.NewInt(150), s.amounts["EUR"])
+	assert.Equal(t, big.NewInt(25), s.amounts["USD"])
+	assert.Equal(t, []string{"alice", "bob"}, s.counterparties)
+	assert.False(t, s.unidentified)
+
+	// an owner without enrollment id is spent to an unidentified recipient, also if the wallet has none
+	outputs = token.NewOutputStream([]*token.Output{
+		{ActionIndex: 0, Owner: view.Identity("script"), Type: "EUR", Quantity: q(100)},
+	}, 64)
+	s = walletSpend(mine, "", inputs, outputs)
+	assert.Equal(t, big.NewInt(100), s.amounts["EUR"])
+	assert.Empty(t, s.counterparties)
+	assert.True(t, s.unidentified)
+}
+
+func TestReservations(t *testing.T) {
+	now := time.Now()
+	r := newReservations()
+	s := newSpend()
+	s.add("EUR", big.NewInt(300), "alice")
+	s.add("USD", big.NewInt(10), "alice")
+	r.reserve("tms:treasury", "tx1", s, now)
+	s = newSpend()
+	s.add("EUR", big.NewInt(200), "bob")
+	r.reserve("tms:treasury", "tx2", s, now)
+	r.reserve("tms:other", "tx3", s, now)
+
+	from := now.Add(-policyWindow)
+	assert.Equal(t, big.NewInt(500), r.reserved("tms:treasury", "EUR", "tx4", nil, from))
+	assert.Equal(t, big.NewInt(200), r.reserved("tms:treasury", "EUR", "tx1", nil, from), "the transaction being checked is excluded")
+	assert.Equal(t, big.NewInt(300), r.reserved("tms:treasury", "EUR", "tx4", map[string]bool{"tx2": true}, from), "the recorded transactions are counted by the ttxdb")
+	assert.Equal(t, big.NewInt(10), r.reserved("tms:treasury", "USD", "tx4", nil, from))
+
+	// a new signature of the same transaction replaces its reservation
+	s = newSpend()
+	s.add("EUR", big.NewInt(100), "alice")
+	r.reserve("tms:treasury", "tx1", s, now)
+	assert.Equal(t, big.NewInt(300), r.reserved("tms:treasury", "EUR", "tx4", nil, from))
+
+	// the deleted transactions release their reservations
+	r.OnReceive(&testEvent{message: owner.TxStatusMessage{TxID: "tx2", Status: Confirmed}})
+	assert.Equal(t, big.NewInt(300), r.reserved("tms:treasury", "EUR", "tx4", nil, from))
+	r.OnReceive(&testEvent{message: owner.TxStatusMessage{TxID: "tx2", Status: Deleted}})
+	assert.Equal(t, big.NewInt(100), r.reserved("tms:treasury", "EUR", "tx4", nil, from))
+	assert.Equal(t, big.NewInt(200), r.reserved("tms:other", "EUR", "tx4", nil, from), "the reservations are per wallet")
+	r.release("tx1")
+	r.release("tx3")
+	assert.Equal(t, big.NewInt(0), r.reserved("tms:treasury", "EUR", "tx4", nil, from))
+	assert.Empty(t, r.reservations)
+
+	// the reservations expire with the window of the daily limits
+	r.reserve("tms:treasury", "tx5", s, now)
+	assert.Equal(t, big.NewInt(0), r.reserved("tms:treasury", "EUR", "tx4", nil, now.Add(time.Second)))
+	assert.Empty(t, r.reservations["tms:treasury"])
+}
+
+type testEvent struct {
+	message interface{}
+}
+
+func (e *testEvent) Topic() string {
+	return owner.TxStatusTopic
+}
+
+func (e *testEvent) Message() interface{} {
+	return e.message
+}
+
+func TestRemoteError(t *testing.T) {
+	violation := &PolicyViolation{Wallet: "treasury", Rule: DailyLimit, TokenType: "EUR", Amount: "2501", Limit: "2500"}
+	err := remoteError(policyErrorPayload(errors.WithMessage(violation, "failed signing")))
+	v, ok := IsPolicyViolation(err)
+	assert.True(t, ok)
+	assert.Equal(t, violation, v)
+	assert.Equal(t, "wallet [treasury]: transfers of [2501] [EUR] in the last 24 hours exceed the daily limit [2500]", err.Error())
+
+	err = remoteError(policyErrorPayload(errors.New("identity [x] is not me")))
+	_, ok = IsPolicyViolation(err)
+	assert.False(t, ok)
+	assert.Equal(t, "identity [x] is not me", err.Error())
+}
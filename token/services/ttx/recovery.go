@@ -0,0 +1,186 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/metrics"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/pkg/errors"
+)
+
+// RecoveryOutcome is what the recovery did with a pending transaction
+type RecoveryOutcome string
+
+const (
+	// Finalized is the outcome of the pending transactions found committed in the vault
+	Finalized RecoveryOutcome = "Finalized"
+	// Rebroadcast is the outcome of the pending transactions whose envelope has been broadcast again
+	Rebroadcast RecoveryOutcome = "Rebroadcast"
+	// Aborted is the outcome of the pending transactions that cannot complete,
+	// their tokens have been released
+	Aborted RecoveryOutcome = "Aborted"
+)
+
+// RecoveredTransaction reports the outcome of the recovery of a pending transaction
+type RecoveredTransaction struct {
+	TxID    string
+	Phase   Phase
+	Outcome RecoveryOutcome
+}
+
+// Recover rebroadcasts or aborts the transactions this node left pending, for instance because of a crash.
+// It is meant to run when the node starts.
+// A transaction is resumed only if it has been distributed to all the parties, by broadcasting it again.
+// The transactions in an earlier phase cannot be resumed, because the sessions with the parties
+// waiting for the envelope are lost with the crash, then they are aborted.
+func Recover(sp view2.ServiceProvider) ([]*RecoveredTransaction, error) {
+	boxed, err := view2.GetManager(sp).InitiateView(NewRecoveryView(sp))
+	if err != nil {
+		return nil, err
+	}
+	return boxed.([]*RecoveredTransaction), nil
+}
+
+type recoveryView struct {
+	sp view2.ServiceProvider
+}
+
+// NewRecoveryView returns an instance of the recoveryView.
+// The view does the following, for each pending transaction:
+// 1. If the vault knows the transaction as valid, it forgets it.
+// 2. If the vault knows the transaction as invalid, it aborts it.
+// 3. If all the parties acknowledged the envelope, it broadcasts the envelope again.
+// 4. Otherwise, it aborts the transaction. This is also the case for the Audited and Approved transactions:
+// the parties that signed the token request wait for the envelope on the sessions opened before the crash,
+// then the envelope cannot be distributed anymore.
+// Aborting a transaction releases the tokens it locked.
+func NewRecoveryView(sp view2.ServiceProvider) *recoveryView {
+	return &recoveryView{sp: sp}
+}
+
+// Call executes the view.
+// It returns the outcome for the pending transactions it could recover, the others stay pending.
+func (r *recoveryView) Call(context view.Context) (interface{}, error) {
+	lifecycle := GetLifecycle(context)
+	records, err := lifecycle.Pending()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed listing pending transactions")
+	}
+	logger.Infof("found [%d] pending transactions", len(records))
+	// the transactions left pending by a previous run are not counted yet
+	metrics.Get().PendingTransactions.Set(float64(len(records)))
+
+	var recovered []*RecoveredTransaction
+	for _, record := range records {
+		outcome, err := r.recover(context, lifecycle, record)
+		if err != nil {
+			logger.Errorf("failed recovering transaction [%s] in phase [%s]: [%s]", record.TxID, record.Phase, err)
+			continue
+		}
+		logger.Infof("recovered transaction [%s] in phase [%s]: [%s]", record.TxID, record.Phase, outcome)
+		recovered = append(recovered, &RecoveredTransaction{
+			TxID:    record.TxID,
+			Phase:   record.Phase,
+			Outcome: outcome,
+		})
+	}
+	return recovered, nil
+}
+
+func (r *recoveryView) recover(context view.Context, lifecycle *Lifecycle, record *LifecycleRecord) (RecoveryOutcome, error) {
+	tx, err := newTransactionFromBytes(context, record.Transaction)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed unmarshalling transaction")
+	}
+	tx.SP = r.sp
+
+	net := network.GetInstance(context, tx.Network(), tx.Channel())
+	if net == nil {
+		return "", errors.Errorf("network [%s:%s] not found", tx.Network(), tx.Channel())
+	}
+	v, err := net.Vault(tx.Namespace())
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed getting vault [%s]", tx.Namespace())
+	}
+	status, err := v.Status(tx.ID())
+	if err != nil {
+		return "", errors.WithMessage(err, "failed getting transaction status")
+	}
+
+	switch {
+	case status == network.Valid:
+		return Finalized, lifecycle.Remove(tx.ID())
+	case status == network.Invalid:
+		return Aborted, r.abort(lifecycle, tx, record, status)
+	case record.Phase >= Distributed:
+		if err := net.Broadcast(tx.Payload.Envelope); err != nil {
+			return "", errors.WithMessage(err, "failed broadcasting transaction")
+		}
+		if err := lifecycle.Record(tx, Ordered); err != nil {
+			return "", err
+		}
+		if err := net.SubscribeTxStatusChanges(tx.ID(), newLifecycleListener(net, tx)); err != nil {
+			return "", errors.WithMessage(err, "failed subscribing to transaction status changes")
+		}
+		return Rebroadcast, nil
+	default:
+		return Aborted, r.abort(lifecycle, tx, record, status)
+	}
+}
+
+func (r *recoveryView) abort(lifecycle *Lifecycle, tx *Transaction, record *LifecycleRecord, status network.ValidationCode) error {
+	tx.Release()
+	spendReservations.release(tx.ID())
+	if record.Phase >= Approved {
+		// the envelope might have been stored locally during the distribution
+		if status == network.Busy {
+			v, err := network.GetInstance(r.sp, tx.Network(), tx.Channel()).Vault(tx.Namespace())
+			if err != nil {
+				return errors.WithMessagef(err, "failed getting vault [%s]", tx.Namespace())
+			}
+			if err := v.DiscardTx(tx.ID()); err != nil {
+				return errors.WithMessage(err, "failed discarding transaction")
+			}
+		}
+		if err := NewOwner(r.sp, tx.TokenService()).SetStatus(tx.ID(), Deleted); err != nil {
+			logger.Debugf("no transaction records to delete for [%s]: [%s]", tx.ID(), err)
+		}
+	}
+	return lifecycle.Remove(tx.ID())
+}
+
+// lifecycleListener forgets an ordered transaction once it is final, and releases its tokens if it is invalid
+type lifecycleListener struct {
+	net *network.Network
+	tx  *Transaction
+}
+
+func newLifecycleListener(net *network.Network, tx *Transaction) *lifecycleListener {
+	return &lifecycleListener{net: net, tx: tx}
+}
+
+func (l *lifecycleListener) OnStatusChange(txID string, status int) error {
+	logger.Debugf("tx status changed for pending tx %s: %d", txID, status)
+	switch network.ValidationCode(status) {
+	case network.Valid:
+	case network.Invalid:
+		l.tx.Release()
+	default:
+		return nil
+	}
+	if err := GetLifecycle(l.tx.SP).Remove(txID); err != nil {
+		return err
+	}
+	go func() {
+		if err := l.net.UnsubscribeTxStatusChanges(txID, l); err != nil {
+			logger.Errorf("failed to unsubscribe lifecycle listener for tx-id [%s]: [%s]", txID, err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,34 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/tracing"
+)
+
+// startSpan starts a span of the passed transaction.
+// If no span of the transaction is in progress on this node, the parent is the span context
+// the transaction carries in its transient, set by the party that sent it.
+func startSpan(tx *Transaction, name string, attrs ...tracing.Attribute) *tracing.Span {
+	return tracing.Get().StartTxSpan(name, tx.ID(), tracing.Extract(tx.Payload.Transient), attrs...)
+}
+
+// propagate stores the context of the span of the transaction in progress in the transaction's transient,
+// so that it reaches the parties the transaction is sent to
+func propagate(tx *Transaction) {
+	if c, ok := tracing.Get().Current(tx.ID()); ok && tx.Payload.Transient != nil {
+		tracing.Inject(tx.Payload.Transient, c)
+	}
+}
+
+// traceparent returns the context of the span of the passed transaction in progress, in the W3C format, if any
+func traceparent(txID string) string {
+	if c, ok := tracing.Get().Current(txID); ok {
+		return c.Traceparent()
+	}
+	return ""
+}
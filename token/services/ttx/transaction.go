@@ -15,6 +15,7 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
 	"go.uber.org/zap/zapcore"
 )
@@ -99,6 +100,16 @@ func NewTransaction(sp view.Context, signer view.Identity, opts ...TxOption) (*T
 }
 
 func NewTransactionFromBytes(sp view.Context, raw []byte) (*Transaction, error) {
+	tx, err := newTransactionFromBytes(sp, raw)
+	if err != nil {
+		return nil, err
+	}
+	sp.OnError(tx.Release)
+	return tx, nil
+}
+
+// newTransactionFromBytes unmarshals the passed transaction without releasing its tokens if the passed context fails
+func newTransactionFromBytes(sp view.Context, raw []byte) (*Transaction, error) {
 	tx := &Transaction{
 		Payload: &Payload{
 			Transient:    map[string][]byte{},
@@ -131,7 +142,6 @@ func NewTransactionFromBytes(sp view.Context, raw []byte) (*Transaction, error)
 	// 		return nil, err
 	// 	}
 	// }
-	sp.OnError(tx.Release)
 	return tx, nil
 }
 
@@ -196,15 +206,56 @@ func (t *Transaction) Issue(wallet *token.IssuerWallet, receiver view.Identity,
 }
 
 // Transfer appends a new Transfer operation to the TokenRequest inside this transaction
+// The transfer is checked against the spending policy of the wallet, if any.
 func (t *Transaction) Transfer(wallet *token.OwnerWallet, typ string, values []uint64, owners []view.Identity, opts ...token.TransferOption) error {
+	quantities := make([]token2.Quantity, len(values))
+	for i, v := range values {
+		quantities[i] = token2.NewQuantityFromUInt64(v)
+	}
+	if err := t.checkTransferPolicy(wallet, typ, quantities, owners); err != nil {
+		return err
+	}
 	_, err := t.TokenRequest.Transfer(wallet, typ, values, owners, opts...)
 	return err
 }
 
+// Redeem appends a new Redeem operation to the TokenRequest inside this transaction.
+// The redemption is checked against the spending policy of the wallet, if any.
 func (t *Transaction) Redeem(wallet *token.OwnerWallet, typ string, value uint64, opts ...token.TransferOption) error {
+	if err := t.checkTransferPolicy(wallet, typ, []token2.Quantity{token2.NewQuantityFromUInt64(value)}, []view.Identity{nil}); err != nil {
+		return err
+	}
 	return t.TokenRequest.Redeem(wallet, typ, value, opts...)
 }
 
+// IssueQuantity appends a new Issue operation to the TokenRequest inside this transaction.
+// The quantity can go beyond 64 bits, up to the precision of the public parameters.
+func (t *Transaction) IssueQuantity(wallet *token.IssuerWallet, receiver view.Identity, typ string, q token2.Quantity, opts ...token.IssueOption) error {
+	_, err := t.TokenRequest.IssueQuantity(wallet, receiver, typ, q, opts...)
+	return err
+}
+
+// TransferQuantities appends a new Transfer operation to the TokenRequest inside this transaction.
+// The quantities can go beyond 64 bits, up to the precision of the public parameters.
+// The transfer is checked against the spending policy of the wallet, if any.
+func (t *Transaction) TransferQuantities(wallet *token.OwnerWallet, typ string, values []token2.Quantity, owners []view.Identity, opts ...token.TransferOption) error {
+	if err := t.checkTransferPolicy(wallet, typ, values, owners); err != nil {
+		return err
+	}
+	_, err := t.TokenRequest.TransferQuantities(wallet, typ, values, owners, opts...)
+	return err
+}
+
+// RedeemQuantity appends a new Redeem operation to the TokenRequest inside this transaction.
+// The quantity can go beyond 64 bits, up to the precision of the public parameters.
+// The redemption is checked against the spending policy of the wallet, if any.
+func (t *Transaction) RedeemQuantity(wallet *token.OwnerWallet, typ string, value token2.Quantity, opts ...token.TransferOption) error {
+	if err := t.checkTransferPolicy(wallet, typ, []token2.Quantity{value}, []view.Identity{nil}); err != nil {
+		return err
+	}
+	return t.TokenRequest.RedeemQuantity(wallet, typ, value, opts...)
+}
+
 func (t *Transaction) Outputs() (*token.OutputStream, error) {
 	return t.TokenRequest.Outputs()
 }
@@ -236,6 +287,10 @@ func (t *Transaction) Release() {
 		logger.Warnf("failed releasing tokens locked by [%s], [%s]", t.ID(), err)
 	}
 
+	if err := GetLifecycle(t.SP).release(t.ID()); err != nil {
+		logger.Warnf("failed releasing lifecycle record of [%s], [%s]", t.ID(), err)
+	}
+
 	pub, err := publisher(t.SP)
 	if err != nil {
 		return
@@ -16,6 +16,7 @@ import (
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/metrics"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/driver"
 	"github.com/pkg/errors"
 	"go.uber.org/atomic"
@@ -202,6 +203,7 @@ func newDB(p driver.TokenTransactionDB) *DB {
 
 // Append appends send and receive movements, and transaction records corresponding to the passed token request
 func (db *DB) Append(req *token.Request) error {
+	defer observeWrite("append", time.Now())
 	logger.Debugf("Appending new record... [%d]", db.counter)
 	db.storeLock.Lock()
 	defer db.storeLock.Unlock()
@@ -224,7 +226,7 @@ func (db *DB) Append(req *token.Request) error {
 		db.rollback(err)
 		return errors.WithMessagef(err, "append received movements for txid '%s' failed", record.Anchor)
 	}
-	if err := db.appendTransactions(record); err != nil {
+	if err := db.appendTransactions(record, applicationMetadata(req)); err != nil {
 		db.rollback(err)
 		return errors.WithMessagef(err, "append transactions for txid '%s' failed", record.Anchor)
 	}
@@ -239,6 +241,7 @@ func (db *DB) Append(req *token.Request) error {
 
 // AppendTransactionRecord appends the transaction records corresponding to the passed token request.
 func (db *DB) AppendTransactionRecord(req *token.Request) error {
+	defer observeWrite("append_transaction_record", time.Now())
 	logger.Debugf("Appending new transaction record... [%d]", db.counter)
 	db.storeLock.Lock()
 	defer db.storeLock.Unlock()
@@ -262,7 +265,7 @@ func (db *DB) AppendTransactionRecord(req *token.Request) error {
 		db.rollback(err)
 		return errors.WithMessagef(err, "begin update for txid '%s' failed", record.Anchor)
 	}
-	if err := db.appendTransactions(record); err != nil {
+	if err := db.appendTransactions(record, applicationMetadata(req)); err != nil {
 		db.rollback(err)
 		return errors.WithMessagef(err, "append transactions for txid '%s' failed", record.Anchor)
 	}
@@ -275,6 +278,34 @@ func (db *DB) AppendTransactionRecord(req *token.Request) error {
 	return nil
 }
 
+// AppendTransactionRecords appends the passed transaction records as they are, preserving their status and timestamp.
+// It is used to restore the history of a wallet from a backup.
+func (db *DB) AppendTransactionRecords(records ...*TransactionRecord) error {
+	defer observeWrite("append_transaction_records", time.Now())
+	logger.Debugf("Appending [%d] transaction records... [%d]", len(records), db.counter)
+	db.storeLock.Lock()
+	defer db.storeLock.Unlock()
+	logger.Debug("lock acquired")
+
+	if err := db.db.BeginUpdate(); err != nil {
+		db.rollback(err)
+		return errors.WithMessagef(err, "begin update failed")
+	}
+	for _, record := range records {
+		if err := db.db.AddTransaction(record); err != nil {
+			db.rollback(err)
+			return errors.WithMessagef(err, "append transaction record for txid '%s' failed", record.TxID)
+		}
+	}
+	if err := db.db.Commit(); err != nil {
+		db.rollback(err)
+		return errors.WithMessagef(err, "committing transaction records failed")
+	}
+
+	logger.Debugf("Appending transaction records completed without errors")
+	return nil
+}
+
 // NewQueryExecutor returns a new query executor
 func (db *DB) NewQueryExecutor() *QueryExecutor {
 	db.counter.Inc()
@@ -285,6 +316,7 @@ func (db *DB) NewQueryExecutor() *QueryExecutor {
 
 // SetStatus sets the status of the audit records with the passed transaction id to the passed status
 func (db *DB) SetStatus(txID string, status TxStatus) error {
+	defer observeWrite("set_status", time.Now())
 	logger.Debugf("Set status [%s][%s]...[%d]", txID, status, db.counter)
 	db.storeLock.Lock()
 	defer db.storeLock.Unlock()
@@ -406,7 +438,7 @@ func (db *DB) appendReceivedMovements(record *token.AuditRecord) error {
 	return nil
 }
 
-func (db *DB) appendTransactions(record *token.AuditRecord) error {
+func (db *DB) appendTransactions(record *token.AuditRecord, metadata map[string][]byte) error {
 	inputs := record.Inputs
 	outputs := record.Outputs
 
@@ -458,14 +490,15 @@ func (db *DB) appendTransactions(record *token.AuditRecord) error {
 				}
 
 				if err := db.db.AddTransaction(&driver.TransactionRecord{
-					TxID:         record.Anchor,
-					SenderEID:    inEID,
-					RecipientEID: outEID,
-					TokenType:    tokenType,
-					Amount:       received,
-					Status:       driver.Pending,
-					ActionType:   tt,
-					Timestamp:    timestamp,
+					TxID:                record.Anchor,
+					SenderEID:           inEID,
+					RecipientEID:        outEID,
+					TokenType:           tokenType,
+					Amount:              received,
+					Status:              driver.Pending,
+					ActionType:          tt,
+					Timestamp:           timestamp,
+					ApplicationMetadata: metadata,
 				}); err != nil {
 					if err1 := db.db.Discard(); err1 != nil {
 						logger.Errorf("got error [%s]; discarding caused [%s]", err.Error(), err1.Error())
@@ -482,6 +515,11 @@ func (db *DB) appendTransactions(record *token.AuditRecord) error {
 	return nil
 }
 
+// observeWrite records the time taken by the passed write operation, including the wait for the store lock
+func observeWrite(operation string, start time.Time) {
+	metrics.Get().DBWriteDuration.With("operation", operation).Observe(metrics.Since(start))
+}
+
 func (db *DB) rollback(err error) {
 	if err1 := db.db.Discard(); err1 != nil {
 		logger.Errorf("got error %s; discarding caused %s", err.Error(), err1.Error())
@@ -559,6 +597,14 @@ func Get(sp view2.ServiceProvider, w Wallet) *DB {
 	return c
 }
 
+// applicationMetadata returns the application metadata of the passed request, if any
+func applicationMetadata(req *token.Request) map[string][]byte {
+	if req.Metadata == nil {
+		return nil
+	}
+	return req.Metadata.Application
+}
+
 // joinIOEIDs joins enrollment IDs of inputs and outputs
 func joinIOEIDs(record *token.AuditRecord) []string {
 	iEIDs := record.Inputs.EnrollmentIDs()
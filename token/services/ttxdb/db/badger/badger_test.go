@@ -98,6 +98,9 @@ func TestTransaction(t *testing.T) {
 			Timestamp:    now,
 			Status:       driver.Pending,
 		}
+		if i%2 == 0 {
+			tr1.ApplicationMetadata = map[string][]byte{"ttx.payment.reference": []byte(fmt.Sprintf("invoice-%d", i))}
+		}
 		assert.NoError(t, db.AddTransaction(tr1))
 		txs = append(txs, tr1)
 	}
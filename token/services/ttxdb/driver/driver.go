@@ -101,6 +101,9 @@ type TransactionRecord struct {
 	Timestamp time.Time
 	// Status is the status of the transaction
 	Status TxStatus
+	// ApplicationMetadata is the application metadata of the token request, if any.
+	// For example, it contains the reference of the payment request the transaction pays.
+	ApplicationMetadata map[string][]byte
 }
 
 func (t *TransactionRecord) String() string {
@@ -204,6 +204,24 @@ func (t *ManagementService) NewMetadataFromBytes(raw []byte) (*Metadata, error)
 	}, nil
 }
 
+// NewLedgerMetadata returns a Metadata object that recovers the tokens of a Token Request from the metadata
+// that its outputs carry on the ledger, encrypted for their owners.
+// It returns false if the driver does not support metadata on the ledger, or if the TMS is not configured
+// to put the metadata of the outputs on the ledger.
+func (t *ManagementService) NewLedgerMetadata() (*Metadata, bool) {
+	if _, ok := t.tms.(driver.TokenInfoDecrypter); !ok {
+		return nil, false
+	}
+	cm := t.tms.ConfigManager()
+	if cm == nil || cm.TMS() == nil || cm.TMS().Outputs == nil || !cm.TMS().Outputs.EncryptMetadata {
+		return nil, false
+	}
+	return &Metadata{
+		TMS:                  t.tms,
+		TokenRequestMetadata: &driver.TokenRequestMetadata{},
+	}, true
+}
+
 // Validator returns a new token validator for this TMS
 func (t *ManagementService) Validator() (*Validator, error) {
 	v, err := t.tms.Validator()
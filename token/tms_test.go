@@ -0,0 +1,53 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/config"
+)
+
+type configManager struct {
+	config.Manager
+	tms *config.TMS
+}
+
+func (c *configManager) TMS() *config.TMS {
+	return c.tms
+}
+
+type decrypterTMS struct {
+	driver.TokenManagerService
+	cm config.Manager
+}
+
+func (d *decrypterTMS) ConfigManager() config.Manager {
+	return d.cm
+}
+
+func (d *decrypterTMS) DecryptTokenInfo(output []byte) ([]byte, error) {
+	return nil, nil
+}
+
+func TestNewLedgerMetadata(t *testing.T) {
+	tms := &ManagementService{tms: &decrypterTMS{cm: &configManager{tms: &config.TMS{}}}}
+	_, ok := tms.NewLedgerMetadata()
+	assert.False(t, ok)
+
+	tms = &ManagementService{tms: &decrypterTMS{cm: &configManager{tms: &config.TMS{Outputs: &config.Outputs{EncryptMetadata: false}}}}}
+	_, ok = tms.NewLedgerMetadata()
+	assert.False(t, ok)
+
+	tms = &ManagementService{tms: &decrypterTMS{cm: &configManager{tms: &config.TMS{Outputs: &config.Outputs{EncryptMetadata: true}}}}}
+	metadata, ok := tms.NewLedgerMetadata()
+	assert.True(t, ok)
+	assert.NotNil(t, metadata)
+}
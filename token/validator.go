@@ -7,7 +7,10 @@ SPDX-License-Identifier: Apache-2.0
 package token
 
 import (
+	"time"
+
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
 )
 
 // Ledger models a read-only ledger
@@ -15,6 +18,13 @@ type Ledger interface {
 	GetState(key string) ([]byte, error)
 }
 
+// TimedLedger models a read-only ledger that knows the timestamp of the transaction being validated
+type TimedLedger interface {
+	Ledger
+	// TxTimestamp returns the timestamp of the transaction being validated
+	TxTimestamp() (time.Time, error)
+}
+
 // Validator validates a token request
 type Validator struct {
 	backend driver.Validator
@@ -25,11 +35,27 @@ func (c *Validator) UnmarshalActions(raw []byte) ([]interface{}, error) {
 	return c.backend.UnmarshalActions(raw)
 }
 
-// UnmarshallAndVerify unmarshalls the token request and verifies it against the passed ledger and anchor
+// UnmarshallAndVerify unmarshalls the token request and verifies it against the passed ledger and anchor.
+// If the ledger is a TimedLedger, and the driver supports it, the time constraints of the request are checked
+// against the timestamp of the transaction.
 func (c *Validator) UnmarshallAndVerify(ledger Ledger, anchor string, raw []byte) ([]interface{}, error) {
-	actions, err := c.backend.VerifyTokenRequestFromRaw(func(key string) ([]byte, error) {
+	getState := func(key string) ([]byte, error) {
 		return ledger.GetState(key)
-	}, anchor, raw)
+	}
+	var actions []interface{}
+	var err error
+	tl, isTimed := ledger.(TimedLedger)
+	tv, supportsTime := c.backend.(driver.TimedValidator)
+	if isTimed && supportsTime {
+		var timestamp time.Time
+		timestamp, err = tl.TxTimestamp()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get timestamp of [%s]", anchor)
+		}
+		actions, err = tv.VerifyTokenRequestFromRawAt(getState, anchor, raw, timestamp)
+	} else {
+		actions, err = c.backend.VerifyTokenRequestFromRaw(getState, anchor, raw)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -38,3 +64,41 @@ func (c *Validator) UnmarshallAndVerify(ledger Ledger, anchor string, raw []byte
 	copy(res, actions)
 	return res, nil
 }
+
+// UnmarshallAndVerifyAll unmarshalls the passed token requests, for instance those of a block,
+// and verifies them against the passed ledger and anchors.
+// The time constraints of the requests are checked against the local clock.
+// It returns, for each request, its actions if it is valid, or the reason why it is not.
+// If the driver supports it, the requests are verified at once.
+func (c *Validator) UnmarshallAndVerifyAll(ledger Ledger, anchors []string, raws [][]byte) ([][]interface{}, []error) {
+	getState := func(key string) ([]byte, error) {
+		return ledger.GetState(key)
+	}
+	if bv, ok := c.backend.(driver.BatchValidator); ok {
+		return bv.VerifyTokenRequestsFromRaw(getState, anchors, raws)
+	}
+
+	actions := make([][]interface{}, len(raws))
+	errs := make([]error, len(raws))
+	if len(anchors) != len(raws) {
+		for i := range errs {
+			errs[i] = errors.Errorf("number of anchors [%d] does not match number of token requests [%d]", len(anchors), len(raws))
+		}
+		return actions, errs
+	}
+	for i, raw := range raws {
+		actions[i], errs[i] = c.backend.VerifyTokenRequestFromRaw(getState, anchors[i], raw)
+	}
+	return actions, errs
+}
+
+// SetBatchVerification enables or disables batch verification of the proofs of a token request.
+// It returns false if the driver does not support it.
+func (c *Validator) SetBatchVerification(enabled bool) bool {
+	bv, ok := c.backend.(driver.BatchValidator)
+	if !ok {
+		return false
+	}
+	bv.SetBatchVerification(enabled)
+	return true
+}
@@ -0,0 +1,78 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+)
+
+type timedValidator struct {
+	driver.Validator
+	timestamp time.Time
+}
+
+func (v *timedValidator) VerifyTokenRequestFromRaw(getState driver.GetStateFnc, anchor string, raw []byte) ([]interface{}, error) {
+	v.timestamp = time.Time{}
+	return []interface{}{"action"}, nil
+}
+
+func (v *timedValidator) VerifyTokenRequestFromRawAt(getState driver.GetStateFnc, anchor string, raw []byte, timestamp time.Time) ([]interface{}, error) {
+	v.timestamp = timestamp
+	return []interface{}{"action"}, nil
+}
+
+type ledger struct{}
+
+func (l *ledger) GetState(key string) ([]byte, error) {
+	return nil, nil
+}
+
+type timedLedger struct {
+	ledger
+	timestamp time.Time
+}
+
+func (l *timedLedger) TxTimestamp() (time.Time, error) {
+	return l.timestamp, nil
+}
+
+func TestUnmarshallAndVerifyAt(t *testing.T) {
+	backend := &timedValidator{}
+	v := &Validator{backend: backend}
+
+	// the timestamp of the transaction is passed to the driver
+	ts := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	actions, err := v.UnmarshallAndVerify(&timedLedger{timestamp: ts}, "1", nil)
+	assert.NoError(t, err)
+	assert.Len(t, actions, 1)
+	assert.Equal(t, ts, backend.timestamp)
+
+	// no timestamp is known
+	_, err = v.UnmarshallAndVerify(&ledger{}, "1", nil)
+	assert.NoError(t, err)
+	assert.True(t, backend.timestamp.IsZero())
+}
+
+func TestUnmarshallAndVerifyAll(t *testing.T) {
+	// the driver does not verify many requests at once, the requests are verified one by one
+	v := &Validator{backend: &timedValidator{}}
+	actions, errs := v.UnmarshallAndVerifyAll(&timedLedger{timestamp: time.Now()}, []string{"1", "2"}, [][]byte{nil, nil})
+	assert.Equal(t, []error{nil, nil}, errs)
+	assert.Len(t, actions, 2)
+	assert.Len(t, actions[0], 1)
+	assert.Len(t, actions[1], 1)
+
+	_, errs = v.UnmarshallAndVerifyAll(&ledger{}, []string{"1"}, [][]byte{nil, nil})
+	assert.Len(t, errs, 2)
+	assert.EqualError(t, errs[0], "number of anchors [1] does not match number of token requests [2]")
+	assert.EqualError(t, errs[1], "number of anchors [1] does not match number of token requests [2]")
+}
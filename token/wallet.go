@@ -241,7 +241,11 @@ type OwnerWallet struct {
 
 // GetRecipientIdentity returns the owner identity. This can be a long term identity or a pseudonym depending
 // on the underlying token driver.
+// If the enrollment ID of the wallet has been revoked, GetRecipientIdentity returns an error wrapping ErrRevoked.
 func (o *OwnerWallet) GetRecipientIdentity() (view.Identity, error) {
+	if err := o.managementService.PublicParametersManager().CheckEnrollmentIDs(o.EnrollmentID()); err != nil {
+		return nil, errors.WithMessagef(err, "wallet [%s] cannot receive tokens", o.ID())
+	}
 	return o.w.GetRecipientIdentity()
 }
 
@@ -288,6 +292,17 @@ func (o *OwnerWallet) EnrollmentID() string {
 	return o.w.EnrollmentID()
 }
 
+// ListIdentities returns the recipient identities bound to this wallet so far.
+func (o *OwnerWallet) ListIdentities() ([]view.Identity, error) {
+	return o.w.ListIdentities()
+}
+
+// RestoreIdentity binds the passed recipient identity, previously generated by this wallet, back to it
+// together with the passed audit information and token metadata.
+func (o *OwnerWallet) RestoreIdentity(id view.Identity, auditInfo []byte, metadata []byte) error {
+	return o.w.RestoreIdentity(id, auditInfo, metadata)
+}
+
 // IssuerWallet models the wallet of an issuer
 type IssuerWallet struct {
 	*Wallet